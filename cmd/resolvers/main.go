@@ -17,16 +17,28 @@ limitations under the License.
 package main
 
 import (
+	"log"
+	nethttp "net/http"
 	"os"
+	"strconv"
 	"strings"
 
+	"contrib.go.opencensus.io/exporter/ocagent"
 	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/bundle"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/cluster"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/configmap"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/filesystem"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/gcs"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/git"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/helm"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/http"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/hub"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/s3"
+	"go.opencensus.io/trace"
 	filteredinformerfactory "knative.dev/pkg/client/injection/kube/informers/factory/filtered"
+	"knative.dev/pkg/injection"
 	"knative.dev/pkg/injection/sharedmain"
 	"knative.dev/pkg/signals"
 )
@@ -36,18 +48,100 @@ func main() {
 
 	apiURL := os.Getenv("HUB_API")
 	hubURL := hub.DefaultHubURL
-	if apiURL == "" {
-		hubURL = hub.DefaultHubURL
-	} else {
-		if !strings.HasSuffix(apiURL, "/") {
-			apiURL += "/"
+	if apiURL != "" {
+		hubURL = strings.TrimSuffix(apiURL, "/")
+	}
+
+	var fallbackHubURLs []string
+	for _, fallbackURL := range strings.Split(os.Getenv("HUB_API_FALLBACKS"), ",") {
+		if fallbackURL = strings.TrimSpace(fallbackURL); fallbackURL != "" {
+			fallbackHubURLs = append(fallbackHubURLs, strings.TrimSuffix(fallbackURL, "/"))
+		}
+	}
+
+	artifactHubURL := hub.DefaultArtifactHubURL
+	if artifactAPIURL := os.Getenv("ARTIFACT_HUB_API"); artifactAPIURL != "" {
+		artifactHubURL = strings.TrimSuffix(artifactAPIURL, "/")
+	}
+
+	// Every resolved resource must be signed with the private key of the
+	// public key at VERIFICATION_PUBLIC_KEY, if it is set.
+	var modifiers []framework.ReconcilerModifier
+	if keyPath := os.Getenv("VERIFICATION_PUBLIC_KEY"); keyPath != "" {
+		pemKey, err := os.ReadFile(keyPath)
+		if err != nil {
+			log.Fatalf("Error reading VERIFICATION_PUBLIC_KEY: %v", err)
+		}
+		verifier, err := framework.NewPublicKeyVerifier(pemKey)
+		if err != nil {
+			log.Fatalf("Error loading VERIFICATION_PUBLIC_KEY: %v", err)
+		}
+		modifiers = append(modifiers, framework.WithVerifier(verifier))
+	}
+
+	// The spans of resolutions are exported to the OpenCensus agent, or
+	// OpenTelemetry Collector with the opencensus receiver, at
+	// TRACING_AGENT_ADDRESS, if it is set, sampled at
+	// TRACING_SAMPLE_RATE, which defaults to every resolution.
+	if agentAddress := os.Getenv("TRACING_AGENT_ADDRESS"); agentAddress != "" {
+		sampleRate := 1.0
+		if rate := os.Getenv("TRACING_SAMPLE_RATE"); rate != "" {
+			var err error
+			if sampleRate, err = strconv.ParseFloat(rate, 64); err != nil || sampleRate < 0 || sampleRate > 1 {
+				log.Fatalf("Error parsing TRACING_SAMPLE_RATE %q: must be a number between 0 and 1", rate)
+			}
 		}
-		hubURL = apiURL + hub.YamlEndpoint
+		exporter, err := ocagent.NewExporter(ocagent.WithInsecure(), ocagent.WithAddress(agentAddress), ocagent.WithServiceName("tekton-pipelines-resolvers"))
+		if err != nil {
+			log.Fatalf("Error creating trace exporter for TRACING_AGENT_ADDRESS: %v", err)
+		}
+		defer exporter.Stop()
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(sampleRate)})
+		framework.RegisterTraceExporter(exporter)
+	}
+
+	// Serves the liveness and readiness probes along with the health of
+	// the resolvers' backends, which doesn't affect the probes so that an
+	// unreachable backend doesn't restart every resolver.
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("/health", handler)
+	mux.HandleFunc("/readiness", handler)
+	mux.Handle("/health/resolvers", framework.DefaultHealthChecks)
+
+	port := os.Getenv("PROBES_PORT")
+	if port == "" {
+		port = "8080"
 	}
 
-	sharedmain.MainWithContext(ctx, "controller",
-		framework.NewController(ctx, &git.Resolver{}),
-		framework.NewController(ctx, &hub.Resolver{HubURL: hubURL}),
-		framework.NewController(ctx, &bundle.Resolver{}),
-		framework.NewController(ctx, &cluster.Resolver{}))
+	go func() {
+		log.Printf("Readiness and health check server listening on port %s", port)
+		log.Fatal(nethttp.ListenAndServe(":"+port, mux))
+	}()
+
+	var controllers []injection.ControllerConstructor
+	for _, resolver := range builtinResolvers(&hub.Resolver{HubURL: hubURL, FallbackHubURLs: fallbackHubURLs, ArtifactHubURL: artifactHubURL}) {
+		controllers = append(controllers, framework.NewController(ctx, resolver, modifiers...))
+	}
+	sharedmain.MainWithContext(ctx, "controller", controllers...)
+}
+
+// builtinResolvers returns the resolvers a controller is started for,
+// with the given hub resolver.
+func builtinResolvers(hubResolver *hub.Resolver) []framework.Resolver {
+	return []framework.Resolver{
+		&git.Resolver{},
+		hubResolver,
+		&bundle.Resolver{},
+		&cluster.Resolver{},
+		&http.Resolver{},
+		&configmap.Resolver{},
+		&s3.Resolver{},
+		&gcs.Resolver{},
+		&helm.Resolver{},
+		&filesystem.Resolver{},
+	}
+}
+
+func handler(w nethttp.ResponseWriter, r *nethttp.Request) {
+	w.WriteHeader(nethttp.StatusOK)
 }
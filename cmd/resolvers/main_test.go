@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/hub"
+)
+
+// TestBuiltinResolversParamSchema checks that every built-in resolver
+// describes its params, so that tooling can offer them.
+func TestBuiltinResolversParamSchema(t *testing.T) {
+	ctx := context.Background()
+	reg := framework.NewRegistry()
+	for _, resolver := range builtinResolvers(&hub.Resolver{HubURL: hub.DefaultHubURL}) {
+		resolverType := resolver.GetSelector(ctx)[common.LabelKeyResolverType]
+		if err := reg.Register(resolverType, resolver); err != nil {
+			t.Fatalf("unexpected error registering the %s resolver: %v", resolver.GetName(ctx), err)
+		}
+	}
+
+	for _, resolverType := range reg.Types() {
+		t.Run(resolverType, func(t *testing.T) {
+			schema, err := reg.ParamSchema(ctx, resolverType)
+			if err != nil {
+				t.Fatalf("unexpected error getting the param schema: %v", err)
+			}
+			var required []string
+			for _, p := range schema {
+				if p.Name == "" {
+					t.Errorf("unexpected param without a name in the schema: %+v", p)
+				}
+				if p.Required {
+					required = append(required, p.Name)
+				}
+			}
+			if len(required) == 0 {
+				t.Errorf("expected the schema to declare the resolver's required params but got %+v", schema)
+			}
+		})
+	}
+}
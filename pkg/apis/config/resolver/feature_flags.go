@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -33,6 +34,22 @@ const (
 	DefaultEnableBundlesResolver = false
 	// DefaultEnableClusterResolver is the default value for "enable-cluster-resolver".
 	DefaultEnableClusterResolver = false
+	// DefaultEnableHTTPResolver is the default value for "enable-http-resolver".
+	DefaultEnableHTTPResolver = false
+	// DefaultEnableConfigMapResolver is the default value for "enable-configmap-resolver".
+	DefaultEnableConfigMapResolver = false
+	// DefaultEnableS3Resolver is the default value for "enable-s3-resolver".
+	DefaultEnableS3Resolver = false
+	// DefaultEnableGCSResolver is the default value for "enable-gcs-resolver".
+	DefaultEnableGCSResolver = false
+	// DefaultEnableHelmResolver is the default value for "enable-helm-resolver".
+	DefaultEnableHelmResolver = false
+	// DefaultEnableFilesystemResolver is the default value for "enable-filesystem-resolver".
+	DefaultEnableFilesystemResolver = false
+	// DefaultEnableResolutionFailureEvents is the default value for "enable-resolution-failure-events".
+	DefaultEnableResolutionFailureEvents = true
+	// DefaultEnableResolutionSuccessEvents is the default value for "enable-resolution-success-events".
+	DefaultEnableResolutionSuccessEvents = false
 
 	// EnableGitResolver is the flag used to enable the git remote resolver
 	EnableGitResolver = "enable-git-resolver"
@@ -42,15 +59,62 @@ const (
 	EnableBundlesResolver = "enable-bundles-resolver"
 	// EnableClusterResolver is the flag used to enable the cluster remote resolver
 	EnableClusterResolver = "enable-cluster-resolver"
+	// EnableHTTPResolver is the flag used to enable the http remote resolver
+	EnableHTTPResolver = "enable-http-resolver"
+	// EnableConfigMapResolver is the flag used to enable the configmap remote resolver
+	EnableConfigMapResolver = "enable-configmap-resolver"
+	// EnableS3Resolver is the flag used to enable the s3 remote resolver
+	EnableS3Resolver = "enable-s3-resolver"
+	// EnableGCSResolver is the flag used to enable the gcs remote resolver
+	EnableGCSResolver = "enable-gcs-resolver"
+	// EnableHelmResolver is the flag used to enable the helm remote resolver
+	EnableHelmResolver = "enable-helm-resolver"
+	// EnableFilesystemResolver is the flag used to enable the filesystem
+	// resolver, which is meant for development only
+	EnableFilesystemResolver = "enable-filesystem-resolver"
+	// EnableResolutionFailureEvents is the flag used to enable warning
+	// events for failed resolutions
+	EnableResolutionFailureEvents = "enable-resolution-failure-events"
+	// EnableResolutionSuccessEvents is the flag used to enable normal
+	// events for successful resolutions
+	EnableResolutionSuccessEvents = "enable-resolution-success-events"
 )
 
 // FeatureFlags holds the features configurations
 // +k8s:deepcopy-gen=true
 type FeatureFlags struct {
-	EnableGitResolver     bool
-	EnableHubResolver     bool
-	EnableBundleResolver  bool
-	EnableClusterResolver bool
+	EnableGitResolver        bool
+	EnableHubResolver        bool
+	EnableBundleResolver     bool
+	EnableClusterResolver    bool
+	EnableHTTPResolver       bool
+	EnableConfigMapResolver  bool
+	EnableS3Resolver         bool
+	EnableGCSResolver        bool
+	EnableHelmResolver       bool
+	EnableFilesystemResolver bool
+
+	EnableResolutionFailureEvents bool
+	EnableResolutionSuccessEvents bool
+
+	// EnabledResolvers holds the value of every enable-<type>-resolver
+	// flag keyed by resolver type: those of the resolvers in this
+	// repository, which default when not in the ConfigMap, and those in
+	// the ConfigMap of resolvers registered outside of this repository.
+	EnabledResolvers map[string]bool
+}
+
+// ResolverFeatureFlag returns the name of the flag used to enable the
+// resolver of the given type, e.g. "enable-git-resolver".
+func ResolverFeatureFlag(resolverType string) string {
+	return fmt.Sprintf("enable-%s-resolver", resolverType)
+}
+
+// IsResolverEnabled returns true if the flag used to enable the resolver
+// of the given type is true. Resolvers are disabled when their flag isn't
+// set.
+func (f *FeatureFlags) IsResolverEnabled(resolverType string) bool {
+	return f.EnabledResolvers[resolverType]
 }
 
 // GetFeatureFlagsConfigName returns the name of the configmap containing all
@@ -90,6 +154,54 @@ func NewFeatureFlagsFromMap(cfgMap map[string]string) (*FeatureFlags, error) {
 	if err := setFeature(EnableClusterResolver, DefaultEnableClusterResolver, &tc.EnableClusterResolver); err != nil {
 		return nil, err
 	}
+	if err := setFeature(EnableHTTPResolver, DefaultEnableHTTPResolver, &tc.EnableHTTPResolver); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableConfigMapResolver, DefaultEnableConfigMapResolver, &tc.EnableConfigMapResolver); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableS3Resolver, DefaultEnableS3Resolver, &tc.EnableS3Resolver); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableGCSResolver, DefaultEnableGCSResolver, &tc.EnableGCSResolver); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableHelmResolver, DefaultEnableHelmResolver, &tc.EnableHelmResolver); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableFilesystemResolver, DefaultEnableFilesystemResolver, &tc.EnableFilesystemResolver); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableResolutionFailureEvents, DefaultEnableResolutionFailureEvents, &tc.EnableResolutionFailureEvents); err != nil {
+		return nil, err
+	}
+	if err := setFeature(EnableResolutionSuccessEvents, DefaultEnableResolutionSuccessEvents, &tc.EnableResolutionSuccessEvents); err != nil {
+		return nil, err
+	}
+	// The flags of the resolvers in this repository fall back to their
+	// defaults, those of other resolvers are only known once set.
+	tc.EnabledResolvers = map[string]bool{
+		"git":        tc.EnableGitResolver,
+		"hub":        tc.EnableHubResolver,
+		"bundles":    tc.EnableBundleResolver,
+		"cluster":    tc.EnableClusterResolver,
+		"http":       tc.EnableHTTPResolver,
+		"configmap":  tc.EnableConfigMapResolver,
+		"s3":         tc.EnableS3Resolver,
+		"gcs":        tc.EnableGCSResolver,
+		"helm":       tc.EnableHelmResolver,
+		"filesystem": tc.EnableFilesystemResolver,
+	}
+	for key, cfg := range cfgMap {
+		if !strings.HasPrefix(key, "enable-") || !strings.HasSuffix(key, "-resolver") {
+			continue
+		}
+		value, err := strconv.ParseBool(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing feature flags config %q: %v", cfg, err)
+		}
+		tc.EnabledResolvers[strings.TrimSuffix(strings.TrimPrefix(key, "enable-"), "-resolver")] = value
+	}
 	return &tc, nil
 }
 
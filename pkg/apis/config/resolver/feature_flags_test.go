@@ -34,19 +34,63 @@ func TestNewFeatureFlagsFromConfigMap(t *testing.T) {
 	testCases := []testCase{
 		{
 			expectedConfig: &resolver.FeatureFlags{
-				EnableGitResolver:     false,
-				EnableHubResolver:     false,
-				EnableBundleResolver:  false,
-				EnableClusterResolver: false,
+				EnableGitResolver:        false,
+				EnableHubResolver:        false,
+				EnableBundleResolver:     false,
+				EnableClusterResolver:    false,
+				EnableHTTPResolver:       false,
+				EnableConfigMapResolver:  false,
+				EnableS3Resolver:         false,
+				EnableGCSResolver:        false,
+				EnableHelmResolver:       false,
+				EnableFilesystemResolver: false,
+
+				EnableResolutionFailureEvents: true,
+				EnableResolutionSuccessEvents: false,
+
+				EnabledResolvers: map[string]bool{
+					"git":        false,
+					"hub":        false,
+					"bundles":    false,
+					"cluster":    false,
+					"http":       false,
+					"configmap":  false,
+					"s3":         false,
+					"gcs":        false,
+					"helm":       false,
+					"filesystem": false,
+				},
 			},
 			fileName: "feature-flags-empty",
 		},
 		{
 			expectedConfig: &resolver.FeatureFlags{
-				EnableGitResolver:     true,
-				EnableHubResolver:     true,
-				EnableBundleResolver:  true,
-				EnableClusterResolver: true,
+				EnableGitResolver:        true,
+				EnableHubResolver:        true,
+				EnableBundleResolver:     true,
+				EnableClusterResolver:    true,
+				EnableHTTPResolver:       true,
+				EnableConfigMapResolver:  true,
+				EnableS3Resolver:         true,
+				EnableGCSResolver:        true,
+				EnableHelmResolver:       true,
+				EnableFilesystemResolver: true,
+
+				EnableResolutionFailureEvents: false,
+				EnableResolutionSuccessEvents: true,
+
+				EnabledResolvers: map[string]bool{
+					"git":        true,
+					"hub":        true,
+					"bundles":    true,
+					"cluster":    true,
+					"http":       true,
+					"configmap":  true,
+					"s3":         true,
+					"gcs":        true,
+					"helm":       true,
+					"filesystem": true,
+				},
 			},
 			fileName: "feature-flags-all-flags-set",
 		},
@@ -67,10 +111,52 @@ func TestNewFeatureFlagsFromEmptyConfigMap(t *testing.T) {
 		EnableGitResolver:    resolver.DefaultEnableGitResolver,
 		EnableHubResolver:    resolver.DefaultEnableHubResolver,
 		EnableBundleResolver: resolver.DefaultEnableBundlesResolver,
+
+		EnableResolutionFailureEvents: resolver.DefaultEnableResolutionFailureEvents,
+		EnableResolutionSuccessEvents: resolver.DefaultEnableResolutionSuccessEvents,
+
+		EnabledResolvers: map[string]bool{
+			"git":        resolver.DefaultEnableGitResolver,
+			"hub":        resolver.DefaultEnableHubResolver,
+			"bundles":    resolver.DefaultEnableBundlesResolver,
+			"cluster":    resolver.DefaultEnableClusterResolver,
+			"http":       resolver.DefaultEnableHTTPResolver,
+			"configmap":  resolver.DefaultEnableConfigMapResolver,
+			"s3":         resolver.DefaultEnableS3Resolver,
+			"gcs":        resolver.DefaultEnableGCSResolver,
+			"helm":       resolver.DefaultEnableHelmResolver,
+			"filesystem": resolver.DefaultEnableFilesystemResolver,
+		},
 	}
 	verifyConfigFileWithExpectedFeatureFlagsConfig(t, FeatureFlagsConfigEmptyName, expectedConfig)
 }
 
+func TestIsResolverEnabled(t *testing.T) {
+	flags, err := resolver.NewFeatureFlagsFromMap(map[string]string{
+		"enable-git-resolver":              "true",
+		"enable-custom-resolver":           "true",
+		"enable-other-resolver":            "false",
+		"enable-resolution-failure-events": "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error parsing feature flags: %v", err)
+	}
+	for resolverType, expected := range map[string]bool{
+		"git":     true,
+		"hub":     resolver.DefaultEnableHubResolver,
+		"custom":  true,
+		"other":   false,
+		"missing": false,
+	} {
+		if enabled := flags.IsResolverEnabled(resolverType); enabled != expected {
+			t.Errorf("expected %s resolver enabled to be %t but got %t", resolverType, expected, enabled)
+		}
+	}
+	if flag := resolver.ResolverFeatureFlag("custom"); flag != "enable-custom-resolver" {
+		t.Errorf("unexpected feature flag name %q", flag)
+	}
+}
+
 func TestGetFeatureFlagsConfigName(t *testing.T) {
 	for _, tc := range []struct {
 		description         string
@@ -24,6 +24,13 @@ package resolver
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureFlags) DeepCopyInto(out *FeatureFlags) {
 	*out = *in
+	if in.EnabledResolvers != nil {
+		in, out := &in.EnabledResolvers, &out.EnabledResolvers
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -22,4 +22,54 @@ const (
 	// AnnotationKeyContentType is the annotation key passed back
 	// with a resolved resource's content type.
 	AnnotationKeyContentType = resolution.GroupName + "/content-type"
+
+	// AnnotationKeyProvenance is the annotation key passed back with
+	// the JSON encoded Provenance of a resolved resource.
+	AnnotationKeyProvenance = resolution.GroupName + "/provenance"
+
+	// AnnotationKeySignature is the annotation key passed back with the
+	// base64 encoded signature over a resolved resource's content, for
+	// resolvers whose remote location signs it.
+	AnnotationKeySignature = resolution.GroupName + "/signature"
+
+	// AnnotationKeyCertificate is the annotation key passed back with
+	// the PEM encoded certificate the signature can be verified with,
+	// if the remote location returned one.
+	AnnotationKeyCertificate = resolution.GroupName + "/certificate"
+
+	// AnnotationKeyResolvedBundle is the annotation key passed back
+	// with the OCI reference a resolved resource was served from, pinned
+	// to its digest, e.g. registry/foo@sha256:.... Resolvers that can't
+	// tell the digest omit it.
+	AnnotationKeyResolvedBundle = resolution.GroupName + "/resolved-bundle"
+
+	// AnnotationKeyDetectedKind is the annotation key passed back with
+	// the kind of a resolved resource, e.g. "task", when the request
+	// didn't give one and the resolver detected it from the content.
+	AnnotationKeyDetectedKind = resolution.GroupName + "/detected-kind"
+
+	// AnnotationKeyFallback is the annotation key passed back with the
+	// value "true" when the resolver's backend was unavailable and the
+	// content of the fallback param was resolved instead.
+	AnnotationKeyFallback = resolution.GroupName + "/fallback"
+
+	// AnnotationKeyFallbackReason is the annotation key passed back with
+	// the error the resolution failed with when the content of the
+	// fallback param was resolved instead.
+	AnnotationKeyFallbackReason = resolution.GroupName + "/fallback-reason"
+
+	// AnnotationKeyDocuments is the annotation key passed back with the
+	// number of YAML documents in the resolved content when the
+	// documents param is "multiple".
+	AnnotationKeyDocuments = resolution.GroupName + "/documents"
+
+	// AnnotationKeyDecoded is the annotation key passed back with the
+	// value of the decode param the resolved content was decoded with.
+	AnnotationKeyDecoded = resolution.GroupName + "/decoded"
+
+	// AnnotationKeyDecodedDigest is the annotation key passed back with
+	// the SHA-256 digest of the decoded content, in the form
+	// "sha256:<hex>". The source, provenance and digest annotations of
+	// the resolver still describe the content it resolved.
+	AnnotationKeyDecodedDigest = resolution.GroupName + "/decoded-digest"
 )
@@ -19,6 +19,7 @@ package common
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Error embeds both a short machine-readable string reason for resolution
@@ -125,6 +126,189 @@ func (e *ErrorUpdatingRequest) Unwrap() error {
 	return e.Original
 }
 
+// ResolutionNotFoundError is returned by a resolver when the requested
+// resource doesn't exist in the remote location. Retrying won't help
+// until the resource is created.
+type ResolutionNotFoundError struct {
+	// Resource identifies the resource that wasn't found, e.g. its url.
+	Resource string
+	Original error
+}
+
+var _ error = &ResolutionNotFoundError{}
+
+// Error returns the original error's message.
+func (e *ResolutionNotFoundError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *ResolutionNotFoundError) Unwrap() error {
+	return e.Original
+}
+
+// ResolutionTimeoutError is returned by a resolver when fetching the
+// requested resource took longer than it is allowed to. The same
+// resolution may succeed when retried.
+type ResolutionTimeoutError struct {
+	// Resource identifies the resource being fetched, e.g. its url.
+	Resource string
+	// ResolverType is the type of the resolver whose resolution
+	// deadline was exceeded, e.g. "git". It is only set when the
+	// framework aborted the resolution.
+	ResolverType string
+	// Timeout is the duration that was exceeded.
+	Timeout  time.Duration
+	Original error
+}
+
+var _ error = &ResolutionTimeoutError{}
+
+// Error returns the original error's message.
+func (e *ResolutionTimeoutError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *ResolutionTimeoutError) Unwrap() error {
+	return e.Original
+}
+
+// RateLimitedError is returned by a resolver when the remote location
+// refused a request because too many requests have been made. The
+// resolution is retried after RetryAfter, or with exponential backoff
+// when the remote location didn't say how long to wait.
+type RateLimitedError struct {
+	// Resource identifies the resource being fetched, e.g. its url.
+	Resource string
+	// RetryAfter is how long the remote location asked to wait before
+	// retrying, or zero if it didn't say.
+	RetryAfter time.Duration
+	Original   error
+}
+
+var _ error = &RateLimitedError{}
+
+// Error returns the original error's message.
+func (e *RateLimitedError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Original
+}
+
+// PermissionDeniedError is returned by a resolver when the remote
+// location refused access to the requested resource with the
+// credentials the resolver used. Retrying won't help until access is
+// granted or other credentials are given.
+type PermissionDeniedError struct {
+	// Resource identifies the resource access was denied to, e.g. its
+	// url.
+	Resource string
+	Original error
+}
+
+var _ error = &PermissionDeniedError{}
+
+// Error returns the original error's message.
+func (e *PermissionDeniedError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *PermissionDeniedError) Unwrap() error {
+	return e.Original
+}
+
+// TransientError is returned by a resolver when the remote location
+// failed in a way that is likely to go away, e.g. with a server error
+// or by resetting the connection. Like a RateLimitedError the
+// resolution is retried with exponential backoff rather than failed.
+type TransientError struct {
+	// Resource identifies the resource being fetched, e.g. its url.
+	Resource string
+	Original error
+}
+
+var _ error = &TransientError{}
+
+// Error returns the original error's message.
+func (e *TransientError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Original
+}
+
+// CredentialAccessError is returned when a secret or service account
+// holding the credentials named by a resolution's params can't be read
+// by the resolvers, either because it doesn't exist or because their
+// service account isn't allowed to read it.
+type CredentialAccessError struct {
+	// Resource is the kind of resource that couldn't be read, e.g.
+	// "secrets" or "serviceaccounts".
+	Resource  string
+	Name      string
+	Namespace string
+	// Forbidden is true when the resource couldn't be read because of
+	// the resolvers' RBAC permissions rather than because it doesn't
+	// exist.
+	Forbidden bool
+	Original  error
+}
+
+var _ error = &CredentialAccessError{}
+
+// Error returns the original error's message.
+func (e *CredentialAccessError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *CredentialAccessError) Unwrap() error {
+	return e.Original
+}
+
+// InvalidParamsError is returned when a resolver rejects the params of
+// a resolution in ValidateParams. Retrying won't help until the params
+// are changed.
+type InvalidParamsError struct {
+	// ResolverName is the name of the resolver that rejected the params.
+	ResolverName string
+	Original     error
+}
+
+var _ error = &InvalidParamsError{}
+
+// Error returns the original error's message.
+func (e *InvalidParamsError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *InvalidParamsError) Unwrap() error {
+	return e.Original
+}
+
+// VerificationError is returned when the signature over a resolved
+// resource's content fails verification, or is missing when the
+// configured policy requires one. Retrying won't help until the content
+// or the policy changes.
+type VerificationError struct {
+	// ResolverType is the type of the resolver whose resolved resource
+	// failed verification, e.g. "hub".
+	ResolverType string
+	Original     error
+}
+
+var _ error = &VerificationError{}
+
+// Error returns the original error's message.
+func (e *VerificationError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Original
+}
+
 // ReasonError extracts the reason and underlying error
 // embedded in a given error or returns some sane defaults
 // if the error isn't a common.Error.
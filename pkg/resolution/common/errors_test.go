@@ -19,6 +19,7 @@ package common
 import (
 	"errors"
 	"testing"
+	"time"
 )
 
 type TestError struct{}
@@ -44,3 +45,19 @@ func TestResolutionErrorMessage(t *testing.T) {
 		t.Errorf("resolution error message expected to equal that of original error")
 	}
 }
+
+func TestStructuredErrorsPreserveMessage(t *testing.T) {
+	originalError := errors.New("this is just a test message")
+	for _, err := range []error{
+		&ResolutionNotFoundError{Resource: "foo", Original: originalError},
+		&ResolutionTimeoutError{Resource: "foo", Timeout: time.Second, Original: originalError},
+		&InvalidParamsError{ResolverName: "Fake", Original: originalError},
+	} {
+		if err.Error() != originalError.Error() {
+			t.Errorf("expected message of %T to equal that of original error but got %q", err, err.Error())
+		}
+		if !errors.Is(err, originalError) {
+			t.Errorf("expected %T to unwrap to the original error", err)
+		}
+	}
+}
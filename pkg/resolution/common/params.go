@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+// The canonical keys of the params that several resolvers accept. A
+// resolver accepting one of these params should use the key defined
+// here so that the param means the same thing whichever resolver a
+// request is for. Params only a single resolver accepts are defined in
+// that resolver's package.
+const (
+	// ParamName is the param holding the name of the resource to
+	// resolve, e.g. the name of a task.
+	ParamName = "name"
+
+	// ParamKind is the param holding the kind of the resource to
+	// resolve, e.g. "task" or "pipeline".
+	ParamKind = "kind"
+
+	// ParamDigest is the param holding the digest, of the form
+	// "sha256:<hex>", that the resolved resource must have.
+	ParamDigest = "digest"
+
+	// ParamTimeout is the param holding the maximum duration of the
+	// requests a resolver makes to its backend, e.g. "30s".
+	ParamTimeout = "timeout"
+
+	// ParamFallback is the param holding inline content, a YAML or
+	// JSON object, that a resolution resolves to when the resolver's
+	// backend is unavailable. It is handled by the resolver framework
+	// and never passed to the resolver itself.
+	ParamFallback = "fallback"
+
+	// ParamDocuments is the param controlling whether resolved content
+	// may hold several YAML documents, either DocumentsSingle or
+	// DocumentsMultiple. It is handled by the resolver framework and
+	// never passed to the resolver itself.
+	ParamDocuments = "documents"
+
+	// ParamDecode is the param naming the transformation the resolved
+	// content is decoded with, one of DecodeBase64, DecodeGzip or
+	// DecodeGzipBase64, for content stored encoded at rest. It is
+	// handled by the resolver framework and never passed to the resolver
+	// itself.
+	ParamDecode = "decode"
+)
+
+// The values of the documents param.
+const (
+	// DocumentsSingle rejects resolved content holding more than one
+	// YAML document.
+	DocumentsSingle = "single"
+
+	// DocumentsMultiple allows resolved content holding several YAML
+	// documents, which are passed back in the order they appear.
+	DocumentsMultiple = "multiple"
+)
+
+// The values of the decode param.
+const (
+	// DecodeBase64 decodes base64 encoded content.
+	DecodeBase64 = "base64"
+
+	// DecodeGzip decompresses gzipped content.
+	DecodeGzip = "gzip"
+
+	// DecodeGzipBase64 decodes content that was gzipped and then base64
+	// encoded, e.g. to store it in a ConfigMap: it is base64 decoded
+	// and then decompressed.
+	DecodeGzipBase64 = "gzip+base64"
+)
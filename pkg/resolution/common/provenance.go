@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Provenance describes where a resolved resource came from. It is
+// passed back JSON encoded in the AnnotationKeyProvenance annotation so
+// that it can be recorded, e.g. in SLSA provenance, in the same format
+// whichever resolver resolved the resource.
+type Provenance struct {
+	// ResolverType is the value of the resolution.tekton.dev/type label
+	// of the resolver that resolved the resource, e.g. "git".
+	ResolverType string `json:"resolverType"`
+	// URI identifies the location the resource was fetched from, e.g.
+	// a repository url or a bundle reference pinned by digest.
+	URI string `json:"uri,omitempty"`
+	// Digest holds digests of the resource, or of what it was fetched
+	// from, keyed by algorithm, e.g. "sha256".
+	Digest map[string]string `json:"digest,omitempty"`
+	// Coordinates are the resolver specific coordinates of the
+	// resource within its location, e.g. a catalog and version.
+	Coordinates map[string]string `json:"coordinates,omitempty"`
+}
+
+// AnnotationValue returns the provenance encoded as the value of the
+// AnnotationKeyProvenance annotation. Map keys are always encoded in
+// sorted order so the same provenance always has the same encoding.
+func (p Provenance) AnnotationValue() string {
+	// A struct of strings and maps of strings can always be encoded.
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// ParseProvenance decodes the value of an AnnotationKeyProvenance
+// annotation.
+func ParseProvenance(value string) (*Provenance, error) {
+	p := &Provenance{}
+	if err := json.Unmarshal([]byte(value), p); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationKeyProvenance, err)
+	}
+	if p.ResolverType == "" {
+		return nil, fmt.Errorf("invalid %s annotation: missing resolverType", AnnotationKeyProvenance)
+	}
+	return p, nil
+}
@@ -40,4 +40,13 @@ const (
 	// ReasonResolutionTimedOut indicates that a resolver did not
 	// manage to respond to a ResolutionRequest within a timeout.
 	ReasonResolutionTimedOut = "ResolutionTimedOut"
+
+	// ReasonResolverDisabled indicates that a resolver could not
+	// handle a ResolutionRequest because it is disabled by its
+	// feature flag.
+	ReasonResolverDisabled = "ResolverDisabled"
+
+	// ReasonResourceNotFound indicates that the resource requested
+	// by a ResolutionRequest does not exist in the remote location.
+	ReasonResourceNotFound = "ResourceNotFound"
 )
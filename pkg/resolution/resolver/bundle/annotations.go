@@ -13,7 +13,10 @@ limitations under the License.
 
 package bundle
 
-import "github.com/tektoncd/pipeline/pkg/apis/resolution"
+import (
+	"github.com/tektoncd/pipeline/pkg/apis/resolution"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+)
 
 const (
 	// BundleAnnotationKind is the image layer annotation used to indicate
@@ -41,4 +44,31 @@ var (
 	// ResolverAnnotationAPIVersion is the resolver annotation used to
 	// indicate the "apiVersion" of resource.
 	ResolverAnnotationAPIVersion = resolution.GroupName + "/" + BundleAnnotationAPIVersion
+
+	// ResolverAnnotationResolvedBundle is the resolver annotation used to
+	// indicate the bundle reference pinned to the digest it resolved to,
+	// e.g. registry/foo@sha256:.... It is shared with the other resolvers
+	// serving resources from OCI references.
+	ResolverAnnotationResolvedBundle = common.AnnotationKeyResolvedBundle
+
+	// ResolverAnnotationRequestedBundle is the resolver annotation used to
+	// indicate the bundle reference given in the request.
+	ResolverAnnotationRequestedBundle = resolution.GroupName + "/requested-bundle"
+
+	// ResolverAnnotationPulledBundle is the resolver annotation used to
+	// indicate the bundle reference that was actually pulled, which
+	// differs from the requested one when a registry mirror matched it.
+	ResolverAnnotationPulledBundle = resolution.GroupName + "/pulled-bundle"
+
+	// ResolverAnnotationSelectedTag is the resolver annotation used to
+	// indicate the tag selected by the tag param when it's a tag pattern
+	// or a range of versions. The digest it pointed to is recorded in
+	// the resolved bundle.
+	ResolverAnnotationSelectedTag = resolution.GroupName + "/selected-tag"
+
+	// ResolverAnnotationReferrers is the resolver annotation used to list,
+	// as a JSON array, the artifacts attached to the resolved bundle
+	// through the OCI referrers API when the referrers param is "true".
+	// It is omitted when the registry doesn't support the referrers API.
+	ResolverAnnotationReferrers = resolution.GroupName + "/referrers"
 )
@@ -16,31 +16,156 @@ package bundle
 import (
 	"archive/tar"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	// MaximumBundleObjects defines the maximum number of objects in a bundle
 	MaximumBundleObjects = 20
+
+	// DefaultTimeout is how long pulling a bundle and extracting an
+	// object from it may take when no timeout is set.
+	DefaultTimeout = time.Minute
 )
 
+// detectableKinds are the kinds an object in a bundle may be detected
+// as when the request doesn't give its kind.
+var detectableKinds = []string{"task", "pipeline"}
+
 // RequestOptions are the options used to request a resource from
 // a remote bundle.
 type RequestOptions struct {
-	ServiceAccount string
-	Bundle         string
-	EntryName      string
-	Kind           string
+	ServiceAccount  string
+	ImagePullSecret string
+	Bundle          string
+	RequireDigest   bool
+	EntryName       string
+	// Kind is the kind of the object. The object may be of any kind
+	// Tekton bundles hold when empty, in which case its kind is detected.
+	Kind string
+	// AnnotationKey and AnnotationValue are the layer annotation that
+	// selects the object instead of EntryName. The object is selected by
+	// name when AnnotationKey is empty.
+	AnnotationKey   string
+	AnnotationValue string
+	// Layer selects the layers the object is looked for in, either by
+	// the index of a layer in the manifest or by their media type.
+	// Every layer is looked in when empty.
+	Layer string
+	// ExpectedDigest is the digest, of the form "sha256:<hex>", that the
+	// bundle's manifest must have. It isn't checked when empty.
+	ExpectedDigest string
+	// Timeout bounds pulling the bundle and extracting the object from
+	// it. Defaults to DefaultTimeout when zero.
+	Timeout time.Duration
+	// LayerMediaTypes are the media types the layer holding the object
+	// may have. Layers of any media type are accepted when empty.
+	LayerMediaTypes []string
+	// MirroredBundle is the reference the bundle is pulled from instead
+	// of Bundle when a registry mirror matches it. Bundle is pulled when
+	// empty.
+	MirroredBundle string
+	// Platform is the platform whose manifest is selected when the
+	// bundle is a multi-platform image index. Defaults to the platform
+	// the resolver runs on when nil.
+	Platform *v1.Platform
+	// Referrers is whether the artifacts attached to the bundle through
+	// the OCI referrers API are listed in the resolved annotations.
+	Referrers bool
+	// TagPattern selects the tag of Bundle, which then has none, among
+	// the tags of its repository: either a tag pattern with wildcards
+	// or a range of versions. Bundle is pulled as is when empty.
+	TagPattern string
+}
+
+// withTag returns the options with the given tag, selected by the tag
+// pattern, added to the bundle and to its mirror, if any.
+func (o RequestOptions) withTag(tag string) RequestOptions {
+	o.Bundle += ":" + tag
+	if o.MirroredBundle != "" {
+		o.MirroredBundle += ":" + tag
+	}
+	return o
+}
+
+// pulledBundle returns the reference the bundle is actually pulled from.
+func (o RequestOptions) pulledBundle() string {
+	if o.MirroredBundle != "" {
+		return o.MirroredBundle
+	}
+	return o.Bundle
+}
+
+// selection describes how the object is selected from the bundle
+// besides its kind, e.g. "name: foo".
+func (o RequestOptions) selection() string {
+	if o.AnnotationKey != "" {
+		return fmt.Sprintf("annotation: %s=%s", o.AnnotationKey, o.AnnotationValue)
+	}
+	return "name: " + o.EntryName
+}
+
+// kind describes the kind of the object that is looked for.
+func (o RequestOptions) kind() string {
+	if o.Kind == "" {
+		return "any"
+	}
+	return o.Kind
+}
+
+// layerSelected returns true if the object is looked for in the layer
+// with the given index in the manifest.
+func (o RequestOptions) layerSelected(idx int, layer v1.Descriptor) bool {
+	if o.Layer == "" {
+		return true
+	}
+	if index, err := strconv.Atoi(o.Layer); err == nil {
+		return idx == index
+	}
+	return string(layer.MediaType) == o.Layer
+}
+
+// layers describes the layers the object is looked for in, e.g. "image
+// layer 1".
+func (o RequestOptions) layers() string {
+	if o.Layer == "" {
+		return "image"
+	}
+	if _, err := strconv.Atoi(o.Layer); err == nil {
+		return "image layer " + o.Layer
+	}
+	return "image layers of media type " + o.Layer
+}
+
+// platform returns the platform whose manifest is selected from a
+// multi-platform bundle.
+func (o RequestOptions) platform() v1.Platform {
+	if o.Platform != nil {
+		return *o.Platform
+	}
+	return v1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}
 }
 
 // ResolvedResource wraps the content of a matched entry in a bundle.
@@ -72,10 +197,78 @@ func (br *ResolvedResource) Source() *v1beta1.ConfigSource {
 // GetEntry accepts a keychain and options for the request and returns
 // either a successfully resolved bundle entry or an error.
 func GetEntry(ctx context.Context, keychain authn.Keychain, opts RequestOptions) (*ResolvedResource, error) {
-	img, err := retrieveImage(ctx, keychain, opts.Bundle)
+	return getEntry(ctx, keychain, opts, nil)
+}
+
+// getEntry is GetEntry with bundles read from and added to the given
+// cache, if it isn't nil.
+func getEntry(ctx context.Context, keychain authn.Keychain, opts RequestOptions, cache *bundleCache) (_ *ResolvedResource, err error) {
+	ctx, span := framework.StartSpan(ctx, "bundle.Pull", trace.StringAttribute("bundle", opts.pulledBundle()), trace.StringAttribute("platform", opts.platform().String()))
+	defer func() { framework.EndSpan(span, err) }()
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	// The timeout covers every request made for the bundle: resolving
+	// and connecting to the registry, fetching the manifest and
+	// downloading the layers.
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// aborted returns the error for a pull that failed while in the
+	// given phase, reporting a pull that was aborted because it timed
+	// out or was cancelled, e.g. because its ResolutionRequest was
+	// deleted, as such rather than as whatever error the registry
+	// client happened to return.
+	aborted := func(err error, phase string) error {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return &common.ResolutionTimeoutError{
+				Resource: opts.Bundle,
+				Timeout:  timeout,
+				Original: fmt.Errorf("timed out after %s %s for bundle %s: %w", timeout, phase, opts.Bundle, err),
+			}
+		case errors.Is(ctx.Err(), context.Canceled):
+			return fmt.Errorf("pull of bundle %s cancelled while %s: %w", opts.Bundle, phase, ctx.Err())
+		}
+		if unavailable := registryUnavailableError(opts.Bundle, err); unavailable != nil {
+			return unavailable
+		}
+		return err
+	}
+
+	// The tags are listed from the repository the bundle is pulled
+	// from, which is that of its mirror when one matches it.
+	requested := opts.Bundle
+	selectedTag := ""
+	if opts.TagPattern != "" {
+		selectedTag, err = selectTag(ctx, keychain, opts.pulledBundle(), opts.TagPattern)
+		if err != nil {
+			return nil, aborted(err, "listing tags")
+		}
+		opts = opts.withTag(selectedTag)
+	}
+
+	imgRef, refDigest, img, release, err := retrieveImage(ctx, keychain, opts.pulledBundle(), opts.platform(), cache)
 	if err != nil {
-		return nil, err
+		if opts.MirroredBundle != "" {
+			err = fmt.Errorf("error pulling bundle %s from mirror %s: %w", opts.Bundle, opts.MirroredBundle, err)
+		}
+		return nil, aborted(err, "connecting to registry")
+	}
+	defer release()
+
+	// Pin the bundle to the digest it resolved to so that the exact image
+	// used is recorded even when it was referenced by a mutable tag.
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("could not compute digest of bundle %s: %w", opts.Bundle, err)
+	}
+	// The expected digest may be either that of the selected manifest or,
+	// for a multi-platform bundle, that of its image index.
+	if opts.ExpectedDigest != "" && digest.String() != opts.ExpectedDigest && refDigest.String() != opts.ExpectedDigest {
+		return nil, fmt.Errorf("digest mismatch for bundle %s: expected %s but got %s", opts.Bundle, opts.ExpectedDigest, digest)
 	}
+	pinnedRef := imgRef.Context().Digest(digest.String()).String()
 
 	manifest, err := img.Manifest()
 	if err != nil {
@@ -88,7 +281,7 @@ func GetEntry(ctx context.Context, keychain authn.Keychain, opts RequestOptions)
 
 	layers, err := img.Layers()
 	if err != nil {
-		return nil, fmt.Errorf("could not read image layers: %w", err)
+		return nil, aborted(fmt.Errorf("could not read image layers: %w", err), "downloading layers")
 	}
 
 	layerMap := map[string]v1.Layer{}
@@ -100,36 +293,280 @@ func GetEntry(ctx context.Context, keychain authn.Keychain, opts RequestOptions)
 		layerMap[digest.String()] = l
 	}
 
+	// Select the object by both kind and either name or annotation, from
+	// the layers selected by the layer param if it was given. When
+	// neither was given a single selected layer holding an object of
+	// the requested kind resolves to that object.
+	var selected []int
 	for idx, l := range manifest.Layers {
+		if opts.layerSelected(idx, l) {
+			selected = append(selected, idx)
+		}
+	}
+	if len(selected) == 0 {
+		found := make([]string, 0, len(manifest.Layers))
+		for idx, l := range manifest.Layers {
+			found = append(found, fmt.Sprintf("%d: %s/%s (%s)", idx, l.Annotations[BundleAnnotationKind], l.Annotations[BundleAnnotationName], l.MediaType))
+		}
+		return nil, &common.ResolutionNotFoundError{
+			Resource: opts.Bundle,
+			Original: fmt.Errorf("bundle %s has no %s, found layers: %s", opts.Bundle, strings.TrimPrefix(opts.layers(), "image "), strings.Join(found, ", ")),
+		}
+	}
+	var matches []int
+	available := make([]string, 0, len(selected))
+	var availableAnnotations []string
+	for _, idx := range selected {
+		l := manifest.Layers[idx]
 		lKind := l.Annotations[BundleAnnotationKind]
 		lName := l.Annotations[BundleAnnotationName]
+		available = append(available, lKind+"/"+lName)
+		lValue, hasAnnotation := l.Annotations[opts.AnnotationKey]
+		if opts.AnnotationKey != "" && hasAnnotation {
+			availableAnnotations = append(availableAnnotations, fmt.Sprintf("%s/%s (%s=%s)", lKind, lName, opts.AnnotationKey, lValue))
+		}
+		if opts.Kind != "" && opts.Kind != lKind {
+			continue
+		}
+		switch {
+		case opts.AnnotationKey != "":
+			if hasAnnotation && lValue == opts.AnnotationValue {
+				matches = append(matches, idx)
+			}
+		case opts.EntryName == lName || (opts.EntryName == "" && len(selected) == 1):
+			matches = append(matches, idx)
+		}
+	}
+
+	switch {
+	case len(matches) == 0 && opts.AnnotationKey != "":
+		if len(availableAnnotations) == 0 {
+			availableAnnotations = []string{"none"}
+		}
+		return nil, &common.ResolutionNotFoundError{
+			Resource: opts.Bundle,
+			Original: fmt.Errorf("could not find object in %s with kind: %s and %s, available annotations: %s", opts.layers(), opts.kind(), opts.selection(), strings.Join(availableAnnotations, ", ")),
+		}
+	case len(matches) == 0 && opts.EntryName == "" && opts.Layer != "":
+		return nil, &common.ResolutionNotFoundError{
+			Resource: opts.Bundle,
+			Original: fmt.Errorf("could not find a single object in %s with kind: %s, available objects: %s, set parameter %q to select one", opts.layers(), opts.kind(), strings.Join(available, ", "), ParamName),
+		}
+	case len(matches) == 0 && opts.EntryName == "":
+		return nil, fmt.Errorf("parameter %q is required unless the bundle contains a single object of kind %s, available objects: %s", ParamName, opts.kind(), strings.Join(available, ", "))
+	case len(matches) == 0:
+		return nil, &common.ResolutionNotFoundError{
+			Resource: opts.Bundle,
+			Original: fmt.Errorf("could not find object in %s with kind: %s and name: %s, available objects: %s", opts.layers(), opts.kind(), opts.EntryName, strings.Join(available, ", ")),
+		}
+	case len(matches) > 1:
+		return nil, fmt.Errorf("bundle %s contains %d objects with kind: %s and %s", opts.Bundle, len(matches), opts.kind(), opts.selection())
+	}
 
-		if opts.Kind == lKind && opts.EntryName == lName {
-			obj, err := readTarLayer(layerMap[l.Digest.String()])
+	idx := matches[0]
+	l := manifest.Layers[idx]
+	lKind := l.Annotations[BundleAnnotationKind]
+	lName := l.Annotations[BundleAnnotationName]
+	if err := checkLayerMediaType(opts, manifest, l); err != nil {
+		return nil, err
+	}
+	obj, err := readLayer(layerMap[l.Digest.String()])
+	if err == nil {
+		err = parseObject(obj)
+	}
+	if err != nil {
+		if ctx.Err() != nil || registryUnavailableError(opts.Bundle, err) != nil {
+			return nil, aborted(err, "downloading layers")
+		}
+		return nil, fmt.Errorf("object with kind: %s and name: %s in bundle %s is malformed: %w, %s",
+			lKind, lName, opts.Bundle, err, describeValidObjects(ctx, manifest, layers, idx))
+	}
+	// Every layer needed has been read, so the cache may be written to
+	// while e.g. the referrers are fetched.
+	release()
+	if opts.Kind == "" {
+		detected, err := framework.DetectKind(obj, detectableKinds)
+		if err != nil {
+			return nil, fmt.Errorf("could not detect the kind of object %s/%s in bundle %s: %w", lKind, lName, opts.Bundle, err)
+		}
+		if detected != lKind {
+			return nil, fmt.Errorf("object %s/%s in bundle %s is a %s", lKind, lName, opts.Bundle, detected)
+		}
+	}
+	annotations := map[string]string{
+		ResolverAnnotationKind:            lKind,
+		ResolverAnnotationName:            lName,
+		ResolverAnnotationAPIVersion:      l.Annotations[BundleAnnotationAPIVersion],
+		ResolverAnnotationResolvedBundle:  pinnedRef,
+		ResolverAnnotationRequestedBundle: requested,
+		ResolverAnnotationPulledBundle:    opts.pulledBundle(),
+		common.AnnotationKeyProvenance: common.Provenance{
+			ResolverType: LabelValueBundleResolverType,
+			URI:          pinnedRef,
+			Digest:       map[string]string{digest.Algorithm: digest.Hex},
+			Coordinates: map[string]string{
+				"kind":       lKind,
+				"name":       lName,
+				"apiVersion": l.Annotations[BundleAnnotationAPIVersion],
+			},
+		}.AnnotationValue(),
+	}
+	if opts.Kind == "" {
+		annotations[common.AnnotationKeyDetectedKind] = lKind
+	}
+	if selectedTag != "" {
+		annotations[ResolverAnnotationSelectedTag] = selectedTag
+	}
+	if opts.Referrers {
+		referrers, err := fetchReferrers(ctx, keychain, imgRef.Context(), digest)
+		switch {
+		case errors.Is(err, errReferrersUnsupported):
+			logging.FromContext(ctx).Warnf("not listing the referrers of bundle %s: %v", opts.Bundle, err)
+		case err != nil:
+			return nil, aborted(fmt.Errorf("could not fetch the referrers of bundle %s: %w", opts.Bundle, err), "fetching referrers")
+		default:
+			value, err := json.Marshal(referrers)
 			if err != nil {
-				// This could still be a raw layer so try to read it as that instead.
-				obj, err = readRawLayer(layers[idx])
+				return nil, err
 			}
-			return &ResolvedResource{
-				data: obj,
-				annotations: map[string]string{
-					ResolverAnnotationKind:       lKind,
-					ResolverAnnotationName:       lName,
-					ResolverAnnotationAPIVersion: l.Annotations[BundleAnnotationAPIVersion],
-				},
-			}, nil
+			annotations[ResolverAnnotationReferrers] = string(value)
 		}
 	}
-	return nil, fmt.Errorf("could not find object in image with kind: %s and name: %s", opts.Kind, opts.EntryName)
+	return &ResolvedResource{
+		data:        obj,
+		annotations: annotations,
+		source: &v1beta1.ConfigSource{
+			URI: pinnedRef,
+			Digest: map[string]string{
+				digest.Algorithm: digest.Hex,
+			},
+			EntryPoint: lName,
+		},
+	}, nil
 }
 
-// retrieveImage will fetch the image's contents and manifest.
-func retrieveImage(ctx context.Context, keychain authn.Keychain, ref string) (v1.Image, error) {
+// retrieveImage will fetch the image's contents and manifest, along
+// with the digest of the manifest the reference points to. When that is
+// an image index, the image for the given platform is selected from it.
+// If a cache is given the image is read from it when possible. The
+// reference is always resolved to its current digest with the given
+// keychain first, so that a cached bundle is only served to callers
+// that may pull it from the registry. The returned function releases
+// the image and must be called once its layers have been read; it may
+// be called more than once.
+func retrieveImage(ctx context.Context, keychain authn.Keychain, ref string, platform v1.Platform, cache *bundleCache) (name.Reference, v1.Hash, v1.Image, func(), error) {
+	noop := func() {}
 	imgRef, err := name.ParseReference(ref)
 	if err != nil {
-		return nil, fmt.Errorf("%s is an unparseable image reference: %w", ref, err)
+		return nil, v1.Hash{}, nil, noop, fmt.Errorf("%s is an unparseable image reference: %w", ref, err)
+	}
+	remoteOpts := []remote.Option{remote.WithAuthFromKeychain(keychain), remote.WithContext(ctx), remote.WithUserAgent(framework.UserAgent(ctx))}
+
+	if cache != nil {
+		// A failed request is left for the pull below to report.
+		if desc, err := remote.Head(imgRef, remoteOpts...); err == nil {
+			if img, release, ok := cache.get(desc.Digest); ok {
+				return imgRef, desc.Digest, img, release, nil
+			}
+		}
+	}
+
+	desc, err := remote.Get(imgRef, remoteOpts...)
+	if err != nil {
+		return nil, v1.Hash{}, nil, noop, err
+	}
+	var img v1.Image
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, v1.Hash{}, nil, noop, err
+		}
+		manifest, err := selectPlatform(ref, index, platform)
+		if err != nil {
+			return nil, v1.Hash{}, nil, noop, err
+		}
+		if cache != nil {
+			if img, release, ok := cache.get(manifest.Digest); ok {
+				return imgRef, desc.Digest, img, release, nil
+			}
+		}
+		img, err = index.Image(manifest.Digest)
+		if err != nil {
+			return nil, v1.Hash{}, nil, noop, err
+		}
+	} else {
+		img, err = desc.Image()
+		if err != nil {
+			return nil, v1.Hash{}, nil, noop, err
+		}
+	}
+	if cache != nil {
+		cached, release, err := cache.put(img)
+		if err == nil {
+			return imgRef, desc.Digest, cached, release, nil
+		}
+		// A pull that was aborted while being cached can't be resolved
+		// either.
+		if ctx.Err() != nil {
+			return nil, v1.Hash{}, nil, noop, err
+		}
+		// Caching is best effort, the bundle can still be resolved.
+		logging.FromContext(ctx).Warnf("failed to cache bundle %s: %v", ref, err)
+	}
+	return imgRef, desc.Digest, img, noop, nil
+}
+
+// registryUnavailableError returns the error for a request to the
+// registry of the given bundle that failed because the registry is
+// unavailable: a RateLimitedError if it rejected the request as one too
+// many, or a TransientError if it failed with a server error or
+// couldn't be reached. Such resolutions are retried and count against
+// the circuit of the registry. It returns nil for any other error.
+func registryUnavailableError(bundle string, err error) error {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		switch {
+		case terr.StatusCode == http.StatusTooManyRequests:
+			return &common.RateLimitedError{Resource: bundle, Original: err}
+		case terr.StatusCode == http.StatusRequestTimeout || terr.StatusCode >= http.StatusInternalServerError:
+			return &common.TransientError{Resource: bundle, Original: err}
+		}
+		return nil
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return &common.TransientError{Resource: bundle, Original: err}
+	}
+	return nil
+}
+
+// selectPlatform returns the descriptor of the manifest for the given
+// platform in a multi-platform bundle. The variant and OS version only
+// have to match when they are given, so that e.g. linux/arm64 selects a
+// linux/arm64/v8 manifest. The first matching manifest is selected.
+func selectPlatform(ref string, index v1.ImageIndex, platform v1.Platform) (*v1.Descriptor, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse image index: %w", err)
+	}
+	var available []string
+	for i, m := range indexManifest.Manifests {
+		if m.Platform == nil || !m.MediaType.IsImage() {
+			continue
+		}
+		p := m.Platform
+		if p.OS == platform.OS && p.Architecture == platform.Architecture &&
+			(platform.Variant == "" || p.Variant == platform.Variant) &&
+			(platform.OSVersion == "" || p.OSVersion == platform.OSVersion) {
+			return &indexManifest.Manifests[i], nil
+		}
+		available = append(available, p.String())
+	}
+	return nil, &common.ResolutionNotFoundError{
+		Resource: ref,
+		Original: fmt.Errorf("bundle %s has no manifest for platform %s, available platforms: %s", ref, platform, strings.Join(available, ", ")),
 	}
-	return remote.Image(imgRef, remote.WithAuthFromKeychain(keychain), remote.WithContext(ctx))
 }
 
 // checkImageCompliance will perform common checks to ensure the Tekton Bundle is compliant to our spec.
@@ -162,6 +599,74 @@ func checkImageCompliance(ref string, manifest *v1.Manifest) error {
 	return nil
 }
 
+// checkLayerMediaType checks that the layer holding the requested
+// object has one of the media types in the options, listing the media
+// types of every object in the bundle when it doesn't.
+func checkLayerMediaType(opts RequestOptions, manifest *v1.Manifest, layer v1.Descriptor) error {
+	if len(opts.LayerMediaTypes) == 0 {
+		return nil
+	}
+	for _, mediaType := range opts.LayerMediaTypes {
+		if string(layer.MediaType) == mediaType {
+			return nil
+		}
+	}
+	found := make([]string, 0, len(manifest.Layers))
+	for _, l := range manifest.Layers {
+		found = append(found, fmt.Sprintf("%s/%s (%s)", l.Annotations[BundleAnnotationKind], l.Annotations[BundleAnnotationName], l.MediaType))
+	}
+	return fmt.Errorf("object with kind: %s and name: %s in bundle %s has unexpected media type %s, expected one of: %s, found objects: %s",
+		layer.Annotations[BundleAnnotationKind], layer.Annotations[BundleAnnotationName], opts.Bundle, layer.MediaType,
+		strings.Join(opts.LayerMediaTypes, ", "), strings.Join(found, ", "))
+}
+
+// readLayer reads out the contents of an image layer holding a single
+// object, either as a tarball or as raw bytes.
+func readLayer(layer v1.Layer) ([]byte, error) {
+	obj, err := readTarLayer(layer)
+	if err != nil {
+		// This could still be a raw layer so try to read it as that instead.
+		return readRawLayer(layer)
+	}
+	return obj, nil
+}
+
+// parseObject returns an error if the contents of a layer aren't a YAML
+// or JSON object.
+func parseObject(data []byte) error {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("could not parse object: %w", err)
+	}
+	if len(obj) == 0 {
+		return errors.New("layer holds no object")
+	}
+	return nil
+}
+
+// describeValidObjects lists the objects in the bundle, other than the
+// one at the given index, that can be read and parsed. This is only
+// done once the requested object turned out to be malformed so that
+// the user can tell whether the rest of the bundle is usable. Objects
+// that can't be downloaded before ctx is done aren't listed.
+func describeValidObjects(ctx context.Context, manifest *v1.Manifest, layers []v1.Layer, skip int) string {
+	var valid []string
+	for idx, l := range manifest.Layers {
+		if idx == skip || idx >= len(layers) || ctx.Err() != nil {
+			continue
+		}
+		obj, err := readLayer(layers[idx])
+		if err != nil || parseObject(obj) != nil {
+			continue
+		}
+		valid = append(valid, l.Annotations[BundleAnnotationKind]+"/"+l.Annotations[BundleAnnotationName])
+	}
+	if len(valid) == 0 {
+		return "the bundle contains no other valid objects"
+	}
+	return "valid objects in the bundle: " + strings.Join(valid, ", ")
+}
+
 // Utility function to read out the contents of an image layer, assumed to be a tarball, as bytes.
 func readTarLayer(layer v1.Layer) ([]byte, error) {
 	rc, err := layer.Uncompressed()
@@ -180,7 +685,7 @@ func readTarLayer(layer v1.Layer) ([]byte, error) {
 	}
 
 	contents := make([]byte, header.Size)
-	if _, err := treader.Read(contents); err != nil && err != io.EOF {
+	if _, err := io.ReadFull(treader, contents); err != nil {
 		// We only allow 1 resource per layer so this tar bundle should have one and only one file.
 		return nil, fmt.Errorf("failed to read tar bundle: %w", err)
 	}
@@ -0,0 +1,235 @@
+/*
+ Copyright 2022 The Tekton Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultCacheMaxSize is the maximum size of the bundle cache when the
+// cache-max-size config isn't set.
+var defaultCacheMaxSize = resource.MustParse("1Gi")
+
+// bundleCache is an on-disk cache of pulled bundles keyed by digest.
+// Each bundle is stored as an OCI image layout in its own directory so
+// that its manifest, including the layer annotations, is kept intact.
+// The least recently used bundles are evicted once the total size of
+// the cache exceeds its maximum size.
+type bundleCache struct {
+	dir     string
+	maxSize int64
+
+	// mu serializes adding and evicting entries with reads. The layout
+	// of a cached bundle is read lazily, so a read holds the lock until
+	// the bundle is released rather than only while it is looked up.
+	mu sync.RWMutex
+}
+
+// cacheFromConfig returns the bundle cache configured for the request,
+// or nil if caching is disabled. The cache is reused across requests as
+// long as its config doesn't change.
+func (r *Resolver) cacheFromConfig(ctx context.Context) (*bundleCache, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	dir := conf[ConfigCacheDir]
+	if dir == "" {
+		return nil, nil
+	}
+	maxSize := defaultCacheMaxSize
+	if s, ok := conf[ConfigCacheMaxSize]; ok {
+		var err error
+		maxSize, err = resource.ParseQuantity(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s config: %w", ConfigCacheMaxSize, err)
+		}
+	}
+
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if r.cache == nil || r.cache.dir != dir || r.cache.maxSize != maxSize.Value() {
+		r.cache = &bundleCache{dir: dir, maxSize: maxSize.Value()}
+	}
+	return r.cache, nil
+}
+
+// entryPath returns the directory in which the bundle with the given
+// digest is stored.
+func (c *bundleCache) entryPath(digest v1.Hash) string {
+	return filepath.Join(c.dir, digest.Algorithm+"-"+digest.Hex)
+}
+
+// get returns the cached bundle with the given digest, if there is one,
+// marking it as recently used. The bundle must be released with the
+// returned function once its layers have been read, until which no
+// entry is added to or evicted from the cache. Releasing it more than
+// once is harmless.
+func (c *bundleCache) get(digest v1.Hash) (v1.Image, func(), bool) {
+	c.mu.RLock()
+	path := c.entryPath(digest)
+	p, err := layout.FromPath(path)
+	if err != nil {
+		c.mu.RUnlock()
+		return nil, nil, false
+	}
+	img, err := p.Image(digest)
+	if err != nil {
+		c.mu.RUnlock()
+		return nil, nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	var once sync.Once
+	return img, func() { once.Do(c.mu.RUnlock) }, true
+}
+
+// put adds a bundle to the cache, evicting the least recently used
+// bundles if the cache has grown beyond its maximum size, and returns
+// it as read from the cache so that its layers aren't downloaded again.
+// The layers are downloaded without holding the cache's lock, which is
+// only taken to move the written entry into place. The returned
+// function releases the bundle as it does for get.
+func (c *bundleCache) put(img v1.Image) (v1.Image, func(), error) {
+	noop := func() {}
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, noop, err
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, noop, err
+	}
+	// Write the layout to a temporary directory first so that readers
+	// never see a partially written entry.
+	tmp, err := os.MkdirTemp(c.dir, ".tmp-")
+	if err != nil {
+		return nil, noop, err
+	}
+	defer os.RemoveAll(tmp)
+
+	p, err := layout.Write(tmp, empty.Index)
+	if err != nil {
+		return nil, noop, err
+	}
+	if err := p.AppendImage(img); err != nil {
+		return nil, noop, err
+	}
+	if err := c.add(tmp, digest); err != nil {
+		return nil, noop, err
+	}
+	if cached, release, ok := c.get(digest); ok {
+		return cached, release, nil
+	}
+	// The bundle was evicted straight away, e.g. because it is larger
+	// than the cache, so it is read from the registry instead.
+	return img, noop, nil
+}
+
+// add moves the layout written to the given directory into place as
+// the entry of the bundle with the given digest and evicts the least
+// recently used entries if need be.
+func (c *bundleCache) add(dir string, digest v1.Hash) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.entryPath(digest)
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if err := os.Rename(dir, path); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used entries until the cache is no
+// larger than its maximum size.
+func (c *bundleCache) evict() error {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	var entries []entry
+	var total int64
+	for _, de := range dirEntries {
+		if !de.IsDir() || strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.dir, de.Name())
+		size, err := dirSize(path)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{path: path, size: size, modTime: info.ModTime()})
+		total += size
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.RemoveAll(e.path); err != nil {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// dirSize returns the total size of the files under the given directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -20,3 +20,59 @@ const ConfigServiceAccount = "default-service-account"
 // ConfigKind is the configuration field name for controlling
 // what the layer name in the bundle image is.
 const ConfigKind = "default-kind"
+
+// ConfigDetectKind is the configuration field name for controlling
+// whether the kind of the object is detected from the object itself
+// when the kind param is omitted, instead of defaulting to
+// default-kind. Defaults to "false".
+const ConfigDetectKind = "detect-kind"
+
+// ConfigTimeout is the configuration field name for controlling the
+// maximum time pulling a bundle and extracting an object from it may
+// take.
+const ConfigTimeout = "fetch-timeout"
+
+// ConfigResolveSingleObject is the configuration field name for
+// controlling whether the name param may be omitted for bundles holding
+// a single object, in which case that object is resolved.
+const ConfigResolveSingleObject = "resolve-single-object"
+
+// ConfigCacheDir is the configuration field name for controlling the
+// directory in which pulled bundles are cached. Caching is disabled when
+// it isn't set.
+const ConfigCacheDir = "cache-dir"
+
+// ConfigCacheMaxSize is the configuration field name for controlling
+// the maximum total size of the bundle cache, e.g. "1Gi".
+const ConfigCacheMaxSize = "cache-max-size"
+
+// ConfigLayerMediaTypes is the configuration field name for controlling
+// which media types, as a comma-separated list, the layer holding the
+// resolved object may have. Layers of any media type are accepted when
+// it isn't set.
+const ConfigLayerMediaTypes = "layer-media-types"
+
+// ConfigHealthCheckRegistries is the configuration field name for
+// controlling which registries, as a comma-separated list, are pinged
+// by the resolver's health checks. Nothing is pinged when it isn't set.
+const ConfigHealthCheckRegistries = "health-check-registries"
+
+// ConfigRegistryMirrors is the configuration field name for a yaml map of
+// source prefixes to mirror prefixes, e.g. "docker.io:
+// mirror.internal/docker.io". A bundle starting with a source prefix is
+// pulled from the reference with that prefix replaced by its mirror,
+// the longest matching prefix winning. Bundles matching no source
+// prefix are pulled as given.
+const ConfigRegistryMirrors = "registry-mirrors"
+
+// ConfigAllowedRegistries is the configuration field name for
+// controlling which registries, as a comma-separated list of host
+// patterns such as "gcr.io" or "*.internal", bundles may be pulled from.
+// Bundles from any registry are allowed when it isn't set.
+const ConfigAllowedRegistries = "allowed-registries"
+
+// ConfigDeniedRegistries is the configuration field name for controlling
+// which registries, as a comma-separated list of host patterns, bundles
+// must not be pulled from, even if they are allowed by
+// allowed-registries.
+const ConfigDeniedRegistries = "denied-registries"
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// CheckHealth pings the registries listed in the health-check-registries
+// config. A registry is reachable if its /v2/ endpoint responds with a
+// 200, or with a 401 asking for credentials.
+func (r *Resolver) CheckHealth(ctx context.Context) error {
+	if r.isDisabled(ctx) {
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	var unreachable []string
+	for _, registry := range strings.Split(framework.GetResolverConfigFromContext(ctx)[ConfigHealthCheckRegistries], ",") {
+		if registry = strings.TrimSpace(registry); registry == "" {
+			continue
+		}
+		if err := pingRegistry(ctx, registry); err != nil {
+			unreachable = append(unreachable, err.Error())
+		}
+	}
+	if len(unreachable) > 0 {
+		return errors.New(strings.Join(unreachable, "; "))
+	}
+	return nil
+}
+
+// pingRegistry sends a request to the /v2/ endpoint of the given
+// registry, over http for registries such as localhost that are assumed
+// to be insecure.
+func pingRegistry(ctx context.Context, registry string) error {
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return fmt.Errorf("invalid registry %q in %s config: %w", registry, ConfigHealthCheckRegistries, err)
+	}
+	u := fmt.Sprintf("%s://%s/v2/", reg.Scheme(), reg.RegistryStr())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("constructing request to registry %s: %w", registry, err)
+	}
+	framework.SetUserAgent(ctx, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("registry %s is unreachable: %w", registry, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("registry %s responded with status %d", registry, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/yaml"
+)
+
+// parseRegistryMirrors parses the registry-mirrors config, a yaml map of
+// source prefixes to the mirror prefixes replacing them.
+func parseRegistryMirrors(config string) (map[string]string, error) {
+	mirrors := map[string]string{}
+	if strings.TrimSpace(config) == "" {
+		return mirrors, nil
+	}
+	if err := yaml.Unmarshal([]byte(config), &mirrors); err != nil {
+		return nil, fmt.Errorf("invalid %s config: must be a map of source prefixes to mirror prefixes: %w", ConfigRegistryMirrors, err)
+	}
+	for source, mirror := range mirrors {
+		if strings.TrimSuffix(source, "/") == "" || strings.TrimSuffix(mirror, "/") == "" {
+			return nil, fmt.Errorf("invalid %s config: source and mirror prefixes must not be empty, got %q: %q", ConfigRegistryMirrors, source, mirror)
+		}
+	}
+	return mirrors, nil
+}
+
+// mirrorBundle returns the reference the bundle is pulled from, which is
+// the bundle with the longest source prefix of the mirrors that it
+// starts with replaced by that prefix's mirror. A prefix only matches
+// whole path components, so "docker.io" matches "docker.io/foo:tag" but
+// not "docker.io.example.com/foo:tag". The empty string is returned when
+// no mirror matches.
+func mirrorBundle(bundle string, mirrors map[string]string) (string, error) {
+	var source, mirror string
+	for prefix, m := range mirrors {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if strings.HasPrefix(bundle, trimmed+"/") && len(trimmed) > len(source) {
+			source, mirror = trimmed, m
+		}
+	}
+	if source == "" {
+		return "", nil
+	}
+	mirrored := strings.TrimSuffix(mirror, "/") + strings.TrimPrefix(bundle, source)
+	if _, err := name.ParseReference(mirrored); err != nil {
+		return "", fmt.Errorf("bundle reference %s is rewritten by the %s config to the invalid reference %s: %w", bundle, ConfigRegistryMirrors, mirrored, err)
+	}
+	return mirrored, nil
+}
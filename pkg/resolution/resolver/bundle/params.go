@@ -16,9 +16,15 @@ package bundle
 import (
 	"context"
 	"fmt"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
 )
 
@@ -26,16 +32,83 @@ import (
 // account name to use for bundle requests.
 const ParamServiceAccount = "serviceAccount"
 
+// ParamSecret is the parameter defining the name of a docker config
+// secret, in the namespace of the request, holding the registry
+// credentials to use for bundle requests instead of a service account.
+const ParamSecret = "secret"
+
 // ParamBundle is the parameter defining what the bundle image url is.
 const ParamBundle = "bundle"
 
+// ParamTag is the parameter defining the tag of the bundle image, for a
+// bundle given without one. It is either a tag or a selection among the
+// tags of the bundle's repository, listed from the registry, as a tag
+// pattern with * and ? wildcards, e.g. "v1.*", or a range of versions,
+// e.g. "^1.2" or ">=1.0 <2.0", in which case the tag with the highest
+// matching version is pulled.
+const ParamTag = "tag"
+
+// ParamRequireDigest is the parameter defining whether the bundle must
+// be referenced by digest rather than by a mutable tag. Defaults to
+// "false".
+const ParamRequireDigest = "requireDigest"
+
+// ParamDigest is the parameter defining the digest, of the form
+// "sha256:<hex>", that the manifest of the pulled bundle must have.
+const ParamDigest = common.ParamDigest
+
 // ParamName is the parameter defining what the layer name in the bundle
-// image is.
-const ParamName = "name"
+// image is. It may be omitted for bundles holding a single object when
+// the resolve-single-object config is "true".
+const ParamName = common.ParamName
+
+// ParamAnnotationKey and ParamAnnotationValue are the parameters
+// defining the key and value of a layer annotation in the bundle image
+// that selects the object instead of its name, for bundles indexing
+// their objects with their own annotations. They must be set together
+// and cannot be combined with ParamName.
+const (
+	ParamAnnotationKey   = "annotationKey"
+	ParamAnnotationValue = "annotationValue"
+)
 
 // ParamKind is the parameter defining what the layer kind in the bundle
 // image is.
-const ParamKind = "kind"
+const ParamKind = common.ParamKind
+
+// ParamTimeout is the parameter defining the maximum time pulling the
+// bundle and extracting the object from it may take, overriding the
+// fetch-timeout config.
+const ParamTimeout = common.ParamTimeout
+
+// ParamMediaType is the parameter defining the media type that the
+// layer holding the object must have, e.g. the custom media type of a
+// bundle stored as an OCI artifact. It overrides the layer-media-types
+// config.
+const ParamMediaType = "mediaType"
+
+// ParamLayer is the parameter defining the layers of the bundle image
+// the object is looked for in, either by the index of a layer in the
+// manifest, e.g. "1", or by the media type of the layers. The name may
+// be omitted when a single layer is selected. Every layer is looked in
+// by default.
+const ParamLayer = "layer"
+
+// ParamPlatform is the parameter defining the platform, of the form
+// "os/arch[/variant]", e.g. "linux/arm64", whose manifest is selected
+// when the bundle is a multi-platform image index. Defaults to the
+// platform the resolver runs on.
+const ParamPlatform = "platform"
+
+// ParamReferrers is the parameter defining whether the artifacts
+// attached to the bundle through the OCI referrers API, such as
+// attestations and SBOMs, are listed in the resolved metadata. Defaults
+// to "false".
+const ParamReferrers = "referrers"
+
+// platformRegex matches the platform param, optionally followed by an
+// OS version, e.g. "windows/amd64:10.0.17763.1234".
+var platformRegex = regexp.MustCompile(`^[a-z0-9_.-]+/[a-z0-9_.-]+(/[a-z0-9_.-]+)?(:[A-Za-z0-9_.-]+)?$`)
 
 // OptionsFromParams parses the params from a resolution request and
 // converts them into options to pass as part of a bundle request.
@@ -50,7 +123,13 @@ func OptionsFromParams(ctx context.Context, params []pipelinev1beta1.Param) (Req
 
 	saVal, ok := paramsMap[ParamServiceAccount]
 	sa := ""
-	if !ok || saVal.StringVal == "" {
+	secretVal, hasSecret := paramsMap[ParamSecret]
+	if hasSecret && secretVal.StringVal != "" {
+		if ok && saVal.StringVal != "" {
+			return opts, fmt.Errorf("only one of parameters %q and %q may be set", ParamServiceAccount, ParamSecret)
+		}
+		opts.ImagePullSecret = secretVal.StringVal
+	} else if !ok || saVal.StringVal == "" {
 		if saString, ok := conf[ConfigServiceAccount]; ok {
 			sa = saString
 		} else {
@@ -64,31 +143,161 @@ func OptionsFromParams(ctx context.Context, params []pipelinev1beta1.Param) (Req
 	if !ok || bundleVal.StringVal == "" {
 		return opts, fmt.Errorf("parameter %q required", ParamBundle)
 	}
-	if _, err := name.ParseReference(bundleVal.StringVal); err != nil {
+	bundle := bundleVal.StringVal
+	if tagVal, ok := paramsMap[ParamTag]; ok && tagVal.StringVal != "" {
+		if _, err := name.NewRepository(bundle); err != nil {
+			return opts, fmt.Errorf("bundle reference %s must not have a tag or digest when parameter %q is set", bundle, ParamTag)
+		}
+		if err := validateTag(tagVal.StringVal); err != nil {
+			return opts, fmt.Errorf("invalid %s param %q: %w", ParamTag, tagVal.StringVal, err)
+		}
+		// A tag pattern is only matched against the registry's tags
+		// when the bundle is resolved.
+		if isTagPattern(tagVal.StringVal) {
+			opts.TagPattern = tagVal.StringVal
+		} else {
+			bundle += ":" + tagVal.StringVal
+		}
+	}
+	bundleRef, err := name.ParseReference(bundle)
+	if err != nil {
 		return opts, fmt.Errorf("invalid bundle reference: %w", err)
 	}
+	if err := checkRegistryAllowed(bundle, bundleRef, conf); err != nil {
+		return opts, err
+	}
+
+	if requireDigestVal, ok := paramsMap[ParamRequireDigest]; ok && requireDigestVal.StringVal != "" {
+		opts.RequireDigest, err = strconv.ParseBool(requireDigestVal.StringVal)
+		if err != nil {
+			return opts, fmt.Errorf("parameter %q must be true or false: %w", ParamRequireDigest, err)
+		}
+	}
+	if _, isDigest := bundleRef.(name.Digest); opts.RequireDigest && !isDigest {
+		return opts, fmt.Errorf("bundle reference %s must be pinned by digest when parameter %q is true", bundle, ParamRequireDigest)
+	}
+
+	if referrersVal, ok := paramsMap[ParamReferrers]; ok && referrersVal.StringVal != "" {
+		opts.Referrers, err = strconv.ParseBool(referrersVal.StringVal)
+		if err != nil {
+			return opts, fmt.Errorf("parameter %q must be true or false: %w", ParamReferrers, err)
+		}
+	}
+
+	if digestVal, ok := paramsMap[ParamDigest]; ok && digestVal.StringVal != "" {
+		if err := framework.ValidateDigest(ParamDigest, digestVal.StringVal); err != nil {
+			return opts, err
+		}
+		if d, isDigest := bundleRef.(name.Digest); isDigest && d.DigestStr() != digestVal.StringVal {
+			return opts, fmt.Errorf("bundle reference %s is pinned to a different digest than %s param %s", bundle, ParamDigest, digestVal.StringVal)
+		}
+		opts.ExpectedDigest = digestVal.StringVal
+	}
+
+	if layerVal, ok := paramsMap[ParamLayer]; ok && layerVal.StringVal != "" {
+		if err := validateLayer(layerVal.StringVal); err != nil {
+			return opts, fmt.Errorf("invalid %s param %q: %w", ParamLayer, layerVal.StringVal, err)
+		}
+		opts.Layer = layerVal.StringVal
+	}
 
-	nameVal, ok := paramsMap[ParamName]
-	if !ok || nameVal.StringVal == "" {
+	nameVal := paramsMap[ParamName]
+	annotationKeyVal := paramsMap[ParamAnnotationKey]
+	annotationValueVal := paramsMap[ParamAnnotationValue]
+	if (annotationKeyVal.StringVal == "") != (annotationValueVal.StringVal == "") {
+		return opts, fmt.Errorf("parameters %q and %q must be set together", ParamAnnotationKey, ParamAnnotationValue)
+	}
+	switch {
+	case annotationKeyVal.StringVal != "" && nameVal.StringVal != "":
+		return opts, fmt.Errorf("only one of parameter %q and parameters %q and %q may be set", ParamName, ParamAnnotationKey, ParamAnnotationValue)
+	case annotationKeyVal.StringVal == "" && nameVal.StringVal == "" && opts.Layer == "" && conf[ConfigResolveSingleObject] != "true":
 		return opts, fmt.Errorf("parameter %q required", ParamName)
 	}
 
-	kindVal, ok := paramsMap[ParamKind]
-	kind := ""
-	if !ok || kindVal.StringVal == "" {
-		if kindString, ok := conf[ConfigKind]; ok {
-			kind = kindString
-		} else {
-			return opts, fmt.Errorf("default resource Kind  was not set during installation of the bundle resolver")
+	detectKind := false
+	if detectKindString, ok := conf[ConfigDetectKind]; ok {
+		detectKind, err = strconv.ParseBool(detectKindString)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigDetectKind, err)
 		}
-	} else {
+	}
+
+	// The kind is left empty when it's detected from the object once
+	// it's read.
+	kind := ""
+	defaultKind, hasDefaultKind := conf[ConfigKind]
+	switch kindVal, ok := paramsMap[ParamKind]; {
+	case ok && kindVal.StringVal != "":
 		kind = kindVal.StringVal
+	case detectKind:
+	case hasDefaultKind:
+		kind = defaultKind
+	default:
+		return opts, fmt.Errorf("default resource Kind  was not set during installation of the bundle resolver")
+	}
+
+	if timeoutVal, ok := paramsMap[ParamTimeout]; ok && timeoutVal.StringVal != "" {
+		opts.Timeout, err = framework.ParseTimeout(timeoutVal.StringVal)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s param: %w", ParamTimeout, err)
+		}
+	} else if timeoutString, ok := conf[ConfigTimeout]; ok {
+		opts.Timeout, err = framework.ParseTimeout(timeoutString)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigTimeout, err)
+		}
+	}
+
+	if mediaTypeVal, ok := paramsMap[ParamMediaType]; ok && mediaTypeVal.StringVal != "" {
+		opts.LayerMediaTypes = []string{mediaTypeVal.StringVal}
+	} else if mediaTypes, ok := conf[ConfigLayerMediaTypes]; ok {
+		for _, mediaType := range strings.Split(mediaTypes, ",") {
+			if mediaType = strings.TrimSpace(mediaType); mediaType != "" {
+				opts.LayerMediaTypes = append(opts.LayerMediaTypes, mediaType)
+			}
+		}
+	}
+
+	if platformVal, ok := paramsMap[ParamPlatform]; ok && platformVal.StringVal != "" {
+		if !platformRegex.MatchString(platformVal.StringVal) {
+			return opts, fmt.Errorf("invalid %s param %q: must be of the form os/arch[/variant]", ParamPlatform, platformVal.StringVal)
+		}
+		opts.Platform, err = v1.ParsePlatform(platformVal.StringVal)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s param: %w", ParamPlatform, err)
+		}
+	}
+
+	mirrors, err := parseRegistryMirrors(conf[ConfigRegistryMirrors])
+	if err != nil {
+		return opts, err
+	}
+	opts.MirroredBundle, err = mirrorBundle(bundle, mirrors)
+	if err != nil {
+		return opts, err
 	}
 
 	opts.ServiceAccount = sa
-	opts.Bundle = bundleVal.StringVal
+	opts.Bundle = bundle
 	opts.EntryName = nameVal.StringVal
+	opts.AnnotationKey = annotationKeyVal.StringVal
+	opts.AnnotationValue = annotationValueVal.StringVal
 	opts.Kind = kind
 
 	return opts, nil
 }
+
+// validateLayer checks that the layer param is either a layer index or
+// a media type.
+func validateLayer(layer string) error {
+	if index, err := strconv.Atoi(layer); err == nil {
+		if index < 0 {
+			return fmt.Errorf("layer index must not be negative")
+		}
+		return nil
+	}
+	if _, _, err := mime.ParseMediaType(layer); err != nil || !strings.Contains(layer, "/") {
+		return fmt.Errorf("must be a layer index or a media type")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// maxReferrersSize is the maximum size of the index listing the
+// referrers of a bundle, the same as the maximum size of a manifest
+// registries are expected to accept.
+const maxReferrersSize = 4 << 20
+
+// errReferrersUnsupported is returned by fetchReferrers when the
+// registry supports neither the referrers API nor the referrers tag
+// schema.
+var errReferrersUnsupported = errors.New("the registry doesn't support the OCI referrers API")
+
+// Referrer describes an artifact attached to a bundle, such as an
+// attestation or an SBOM, as listed by the OCI referrers API.
+type Referrer struct {
+	Digest       string            `json:"digest"`
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// referrersIndex is the image index listing the referrers of a
+// manifest.
+type referrersIndex struct {
+	Manifests []Referrer `json:"manifests"`
+}
+
+// fetchReferrers returns the artifacts referring to the manifest with
+// the given digest in the repository. Registries that don't support the
+// referrers API are queried for the index tagged with the referrers tag
+// schema, e.g. sha256-<hex>, instead. errReferrersUnsupported is
+// returned when neither is available.
+func fetchReferrers(ctx context.Context, keychain authn.Keychain, repo name.Repository, digest v1.Hash) (_ []Referrer, err error) {
+	ctx, span := framework.StartSpan(ctx, "bundle.Referrers")
+	defer func() { framework.EndSpan(span, err) }()
+
+	auth, err := keychain.Resolve(repo.Registry)
+	if err != nil {
+		return nil, err
+	}
+	rt := transport.NewUserAgent(remote.DefaultTransport, framework.UserAgent(ctx))
+	rt, err = transport.NewWithContext(ctx, repo.Registry, auth, rt, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+
+	u := url.URL{
+		Scheme: repo.Registry.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/referrers/%s", repo.RepositoryStr(), digest),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(types.OCIImageIndex))
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if body, err = io.ReadAll(framework.LimitReader(resp.Body, maxReferrersSize)); err != nil {
+			return nil, err
+		}
+	case http.StatusNotFound:
+		body, err = referrersFromTag(ctx, keychain, repo, digest)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, transport.CheckError(resp, http.StatusOK)
+	}
+
+	var index referrersIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("could not parse referrers index: %w", err)
+	}
+	if index.Manifests == nil {
+		return []Referrer{}, nil
+	}
+	return index.Manifests, nil
+}
+
+// referrersFromTag returns the index tagged with the referrers tag
+// schema for the manifest with the given digest.
+func referrersFromTag(ctx context.Context, keychain authn.Keychain, repo name.Repository, digest v1.Hash) ([]byte, error) {
+	desc, err := remote.Get(repo.Tag(digest.Algorithm+"-"+digest.Hex),
+		remote.WithAuthFromKeychain(keychain), remote.WithContext(ctx), remote.WithUserAgent(framework.UserAgent(ctx)))
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+		return nil, errReferrersUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return desc.Manifest, nil
+}
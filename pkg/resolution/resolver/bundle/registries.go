@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// parseRegistryPatterns parses a comma-separated list of registry host
+// patterns from the named config, lower-casing them since hosts are
+// case-insensitive.
+func parseRegistryPatterns(key, config string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(config, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s config: invalid registry pattern %q: %w", key, pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// matchesRegistry returns whether the registry matches one of the
+// patterns. A "*" in a pattern matches any part of a host, so
+// "*.internal" matches "registry.internal" and "a.b.internal" but not
+// "registry.internal:5000". Docker Hub matches "docker.io" as well as
+// the "index.docker.io" host references to it are normalized to.
+func matchesRegistry(registry string, patterns []string) bool {
+	hosts := []string{strings.ToLower(registry)}
+	if registry == name.DefaultRegistry {
+		hosts = append(hosts, "docker.io")
+	}
+	for _, pattern := range patterns {
+		for _, host := range hosts {
+			// The patterns were validated when they were parsed.
+			if ok, _ := path.Match(pattern, host); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkRegistryAllowed returns an error if the registry of the bundle
+// reference is denied by the denied-registries config, or isn't allowed
+// by the allowed-registries config when that is set.
+func checkRegistryAllowed(bundle string, ref name.Reference, conf map[string]string) error {
+	allowed, err := parseRegistryPatterns(ConfigAllowedRegistries, conf[ConfigAllowedRegistries])
+	if err != nil {
+		return err
+	}
+	denied, err := parseRegistryPatterns(ConfigDeniedRegistries, conf[ConfigDeniedRegistries])
+	if err != nil {
+		return err
+	}
+	registry := ref.Context().RegistryStr()
+	if matchesRegistry(registry, denied) {
+		return fmt.Errorf("bundle reference %s is from registry %s, which is denied by the %s config", bundle, registry, ConfigDeniedRegistries)
+	}
+	if len(allowed) > 0 && !matchesRegistry(registry, allowed) {
+		return fmt.Errorf("bundle reference %s is from registry %s, which isn't in the %s config", bundle, registry, ConfigAllowedRegistries)
+	}
+	return nil
+}
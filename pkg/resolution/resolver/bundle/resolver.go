@@ -19,13 +19,17 @@ package bundle
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
+	"sync"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
+	"github.com/google/go-containerregistry/pkg/name"
 	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"knative.dev/pkg/client/injection/kube/client"
 )
@@ -36,13 +40,12 @@ const disabledError = "cannot handle resolution request, enable-bundles-resolver
 // resolution.tekton.dev/type label on resource requests
 const LabelValueBundleResolverType string = "bundles"
 
-// TODO(sbwsg): This should be exposed as a configurable option for
-// admins (e.g. via ConfigMap)
-const timeoutDuration = time.Minute
-
 // Resolver implements a framework.Resolver that can fetch files from OCI bundles.
 type Resolver struct {
 	kubeClientSet kubernetes.Interface
+
+	cacheMu sync.Mutex
+	cache   *bundleCache
 }
 
 // Initialize sets up any dependencies needed by the Resolver. None atm.
@@ -61,6 +64,14 @@ func (r *Resolver) GetConfigName(context.Context) string {
 	return "bundleresolver-config"
 }
 
+var _ framework.ClusterScopedConfig = &Resolver{}
+
+// ClusterScopedConfigKeys returns the config of the bundle cache, which
+// is shared by the resolutions of every namespace.
+func (r *Resolver) ClusterScopedConfigKeys(context.Context) []string {
+	return []string{ConfigCacheDir, ConfigCacheMaxSize}
+}
+
 // GetSelector returns a map of labels to match requests to this Resolver.
 func (r *Resolver) GetSelector(context.Context) map[string]string {
 	return map[string]string{
@@ -68,34 +79,186 @@ func (r *Resolver) GetSelector(context.Context) map[string]string {
 	}
 }
 
+var _ framework.ParamTracer = &Resolver{}
+
+// TracedParams returns the params recorded on the spans of resolutions,
+// which identify the requested resource.
+func (r *Resolver) TracedParams(context.Context) []string {
+	return []string{ParamBundle, ParamTag, ParamKind, ParamName, ParamAnnotationKey, ParamAnnotationValue, ParamPlatform}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver, with the
+// service account and kind defaulting to those set in the resolver's
+// config.
+func (r *Resolver) GetParamSchema(ctx context.Context) []framework.ParamSchema {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	return []framework.ParamSchema{{
+		Name:        ParamBundle,
+		Description: "The reference of the bundle image.",
+		Required:    true,
+	}, {
+		Name:        ParamTag,
+		Description: "The tag of a bundle given without one, or a tag pattern or range of versions selecting the highest matching tag.",
+	}, {
+		Name:        ParamName,
+		Description: "The name of the object in the bundle. Required unless the object is selected by annotation or layer, or the bundle holds a single object and the resolve-single-object config is enabled.",
+	}, {
+		Name:        ParamKind,
+		Description: "The kind of the object in the bundle.",
+		Default:     conf[ConfigKind],
+	}, {
+		Name:        ParamServiceAccount,
+		Description: "The service account whose image pull secrets are used to pull the bundle. Can't be combined with the secret param.",
+		Default:     conf[ConfigServiceAccount],
+	}, {
+		Name:        ParamSecret,
+		Description: "The name of a docker config secret, in the namespace of the request, used to pull the bundle.",
+	}, {
+		Name:        ParamRequireDigest,
+		Description: "Whether the bundle must be referenced by digest.",
+		Enum:        []string{"true", "false"},
+		Default:     "false",
+	}, {
+		Name:        ParamDigest,
+		Description: "The digest, in the form sha256:<hex>, the manifest of the pulled bundle must have.",
+	}, {
+		Name:        ParamAnnotationKey,
+		Description: "The key of the layer annotation selecting the object instead of its name. Must be set along with annotationValue.",
+	}, {
+		Name:        ParamAnnotationValue,
+		Description: "The value of the layer annotation selecting the object instead of its name. Must be set along with annotationKey.",
+	}, {
+		Name:        ParamTimeout,
+		Description: "The maximum time pulling the bundle may take, overriding the fetch-timeout config.",
+	}, {
+		Name:        ParamMediaType,
+		Description: "The media type the layer holding the object must have, overriding the layer-media-types config.",
+	}, {
+		Name:        ParamLayer,
+		Description: "The layers the object is looked for in, either a layer index or a media type.",
+	}, {
+		Name:        ParamPlatform,
+		Description: "The platform, in the form os/arch[/variant], whose manifest is selected from an image index.",
+	}, {
+		Name:        ParamReferrers,
+		Description: "Whether the artifacts attached to the bundle are listed in the resolved metadata.",
+		Enum:        []string{"true", "false"},
+		Default:     "false",
+	}}
+}
+
+var _ framework.BackendIdentifier = &Resolver{}
+
+// Backend returns the registry the bundle is pulled from, which is that
+// of its mirror when one matches it.
+func (r *Resolver) Backend(ctx context.Context, params []pipelinev1beta1.Param) string {
+	opts, err := OptionsFromParams(ctx, params)
+	if err != nil {
+		return ""
+	}
+	ref, err := name.ParseReference(opts.pulledBundle())
+	if err != nil {
+		return ""
+	}
+	return ref.Context().RegistryStr()
+}
+
 // ValidateParams ensures parameters from a request are as expected.
 func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
 	if r.isDisabled(ctx) {
-		return errors.New(disabledError)
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
 	}
-	if _, err := OptionsFromParams(ctx, params); err != nil {
+	if err := framework.ValidateNoVariableReferences(params); err != nil {
 		return err
 	}
-	return nil
+	_, err := OptionsFromParams(ctx, params)
+	return err
 }
 
 // Resolve uses the given params to resolve the requested file or resource.
 func (r *Resolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
 	if r.isDisabled(ctx) {
-		return nil, errors.New(disabledError)
+		return nil, common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
 	}
 	opts, err := OptionsFromParams(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	kc, err := r.keychain(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := r.cacheFromConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getEntry(ctx, kc, opts, cache)
+}
+
+// keychain returns the registry credentials for the request, from the
+// configured credential provider if there is one, otherwise read either
+// from the pull secret or the service account in the options.
+func (r *Resolver) keychain(ctx context.Context, opts RequestOptions) (authn.Keychain, error) {
+	namespace := common.RequestNamespace(ctx)
+	if provider := framework.GetCredentialProviderFromContext(ctx); provider != nil {
+		ref, err := name.ParseReference(opts.pulledBundle())
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle reference %s: %w", opts.pulledBundle(), err)
+		}
+		registry := ref.Context().RegistryStr()
+		kc, err := provider.RegistryKeychain(ctx, registry, framework.CredentialRequest{
+			Namespace:      namespace,
+			ServiceAccount: opts.ServiceAccount,
+			Secret:         opts.ImagePullSecret,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting registry credentials for %s: %w", registry, err)
+		}
+		return kc, nil
+	}
+	if opts.ImagePullSecret == "" {
+		kc, err := k8schain.New(ctx, r.kubeClientSet, k8schain.Options{
+			Namespace:          namespace,
+			ServiceAccountName: opts.ServiceAccount,
+		})
+		if err != nil {
+			// The service account or one of its image pull secrets
+			// couldn't be read.
+			return nil, framework.WrapCredentialError(err, namespace, "registry credentials")
+		}
+		return kc, nil
+	}
+
+	secret, err := r.getPullSecret(ctx, opts.ImagePullSecret)
+	if err != nil {
+		return nil, err
+	}
+	kc, err := k8schain.NewFromPullSecrets(ctx, []corev1.Secret{*secret})
+	if err != nil {
+		// The error is deliberately dropped since it may contain part of
+		// the secret's data.
+		return nil, fmt.Errorf("cannot parse registry credentials in secret %s in namespace %s", opts.ImagePullSecret, namespace)
+	}
+	return kc, nil
+}
+
+// getPullSecret returns the named docker config secret from the
+// namespace of the request.
+func (r *Resolver) getPullSecret(ctx context.Context, name string) (*corev1.Secret, error) {
 	namespace := common.RequestNamespace(ctx)
-	kc, err := k8schain.New(ctx, r.kubeClientSet, k8schain.Options{
-		Namespace:          namespace,
-		ServiceAccountName: opts.ServiceAccount,
-	})
-	ctx, cancelFn := context.WithTimeout(ctx, timeoutDuration)
-	defer cancelFn()
-	return GetEntry(ctx, kc, opts)
+	if r.kubeClientSet == nil {
+		return nil, fmt.Errorf("cannot read secret %s: no kubernetes client available", name)
+	}
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClientSet, namespace, name, "registry credentials")
+	if err != nil {
+		return nil, err
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+		return nil, fmt.Errorf("secret %s in namespace %s must be of type %s or %s", name, namespace, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg)
+	}
+	return secret, nil
 }
 
 func (r *Resolver) isDisabled(ctx context.Context) bool {
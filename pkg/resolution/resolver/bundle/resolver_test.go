@@ -18,13 +18,47 @@ package bundle
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	goruntime "runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
 	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
 	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
+	"github.com/tektoncd/pipeline/test"
 	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	logtesting "knative.dev/pkg/logging/testing"
+	"sigs.k8s.io/yaml"
 )
 
 func TestGetSelector(t *testing.T) {
@@ -76,6 +110,33 @@ func TestValidateParams(t *testing.T) {
 	}
 }
 
+func TestValidateParamsNoNetwork(t *testing.T) {
+	trap := frtesting.NewNetworkTrap(t)
+	resolver := Resolver{}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		ConfigRegistryMirrors: "docker.io: " + trap.Addr() + "/docker.io",
+	})
+	for _, bundle := range []string{trap.Addr() + "/foo:tag", "docker.io/foo:tag"} {
+		params := []pipelinev1beta1.Param{{
+			Name:  ParamKind,
+			Value: *pipelinev1beta1.NewStructuredValues("task"),
+		}, {
+			Name:  ParamName,
+			Value: *pipelinev1beta1.NewStructuredValues("foo"),
+		}, {
+			Name:  ParamBundle,
+			Value: *pipelinev1beta1.NewStructuredValues(bundle),
+		}, {
+			Name:  ParamServiceAccount,
+			Value: *pipelinev1beta1.NewStructuredValues("baz"),
+		}}
+		if err := resolver.ValidateParams(ctx, params); err != nil {
+			t.Fatalf("unexpected error validating params: %v", err)
+		}
+	}
+	trap.ExpectNoConnections(t)
+}
+
 func TestValidateParamsDisabled(t *testing.T) {
 	resolver := Resolver{}
 
@@ -104,6 +165,44 @@ func TestValidateParamsDisabled(t *testing.T) {
 	}
 }
 
+func TestValidateParamsFeatureFlagChange(t *testing.T) {
+	resolver := Resolver{}
+	params := []pipelinev1beta1.Param{{
+		Name:  ParamKind,
+		Value: *pipelinev1beta1.NewStructuredValues("task"),
+	}, {
+		Name:  ParamName,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  ParamBundle,
+		Value: *pipelinev1beta1.NewStructuredValues("bar"),
+	}, {
+		Name:  ParamServiceAccount,
+		Value: *pipelinev1beta1.NewStructuredValues("baz"),
+	}}
+	store := framework.NewConfigStore("bundleresolver-config", logtesting.TestLogger(t))
+	setEnabled := func(enabled string) {
+		store.Store.OnConfigChanged(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: resolverconfig.GetFeatureFlagsConfigName()},
+			Data:       map[string]string{resolverconfig.EnableBundlesResolver: enabled},
+		})
+	}
+
+	setEnabled("true")
+	if err := resolver.ValidateParams(store.ToContext(context.Background()), params); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+
+	setEnabled("false")
+	err := resolver.ValidateParams(store.ToContext(context.Background()), params)
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
 func TestValidateParamsMissing(t *testing.T) {
 	resolver := Resolver{}
 
@@ -141,6 +240,3192 @@ func TestValidateParamsMissing(t *testing.T) {
 
 }
 
+func TestValidateParamsVariableReferences(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		name        string
+		bundle      string
+		objectName  string
+		expectedErr string
+	}{{
+		name:       "legitimate dollar sign",
+		bundle:     "gcr.io/foo/bar:v1",
+		objectName: "foo$bar",
+	}, {
+		name:        "unresolved bundle",
+		bundle:      "gcr.io/foo/bar:$(params.tag)",
+		objectName:  "foo",
+		expectedErr: `param "bundle" still contains an unresolved variable reference "$(params.tag)"`,
+	}, {
+		name:        "unresolved name",
+		bundle:      "gcr.io/foo/bar:v1",
+		objectName:  "$(params.task-name)",
+		expectedErr: `param "name" still contains an unresolved variable reference "$(params.task-name)"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.objectName),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestGetParamSchema(t *testing.T) {
+	resolver := Resolver{}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		ConfigKind:           "task",
+		ConfigServiceAccount: "default",
+	})
+	schema, err := framework.GetParamSchema(ctx, &resolver)
+	if err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+	params := map[string]framework.ParamSchema{}
+	for _, p := range schema {
+		params[p.Name] = p
+	}
+	if p := params[ParamBundle]; !p.Required {
+		t.Errorf("expected bundle to be required but got %+v", p)
+	}
+	if p := params[ParamKind]; p.Default != "task" || p.Required {
+		t.Errorf("expected optional kind defaulting to task but got %+v", p)
+	}
+	if p := params[ParamServiceAccount]; p.Default != "default" || p.Required {
+		t.Errorf("expected optional serviceAccount defaulting to default but got %+v", p)
+	}
+
+	// The schema matches what ValidateParams accepts, the params handled
+	// by the framework aside. The bundle is pinned by digest so that
+	// requireDigest may be true.
+	valid := map[string]string{
+		ParamBundle: "gcr.io/foo/bar@sha256:" + strings.Repeat("a", 64),
+		ParamName:   "foo",
+	}
+	toParams := func(m map[string]string) []pipelinev1beta1.Param {
+		var ps []pipelinev1beta1.Param
+		for k, v := range m {
+			ps = append(ps, pipelinev1beta1.Param{Name: k, Value: *pipelinev1beta1.NewStructuredValues(v)})
+		}
+		return ps
+	}
+	if err := resolver.ValidateParams(ctx, toParams(valid)); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	for _, p := range schema {
+		if p.Name == resolutioncommon.ParamFallback || p.Name == resolutioncommon.ParamDocuments || p.Name == resolutioncommon.ParamDecode {
+			continue
+		}
+		if p.Required {
+			t.Run("without "+p.Name, func(t *testing.T) {
+				without := map[string]string{}
+				for k, v := range valid {
+					if k != p.Name {
+						without[k] = v
+					}
+				}
+				if err := resolver.ValidateParams(ctx, toParams(without)); err == nil {
+					t.Errorf("expected params without required %s to be invalid", p.Name)
+				}
+			})
+		}
+		for _, value := range p.Enum {
+			t.Run(p.Name+" "+value, func(t *testing.T) {
+				with := map[string]string{p.Name: value}
+				for k, v := range valid {
+					with[k] = v
+				}
+				if err := resolver.ValidateParams(ctx, toParams(with)); err != nil {
+					t.Errorf("expected %s %q from the schema to be valid but got %v", p.Name, value, err)
+				}
+			})
+		}
+		if len(p.Enum) > 0 {
+			t.Run(p.Name+" outside of enum", func(t *testing.T) {
+				with := map[string]string{p.Name: "bogus"}
+				for k, v := range valid {
+					with[k] = v
+				}
+				if err := resolver.ValidateParams(ctx, toParams(with)); err == nil {
+					t.Errorf("expected %s %q outside of the schema's enum to be invalid", p.Name, "bogus")
+				}
+			})
+		}
+	}
+}
+
+func TestValidateParamsSecret(t *testing.T) {
+	kubeClientSet := fakek8s.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-creds", Namespace: "foo"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "foo"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	})
+	resolver := Resolver{kubeClientSet: kubeClientSet}
+
+	testCases := []struct {
+		name         string
+		params       map[string]string
+		expectedErr  string
+		errOnResolve bool
+	}{
+		{
+			name:   "secret",
+			params: map[string]string{ParamSecret: "registry-creds"},
+		},
+		{
+			name:        "secret and service account",
+			params:      map[string]string{ParamSecret: "registry-creds", ParamServiceAccount: "baz"},
+			expectedErr: `only one of parameters "serviceAccount" and "secret" may be set`,
+		},
+		{
+			name:         "secret not found",
+			params:       map[string]string{ParamSecret: "missing"},
+			expectedErr:  "cannot get registry credentials, secret missing not found in namespace foo",
+			errOnResolve: true,
+		},
+		{
+			name:         "secret not a docker config",
+			params:       map[string]string{ParamSecret: "opaque"},
+			expectedErr:  "secret opaque in namespace foo must be of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg",
+			errOnResolve: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}}
+			for k, v := range tc.params {
+				params = append(params, pipelinev1beta1.Param{Name: k, Value: *pipelinev1beta1.NewStructuredValues(v)})
+			}
+
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo")
+			err := resolver.ValidateParams(ctx, params)
+			if tc.errOnResolve {
+				// The secret is only read when resolving.
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				_, err = resolver.Resolve(ctx, params)
+			}
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+			if strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("error leaked secret data: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveSecretNotLeaked(t *testing.T) {
+	kubeClientSet := fakek8s.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-creds", Namespace: "foo"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`hunter2`)},
+	})
+	resolver := Resolver{kubeClientSet: kubeClientSet}
+
+	params := []pipelinev1beta1.Param{{
+		Name:  ParamKind,
+		Value: *pipelinev1beta1.NewStructuredValues("task"),
+	}, {
+		Name:  ParamName,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  ParamBundle,
+		Value: *pipelinev1beta1.NewStructuredValues("bar"),
+	}, {
+		Name:  ParamSecret,
+		Value: *pipelinev1beta1.NewStructuredValues("registry-creds"),
+	}}
+
+	ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo")
+	_, err := resolver.Resolve(ctx, params)
+	if err == nil {
+		t.Fatalf("expected err but didn't get one")
+	}
+	if d := cmp.Diff("cannot parse registry credentials in secret registry-creds in namespace foo", err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolveCredentialsForbidden(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		params      map[string]string
+		resource    string
+		expectedErr string
+	}{{
+		name:        "service account",
+		params:      map[string]string{ParamServiceAccount: "builder"},
+		resource:    "serviceaccounts",
+		expectedErr: "cannot get registry credentials, the resolver's service account is not allowed to get service account builder in namespace foo, grant it get access to serviceaccounts with a Role and RoleBinding in that namespace",
+	}, {
+		name:        "secret",
+		params:      map[string]string{ParamSecret: "registry-creds"},
+		resource:    "secrets",
+		expectedErr: "cannot get registry credentials, the resolver's service account is not allowed to get secret registry-creds in namespace foo, grant it get access to secrets with a Role and RoleBinding in that namespace",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClientSet := fakek8s.NewSimpleClientset()
+			kubeClientSet.PrependReactor("get", tc.resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+				name := action.(k8stesting.GetAction).GetName()
+				return true, nil, apierrors.NewForbidden(corev1.Resource(tc.resource), name, errors.New("denied"))
+			})
+			resolver := Resolver{kubeClientSet: kubeClientSet}
+
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}}
+			for k, v := range tc.params {
+				params = append(params, pipelinev1beta1.Param{Name: k, Value: *pipelinev1beta1.NewStructuredValues(v)})
+			}
+
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo")
+			_, err := resolver.Resolve(ctx, params)
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+			var accessErr *resolutioncommon.CredentialAccessError
+			if !errors.As(err, &accessErr) || !accessErr.Forbidden || accessErr.Resource != tc.resource || accessErr.Namespace != "foo" {
+				t.Errorf("expected a forbidden CredentialAccessError for %s in namespace foo but got %v", tc.resource, err)
+			}
+		})
+	}
+}
+
+// fakeCredentialProvider records the registries and credential
+// requests it is asked for, failing each one with err.
+type fakeCredentialProvider struct {
+	err        error
+	registries []string
+	requests   []framework.CredentialRequest
+}
+
+func (p *fakeCredentialProvider) RegistryKeychain(_ context.Context, registry string, req framework.CredentialRequest) (authn.Keychain, error) {
+	p.registries = append(p.registries, registry)
+	p.requests = append(p.requests, req)
+	return nil, p.err
+}
+
+func (p *fakeCredentialProvider) Token(context.Context, string, framework.CredentialRequest) (string, error) {
+	return "", errors.New("not a hub")
+}
+
+func TestResolveWithCredentialProvider(t *testing.T) {
+	provider := &fakeCredentialProvider{err: errors.New("vault is sealed")}
+	// The secret doesn't exist since the provider is asked for it instead.
+	resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset()}
+
+	params := []pipelinev1beta1.Param{{
+		Name:  ParamKind,
+		Value: *pipelinev1beta1.NewStructuredValues("task"),
+	}, {
+		Name:  ParamName,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  ParamBundle,
+		Value: *pipelinev1beta1.NewStructuredValues("registry.example.com:5000/tasks/foo:latest"),
+	}, {
+		Name:  ParamSecret,
+		Value: *pipelinev1beta1.NewStructuredValues("vault/registry-creds"),
+	}}
+
+	ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo")
+	ctx = framework.InjectCredentialProviderToContext(ctx, provider)
+	if err := resolver.ValidateParams(ctx, params); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	_, err := resolver.Resolve(ctx, params)
+	if d := cmp.Diff("error getting registry credentials for registry.example.com:5000: vault is sealed", fmt.Sprint(err)); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+	if d := cmp.Diff([]string{"registry.example.com:5000"}, provider.registries); d != "" {
+		t.Errorf("unexpected registries: %s", diff.PrintWantGot(d))
+	}
+	expected := []framework.CredentialRequest{{Namespace: "foo", Secret: "vault/registry-creds"}}
+	if d := cmp.Diff(expected, provider.requests); d != "" {
+		t.Errorf("unexpected credential requests: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestValidateParamsRequireDigest(t *testing.T) {
+	resolver := Resolver{}
+
+	testCases := []struct {
+		name          string
+		bundle        string
+		requireDigest string
+		expectedErr   string
+	}{
+		{
+			name:          "tag allowed by default",
+			bundle:        "registry.example.com/foo:latest",
+			requireDigest: "",
+		},
+		{
+			name:          "tag allowed when not required",
+			bundle:        "registry.example.com/foo:latest",
+			requireDigest: "false",
+		},
+		{
+			name:          "digest required",
+			bundle:        "registry.example.com/foo@sha256:" + strings.Repeat("a", 64),
+			requireDigest: "true",
+		},
+		{
+			name:          "tag rejected when digest required",
+			bundle:        "registry.example.com/foo:latest",
+			requireDigest: "true",
+			expectedErr:   `bundle reference registry.example.com/foo:latest must be pinned by digest when parameter "requireDigest" is true`,
+		},
+		{
+			name:          "invalid value",
+			bundle:        "registry.example.com/foo:latest",
+			requireDigest: "maybe",
+			expectedErr:   `parameter "requireDigest" must be true or false: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}, {
+				Name:  ParamRequireDigest,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.requireDigest),
+			}}
+
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryPinsDigest(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	tagRef := fmt.Sprintf("%s/bundle:latest", u.Host)
+	digestRef, err := test.CreateImage(tagRef, task)
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+
+	for _, ref := range []string{tagRef, digestRef} {
+		resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+			Bundle:    ref,
+			EntryName: "foo",
+			Kind:      "task",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error getting entry from %s: %v", ref, err)
+		}
+		if d := cmp.Diff(digestRef, resolved.Annotations()[ResolverAnnotationResolvedBundle]); d != "" {
+			t.Errorf("unexpected resolved bundle for %s: %s", ref, diff.PrintWantGot(d))
+		}
+		digest := strings.SplitN(digestRef, "@sha256:", 2)[1]
+		expectedSource := &v1beta1.ConfigSource{
+			URI:        digestRef,
+			Digest:     map[string]string{"sha256": digest},
+			EntryPoint: "foo",
+		}
+		if d := cmp.Diff(expectedSource, resolved.Source()); d != "" {
+			t.Errorf("unexpected source for %s: %s", ref, diff.PrintWantGot(d))
+		}
+	}
+}
+
+func TestGetEntryProvenance(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	tagRef := fmt.Sprintf("%s/bundle:latest", u.Host)
+	digestRef, err := test.CreateImage(tagRef, task)
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+
+	resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:    tagRef,
+		EntryName: "foo",
+		Kind:      "task",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error getting entry: %v", err)
+	}
+	digest := strings.SplitN(digestRef, "@sha256:", 2)[1]
+	expected := `{"resolverType":"bundles","uri":"` + digestRef + `","digest":{"sha256":"` + digest + `"},` +
+		`"coordinates":{"apiVersion":"v1beta1","kind":"task","name":"foo"}}`
+	if d := cmp.Diff(expected, resolved.Annotations()[resolutioncommon.AnnotationKeyProvenance]); d != "" {
+		t.Errorf("unexpected provenance: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestValidateParamsDigest(t *testing.T) {
+	resolver := Resolver{}
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	testCases := []struct {
+		name        string
+		bundle      string
+		digest      string
+		expectedErr string
+	}{
+		{
+			name:   "tag with digest",
+			bundle: "registry.example.com/foo:latest",
+			digest: digest,
+		},
+		{
+			name:   "matching pinned digest",
+			bundle: "registry.example.com/foo@" + digest,
+			digest: digest,
+		},
+		{
+			name:        "different pinned digest",
+			bundle:      "registry.example.com/foo@sha256:" + strings.Repeat("b", 64),
+			digest:      digest,
+			expectedErr: fmt.Sprintf("bundle reference registry.example.com/foo@sha256:%s is pinned to a different digest than digest param %s", strings.Repeat("b", 64), digest),
+		},
+		{
+			name:        "missing algorithm",
+			bundle:      "registry.example.com/foo:latest",
+			digest:      strings.Repeat("a", 64),
+			expectedErr: fmt.Sprintf(`invalid digest param %q: must be of the form sha256:<hex>`, strings.Repeat("a", 64)),
+		},
+		{
+			name:        "truncated",
+			bundle:      "registry.example.com/foo:latest",
+			digest:      "sha256:abc",
+			expectedErr: `invalid digest param "sha256:abc": must be of the form sha256:<hex>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}, {
+				Name:  ParamDigest,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.digest),
+			}}
+
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryExpectedDigest(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	tagRef := fmt.Sprintf("%s/bundle:latest", u.Host)
+	digestRef, err := test.CreateImage(tagRef, task)
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+	digest := strings.SplitN(digestRef, "@", 2)[1]
+
+	if _, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:         tagRef,
+		EntryName:      "foo",
+		Kind:           "task",
+		ExpectedDigest: digest,
+	}); err != nil {
+		t.Fatalf("unexpected error getting entry with matching digest: %v", err)
+	}
+
+	otherDigest := "sha256:" + strings.Repeat("a", 64)
+	_, err = GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:         tagRef,
+		EntryName:      "foo",
+		Kind:           "task",
+		ExpectedDigest: otherDigest,
+	})
+	if err == nil {
+		t.Fatalf("expected digest mismatch err")
+	}
+	expectedErr := fmt.Sprintf("digest mismatch for bundle %s: expected %s but got %s", tagRef, otherDigest, digest)
+	if d := cmp.Diff(expectedErr, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestGetEntrySelection(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTask := func(name string) *pipelinev1beta1.Task {
+		return &pipelinev1beta1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+		}
+	}
+	newPipeline := func(name string) *pipelinev1beta1.Pipeline {
+		return &pipelinev1beta1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Pipeline"},
+		}
+	}
+	push := func(repo string, objs ...runtime.Object) string {
+		ref, err := test.CreateImage(fmt.Sprintf("%s/%s:latest", u.Host, repo), objs...)
+		if err != nil {
+			t.Fatalf("failed to push bundle: %v", err)
+		}
+		return ref
+	}
+	multi := push("multi", newTask("foo"), newPipeline("foo"), newTask("bar"))
+	single := push("single", newTask("foo"))
+	duplicate := push("duplicate", newTask("foo"), newTask("foo"))
+
+	testCases := []struct {
+		name         string
+		bundle       string
+		kind         string
+		entryName    string
+		expectedName string
+		expectedErr  string
+	}{
+		{
+			name:         "task by kind and name",
+			bundle:       multi,
+			kind:         "task",
+			entryName:    "foo",
+			expectedName: "foo",
+		},
+		{
+			name:         "pipeline with same name as task",
+			bundle:       multi,
+			kind:         "pipeline",
+			entryName:    "foo",
+			expectedName: "foo",
+		},
+		{
+			name:        "no match lists available objects",
+			bundle:      multi,
+			kind:        "task",
+			entryName:   "baz",
+			expectedErr: "could not find object in image with kind: task and name: baz, available objects: task/foo, pipeline/foo, task/bar",
+		},
+		{
+			name:         "single object without name",
+			bundle:       single,
+			kind:         "task",
+			expectedName: "foo",
+		},
+		{
+			name:        "single object of another kind without name",
+			bundle:      single,
+			kind:        "pipeline",
+			expectedErr: `parameter "name" is required unless the bundle contains a single object of kind pipeline, available objects: task/foo`,
+		},
+		{
+			name:        "multiple objects without name",
+			bundle:      multi,
+			kind:        "task",
+			expectedErr: `parameter "name" is required unless the bundle contains a single object of kind task, available objects: task/foo, pipeline/foo, task/bar`,
+		},
+		{
+			name:        "ambiguous match",
+			bundle:      duplicate,
+			kind:        "task",
+			entryName:   "foo",
+			expectedErr: fmt.Sprintf("bundle %s contains 2 objects with kind: task and name: foo", duplicate),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:    tc.bundle,
+				EntryName: tc.entryName,
+				Kind:      tc.kind,
+			})
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if d := cmp.Diff(tc.kind, resolved.Annotations()[ResolverAnnotationKind]); d != "" {
+				t.Errorf("unexpected kind: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedName, resolved.Annotations()[ResolverAnnotationName]); d != "" {
+				t.Errorf("unexpected name: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestValidateParamsResolveSingleObject(t *testing.T) {
+	resolver := Resolver{}
+	params := []pipelinev1beta1.Param{{
+		Name:  ParamKind,
+		Value: *pipelinev1beta1.NewStructuredValues("task"),
+	}, {
+		Name:  ParamBundle,
+		Value: *pipelinev1beta1.NewStructuredValues("bar"),
+	}, {
+		Name:  ParamServiceAccount,
+		Value: *pipelinev1beta1.NewStructuredValues("baz"),
+	}}
+
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{ConfigResolveSingleObject: "true"})
+	if err := resolver.ValidateParams(ctx, params); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+}
+
+func TestValidateParamsAnnotation(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		name        string
+		params      map[string]string
+		expectedErr string
+	}{{
+		name:   "annotation",
+		params: map[string]string{ParamAnnotationKey: "example.com/id", ParamAnnotationValue: "build"},
+	}, {
+		name:        "annotation and name",
+		params:      map[string]string{ParamName: "foo", ParamAnnotationKey: "example.com/id", ParamAnnotationValue: "build"},
+		expectedErr: `only one of parameter "name" and parameters "annotationKey" and "annotationValue" may be set`,
+	}, {
+		name:        "key without value",
+		params:      map[string]string{ParamAnnotationKey: "example.com/id"},
+		expectedErr: `parameters "annotationKey" and "annotationValue" must be set together`,
+	}, {
+		name:        "value without key",
+		params:      map[string]string{ParamName: "foo", ParamAnnotationValue: "build"},
+		expectedErr: `parameters "annotationKey" and "annotationValue" must be set together`,
+	}, {
+		name:        "neither name nor annotation",
+		params:      map[string]string{},
+		expectedErr: `parameter "name" required`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			for _, key := range []string{ParamName, ParamAnnotationKey, ParamAnnotationValue} {
+				if value, ok := tc.params[key]; ok {
+					params = append(params, pipelinev1beta1.Param{Name: key, Value: *pipelinev1beta1.NewStructuredValues(value)})
+				}
+			}
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryAnnotationSelection(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle indexes its objects with its own example.com/id
+	// annotation besides the usual kind and name.
+	const annotationKey = "example.com/id"
+	addendum := func(kind, name, id string) mutate.Addendum {
+		content := fmt.Sprintf("apiVersion: tekton.dev/v1beta1\nkind: %s\nmetadata:\n  name: %s\n", kind, name)
+		annotations := map[string]string{
+			BundleAnnotationAPIVersion: "tekton.dev/v1beta1",
+			BundleAnnotationKind:       strings.ToLower(kind),
+			BundleAnnotationName:       name,
+		}
+		if id != "" {
+			annotations[annotationKey] = id
+		}
+		return mutate.Addendum{
+			Layer:       static.NewLayer([]byte(content), "application/vnd.tekton.task.v1beta1+yaml"),
+			Annotations: annotations,
+		}
+	}
+	push := func(repo string, adds ...mutate.Addendum) string {
+		ref, err := name.ParseReference(fmt.Sprintf("%s/%s:latest", u.Host, repo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := mutate.Append(empty.Image, adds...)
+		if err != nil {
+			t.Fatalf("failed to create bundle: %v", err)
+		}
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatalf("failed to push bundle: %v", err)
+		}
+		return ref.String()
+	}
+	indexed := push("indexed", addendum("Task", "foo", "build"), addendum("Pipeline", "bar", "build"), addendum("Task", "baz", "test"), addendum("Task", "qux", ""))
+	duplicate := push("duplicate", addendum("Task", "foo", "build"), addendum("Task", "bar", "build"))
+
+	testCases := []struct {
+		name            string
+		bundle          string
+		kind            string
+		annotationValue string
+		expectedName    string
+		expectedErr     string
+	}{
+		{
+			name:            "task by annotation",
+			bundle:          indexed,
+			kind:            "task",
+			annotationValue: "test",
+			expectedName:    "baz",
+		},
+		{
+			name:            "pipeline with same annotation as task",
+			bundle:          indexed,
+			kind:            "pipeline",
+			annotationValue: "build",
+			expectedName:    "bar",
+		},
+		{
+			name:            "no match lists available annotations",
+			bundle:          indexed,
+			kind:            "task",
+			annotationValue: "deploy",
+			expectedErr:     "could not find object in image with kind: task and annotation: example.com/id=deploy, available annotations: task/foo (example.com/id=build), pipeline/bar (example.com/id=build), task/baz (example.com/id=test)",
+		},
+		{
+			name:            "ambiguous match",
+			bundle:          duplicate,
+			kind:            "task",
+			annotationValue: "build",
+			expectedErr:     fmt.Sprintf("bundle %s contains 2 objects with kind: task and annotation: example.com/id=build", duplicate),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:          tc.bundle,
+				Kind:            tc.kind,
+				AnnotationKey:   annotationKey,
+				AnnotationValue: tc.annotationValue,
+			})
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedName, resolved.Annotations()[ResolverAnnotationName]); d != "" {
+				t.Errorf("unexpected name: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestValidateParamsLayer(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		name        string
+		layer       string
+		entryName   string
+		expectedErr string
+	}{{
+		name:  "index",
+		layer: "1",
+	}, {
+		name:      "index and name",
+		layer:     "0",
+		entryName: "foo",
+	}, {
+		name:  "media type",
+		layer: "application/vnd.tekton.task.v1beta1+yaml",
+	}, {
+		name:        "negative index",
+		layer:       "-1",
+		expectedErr: `invalid layer param "-1": layer index must not be negative`,
+	}, {
+		name:        "neither index nor media type",
+		layer:       "first",
+		expectedErr: `invalid layer param "first": must be a layer index or a media type`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}, {
+				Name:  ParamLayer,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.layer),
+			}}
+			if tc.entryName != "" {
+				params = append(params, pipelinev1beta1.Param{Name: ParamName, Value: *pipelinev1beta1.NewStructuredValues(tc.entryName)})
+			}
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryLayer(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		yamlMediaType = "application/vnd.tekton.task.v1beta1+yaml"
+		jsonMediaType = "application/vnd.tekton.task.v1beta1+json"
+	)
+	addendum := func(kind, name, mediaType string) mutate.Addendum {
+		content := fmt.Sprintf("apiVersion: tekton.dev/v1beta1\nkind: %s\nmetadata:\n  name: %s\n", kind, name)
+		return mutate.Addendum{
+			Layer: static.NewLayer([]byte(content), types.MediaType(mediaType)),
+			Annotations: map[string]string{
+				BundleAnnotationAPIVersion: "tekton.dev/v1beta1",
+				BundleAnnotationKind:       strings.ToLower(kind),
+				BundleAnnotationName:       name,
+			},
+		}
+	}
+	ref, err := name.ParseReference(fmt.Sprintf("%s/layers:latest", u.Host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image,
+		addendum("Task", "foo", yamlMediaType),
+		addendum("Task", "bar", yamlMediaType),
+		addendum("Pipeline", "baz", jsonMediaType),
+		addendum("Task", "qux", "application/vnd.example.task+yaml"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+	bundle := ref.String()
+
+	testCases := []struct {
+		name         string
+		layer        string
+		kind         string
+		entryName    string
+		expectedName string
+		expectedErr  string
+	}{
+		{
+			name:         "index without name",
+			layer:        "1",
+			kind:         "task",
+			expectedName: "bar",
+		},
+		{
+			name:         "index with name",
+			layer:        "0",
+			kind:         "task",
+			entryName:    "foo",
+			expectedName: "foo",
+		},
+		{
+			name:        "index with name of another layer",
+			layer:       "0",
+			kind:        "task",
+			entryName:   "bar",
+			expectedErr: "could not find object in image layer 0 with kind: task and name: bar, available objects: task/foo",
+		},
+		{
+			name:        "index of a layer of another kind",
+			layer:       "2",
+			kind:        "task",
+			expectedErr: `could not find a single object in image layer 2 with kind: task, available objects: pipeline/baz, set parameter "name" to select one`,
+		},
+		{
+			name:        "index out of range",
+			layer:       "4",
+			kind:        "task",
+			expectedErr: "bundle " + bundle + " has no layer 4, found layers: 0: task/foo (" + yamlMediaType + "), 1: task/bar (" + yamlMediaType + "), 2: pipeline/baz (" + jsonMediaType + "), 3: task/qux (application/vnd.example.task+yaml)",
+		},
+		{
+			name:         "media type without name",
+			layer:        "application/vnd.example.task+yaml",
+			kind:         "task",
+			expectedName: "qux",
+		},
+		{
+			name:         "media type with name",
+			layer:        yamlMediaType,
+			kind:         "task",
+			entryName:    "bar",
+			expectedName: "bar",
+		},
+		{
+			name:        "media type of several layers without name",
+			layer:       yamlMediaType,
+			kind:        "task",
+			expectedErr: `could not find a single object in image layers of media type ` + yamlMediaType + ` with kind: task, available objects: task/foo, task/bar, set parameter "name" to select one`,
+		},
+		{
+			name:        "media type of no layer",
+			layer:       "application/vnd.tekton.pipeline.v1beta1+yaml",
+			kind:        "pipeline",
+			entryName:   "baz",
+			expectedErr: "bundle " + bundle + " has no layers of media type application/vnd.tekton.pipeline.v1beta1+yaml, found layers: 0: task/foo (" + yamlMediaType + "), 1: task/bar (" + yamlMediaType + "), 2: pipeline/baz (" + jsonMediaType + "), 3: task/qux (application/vnd.example.task+yaml)",
+		},
+		{
+			name:         "no layer param",
+			kind:         "pipeline",
+			entryName:    "baz",
+			expectedName: "baz",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:    bundle,
+				EntryName: tc.entryName,
+				Kind:      tc.kind,
+				Layer:     tc.layer,
+			})
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedName, resolved.Annotations()[ResolverAnnotationName]); d != "" {
+				t.Errorf("unexpected name: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryDetectKind(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addendum := func(annotatedKind, kind, name string) mutate.Addendum {
+		content := fmt.Sprintf("apiVersion: tekton.dev/v1beta1\nkind: %s\nmetadata:\n  name: %s\n", kind, name)
+		return mutate.Addendum{
+			Layer: static.NewLayer([]byte(content), types.MediaType("application/vnd.tekton.v1beta1+yaml")),
+			Annotations: map[string]string{
+				BundleAnnotationAPIVersion: "tekton.dev/v1beta1",
+				BundleAnnotationKind:       annotatedKind,
+				BundleAnnotationName:       name,
+			},
+		}
+	}
+	ref, err := name.ParseReference(fmt.Sprintf("%s/detect:latest", u.Host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image,
+		addendum("task", "Task", "foo"),
+		addendum("pipeline", "Pipeline", "bar"),
+		addendum("task", "Pipeline", "mislabelled"),
+		addendum("stepaction", "StepAction", "qux"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+	bundle := ref.String()
+
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		ConfigServiceAccount: "default",
+		ConfigKind:           "task",
+		ConfigDetectKind:     "true",
+	})
+	for _, tc := range []struct {
+		name         string
+		entryName    string
+		expectedKind string
+		expectedErr  string
+	}{{
+		name:         "task",
+		entryName:    "foo",
+		expectedKind: "task",
+	}, {
+		name:         "pipeline",
+		entryName:    "bar",
+		expectedKind: "pipeline",
+	}, {
+		name:        "annotated kind differs",
+		entryName:   "mislabelled",
+		expectedErr: "object task/mislabelled in bundle " + bundle + " is a pipeline",
+	}, {
+		name:        "kind not detectable",
+		entryName:   "qux",
+		expectedErr: "could not detect the kind of object stepaction/qux in bundle " + bundle + ": detected kind StepAction is not one of the allowed kinds: task, pipeline",
+	}, {
+		name:        "not found",
+		entryName:   "missing",
+		expectedErr: "could not find object in image with kind: any and name: missing, available objects: task/foo, pipeline/bar, task/mislabelled, stepaction/qux",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := OptionsFromParams(ctx, []pipelinev1beta1.Param{{
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.entryName),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(bundle),
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error parsing params: %v", err)
+			}
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, opts)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedKind, resolved.Annotations()[resolutioncommon.AnnotationKeyDetectedKind]); d != "" {
+				t.Errorf("unexpected detected kind: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+
+	t.Run("given kind isn't detected", func(t *testing.T) {
+		resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+			Bundle:    bundle,
+			EntryName: "mislabelled",
+			Kind:      "task",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error getting entry: %v", err)
+		}
+		if kind, ok := resolved.Annotations()[resolutioncommon.AnnotationKeyDetectedKind]; ok {
+			t.Errorf("expected no detected kind but got %q", kind)
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+			ConfigServiceAccount: "default",
+			ConfigDetectKind:     "sometimes",
+		})
+		_, err := OptionsFromParams(ctx, []pipelinev1beta1.Param{{
+			Name:  ParamName,
+			Value: *pipelinev1beta1.NewStructuredValues("foo"),
+		}, {
+			Name:  ParamBundle,
+			Value: *pipelinev1beta1.NewStructuredValues(bundle),
+		}})
+		expected := `invalid detect-kind config: strconv.ParseBool: parsing "sometimes": invalid syntax`
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected error %q but got %v", expected, err)
+		}
+	})
+}
+
+func TestGetEntryTimeout(t *testing.T) {
+	testCases := []struct {
+		name          string
+		slowPath      string
+		expectedPhase string
+	}{
+		{
+			name:          "manifest",
+			slowPath:      "/manifests/",
+			expectedPhase: "connecting to registry",
+		},
+		{
+			name:          "blobs",
+			slowPath:      "/blobs/",
+			expectedPhase: "downloading layers",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := registry.New()
+			var slow int32
+			done := make(chan struct{})
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.LoadInt32(&slow) == 1 && r.Method == http.MethodGet && strings.Contains(r.URL.Path, tc.slowPath) {
+					select {
+					case <-r.Context().Done():
+					case <-done:
+					}
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			defer svr.Close()
+			defer close(done)
+			u, err := url.Parse(svr.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := &pipelinev1beta1.Task{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+			}
+			ref, err := test.CreateImage(fmt.Sprintf("%s/bundle:latest", u.Host), task)
+			if err != nil {
+				t.Fatalf("failed to push bundle: %v", err)
+			}
+			atomic.StoreInt32(&slow, 1)
+
+			_, err = GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:    ref,
+				EntryName: "foo",
+				Kind:      "task",
+				Timeout:   100 * time.Millisecond,
+			})
+			if err == nil {
+				t.Fatalf("expected timeout err but didn't get one")
+			}
+			expectedPrefix := fmt.Sprintf("timed out after 100ms %s for bundle %s: ", tc.expectedPhase, ref)
+			if !strings.HasPrefix(err.Error(), expectedPrefix) {
+				t.Errorf("expected error starting with %q but got %q", expectedPrefix, err.Error())
+			}
+		})
+	}
+}
+
+func TestOptionsFromParamsTimeout(t *testing.T) {
+	testCases := []struct {
+		name        string
+		param       string
+		config      map[string]string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		{
+			name:     "default",
+			expected: 0,
+		},
+		{
+			name:     "config",
+			config:   map[string]string{ConfigTimeout: "2m"},
+			expected: 2 * time.Minute,
+		},
+		{
+			name:     "param over config",
+			param:    "30s",
+			config:   map[string]string{ConfigTimeout: "2m"},
+			expected: 30 * time.Second,
+		},
+		{
+			name:        "invalid param",
+			param:       "soon",
+			expectedErr: true,
+		},
+		{
+			name:        "negative config",
+			config:      map[string]string{ConfigTimeout: "-1s"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			if tc.param != "" {
+				params = append(params, pipelinev1beta1.Param{Name: ParamTimeout, Value: *pipelinev1beta1.NewStructuredValues(tc.param)})
+			}
+
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			opts, err := OptionsFromParams(ctx, params)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opts.Timeout != tc.expected {
+				t.Errorf("expected timeout %s but got %s", tc.expected, opts.Timeout)
+			}
+		})
+	}
+}
+
+func TestOptionsFromParamsRegistryMirrors(t *testing.T) {
+	mirrors := `
+docker.io: mirror.internal/docker.io
+gcr.io/tekton-releases/: mirror.internal/tekton/
+gcr.io: mirror.internal/gcr.io
+`
+	testCases := []struct {
+		name        string
+		bundle      string
+		mirrors     string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:   "no mirrors",
+			bundle: "docker.io/foo:tag",
+		},
+		{
+			name:     "matching mirror",
+			bundle:   "docker.io/foo:tag",
+			mirrors:  mirrors,
+			expected: "mirror.internal/docker.io/foo:tag",
+		},
+		{
+			name:     "longest matching prefix",
+			bundle:   "gcr.io/tekton-releases/catalog/upstream/git-clone:0.9",
+			mirrors:  mirrors,
+			expected: "mirror.internal/tekton/catalog/upstream/git-clone:0.9",
+		},
+		{
+			name:     "digest reference",
+			bundle:   "gcr.io/foo@sha256:" + strings.Repeat("a", 64),
+			mirrors:  mirrors,
+			expected: "mirror.internal/gcr.io/foo@sha256:" + strings.Repeat("a", 64),
+		},
+		{
+			name:    "prefix only matches whole path components",
+			bundle:  "docker.io.example.com/foo:tag",
+			mirrors: mirrors,
+		},
+		{
+			name:    "no matching mirror",
+			bundle:  "quay.io/foo:tag",
+			mirrors: mirrors,
+		},
+		{
+			name:        "invalid config",
+			bundle:      "docker.io/foo:tag",
+			mirrors:     "- docker.io",
+			expectedErr: "invalid registry-mirrors config: must be a map of source prefixes to mirror prefixes: error unmarshaling JSON: while decoding JSON: json: cannot unmarshal array into Go value of type map[string]string",
+		},
+		{
+			name:        "empty mirror",
+			bundle:      "docker.io/foo:tag",
+			mirrors:     `docker.io: ""`,
+			expectedErr: `invalid registry-mirrors config: source and mirror prefixes must not be empty, got "docker.io": ""`,
+		},
+		{
+			name:        "invalid rewritten reference",
+			bundle:      "docker.io/foo:tag",
+			mirrors:     "docker.io: Mirror.internal/UPPER",
+			expectedErr: "bundle reference docker.io/foo:tag is rewritten by the registry-mirrors config to the invalid reference Mirror.internal/UPPER/foo:tag: could not parse reference: Mirror.internal/UPPER/foo:tag",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{ConfigRegistryMirrors: tc.mirrors})
+			opts, err := OptionsFromParams(ctx, params)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opts.Bundle != tc.bundle {
+				t.Errorf("expected bundle %s but got %s", tc.bundle, opts.Bundle)
+			}
+			if opts.MirroredBundle != tc.expected {
+				t.Errorf("expected mirrored bundle %q but got %q", tc.expected, opts.MirroredBundle)
+			}
+		})
+	}
+}
+
+func TestValidateParamsRegistries(t *testing.T) {
+	testCases := []struct {
+		name        string
+		bundle      string
+		allowed     string
+		denied      string
+		expectedErr string
+	}{
+		{
+			name:   "no lists",
+			bundle: "quay.io/foo:tag",
+		},
+		{
+			name:    "allowed registry",
+			bundle:  "gcr.io/foo:tag",
+			allowed: "gcr.io, quay.io",
+		},
+		{
+			name:        "registry not allowed",
+			bundle:      "ghcr.io/foo:tag",
+			allowed:     "gcr.io, quay.io",
+			expectedErr: "bundle reference ghcr.io/foo:tag is from registry ghcr.io, which isn't in the allowed-registries config",
+		},
+		{
+			name:    "wildcard allowed",
+			bundle:  "registry.corp.internal/foo:tag",
+			allowed: "*.internal",
+		},
+		{
+			name:        "wildcard doesn't match port",
+			bundle:      "registry.internal:5000/foo:tag",
+			allowed:     "*.internal",
+			expectedErr: "bundle reference registry.internal:5000/foo:tag is from registry registry.internal:5000, which isn't in the allowed-registries config",
+		},
+		{
+			name:    "wildcard with port",
+			bundle:  "registry.internal:5000/foo:tag",
+			allowed: "*.internal:*",
+		},
+		{
+			name:    "docker hub",
+			bundle:  "foo:tag",
+			allowed: "docker.io",
+		},
+		{
+			name:    "hosts are case-insensitive",
+			bundle:  "gcr.io/foo:tag",
+			allowed: "GCR.io",
+		},
+		{
+			name:        "denied registry",
+			bundle:      "docker.io/foo:tag",
+			denied:      "docker.io",
+			expectedErr: "bundle reference docker.io/foo:tag is from registry index.docker.io, which is denied by the denied-registries config",
+		},
+		{
+			name:        "wildcard denied",
+			bundle:      "untrusted.example.com/foo:tag",
+			denied:      "*.example.com",
+			expectedErr: "bundle reference untrusted.example.com/foo:tag is from registry untrusted.example.com, which is denied by the denied-registries config",
+		},
+		{
+			name:        "denied takes precedence",
+			bundle:      "scratch.internal/foo:tag",
+			allowed:     "*.internal",
+			denied:      "scratch.internal",
+			expectedErr: "bundle reference scratch.internal/foo:tag is from registry scratch.internal, which is denied by the denied-registries config",
+		},
+		{
+			name:   "not denied",
+			bundle: "gcr.io/foo:tag",
+			denied: "*.example.com",
+		},
+		{
+			name:        "invalid pattern",
+			bundle:      "gcr.io/foo:tag",
+			allowed:     "gcr.io, [quay.io",
+			expectedErr: `invalid allowed-registries config: invalid registry pattern "[quay.io": syntax error in pattern`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+				ConfigAllowedRegistries: tc.allowed,
+				ConfigDeniedRegistries:  tc.denied,
+			})
+			err := (&Resolver{}).ValidateParams(ctx, params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryRegistryUnavailable(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		status            int
+		path              string
+		closed            bool
+		expectedTransient bool
+		expectedLimited   bool
+	}{{
+		name:              "server error for the manifest",
+		status:            http.StatusNotImplemented,
+		path:              "/manifests/",
+		expectedTransient: true,
+	}, {
+		name:              "server error for the layers",
+		status:            http.StatusNotImplemented,
+		path:              "/blobs/",
+		expectedTransient: true,
+	}, {
+		name:            "rate limited",
+		status:          http.StatusTooManyRequests,
+		path:            "/manifests/",
+		expectedLimited: true,
+	}, {
+		name:              "connection refused",
+		closed:            true,
+		expectedTransient: true,
+	}, {
+		name:   "access denied",
+		status: http.StatusForbidden,
+		path:   "/manifests/",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := registry.New()
+			var failing int32
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.LoadInt32(&failing) == 1 && r.Method == http.MethodGet && strings.Contains(r.URL.Path, tc.path) {
+					w.WriteHeader(tc.status)
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			defer svr.Close()
+			u, err := url.Parse(svr.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := &pipelinev1beta1.Task{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+			}
+			ref, err := test.CreateImage(fmt.Sprintf("%s/bundle:latest", u.Host), task)
+			if err != nil {
+				t.Fatalf("failed to push bundle: %v", err)
+			}
+			atomic.StoreInt32(&failing, 1)
+			if tc.closed {
+				svr.Close()
+			}
+
+			_, err = GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:    ref,
+				EntryName: "foo",
+				Kind:      "task",
+			})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var transientErr *resolutioncommon.TransientError
+			if errors.As(err, &transientErr) != tc.expectedTransient {
+				t.Errorf("expected the error to be a TransientError: %t, but got %v", tc.expectedTransient, err)
+			}
+			var rateLimitedErr *resolutioncommon.RateLimitedError
+			if errors.As(err, &rateLimitedErr) != tc.expectedLimited {
+				t.Errorf("expected the error to be a RateLimitedError: %t, but got %v", tc.expectedLimited, err)
+			}
+		})
+	}
+}
+
+func TestGetEntryRegistryMirror(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle is only pushed to the mirror, so it can only be
+	// resolved by pulling it from there.
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	mirroredRef := fmt.Sprintf("%s/mirror/docker.io/bundle:latest", u.Host)
+	digestRef, err := test.CreateImage(mirroredRef, task)
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		ConfigServiceAccount:  "default",
+		ConfigRegistryMirrors: fmt.Sprintf("docker.io: %s/mirror/docker.io", u.Host),
+	})
+	opts, err := OptionsFromParams(ctx, []pipelinev1beta1.Param{{
+		Name:  ParamKind,
+		Value: *pipelinev1beta1.NewStructuredValues("task"),
+	}, {
+		Name:  ParamName,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  ParamBundle,
+		Value: *pipelinev1beta1.NewStructuredValues("docker.io/bundle:latest"),
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error parsing params: %v", err)
+	}
+	resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, opts)
+	if err != nil {
+		t.Fatalf("unexpected error getting entry: %v", err)
+	}
+	expected := map[string]string{
+		ResolverAnnotationRequestedBundle: "docker.io/bundle:latest",
+		ResolverAnnotationPulledBundle:    mirroredRef,
+		ResolverAnnotationResolvedBundle:  digestRef,
+	}
+	for key, value := range expected {
+		if d := cmp.Diff(value, resolved.Annotations()[key]); d != "" {
+			t.Errorf("unexpected %s annotation: %s", key, diff.PrintWantGot(d))
+		}
+	}
+}
+
+func TestValidateParamsTag(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		name        string
+		bundle      string
+		tag         string
+		expectedErr string
+	}{{
+		name:   "exact tag",
+		bundle: "gcr.io/foo/bar",
+		tag:    "v1.2.0",
+	}, {
+		name:   "tag pattern",
+		bundle: "gcr.io/foo/bar",
+		tag:    "v1.*",
+	}, {
+		name:   "caret range",
+		bundle: "gcr.io/foo/bar",
+		tag:    "^1.2",
+	}, {
+		name:   "bounded range",
+		bundle: "gcr.io/foo/bar",
+		tag:    ">=1.0 <2.0",
+	}, {
+		name:        "bundle with a tag",
+		bundle:      "gcr.io/foo/bar:v1",
+		tag:         "v1.*",
+		expectedErr: `bundle reference gcr.io/foo/bar:v1 must not have a tag or digest when parameter "tag" is set`,
+	}, {
+		name:        "invalid range",
+		bundle:      "gcr.io/foo/bar",
+		tag:         ">=1.0 <",
+		expectedErr: `invalid tag param ">=1.0 <": invalid version constraint: operator "<" is missing a version`,
+	}, {
+		name:        "invalid pattern",
+		bundle:      "gcr.io/foo/bar",
+		tag:         "v1/*",
+		expectedErr: `invalid tag param "v1/*": must be a tag, a tag pattern such as "v1.*" or a range of versions such as "^1.2" or ">=1.0 <2.0"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}, {
+				Name:  ParamTag,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.tag),
+			}}
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestGetEntryTag(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each tag is pushed with a different task so that each has its own
+	// digest.
+	repo := fmt.Sprintf("%s/bundle", u.Host)
+	digests := map[string]string{}
+	for _, tag := range []string{"v1.0.0", "v1.2.0", "v1.10.0", "v2.0.0", "nightly-20240101", "nightly-20240201"} {
+		task := &pipelinev1beta1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+			Spec:       pipelinev1beta1.TaskSpec{Description: tag},
+		}
+		if digests[tag], err = test.CreateImage(repo+":"+tag, task); err != nil {
+			t.Fatalf("failed to push bundle: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		tag      string
+		expected string
+	}{
+		{tag: "^1.0", expected: "v1.10.0"},
+		{tag: "~1.2", expected: "v1.2.0"},
+		{tag: ">=1.0 <1.5", expected: "v1.2.0"},
+		{tag: "v1.*", expected: "v1.10.0"},
+		{tag: "v*", expected: "v2.0.0"},
+		{tag: "nightly-*", expected: "nightly-20240201"},
+	} {
+		t.Run(tc.tag, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{ConfigServiceAccount: "default"})
+			opts, err := OptionsFromParams(ctx, []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(repo),
+			}, {
+				Name:  ParamTag,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.tag),
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error parsing params: %v", err)
+			}
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, opts)
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			expected := map[string]string{
+				ResolverAnnotationSelectedTag:     tc.expected,
+				ResolverAnnotationResolvedBundle:  digests[tc.expected],
+				ResolverAnnotationRequestedBundle: repo,
+				ResolverAnnotationPulledBundle:    repo + ":" + tc.expected,
+			}
+			for key, value := range expected {
+				if d := cmp.Diff(value, resolved.Annotations()[key]); d != "" {
+					t.Errorf("unexpected %s annotation: %s", key, diff.PrintWantGot(d))
+				}
+			}
+		})
+	}
+
+	// An exact tag is pulled without listing the tags.
+	resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:    repo + ":v1.0.0",
+		EntryName: "foo",
+		Kind:      "task",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error getting entry: %v", err)
+	}
+	if _, ok := resolved.Annotations()[ResolverAnnotationSelectedTag]; ok {
+		t.Errorf("expected no %s annotation for an exact tag", ResolverAnnotationSelectedTag)
+	}
+
+	_, err = GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:     repo,
+		TagPattern: "^3.0",
+		EntryName:  "foo",
+		Kind:       "task",
+	})
+	var notFound *resolutioncommon.ResolutionNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+}
+
+func TestGetEntryTagListingUnsupported(t *testing.T) {
+	reg := registry.New()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tags/list") {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	repo := fmt.Sprintf("%s/bundle", u.Host)
+	if _, err := test.CreateImage(repo+":v1.0.0", task); err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+
+	_, err = GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:     repo,
+		TagPattern: "^1.0",
+		EntryName:  "foo",
+		Kind:       "task",
+	})
+	expected := fmt.Sprintf(`the registry of %s doesn't support listing tags, which the tag param "^1.0" requires, use an exact tag instead`, repo)
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Fatalf("expected error %q but got %v", expected, err)
+	}
+}
+
+func TestGetEntryUserAgent(t *testing.T) {
+	reg := registry.New()
+	var userAgents []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	ref := fmt.Sprintf("%s/bundle:latest", u.Host)
+	if _, err := test.CreateImage(ref, task); err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+	userAgents = nil
+
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		ConfigServiceAccount:      "default",
+		framework.ConfigUserAgent: "acme-ci/1.0",
+	})
+	opts, err := OptionsFromParams(ctx, []pipelinev1beta1.Param{{
+		Name:  ParamKind,
+		Value: *pipelinev1beta1.NewStructuredValues("task"),
+	}, {
+		Name:  ParamName,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  ParamBundle,
+		Value: *pipelinev1beta1.NewStructuredValues(ref),
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error parsing params: %v", err)
+	}
+	if _, err := GetEntry(ctx, authn.DefaultKeychain, opts); err != nil {
+		t.Fatalf("unexpected error getting entry: %v", err)
+	}
+	if len(userAgents) == 0 {
+		t.Fatal("expected the bundle to be pulled from the registry")
+	}
+	for _, ua := range userAgents {
+		// go-containerregistry appends its own name and version.
+		if !strings.HasPrefix(ua, "acme-ci/1.0 ") {
+			t.Errorf("expected User-Agent to start with the configured one but got %q", ua)
+		}
+	}
+}
+
+func TestGetEntryCancelledMidPull(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cached bool
+	}{{
+		name: "without cache",
+	}, {
+		name:   "while caching",
+		cached: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := registry.New()
+			var blocking int32
+			pulling := make(chan struct{}, 1)
+			abandoned := make(chan struct{}, 1)
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Layer downloads hang until the client gives up on
+				// them, like a pull of a large bundle from a slow
+				// registry.
+				if atomic.LoadInt32(&blocking) == 1 && r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+					pulling <- struct{}{}
+					<-r.Context().Done()
+					abandoned <- struct{}{}
+					return
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			defer svr.Close()
+			u, err := url.Parse(svr.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := &pipelinev1beta1.Task{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+			}
+			ref := fmt.Sprintf("%s/bundle:latest", u.Host)
+			if _, err := test.CreateImage(ref, task); err != nil {
+				t.Fatalf("failed to push bundle: %v", err)
+			}
+			atomic.StoreInt32(&blocking, 1)
+
+			var cache *bundleCache
+			if tc.cached {
+				cache = &bundleCache{dir: t.TempDir(), maxSize: defaultCacheMaxSize.Value()}
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				<-pulling
+				cancel()
+			}()
+
+			start := time.Now()
+			_, err = getEntry(ctx, authn.DefaultKeychain, RequestOptions{
+				Bundle:    ref,
+				EntryName: "foo",
+				Kind:      "task",
+			}, cache)
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Fatalf("expected the pull to stop once cancelled but it took %s", elapsed)
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected error to wrap context.Canceled but got %v", err)
+			}
+			var timeoutErr *resolutioncommon.ResolutionTimeoutError
+			if errors.As(err, &timeoutErr) {
+				t.Errorf("expected a cancellation rather than a timeout but got %v", err)
+			}
+			if !strings.Contains(err.Error(), "cancelled") {
+				t.Errorf("expected the error to report the pull as cancelled but got %q", err.Error())
+			}
+			select {
+			case <-abandoned:
+			case <-time.After(10 * time.Second):
+				t.Fatal("expected the layer download to be abandoned")
+			}
+			if tc.cached {
+				entries, err := os.ReadDir(cache.dir)
+				if err != nil {
+					t.Fatalf("reading cache dir: %v", err)
+				}
+				if len(entries) != 0 {
+					t.Errorf("expected nothing to be left in the cache but found %d entries", len(entries))
+				}
+			}
+		})
+	}
+}
+
+func TestGetEntryMediaType(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The image style bundle holds the task in a tarball layer.
+	imageBundle, err := test.CreateImage(fmt.Sprintf("%s/image:latest", u.Host), &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	})
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+
+	// The artifact bundle holds the task's raw yaml in a layer of a
+	// custom media type, with a custom config media type.
+	const taskMediaType = "application/vnd.tekton.task.v1beta1+yaml"
+	artifactRef, err := name.ParseReference(fmt.Sprintf("%s/artifact:latest", u.Host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: foo\n"
+	artifact, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer([]byte(content), taskMediaType),
+		Annotations: map[string]string{
+			BundleAnnotationAPIVersion: "tekton.dev/v1beta1",
+			BundleAnnotationKind:       "task",
+			BundleAnnotationName:       "foo",
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create artifact: %v", err)
+	}
+	artifact = mutate.ConfigMediaType(mutate.MediaType(artifact, types.OCIManifestSchema1), "application/vnd.tekton.bundle.config.v1+json")
+	if err := remote.Write(artifactRef, artifact); err != nil {
+		t.Fatalf("failed to push artifact: %v", err)
+	}
+	artifactBundle := artifactRef.String()
+
+	testCases := []struct {
+		name        string
+		bundle      string
+		mediaTypes  []string
+		expectedErr string
+	}{
+		{
+			name:   "image without media types",
+			bundle: imageBundle,
+		},
+		{
+			name:       "image with its layer media type",
+			bundle:     imageBundle,
+			mediaTypes: []string{string(types.DockerLayer)},
+		},
+		{
+			name:   "artifact without media types",
+			bundle: artifactBundle,
+		},
+		{
+			name:       "artifact with its layer media type",
+			bundle:     artifactBundle,
+			mediaTypes: []string{string(types.DockerLayer), taskMediaType},
+		},
+		{
+			name:       "unexpected media type",
+			bundle:     artifactBundle,
+			mediaTypes: []string{string(types.DockerLayer), string(types.OCILayer)},
+			expectedErr: fmt.Sprintf("object with kind: task and name: foo in bundle %s has unexpected media type %s, expected one of: %s, %s, found objects: task/foo (%s)",
+				artifactBundle, taskMediaType, types.DockerLayer, types.OCILayer, taskMediaType),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:          tc.bundle,
+				EntryName:       "foo",
+				Kind:            "task",
+				LayerMediaTypes: tc.mediaTypes,
+			})
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if !strings.Contains(string(resolved.Data()), "name: foo") {
+				t.Errorf("expected the task's yaml but got %q", resolved.Data())
+			}
+		})
+	}
+}
+
+func TestGetEntryMalformedObject(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle holds a valid task, a task that isn't valid yaml and a
+	// pipeline whose layer is empty.
+	ref, err := name.ParseReference(fmt.Sprintf("%s/malformed:latest", u.Host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := empty.Image
+	for _, obj := range []struct {
+		kind, name, content string
+	}{
+		{kind: "task", name: "valid", content: "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: valid\n"},
+		{kind: "task", name: "malformed", content: "apiVersion: tekton.dev/v1beta1\nkind: [Task\n"},
+		{kind: "pipeline", name: "empty", content: ""},
+	} {
+		img, err = mutate.Append(img, mutate.Addendum{
+			Layer: static.NewLayer([]byte(obj.content), types.OCILayer),
+			Annotations: map[string]string{
+				BundleAnnotationAPIVersion: "tekton.dev/v1beta1",
+				BundleAnnotationKind:       obj.kind,
+				BundleAnnotationName:       obj.name,
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create bundle: %v", err)
+		}
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+	bundle := ref.String()
+
+	testCases := []struct {
+		name        string
+		kind        string
+		entryName   string
+		expectedErr string
+	}{
+		{
+			name:      "valid object next to malformed ones",
+			kind:      "task",
+			entryName: "valid",
+		},
+		{
+			name:        "object that isn't valid yaml",
+			kind:        "task",
+			entryName:   "malformed",
+			expectedErr: fmt.Sprintf("object with kind: task and name: malformed in bundle %s is malformed: could not parse object: error converting YAML to JSON: yaml: line 2: did not find expected ',' or ']', valid objects in the bundle: task/valid", bundle),
+		},
+		{
+			name:        "empty object",
+			kind:        "pipeline",
+			entryName:   "empty",
+			expectedErr: fmt.Sprintf("object with kind: pipeline and name: empty in bundle %s is malformed: layer holds no object, valid objects in the bundle: task/valid", bundle),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:    bundle,
+				EntryName: tc.entryName,
+				Kind:      tc.kind,
+			})
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if !strings.Contains(string(resolved.Data()), "name: valid") {
+				t.Errorf("expected the task's yaml but got %q", resolved.Data())
+			}
+		})
+	}
+}
+
+func TestOptionsFromParamsMediaType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		param    string
+		config   map[string]string
+		expected []string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:     "config",
+			config:   map[string]string{ConfigLayerMediaTypes: "application/vnd.tekton.task.v1beta1+yaml, application/vnd.oci.image.layer.v1.tar"},
+			expected: []string{"application/vnd.tekton.task.v1beta1+yaml", "application/vnd.oci.image.layer.v1.tar"},
+		},
+		{
+			name:     "param over config",
+			param:    "application/vnd.tekton.pipeline.v1beta1+yaml",
+			config:   map[string]string{ConfigLayerMediaTypes: "application/vnd.tekton.task.v1beta1+yaml"},
+			expected: []string{"application/vnd.tekton.pipeline.v1beta1+yaml"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			if tc.param != "" {
+				params = append(params, pipelinev1beta1.Param{Name: ParamMediaType, Value: *pipelinev1beta1.NewStructuredValues(tc.param)})
+			}
+
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			opts, err := OptionsFromParams(ctx, params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(tc.expected, opts.LayerMediaTypes); d != "" {
+				t.Errorf("unexpected media types: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestOptionsFromParamsPlatform(t *testing.T) {
+	testCases := []struct {
+		name        string
+		param       string
+		expected    *v1.Platform
+		expectedErr string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:     "os and architecture",
+			param:    "linux/arm64",
+			expected: &v1.Platform{OS: "linux", Architecture: "arm64"},
+		},
+		{
+			name:     "variant",
+			param:    "linux/arm/v7",
+			expected: &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		},
+		{
+			name:     "os version",
+			param:    "windows/amd64:10.0.17763.1234",
+			expected: &v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.1234"},
+		},
+		{
+			name:        "missing architecture",
+			param:       "linux",
+			expectedErr: `invalid platform param "linux": must be of the form os/arch[/variant]`,
+		},
+		{
+			name:        "empty architecture",
+			param:       "linux/",
+			expectedErr: `invalid platform param "linux/": must be of the form os/arch[/variant]`,
+		},
+		{
+			name:        "too many parts",
+			param:       "linux/arm/v7/extra",
+			expectedErr: `invalid platform param "linux/arm/v7/extra": must be of the form os/arch[/variant]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}}
+			if tc.param != "" {
+				params = append(params, pipelinev1beta1.Param{Name: ParamPlatform, Value: *pipelinev1beta1.NewStructuredValues(tc.param)})
+			}
+
+			opts, err := OptionsFromParams(resolverContext(), params)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(tc.expected, opts.Platform); d != "" {
+				t.Errorf("unexpected platform: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryPlatform(t *testing.T) {
+	svr := httptest.NewServer(registry.New())
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each platform's bundle holds a task describing the platform.
+	defaultPlatform := goruntime.GOOS + "/" + goruntime.GOARCH
+	index := v1.ImageIndex(empty.Index)
+	digests := map[string]string{}
+	for _, platform := range []string{"linux/s390x", "linux/arm/v7", defaultPlatform} {
+		task := &pipelinev1beta1.Task{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+			Spec:       pipelinev1beta1.TaskSpec{Description: platform},
+		}
+		digestRef, err := test.CreateImage(fmt.Sprintf("%s/bundle:%s", u.Host, strings.ReplaceAll(platform, "/", "-")), task)
+		if err != nil {
+			t.Fatalf("failed to push bundle: %v", err)
+		}
+		ref, err := name.ParseReference(digestRef)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := remote.Image(ref)
+		if err != nil {
+			t.Fatalf("failed to pull bundle: %v", err)
+		}
+		p, err := v1.ParsePlatform(platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: p},
+		})
+		digests[platform] = digestRef
+	}
+	tagRef := fmt.Sprintf("%s/bundle:latest", u.Host)
+	ref, err := name.ParseReference(tagRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.WriteIndex(ref, index); err != nil {
+		t.Fatalf("failed to push index: %v", err)
+	}
+	indexDigest, err := index.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		platform string
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: defaultPlatform,
+		},
+		{
+			name:     "os and architecture",
+			platform: "linux/s390x",
+			expected: "linux/s390x",
+		},
+		{
+			name:     "variant",
+			platform: "linux/arm/v7",
+			expected: "linux/arm/v7",
+		},
+		{
+			name:     "any variant",
+			platform: "linux/arm",
+			expected: "linux/arm/v7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := RequestOptions{
+				Bundle:         tagRef,
+				EntryName:      "foo",
+				Kind:           "task",
+				ExpectedDigest: indexDigest.String(),
+			}
+			if tc.platform != "" {
+				opts.Platform, err = v1.ParsePlatform(tc.platform)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, opts)
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if !strings.Contains(string(resolved.Data()), "description: "+tc.expected) {
+				t.Errorf("expected the task for platform %s but got %q", tc.expected, resolved.Data())
+			}
+			if d := cmp.Diff(digests[tc.expected], resolved.Annotations()[ResolverAnnotationResolvedBundle]); d != "" {
+				t.Errorf("unexpected resolved bundle: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+
+	_, err = GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+		Bundle:    tagRef,
+		EntryName: "foo",
+		Kind:      "task",
+		Platform:  &v1.Platform{OS: "windows", Architecture: "amd64"},
+	})
+	var notFound *resolutioncommon.ResolutionNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected a not found error but got %v", err)
+	}
+	expectedErr := fmt.Sprintf("bundle %s has no manifest for platform windows/amd64, available platforms: linux/s390x, linux/arm/v7, %s", tagRef, defaultPlatform)
+	if d := cmp.Diff(expectedErr, notFound.Original.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestValidateParamsReferrers(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		value       string
+		expectedErr string
+	}{
+		{value: "true"},
+		{value: "false"},
+		{value: "maybe", expectedErr: `parameter "referrers" must be true or false: strconv.ParseBool: parsing "maybe": invalid syntax`},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues("task"),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}, {
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("baz"),
+			}, {
+				Name:  ParamReferrers,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.value),
+			}}
+			err := resolver.ValidateParams(resolverContext(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetEntryReferrers(t *testing.T) {
+	attestation := Referrer{
+		Digest:       "sha256:" + strings.Repeat("a", 64),
+		MediaType:    string(types.OCIManifestSchema1),
+		ArtifactType: "application/vnd.in-toto+json",
+		Size:         1234,
+		Annotations:  map[string]string{"org.opencontainers.image.created": "2022-10-01T00:00:00Z"},
+	}
+	sbom := Referrer{
+		Digest:       "sha256:" + strings.Repeat("b", 64),
+		MediaType:    string(types.OCIManifestSchema1),
+		ArtifactType: "application/spdx+json",
+		Size:         5678,
+	}
+	referrersIndex := func(referrers ...Referrer) string {
+		t.Helper()
+		data, err := json.Marshal(struct {
+			SchemaVersion int        `json:"schemaVersion"`
+			MediaType     string     `json:"mediaType"`
+			Manifests     []Referrer `json:"manifests"`
+		}{2, string(types.OCIImageIndex), referrers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	testCases := []struct {
+		name string
+		// referrers is the response of the referrers API, which isn't
+		// supported when it's empty.
+		referrers string
+		status    int
+		// tagSchema is whether an artifact is attached to the bundle with
+		// the referrers tag schema, in which case it is expected to be
+		// listed.
+		tagSchema     bool
+		disabled      bool
+		expected      string
+		expectedError string
+	}{
+		{
+			name:      "referrers API",
+			referrers: referrersIndex(attestation, sbom),
+			expected: `[{"digest":"sha256:` + strings.Repeat("a", 64) + `","mediaType":"application/vnd.oci.image.manifest.v1+json","artifactType":"application/vnd.in-toto+json","size":1234,"annotations":{"org.opencontainers.image.created":"2022-10-01T00:00:00Z"}},` +
+				`{"digest":"sha256:` + strings.Repeat("b", 64) + `","mediaType":"application/vnd.oci.image.manifest.v1+json","artifactType":"application/spdx+json","size":5678}]`,
+		},
+		{
+			name:      "no referrers",
+			referrers: referrersIndex(),
+			expected:  `[]`,
+		},
+		{
+			name:      "referrers tag schema",
+			tagSchema: true,
+		},
+		{
+			name: "referrers unsupported",
+		},
+		{
+			name:      "not requested",
+			referrers: referrersIndex(attestation),
+			disabled:  true,
+		},
+		{
+			name:          "registry error",
+			status:        http.StatusInternalServerError,
+			expectedError: "could not fetch the referrers of bundle",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var referrersRequested bool
+			reg := registry.New()
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/v2/bundle/referrers/") {
+					referrersRequested = true
+					switch {
+					case tc.status != 0:
+						w.WriteHeader(tc.status)
+						return
+					case tc.referrers != "":
+						w.Header().Set("Content-Type", string(types.OCIImageIndex))
+						fmt.Fprint(w, tc.referrers)
+						return
+					}
+				}
+				reg.ServeHTTP(w, r)
+			}))
+			defer svr.Close()
+			u, err := url.Parse(svr.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := &pipelinev1beta1.Task{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+			}
+			tagRef := fmt.Sprintf("%s/bundle:latest", u.Host)
+			digestRef, err := test.CreateImage(tagRef, task)
+			if err != nil {
+				t.Fatalf("failed to push bundle: %v", err)
+			}
+			expected := tc.expected
+			if tc.tagSchema {
+				// The registry checks that the manifests an index lists
+				// exist, so the attached artifact is pushed first.
+				artifact, err := random.Image(10, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				repo, err := name.NewRepository(u.Host + "/bundle")
+				if err != nil {
+					t.Fatal(err)
+				}
+				artifactDigest, err := artifact.Digest()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := remote.Write(repo.Digest(artifactDigest.String()), artifact); err != nil {
+					t.Fatalf("failed to push artifact: %v", err)
+				}
+				desc, err := partial.Descriptor(artifact)
+				if err != nil {
+					t.Fatal(err)
+				}
+				attached := Referrer{
+					Digest:       desc.Digest.String(),
+					MediaType:    string(desc.MediaType),
+					ArtifactType: "application/spdx+json",
+					Size:         desc.Size,
+				}
+				digest := strings.SplitN(digestRef, "@", 2)[1]
+				if err := remote.Put(repo.Tag(strings.Replace(digest, ":", "-", 1)), rawIndex(referrersIndex(attached))); err != nil {
+					t.Fatalf("failed to push referrers index: %v", err)
+				}
+				listed, err := json.Marshal([]Referrer{attached})
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected = string(listed)
+			}
+
+			resolved, err := GetEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+				Bundle:    tagRef,
+				EntryName: "foo",
+				Kind:      "task",
+				Referrers: !tc.disabled,
+			})
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q but got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error getting entry: %v", err)
+			}
+			if tc.disabled && referrersRequested {
+				t.Errorf("expected the referrers not to be requested")
+			}
+			got, ok := resolved.Annotations()[ResolverAnnotationReferrers]
+			if expected == "" {
+				if ok {
+					t.Errorf("expected no referrers annotation but got %s", got)
+				}
+				return
+			}
+			if d := cmp.Diff(expected, got); d != "" {
+				t.Errorf("unexpected referrers: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+// rawIndex is an image index pushed as is.
+type rawIndex string
+
+func (i rawIndex) RawManifest() ([]byte, error) {
+	return []byte(i), nil
+}
+
+func (i rawIndex) MediaType() (types.MediaType, error) {
+	return types.OCIImageIndex, nil
+}
+
+func TestGetEntryCache(t *testing.T) {
+	var requests []string
+	reg := registry.New()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		reg.ServeHTTP(w, r)
+	}))
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	tagRef := fmt.Sprintf("%s/bundle:latest", u.Host)
+	digestRef, err := test.CreateImage(tagRef, task)
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+
+	cache := &bundleCache{dir: t.TempDir(), maxSize: defaultCacheMaxSize.Value()}
+	getEntry := func(ref string) []byte {
+		t.Helper()
+		resolved, err := getEntry(context.Background(), authn.DefaultKeychain, RequestOptions{
+			Bundle:    ref,
+			EntryName: "foo",
+			Kind:      "task",
+		}, cache)
+		if err != nil {
+			t.Fatalf("unexpected error getting entry from %s: %v", ref, err)
+		}
+		return resolved.Data()
+	}
+
+	requests = nil
+	expected := getEntry(digestRef)
+	if len(requests) == 0 {
+		t.Fatalf("expected requests to the registry on a cache miss")
+	}
+	// The bundle is resolved from the entry written to the cache rather
+	// than downloaded again.
+	seen := map[string]bool{}
+	for _, r := range requests {
+		if strings.Contains(r, "/blobs/") && seen[r] {
+			t.Errorf("expected each blob to be downloaded once on a cache miss but got %v", requests)
+			break
+		}
+		seen[r] = true
+	}
+
+	for _, ref := range []string{digestRef, tagRef} {
+		requests = nil
+		if d := cmp.Diff(expected, getEntry(ref)); d != "" {
+			t.Errorf("unexpected data from cache for %s: %s", ref, diff.PrintWantGot(d))
+		}
+		for _, r := range requests {
+			if !strings.HasPrefix(r, http.MethodHead+" ") && r != http.MethodGet+" /v2/" {
+				t.Errorf("expected only %s to be revalidated when cached but got %v", ref, requests)
+				break
+			}
+		}
+	}
+}
+
+// basicAuthKeychain authenticates to every registry with a username
+// and password.
+type basicAuthKeychain struct {
+	username, password string
+}
+
+func (k basicAuthKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	return &authn.Basic{Username: k.username, Password: k.password}, nil
+}
+
+func TestGetEntryCacheRequiresAccess(t *testing.T) {
+	// Authentication is only required once the bundle has been pushed.
+	var requireAuth int32
+	reg := registry.New()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&requireAuth) == 1 {
+			if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer svr.Close()
+	u, err := url.Parse(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+	}
+	digestRef, err := test.CreateImage(fmt.Sprintf("%s/bundle:latest", u.Host), task)
+	if err != nil {
+		t.Fatalf("failed to push bundle: %v", err)
+	}
+	atomic.StoreInt32(&requireAuth, 1)
+
+	cache := &bundleCache{dir: t.TempDir(), maxSize: defaultCacheMaxSize.Value()}
+	opts := RequestOptions{
+		Bundle:    digestRef,
+		EntryName: "foo",
+		Kind:      "task",
+	}
+	if _, err := getEntry(context.Background(), basicAuthKeychain{username: "user", password: "secret"}, opts, cache); err != nil {
+		t.Fatalf("unexpected error getting entry with credentials: %v", err)
+	}
+
+	for _, keychain := range []authn.Keychain{authn.DefaultKeychain, basicAuthKeychain{username: "user", password: "wrong"}} {
+		if _, err := getEntry(context.Background(), keychain, opts, cache); err == nil {
+			t.Errorf("expected the cached bundle not to be served without valid credentials")
+		}
+	}
+}
+
+func TestBundleCacheEviction(t *testing.T) {
+	var imgs []v1.Image
+	for i := 0; i < 3; i++ {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imgs = append(imgs, img)
+	}
+
+	cache := &bundleCache{dir: t.TempDir(), maxSize: defaultCacheMaxSize.Value()}
+	putImage(t, cache, imgs[0])
+	entrySize, err := dirSize(cache.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave room for two entries only.
+	cache.maxSize = 2*entrySize + entrySize/2
+
+	for _, img := range imgs[1:] {
+		// Make sure the modification times of the entries differ.
+		time.Sleep(10 * time.Millisecond)
+		putImage(t, cache, img)
+	}
+
+	for i, img := range imgs {
+		digest, err := img.Digest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, release, ok := cache.get(digest)
+		if ok {
+			release()
+		}
+		if expected := i != 0; ok != expected {
+			t.Errorf("expected image %d cached to be %t but was %t", i, expected, ok)
+		}
+	}
+}
+
+// putImage adds the image to the cache, failing the test if it can't.
+func putImage(t *testing.T, cache *bundleCache, img v1.Image) {
+	t.Helper()
+	_, release, err := cache.put(img)
+	if err != nil {
+		t.Fatalf("unexpected error caching image: %v", err)
+	}
+	release()
+}
+
+// blockingImage is an image whose layers can't be listed until it is
+// unblocked, as if they were slow to download.
+type blockingImage struct {
+	v1.Image
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (i *blockingImage) Layers() ([]v1.Layer, error) {
+	close(i.started)
+	<-i.unblock
+	return i.Image.Layers()
+}
+
+func TestBundleCachePutDoesNotBlockReads(t *testing.T) {
+	imgs := make([]v1.Image, 2)
+	for i := range imgs {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imgs[i] = img
+	}
+	digest, err := imgs[0].Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := &bundleCache{dir: t.TempDir(), maxSize: defaultCacheMaxSize.Value()}
+	putImage(t, cache, imgs[0])
+
+	slow := &blockingImage{Image: imgs[1], started: make(chan struct{}), unblock: make(chan struct{})}
+	written := make(chan error)
+	go func() {
+		_, release, err := cache.put(slow)
+		release()
+		written <- err
+	}()
+	<-slow.started
+
+	read := make(chan bool, 1)
+	go func() {
+		_, release, ok := cache.get(digest)
+		if ok {
+			release()
+		}
+		read <- ok
+	}()
+	select {
+	case ok := <-read:
+		if !ok {
+			t.Errorf("expected image to be cached")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the cache to be read while another image is downloaded")
+	}
+	close(slow.unblock)
+	if err := <-written; err != nil {
+		t.Fatalf("unexpected error caching image: %v", err)
+	}
+}
+
+func TestBundleCacheGetBlocksWrites(t *testing.T) {
+	imgs := make([]v1.Image, 2)
+	for i := range imgs {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imgs[i] = img
+	}
+	digest, err := imgs[0].Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := &bundleCache{dir: t.TempDir(), maxSize: defaultCacheMaxSize.Value()}
+	putImage(t, cache, imgs[0])
+	img, release, ok := cache.get(digest)
+	if !ok {
+		t.Fatal("expected image to be cached")
+	}
+	// Evict the cached image as soon as anything else is cached.
+	cache.maxSize = 1
+
+	written := make(chan error)
+	go func() {
+		_, release, err := cache.put(imgs[1])
+		release()
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		t.Fatalf("expected the cache not to be written to while an image is read but put returned %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := img.Layers(); err != nil {
+		t.Errorf("unexpected error reading cached image: %v", err)
+	}
+	release()
+	if err := <-written; err != nil {
+		t.Fatalf("unexpected error caching image: %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	task := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+		Spec: pipelinev1beta1.TaskSpec{
+			Steps: []pipelinev1beta1.Step{{Name: "build", Image: "golang:1.18"}},
+		},
+	}
+	pipeline := &pipelinev1beta1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Pipeline"},
+		Spec: pipelinev1beta1.PipelineSpec{
+			Tasks: []pipelinev1beta1.PipelineTask{{Name: "build", TaskRef: &pipelinev1beta1.TaskRef{Name: "foo"}}},
+		},
+	}
+	oldTask := &pipelinev1beta1.Task{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Task"},
+		Spec: pipelinev1beta1.TaskSpec{
+			Steps: []pipelinev1beta1.Step{{Name: "build", Image: "golang:1.17"}},
+		},
+	}
+	reg, refs := frtesting.NewFakeRegistry(t, frtesting.FakeBundle{
+		Repository: "catalog",
+		Tags:       []string{"v0"},
+		Objects:    []runtime.Object{oldTask},
+	}, frtesting.FakeBundle{
+		Repository: "catalog",
+		Tags:       []string{"v1", "latest"},
+		Objects:    []runtime.Object{task, pipeline},
+	})
+
+	for _, tc := range []struct {
+		name           string
+		bundle         string
+		kind           string
+		entry          string
+		expected       runtime.Object
+		expectedBundle string
+		expectedErr    string
+	}{{
+		name:           "task by tag",
+		bundle:         reg.Reference("catalog", "latest"),
+		kind:           "task",
+		entry:          "foo",
+		expected:       task,
+		expectedBundle: refs[1],
+	}, {
+		name:           "pipeline by another tag of the same bundle",
+		bundle:         reg.Reference("catalog", "v1"),
+		kind:           "pipeline",
+		entry:          "bar",
+		expected:       pipeline,
+		expectedBundle: refs[1],
+	}, {
+		name:           "task by digest",
+		bundle:         refs[0],
+		kind:           "task",
+		entry:          "foo",
+		expected:       oldTask,
+		expectedBundle: refs[0],
+	}, {
+		name:           "task by older tag",
+		bundle:         reg.Reference("catalog", "v0"),
+		kind:           "task",
+		entry:          "foo",
+		expected:       oldTask,
+		expectedBundle: refs[0],
+	}, {
+		name:        "entry not in bundle",
+		bundle:      reg.Reference("catalog", "v0"),
+		kind:        "pipeline",
+		entry:       "bar",
+		expectedErr: "could not find object in image with kind: pipeline and name: bar, available objects: task/foo",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{kubeClientSet: fakek8s.NewSimpleClientset(&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "foo"},
+			})}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo")
+			params := []pipelinev1beta1.Param{{
+				Name:  ParamBundle,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.bundle),
+			}, {
+				Name:  ParamName,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.entry),
+			}, {
+				Name:  ParamKind,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.kind),
+			}, {
+				Name:  ParamServiceAccount,
+				Value: *pipelinev1beta1.NewStructuredValues("default"),
+			}}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resolved, err := resolver.Resolve(ctx, params)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			expectedData, err := yaml.Marshal(tc.expected)
+			if err != nil {
+				t.Fatalf("failed to marshal expected object: %v", err)
+			}
+			if d := cmp.Diff(string(expectedData), string(resolved.Data())); d != "" {
+				t.Errorf("unexpected resolved data: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedBundle, resolved.Annotations()[ResolverAnnotationResolvedBundle]); d != "" {
+				t.Errorf("unexpected resolved bundle: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
 func TestResolveDisabled(t *testing.T) {
 	resolver := Resolver{}
 
@@ -169,6 +3454,69 @@ func TestResolveDisabled(t *testing.T) {
 	}
 }
 
+func TestCheckHealth(t *testing.T) {
+	reachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer reachable.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	reachableHost := strings.TrimPrefix(reachable.URL, "http://")
+	brokenHost := strings.TrimPrefix(broken.URL, "http://")
+
+	for _, tc := range []struct {
+		name        string
+		registries  string
+		expectedErr string
+	}{{
+		name: "no registries",
+	}, {
+		name:       "reachable registry",
+		registries: reachableHost,
+	}, {
+		name:        "unhealthy registry",
+		registries:  reachableHost + ", " + brokenHost,
+		expectedErr: fmt.Sprintf("registry %s responded with status 503", brokenHost),
+	}, {
+		name:        "invalid registry",
+		registries:  "not a registry",
+		expectedErr: `invalid registry "not a registry" in health-check-registries config`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+				ConfigHealthCheckRegistries: tc.registries,
+			})
+			err := resolver.CheckHealth(ctx)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tc.expectedErr) {
+				t.Fatalf("expected error starting with %q but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckHealthDisabled(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.CheckHealth(context.Background())
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
 func resolverContext() context.Context {
 	return frtesting.ContextWithBundlesResolverEnabled(context.Background())
 }
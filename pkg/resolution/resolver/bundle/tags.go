@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// tagPatternRegex matches the tag param when it's a tag or a tag
+// pattern: the characters allowed in tags along with the * and ?
+// wildcards.
+var tagPatternRegex = regexp.MustCompile(`^[A-Za-z0-9_.*?-]{1,128}$`)
+
+// isTagPattern returns true if the given tag param selects a tag among
+// the registry's tags, either with wildcards or as a range of versions,
+// rather than naming a single tag.
+func isTagPattern(tag string) bool {
+	return strings.ContainsAny(tag, "*?") || framework.IsVersionConstraint(tag)
+}
+
+// validateTag returns an error if the given tag param is neither a tag,
+// a tag pattern nor a range of versions that can be parsed.
+func validateTag(tag string) error {
+	if framework.IsVersionConstraint(tag) {
+		if _, err := framework.ParseVersionConstraint(tag); err != nil {
+			return fmt.Errorf("invalid version constraint: %w", err)
+		}
+		return nil
+	}
+	if !tagPatternRegex.MatchString(tag) {
+		return fmt.Errorf("must be a tag, a tag pattern such as \"v1.*\" or a range of versions such as \"^1.2\" or \">=1.0 <2.0\"")
+	}
+	return nil
+}
+
+// selectTag lists the tags of the repository and returns the one that
+// best matches the tag pattern: the highest version satisfying a range
+// of versions, or the highest version among the tags matching a pattern
+// with wildcards. When none of the tags matching a pattern are versions
+// the last of them in lexical order is selected, e.g. the latest of
+// date-stamped tags.
+func selectTag(ctx context.Context, keychain authn.Keychain, repository, pattern string) (_ string, err error) {
+	ctx, span := framework.StartSpan(ctx, "bundle.ListTags")
+	defer func() { framework.EndSpan(span, err) }()
+
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return "", fmt.Errorf("%s is an unparseable repository: %w", repository, err)
+	}
+	tags, err := remote.List(repo, remote.WithAuthFromKeychain(keychain), remote.WithContext(ctx), remote.WithUserAgent(framework.UserAgent(ctx)))
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && tagListingUnsupported(terr) {
+			return "", fmt.Errorf("the registry of %s doesn't support listing tags, which the %s param %q requires, use an exact tag instead: %w", repository, ParamTag, pattern, err)
+		}
+		return "", fmt.Errorf("could not list the tags of %s: %w", repository, err)
+	}
+
+	var tag string
+	var ok bool
+	if framework.IsVersionConstraint(pattern) {
+		constraints, err := framework.ParseVersionConstraint(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s param %q: %w", ParamTag, pattern, err)
+		}
+		tag, ok = framework.SelectVersion(constraints, tags)
+	} else {
+		var matches []string
+		for _, t := range tags {
+			if matched, _ := path.Match(pattern, t); matched {
+				matches = append(matches, t)
+			}
+		}
+		if tag, ok = framework.SelectVersion(nil, matches); !ok && len(matches) > 0 {
+			sort.Strings(matches)
+			tag, ok = matches[len(matches)-1], true
+		}
+	}
+	if !ok {
+		return "", &common.ResolutionNotFoundError{
+			Resource: repository,
+			Original: fmt.Errorf("no tag of %s matches %s param %q", repository, ParamTag, pattern),
+		}
+	}
+	return tag, nil
+}
+
+// tagListingUnsupported returns true if the error returned when listing
+// a repository's tags means that the registry doesn't support it, rather
+// than e.g. that the repository doesn't exist.
+func tagListingUnsupported(err *transport.Error) bool {
+	for _, d := range err.Errors {
+		switch d.Code {
+		case transport.UnsupportedErrorCode:
+			return true
+		case transport.NameUnknownErrorCode:
+			return false
+		}
+	}
+	switch err.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
@@ -18,6 +18,8 @@ package cluster
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -29,6 +31,7 @@ import (
 	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
 	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/logging"
 	"sigs.k8s.io/yaml"
@@ -75,22 +78,69 @@ func (r *Resolver) GetSelector(_ context.Context) map[string]string {
 	}
 }
 
+var _ framework.ParamTracer = &Resolver{}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver, with the
+// kind and namespace defaulting to those set in the resolver's config.
+func (r *Resolver) GetParamSchema(ctx context.Context) []framework.ParamSchema {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	return []framework.ParamSchema{{
+		Name:        KindParam,
+		Description: "The kind of the resource.",
+		Required:    conf[DefaultKindKey] == "",
+		Enum:        []string{"task", "pipeline"},
+		Default:     conf[DefaultKindKey],
+	}, {
+		Name:        NameParam,
+		Description: "The name of the resource.",
+		Required:    true,
+	}, {
+		Name:        NamespaceParam,
+		Description: "The namespace of the resource.",
+		Required:    conf[DefaultNamespaceKey] == "",
+		Default:     conf[DefaultNamespaceKey],
+	}}
+}
+
+// TracedParams returns the params recorded on the spans of resolutions,
+// which identify the requested resource.
+func (r *Resolver) TracedParams(context.Context) []string {
+	return []string{KindParam, NamespaceParam, NameParam}
+}
+
 // ValidateParams returns an error if the given parameter map is not
 // valid for a resource request targeting the cluster resolver.
 func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
 	if r.isDisabled(ctx) {
-		return errors.New(disabledError)
+		return resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
+	}
+
+	if err := framework.ValidateNoVariableReferences(params); err != nil {
+		return err
 	}
 
 	_, err := populateParamsWithDefaults(ctx, params)
 	return err
 }
 
+// permissionError returns the PermissionDeniedError for a Forbidden
+// error getting a resource, describing the RBAC the resolver's service
+// account is missing to read resources of its kind from its namespace.
+func permissionError(params map[string]string) error {
+	kind, namespace := params[KindParam], params[NamespaceParam]
+	return &resolutioncommon.PermissionDeniedError{
+		Resource: fmt.Sprintf("%s %s/%s", kind, namespace, params[NameParam]),
+		Original: fmt.Errorf("the resolver's service account is not allowed to get %ss.tekton.dev in namespace %s, grant it get access to them with a Role and RoleBinding in that namespace", kind, namespace),
+	}
+}
+
 // Resolve performs the work of fetching a resource from a namespace with the given
 // parameters.
 func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
 	if r.isDisabled(ctx) {
-		return nil, errors.New(disabledError)
+		return nil, resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
 	}
 
 	logger := logging.FromContext(ctx)
@@ -100,7 +150,6 @@ func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Par
 		logger.Infof("cluster resolver parameter(s) invalid: %v", err)
 		return nil, err
 	}
-
 	var data []byte
 
 	switch params[KindParam] {
@@ -108,6 +157,15 @@ func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Par
 		task, err := r.pipelineClientSet.TektonV1beta1().Tasks(params[NamespaceParam]).Get(ctx, params[NameParam], metav1.GetOptions{})
 		if err != nil {
 			logger.Infof("failed to load task %s from namespace %s: %v", params[NameParam], params[NamespaceParam], err)
+			if apierrors.IsForbidden(err) {
+				return nil, permissionError(params)
+			}
+			if apierrors.IsNotFound(err) {
+				return nil, &resolutioncommon.ResolutionNotFoundError{
+					Resource: fmt.Sprintf("%s %s/%s", params[KindParam], params[NamespaceParam], params[NameParam]),
+					Original: err,
+				}
+			}
 			return nil, err
 		}
 		task.Kind = "Task"
@@ -121,6 +179,15 @@ func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Par
 		pipeline, err := r.pipelineClientSet.TektonV1beta1().Pipelines(params[NamespaceParam]).Get(ctx, params[NameParam], metav1.GetOptions{})
 		if err != nil {
 			logger.Infof("failed to load pipeline %s from namespace %s: %v", params[NameParam], params[NamespaceParam], err)
+			if apierrors.IsForbidden(err) {
+				return nil, permissionError(params)
+			}
+			if apierrors.IsNotFound(err) {
+				return nil, &resolutioncommon.ResolutionNotFoundError{
+					Resource: fmt.Sprintf("%s %s/%s", params[KindParam], params[NamespaceParam], params[NameParam]),
+					Original: err,
+				}
+			}
 			return nil, err
 		}
 		pipeline.Kind = "Pipeline"
@@ -175,9 +242,18 @@ func (r *ResolvedClusterResource) Data() []byte {
 
 // Annotations returns the metadata that accompanies the resource fetched from the cluster.
 func (r *ResolvedClusterResource) Annotations() map[string]string {
+	sum := sha256.Sum256(r.Content)
 	return map[string]string{
 		ResourceNameAnnotation:      r.Name,
 		ResourceNamespaceAnnotation: r.Namespace,
+		resolutioncommon.AnnotationKeyProvenance: resolutioncommon.Provenance{
+			ResolverType: LabelValueClusterResolverType,
+			Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+			Coordinates: map[string]string{
+				"name":      r.Name,
+				"namespace": r.Namespace,
+			},
+		}.AnnotationValue(),
 	}
 }
 
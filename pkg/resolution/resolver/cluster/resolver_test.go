@@ -19,7 +19,9 @@ package cluster
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"testing"
 	"time"
@@ -28,6 +30,8 @@ import (
 	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	fakepipelineclientset "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/fake"
+	fakepipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client/fake"
 	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
 	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
@@ -35,7 +39,10 @@ import (
 	"github.com/tektoncd/pipeline/test"
 	"github.com/tektoncd/pipeline/test/diff"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/system"
@@ -240,6 +247,7 @@ func TestResolve(t *testing.T) {
 		namespace         string
 		allowedNamespaces string
 		blockedNamespaces string
+		deniedNamespace   string
 		expectedStatus    *v1beta1.ResolutionRequestStatus
 		expectedErr       error
 	}{
@@ -342,12 +350,33 @@ func TestResolve(t *testing.T) {
 				ResolutionRequestKey: "foo/rr",
 				Message:              "access to specified namespace other-ns is blocked",
 			},
+		}, {
+			name:            "no permission in namespace",
+			kind:            "task",
+			resourceName:    exampleTask.Name,
+			namespace:       exampleTask.Namespace,
+			deniedNamespace: exampleTask.Namespace,
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{
+					Conditions: duckv1.Conditions{{
+						Type:   apis.ConditionSucceeded,
+						Status: corev1.ConditionFalse,
+						Reason: resolutioncommon.ReasonResolutionFailed,
+					}},
+				},
+			},
+			expectedErr: &resolutioncommon.ErrorGettingResource{
+				ResolverName: ClusterResolverName,
+				Key:          "foo/rr",
+				Original:     errors.New("the resolver's service account is not allowed to get tasks.tekton.dev in namespace task-ns, grant it get access to them with a Role and RoleBinding in that namespace"),
+			},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx, _ := ttesting.SetupFakeContext(t)
+			fakepipelineclient.Get(ctx).PrependReactor("get", "tasks", forbiddenReactor(tc.deniedNamespace))
 
 			request := createRequest(tc.kind, tc.resourceName, tc.namespace)
 
@@ -403,6 +432,19 @@ func TestResolve(t *testing.T) {
 					} else {
 						expectedStatus.Annotations[ResourceNamespaceAnnotation] = defaultNS
 					}
+					content, err := base64.StdEncoding.DecodeString(expectedStatus.Data)
+					if err != nil {
+						t.Fatalf("failed to decode expected data: %v", err)
+					}
+					sum := sha256.Sum256(content)
+					expectedStatus.Annotations[resolutioncommon.AnnotationKeyProvenance] = resolutioncommon.Provenance{
+						ResolverType: LabelValueClusterResolverType,
+						Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+						Coordinates: map[string]string{
+							"name":      expectedStatus.Annotations[ResourceNameAnnotation],
+							"namespace": expectedStatus.Annotations[ResourceNamespaceAnnotation],
+						},
+					}.AnnotationValue()
 				} else {
 					expectedStatus.Status.Conditions[0].Message = tc.expectedErr.Error()
 				}
@@ -413,6 +455,66 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+func TestResolveForbidden(t *testing.T) {
+	for _, tc := range []struct {
+		kind        string
+		expectedErr string
+	}{{
+		kind:        "task",
+		expectedErr: "the resolver's service account is not allowed to get tasks.tekton.dev in namespace pipeline-ns, grant it get access to them with a Role and RoleBinding in that namespace",
+	}, {
+		kind:        "pipeline",
+		expectedErr: "the resolver's service account is not allowed to get pipelines.tekton.dev in namespace pipeline-ns, grant it get access to them with a Role and RoleBinding in that namespace",
+	}} {
+		t.Run(tc.kind, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{})
+			pipelineClient := fakepipelineclientset.NewSimpleClientset()
+			pipelineClient.PrependReactor("get", tc.kind+"s", forbiddenReactor("pipeline-ns"))
+			resolver := &Resolver{pipelineClientSet: pipelineClient}
+			params := []pipelinev1beta1.Param{{
+				Name:  KindParam,
+				Value: *pipelinev1beta1.NewStructuredValues(tc.kind),
+			}, {
+				Name:  NameParam,
+				Value: *pipelinev1beta1.NewStructuredValues("example"),
+			}, {
+				Name:  NamespaceParam,
+				Value: *pipelinev1beta1.NewStructuredValues("pipeline-ns"),
+			}}
+			// Access is only known once the resource is fetched.
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			_, err := resolver.Resolve(ctx, params)
+			if err == nil {
+				t.Fatalf("expected permission error but got none")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+			var denied *resolutioncommon.PermissionDeniedError
+			if !errors.As(err, &denied) {
+				t.Errorf("expected a PermissionDeniedError but got %#v", err)
+			}
+			if d := cmp.Diff(tc.kind+" pipeline-ns/example", denied.Resource); d != "" {
+				t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+// forbiddenReactor forbids getting resources from the denied namespace
+// and passes every other get on.
+func forbiddenReactor(deniedNamespace string) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if deniedNamespace == "" || action.GetNamespace() != deniedNamespace {
+			return false, nil, nil
+		}
+		get := action.(k8stesting.GetAction)
+		return true, nil, apierrors.NewForbidden(get.GetResource().GroupResource(), get.GetName(), errors.New("denied"))
+	}
+}
+
 func createRequest(kind, name, namespace string) *v1beta1.ResolutionRequest {
 	rr := &v1beta1.ResolutionRequest{
 		TypeMeta: metav1.TypeMeta{
@@ -0,0 +1,288 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	disabledError = "cannot handle resolution request, enable-configmap-resolver feature flag not true"
+
+	// LabelValueConfigMapResolverType is the value to use for the
+	// resolution.tekton.dev/type label on resource requests
+	LabelValueConfigMapResolverType string = "configmap"
+
+	// ConfigMapResolverName is the name that the configmap resolver should be
+	// associated with
+	ConfigMapResolverName string = "ConfigMap"
+
+	configMapName = "configmap-resolver-config"
+)
+
+var _ framework.Resolver = &Resolver{}
+
+// Resolver implements a framework.Resolver that can fetch resources
+// stored under a key of a ConfigMap.
+type Resolver struct {
+	kubeClientSet kubernetes.Interface
+}
+
+// Initialize performs any setup required by the configmap resolver.
+func (r *Resolver) Initialize(ctx context.Context) error {
+	r.kubeClientSet = kubeclient.Get(ctx)
+	return nil
+}
+
+// GetName returns the string name that the configmap resolver should be
+// associated with.
+func (r *Resolver) GetName(_ context.Context) string {
+	return ConfigMapResolverName
+}
+
+// GetSelector returns the labels that resource requests are required to have for
+// the configmap resolver to process them.
+func (r *Resolver) GetSelector(_ context.Context) map[string]string {
+	return map[string]string{
+		resolutioncommon.LabelKeyResolverType: LabelValueConfigMapResolverType,
+	}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver, with the
+// namespace defaulting to the one set in the resolver's config.
+func (r *Resolver) GetParamSchema(ctx context.Context) []framework.ParamSchema {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	return []framework.ParamSchema{{
+		Name:        NameParam,
+		Description: "The name of the ConfigMap.",
+		Required:    true,
+	}, {
+		Name:        NamespaceParam,
+		Description: "The namespace of the ConfigMap.",
+		Required:    conf[DefaultNamespaceKey] == "",
+		Default:     conf[DefaultNamespaceKey],
+	}, {
+		Name:        KeyParam,
+		Description: "The key of the ConfigMap holding the resource.",
+		Required:    true,
+	}}
+}
+
+// ValidateParams returns an error if the given parameter map is not
+// valid for a resource request targeting the configmap resolver.
+func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if r.isDisabled(ctx) {
+		return resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
+	}
+
+	_, err := populateParamsWithDefaults(ctx, params)
+	return err
+}
+
+// Resolve performs the work of fetching the value of a key of a
+// ConfigMap with the given parameters.
+func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	if r.isDisabled(ctx) {
+		return nil, resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
+	}
+
+	logger := logging.FromContext(ctx)
+
+	params, err := populateParamsWithDefaults(ctx, origParams)
+	if err != nil {
+		logger.Infof("configmap resolver parameter(s) invalid: %v", err)
+		return nil, err
+	}
+	name, namespace, key := params[NameParam], params[NamespaceParam], params[KeyParam]
+
+	cm, err := r.kubeClientSet.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		logger.Infof("failed to load ConfigMap %s from namespace %s: %v", name, namespace, err)
+		if apierrors.IsForbidden(err) {
+			return nil, permissionError(namespace)
+		}
+		if apierrors.IsNotFound(err) {
+			return nil, &resolutioncommon.ResolutionNotFoundError{
+				Resource: fmt.Sprintf("ConfigMap %s/%s", namespace, name),
+				Original: fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err),
+			}
+		}
+		return nil, err
+	}
+
+	var data []byte
+	if value, ok := cm.Data[key]; ok {
+		data = []byte(value)
+	} else if value, ok := cm.BinaryData[key]; ok {
+		data = value
+	} else {
+		return nil, &resolutioncommon.ResolutionNotFoundError{
+			Resource: fmt.Sprintf("key %s of ConfigMap %s/%s", key, namespace, name),
+			Original: fmt.Errorf("ConfigMap %s/%s has no key %q", namespace, name, key),
+		}
+	}
+
+	return &ResolvedConfigMapResource{
+		Content:   data,
+		Name:      name,
+		Namespace: namespace,
+		Key:       key,
+	}, nil
+}
+
+// permissionError describes the RBAC the resolver's service account is
+// missing to read ConfigMaps from a namespace.
+func permissionError(namespace string) error {
+	return fmt.Errorf("the resolver's service account is not allowed to get configmaps in namespace %s, grant it get access to them with a Role and RoleBinding in that namespace", namespace)
+}
+
+var _ framework.ConfigWatcher = &Resolver{}
+
+// GetConfigName returns the name of the configmap resolver's configmap.
+func (r *Resolver) GetConfigName(context.Context) string {
+	return configMapName
+}
+
+func (r *Resolver) isDisabled(ctx context.Context) bool {
+	cfg := resolverconfig.FromContextOrDefaults(ctx)
+	if cfg.FeatureFlags.EnableConfigMapResolver {
+		return false
+	}
+
+	return true
+}
+
+// ResolvedConfigMapResource implements framework.ResolvedResource and returns
+// the value of the ConfigMap's key and an annotation map for any metadata.
+type ResolvedConfigMapResource struct {
+	Content   []byte
+	Name      string
+	Namespace string
+	Key       string
+}
+
+var _ framework.ResolvedResource = &ResolvedConfigMapResource{}
+
+// Data returns the bytes of the value resolved from the ConfigMap.
+func (r *ResolvedConfigMapResource) Data() []byte {
+	return r.Content
+}
+
+// Annotations returns the metadata that accompanies the value fetched from the ConfigMap.
+func (r *ResolvedConfigMapResource) Annotations() map[string]string {
+	sum := sha256.Sum256(r.Content)
+	return map[string]string{
+		ResourceNameAnnotation:      r.Name,
+		ResourceNamespaceAnnotation: r.Namespace,
+		ResourceKeyAnnotation:       r.Key,
+		resolutioncommon.AnnotationKeyProvenance: resolutioncommon.Provenance{
+			ResolverType: LabelValueConfigMapResolverType,
+			Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+			Coordinates: map[string]string{
+				"name":      r.Name,
+				"namespace": r.Namespace,
+				"key":       r.Key,
+			},
+		}.AnnotationValue(),
+	}
+}
+
+// Source is the source reference of the remote data. ConfigMaps have no
+// source to record so it is always nil.
+func (r *ResolvedConfigMapResource) Source() *v1beta1.ConfigSource {
+	return nil
+}
+
+func populateParamsWithDefaults(ctx context.Context, origParams []pipelinev1beta1.Param) (map[string]string, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+
+	paramsMap := make(map[string]pipelinev1beta1.ParamValue)
+	for _, p := range origParams {
+		paramsMap[p.Name] = p.Value
+	}
+
+	params := make(map[string]string)
+
+	var missingParams []string
+
+	if pName, ok := paramsMap[NameParam]; !ok || pName.StringVal == "" {
+		missingParams = append(missingParams, NameParam)
+	} else {
+		params[NameParam] = pName.StringVal
+	}
+
+	if pNS, ok := paramsMap[NamespaceParam]; !ok || pNS.StringVal == "" {
+		if nsVal, ok := conf[DefaultNamespaceKey]; !ok || nsVal == "" {
+			missingParams = append(missingParams, NamespaceParam)
+		} else {
+			params[NamespaceParam] = nsVal
+		}
+	} else {
+		params[NamespaceParam] = pNS.StringVal
+	}
+
+	if pKey, ok := paramsMap[KeyParam]; !ok || pKey.StringVal == "" {
+		missingParams = append(missingParams, KeyParam)
+	} else {
+		params[KeyParam] = pKey.StringVal
+	}
+
+	if len(missingParams) > 0 {
+		return nil, fmt.Errorf("missing required configmap resolver params: %s", strings.Join(missingParams, ", "))
+	}
+
+	if errs := validation.IsDNS1123Subdomain(params[NameParam]); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid %s param %q: %s", NameParam, params[NameParam], strings.Join(errs, ", "))
+	}
+	if errs := validation.IsConfigMapKey(params[KeyParam]); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid %s param %q: %s", KeyParam, params[KeyParam], strings.Join(errs, ", "))
+	}
+
+	if !isInCommaSeparatedList(params[NamespaceParam], conf[AllowedNamespacesKey]) {
+		return nil, fmt.Errorf("access to specified namespace %s is not allowed", params[NamespaceParam])
+	}
+
+	return params, nil
+}
+
+func isInCommaSeparatedList(checkVal string, commaList string) bool {
+	for _, s := range strings.Split(commaList, ",") {
+		if strings.TrimSpace(s) == checkVal {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,500 @@
+/*
+ Copyright 2022 The Tekton Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package configmap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
+	"github.com/tektoncd/pipeline/test"
+	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/system"
+
+	_ "knative.dev/pkg/system/testing"
+)
+
+const exampleTaskYAML = `apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+  name: example-task
+spec:
+  steps:
+  - name: some-step
+    image: some-image
+`
+
+func TestGetSelector(t *testing.T) {
+	resolver := Resolver{}
+	sel := resolver.GetSelector(resolverContext())
+	if typ, has := sel[resolutioncommon.LabelKeyResolverType]; !has {
+		t.Fatalf("unexpected selector: %v", sel)
+	} else if typ != LabelValueConfigMapResolverType {
+		t.Fatalf("unexpected type: %q", typ)
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	resolver := Resolver{}
+
+	params := []pipelinev1beta1.Param{{
+		Name:  NameParam,
+		Value: *pipelinev1beta1.NewStructuredValues("tasks"),
+	}, {
+		Name:  NamespaceParam,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  KeyParam,
+		Value: *pipelinev1beta1.NewStructuredValues("example-task.yaml"),
+	}}
+
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		AllowedNamespacesKey: "foo,bar",
+	})
+
+	if err := resolver.ValidateParams(ctx, params); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+}
+
+func TestValidateParamsNotEnabled(t *testing.T) {
+	resolver := Resolver{}
+
+	params := []pipelinev1beta1.Param{{
+		Name:  NameParam,
+		Value: *pipelinev1beta1.NewStructuredValues("tasks"),
+	}, {
+		Name:  NamespaceParam,
+		Value: *pipelinev1beta1.NewStructuredValues("foo"),
+	}, {
+		Name:  KeyParam,
+		Value: *pipelinev1beta1.NewStructuredValues("example-task.yaml"),
+	}}
+	err := resolver.ValidateParams(context.Background(), params)
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestValidateParamsFailure(t *testing.T) {
+	testCases := []struct {
+		name        string
+		params      map[string]string
+		conf        map[string]string
+		expectedErr string
+	}{
+		{
+			name: "missing name",
+			params: map[string]string{
+				NamespaceParam: "foo",
+				KeyParam:       "example-task.yaml",
+			},
+			expectedErr: "missing required configmap resolver params: name",
+		}, {
+			name: "missing multiple",
+			params: map[string]string{
+				NamespaceParam: "foo",
+			},
+			expectedErr: "missing required configmap resolver params: name, key",
+		}, {
+			name: "missing namespace without default",
+			params: map[string]string{
+				NameParam: "tasks",
+				KeyParam:  "example-task.yaml",
+			},
+			expectedErr: "missing required configmap resolver params: namespace",
+		}, {
+			name: "invalid name",
+			params: map[string]string{
+				NameParam:      "Tasks",
+				NamespaceParam: "foo",
+				KeyParam:       "example-task.yaml",
+			},
+			expectedErr: `invalid name param "Tasks": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		}, {
+			name: "invalid key",
+			params: map[string]string{
+				NameParam:      "tasks",
+				NamespaceParam: "foo",
+				KeyParam:       "tasks/example-task.yaml",
+			},
+			expectedErr: `invalid key param "tasks/example-task.yaml": a valid config key must consist of alphanumeric characters, '-', '_' or '.' (e.g. 'key.name',  or 'KEY_NAME',  or 'key-name', regex used for validation is '[-._a-zA-Z0-9]+')`,
+		}, {
+			name: "not in allowed namespaces",
+			params: map[string]string{
+				NameParam:      "tasks",
+				NamespaceParam: "foo",
+				KeyParam:       "example-task.yaml",
+			},
+			conf: map[string]string{
+				AllowedNamespacesKey: "abc,def",
+			},
+			expectedErr: "access to specified namespace foo is not allowed",
+		}, {
+			name: "no allowed namespaces",
+			params: map[string]string{
+				NameParam:      "tasks",
+				NamespaceParam: "foo",
+				KeyParam:       "example-task.yaml",
+			},
+			expectedErr: "access to specified namespace foo is not allowed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{}
+
+			ctx := resolverContext()
+			if len(tc.conf) > 0 {
+				ctx = framework.InjectResolverConfigToContext(ctx, tc.conf)
+			}
+
+			var asParams []pipelinev1beta1.Param
+			for k, v := range tc.params {
+				asParams = append(asParams, pipelinev1beta1.Param{
+					Name:  k,
+					Value: *pipelinev1beta1.NewStructuredValues(v),
+				})
+			}
+			err := resolver.ValidateParams(ctx, asParams)
+			if err == nil {
+				t.Fatalf("got no error, but expected: %s", tc.expectedErr)
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("error did not match: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	defaultNS := "tasks-ns"
+
+	exampleConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tasks",
+			Namespace: defaultNS,
+		},
+		Data: map[string]string{
+			"example-task.yaml": exampleTaskYAML,
+		},
+		BinaryData: map[string][]byte{
+			"binary-task.yaml": []byte(exampleTaskYAML),
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		configMapName     string
+		namespace         string
+		key               string
+		allowedNamespaces string
+		expectedStatus    *v1beta1.ResolutionRequestStatus
+		expectedErr       error
+	}{
+		{
+			name:          "successful key",
+			configMapName: exampleConfigMap.Name,
+			namespace:     exampleConfigMap.Namespace,
+			key:           "example-task.yaml",
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{},
+				ResolutionRequestStatusFields: v1beta1.ResolutionRequestStatusFields{
+					Data: base64.StdEncoding.Strict().EncodeToString([]byte(exampleTaskYAML)),
+				},
+			},
+		}, {
+			name:          "successful binary key",
+			configMapName: exampleConfigMap.Name,
+			namespace:     exampleConfigMap.Namespace,
+			key:           "binary-task.yaml",
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{},
+				ResolutionRequestStatusFields: v1beta1.ResolutionRequestStatusFields{
+					Data: base64.StdEncoding.Strict().EncodeToString([]byte(exampleTaskYAML)),
+				},
+			},
+		}, {
+			name:          "default namespace",
+			configMapName: exampleConfigMap.Name,
+			key:           "example-task.yaml",
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{},
+				ResolutionRequestStatusFields: v1beta1.ResolutionRequestStatusFields{
+					Data: base64.StdEncoding.Strict().EncodeToString([]byte(exampleTaskYAML)),
+				},
+			},
+		}, {
+			name:          "no such configmap",
+			configMapName: "other-tasks",
+			namespace:     exampleConfigMap.Namespace,
+			key:           "example-task.yaml",
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{
+					Conditions: duckv1.Conditions{{
+						Type:   apis.ConditionSucceeded,
+						Status: corev1.ConditionFalse,
+						Reason: resolutioncommon.ReasonResolutionFailed,
+					}},
+				},
+			},
+			expectedErr: &resolutioncommon.ErrorGettingResource{
+				ResolverName: ConfigMapResolverName,
+				Key:          "foo/rr",
+				Original:     errors.New(`failed to get ConfigMap tasks-ns/other-tasks: configmaps "other-tasks" not found`),
+			},
+		}, {
+			name:          "no such key",
+			configMapName: exampleConfigMap.Name,
+			namespace:     exampleConfigMap.Namespace,
+			key:           "other-task.yaml",
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{
+					Conditions: duckv1.Conditions{{
+						Type:   apis.ConditionSucceeded,
+						Status: corev1.ConditionFalse,
+						Reason: resolutioncommon.ReasonResolutionFailed,
+					}},
+				},
+			},
+			expectedErr: &resolutioncommon.ErrorGettingResource{
+				ResolverName: ConfigMapResolverName,
+				Key:          "foo/rr",
+				Original:     errors.New(`ConfigMap tasks-ns/tasks has no key "other-task.yaml"`),
+			},
+		}, {
+			name:              "not in allowed namespaces",
+			configMapName:     exampleConfigMap.Name,
+			namespace:         exampleConfigMap.Namespace,
+			key:               "example-task.yaml",
+			allowedNamespaces: "foo,bar",
+			expectedStatus: &v1beta1.ResolutionRequestStatus{
+				Status: duckv1.Status{
+					Conditions: duckv1.Conditions{{
+						Type:   apis.ConditionSucceeded,
+						Status: corev1.ConditionFalse,
+						Reason: resolutioncommon.ReasonResolutionFailed,
+					}},
+				},
+			},
+			expectedErr: &resolutioncommon.ErrorInvalidRequest{
+				ResolutionRequestKey: "foo/rr",
+				Message:              "access to specified namespace tasks-ns is not allowed",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := ttesting.SetupFakeContext(t)
+
+			request := createRequest(tc.configMapName, tc.namespace, tc.key)
+
+			allowedNamespaces := defaultNS
+			if tc.allowedNamespaces != "" {
+				allowedNamespaces = tc.allowedNamespaces
+			}
+
+			d := test.Data{
+				ConfigMaps: []*corev1.ConfigMap{{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      configMapName,
+						Namespace: resolverconfig.ResolversNamespace(system.Namespace()),
+					},
+					Data: map[string]string{
+						DefaultNamespaceKey:  defaultNS,
+						AllowedNamespacesKey: allowedNamespaces,
+					},
+				}, {
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: resolverconfig.ResolversNamespace(system.Namespace()),
+						Name:      resolverconfig.GetFeatureFlagsConfigName(),
+					},
+					Data: map[string]string{
+						"enable-configmap-resolver": "true",
+					},
+				}, exampleConfigMap},
+				ResolutionRequests: []*v1beta1.ResolutionRequest{request},
+			}
+
+			resolver := &Resolver{}
+
+			var expectedStatus *v1beta1.ResolutionRequestStatus
+			if tc.expectedStatus != nil {
+				expectedStatus = tc.expectedStatus.DeepCopy()
+
+				if tc.expectedErr == nil {
+					content, err := base64.StdEncoding.DecodeString(expectedStatus.Data)
+					if err != nil {
+						t.Fatalf("failed to decode expected data: %v", err)
+					}
+					sum := sha256.Sum256(content)
+					expectedStatus.Annotations = map[string]string{
+						ResourceNameAnnotation:      tc.configMapName,
+						ResourceNamespaceAnnotation: defaultNS,
+						ResourceKeyAnnotation:       tc.key,
+						resolutioncommon.AnnotationKeyProvenance: resolutioncommon.Provenance{
+							ResolverType: LabelValueConfigMapResolverType,
+							Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+							Coordinates: map[string]string{
+								"name":      tc.configMapName,
+								"namespace": defaultNS,
+								"key":       tc.key,
+							},
+						}.AnnotationValue(),
+					}
+				} else {
+					expectedStatus.Status.Conditions[0].Message = tc.expectedErr.Error()
+				}
+			}
+
+			frtesting.RunResolverReconcileTest(ctx, t, d, resolver, request, expectedStatus, tc.expectedErr)
+		})
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		AllowedNamespacesKey: "tasks-ns",
+	})
+
+	resolver := &Resolver{
+		kubeClientSet: fakek8s.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "tasks",
+				Namespace: "tasks-ns",
+			},
+			Data: map[string]string{
+				"example-task.yaml": exampleTaskYAML,
+			},
+		}),
+	}
+
+	for _, tc := range []struct {
+		name             string
+		configMapName    string
+		key              string
+		expectedResource string
+	}{{
+		name:             "missing configmap",
+		configMapName:    "other-tasks",
+		key:              "example-task.yaml",
+		expectedResource: "ConfigMap tasks-ns/other-tasks",
+	}, {
+		name:             "missing key",
+		configMapName:    "tasks",
+		key:              "other-task.yaml",
+		expectedResource: "key other-task.yaml of ConfigMap tasks-ns/tasks",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := resolver.Resolve(ctx, createRequest(tc.configMapName, "tasks-ns", tc.key).Spec.Params)
+			var notFound *resolutioncommon.ResolutionNotFoundError
+			if !errors.As(err, &notFound) {
+				t.Fatalf("expected ResolutionNotFoundError but got %v", err)
+			}
+			if d := cmp.Diff(tc.expectedResource, notFound.Resource); d != "" {
+				t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveForbidden(t *testing.T) {
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		AllowedNamespacesKey: "tasks-ns",
+	})
+
+	kubeClient := fakek8s.NewSimpleClientset()
+	kubeClient.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(corev1.Resource("configmaps"), "tasks", errors.New("denied"))
+	})
+
+	resolver := &Resolver{
+		kubeClientSet: kubeClient,
+	}
+	_, err := resolver.Resolve(ctx, createRequest("tasks", "tasks-ns", "example-task.yaml").Spec.Params)
+	if err == nil {
+		t.Fatalf("expected permission error but got none")
+	}
+	expectedErr := "the resolver's service account is not allowed to get configmaps in namespace tasks-ns, grant it get access to them with a Role and RoleBinding in that namespace"
+	if d := cmp.Diff(expectedErr, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func createRequest(name, namespace, key string) *v1beta1.ResolutionRequest {
+	rr := &v1beta1.ResolutionRequest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "resolution.tekton.dev/v1beta1",
+			Kind:       "ResolutionRequest",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: time.Now()},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueConfigMapResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: []pipelinev1beta1.Param{{
+				Name:  NameParam,
+				Value: *pipelinev1beta1.NewStructuredValues(name),
+			}, {
+				Name:  KeyParam,
+				Value: *pipelinev1beta1.NewStructuredValues(key),
+			}},
+		},
+	}
+	if namespace != "" {
+		rr.Spec.Params = append(rr.Spec.Params, pipelinev1beta1.Param{
+			Name:  NamespaceParam,
+			Value: *pipelinev1beta1.NewStructuredValues(namespace),
+		})
+	}
+
+	return rr
+}
+
+func resolverContext() context.Context {
+	return frtesting.ContextWithConfigMapResolverEnabled(context.Background())
+}
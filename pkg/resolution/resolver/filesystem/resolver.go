@@ -0,0 +1,265 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	disabledError = "cannot handle resolution request, enable-filesystem-resolver feature flag not true"
+
+	// LabelValueFilesystemResolverType is the value to use for the
+	// resolution.tekton.dev/type label on resource requests
+	LabelValueFilesystemResolverType string = "filesystem"
+
+	// FilesystemResolverName is the name that the filesystem resolver
+	// should be associated with
+	FilesystemResolverName string = "Filesystem"
+
+	configMapName = "filesystem-resolver-config"
+)
+
+var _ framework.Resolver = &Resolver{}
+
+// Resolver implements a framework.Resolver that can fetch files from a
+// directory on the local filesystem of the resolver. It is meant for
+// developing tasks and pipelines with the controller running outside of
+// a cluster and is disabled by default.
+type Resolver struct{}
+
+// Initialize performs any setup required by the filesystem resolver.
+func (r *Resolver) Initialize(context.Context) error {
+	return nil
+}
+
+// GetName returns the string name that the filesystem resolver should be
+// associated with.
+func (r *Resolver) GetName(context.Context) string {
+	return FilesystemResolverName
+}
+
+// GetSelector returns the labels that resource requests are required to have for
+// the filesystem resolver to process them.
+func (r *Resolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{
+		resolutioncommon.LabelKeyResolverType: LabelValueFilesystemResolverType,
+	}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver.
+func (r *Resolver) GetParamSchema(context.Context) []framework.ParamSchema {
+	return []framework.ParamSchema{{
+		Name:        PathParam,
+		Description: "The path of the file, relative to the root directory set in the resolver's config.",
+		Required:    true,
+	}}
+}
+
+// ValidateParams returns an error if the given parameter map is not
+// valid for a resource request targeting the filesystem resolver.
+func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if r.isDisabled(ctx) {
+		return resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
+	}
+
+	if err := framework.ValidateNoVariableReferences(params); err != nil {
+		return err
+	}
+
+	_, err := populateParams(ctx, params)
+	return err
+}
+
+// Resolve performs the work of reading the file at the path given in
+// the parameters, relative to the configured root directory.
+func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	if r.isDisabled(ctx) {
+		return nil, resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
+	}
+
+	logger := logging.FromContext(ctx)
+
+	params, err := populateParams(ctx, origParams)
+	if err != nil {
+		logger.Infof("filesystem resolver parameter(s) invalid: %v", err)
+		return nil, err
+	}
+	path := params[PathParam]
+
+	root, err := filepath.EvalSymlinks(params[RootKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", RootKey, err)
+	}
+
+	// Symlinks are followed before checking that the file is within the
+	// root, so that a link can't be used to read files outside of it.
+	file, err := filepath.EvalSymlinks(filepath.Join(root, path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &resolutioncommon.ResolutionNotFoundError{
+				Resource: path,
+				Original: fmt.Errorf("file %s not found in the root directory", path),
+			}
+		}
+		return nil, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+	if !withinRoot(root, file) {
+		return nil, fmt.Errorf("invalid %s param %q: resolves to a file outside of the root directory", PathParam, path)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("invalid %s param %q: is a directory", PathParam, path)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+
+	return &ResolvedFilesystemResource{
+		Content: content,
+		Path:    path,
+	}, nil
+}
+
+var _ framework.ConfigWatcher = &Resolver{}
+
+// GetConfigName returns the name of the filesystem resolver's configmap.
+func (r *Resolver) GetConfigName(context.Context) string {
+	return configMapName
+}
+
+func (r *Resolver) isDisabled(ctx context.Context) bool {
+	cfg := resolverconfig.FromContextOrDefaults(ctx)
+	if cfg.FeatureFlags.EnableFilesystemResolver {
+		return false
+	}
+
+	return true
+}
+
+// ResolvedFilesystemResource implements framework.ResolvedResource and
+// returns the content of the file and an annotation map for any
+// metadata.
+type ResolvedFilesystemResource struct {
+	Content []byte
+	Path    string
+}
+
+var _ framework.ResolvedResource = &ResolvedFilesystemResource{}
+
+// Data returns the bytes of the file.
+func (r *ResolvedFilesystemResource) Data() []byte {
+	return r.Content
+}
+
+// Annotations returns the metadata that accompanies the file.
+func (r *ResolvedFilesystemResource) Annotations() map[string]string {
+	sum := sha256.Sum256(r.Content)
+	return map[string]string{
+		ResourcePathAnnotation: r.Path,
+		resolutioncommon.AnnotationKeyProvenance: resolutioncommon.Provenance{
+			ResolverType: LabelValueFilesystemResolverType,
+			Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+			Coordinates: map[string]string{
+				"path": r.Path,
+			},
+		}.AnnotationValue(),
+	}
+}
+
+// Source is the source reference of the remote data. Local files have
+// no source to record so it is always nil.
+func (r *ResolvedFilesystemResource) Source() *v1beta1.ConfigSource {
+	return nil
+}
+
+// populateParams returns the path param along with the root directory
+// from the resolver's config, under the RootKey, or an error if the path
+// is missing or could escape the root.
+func populateParams(ctx context.Context, origParams []pipelinev1beta1.Param) (map[string]string, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+
+	params := make(map[string]string)
+	for _, p := range origParams {
+		if p.Name == PathParam {
+			params[PathParam] = p.Value.StringVal
+		}
+	}
+	if params[PathParam] == "" {
+		return nil, fmt.Errorf("missing required filesystem resolver params: %s", PathParam)
+	}
+	if err := validatePath(params[PathParam]); err != nil {
+		return nil, err
+	}
+
+	if root := conf[RootKey]; root == "" {
+		return nil, fmt.Errorf("the %s config of the filesystem resolver must be set to the directory files are resolved from", RootKey)
+	}
+	params[RootKey] = conf[RootKey]
+
+	return params, nil
+}
+
+// validatePath returns an error if the path isn't relative or has a ".."
+// segment, either of which could be used to read files outside of the
+// root directory.
+func validatePath(path string) error {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid %s param %q: must be relative to the root directory", PathParam, path)
+	}
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == filepath.Separator }) {
+		if segment == ".." {
+			return fmt.Errorf("invalid %s param %q: must not contain .. segments", PathParam, path)
+		}
+	}
+	if strings.ContainsRune(path, 0) {
+		return fmt.Errorf("invalid %s param %q: must not contain NUL characters", PathParam, path)
+	}
+	return nil
+}
+
+// withinRoot returns true if the file is the root directory itself or
+// is within it. Both must be absolute or relative to the same directory,
+// with symlinks already evaluated.
+func withinRoot(root, file string) bool {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
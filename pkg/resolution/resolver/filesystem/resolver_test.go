@@ -0,0 +1,249 @@
+/*
+ Copyright 2022 The Tekton Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
+	"github.com/tektoncd/pipeline/test/diff"
+)
+
+const exampleTaskYAML = `apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+  name: example-task
+spec:
+  steps:
+  - name: some-step
+    image: some-image
+`
+
+func TestGetSelector(t *testing.T) {
+	resolver := Resolver{}
+	sel := resolver.GetSelector(resolverContext())
+	if typ, has := sel[resolutioncommon.LabelKeyResolverType]; !has {
+		t.Fatalf("unexpected selector: %v", sel)
+	} else if typ != LabelValueFilesystemResolverType {
+		t.Fatalf("unexpected type: %q", typ)
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	resolver := Resolver{}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		RootKey: t.TempDir(),
+	})
+
+	for _, path := range []string{"task.yaml", "tasks/task.yaml", "./tasks/task.yaml", "tasks/..task.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			if err := resolver.ValidateParams(ctx, pathParams(path)); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateParamsNotEnabled(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), pathParams("task.yaml"))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestValidateParamsFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		path        string
+		conf        map[string]string
+		expectedErr string
+	}{{
+		name:        "missing path",
+		conf:        map[string]string{RootKey: "/tasks"},
+		expectedErr: "missing required filesystem resolver params: path",
+	}, {
+		name:        "parent directory",
+		path:        "../secret.yaml",
+		conf:        map[string]string{RootKey: "/tasks"},
+		expectedErr: `invalid path param "../secret.yaml": must not contain .. segments`,
+	}, {
+		name:        "parent directory in the middle",
+		path:        "tasks/../../secret.yaml",
+		conf:        map[string]string{RootKey: "/tasks"},
+		expectedErr: `invalid path param "tasks/../../secret.yaml": must not contain .. segments`,
+	}, {
+		name:        "parent directory at the end",
+		path:        "tasks/..",
+		conf:        map[string]string{RootKey: "/tasks"},
+		expectedErr: `invalid path param "tasks/..": must not contain .. segments`,
+	}, {
+		name:        "absolute path",
+		path:        "/etc/passwd",
+		conf:        map[string]string{RootKey: "/tasks"},
+		expectedErr: `invalid path param "/etc/passwd": must be relative to the root directory`,
+	}, {
+		name:        "root not set",
+		path:        "task.yaml",
+		expectedErr: "the root config of the filesystem resolver must be set to the directory files are resolved from",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.conf)
+			err := (&Resolver{}).ValidateParams(ctx, pathParams(tc.path))
+			if err == nil {
+				t.Fatalf("got no error, but expected: %s", tc.expectedErr)
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("error did not match: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "tasks"), 0o755); err != nil {
+		t.Fatalf("couldn't create tasks directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "tasks", "example-task.yaml"), []byte(exampleTaskYAML), 0o644); err != nil {
+		t.Fatalf("couldn't write task: %v", err)
+	}
+	if err := os.Symlink("tasks/example-task.yaml", filepath.Join(root, "link.yaml")); err != nil {
+		t.Fatalf("couldn't create symlink: %v", err)
+	}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		RootKey: root,
+	})
+
+	for _, path := range []string{"tasks/example-task.yaml", "./tasks/example-task.yaml", "link.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			resource, err := (&Resolver{}).Resolve(ctx, pathParams(path))
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(exampleTaskYAML, string(resource.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+			sum := sha256.Sum256([]byte(exampleTaskYAML))
+			expectedAnnotations := map[string]string{
+				ResourcePathAnnotation: path,
+				resolutioncommon.AnnotationKeyProvenance: resolutioncommon.Provenance{
+					ResolverType: LabelValueFilesystemResolverType,
+					Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+					Coordinates:  map[string]string{"path": path},
+				}.AnnotationValue(),
+			}
+			if d := cmp.Diff(expectedAnnotations, resource.Annotations()); d != "" {
+				t.Errorf("unexpected annotations: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		RootKey: t.TempDir(),
+	})
+
+	for _, path := range []string{"missing.yaml", "tasks/missing.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := (&Resolver{}).Resolve(ctx, pathParams(path))
+			var notFound *resolutioncommon.ResolutionNotFoundError
+			if !errors.As(err, &notFound) {
+				t.Fatalf("expected ResolutionNotFoundError but got %v", err)
+			}
+			if d := cmp.Diff(path, notFound.Resource); d != "" {
+				t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "tasks"), 0o755); err != nil {
+		t.Fatalf("couldn't create root directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("couldn't write secret: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.yaml"), filepath.Join(root, "file-link.yaml")); err != nil {
+		t.Fatalf("couldn't create symlink: %v", err)
+	}
+	if err := os.Symlink(dir, filepath.Join(root, "dir-link")); err != nil {
+		t.Fatalf("couldn't create symlink: %v", err)
+	}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		RootKey: root,
+	})
+
+	for _, tc := range []struct {
+		path        string
+		expectedErr string
+	}{{
+		path:        "../secret.yaml",
+		expectedErr: `invalid path param "../secret.yaml": must not contain .. segments`,
+	}, {
+		path:        "file-link.yaml",
+		expectedErr: `invalid path param "file-link.yaml": resolves to a file outside of the root directory`,
+	}, {
+		path:        "dir-link/secret.yaml",
+		expectedErr: `invalid path param "dir-link/secret.yaml": resolves to a file outside of the root directory`,
+	}, {
+		path:        "tasks",
+		expectedErr: `invalid path param "tasks": is a directory`,
+	}} {
+		t.Run(tc.path, func(t *testing.T) {
+			resource, err := (&Resolver{}).Resolve(ctx, pathParams(tc.path))
+			if err == nil {
+				t.Fatalf("expected error %q but resolved %q", tc.expectedErr, resource.Data())
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func pathParams(path string) []pipelinev1beta1.Param {
+	if path == "" {
+		return nil
+	}
+	return []pipelinev1beta1.Param{{
+		Name:  PathParam,
+		Value: *pipelinev1beta1.NewStructuredValues(path),
+	}}
+}
+
+func resolverContext() context.Context {
+	return frtesting.ContextWithFilesystemResolverEnabled(context.Background())
+}
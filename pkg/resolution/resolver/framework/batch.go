@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// BatchResult is the outcome of resolving one of the param sets given
+// to DryRunBatch: either the resolved resource or the error resolving
+// it.
+type BatchResult struct {
+	Resource ResolvedResource
+	Err      error
+}
+
+// DryRunBatch resolves each of the given param sets with the resolver,
+// just as DryRun does, and returns a result per param set in the same
+// order. It is meant for resolving all the remote Tasks of a Pipeline
+// at once rather than one round-trip after another.
+//
+// Param sets that are identical, whatever the order of their params,
+// are only resolved once and share the same result. The others are
+// resolved concurrently by the same resolver, so that they share its
+// connections and caches, with at most max-concurrent-resolutions of
+// them in flight at once as set in the resolver's config in ctx. A
+// param set that fails to resolve only fails its own result: the
+// others are resolved all the same. Each resolution gets the resolver's
+// full timeout once it starts.
+func DryRunBatch(ctx context.Context, resolver Resolver, paramSets [][]pipelinev1beta1.Param) []BatchResult {
+	resolverType := resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType]
+	namespace := resolutioncommon.RequestNamespace(ctx)
+
+	// keys holds the key of each distinct param set in the order they
+	// first appear, and shared the indexes of the param sets with each.
+	var keys []string
+	shared := map[string][]int{}
+	for i, params := range paramSets {
+		key, err := coalesceKey(resolverType, namespace, params)
+		if err != nil {
+			// Keys are otherwise JSON objects, so this can't clash.
+			key = fmt.Sprintf("#%d", i)
+		}
+		if _, ok := shared[key]; !ok {
+			keys = append(keys, key)
+		}
+		shared[key] = append(shared[key], i)
+	}
+
+	results := make([]BatchResult, len(paramSets))
+	limiter := &concurrencyLimiter{}
+	limit := maxConcurrentResolutions(ctx)
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			var result BatchResult
+			if err := limiter.acquire(ctx, limit); err != nil {
+				result.Err = resolutionContextError(ctx, resolverType, resolutionTimeout(ctx, resolver))
+			} else {
+				result.Resource, result.Err = DryRun(ctx, resolver, paramSets[indexes[0]])
+				limiter.release()
+			}
+			for _, i := range indexes {
+				results[i] = result
+			}
+		}(shared[key])
+	}
+	wg.Wait()
+	return results
+}
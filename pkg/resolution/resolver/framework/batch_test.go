@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// httpResolver is a FakeResolver that resolves its param by fetching it
+// from url with a client shared between resolutions.
+type httpResolver struct {
+	FakeResolver
+	client *http.Client
+	url    string
+}
+
+func (r *httpResolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+"/"+params[0].Value.StringVal, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", params[0].Value.StringVal, resp.Status)
+	}
+	return &FakeResolvedResource{Content: string(body)}, nil
+}
+
+func TestDryRunBatch(t *testing.T) {
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "foo content"},
+			"bar": {ErrorWith: "something went wrong"},
+			"baz": {Content: "baz content"},
+		},
+	}
+
+	results := DryRunBatch(context.Background(), resolver, [][]pipelinev1beta1.Param{
+		fakeParams("foo"),
+		fakeParams("bar"),
+		nil,
+		fakeParams("baz"),
+	})
+
+	expected := []struct {
+		content string
+		err     string
+	}{
+		{content: "foo content"},
+		{err: "error resolving with Fake resolver: something went wrong"},
+		{err: "invalid params for Fake resolver: missing fake-key"},
+		{content: "baz content"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results but got %d", len(expected), len(results))
+	}
+	for i, e := range expected {
+		result := results[i]
+		if e.err != "" {
+			if result.Err == nil || result.Err.Error() != e.err {
+				t.Errorf("result %d: expected error %q but got %v", i, e.err, result.Err)
+			}
+			if result.Resource != nil {
+				t.Errorf("result %d: expected no resource but got %q", i, result.Resource.Data())
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Errorf("result %d: unexpected error: %v", i, result.Err)
+			continue
+		}
+		if string(result.Resource.Data()) != e.content {
+			t.Errorf("result %d: expected content %q but got %q", i, e.content, result.Resource.Data())
+		}
+	}
+
+	var invalidErr *resolutioncommon.InvalidParamsError
+	if !errors.As(results[2].Err, &invalidErr) {
+		t.Errorf("expected an InvalidParamsError but got %v", results[2].Err)
+	}
+}
+
+func TestDryRunBatchCoalescesDuplicates(t *testing.T) {
+	resolver := &blockingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "foo content"},
+			"bar": {Content: "bar content"},
+		}},
+		release: make(chan struct{}),
+	}
+	close(resolver.release)
+
+	paramSets := [][]pipelinev1beta1.Param{fakeParams("foo"), fakeParams("bar"), fakeParams("foo"), fakeParams("foo")}
+	results := DryRunBatch(context.Background(), resolver, paramSets)
+
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 2 {
+		t.Errorf("expected 2 calls to Resolve but got %d", calls)
+	}
+	for i, expected := range []string{"foo content", "bar content", "foo content", "foo content"} {
+		if results[i].Err != nil {
+			t.Fatalf("result %d: unexpected error: %v", i, results[i].Err)
+		}
+		if string(results[i].Resource.Data()) != expected {
+			t.Errorf("result %d: expected content %q but got %q", i, expected, results[i].Resource.Data())
+		}
+	}
+}
+
+func TestDryRunBatchReusesConnections(t *testing.T) {
+	var connections int32
+	svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "%s content", name)
+	}))
+	svr.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	svr.Start()
+	defer svr.Close()
+
+	resolver := &httpResolver{client: svr.Client(), url: svr.URL}
+	// Resolving one param set at a time lets each resolution reuse the
+	// connection of the one before.
+	ctx := InjectResolverConfigToContext(context.Background(), map[string]string{ConfigMaxConcurrentResolutions: "1"})
+	results := DryRunBatch(ctx, resolver, [][]pipelinev1beta1.Param{
+		fakeParams("foo"),
+		fakeParams("missing"),
+		fakeParams("bar"),
+		fakeParams("baz"),
+	})
+
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Errorf("expected the resolutions to share a single connection but %d were opened", n)
+	}
+	for i, name := range []string{"foo", "missing", "bar", "baz"} {
+		if name == "missing" {
+			expected := "error resolving with Fake resolver: fetching missing: 404 Not Found"
+			if results[i].Err == nil || results[i].Err.Error() != expected {
+				t.Errorf("result %d: expected error %q but got %v", i, expected, results[i].Err)
+			}
+			continue
+		}
+		if results[i].Err != nil {
+			t.Errorf("result %d: unexpected error: %v", i, results[i].Err)
+			continue
+		}
+		if expected := name + " content"; string(results[i].Resource.Data()) != expected {
+			t.Errorf("result %d: expected content %q but got %q", i, expected, results[i].Resource.Data())
+		}
+	}
+}
+
+func TestDryRunBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "foo content", WaitFor: time.Minute},
+			"bar": {Content: "bar content", WaitFor: time.Minute},
+		},
+	}
+	results := DryRunBatch(ctx, resolver, [][]pipelinev1beta1.Param{fakeParams("foo"), fakeParams("bar")})
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("result %d: expected error to wrap context.Canceled but got %v", i, result.Err)
+		}
+	}
+}
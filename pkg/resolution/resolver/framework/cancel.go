@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resolutionTracker records the resolutions in flight so that they can
+// be cancelled when their ResolutionRequest is deleted, instead of
+// running to completion for a result nobody will read. Its zero value
+// is ready to use.
+type resolutionTracker struct {
+	mu          sync.Mutex
+	resolutions map[string]*trackedResolution
+}
+
+// trackedResolution is a resolution in flight for the
+// ResolutionRequest with the given UID.
+type trackedResolution struct {
+	uid     types.UID
+	cancel  context.CancelFunc
+	deleted bool
+}
+
+// track records the resolution for the ResolutionRequest with the given
+// key and UID, to be cancelled with cancel if the request is deleted.
+// The returned func stops tracking it.
+func (t *resolutionTracker) track(key string, uid types.UID, cancel context.CancelFunc) func() {
+	tracked := &trackedResolution{uid: uid, cancel: cancel}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resolutions == nil {
+		t.resolutions = map[string]*trackedResolution{}
+	}
+	t.resolutions[key] = tracked
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.resolutions[key] == tracked {
+			delete(t.resolutions, key)
+		}
+	}
+}
+
+// cancelDeleted cancels the resolution in flight for the deleted
+// ResolutionRequest, if any. It is meant to be used as the DeleteFunc
+// of the ResolutionRequest informer, so obj may also be a tombstone.
+func (t *resolutionTracker) cancelDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	rr, ok := obj.(*v1beta1.ResolutionRequest)
+	if !ok {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(rr)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// A request recreated with the same name is a different request
+	// whose resolution must carry on.
+	if tracked, ok := t.resolutions[key]; ok && tracked.uid == rr.UID {
+		tracked.deleted = true
+		tracked.cancel()
+	}
+}
+
+// deleted returns true if the resolution for the ResolutionRequest with
+// the given key and UID was cancelled because the request was deleted.
+func (t *resolutionTracker) deleted(key string, uid types.UID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tracked, ok := t.resolutions[key]
+	return ok && tracked.uid == uid && tracked.deleted
+}
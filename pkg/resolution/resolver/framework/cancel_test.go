@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolutionTrackerCancelDeleted(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "rr", UID: "first"}}
+	recreated := rr.DeepCopy()
+	recreated.UID = "second"
+
+	var tracker resolutionTracker
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+	untrackFirst := tracker.track("foo/rr", rr.UID, cancelFirst)
+
+	// The request is deleted and recreated while the first resolution
+	// is still winding down.
+	tracker.cancelDeleted(rr)
+	if !errors.Is(firstCtx.Err(), context.Canceled) {
+		t.Fatalf("expected the first resolution to be cancelled but got %v", firstCtx.Err())
+	}
+	if !tracker.deleted("foo/rr", rr.UID) {
+		t.Errorf("expected the first resolution to be reported as deleted")
+	}
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	untrackSecond := tracker.track("foo/rr", recreated.UID, cancelSecond)
+	untrackFirst()
+
+	tracker.cancelDeleted(rr)
+	if secondCtx.Err() != nil {
+		t.Fatalf("expected the recreated request's resolution not to be cancelled but got %v", secondCtx.Err())
+	}
+	if tracker.deleted("foo/rr", recreated.UID) {
+		t.Errorf("expected the recreated request's resolution not to be reported as deleted")
+	}
+
+	untrackSecond()
+	tracker.cancelDeleted(recreated)
+	if secondCtx.Err() != nil {
+		t.Errorf("expected a resolution that's no longer tracked not to be cancelled but got %v", secondCtx.Err())
+	}
+}
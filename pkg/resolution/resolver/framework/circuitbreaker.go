@@ -0,0 +1,285 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// ConfigCircuitBreakerFailureThreshold is the key in a resolver's
+	// ConfigMap for the number of consecutive failed resolutions from a
+	// backend after which its circuit opens and further resolutions from
+	// it fail straight away. Zero, the default, disables the circuit
+	// breaker.
+	ConfigCircuitBreakerFailureThreshold = "circuit-breaker-failure-threshold"
+
+	// ConfigCircuitBreakerCooldown is the key in a resolver's ConfigMap
+	// for how long the circuit of a backend stays open before probe
+	// resolutions are let through, e.g. "30s".
+	ConfigCircuitBreakerCooldown = "circuit-breaker-cooldown"
+
+	// ConfigCircuitBreakerProbes is the key in a resolver's ConfigMap for
+	// the number of probe resolutions that may be in flight at once once
+	// the cooldown has passed. The circuit closes as soon as one of them
+	// succeeds and opens again if one fails.
+	ConfigCircuitBreakerProbes = "circuit-breaker-probes"
+)
+
+// DefaultCircuitBreakerSettings are the circuit breaker settings used
+// for the ones that aren't set in a resolver's ConfigMap.
+var DefaultCircuitBreakerSettings = CircuitBreakerSettings{
+	Cooldown: 30 * time.Second,
+	Probes:   1,
+}
+
+// ErrCircuitOpen is wrapped by the error a resolution fails with when
+// the circuit of its backend is open.
+var ErrCircuitOpen = errors.New("backend circuit open")
+
+// errUncounted is recorded instead of the outcome of a resolution that
+// says nothing about the health of its backend, so that it neither
+// counts as a failure nor closes the circuit.
+var errUncounted = errors.New("resolution outcome not counted")
+
+// CircuitBreakerSettings configure the circuit breaker of a resolver.
+type CircuitBreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that open
+	// the circuit of a backend, or zero to disable the breaker.
+	FailureThreshold int
+	Cooldown         time.Duration
+	Probes           int
+}
+
+// CircuitBreakerSettingsFromContext returns the circuit breaker
+// settings in the resolver's config, using
+// DefaultCircuitBreakerSettings for the ones that aren't set. Invalid
+// values are ignored so that a typo doesn't stop every resolution.
+func CircuitBreakerSettingsFromContext(ctx context.Context) CircuitBreakerSettings {
+	conf := GetResolverConfigFromContext(ctx)
+	settings := DefaultCircuitBreakerSettings
+	for key, setting := range map[string]*int{
+		ConfigCircuitBreakerFailureThreshold: &settings.FailureThreshold,
+		ConfigCircuitBreakerProbes:           &settings.Probes,
+	} {
+		value, ok := conf[key]
+		if !ok || value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || (n == 0 && key == ConfigCircuitBreakerProbes) {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a positive integer", key, value)
+			continue
+		}
+		*setting = n
+	}
+	if value, ok := conf[ConfigCircuitBreakerCooldown]; ok && value != "" {
+		cooldown, err := time.ParseDuration(value)
+		if err != nil || cooldown <= 0 {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a positive duration", ConfigCircuitBreakerCooldown, value)
+		} else {
+			settings.Cooldown = cooldown
+		}
+	}
+	return settings
+}
+
+// circuitBreaker tracks the consecutive failures of the backends of a
+// resolver and fails resolutions from the backends whose circuit is
+// open. Its zero value is ready to use.
+type circuitBreaker struct {
+	mu sync.Mutex
+	// circuits holds the state of the backends that have failed since
+	// they last succeeded.
+	circuits map[string]*circuit
+}
+
+// circuit is the state of a single backend.
+type circuit struct {
+	// failures is the number of consecutive failures.
+	failures int
+	// openedAt is when the circuit last opened, or the zero time if it
+	// hasn't.
+	openedAt time.Time
+	// probes is the number of probe resolutions in flight.
+	probes int
+}
+
+// allow returns an error wrapping ErrCircuitOpen if resolutions from
+// the given backend must fail straight away. Otherwise the returned
+// function must be called with the outcome of the resolution, and
+// returns true if that opened the backend's circuit.
+func (b *circuitBreaker) allow(backend string, settings CircuitBreakerSettings, now time.Time) (func(error, time.Time) bool, error) {
+	if settings.FailureThreshold <= 0 {
+		return func(error, time.Time) bool { return false }, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[backend]
+	if !ok || c.failures < settings.FailureThreshold {
+		return func(err error, now time.Time) bool { return b.record(backend, settings, false, err, now) }, nil
+	}
+
+	retryAfter := c.openedAt.Add(settings.Cooldown).Sub(now)
+	if retryAfter <= 0 && c.probes < settings.Probes {
+		c.probes++
+		return func(err error, now time.Time) bool { return b.record(backend, settings, true, err, now) }, nil
+	}
+	if retryAfter < 0 {
+		// The probes in flight will decide whether the circuit closes.
+		retryAfter = 0
+	}
+	return nil, &resolutioncommon.RateLimitedError{
+		Resource:   backend,
+		RetryAfter: retryAfter,
+		Original:   fmt.Errorf("%w for %s after %d consecutive failures", ErrCircuitOpen, backendName(backend), c.failures),
+	}
+}
+
+// record records the outcome of a resolution allowed by allow and
+// returns true if it opened the backend's circuit, either because the
+// backend reached the failure threshold or because a probe failed.
+func (b *circuitBreaker) record(backend string, settings CircuitBreakerSettings, probe bool, err error, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[backend]
+	if !ok {
+		c = &circuit{}
+	}
+	if probe {
+		c.probes--
+	}
+	if errors.Is(err, errUncounted) {
+		return false
+	}
+	if !isBackendFailure(err) {
+		// The backend answered, so its circuit closes. Failures of
+		// resolutions that started before it did are counted afresh.
+		if c.probes <= 0 {
+			delete(b.circuits, backend)
+		} else {
+			c.failures = 0
+		}
+		return false
+	}
+	c.failures++
+	opened := c.failures == settings.FailureThreshold || (probe && c.failures > settings.FailureThreshold)
+	if c.failures >= settings.FailureThreshold {
+		c.openedAt = now
+	}
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+	b.circuits[backend] = c
+	return opened
+}
+
+// isBackendFailure returns true if the resolution failed because its
+// backend is unavailable, i.e. it timed out or failed transiently, as
+// opposed to e.g. not finding the resource, which shows the backend is
+// up.
+func isBackendFailure(err error) bool {
+	var transient *resolutioncommon.TransientError
+	return isTimeout(err) || errors.As(err, &transient)
+}
+
+// isTimeout returns true if the resolution failed by timing out.
+func isTimeout(err error) bool {
+	var timeout *resolutioncommon.ResolutionTimeoutError
+	return errors.As(err, &timeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// shortensTimeout returns true if the timeout param of a resolution is
+// shorter than the fetch-timeout config, or DefaultFetchTimeout when
+// that isn't set, so that its timing out doesn't show that the backend
+// is down.
+func shortensTimeout(ctx context.Context, params []pipelinev1beta1.Param) bool {
+	var value string
+	for _, p := range params {
+		if p.Name == resolutioncommon.ParamTimeout {
+			value = p.Value.StringVal
+		}
+	}
+	if value == "" {
+		return false
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return false
+	}
+	opts, err := FetchOptionsFromConfig(GetResolverConfigFromContext(ctx))
+	if err != nil {
+		return false
+	}
+	return timeout < opts.Timeout
+}
+
+// backendName returns how the given backend is referred to in errors.
+func backendName(backend string) string {
+	if backend == "" {
+		return "the resolver's backend"
+	}
+	return "backend " + backend
+}
+
+// resolutionBackend returns the backend the resolution with the given
+// params is sent to, or an empty string if the resolver doesn't
+// distinguish between its backends.
+func resolutionBackend(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) string {
+	if identifier, ok := resolver.(BackendIdentifier); ok {
+		return identifier.Backend(ctx, params)
+	}
+	return ""
+}
+
+// resolveBreaking calls the reconciler's resolver unless the circuit of
+// the resolution's backend is open, recording whether the backend
+// failed.
+func (r *Reconciler) resolveBreaking(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	backend := resolutionBackend(ctx, r.resolver, params)
+	settings := CircuitBreakerSettingsFromContext(ctx)
+	done, err := r.breaker.allow(backend, settings, r.now())
+	if err != nil {
+		return nil, err
+	}
+	resource, err := r.resolveLimited(ctx, params)
+	outcome := err
+	switch {
+	case errors.Is(err, context.Canceled):
+		// The resolution was cancelled, e.g. because its
+		// ResolutionRequest was deleted, before the backend answered.
+		outcome = errUncounted
+	case isTimeout(err) && shortensTimeout(ctx, params):
+		// The caller asked for less time than the backend is expected
+		// to need, so the timeout isn't held against the backend.
+		outcome = errUncounted
+	}
+	if done(outcome, r.now()) {
+		logging.FromContext(ctx).Warnf("Circuit of %s opened for %s after %d consecutive failures, the last one: %v",
+			backendName(backend), settings.Cooldown, settings.FailureThreshold, err)
+	}
+	return resource, err
+}
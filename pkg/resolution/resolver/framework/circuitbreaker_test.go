@@ -0,0 +1,307 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test/diff"
+	clock "k8s.io/utils/clock/testing"
+)
+
+// backendResolver is a fake resolver whose resolutions from the backend
+// named by the fake param fail with the backend's error.
+type backendResolver struct {
+	FakeResolver
+	errs  map[string]error
+	calls map[string]int
+}
+
+var _ BackendIdentifier = &backendResolver{}
+
+func (r *backendResolver) Backend(_ context.Context, params []pipelinev1beta1.Param) string {
+	return params[0].Value.StringVal
+}
+
+func (r *backendResolver) Resolve(_ context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	backend := params[0].Value.StringVal
+	r.calls[backend]++
+	if err := r.errs[backend]; err != nil {
+		return nil, err
+	}
+	return &FakeResolvedResource{Content: backend}, nil
+}
+
+func TestCircuitBreakerSettingsFromContext(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   map[string]string
+		expected CircuitBreakerSettings
+	}{{
+		name:     "defaults",
+		expected: DefaultCircuitBreakerSettings,
+	}, {
+		name: "configured",
+		config: map[string]string{
+			ConfigCircuitBreakerFailureThreshold: "5",
+			ConfigCircuitBreakerCooldown:         "1m",
+			ConfigCircuitBreakerProbes:           "3",
+		},
+		expected: CircuitBreakerSettings{FailureThreshold: 5, Cooldown: time.Minute, Probes: 3},
+	}, {
+		name: "invalid values are ignored",
+		config: map[string]string{
+			ConfigCircuitBreakerFailureThreshold: "-1",
+			ConfigCircuitBreakerCooldown:         "0s",
+			ConfigCircuitBreakerProbes:           "0",
+		},
+		expected: DefaultCircuitBreakerSettings,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := InjectResolverConfigToContext(context.Background(), tc.config)
+			if d := cmp.Diff(tc.expected, CircuitBreakerSettingsFromContext(ctx)); d != "" {
+				t.Errorf("unexpected settings: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	settings := CircuitBreakerSettings{FailureThreshold: 2, Cooldown: time.Minute, Probes: 1}
+	failure := &resolutioncommon.TransientError{Original: errors.New("connection refused")}
+	now := time.Now()
+	var b circuitBreaker
+
+	call := func(err error) bool {
+		t.Helper()
+		done, allowErr := b.allow("hub", settings, now)
+		if allowErr != nil {
+			t.Fatalf("expected the resolution to be allowed but got %v", allowErr)
+		}
+		return done(err, now)
+	}
+	expectOpen := func(retryAfter time.Duration) {
+		t.Helper()
+		_, err := b.allow("hub", settings, now)
+		var rateLimited *resolutioncommon.RateLimitedError
+		if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &rateLimited) {
+			t.Fatalf("expected the circuit to be open but got %v", err)
+		}
+		if rateLimited.RetryAfter != retryAfter {
+			t.Errorf("expected to retry after %s but got %s", retryAfter, rateLimited.RetryAfter)
+		}
+	}
+
+	// Failures that show the backend is up don't count.
+	call(&resolutioncommon.ResolutionNotFoundError{Original: errors.New("not found")})
+	if call(failure) {
+		t.Errorf("expected the circuit to stay closed below the threshold")
+	}
+	if !call(failure) {
+		t.Errorf("expected the circuit to open at the threshold")
+	}
+	expectOpen(time.Minute)
+
+	// Other backends are unaffected.
+	if _, err := b.allow("artifact", settings, now); err != nil {
+		t.Errorf("unexpected error for another backend: %v", err)
+	}
+
+	now = now.Add(20 * time.Second)
+	expectOpen(40 * time.Second)
+
+	// Once the cooldown has passed a single probe is let through.
+	now = now.Add(40 * time.Second)
+	done, err := b.allow("hub", settings, now)
+	if err != nil {
+		t.Fatalf("expected a probe to be allowed after the cooldown but got %v", err)
+	}
+	expectOpen(0)
+	// A failing probe opens the circuit again for another cooldown.
+	if !done(failure, now) {
+		t.Errorf("expected a failed probe to open the circuit again")
+	}
+	expectOpen(time.Minute)
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	if call(nil) {
+		t.Errorf("expected a successful probe not to open the circuit")
+	}
+	if call(failure) {
+		t.Errorf("expected failures to be counted afresh once the circuit closed")
+	}
+	if _, err := b.allow("hub", settings, now); err != nil {
+		t.Errorf("expected the circuit to be closed but got %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var b circuitBreaker
+	for i := 0; i < 10; i++ {
+		done, err := b.allow("hub", DefaultCircuitBreakerSettings, time.Now())
+		if err != nil {
+			t.Fatalf("expected resolutions to be allowed with the breaker disabled but got %v", err)
+		}
+		done(context.DeadlineExceeded, time.Now())
+	}
+}
+
+func TestResolveBreaking(t *testing.T) {
+	resolver := &backendResolver{
+		errs: map[string]error{
+			"down": &resolutioncommon.ResolutionTimeoutError{Timeout: time.Second, Original: errors.New("timed out")},
+		},
+		calls: map[string]int{},
+	}
+	fakeClock := clock.NewFakeClock(now)
+	r := &Reconciler{resolver: resolver, Clock: fakeClock}
+	ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "ns")
+	ctx = InjectResolverConfigToContext(ctx, map[string]string{
+		ConfigCircuitBreakerFailureThreshold: "3",
+		ConfigCircuitBreakerCooldown:         "30s",
+	})
+	resolve := func(backend string) error {
+		t.Helper()
+		_, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams(backend))
+		return err
+	}
+
+	for i := 0; i < 5; i++ {
+		err := resolve("down")
+		if i < 3 && errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("resolution %d: expected the circuit to be closed but got %v", i, err)
+		}
+		if i >= 3 && !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("resolution %d: expected the circuit to be open but got %v", i, err)
+		}
+	}
+	if calls := resolver.calls["down"]; calls != 3 {
+		t.Errorf("expected the resolver to be called 3 times before the circuit opened but got %d", calls)
+	}
+	expectedErr := "backend circuit open for backend down after 3 consecutive failures"
+	if err := resolve("down"); err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q but got %v", expectedErr, err)
+	}
+	if err := resolve("up"); err != nil {
+		t.Errorf("unexpected error resolving from another backend: %v", err)
+	}
+
+	// The backend recovers during the cooldown, which the probe
+	// resolution after it finds.
+	delete(resolver.errs, "down")
+	fakeClock.Step(30 * time.Second)
+	if err := resolve("down"); err != nil {
+		t.Fatalf("unexpected error from the probe: %v", err)
+	}
+	if err := resolve("down"); err != nil {
+		t.Errorf("expected the circuit to be closed after a successful probe but got %v", err)
+	}
+	if calls := resolver.calls["down"]; calls != 5 {
+		t.Errorf("expected 5 calls to the resolver but got %d", calls)
+	}
+}
+
+func TestResolveBreakingShortTimeout(t *testing.T) {
+	resolver := &backendResolver{
+		errs: map[string]error{
+			"down": &resolutioncommon.ResolutionTimeoutError{Timeout: time.Second, Original: errors.New("timed out")},
+		},
+		calls: map[string]int{},
+	}
+	r := &Reconciler{resolver: resolver, Clock: clock.NewFakeClock(now)}
+	ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "ns")
+	ctx = InjectResolverConfigToContext(ctx, map[string]string{
+		ConfigCircuitBreakerFailureThreshold: "3",
+		ConfigFetchTimeout:                   "10s",
+	})
+	resolve := func(timeout string) error {
+		t.Helper()
+		params := append(fakeParams("down"), pipelinev1beta1.Param{
+			Name:  resolutioncommon.ParamTimeout,
+			Value: *pipelinev1beta1.NewStructuredValues(timeout),
+		})
+		_, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, params)
+		return err
+	}
+
+	// Timeouts shorter than the fetch-timeout config aren't held against
+	// the backend.
+	for i := 0; i < 5; i++ {
+		if err := resolve("1s"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("resolution %d: expected the circuit to stay closed but got %v", i, err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := resolve("10s"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("resolution %d: expected the circuit to be closed but got %v", i, err)
+		}
+	}
+	if err := resolve("1s"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the circuit to be open but got %v", err)
+	}
+	if calls := resolver.calls["down"]; calls != 8 {
+		t.Errorf("expected 8 calls to the resolver but got %d", calls)
+	}
+}
+
+func TestResolveBreakingCancelledProbe(t *testing.T) {
+	unavailable := &resolutioncommon.TransientError{Original: errors.New("service unavailable")}
+	resolver := &backendResolver{
+		errs:  map[string]error{"down": unavailable},
+		calls: map[string]int{},
+	}
+	fakeClock := clock.NewFakeClock(now)
+	r := &Reconciler{resolver: resolver, Clock: fakeClock}
+	ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "ns")
+	ctx = InjectResolverConfigToContext(ctx, map[string]string{ConfigCircuitBreakerFailureThreshold: "2"})
+	resolve := func() error {
+		t.Helper()
+		_, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams("down"))
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := resolve(); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("resolution %d: expected the circuit to be closed but got %v", i, err)
+		}
+	}
+
+	// A probe cancelled e.g. by deleting its ResolutionRequest says
+	// nothing about the backend, so the next resolution probes it again.
+	fakeClock.Step(30 * time.Second)
+	resolver.errs["down"] = fmt.Errorf("pull cancelled: %w", context.Canceled)
+	if err := resolve(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the probe to be cancelled but got %v", err)
+	}
+	resolver.errs["down"] = unavailable
+	if err := resolve(); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected another probe but got %v", err)
+	}
+	if err := resolve(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the circuit to open again after the failed probe but got %v", err)
+	}
+	if calls := resolver.calls["down"]; calls != 4 {
+		t.Errorf("expected 4 calls to the resolver but got %d", calls)
+	}
+}
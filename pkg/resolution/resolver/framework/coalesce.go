@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// coalescer shares a single in-flight call between all the callers
+// asking for the same key at the same time. Its zero value is ready to
+// use.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// coalescedCall is a call in flight, or one that has just completed,
+// along with the number of callers that have waited for it and the
+// number still waiting.
+type coalescedCall struct {
+	done     chan struct{}
+	waiters  int
+	waiting  int
+	cancel   context.CancelFunc
+	resource ResolvedResource
+	err      error
+}
+
+// do calls fn unless a call with the same key is already in flight, in
+// which case it waits for that call instead, and returns its result.
+// Every caller gets the same resource or error. fn is called in its own
+// goroutine so that a caller can stop waiting as soon as its ctx is
+// done, in which case ctx's error is returned, without cancelling the
+// call for the other callers. The ctx fn is called with carries the
+// values of the first caller's ctx but not its deadline or
+// cancellation, and is only cancelled once every caller has stopped
+// waiting, so that e.g. a download nobody needs anymore is aborted.
+func (c *coalescer) do(ctx context.Context, key string, fn func(context.Context) (ResolvedResource, error)) (ResolvedResource, error) {
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = map[string]*coalescedCall{}
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		call = &coalescedCall{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = call
+		go func() {
+			defer cancel()
+			call.resource, call.err = fn(callCtx)
+			c.mu.Lock()
+			c.forget(key, call)
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	call.waiting++
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.resource, call.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		call.waiting--
+		if call.waiting == 0 {
+			// A caller arriving from now on starts a new call rather
+			// than joining the cancelled one.
+			c.forget(key, call)
+			call.cancel()
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes the given call from the calls in flight unless
+// another call has already taken its key. c.mu must be held.
+func (c *coalescer) forget(key string, call *coalescedCall) {
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// waiters returns the number of callers that have waited for the call
+// in flight with the given key.
+func (c *coalescer) waiters(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if call, ok := c.calls[key]; ok {
+		return call.waiters
+	}
+	return 0
+}
+
+// resolveCoalesced resolves the params with the reconciler's resolver,
+// sharing a single call to Resolve between all the resolutions of the
+// same params, by the same type of resolver and for the same
+// namespace, that are in flight at the same time. This protects remote
+// locations from many identical requests when, e.g., lots of
+// PipelineRuns referencing the same Task start at once.
+func (r *Reconciler) resolveCoalesced(ctx context.Context, resolverType string, timeout time.Duration, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	key, err := coalesceKey(resolverType, resolutioncommon.RequestNamespace(ctx), params)
+	if err != nil {
+		return r.resolveBreaking(ctx, params)
+	}
+	return r.inflight.do(ctx, key, func(sharedCtx context.Context) (ResolvedResource, error) {
+		// The call is shared by all the resolutions waiting for it, so
+		// it mustn't be cancelled along with the one that started it,
+		// only once none of them are waiting anymore.
+		sharedCtx, cancel := context.WithTimeout(sharedCtx, timeout)
+		defer cancel()
+		return r.resolveBreaking(sharedCtx, params)
+	})
+}
+
+// coalesceKey returns the key identifying resolutions that can share a
+// single call to Resolve.
+func coalesceKey(resolverType, namespace string, params []pipelinev1beta1.Param) (string, error) {
+	sorted := append([]pipelinev1beta1.Param{}, params...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	b, err := json.Marshal(struct {
+		Type      string                  `json:"type"`
+		Namespace string                  `json:"namespace"`
+		Params    []pipelinev1beta1.Param `json:"params"`
+	}{resolverType, namespace, sorted})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// detachedContext carries the values of its parent, such as the
+// resolver's config and the request's namespace, but not its deadline
+// or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+var _ context.Context = detachedContext{}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
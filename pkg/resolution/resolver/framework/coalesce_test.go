@@ -0,0 +1,347 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// blockingResolver is a FakeResolver that counts its calls to Resolve
+// and blocks them until release is closed.
+type blockingResolver struct {
+	FakeResolver
+	calls   int32
+	release chan struct{}
+}
+
+func (r *blockingResolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	atomic.AddInt32(&r.calls, 1)
+	<-r.release
+	return r.FakeResolver.Resolve(ctx, params)
+}
+
+func TestResolveCoalesced(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		resource        *FakeResolvedResource
+		expectedContent string
+		expectedErr     string
+	}{{
+		name:            "result is shared",
+		resource:        &FakeResolvedResource{Content: "some content"},
+		expectedContent: "some content",
+	}, {
+		name:        "error is shared",
+		resource:    &FakeResolvedResource{ErrorWith: "fake failure"},
+		expectedErr: "fake failure",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &blockingResolver{
+				FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{"foo": tc.resource}},
+				release:      make(chan struct{}),
+			}
+			r := &Reconciler{resolver: resolver}
+			ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "ns")
+			key, err := coalesceKey(LabelValueFakeResolverType, "ns", fakeParams("foo"))
+			if err != nil {
+				t.Fatalf("unexpected error building key: %v", err)
+			}
+
+			const callers = 10
+			var wg sync.WaitGroup
+			resources := make([]ResolvedResource, callers)
+			errs := make([]error, callers)
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					resources[i], errs[i] = r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams("foo"))
+				}(i)
+			}
+			waitForWaiters(t, &r.inflight, key, callers)
+			close(resolver.release)
+			wg.Wait()
+
+			if calls := atomic.LoadInt32(&resolver.calls); calls != 1 {
+				t.Errorf("expected a single call to Resolve but got %d", calls)
+			}
+			for i := 0; i < callers; i++ {
+				if tc.expectedErr != "" {
+					if errs[i] == nil || errs[i].Error() != tc.expectedErr {
+						t.Errorf("caller %d: expected error %q but got %v", i, tc.expectedErr, errs[i])
+					}
+					continue
+				}
+				if errs[i] != nil {
+					t.Fatalf("caller %d: unexpected error: %v", i, errs[i])
+				}
+				if string(resources[i].Data()) != tc.expectedContent {
+					t.Errorf("caller %d: expected content %q but got %q", i, tc.expectedContent, string(resources[i].Data()))
+				}
+			}
+		})
+	}
+}
+
+func TestResolveCoalescedDifferentKeys(t *testing.T) {
+	resolver := &blockingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "foo"},
+			"bar": {Content: "bar"},
+		}},
+		release: make(chan struct{}),
+	}
+	close(resolver.release)
+	r := &Reconciler{resolver: resolver}
+
+	for _, tc := range []struct {
+		namespace string
+		param     string
+	}{{"ns", "foo"}, {"ns", "bar"}, {"other", "foo"}} {
+		ctx := resolutioncommon.InjectRequestNamespace(context.Background(), tc.namespace)
+		resource, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams(tc.param))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(resource.Data()) != tc.param {
+			t.Errorf("expected content %q but got %q", tc.param, string(resource.Data()))
+		}
+	}
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 3 {
+		t.Errorf("expected 3 calls to Resolve but got %d", calls)
+	}
+}
+
+func TestResolveCoalescedWaiterCancelled(t *testing.T) {
+	resolver := &blockingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "some content"},
+		}},
+		release: make(chan struct{}),
+	}
+	r := &Reconciler{resolver: resolver}
+	key, err := coalesceKey(LabelValueFakeResolverType, "", fakeParams("foo"))
+	if err != nil {
+		t.Fatalf("unexpected error building key: %v", err)
+	}
+
+	// The first caller starts the call and is then cancelled, which
+	// mustn't cancel the call for the second caller.
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := r.resolveCoalesced(firstCtx, LabelValueFakeResolverType, time.Minute, fakeParams("foo"))
+		firstErr <- err
+	}()
+	waitForWaiters(t, &r.inflight, key, 1)
+
+	type result struct {
+		resource ResolvedResource
+		err      error
+	}
+	second := make(chan result, 1)
+	go func() {
+		resource, err := r.resolveCoalesced(context.Background(), LabelValueFakeResolverType, time.Minute, fakeParams("foo"))
+		second <- result{resource, err}
+	}()
+	waitForWaiters(t, &r.inflight, key, 2)
+
+	cancelFirst()
+	select {
+	case err := <-firstErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the cancelled caller to get context.Canceled but got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the cancelled caller to stop waiting")
+	}
+
+	close(resolver.release)
+	res := <-second
+	if res.err != nil {
+		t.Fatalf("unexpected error for the second caller: %v", res.err)
+	}
+	if string(res.resource.Data()) != "some content" {
+		t.Errorf("expected content %q but got %q", "some content", string(res.resource.Data()))
+	}
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 1 {
+		t.Errorf("expected a single call to Resolve but got %d", calls)
+	}
+}
+
+// abortableResolver is a FakeResolver whose Resolve blocks until its
+// ctx is done and then reports ctx's error.
+type abortableResolver struct {
+	FakeResolver
+	aborted chan error
+}
+
+func (r *abortableResolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	<-ctx.Done()
+	r.aborted <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestResolveCoalescedAllWaitersCancelled(t *testing.T) {
+	resolver := &abortableResolver{aborted: make(chan error, 1)}
+	r := &Reconciler{resolver: resolver}
+	key, err := coalesceKey(LabelValueFakeResolverType, "", fakeParams("foo"))
+	if err != nil {
+		t.Fatalf("unexpected error building key: %v", err)
+	}
+
+	var cancels []context.CancelFunc
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+		go func() {
+			_, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams("foo"))
+			errs <- err
+		}()
+		waitForWaiters(t, &r.inflight, key, i+1)
+	}
+
+	// The call carries on while one of its callers is still waiting.
+	cancels[0]()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled caller to get context.Canceled but got %v", err)
+	}
+	select {
+	case err := <-resolver.aborted:
+		t.Fatalf("expected the call not to be cancelled while a caller is waiting but it was: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once nobody is waiting for it the call is cancelled.
+	cancels[1]()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled caller to get context.Canceled but got %v", err)
+	}
+	select {
+	case err := <-resolver.aborted:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the call to be cancelled but got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the call to be cancelled once no caller was waiting")
+	}
+}
+
+// lingeringResolver is a FakeResolver whose first call to Resolve only
+// returns once both its ctx is done and linger is closed, as if it took
+// a while to abort.
+type lingeringResolver struct {
+	FakeResolver
+	calls  int32
+	linger chan struct{}
+}
+
+func (r *lingeringResolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	if atomic.AddInt32(&r.calls, 1) == 1 {
+		<-ctx.Done()
+		<-r.linger
+		return nil, ctx.Err()
+	}
+	return r.FakeResolver.Resolve(ctx, params)
+}
+
+func TestResolveCoalescedJoinAfterAllWaitersCancelled(t *testing.T) {
+	resolver := &lingeringResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{"foo": {Content: "foo content"}}},
+		linger:       make(chan struct{}),
+	}
+	defer close(resolver.linger)
+	r := &Reconciler{resolver: resolver}
+	key, err := coalesceKey(LabelValueFakeResolverType, "", fakeParams("foo"))
+	if err != nil {
+		t.Fatalf("unexpected error building key: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams("foo"))
+		errs <- err
+	}()
+	waitForWaiters(t, &r.inflight, key, 1)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled caller to get context.Canceled but got %v", err)
+	}
+
+	// The cancelled call is still aborting, but an identical resolution
+	// arriving now doesn't join it.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resource, err := r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams("foo"))
+	if err != nil {
+		t.Fatalf("expected a new call for the caller arriving after the others cancelled but got %v", err)
+	}
+	if string(resource.Data()) != "foo content" {
+		t.Errorf("unexpected content %q", resource.Data())
+	}
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 2 {
+		t.Errorf("expected 2 calls to Resolve but got %d", calls)
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	params := []pipelinev1beta1.Param{{
+		Name:  "a",
+		Value: *pipelinev1beta1.NewStructuredValues("1"),
+	}, {
+		Name:  "b",
+		Value: *pipelinev1beta1.NewStructuredValues("2"),
+	}}
+	reordered := []pipelinev1beta1.Param{params[1], params[0]}
+
+	key, err := coalesceKey("hub", "ns", params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other, _ := coalesceKey("hub", "ns", reordered); other != key {
+		t.Errorf("expected the order of params not to matter but got %q and %q", key, other)
+	}
+	if other, _ := coalesceKey("hub", "other", params); other == key {
+		t.Errorf("expected different namespaces to have different keys")
+	}
+	if other, _ := coalesceKey("git", "ns", params); other == key {
+		t.Errorf("expected different resolver types to have different keys")
+	}
+}
+
+// waitForWaiters waits until the given number of callers are waiting
+// for the call with the given key.
+func waitForWaiters(t *testing.T, c *coalescer, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for c.waiters(key) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d callers, got %d", n, c.waiters(key))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
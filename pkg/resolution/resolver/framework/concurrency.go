@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"knative.dev/pkg/logging"
+)
+
+// ConfigMaxConcurrentResolutions is the key in a resolver's ConfigMap
+// for the maximum number of calls to its Resolve method that may be in
+// flight at once. Further resolutions wait for a call to finish. Zero,
+// the default, means no limit.
+const ConfigMaxConcurrentResolutions = "max-concurrent-resolutions"
+
+// concurrencyLimiter hands out a limited number of slots to its callers
+// in the order they asked for them. The limit is given with every call
+// to acquire so that changes to it take effect straight away. Its zero
+// value is ready to use.
+type concurrencyLimiter struct {
+	mu sync.Mutex
+	// limit is the number of slots, or zero or less for no limit.
+	limit  int
+	active int
+	// waiting holds a channel per caller waiting for a slot, which is
+	// closed once the caller is given one.
+	waiting []chan struct{}
+}
+
+// acquire waits until a slot is free, or ctx is done in which case its
+// error is returned. Callers that acquire a slot must release it.
+func (l *concurrencyLimiter) acquire(ctx context.Context, limit int) error {
+	l.mu.Lock()
+	l.limit = limit
+	slot := make(chan struct{})
+	l.waiting = append(l.waiting, slot)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-slot:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-slot:
+		// The slot was handed out just as ctx was done.
+		l.active--
+		l.dispatch()
+	default:
+		for i, s := range l.waiting {
+			if s == slot {
+				l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// release frees a slot for the next waiting caller.
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.dispatch()
+}
+
+// dispatch hands out free slots to waiting callers. l.mu must be held.
+func (l *concurrencyLimiter) dispatch() {
+	for len(l.waiting) > 0 && (l.limit <= 0 || l.active < l.limit) {
+		close(l.waiting[0])
+		l.waiting = l.waiting[1:]
+		l.active++
+	}
+}
+
+// maxConcurrentResolutions returns the limit on in-flight resolutions
+// set in the resolver's config. Invalid values are ignored so that a
+// typo doesn't stop every resolution.
+func maxConcurrentResolutions(ctx context.Context) int {
+	value, ok := GetResolverConfigFromContext(ctx)[ConfigMaxConcurrentResolutions]
+	if !ok || value == "" {
+		return 0
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a non-negative integer", ConfigMaxConcurrentResolutions, value)
+		return 0
+	}
+	return limit
+}
+
+// resolveLimited calls the reconciler's resolver once fewer than the
+// configured maximum number of resolutions are in flight, waiting until
+// ctx is done at the latest.
+func (r *Reconciler) resolveLimited(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	if err := r.concurrency.acquire(ctx, maxConcurrentResolutions(ctx)); err != nil {
+		return nil, err
+	}
+	defer r.concurrency.release()
+	return tracedResolve(ctx, r.resolver, params)
+}
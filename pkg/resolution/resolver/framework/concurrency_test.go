@@ -0,0 +1,200 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+func TestConcurrencyLimiterWaitsForSlot(t *testing.T) {
+	var l concurrencyLimiter
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := l.acquire(ctx, 2); err != nil {
+			t.Fatalf("unexpected error acquiring slot %d: %v", i, err)
+		}
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- l.acquire(ctx, 2)
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("expected the third caller to wait but it returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("unexpected error acquiring freed slot: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the third caller to get the freed slot")
+	}
+}
+
+func TestConcurrencyLimiterDeadline(t *testing.T) {
+	var l concurrencyLimiter
+	if err := l.acquire(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while waiting but got %v", err)
+	}
+
+	// The caller that gave up mustn't hold on to a slot or a place in
+	// the queue.
+	l.release()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.acquire(ctx, 1); err != nil {
+		t.Fatalf("expected the freed slot to be available but got %v", err)
+	}
+}
+
+func TestConcurrencyLimiterLimitRaised(t *testing.T) {
+	var l concurrencyLimiter
+	if err := l.acquire(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- l.acquire(context.Background(), 1)
+	}()
+	waitForQueued(t, &l, 1)
+
+	// Raising the limit hands the new slots to the waiting caller first.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.acquire(ctx, 3); err != nil {
+		t.Fatalf("unexpected error acquiring with a raised limit: %v", err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the waiting caller to get a slot once the limit was raised")
+	}
+}
+
+func TestConcurrencyLimiterUnlimited(t *testing.T) {
+	var l concurrencyLimiter
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		if err := l.acquire(ctx, 0); err != nil {
+			t.Fatalf("unexpected error acquiring slot %d: %v", i, err)
+		}
+	}
+}
+
+func TestMaxConcurrentResolutions(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected int
+	}{
+		{"", 0},
+		{"5", 5},
+		{"0", 0},
+		{"-1", 0},
+		{"lots", 0},
+	} {
+		ctx := context.Background()
+		if tc.value != "" {
+			ctx = InjectResolverConfigToContext(ctx, map[string]string{ConfigMaxConcurrentResolutions: tc.value})
+		}
+		if got := maxConcurrentResolutions(ctx); got != tc.expected {
+			t.Errorf("%q: expected %d but got %d", tc.value, tc.expected, got)
+		}
+	}
+}
+
+func TestResolveLimited(t *testing.T) {
+	resolver := &blockingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "foo"},
+			"bar": {Content: "bar"},
+		}},
+		release: make(chan struct{}),
+	}
+	r := &Reconciler{resolver: resolver}
+	ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "ns")
+	ctx = InjectResolverConfigToContext(ctx, map[string]string{ConfigMaxConcurrentResolutions: "1"})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, param := range []string{"foo", "bar"} {
+		wg.Add(1)
+		go func(i int, param string) {
+			defer wg.Done()
+			_, errs[i] = r.resolveCoalesced(ctx, LabelValueFakeResolverType, time.Minute, fakeParams(param))
+		}(i, param)
+	}
+	waitForQueued(t, &r.concurrency, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&resolver.calls) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 1 {
+		t.Errorf("expected a single call to Resolve while the other waits but got %d", calls)
+	}
+
+	close(resolver.release)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("caller %d: unexpected error: %v", i, err)
+		}
+	}
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 2 {
+		t.Errorf("expected 2 calls to Resolve but got %d", calls)
+	}
+}
+
+// waitForQueued waits until the given number of callers are waiting
+// for a slot of the limiter.
+func waitForQueued(t *testing.T, l *concurrencyLimiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.mu.Lock()
+		queued := len(l.waiting)
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d callers waiting but got %d", n, queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
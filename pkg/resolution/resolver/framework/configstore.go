@@ -20,6 +20,7 @@ import (
 	"context"
 
 	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/pkg/configmap"
 )
@@ -48,28 +49,39 @@ type ConfigStore struct {
 	*resolverconfig.Store
 	resolverConfigName string
 	untyped            *configmap.UntypedStore
+	// clusterScopedKeys are the keys of the resolver's config that
+	// namespace-overrides can't set.
+	clusterScopedKeys []string
 }
 
 // NewConfigStore creates a new untyped store for the resolver's configuration and a config.Store for general Pipeline configuration.
-func NewConfigStore(resolverConfigName string, logger configmap.Logger) *ConfigStore {
-	return &ConfigStore{
-		Store:              resolverconfig.NewStore(logger),
+// The onAfterStore funcs are called whenever the general configuration,
+// such as the resolvers' feature flags, changes. When resolverConfigName
+// is empty only the general configuration is stored.
+func NewConfigStore(resolverConfigName string, logger configmap.Logger, onAfterStore ...func(name string, value interface{})) *ConfigStore {
+	store := &ConfigStore{
+		Store:              resolverconfig.NewStore(logger, onAfterStore...),
 		resolverConfigName: resolverConfigName,
-		untyped: configmap.NewUntypedStore(
+	}
+	if resolverConfigName != "" {
+		store.untyped = configmap.NewUntypedStore(
 			"resolver-config",
 			logger,
 			configmap.Constructors{
 				resolverConfigName: DataFromConfigMap,
 			},
-		),
+		)
 	}
+	return store
 }
 
 // WatchConfigs uses the provided configmap.Watcher
 // to setup watches for the config names provided in the
 // Constructors map
 func (store *ConfigStore) WatchConfigs(w configmap.Watcher) {
-	store.untyped.WatchConfigs(w)
+	if store.untyped != nil {
+		store.untyped.WatchConfigs(w)
+	}
 	store.Store.WatchConfigs(w)
 }
 
@@ -77,6 +89,9 @@ func (store *ConfigStore) WatchConfigs(w configmap.Watcher) {
 // configuration or an empty map if the stored config is nil or invalid.
 func (store *ConfigStore) GetResolverConfig() map[string]string {
 	resolverConfig := map[string]string{}
+	if store.untyped == nil {
+		return resolverConfig
+	}
 	untypedConf := store.untyped.UntypedLoad(store.resolverConfigName)
 	if conf, ok := untypedConf.(map[string]string); ok {
 		for key, val := range conf {
@@ -87,9 +102,11 @@ func (store *ConfigStore) GetResolverConfig() map[string]string {
 }
 
 // ToContext returns a new context with the resolver's configuration
-// data stored in it.
+// data stored in it. The settings the namespace-overrides config sets
+// for the namespace of the resolution request in ctx, if any, take
+// precedence over the rest of the configuration.
 func (store *ConfigStore) ToContext(ctx context.Context) context.Context {
-	conf := store.GetResolverConfig()
+	conf := ApplyNamespaceOverrides(ctx, store.GetResolverConfig(), resolutioncommon.RequestNamespace(ctx), store.clusterScopedKeys...)
 	return InjectResolverConfigToContext(store.Store.ToContext(ctx), conf)
 }
 
@@ -17,9 +17,12 @@
 package framework
 
 import (
+	"context"
 	"testing"
 
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/configmap"
 	logtesting "knative.dev/pkg/logging/testing"
 )
@@ -76,6 +79,57 @@ func TestGetResolverConfig(t *testing.T) {
 	}
 }
 
+// TestConfigStoreFeatureFlagsChange checks that changes to the resolvers'
+// feature flags are reflected in the contexts of later resolutions.
+func TestConfigStoreFeatureFlagsChange(t *testing.T) {
+	changes := 0
+	store := NewConfigStore("test", logtesting.TestLogger(t), func(string, interface{}) {
+		changes++
+	})
+	setFlags := func(enabled string) {
+		store.Store.OnConfigChanged(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: resolverconfig.GetFeatureFlagsConfigName()},
+			Data:       map[string]string{resolverconfig.EnableBundlesResolver: enabled},
+		})
+	}
+
+	setFlags("true")
+	flags := resolverconfig.FromContextOrDefaults(store.ToContext(context.Background())).FeatureFlags
+	if !flags.EnableBundleResolver {
+		t.Errorf("expected bundle resolver to be enabled")
+	}
+	if flags.EnableGitResolver {
+		t.Errorf("expected git resolver that isn't listed to be disabled")
+	}
+
+	setFlags("false")
+	flags = resolverconfig.FromContextOrDefaults(store.ToContext(context.Background())).FeatureFlags
+	if flags.EnableBundleResolver {
+		t.Errorf("expected bundle resolver to be disabled")
+	}
+	if changes != 2 {
+		t.Errorf("expected 2 feature flag changes to be observed but got %d", changes)
+	}
+}
+
+// TestConfigStoreWithoutResolverConfig checks that a store created
+// without a resolver config name only holds the feature flags.
+func TestConfigStoreWithoutResolverConfig(t *testing.T) {
+	store := NewConfigStore("", logtesting.TestLogger(t))
+	store.Store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: resolverconfig.GetFeatureFlagsConfigName()},
+		Data:       map[string]string{"enable-demo-resolver": "true"},
+	})
+
+	ctx := store.ToContext(context.Background())
+	if !resolverconfig.FromContextOrDefaults(ctx).FeatureFlags.IsResolverEnabled("demo") {
+		t.Errorf("expected demo resolver to be enabled")
+	}
+	if conf := GetResolverConfigFromContext(ctx); len(conf) != 0 {
+		t.Errorf("expected empty resolver config but got %#v", conf)
+	}
+}
+
 func mapsAreEqual(m1, m2 map[string]string) bool {
 	if m1 == nil || m2 == nil {
 		return m1 == nil && m2 == nil
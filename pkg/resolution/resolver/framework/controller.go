@@ -26,9 +26,14 @@ import (
 	rrinformer "github.com/tektoncd/pipeline/pkg/client/resolution/injection/informers/resolution/v1beta1/resolutionrequest"
 	rrlister "github.com/tektoncd/pipeline/pkg/client/resolution/listers/resolution/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/configmap"
@@ -58,6 +63,7 @@ func NewController(ctx context.Context, resolver Resolver, modifiers ...Reconcil
 		if err := resolver.Initialize(ctx); err != nil {
 			panic(err.Error())
 		}
+		registerMetrics(ctx)
 
 		r := &Reconciler{
 			LeaderAwareFuncs:           leaderAwareFuncs(rrInformer.Lister()),
@@ -65,24 +71,35 @@ func NewController(ctx context.Context, resolver Resolver, modifiers ...Reconcil
 			resolutionRequestLister:    rrInformer.Lister(),
 			resolutionRequestClientSet: rrclientset,
 			resolver:                   resolver,
+			recorder:                   eventRecorder(ctx, resolver),
 		}
+		applyModifiersAndDefaults(ctx, r, modifiers)
 
-		watchConfigChanges(ctx, r, cmw)
+		// The feature flags can be changed before the controller below
+		// exists, in which case there's nothing to resync yet.
+		var impl *controller.Impl
+		filterFunc := filterResolutionRequestsBySelector(resolver.GetSelector(ctx))
+		watchConfigChanges(ctx, r, cmw, func() {
+			if impl != nil {
+				logger.Infof("Resolver feature flags changed, resyncing %s resolution requests", resolver.GetName(ctx))
+				impl.FilteredGlobalResync(filterFunc, rrInformer.Informer())
+			}
+		})
 
 		// TODO(sbwsg): Do better sanitize.
 		resolverName := resolver.GetName(ctx)
 		resolverName = strings.ReplaceAll(resolverName, "/", "")
 		resolverName = strings.ReplaceAll(resolverName, " ", "")
 
-		applyModifiersAndDefaults(ctx, r, modifiers)
-
-		impl := controller.NewContext(ctx, r, controller.ControllerOptions{
+		impl = controller.NewContext(ctx, r, controller.ControllerOptions{
 			WorkQueueName: "TektonResolverFramework." + resolverName,
 			Logger:        logger,
 		})
 
+		r.runHealthChecks(ctx)
+
 		rrInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
-			FilterFunc: filterResolutionRequestsBySelector(resolver.GetSelector(ctx)),
+			FilterFunc: filterFunc,
 			Handler: cache.ResourceEventHandlerFuncs{
 				AddFunc: impl.Enqueue,
 				UpdateFunc: func(oldObj, newObj interface{}) {
@@ -93,6 +110,13 @@ func NewController(ctx context.Context, resolver Resolver, modifiers ...Reconcil
 				// DeleteFunc: impl.Enqueue,
 			},
 		})
+		// Deleted requests aren't filtered by the resolver's selector,
+		// which doesn't unwrap the tombstones of requests whose deletion
+		// was missed, since only the resolutions this reconciler has in
+		// flight can be cancelled anyway.
+		rrInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			DeleteFunc: r.resolutions.cancelDeleted,
+		})
 
 		return impl
 	}
@@ -158,18 +182,54 @@ func validateResolver(ctx context.Context, r Resolver) error {
 }
 
 // watchConfigChanges binds a framework.Resolver to updates on its
-// configmap, using knative's configmap helpers. This is only done if
-// the resolver implements the framework.ConfigWatcher interface.
-func watchConfigChanges(ctx context.Context, reconciler *Reconciler, cmw configmap.Watcher) {
+// configmap and the resolvers' feature flags, using knative's configmap
+// helpers. This is only done if the resolver implements the
+// framework.ConfigWatcher interface or, for the feature flags alone, if
+// the reconciler gates the resolver on its feature flag.
+// onFeatureFlagsChange is called whenever the feature flags change, e.g.
+// to enable or disable a resolver, so that they take effect without
+// restarting the resolver.
+func watchConfigChanges(ctx context.Context, reconciler *Reconciler, cmw configmap.Watcher, onFeatureFlagsChange func()) {
+	var resolverConfigName string
 	if configWatcher, ok := reconciler.resolver.(ConfigWatcher); ok {
-		logger := logging.FromContext(ctx)
-		resolverConfigName := configWatcher.GetConfigName(ctx)
+		resolverConfigName = configWatcher.GetConfigName(ctx)
 		if resolverConfigName == "" {
 			panic("resolver returned empty config name")
 		}
-		reconciler.configStore = NewConfigStore(resolverConfigName, logger)
-		reconciler.configStore.WatchConfigs(cmw)
+	} else if reconciler.gatedType == "" {
+		return
 	}
+	reconciler.configStore = NewConfigStore(resolverConfigName, logging.FromContext(ctx), func(string, interface{}) {
+		onFeatureFlagsChange()
+	})
+	if scoped, ok := reconciler.resolver.(ClusterScopedConfig); ok {
+		reconciler.configStore.clusterScopedKeys = scoped.ClusterScopedConfigKeys(ctx)
+	}
+	reconciler.configStore.WatchConfigs(cmw)
+}
+
+// eventRecorder returns the event recorder in ctx or, if there isn't
+// one, a new recorder that sends events to the API server until ctx is
+// done.
+func eventRecorder(ctx context.Context, resolver Resolver) record.EventRecorder {
+	if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+		return recorder
+	}
+	logger := logging.FromContext(ctx)
+	eventBroadcaster := record.NewBroadcaster()
+	watches := []watch.Interface{
+		eventBroadcaster.StartLogging(logger.Named("event-broadcaster").Infof),
+		eventBroadcaster.StartRecordingToSink(
+			&typedcorev1.EventSinkImpl{Interface: kubeclient.Get(ctx).CoreV1().Events("")}),
+	}
+	go func() {
+		<-ctx.Done()
+		for _, w := range watches {
+			w.Stop()
+		}
+	}()
+	component := strings.ToLower(resolver.GetSelector(ctx)[common.LabelKeyResolverType]) + "-resolver"
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
 }
 
 // applyModifiersAndDefaults applies the given modifiers to
@@ -183,4 +243,7 @@ func applyModifiersAndDefaults(ctx context.Context, r *Reconciler, modifiers []R
 	if r.Clock == nil {
 		r.Clock = clock.RealClock{}
 	}
+	if r.healthChecks == nil {
+		r.healthChecks = DefaultHealthChecks
+	}
 }
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// credentialProviderKey is the context key associated with the
+// CredentialProvider resolvers consult for auth material.
+var credentialProviderKey = struct{ name string }{"credential-provider"}
+
+// CredentialRequest describes the credentials a resolution asks for.
+type CredentialRequest struct {
+	// Namespace is the namespace of the ResolutionRequest.
+	Namespace string
+	// ServiceAccount is the service account, in Namespace, named by the
+	// request's params, if any.
+	ServiceAccount string
+	// Secret is the secret, in Namespace, named by the request's params,
+	// if any, and SecretKey the key within it holding the credentials.
+	Secret    string
+	SecretKey string
+}
+
+// CredentialProvider supplies the auth material resolvers use to reach
+// their backends, e.g. by minting short-lived tokens with workload
+// identity or reading them from Vault. Each method is keyed by the
+// target the credentials are for.
+//
+// When no CredentialProvider is configured resolvers read the secrets
+// and service accounts named by a request's params from the request's
+// namespace.
+type CredentialProvider interface {
+	// RegistryKeychain returns the keychain used to pull images from
+	// the given registry host, e.g. "gcr.io" or "registry:5000".
+	RegistryKeychain(ctx context.Context, registry string, req CredentialRequest) (authn.Keychain, error)
+
+	// Token returns the bearer token sent with requests to the given
+	// url, e.g. the api of a hub, or an empty string if requests to it
+	// aren't authenticated.
+	Token(ctx context.Context, url string, req CredentialRequest) (string, error)
+}
+
+// WithCredentialProvider returns a ReconcilerModifier that makes the
+// given CredentialProvider available to the resolver in the context of
+// each resolution.
+func WithCredentialProvider(provider CredentialProvider) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.credentialProvider = provider
+	}
+}
+
+// InjectCredentialProviderToContext returns a new context with the given
+// CredentialProvider stored in it.
+func InjectCredentialProviderToContext(ctx context.Context, provider CredentialProvider) context.Context {
+	return context.WithValue(ctx, credentialProviderKey, provider)
+}
+
+// GetCredentialProviderFromContext returns the CredentialProvider
+// stored in the context, or nil if there isn't one, in which case the
+// resolver should fall back to reading Kubernetes secrets and service
+// accounts.
+func GetCredentialProviderFromContext(ctx context.Context) CredentialProvider {
+	provider, _ := ctx.Value(credentialProviderKey).(CredentialProvider)
+	return provider
+}
+
+// GetCredentialSecret returns the named secret from the given namespace,
+// read only once a resolution needs the credentials it holds. A secret
+// that doesn't exist or that the resolvers aren't allowed to read is
+// reported with a CredentialAccessError, see WrapCredentialError. The
+// description names the credentials in errors, e.g. "git credentials".
+// The values of the secret are redacted from everything logged in ctx
+// afterwards, see WithRedaction.
+func GetCredentialSecret(ctx context.Context, client kubernetes.Interface, namespace, name, description string) (*corev1.Secret, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, credentialError(err, "secrets", name, namespace, description)
+	}
+	for _, value := range secret.Data {
+		RedactSecret(ctx, string(value))
+	}
+	for _, value := range secret.StringData {
+		RedactSecret(ctx, value)
+	}
+	return secret, nil
+}
+
+// WrapCredentialError wraps an error from reading the secrets or
+// service accounts holding credentials from the given namespace, e.g.
+// one returned by k8schain.New, naming the resource that couldn't be
+// read. Forbidden and NotFound errors from the Kubernetes API become a
+// CredentialAccessError, telling operators which RBAC permission the
+// resolvers are missing in the Forbidden case. Other errors are wrapped
+// with %w.
+func WrapCredentialError(err error, namespace, description string) error {
+	var resource, name string
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Details != nil {
+		resource, name = status.Status().Details.Kind, status.Status().Details.Name
+	}
+	return credentialError(err, resource, name, namespace, description)
+}
+
+func credentialError(err error, resource, name, namespace, description string) error {
+	what := "credentials"
+	if resource != "" {
+		what = fmt.Sprintf("%s %s", singularResource(resource), name)
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		return &common.CredentialAccessError{
+			Resource:  resource,
+			Name:      name,
+			Namespace: namespace,
+			Original:  fmt.Errorf("cannot get %s, %s not found in namespace %s", description, what, namespace),
+		}
+	case apierrors.IsForbidden(err):
+		rbacResource := resource
+		if rbacResource == "" {
+			rbacResource = "secrets and serviceaccounts"
+		}
+		return &common.CredentialAccessError{
+			Resource:  resource,
+			Name:      name,
+			Namespace: namespace,
+			Forbidden: true,
+			Original: fmt.Errorf("cannot get %s, the resolver's service account is not allowed to get %s in namespace %s, grant it get access to %s with a Role and RoleBinding in that namespace",
+				description, what, namespace, rbacResource),
+		}
+	}
+	return fmt.Errorf("error reading %s from %s in namespace %s: %w", description, what, namespace, err)
+}
+
+// singularResource returns the name of a single resource of the given
+// kind, e.g. "service account" for "serviceaccounts".
+func singularResource(resource string) string {
+	if resource == "serviceaccounts" {
+		return "service account"
+	}
+	return strings.TrimSuffix(resource, "s")
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/system"
+)
+
+// fakeCredentialProvider returns the same token for every url.
+type fakeCredentialProvider struct {
+	token string
+}
+
+func (p *fakeCredentialProvider) RegistryKeychain(context.Context, string, CredentialRequest) (authn.Keychain, error) {
+	return authn.DefaultKeychain, nil
+}
+
+func (p *fakeCredentialProvider) Token(context.Context, string, CredentialRequest) (string, error) {
+	return p.token, nil
+}
+
+// credentialRecordingResolver is a FakeResolver recording the
+// CredentialProvider in the context of its last resolution.
+type credentialRecordingResolver struct {
+	FakeResolver
+	provider CredentialProvider
+}
+
+func (r *credentialRecordingResolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	r.provider = GetCredentialProviderFromContext(ctx)
+	return r.FakeResolver.Resolve(ctx, params)
+}
+
+func TestCredentialProviderContext(t *testing.T) {
+	if provider := GetCredentialProviderFromContext(context.Background()); provider != nil {
+		t.Errorf("expected no credential provider but got %v", provider)
+	}
+	provider := &fakeCredentialProvider{token: "minted"}
+	ctx := InjectCredentialProviderToContext(context.Background(), provider)
+	if got := GetCredentialProviderFromContext(ctx); got != provider {
+		t.Errorf("expected the injected credential provider but got %v", got)
+	}
+}
+
+func TestReconcileWithCredentialProvider(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: time.Now()},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: fakeParams("foo"),
+		},
+	}
+	d := test.Data{
+		ConfigMaps: []*corev1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resolverconfig.GetFeatureFlagsConfigName(),
+				Namespace: system.Namespace(),
+			},
+		}},
+		ResolutionRequests: []*v1beta1.ResolutionRequest{rr},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		provider CredentialProvider
+	}{{
+		name: "no provider",
+	}, {
+		name:     "provider",
+		provider: &fakeCredentialProvider{token: "minted"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &credentialRecordingResolver{FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+				"foo": {Content: "some content"},
+			}}}
+			modifiers := []ReconcilerModifier{setClockOnReconciler}
+			if tc.provider != nil {
+				modifiers = append(modifiers, WithCredentialProvider(tc.provider))
+			}
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, d, resolver, modifiers...)
+			defer cancel()
+
+			if err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr)); err != nil {
+				t.Fatalf("unexpected error reconciling: %v", err)
+			}
+			if resolver.provider != tc.provider {
+				t.Errorf("expected the resolver to get credential provider %v but got %v", tc.provider, resolver.provider)
+			}
+		})
+	}
+}
+
+func TestGetCredentialSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "foo"},
+		Data:       map[string][]byte{"token": []byte("hunter2")},
+	}
+
+	for _, tc := range []struct {
+		name              string
+		secretName        string
+		getErr            error
+		expectedErr       string
+		expectedForbidden bool
+		expectedAccessErr bool
+	}{{
+		name:       "found",
+		secretName: "creds",
+	}, {
+		name:              "not found",
+		secretName:        "missing",
+		expectedErr:       "cannot get git credentials, secret missing not found in namespace foo",
+		expectedAccessErr: true,
+	}, {
+		name:              "forbidden",
+		secretName:        "creds",
+		getErr:            apierrors.NewForbidden(corev1.Resource("secrets"), "creds", errors.New("denied")),
+		expectedErr:       "cannot get git credentials, the resolver's service account is not allowed to get secret creds in namespace foo, grant it get access to secrets with a Role and RoleBinding in that namespace",
+		expectedForbidden: true,
+		expectedAccessErr: true,
+	}, {
+		name:        "other error",
+		secretName:  "creds",
+		getErr:      apierrors.NewServiceUnavailable("try again"),
+		expectedErr: "error reading git credentials from secret creds in namespace foo: try again",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakek8s.NewSimpleClientset(secret)
+			if tc.getErr != nil {
+				client.PrependReactor("get", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tc.getErr
+				})
+			}
+			got, err := GetCredentialSecret(context.Background(), client, "foo", tc.secretName, "git credentials")
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error getting secret: %v", err)
+				}
+				if string(got.Data["token"]) != "hunter2" {
+					t.Errorf("unexpected secret %v", got)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+			}
+			var accessErr *resolutioncommon.CredentialAccessError
+			if errors.As(err, &accessErr) != tc.expectedAccessErr {
+				t.Fatalf("expected CredentialAccessError %t but got %T", tc.expectedAccessErr, err)
+			}
+			if !tc.expectedAccessErr {
+				if !errors.Is(err, tc.getErr) {
+					t.Errorf("expected the error to wrap %v", tc.getErr)
+				}
+				return
+			}
+			expected := resolutioncommon.CredentialAccessError{Resource: "secrets", Name: tc.secretName, Namespace: "foo", Forbidden: tc.expectedForbidden}
+			if accessErr.Resource != expected.Resource || accessErr.Name != expected.Name || accessErr.Namespace != expected.Namespace || accessErr.Forbidden != expected.Forbidden {
+				t.Errorf("expected %+v but got %+v", expected, *accessErr)
+			}
+		})
+	}
+}
+
+func TestWrapCredentialError(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		err          error
+		expectedErr  string
+		expectedName string
+	}{{
+		name:         "service account not found",
+		err:          apierrors.NewNotFound(corev1.Resource("serviceaccounts"), "builder"),
+		expectedErr:  "cannot get registry credentials, service account builder not found in namespace foo",
+		expectedName: "builder",
+	}, {
+		name:         "service account forbidden",
+		err:          apierrors.NewForbidden(corev1.Resource("serviceaccounts"), "builder", errors.New("denied")),
+		expectedErr:  "cannot get registry credentials, the resolver's service account is not allowed to get service account builder in namespace foo, grant it get access to serviceaccounts with a Role and RoleBinding in that namespace",
+		expectedName: "builder",
+	}, {
+		name:        "forbidden without details",
+		err:         &apierrors.StatusError{ErrStatus: metav1.Status{Reason: metav1.StatusReasonForbidden, Code: 403}},
+		expectedErr: "cannot get registry credentials, the resolver's service account is not allowed to get credentials in namespace foo, grant it get access to secrets and serviceaccounts with a Role and RoleBinding in that namespace",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WrapCredentialError(tc.err, "foo", "registry credentials")
+			if err.Error() != tc.expectedErr {
+				t.Errorf("expected error %q but got %q", tc.expectedErr, err.Error())
+			}
+			var accessErr *resolutioncommon.CredentialAccessError
+			if !errors.As(err, &accessErr) {
+				t.Fatalf("expected a CredentialAccessError but got %T", err)
+			}
+			if accessErr.Name != tc.expectedName || accessErr.Namespace != "foo" {
+				t.Errorf("expected the error to name %s in namespace foo but got %+v", tc.expectedName, *accessErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// decodeModes are the values of the decode param.
+var decodeModes = []string{resolutioncommon.DecodeBase64, resolutioncommon.DecodeGzip, resolutioncommon.DecodeGzipBase64}
+
+// DecodeError is returned when the resolved content can't be decoded as
+// the decode param asks, e.g. because it isn't valid base64. The content
+// itself was fetched, so retrying won't help until it or the param
+// changes.
+type DecodeError struct {
+	// Mode is the value of the decode param.
+	Mode     string
+	Original error
+}
+
+var _ error = &DecodeError{}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("resolved content could not be decoded as %s param %q: %v", resolutioncommon.ParamDecode, e.Mode, e.Original)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Original
+}
+
+// decodedResource is a resolved resource whose data is the decoded
+// content of the resource the resolver returned.
+type decodedResource struct {
+	ResolvedResource
+	data        []byte
+	annotations map[string]string
+}
+
+var _ ResolvedResource = &decodedResource{}
+
+// Data returns the decoded content.
+func (d *decodedResource) Data() []byte {
+	return d.data
+}
+
+// Annotations returns the annotations of the resolved resource along
+// with the decode param it was decoded with and the digest of the
+// decoded content.
+func (d *decodedResource) Annotations() map[string]string {
+	return d.annotations
+}
+
+// extractDecodeMode returns the params without the decode param, along
+// with its value or an empty string if it isn't set.
+func extractDecodeMode(params []pipelinev1beta1.Param) ([]pipelinev1beta1.Param, string, error) {
+	var mode string
+	rest := make([]pipelinev1beta1.Param, 0, len(params))
+	for _, p := range params {
+		if p.Name != resolutioncommon.ParamDecode {
+			rest = append(rest, p)
+			continue
+		}
+		if p.Value.Type != pipelinev1beta1.ParamTypeString || !isDecodeMode(p.Value.StringVal) {
+			return nil, "", fmt.Errorf("param %q must be %q, %q or %q", resolutioncommon.ParamDecode, resolutioncommon.DecodeBase64, resolutioncommon.DecodeGzip, resolutioncommon.DecodeGzipBase64)
+		}
+		mode = p.Value.StringVal
+	}
+	if mode == "" {
+		return params, "", nil
+	}
+	return rest, mode, nil
+}
+
+func isDecodeMode(mode string) bool {
+	for _, m := range decodeModes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDecodeMode decodes the content of the resolved resource as the
+// given value of the decode param asks, failing with a DecodeError if it
+// can't be. Resources are passed back untouched when the param isn't
+// set. Decompressed content is read up to just over the max-resolved-size
+// config, so that a small gzip bomb is caught by the size check rather
+// than exhausting the resolvers' memory, or DefaultMaxResponseSize when
+// that isn't set.
+func applyDecodeMode(ctx context.Context, resource ResolvedResource, mode string) (ResolvedResource, error) {
+	if mode == "" {
+		return resource, nil
+	}
+	data := resource.Data()
+	var err error
+	if mode == resolutioncommon.DecodeBase64 || mode == resolutioncommon.DecodeGzipBase64 {
+		if data, err = decodeBase64(data); err != nil {
+			return nil, &DecodeError{Mode: mode, Original: fmt.Errorf("invalid base64: %w", err)}
+		}
+	}
+	if mode == resolutioncommon.DecodeGzip || mode == resolutioncommon.DecodeGzipBase64 {
+		maxSize := maxResolvedSize(ctx)
+		limit := maxSize
+		if limit <= 0 {
+			limit = DefaultMaxResponseSize
+		}
+		if data, err = gunzip(data, limit); err != nil {
+			return nil, &DecodeError{Mode: mode, Original: fmt.Errorf("invalid gzip: %w", err)}
+		}
+		if maxSize <= 0 && int64(len(data)) > limit {
+			// Without a max-resolved-size the size check won't catch it.
+			return nil, &DecodeError{Mode: mode, Original: fmt.Errorf("decompressed content exceeds %d bytes", limit)}
+		}
+	}
+	annotations := make(map[string]string, len(resource.Annotations())+1)
+	for k, v := range resource.Annotations() {
+		annotations[k] = v
+	}
+	annotations[resolutioncommon.AnnotationKeyDecoded] = mode
+	// The source and provenance of the resource, e.g. a bundle pinned
+	// to its digest, still describe the encoded content, so the digest
+	// of the decoded content is passed back on its own.
+	sum := sha256.Sum256(data)
+	annotations[resolutioncommon.AnnotationKeyDecodedDigest] = "sha256:" + hex.EncodeToString(sum[:])
+	return &decodedResource{
+		ResolvedResource: resource,
+		data:             data,
+		annotations:      annotations,
+	}, nil
+}
+
+// decodeBase64 decodes standard base64, with or without padding. Line
+// breaks and other whitespace, e.g. from wrapping the encoded content in
+// a YAML file, are ignored.
+func decodeBase64(data []byte) ([]byte, error) {
+	encoded := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(data))
+	if strings.HasSuffix(encoded, "=") || len(encoded)%4 == 0 {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	return base64.RawStdEncoding.DecodeString(encoded)
+}
+
+// gunzip decompresses the data, reading at most one byte more than
+// maxSize. Truncated or corrupt data fails its checksum or length check
+// once it is read to the end.
+func gunzip(data []byte, maxSize int64) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, maxSize+1))
+}
@@ -0,0 +1,386 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	"github.com/tektoncd/pipeline/test/diff"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func withDecode(params []pipelinev1beta1.Param, mode string) []pipelinev1beta1.Param {
+	return append(params, pipelinev1beta1.Param{
+		Name:  resolutioncommon.ParamDecode,
+		Value: *pipelinev1beta1.NewStructuredValues(mode),
+	})
+}
+
+// gzipped returns the content compressed with gzip.
+func gzipped(t *testing.T, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatalf("gzipping content: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("gzipping content: %v", err)
+	}
+	return buf.String()
+}
+
+func TestExtractDecodeMode(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		params       []pipelinev1beta1.Param
+		expectedMode string
+		expectedErr  string
+	}{{
+		name:   "not set",
+		params: fakeParams("foo"),
+	}, {
+		name:         "base64",
+		params:       withDecode(fakeParams("foo"), "base64"),
+		expectedMode: "base64",
+	}, {
+		name:         "gzip",
+		params:       withDecode(fakeParams("foo"), "gzip"),
+		expectedMode: "gzip",
+	}, {
+		name:         "gzip+base64",
+		params:       withDecode(fakeParams("foo"), "gzip+base64"),
+		expectedMode: "gzip+base64",
+	}, {
+		name:        "unknown mode",
+		params:      withDecode(fakeParams("foo"), "zip"),
+		expectedErr: `param "decode" must be "base64", "gzip" or "gzip+base64"`,
+	}, {
+		name: "array value",
+		params: append(fakeParams("foo"), pipelinev1beta1.Param{
+			Name:  resolutioncommon.ParamDecode,
+			Value: *pipelinev1beta1.NewStructuredValues("base64", "gzip"),
+		}),
+		expectedErr: `param "decode" must be "base64", "gzip" or "gzip+base64"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, mode, err := extractDecodeMode(tc.params)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(fakeParams("foo"), params); d != "" {
+				t.Errorf("unexpected params: %s", diff.PrintWantGot(d))
+			}
+			if mode != tc.expectedMode {
+				t.Errorf("expected mode %q but got %q", tc.expectedMode, mode)
+			}
+		})
+	}
+}
+
+func TestDryRunDecode(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(singleDocument))
+	compressed := gzipped(t, singleDocument)
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"plain":           {Content: singleDocument},
+			"base64":          {Content: encoded},
+			"base64-wrapped":  {Content: encoded[:20] + "\n" + encoded[20:40] + "\r\n  " + encoded[40:] + "\n"},
+			"base64-unpadded": {Content: strings.TrimRight(encoded, "=")},
+			"gzip":            {Content: compressed},
+			"gzip+base64":     {Content: base64.StdEncoding.EncodeToString([]byte(compressed))},
+			"corrupt-base64":  {Content: "not-base64"},
+			"corrupt-gzip":    {Content: "this isn't gzipped content"},
+			"truncated-gzip":  {Content: compressed[:len(compressed)-4]},
+			"base64-not-gzip": {Content: encoded},
+		},
+	}
+	for _, tc := range []struct {
+		name        string
+		content     string
+		mode        string
+		expectedErr string
+	}{{
+		name:    "no decode by default",
+		content: "plain",
+	}, {
+		name:    "base64",
+		content: "base64",
+		mode:    "base64",
+	}, {
+		name:    "base64 wrapped over several lines",
+		content: "base64-wrapped",
+		mode:    "base64",
+	}, {
+		name:    "base64 without padding",
+		content: "base64-unpadded",
+		mode:    "base64",
+	}, {
+		name:    "gzip",
+		content: "gzip",
+		mode:    "gzip",
+	}, {
+		name:    "gzip+base64",
+		content: "gzip+base64",
+		mode:    "gzip+base64",
+	}, {
+		name:        "corrupt base64",
+		content:     "corrupt-base64",
+		mode:        "base64",
+		expectedErr: `error resolving with Fake resolver: resolved content could not be decoded as decode param "base64": invalid base64: illegal base64 data at input byte 3`,
+	}, {
+		name:        "corrupt gzip",
+		content:     "corrupt-gzip",
+		mode:        "gzip",
+		expectedErr: `error resolving with Fake resolver: resolved content could not be decoded as decode param "gzip": invalid gzip: gzip: invalid header`,
+	}, {
+		name:        "truncated gzip",
+		content:     "truncated-gzip",
+		mode:        "gzip",
+		expectedErr: `error resolving with Fake resolver: resolved content could not be decoded as decode param "gzip": invalid gzip: unexpected EOF`,
+	}, {
+		name:        "base64 content that isn't gzipped",
+		content:     "base64-not-gzip",
+		mode:        "gzip+base64",
+		expectedErr: `error resolving with Fake resolver: resolved content could not be decoded as decode param "gzip+base64": invalid gzip: gzip: invalid header`,
+	}, {
+		name:        "gzip content decoded as base64",
+		content:     "gzip",
+		mode:        "base64",
+		expectedErr: `error resolving with Fake resolver: resolved content could not be decoded as decode param "base64": invalid base64: illegal base64 data at input byte 0`,
+	}, {
+		name:        "invalid mode",
+		content:     "base64",
+		mode:        "rot13",
+		expectedErr: `invalid params for Fake resolver: param "decode" must be "base64", "gzip" or "gzip+base64"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := fakeParams(tc.content)
+			if tc.mode != "" {
+				params = withDecode(params, tc.mode)
+			}
+			resource, err := DryRun(context.Background(), resolver, params)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				var decodeErr *DecodeError
+				if expected := tc.mode != "rot13"; errors.As(err, &decodeErr) != expected {
+					t.Errorf("expected the error to be a DecodeError: %t, but got %v", expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(singleDocument, string(resource.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+			if decoded := resource.Annotations()[resolutioncommon.AnnotationKeyDecoded]; decoded != tc.mode {
+				t.Errorf("expected the decoded annotation to be %q but got %q", tc.mode, decoded)
+			}
+		})
+	}
+}
+
+func TestDryRunDecodeMultipleDocuments(t *testing.T) {
+	resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"multiple": {Content: base64.StdEncoding.EncodeToString([]byte(multiDocument))},
+	}}
+	params := withDocuments(withDecode(fakeParams("multiple"), "base64"), "multiple")
+	resource, err := DryRun(context.Background(), resolver, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	multi, ok := resource.(MultiDocumentResource)
+	if !ok {
+		t.Fatalf("expected a MultiDocumentResource but got %T", resource)
+	}
+	documents, err := multi.Documents()
+	if err != nil {
+		t.Fatalf("unexpected error getting documents: %v", err)
+	}
+	if len(documents) != 2 {
+		t.Errorf("expected the decoded content to be split into 2 documents but got %d", len(documents))
+	}
+	sum := sha256.Sum256([]byte(multiDocument))
+	expectedAnnotations := map[string]string{
+		resolutioncommon.AnnotationKeyDecoded:       "base64",
+		resolutioncommon.AnnotationKeyDecodedDigest: "sha256:" + hex.EncodeToString(sum[:]),
+		resolutioncommon.AnnotationKeyDocuments:     "2",
+	}
+	if d := cmp.Diff(expectedAnnotations, resource.Annotations()); d != "" {
+		t.Errorf("unexpected annotations: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestDryRunDecodeMaxResolvedSize(t *testing.T) {
+	// A kilobyte of zeros compresses to a few dozen bytes, but the
+	// decompressed content is held to the max-resolved-size.
+	resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"bomb": {Content: gzipped(t, strings.Repeat("\x00", 1024))},
+	}}
+	ctx := InjectResolverConfigToContext(context.Background(), map[string]string{ConfigMaxResolvedSize: "100"})
+	_, err := DryRun(ctx, resolver, withDecode(fakeParams("bomb"), "gzip"))
+	var tooLarge *ResolvedResourceTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected a ResolvedResourceTooLargeError but got %v", err)
+	}
+	if tooLarge.Size != 101 {
+		t.Errorf("expected decompression to stop after 101 bytes but got %d", tooLarge.Size)
+	}
+}
+
+func TestDryRunDecodeDefaultMaxSize(t *testing.T) {
+	// Without a max-resolved-size decompression is still held to
+	// DefaultMaxResponseSize.
+	resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"bomb": {Content: gzipped(t, strings.Repeat("\x00", int(DefaultMaxResponseSize)+1))},
+	}}
+	_, err := DryRun(context.Background(), resolver, withDecode(fakeParams("bomb"), "gzip"))
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError but got %v", err)
+	}
+	if expected := fmt.Sprintf("decompressed content exceeds %d bytes", DefaultMaxResponseSize); decodeErr.Original.Error() != expected {
+		t.Errorf("expected error %q but got %q", expected, decodeErr.Original)
+	}
+}
+
+func TestDryRunDecodeSource(t *testing.T) {
+	source := &v1beta1.ConfigSource{
+		URI:    "registry.example.com/tasks@sha256:290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56",
+		Digest: map[string]string{"sha256": "290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"},
+	}
+	provenance := resolutioncommon.Provenance{
+		ResolverType: LabelValueFakeResolverType,
+		URI:          source.URI,
+		Digest:       source.Digest,
+	}.AnnotationValue()
+	resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"encoded": {
+			Content:       base64.StdEncoding.EncodeToString([]byte(singleDocument)),
+			ContentSource: source,
+			AnnotationMap: map[string]string{resolutioncommon.AnnotationKeyProvenance: provenance},
+		},
+	}}
+	resource, err := DryRun(context.Background(), resolver, withDecode(fakeParams("encoded"), "base64"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The source and provenance, pinned to the encoded content, are
+	// left untouched, and the digest of the decoded content is passed
+	// back on its own.
+	if d := cmp.Diff(source, resource.Source()); d != "" {
+		t.Errorf("unexpected source: %s", diff.PrintWantGot(d))
+	}
+	sum := sha256.Sum256([]byte(singleDocument))
+	expectedAnnotations := map[string]string{
+		resolutioncommon.AnnotationKeyProvenance:    provenance,
+		resolutioncommon.AnnotationKeyDecoded:       "base64",
+		resolutioncommon.AnnotationKeyDecodedDigest: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if d := cmp.Diff(expectedAnnotations, resource.Annotations()); d != "" {
+		t.Errorf("unexpected annotations: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestReconcileDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		content         string
+		expectedFailure string
+	}{{
+		name:    "decoded",
+		content: base64.StdEncoding.EncodeToString([]byte(singleDocument)),
+	}, {
+		name:            "corrupt",
+		content:         "not-base64",
+		expectedFailure: `resolved content could not be decoded as decode param "base64"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: now},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: withDecode(fakeParams("encoded"), "base64"),
+				},
+			}
+			resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+				"encoded": {Content: tc.content},
+			}}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+			defer cancel()
+
+			err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+			if tc.expectedFailure != "" && err == nil {
+				t.Fatalf("expected an error but got nothing")
+			}
+			if tc.expectedFailure == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			cond := reconciledRR.Status.GetCondition(apis.ConditionSucceeded)
+			if tc.expectedFailure != "" {
+				if cond == nil || !cond.IsFalse() || !strings.Contains(cond.Message, tc.expectedFailure) {
+					t.Errorf("expected failed condition with %q but got %v", tc.expectedFailure, cond)
+				}
+				return
+			}
+			if d := cmp.Diff(base64.StdEncoding.EncodeToString([]byte(singleDocument)), reconciledRR.Status.Data); d != "" {
+				t.Errorf("unexpected data: %s", diff.PrintWantGot(d))
+			}
+			if decoded := reconciledRR.Status.Annotations[resolutioncommon.AnnotationKeyDecoded]; decoded != "base64" {
+				t.Errorf("expected the decoded annotation to be %q but got %q", "base64", decoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// documentsResource is a resolved resource whose data holds several
+// YAML documents, passed back when the documents param is "multiple".
+type documentsResource struct {
+	ResolvedResource
+	documents   [][]byte
+	annotations map[string]string
+}
+
+var _ MultiDocumentResource = &documentsResource{}
+
+// Annotations returns the annotations of the resolved resource along
+// with the number of documents its data holds.
+func (d *documentsResource) Annotations() map[string]string {
+	return d.annotations
+}
+
+// Documents returns the documents of the resolved resource decoded to
+// JSON, in the order they appear.
+func (d *documentsResource) Documents() ([][]byte, error) {
+	return d.documents, nil
+}
+
+// frameworkParams are the params handled by the resolver framework
+// rather than by the resolver.
+type frameworkParams struct {
+	// fallback is the content of the fallback param, or nil if it isn't
+	// set.
+	fallback []byte
+	// documents is the value of the documents param, or empty if it
+	// isn't set.
+	documents string
+	// decode is the value of the decode param, or empty if it isn't
+	// set.
+	decode string
+}
+
+// extractFrameworkParams returns the params without the ones handled by
+// the resolver framework, along with their values.
+func extractFrameworkParams(params []pipelinev1beta1.Param) ([]pipelinev1beta1.Param, frameworkParams, error) {
+	var fp frameworkParams
+	params, fallback, err := extractFallback(params)
+	if err != nil {
+		return nil, fp, err
+	}
+	params, documents, err := extractDocumentsMode(params)
+	if err != nil {
+		return nil, fp, err
+	}
+	params, decode, err := extractDecodeMode(params)
+	if err != nil {
+		return nil, fp, err
+	}
+	fp.fallback, fp.documents, fp.decode = fallback, documents, decode
+	return params, fp, nil
+}
+
+// extractDocumentsMode returns the params without the documents param,
+// along with its value or an empty string if it isn't set.
+func extractDocumentsMode(params []pipelinev1beta1.Param) ([]pipelinev1beta1.Param, string, error) {
+	var mode string
+	rest := make([]pipelinev1beta1.Param, 0, len(params))
+	for _, p := range params {
+		if p.Name != resolutioncommon.ParamDocuments {
+			rest = append(rest, p)
+			continue
+		}
+		if p.Value.Type != pipelinev1beta1.ParamTypeString ||
+			(p.Value.StringVal != resolutioncommon.DocumentsSingle && p.Value.StringVal != resolutioncommon.DocumentsMultiple) {
+			return nil, "", fmt.Errorf("param %q must be %q or %q", resolutioncommon.ParamDocuments, resolutioncommon.DocumentsSingle, resolutioncommon.DocumentsMultiple)
+		}
+		mode = p.Value.StringVal
+	}
+	if mode == "" {
+		return params, "", nil
+	}
+	return rest, mode, nil
+}
+
+// applyDocumentsMode checks the documents the resolved resource holds
+// against the given value of the documents param. Content holding more
+// than one document is rejected unless the param is "multiple", in
+// which case the resource is passed back as a MultiDocumentResource.
+// Resources are passed back untouched when the param isn't set, just as
+// they were before it existed.
+func applyDocumentsMode(resource ResolvedResource, mode string) (ResolvedResource, error) {
+	if mode == "" {
+		return resource, nil
+	}
+	documents, err := resourceDocuments(resource)
+	if err != nil {
+		return nil, fmt.Errorf("could not split the resolved content into documents: %w", err)
+	}
+	if mode == resolutioncommon.DocumentsSingle {
+		if len(documents) > 1 {
+			return nil, fmt.Errorf("resolved content holds %d documents but the %s param is %q, set it to %q to allow them",
+				len(documents), resolutioncommon.ParamDocuments, mode, resolutioncommon.DocumentsMultiple)
+		}
+		return resource, nil
+	}
+	annotations := make(map[string]string, len(resource.Annotations())+1)
+	for k, v := range resource.Annotations() {
+		annotations[k] = v
+	}
+	annotations[resolutioncommon.AnnotationKeyDocuments] = strconv.Itoa(len(documents))
+	return &documentsResource{
+		ResolvedResource: resource,
+		documents:        documents,
+		annotations:      annotations,
+	}, nil
+}
+
+// resourceDocuments returns the documents of the resolved resource, as
+// split by the resource itself if it's a MultiDocumentResource.
+func resourceDocuments(resource ResolvedResource) ([][]byte, error) {
+	if multi, ok := resource.(MultiDocumentResource); ok {
+		return multi.Documents()
+	}
+	return splitDocuments(resource.Data())
+}
+
+// splitDocuments returns the YAML documents in data decoded to JSON, in
+// the order they appear. Empty documents, e.g. those holding only
+// comments, are skipped.
+func splitDocuments(data []byte) ([][]byte, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var documents [][]byte
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := yaml.YAMLToJSON(document)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document %d: %w", len(documents)+1, err)
+		}
+		if bytes.Equal(decoded, []byte("null")) {
+			continue
+		}
+		documents = append(documents, decoded)
+	}
+}
+
+// isFallback returns true if the resolved resource is the content of
+// the fallback param.
+func isFallback(resource ResolvedResource) bool {
+	if d, ok := resource.(*documentsResource); ok {
+		resource = d.ResolvedResource
+	}
+	_, ok := resource.(*fallbackResource)
+	return ok
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	"github.com/tektoncd/pipeline/test/diff"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+const (
+	singleDocument = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: build\n"
+	multiDocument  = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: build\n" +
+		"---\n# only a comment\n---\n" +
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: build-config\n"
+)
+
+func withDocuments(params []pipelinev1beta1.Param, mode string) []pipelinev1beta1.Param {
+	return append(params, pipelinev1beta1.Param{
+		Name:  resolutioncommon.ParamDocuments,
+		Value: *pipelinev1beta1.NewStructuredValues(mode),
+	})
+}
+
+func TestSplitDocuments(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        string
+		expected    []string
+		expectedErr string
+	}{{
+		name:     "single document",
+		data:     singleDocument,
+		expected: []string{`{"apiVersion":"tekton.dev/v1beta1","kind":"Task","metadata":{"name":"build"}}`},
+	}, {
+		name: "documents in order, skipping empty ones",
+		data: "---\n" + multiDocument,
+		expected: []string{
+			`{"apiVersion":"tekton.dev/v1beta1","kind":"Task","metadata":{"name":"build"}}`,
+			`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"build-config"}}`,
+		},
+	}, {
+		name:     "json",
+		data:     `{"kind":"Task"}`,
+		expected: []string{`{"kind":"Task"}`},
+	}, {
+		name: "empty",
+	}, {
+		name:        "malformed document",
+		data:        singleDocument + "---\nkind: Task\n  name: [foo\n",
+		expectedErr: "invalid document 2: yaml: line 2: mapping values are not allowed in this context",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			documents, err := splitDocuments([]byte(tc.data))
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, d := range documents {
+				got = append(got, string(d))
+			}
+			if d := cmp.Diff(tc.expected, got); d != "" {
+				t.Errorf("unexpected documents: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestExtractDocumentsMode(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		params       []pipelinev1beta1.Param
+		expectedMode string
+		expectedErr  string
+	}{{
+		name:   "not set",
+		params: fakeParams("foo"),
+	}, {
+		name:         "single",
+		params:       withDocuments(fakeParams("foo"), "single"),
+		expectedMode: "single",
+	}, {
+		name:         "multiple",
+		params:       withDocuments(fakeParams("foo"), "multiple"),
+		expectedMode: "multiple",
+	}, {
+		name:        "unknown mode",
+		params:      withDocuments(fakeParams("foo"), "many"),
+		expectedErr: `param "documents" must be "single" or "multiple"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, mode, err := extractDocumentsMode(tc.params)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(fakeParams("foo"), params); d != "" {
+				t.Errorf("unexpected params: %s", diff.PrintWantGot(d))
+			}
+			if mode != tc.expectedMode {
+				t.Errorf("expected mode %q but got %q", tc.expectedMode, mode)
+			}
+		})
+	}
+}
+
+func TestDryRunDocuments(t *testing.T) {
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"single":   {Content: singleDocument},
+			"multiple": {Content: multiDocument},
+		},
+	}
+	for _, tc := range []struct {
+		name              string
+		content           string
+		mode              string
+		expectedDocuments []string
+		expectedErr       string
+	}{{
+		name:    "single document by default",
+		content: "single",
+	}, {
+		name:    "multiple documents by default are left as they are",
+		content: "multiple",
+	}, {
+		name:    "single document in single mode",
+		content: "single",
+		mode:    "single",
+	}, {
+		name:        "multiple documents in single mode",
+		content:     "multiple",
+		mode:        "single",
+		expectedErr: `error resolving with Fake resolver: resolved content holds 2 documents but the documents param is "single", set it to "multiple" to allow them`,
+	}, {
+		name:              "single document in multiple mode",
+		content:           "single",
+		mode:              "multiple",
+		expectedDocuments: []string{`{"apiVersion":"tekton.dev/v1beta1","kind":"Task","metadata":{"name":"build"}}`},
+	}, {
+		name:    "multiple documents in multiple mode",
+		content: "multiple",
+		mode:    "multiple",
+		expectedDocuments: []string{
+			`{"apiVersion":"tekton.dev/v1beta1","kind":"Task","metadata":{"name":"build"}}`,
+			`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"build-config"}}`,
+		},
+	}, {
+		name:        "invalid mode",
+		content:     "single",
+		mode:        "all",
+		expectedErr: `invalid params for Fake resolver: param "documents" must be "single" or "multiple"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := fakeParams(tc.content)
+			if tc.mode != "" {
+				params = withDocuments(params, tc.mode)
+			}
+			resource, err := DryRun(context.Background(), resolver, params)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(resolver.ForParam[tc.content].Content, string(resource.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+			multi, ok := resource.(MultiDocumentResource)
+			if tc.expectedDocuments == nil {
+				if ok {
+					t.Errorf("expected a single document resource but got %T", resource)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected a MultiDocumentResource but got %T", resource)
+			}
+			documents, err := multi.Documents()
+			if err != nil {
+				t.Fatalf("unexpected error getting documents: %v", err)
+			}
+			var got []string
+			for _, d := range documents {
+				got = append(got, string(d))
+			}
+			if d := cmp.Diff(tc.expectedDocuments, got); d != "" {
+				t.Errorf("unexpected documents: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestReconcileDocuments(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		mode                string
+		expectedAnnotations map[string]string
+		expectedFailed      bool
+	}{{
+		name: "multiple",
+		mode: "multiple",
+		expectedAnnotations: map[string]string{
+			resolutioncommon.AnnotationKeyDocuments:  "2",
+			resolutioncommon.AnnotationKeyProvenance: `{"resolverType":"fake"}`,
+		},
+	}, {
+		name:           "single",
+		mode:           "single",
+		expectedFailed: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: now},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: withDocuments(fakeParams("multiple"), tc.mode),
+				},
+			}
+			resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+				"multiple": {Content: multiDocument},
+			}}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+			defer cancel()
+
+			err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+			if tc.expectedFailed && err == nil {
+				t.Fatalf("expected an error but got nothing")
+			}
+			if !tc.expectedFailed && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			if failed := reconciledRR.Status.GetCondition(apis.ConditionSucceeded).IsFalse(); failed != tc.expectedFailed {
+				t.Errorf("expected request failed to be %t but got status %v", tc.expectedFailed, reconciledRR.Status)
+			}
+			if tc.expectedFailed {
+				return
+			}
+			if d := cmp.Diff(base64.StdEncoding.EncodeToString([]byte(multiDocument)), reconciledRR.Status.Data); d != "" {
+				t.Errorf("unexpected data: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedAnnotations, reconciledRR.Status.Annotations); d != "" {
+				t.Errorf("unexpected annotations: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"knative.dev/pkg/logging"
+)
+
+// DryRun resolves the given params with the resolver directly, without
+// creating a ResolutionRequest, and returns the resolved resource. It
+// is meant for tooling such as CLIs and admission webhooks that need
+// the resolved content but not the ResolutionRequest lifecycle.
+//
+// Param aliases are replaced, with deprecation warnings logged, and the
+// resolver's default params are added and the params are validated
+// with ValidateParams, and checked with Preflight if the resolver is a
+// Preflighter, before Resolve is called, just as they are for a
+// ResolutionRequest, with failures of either returned as a
+// resolutioncommon.InvalidParamsError. The content of the fallback
+// param, if any, is returned when the resolver's backend is
+// unavailable, and content holding several YAML documents is checked
+// against the documents param, the resource being returned as a
+// MultiDocumentResource when it is "multiple". The resolver's timeout
+// is also enforced: DryRun returns as soon as ctx is done or the
+// timeout passes, with a resolutioncommon.ResolutionTimeoutError in
+// the latter case. Content larger than the max-resolved-size config
+// fails with a ResolvedResourceTooLargeError, and content rejected by the Verifier in
+// ctx, if any, with a resolutioncommon.VerificationError. The resolver
+// must already be initialized. Its configuration, feature flags and the
+// namespace of the request are read from ctx, so a resolver that is
+// disabled in ctx returns an error. What the resolver logs is redacted,
+// as it is during a resolution, see WithRedaction.
+func DryRun(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	ctx = WithRedaction(ctx)
+	timeout := resolutionTimeout(ctx, resolver)
+	resolutionCtx, cancelFn := context.WithTimeout(ctx, timeout)
+	defer cancelFn()
+	resolverType := resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType]
+
+	errChan := make(chan error, 1)
+	resourceChan := make(chan ResolvedResource, 1)
+	go func() {
+		params, fp, err := validateParams(resolutionCtx, resolver, params)
+		if err == nil {
+			err = preflight(resolutionCtx, resolver, params)
+		}
+		if err != nil {
+			if resolutionCtx.Err() != nil {
+				errChan <- resolutionContextError(resolutionCtx, resolverType, timeout)
+				return
+			}
+			errChan <- invalidParamsError(resolutionCtx, resolver, err)
+			return
+		}
+		resource, err := tracedResolve(resolutionCtx, resolver, params)
+		if err == nil {
+			// A signature covers the content as the resolver returned
+			// it, so it is verified before being decoded.
+			err = verifyResolved(resolutionCtx, resolverType, resource)
+		}
+		if err == nil {
+			resource, err = applyDecodeMode(resolutionCtx, resource, fp.decode)
+		}
+		if err != nil && fp.fallback != nil && isBackendUnavailable(err) {
+			resource = newFallbackResource(fp.fallback, err)
+			err = verifyResolved(resolutionCtx, resolverType, resource)
+		}
+		if err == nil {
+			resource, err = applyDocumentsMode(resource, fp.documents)
+		}
+		if err == nil {
+			err = checkResolvedSize(resolutionCtx, resource)
+		}
+		if err != nil && resolutionCtx.Err() != nil {
+			errChan <- resolutionContextError(resolutionCtx, resolverType, timeout)
+			return
+		}
+		if err != nil {
+			errChan <- fmt.Errorf("error resolving with %s resolver: %w", resolver.GetName(resolutionCtx), err)
+			return
+		}
+		resourceChan <- resource
+	}()
+
+	select {
+	case err := <-errChan:
+		return nil, err
+	case resource := <-resourceChan:
+		return resource, nil
+	case <-resolutionCtx.Done():
+		return nil, resolutionContextError(resolutionCtx, resolverType, timeout)
+	}
+}
+
+// DryValidate validates the given params with the resolver directly,
+// without resolving them, e.g. for linters checking the params of remote
+// Tasks and Pipelines. Param aliases are replaced and the resolver's
+// default params are added before ValidateParams is called, just as they
+// are by DryRun, with validation failures returned as a
+// resolutioncommon.InvalidParamsError.
+//
+// Unlike DryRun, DryValidate never calls Preflight or Resolve, so it
+// makes no requests to the service the resolver resolves from and can
+// run without network access. Resolvers may still read objects, such as
+// the secrets named in the params, through their Kubernetes clients. As
+// with DryRun the resolver must already be initialized, everything it
+// reads from the request's context has to be in ctx and what it logs is
+// redacted.
+func DryValidate(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) error {
+	ctx = WithRedaction(ctx)
+	if _, _, err := validateParams(ctx, resolver, params); err != nil {
+		return invalidParamsError(ctx, resolver, err)
+	}
+	return nil
+}
+
+// validateParams checks the params against the configured limits,
+// replaces the aliases in them, logging a warning for each, takes out
+// the params handled by the framework, such as the fallback param, adds
+// the resolver's default params and validates them, returning the
+// params the resolver should resolve along with the ones taken out.
+func validateParams(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) ([]pipelinev1beta1.Param, frameworkParams, error) {
+	if err := checkParamLimits(ctx, params); err != nil {
+		return nil, frameworkParams{}, err
+	}
+	params, warnings, err := NormalizeParamAliases(ctx, resolver, params)
+	for _, warning := range warnings {
+		logging.FromContext(ctx).Warn(warning)
+	}
+	if err != nil {
+		return nil, frameworkParams{}, err
+	}
+	params, fp, err := extractFrameworkParams(params)
+	if err != nil {
+		return nil, frameworkParams{}, err
+	}
+	params = ApplyDefaultParams(ctx, resolver, params)
+	if err := resolver.ValidateParams(ctx, params); err != nil {
+		return nil, frameworkParams{}, err
+	}
+	return params, fp, nil
+}
+
+// invalidParamsError wraps the error the resolver rejected a request's
+// params with in a resolutioncommon.InvalidParamsError.
+func invalidParamsError(ctx context.Context, resolver Resolver, err error) error {
+	resolverName := resolver.GetName(ctx)
+	return fmt.Errorf("invalid params for %s resolver: %w", resolverName, &resolutioncommon.InvalidParamsError{
+		ResolverName: resolverName,
+		Original:     err,
+	})
+}
+
+// DryRunType is like DryRun but resolves the params with whichever of
+// the given resolvers handles the given resolver type, e.g. "git".
+func DryRunType(ctx context.Context, resolverType string, params []pipelinev1beta1.Param, resolvers ...Resolver) (ResolvedResource, error) {
+	for _, resolver := range resolvers {
+		if resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType] == resolverType {
+			return DryRun(ctx, resolver, params)
+		}
+	}
+	return nil, fmt.Errorf("no resolver for type %q", resolverType)
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+func TestDryRun(t *testing.T) {
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "some content"},
+			"bar": {ErrorWith: "something went wrong"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name            string
+		params          []pipelinev1beta1.Param
+		expectedContent string
+		expectedErr     string
+	}{{
+		name:            "resolves",
+		params:          fakeParams("foo"),
+		expectedContent: "some content",
+	}, {
+		name:        "invalid params",
+		expectedErr: "invalid params for Fake resolver: missing fake-key",
+	}, {
+		name:        "resolution error",
+		params:      fakeParams("bar"),
+		expectedErr: "error resolving with Fake resolver: something went wrong",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resource, err := DryRun(context.Background(), resolver, tc.params)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected error %q but got none", tc.expectedErr)
+				}
+				if err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %q", tc.expectedErr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resource.Data()) != tc.expectedContent {
+				t.Errorf("expected content %q but got %q", tc.expectedContent, string(resource.Data()))
+			}
+		})
+	}
+}
+
+func TestDryRunInvalidParams(t *testing.T) {
+	_, err := DryRun(context.Background(), &FakeResolver{}, nil)
+	var invalidErr *resolutioncommon.InvalidParamsError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected an InvalidParamsError but got %v", err)
+	}
+	if invalidErr.ResolverName != FakeResolverName {
+		t.Errorf("expected resolver name %q but got %q", FakeResolverName, invalidErr.ResolverName)
+	}
+}
+
+// preflightResolver is a FakeResolver whose Preflight fails with err,
+// recording whether it was called.
+type preflightResolver struct {
+	*FakeResolver
+	err    error
+	called bool
+}
+
+var _ Preflighter = &preflightResolver{}
+
+func (r *preflightResolver) Preflight(context.Context, []pipelinev1beta1.Param) error {
+	r.called = true
+	return r.err
+}
+
+func TestDryRunPreflight(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		params         []pipelinev1beta1.Param
+		err            error
+		expectedCalled bool
+		expectedErr    string
+	}{{
+		name:           "passes",
+		params:         fakeParams("foo"),
+		expectedCalled: true,
+	}, {
+		name:           "fails",
+		params:         fakeParams("foo"),
+		err:            errors.New("catalog not found"),
+		expectedCalled: true,
+		expectedErr:    "invalid params for Fake resolver: catalog not found",
+	}, {
+		name:        "not called for invalid params",
+		expectedErr: "invalid params for Fake resolver: missing fake-key",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &preflightResolver{
+				FakeResolver: &FakeResolver{ForParam: map[string]*FakeResolvedResource{"foo": {Content: "some content"}}},
+				err:          tc.err,
+			}
+			_, err := DryRun(context.Background(), resolver, tc.params)
+			if resolver.called != tc.expectedCalled {
+				t.Errorf("expected Preflight called to be %t but got %t", tc.expectedCalled, resolver.called)
+			}
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalidErr *resolutioncommon.InvalidParamsError
+			if !errors.As(err, &invalidErr) {
+				t.Fatalf("expected an InvalidParamsError but got %v", err)
+			}
+			if err.Error() != tc.expectedErr {
+				t.Errorf("expected error %q but got %q", tc.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestDryValidate(t *testing.T) {
+	resolver := &preflightResolver{
+		FakeResolver: &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"bar": {ErrorWith: "something went wrong"},
+		}},
+		err: errors.New("catalog not found"),
+	}
+
+	// Neither Preflight nor Resolve is called, so their errors aren't
+	// reported.
+	if err := DryValidate(context.Background(), resolver, fakeParams("bar")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolver.called {
+		t.Errorf("expected Preflight not to be called")
+	}
+
+	err := DryValidate(context.Background(), resolver, nil)
+	var invalidErr *resolutioncommon.InvalidParamsError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected an InvalidParamsError but got %v", err)
+	}
+	if expected := "invalid params for Fake resolver: missing fake-key"; err.Error() != expected {
+		t.Errorf("expected error %q but got %q", expected, err.Error())
+	}
+}
+
+func TestDryRunTimeout(t *testing.T) {
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"slow": {Content: "slow content", WaitFor: time.Second},
+		},
+		Timeout: 10 * time.Millisecond,
+	}
+	_, err := DryRun(context.Background(), resolver, fakeParams("slow"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error to wrap context.DeadlineExceeded but got %v", err)
+	}
+	var timeoutErr *resolutioncommon.ResolutionTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a ResolutionTimeoutError but got %v", err)
+	}
+	if timeoutErr.ResolverType != LabelValueFakeResolverType {
+		t.Errorf("expected resolver type %q but got %q", LabelValueFakeResolverType, timeoutErr.ResolverType)
+	}
+}
+
+func TestDryRunCancelled(t *testing.T) {
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"slow": {Content: "slow content", WaitFor: time.Minute},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := DryRun(ctx, resolver, fakeParams("slow"))
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected dry run to return once cancelled but it took %s", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error to wrap context.Canceled but got %v", err)
+	}
+}
+
+func TestDryRunType(t *testing.T) {
+	resolver := &FakeResolver{
+		ForParam: map[string]*FakeResolvedResource{
+			"foo": {Content: "some content"},
+		},
+	}
+
+	resource, err := DryRunType(context.Background(), LabelValueFakeResolverType, fakeParams("foo"), resolver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resource.Data()) != "some content" {
+		t.Errorf("expected content %q but got %q", "some content", string(resource.Data()))
+	}
+
+	expectedErr := `no resolver for type "git"`
+	if _, err := DryRunType(context.Background(), "git", fakeParams("foo"), resolver); err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q but got %v", expectedErr, err)
+	}
+}
+
+func fakeParams(value string) []pipelinev1beta1.Param {
+	return []pipelinev1beta1.Param{{
+		Name:  FakeParamName,
+		Value: *pipelinev1beta1.NewStructuredValues(value),
+	}}
+}
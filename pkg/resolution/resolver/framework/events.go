@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// The reasons of the events emitted for resolutions. They're stable so
+// that they can be used for alerting.
+const (
+	EventReasonResolutionSucceeded     = "ResolutionSucceeded"
+	EventReasonResolvedWithFallback    = "ResolvedWithFallback"
+	EventReasonResolutionFailed        = "ResolutionFailed"
+	EventReasonResolutionNotFound      = "ResolutionNotFound"
+	EventReasonResolutionTimedOut      = "ResolutionTimedOut"
+	EventReasonResolutionInvalidParams = "ResolutionInvalidParams"
+	EventReasonResolverDisabled        = "ResolverDisabled"
+	EventReasonDeprecatedParam         = "DeprecatedParam"
+)
+
+// eventReasons maps the results of resolutions to the reasons of the
+// events emitted for them.
+var eventReasons = map[string]string{
+	ResultSuccess:  EventReasonResolutionSucceeded,
+	ResultFallback: EventReasonResolvedWithFallback,
+	ResultNotFound: EventReasonResolutionNotFound,
+	ResultTimeout:  EventReasonResolutionTimedOut,
+	ResultInvalid:  EventReasonResolutionInvalidParams,
+	ResultDisabled: EventReasonResolverDisabled,
+	ResultError:    EventReasonResolutionFailed,
+}
+
+// emitResolutionEvent emits an event for a resolution with the given
+// result, unless events for it are disabled by the resolvers' feature
+// flags. Failures are emitted as warnings and successes as normal
+// events. The event is emitted on the object that owns the
+// ResolutionRequest, e.g. a PipelineRun, so that it shows up alongside
+// it, or on the ResolutionRequest itself if it has no owner.
+func (r *Reconciler) emitResolutionEvent(ctx context.Context, rr *v1beta1.ResolutionRequest, resolverType, result string, err error) {
+	if r.recorder == nil {
+		return
+	}
+	flags := resolverconfig.FromContextOrDefaults(ctx).FeatureFlags
+	reason, ok := eventReasons[result]
+	if !ok {
+		reason = EventReasonResolutionFailed
+	}
+	if err == nil {
+		if flags.EnableResolutionSuccessEvents {
+			r.recorder.Eventf(eventObject(rr), corev1.EventTypeNormal, reason, "Resolved %s/%s with the %s resolver", rr.Namespace, rr.Name, resolverType)
+		}
+		return
+	}
+	if flags.EnableResolutionFailureEvents {
+		r.recorder.Eventf(eventObject(rr), corev1.EventTypeWarning, reason, "Failed to resolve %s/%s with the %s resolver: %v", rr.Namespace, rr.Name, resolverType, err)
+	}
+}
+
+// emitDeprecationWarnings logs each deprecation warning about the params
+// of a ResolutionRequest and emits it as a warning event so that the
+// owner of the request finds out that its params need updating.
+func (r *Reconciler) emitDeprecationWarnings(ctx context.Context, rr *v1beta1.ResolutionRequest, warnings []string) {
+	for _, warning := range warnings {
+		logging.FromContext(ctx).Warnf("Resolution request %s/%s: %s", rr.Namespace, rr.Name, warning)
+		if r.recorder != nil {
+			r.recorder.Eventf(eventObject(rr), corev1.EventTypeWarning, EventReasonDeprecatedParam, "Resolution request %s/%s: %s", rr.Namespace, rr.Name, warning)
+		}
+	}
+}
+
+// eventObject returns a reference to the object that events for the
+// given ResolutionRequest are emitted on.
+func eventObject(rr *v1beta1.ResolutionRequest) *corev1.ObjectReference {
+	if owner := metav1.GetControllerOf(rr); owner != nil {
+		return &corev1.ObjectReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			Namespace:  rr.Namespace,
+			UID:        owner.UID,
+		}
+	}
+	return &corev1.ObjectReference{
+		APIVersion: v1beta1.SchemeGroupVersion.String(),
+		Kind:       "ResolutionRequest",
+		Name:       rr.Name,
+		Namespace:  rr.Namespace,
+		UID:        rr.UID,
+	}
+}
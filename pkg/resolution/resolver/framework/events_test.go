@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"github.com/tektoncd/pipeline/test/diff"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEmitResolutionEvent(t *testing.T) {
+	isController := true
+	owned := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rr",
+			Namespace: "foo",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "tekton.dev/v1beta1",
+				Kind:       "PipelineRun",
+				Name:       "pr",
+				Controller: &isController,
+			}},
+		},
+	}
+	unowned := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "rr", Namespace: "foo"},
+	}
+	for _, tc := range []struct {
+		name           string
+		flags          map[string]string
+		rr             *v1beta1.ResolutionRequest
+		result         string
+		err            error
+		expectedEvents []string
+	}{{
+		name:   "failure on owner",
+		rr:     owned,
+		result: ResultNotFound,
+		err:    errors.New("not found"),
+		expectedEvents: []string{
+			"Warning ResolutionNotFound Failed to resolve foo/rr with the fake resolver: not found involvedObject{kind=PipelineRun,apiVersion=tekton.dev/v1beta1}",
+		},
+	}, {
+		name:   "failure without owner",
+		rr:     unowned,
+		result: ResultError,
+		err:    errors.New("boom"),
+		expectedEvents: []string{
+			"Warning ResolutionFailed Failed to resolve foo/rr with the fake resolver: boom involvedObject{kind=ResolutionRequest,apiVersion=resolution.tekton.dev/v1beta1}",
+		},
+	}, {
+		name:   "failure events disabled",
+		flags:  map[string]string{resolverconfig.EnableResolutionFailureEvents: "false"},
+		rr:     owned,
+		result: ResultTimeout,
+		err:    errors.New("timed out"),
+	}, {
+		name:   "success events disabled by default",
+		rr:     owned,
+		result: ResultSuccess,
+	}, {
+		name:   "success events enabled",
+		flags:  map[string]string{resolverconfig.EnableResolutionSuccessEvents: "true"},
+		rr:     owned,
+		result: ResultSuccess,
+		expectedEvents: []string{
+			"Normal ResolutionSucceeded Resolved foo/rr with the fake resolver involvedObject{kind=PipelineRun,apiVersion=tekton.dev/v1beta1}",
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, err := resolverconfig.NewFeatureFlagsFromMap(tc.flags)
+			if err != nil {
+				t.Fatalf("unexpected error parsing feature flags: %v", err)
+			}
+			ctx := resolverconfig.ToContext(context.Background(), &resolverconfig.Config{FeatureFlags: flags})
+			recorder := record.NewFakeRecorder(10)
+			recorder.IncludeObject = true
+			r := &Reconciler{recorder: recorder}
+
+			r.emitResolutionEvent(ctx, tc.rr, LabelValueFakeResolverType, tc.result, tc.err)
+			close(recorder.Events)
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			if d := cmp.Diff(tc.expectedEvents, events); d != "" {
+				t.Errorf("unexpected events: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestEmitDeprecationWarnings(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "rr", Namespace: "foo"},
+	}
+	recorder := record.NewFakeRecorder(10)
+	recorder.IncludeObject = true
+	r := &Reconciler{recorder: recorder}
+
+	r.emitDeprecationWarnings(context.Background(), rr, []string{`param "old-key" is deprecated, use "fake-key" instead`})
+	close(recorder.Events)
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	expected := []string{
+		`Warning DeprecatedParam Resolution request foo/rr: param "old-key" is deprecated, use "fake-key" instead involvedObject{kind=ResolutionRequest,apiVersion=resolution.tekton.dev/v1beta1}`,
+	}
+	if d := cmp.Diff(expected, events); d != "" {
+		t.Errorf("unexpected events: %s", diff.PrintWantGot(d))
+	}
+}
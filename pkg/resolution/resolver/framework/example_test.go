@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// greetingResolver resolves to a greeting for the name given in its
+// name param.
+type greetingResolver struct{}
+
+func (r *greetingResolver) Initialize(context.Context) error { return nil }
+
+func (r *greetingResolver) GetName(context.Context) string { return "Greeting" }
+
+func (r *greetingResolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{resolutioncommon.LabelKeyResolverType: "greeting"}
+}
+
+func (r *greetingResolver) ValidateParams(_ context.Context, params []pipelinev1beta1.Param) error {
+	if len(params) != 1 || params[0].Name != "name" {
+		return errors.New("expected a single name param")
+	}
+	return nil
+}
+
+func (r *greetingResolver) Resolve(_ context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	return &framework.FakeResolvedResource{Content: "hello " + params[0].Value.StringVal}, nil
+}
+
+func ExampleRegistry() {
+	reg := framework.NewRegistry()
+	if err := reg.Register("greeting", &greetingResolver{}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// A second resolver for the same type is rejected.
+	fmt.Println(reg.Register("greeting", &greetingResolver{}))
+
+	// Requests are only resolved while enable-greeting-resolver is true.
+	featureFlags, err := resolverconfig.NewFeatureFlagsFromMap(map[string]string{
+		resolverconfig.ResolverFeatureFlag("greeting"): "true",
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ctx := resolverconfig.ToContext(context.Background(), &resolverconfig.Config{FeatureFlags: featureFlags})
+
+	resource, err := reg.DryRun(ctx, "greeting", []pipelinev1beta1.Param{{
+		Name:  "name",
+		Value: *pipelinev1beta1.NewStructuredValues("tekton"),
+	}})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(resource.Data()))
+	// Output:
+	// a resolver is already registered for type "greeting"
+	// hello tekton
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"errors"
+	"fmt"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"sigs.k8s.io/yaml"
+)
+
+// fallbackResource is the content of the fallback param that a
+// resolution resolves to when the resolver's backend is unavailable.
+type fallbackResource struct {
+	data        []byte
+	annotations map[string]string
+}
+
+var _ ResolvedResource = &fallbackResource{}
+
+// newFallbackResource returns the fallback content resolved instead of
+// the resource whose resolution failed with the given error.
+func newFallbackResource(data []byte, err error) *fallbackResource {
+	return &fallbackResource{
+		data: data,
+		annotations: map[string]string{
+			resolutioncommon.AnnotationKeyFallback:       "true",
+			resolutioncommon.AnnotationKeyFallbackReason: err.Error(),
+		},
+	}
+}
+
+// Data returns the fallback content.
+func (f *fallbackResource) Data() []byte {
+	return f.data
+}
+
+// Annotations returns the annotations flagging the content as the
+// fallback.
+func (f *fallbackResource) Annotations() map[string]string {
+	return f.annotations
+}
+
+// Source returns nil since the fallback content wasn't fetched from
+// anywhere.
+func (f *fallbackResource) Source() *v1beta1.ConfigSource {
+	return nil
+}
+
+// extractFallback returns the params without the fallback param, along
+// with the content it holds or nil if it isn't set. The content must be
+// a YAML or JSON object with an apiVersion and a kind so that a typo
+// surfaces when the request is made rather than during an outage.
+func extractFallback(params []pipelinev1beta1.Param) ([]pipelinev1beta1.Param, []byte, error) {
+	var fallback []byte
+	rest := make([]pipelinev1beta1.Param, 0, len(params))
+	for _, p := range params {
+		if p.Name != resolutioncommon.ParamFallback {
+			rest = append(rest, p)
+			continue
+		}
+		if p.Value.Type != pipelinev1beta1.ParamTypeString || p.Value.StringVal == "" {
+			return nil, nil, fmt.Errorf("param %q must be a non-empty string", resolutioncommon.ParamFallback)
+		}
+		var obj struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := yaml.Unmarshal([]byte(p.Value.StringVal), &obj); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s param: %w", resolutioncommon.ParamFallback, err)
+		}
+		if obj.APIVersion == "" || obj.Kind == "" {
+			return nil, nil, fmt.Errorf("invalid %s param: must be an object with an apiVersion and a kind", resolutioncommon.ParamFallback)
+		}
+		fallback = []byte(p.Value.StringVal)
+	}
+	if fallback == nil {
+		return params, nil, nil
+	}
+	return rest, fallback, nil
+}
+
+// isBackendUnavailable returns true if the resolution failed because the
+// resolver's backend couldn't be reached, as opposed to e.g. it not
+// finding the resource or denying access to it.
+func isBackendUnavailable(err error) bool {
+	return isBackendFailure(err) || errors.Is(err, ErrCircuitOpen)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	"github.com/tektoncd/pipeline/test/diff"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+const fallbackTask = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: fallback\n"
+
+func withFallback(params []pipelinev1beta1.Param, fallback string) []pipelinev1beta1.Param {
+	return append(params, pipelinev1beta1.Param{
+		Name:  resolutioncommon.ParamFallback,
+		Value: *pipelinev1beta1.NewStructuredValues(fallback),
+	})
+}
+
+func TestExtractFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		params           []pipelinev1beta1.Param
+		expectedParams   []pipelinev1beta1.Param
+		expectedFallback string
+		expectedErr      string
+	}{{
+		name:           "no fallback",
+		params:         fakeParams("foo"),
+		expectedParams: fakeParams("foo"),
+	}, {
+		name:             "yaml fallback",
+		params:           withFallback(fakeParams("foo"), fallbackTask),
+		expectedParams:   fakeParams("foo"),
+		expectedFallback: fallbackTask,
+	}, {
+		name:             "json fallback",
+		params:           withFallback(fakeParams("foo"), `{"apiVersion":"tekton.dev/v1beta1","kind":"Task"}`),
+		expectedParams:   fakeParams("foo"),
+		expectedFallback: `{"apiVersion":"tekton.dev/v1beta1","kind":"Task"}`,
+	}, {
+		name:        "empty fallback",
+		params:      withFallback(fakeParams("foo"), ""),
+		expectedErr: `param "fallback" must be a non-empty string`,
+	}, {
+		name: "array fallback",
+		params: append(fakeParams("foo"), pipelinev1beta1.Param{
+			Name:  resolutioncommon.ParamFallback,
+			Value: *pipelinev1beta1.NewStructuredValues("a", "b"),
+		}),
+		expectedErr: `param "fallback" must be a non-empty string`,
+	}, {
+		name:        "malformed fallback",
+		params:      withFallback(fakeParams("foo"), "kind: Task\n  name: [foo"),
+		expectedErr: "invalid fallback param: error converting YAML to JSON: yaml: line 2: mapping values are not allowed in this context",
+	}, {
+		name:        "fallback without kind",
+		params:      withFallback(fakeParams("foo"), "apiVersion: tekton.dev/v1beta1\nmetadata:\n  name: fallback\n"),
+		expectedErr: "invalid fallback param: must be an object with an apiVersion and a kind",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, fallback, err := extractFallback(tc.params)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedParams, params); d != "" {
+				t.Errorf("unexpected params: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedFallback, string(fallback)); d != "" {
+				t.Errorf("unexpected fallback: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestReconcileFallback(t *testing.T) {
+	unavailable := &resolutioncommon.TransientError{Resource: "down", Original: errors.New("connection refused")}
+	for _, tc := range []struct {
+		name                string
+		backend             string
+		fallback            string
+		expectedData        string
+		expectedAnnotations map[string]string
+		expectedFailed      bool
+	}{{
+		name:         "backend available",
+		backend:      "up",
+		fallback:     fallbackTask,
+		expectedData: "up",
+		expectedAnnotations: map[string]string{
+			resolutioncommon.AnnotationKeyProvenance: `{"resolverType":"fake"}`,
+		},
+	}, {
+		name:         "backend unavailable",
+		backend:      "down",
+		fallback:     fallbackTask,
+		expectedData: fallbackTask,
+		expectedAnnotations: map[string]string{
+			resolutioncommon.AnnotationKeyFallback:       "true",
+			resolutioncommon.AnnotationKeyFallbackReason: unavailable.Error(),
+			resolutioncommon.AnnotationKeyProvenance:     `{"resolverType":"fake"}`,
+		},
+	}, {
+		name:           "resource not found",
+		backend:        "missing",
+		fallback:       fallbackTask,
+		expectedFailed: true,
+	}, {
+		name:           "invalid fallback",
+		backend:        "down",
+		fallback:       "not an object",
+		expectedFailed: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: now},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: withFallback(fakeParams(tc.backend), tc.fallback),
+				},
+			}
+			resolver := &backendResolver{
+				errs: map[string]error{
+					"down":    unavailable,
+					"missing": &resolutioncommon.ResolutionNotFoundError{Resource: "missing", Original: errors.New("no such task")},
+				},
+				calls: map[string]int{},
+			}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+			defer cancel()
+
+			err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+			if tc.expectedFailed && err == nil {
+				t.Fatalf("expected an error but got nothing")
+			}
+			if !tc.expectedFailed && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			if failed := reconciledRR.Status.GetCondition(apis.ConditionSucceeded).IsFalse(); failed != tc.expectedFailed {
+				t.Errorf("expected request failed to be %t but got status %v", tc.expectedFailed, reconciledRR.Status)
+			}
+			if tc.expectedFailed {
+				return
+			}
+			if d := cmp.Diff(base64.StdEncoding.EncodeToString([]byte(tc.expectedData)), reconciledRR.Status.Data); d != "" {
+				t.Errorf("unexpected data: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedAnnotations, reconciledRR.Status.Annotations); d != "" {
+				t.Errorf("unexpected annotations: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestDryRunFallback(t *testing.T) {
+	resolver := &backendResolver{
+		errs: map[string]error{
+			"down": &resolutioncommon.ResolutionTimeoutError{Timeout: time.Second, Original: errors.New("timed out")},
+		},
+		calls: map[string]int{},
+	}
+	ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "ns")
+	resource, err := DryRun(ctx, resolver, withFallback(fakeParams("down"), fallbackTask))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := cmp.Diff(fallbackTask, string(resource.Data())); d != "" {
+		t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+	}
+	if resource.Annotations()[resolutioncommon.AnnotationKeyFallback] != "true" {
+		t.Errorf("expected the resource to be flagged as the fallback but got annotations %v", resource.Annotations())
+	}
+
+	if err := DryValidate(ctx, resolver, withFallback(fakeParams("down"), "[]")); err == nil {
+		t.Errorf("expected an invalid fallback to fail validation")
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ConfigFetchTimeout is the configuration field name for the
+	// maximum duration of a single request made by a resolver that
+	// fetches over http, e.g. for an object or a chart.
+	ConfigFetchTimeout = "fetch-timeout"
+
+	// ConfigMaxResponseSize is the configuration field name for the
+	// maximum size of a response read by a resolver that fetches over
+	// http. Defaults to DefaultMaxResponseSize.
+	ConfigMaxResponseSize = "max-response-size"
+
+	// DefaultFetchTimeout is the maximum duration of a single request
+	// when the fetch-timeout config isn't set.
+	DefaultFetchTimeout = time.Minute
+)
+
+// FetchOptions are the limits of the requests a resolver makes to
+// fetch a resource, as set by its fetch-timeout and max-response-size
+// config.
+type FetchOptions struct {
+	// Timeout is the maximum duration of a single request.
+	Timeout time.Duration
+	// MaxResponseSize is the maximum size in bytes of a response.
+	MaxResponseSize int64
+}
+
+// FetchOptionsFromConfig returns the fetch options set by the given
+// resolver config, defaulting to DefaultFetchTimeout and
+// DefaultMaxResponseSize, or an error if either is invalid.
+func FetchOptionsFromConfig(conf map[string]string) (FetchOptions, error) {
+	opts := FetchOptions{
+		Timeout:         DefaultFetchTimeout,
+		MaxResponseSize: DefaultMaxResponseSize,
+	}
+	if timeout, ok := conf[ConfigFetchTimeout]; ok {
+		d, err := ParseTimeout(timeout)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigFetchTimeout, err)
+		}
+		opts.Timeout = d
+	}
+	if maxSize, ok := conf[ConfigMaxResponseSize]; ok {
+		size, err := resource.ParseQuantity(maxSize)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigMaxResponseSize, err)
+		}
+		if size.Sign() <= 0 {
+			return opts, fmt.Errorf("invalid %s config: must be greater than zero, got %q", ConfigMaxResponseSize, maxSize)
+		}
+		opts.MaxResponseSize = size.Value()
+	}
+	return opts, nil
+}
+
+// ParseTimeout parses a timeout set in a resolver's config or params,
+// e.g. "30s", which must be a duration greater than zero.
+func ParseTimeout(timeout string) (time.Duration, error) {
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be greater than zero, got %s", timeout)
+	}
+	return d, nil
+}
+
+// TimeoutError returns the error for a request for the given resource,
+// e.g. its url, that exceeded the given timeout.
+func TimeoutError(resource string, timeout time.Duration) error {
+	return &resolutioncommon.ResolutionTimeoutError{
+		Resource: resource,
+		Timeout:  timeout,
+		Original: fmt.Errorf("request for %s timed out after %s", resource, timeout),
+	}
+}
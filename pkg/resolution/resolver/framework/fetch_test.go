@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test/diff"
+)
+
+func TestFetchOptionsFromConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		conf        map[string]string
+		expected    FetchOptions
+		expectedErr string
+	}{{
+		name: "defaults",
+		expected: FetchOptions{
+			Timeout:         DefaultFetchTimeout,
+			MaxResponseSize: DefaultMaxResponseSize,
+		},
+	}, {
+		name: "configured",
+		conf: map[string]string{
+			ConfigFetchTimeout:    "5s",
+			ConfigMaxResponseSize: "1Mi",
+		},
+		expected: FetchOptions{
+			Timeout:         5 * time.Second,
+			MaxResponseSize: 1 << 20,
+		},
+	}, {
+		name:        "invalid timeout",
+		conf:        map[string]string{ConfigFetchTimeout: "soon"},
+		expectedErr: `invalid fetch-timeout config: time: invalid duration "soon"`,
+	}, {
+		name:        "zero timeout",
+		conf:        map[string]string{ConfigFetchTimeout: "0s"},
+		expectedErr: "invalid fetch-timeout config: timeout must be greater than zero, got 0s",
+	}, {
+		name:        "invalid max response size",
+		conf:        map[string]string{ConfigMaxResponseSize: "lots"},
+		expectedErr: "invalid max-response-size config: quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'",
+	}, {
+		name:        "negative max response size",
+		conf:        map[string]string{ConfigMaxResponseSize: "-1Mi"},
+		expectedErr: `invalid max-response-size config: must be greater than zero, got "-1Mi"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := FetchOptionsFromConfig(tc.conf)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected error %q but got none", tc.expectedErr)
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(tc.expected, opts); d != "" {
+				t.Errorf("unexpected options: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		timeout     string
+		expected    time.Duration
+		expectedErr string
+	}{{
+		timeout:  "1m30s",
+		expected: 90 * time.Second,
+	}, {
+		timeout:     "soon",
+		expectedErr: `time: invalid duration "soon"`,
+	}, {
+		timeout:     "0s",
+		expectedErr: "timeout must be greater than zero, got 0s",
+	}, {
+		timeout:     "-1s",
+		expectedErr: "timeout must be greater than zero, got -1s",
+	}} {
+		t.Run(tc.timeout, func(t *testing.T) {
+			d, err := ParseTimeout(tc.timeout)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected error %q but got none", tc.expectedErr)
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d != tc.expected {
+				t.Errorf("expected %s but got %s", tc.expected, d)
+			}
+		})
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	err := TimeoutError("gs://bucket/task.yaml", time.Second)
+	var timeoutErr *resolutioncommon.ResolutionTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a ResolutionTimeoutError but got %#v", err)
+	}
+	if timeoutErr.Resource != "gs://bucket/task.yaml" || timeoutErr.Timeout != time.Second {
+		t.Errorf("unexpected resource %q or timeout %s", timeoutErr.Resource, timeoutErr.Timeout)
+	}
+	if d := cmp.Diff("request for gs://bucket/task.yaml timed out after 1s", err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// ConfigHealthCheckInterval is the key in a resolver's ConfigMap for
+	// the time between health checks of its backend, e.g. "30s".
+	ConfigHealthCheckInterval = "health-check-interval"
+	// ConfigHealthCheckTimeout is the key in a resolver's ConfigMap for
+	// the maximum time a single health check may take, e.g. "2s".
+	ConfigHealthCheckTimeout = "health-check-timeout"
+
+	defaultHealthCheckInterval = time.Minute
+	defaultHealthCheckTimeout  = 5 * time.Second
+
+	// firstHealthCheckDelay gives the resolver's config and feature flags
+	// time to be loaded, which only happens once all the controllers
+	// have been constructed, before the first health check.
+	firstHealthCheckDelay = 10 * time.Second
+)
+
+// DefaultHealthChecks holds the health of the resolvers started with
+// NewController.
+var DefaultHealthChecks = NewHealthChecks()
+
+// HealthStatus is the outcome of the latest health check of a resolver.
+// A resolver that is disabled by its feature flag isn't checked and is
+// reported as Disabled rather than as unhealthy.
+type HealthStatus struct {
+	Healthy     bool      `json:"healthy"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	LastChecked time.Time `json:"lastChecked"`
+}
+
+// HealthChecks holds the latest HealthStatus of each resolver that
+// implements HealthChecker, keyed by resolver type. It is an
+// http.Handler reporting them as JSON so that operators can tell which
+// backends are unreachable without reading the resolvers' logs.
+type HealthChecks struct {
+	mu       sync.Mutex
+	statuses map[string]HealthStatus
+}
+
+// NewHealthChecks returns an empty HealthChecks.
+func NewHealthChecks() *HealthChecks {
+	return &HealthChecks{statuses: map[string]HealthStatus{}}
+}
+
+// Set records the latest health status of the given resolver type.
+func (h *HealthChecks) Set(resolverType string, status HealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.statuses[resolverType] = status
+}
+
+// Statuses returns a copy of the latest health status of each resolver
+// type that has been checked.
+func (h *HealthChecks) Statuses() map[string]HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	statuses := make(map[string]HealthStatus, len(h.statuses))
+	for resolverType, status := range h.statuses {
+		statuses[resolverType] = status
+	}
+	return statuses
+}
+
+// ServeHTTP responds with the health statuses keyed by resolver type,
+// or only that of the resolver type in the "type" query parameter. The
+// response has a 503 status if any of the statuses reported is
+// unhealthy, or if the requested resolver type hasn't been checked.
+// Disabled resolvers are reported without affecting the status.
+func (h *HealthChecks) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	statuses := h.Statuses()
+	code := http.StatusOK
+	if resolverType := req.URL.Query().Get("type"); resolverType != "" {
+		status, ok := statuses[resolverType]
+		statuses = map[string]HealthStatus{}
+		if ok {
+			statuses[resolverType] = status
+		} else {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	for _, status := range statuses {
+		if !status.Healthy && !status.Disabled {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(statuses)
+}
+
+// healthCheckTiming returns the interval between health checks and the
+// timeout of each set in the resolver's config. Invalid values are
+// ignored so that a typo doesn't stop the checks.
+func healthCheckTiming(ctx context.Context) (interval, timeout time.Duration) {
+	conf := GetResolverConfigFromContext(ctx)
+	parse := func(key string, defaultValue time.Duration) time.Duration {
+		value, ok := conf[key]
+		if !ok || value == "" {
+			return defaultValue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a positive duration", key, value)
+			return defaultValue
+		}
+		return d
+	}
+	return parse(ConfigHealthCheckInterval, defaultHealthCheckInterval), parse(ConfigHealthCheckTimeout, defaultHealthCheckTimeout)
+}
+
+// checkHealth runs a single health check of the reconciler's resolver,
+// which must implement HealthChecker, and records its outcome. It
+// returns the time to wait before the next check.
+func (r *Reconciler) checkHealth(ctx context.Context) time.Duration {
+	if r.configStore != nil {
+		ctx = r.configStore.ToContext(ctx)
+	}
+	interval, timeout := healthCheckTiming(ctx)
+
+	err := checkEnabled(ctx, r.gatedType)
+	if err == nil {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = r.resolver.(HealthChecker).CheckHealth(probeCtx)
+		cancel()
+	}
+	status := HealthStatus{Healthy: err == nil, LastChecked: r.Clock.Now()}
+	switch {
+	case err == nil:
+	case resultFromError(err) == ResultDisabled:
+		status.Disabled = true
+	default:
+		status.Error = err.Error()
+	}
+	r.healthChecks.Set(r.resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType], status)
+	return interval
+}
+
+// runHealthChecks checks the health of the reconciler's resolver until
+// ctx is done, if the resolver implements HealthChecker.
+func (r *Reconciler) runHealthChecks(ctx context.Context) {
+	if _, ok := r.resolver.(HealthChecker); !ok {
+		return
+	}
+	go func() {
+		wait := firstHealthCheckDelay
+		for {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			wait = r.checkHealth(ctx)
+		}
+	}()
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test/diff"
+	clock "k8s.io/utils/clock/testing"
+)
+
+// healthCheckingResolver is a FakeResolver whose health checks return
+// err, after waiting for ctx to be done if block is set.
+type healthCheckingResolver struct {
+	FakeResolver
+	err   error
+	block bool
+}
+
+func (r *healthCheckingResolver) CheckHealth(ctx context.Context) error {
+	if r.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return r.err
+}
+
+func TestCheckHealth(t *testing.T) {
+	now := time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+	featureFlags, err := resolverconfig.NewFeatureFlagsFromMap(map[string]string{"enable-fake-resolver": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error parsing feature flags: %v", err)
+	}
+	enabledCtx := resolverconfig.ToContext(context.Background(), &resolverconfig.Config{FeatureFlags: featureFlags})
+
+	for _, tc := range []struct {
+		name      string
+		ctx       context.Context
+		resolver  *healthCheckingResolver
+		gatedType string
+		expected  HealthStatus
+	}{{
+		name:     "healthy",
+		ctx:      context.Background(),
+		resolver: &healthCheckingResolver{},
+		expected: HealthStatus{Healthy: true, LastChecked: now},
+	}, {
+		name:     "unhealthy",
+		ctx:      context.Background(),
+		resolver: &healthCheckingResolver{err: errors.New("backend is down")},
+		expected: HealthStatus{Error: "backend is down", LastChecked: now},
+	}, {
+		name:     "timed out",
+		ctx:      InjectResolverConfigToContext(context.Background(), map[string]string{ConfigHealthCheckTimeout: "10ms"}),
+		resolver: &healthCheckingResolver{block: true},
+		expected: HealthStatus{Error: context.DeadlineExceeded.Error(), LastChecked: now},
+	}, {
+		name:      "gated and enabled",
+		ctx:       enabledCtx,
+		resolver:  &healthCheckingResolver{},
+		gatedType: LabelValueFakeResolverType,
+		expected:  HealthStatus{Healthy: true, LastChecked: now},
+	}, {
+		name:      "gated and disabled",
+		ctx:       context.Background(),
+		resolver:  &healthCheckingResolver{},
+		gatedType: LabelValueFakeResolverType,
+		expected:  HealthStatus{Disabled: true, LastChecked: now},
+	}, {
+		name:     "disabled by the resolver",
+		ctx:      context.Background(),
+		resolver: &healthCheckingResolver{err: resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New("enable-fake-resolver feature flag not true"))},
+		expected: HealthStatus{Disabled: true, LastChecked: now},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Reconciler{
+				Clock:        clock.NewFakePassiveClock(now),
+				resolver:     tc.resolver,
+				gatedType:    tc.gatedType,
+				healthChecks: NewHealthChecks(),
+			}
+			if interval := r.checkHealth(tc.ctx); interval != defaultHealthCheckInterval {
+				t.Errorf("expected the default interval but got %s", interval)
+			}
+			expected := map[string]HealthStatus{LabelValueFakeResolverType: tc.expected}
+			if d := cmp.Diff(expected, r.healthChecks.Statuses()); d != "" {
+				t.Errorf("unexpected health statuses: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestHealthCheckTiming(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		config           map[string]string
+		expectedInterval time.Duration
+		expectedTimeout  time.Duration
+	}{{
+		name:             "defaults",
+		expectedInterval: defaultHealthCheckInterval,
+		expectedTimeout:  defaultHealthCheckTimeout,
+	}, {
+		name: "configured",
+		config: map[string]string{
+			ConfigHealthCheckInterval: "30s",
+			ConfigHealthCheckTimeout:  "2s",
+		},
+		expectedInterval: 30 * time.Second,
+		expectedTimeout:  2 * time.Second,
+	}, {
+		name: "invalid",
+		config: map[string]string{
+			ConfigHealthCheckInterval: "often",
+			ConfigHealthCheckTimeout:  "-1s",
+		},
+		expectedInterval: defaultHealthCheckInterval,
+		expectedTimeout:  defaultHealthCheckTimeout,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			interval, timeout := healthCheckTiming(InjectResolverConfigToContext(context.Background(), tc.config))
+			if interval != tc.expectedInterval {
+				t.Errorf("expected interval %s but got %s", tc.expectedInterval, interval)
+			}
+			if timeout != tc.expectedTimeout {
+				t.Errorf("expected timeout %s but got %s", tc.expectedTimeout, timeout)
+			}
+		})
+	}
+}
+
+func TestHealthChecksServeHTTP(t *testing.T) {
+	checked := time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+	healthChecks := NewHealthChecks()
+	healthChecks.Set("hub", HealthStatus{Healthy: true, LastChecked: checked})
+	healthChecks.Set("bundles", HealthStatus{Error: "registry is down", LastChecked: checked})
+	disabledChecks := NewHealthChecks()
+	disabledChecks.Set("hub", HealthStatus{Healthy: true, LastChecked: checked})
+	disabledChecks.Set("git", HealthStatus{Disabled: true, LastChecked: checked})
+
+	for _, tc := range []struct {
+		name         string
+		checks       *HealthChecks
+		query        string
+		expectedCode int
+		expected     map[string]HealthStatus
+	}{{
+		name:         "all resolvers",
+		expectedCode: http.StatusServiceUnavailable,
+		expected: map[string]HealthStatus{
+			"hub":     {Healthy: true, LastChecked: checked},
+			"bundles": {Error: "registry is down", LastChecked: checked},
+		},
+	}, {
+		name:         "healthy resolver",
+		query:        "?type=hub",
+		expectedCode: http.StatusOK,
+		expected:     map[string]HealthStatus{"hub": {Healthy: true, LastChecked: checked}},
+	}, {
+		name:         "unhealthy resolver",
+		query:        "?type=bundles",
+		expectedCode: http.StatusServiceUnavailable,
+		expected:     map[string]HealthStatus{"bundles": {Error: "registry is down", LastChecked: checked}},
+	}, {
+		name:         "unchecked resolver",
+		query:        "?type=git",
+		expectedCode: http.StatusServiceUnavailable,
+		expected:     map[string]HealthStatus{},
+	}, {
+		name:         "disabled resolvers",
+		checks:       disabledChecks,
+		expectedCode: http.StatusOK,
+		expected: map[string]HealthStatus{
+			"hub": {Healthy: true, LastChecked: checked},
+			"git": {Disabled: true, LastChecked: checked},
+		},
+	}, {
+		name:         "disabled resolver",
+		checks:       disabledChecks,
+		query:        "?type=git",
+		expectedCode: http.StatusOK,
+		expected:     map[string]HealthStatus{"git": {Disabled: true, LastChecked: checked}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			checks := healthChecks
+			if tc.checks != nil {
+				checks = tc.checks
+			}
+			rec := httptest.NewRecorder()
+			checks.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/resolvers"+tc.query, nil))
+			if rec.Code != tc.expectedCode {
+				t.Errorf("expected status %d but got %d", tc.expectedCode, rec.Code)
+			}
+			var got map[string]HealthStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unexpected error decoding response: %v", err)
+			}
+			if d := cmp.Diff(tc.expected, got); d != "" {
+				t.Errorf("unexpected health statuses: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// ConfigMaxIdleConnections is the key in a resolver's ConfigMap for
+	// the maximum number of idle connections the shared HTTP client
+	// keeps open across all hosts.
+	ConfigMaxIdleConnections = "max-idle-connections"
+
+	// ConfigMaxIdleConnectionsPerHost is the key in a resolver's
+	// ConfigMap for the maximum number of idle connections the shared
+	// HTTP client keeps open to a single host. Resolvers resolving many
+	// resources from the same hub or server at once need more than the
+	// two Go keeps by default to reuse their connections.
+	ConfigMaxIdleConnectionsPerHost = "max-idle-connections-per-host"
+
+	// ConfigIdleConnectionTimeout is the key in a resolver's ConfigMap
+	// for how long an idle connection is kept open, e.g. "90s".
+	ConfigIdleConnectionTimeout = "idle-connection-timeout"
+
+	// ConfigDNSServer is the key in a resolver's ConfigMap for the
+	// address, host:port, of the DNS server the shared HTTP client looks
+	// up hosts with instead of the system's resolver.
+	ConfigDNSServer = "dns-server"
+)
+
+// DefaultHTTPPoolSettings are the HTTP client settings used for the
+// ones that aren't set in a resolver's ConfigMap.
+var DefaultHTTPPoolSettings = HTTPPoolSettings{
+	MaxIdleConnections:        100,
+	MaxIdleConnectionsPerHost: 10,
+	IdleConnectionTimeout:     90 * time.Second,
+}
+
+// HTTPPoolSettings configure the connection pool of the HTTP client
+// resolvers share.
+type HTTPPoolSettings struct {
+	MaxIdleConnections        int
+	MaxIdleConnectionsPerHost int
+	IdleConnectionTimeout     time.Duration
+	// DNSServer is the host:port of the DNS server hosts are looked up
+	// with, or empty to use the system's resolver.
+	DNSServer string
+}
+
+// HTTPPoolSettingsFromContext returns the HTTP client settings in the
+// resolver's config, using DefaultHTTPPoolSettings for the ones that
+// aren't set. Invalid values are ignored so that a typo doesn't stop
+// every resolution.
+func HTTPPoolSettingsFromContext(ctx context.Context) HTTPPoolSettings {
+	conf := GetResolverConfigFromContext(ctx)
+	settings := DefaultHTTPPoolSettings
+	for key, setting := range map[string]*int{
+		ConfigMaxIdleConnections:        &settings.MaxIdleConnections,
+		ConfigMaxIdleConnectionsPerHost: &settings.MaxIdleConnectionsPerHost,
+	} {
+		value, ok := conf[key]
+		if !ok || value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a non-negative integer", key, value)
+			continue
+		}
+		*setting = n
+	}
+	if value, ok := conf[ConfigIdleConnectionTimeout]; ok && value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a non-negative duration", ConfigIdleConnectionTimeout, value)
+		} else {
+			settings.IdleConnectionTimeout = timeout
+		}
+	}
+	if value, ok := conf[ConfigDNSServer]; ok && value != "" {
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a host:port address", ConfigDNSServer, value)
+		} else {
+			settings.DNSServer = value
+		}
+	}
+	return settings
+}
+
+// NewTransport returns a new transport with the settings' connection
+// pool, keep-alives and DNS server, and otherwise the same defaults as
+// http.DefaultTransport, e.g. honoring the proxy environment variables.
+// Resolvers that need to customize their transport further, e.g. to
+// trust another certificate authority, start from it.
+func (s HTTPPoolSettings) NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if s.DNSServer != "" {
+		server := s.DNSServer
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server)
+			},
+		}
+	}
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = s.MaxIdleConnections
+	transport.MaxIdleConnsPerHost = s.MaxIdleConnectionsPerHost
+	transport.IdleConnTimeout = s.IdleConnectionTimeout
+	return transport
+}
+
+var (
+	sharedClientsMu sync.Mutex
+	sharedClients   = map[HTTPPoolSettings]*http.Client{}
+)
+
+// HTTPClient returns the HTTP client resolvers share for their outbound
+// requests, with the connection pool configured in the resolver's
+// config. The same client is returned for the same settings, so that
+// sequential resolutions reuse its connections rather than opening new
+// ones. Callers must not modify it; to change e.g. how redirects are
+// followed, modify a copy, which still shares its connections.
+func HTTPClient(ctx context.Context) *http.Client {
+	settings := HTTPPoolSettingsFromContext(ctx)
+	sharedClientsMu.Lock()
+	defer sharedClientsMu.Unlock()
+	if client, ok := sharedClients[settings]; ok {
+		return client
+	}
+	client := &http.Client{Transport: settings.NewTransport()}
+	sharedClients[settings] = client
+	return client
+}
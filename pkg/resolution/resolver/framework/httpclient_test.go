@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/pipeline/test/diff"
+)
+
+func TestHTTPPoolSettingsFromContext(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   map[string]string
+		expected HTTPPoolSettings
+	}{{
+		name:     "defaults",
+		expected: DefaultHTTPPoolSettings,
+	}, {
+		name: "configured",
+		config: map[string]string{
+			ConfigMaxIdleConnections:        "500",
+			ConfigMaxIdleConnectionsPerHost: "50",
+			ConfigIdleConnectionTimeout:     "2m",
+			ConfigDNSServer:                 "10.0.0.10:53",
+		},
+		expected: HTTPPoolSettings{
+			MaxIdleConnections:        500,
+			MaxIdleConnectionsPerHost: 50,
+			IdleConnectionTimeout:     2 * time.Minute,
+			DNSServer:                 "10.0.0.10:53",
+		},
+	}, {
+		name: "invalid values are ignored",
+		config: map[string]string{
+			ConfigMaxIdleConnections:        "lots",
+			ConfigMaxIdleConnectionsPerHost: "-1",
+			ConfigIdleConnectionTimeout:     "forever",
+			ConfigDNSServer:                 "10.0.0.10",
+		},
+		expected: DefaultHTTPPoolSettings,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := InjectResolverConfigToContext(context.Background(), tc.config)
+			if d := cmp.Diff(tc.expected, HTTPPoolSettingsFromContext(ctx)); d != "" {
+				t.Errorf("unexpected settings: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestHTTPClientShared(t *testing.T) {
+	ctx := context.Background()
+	client := HTTPClient(ctx)
+	if HTTPClient(ctx) != client {
+		t.Errorf("expected the same client for the same settings")
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an *http.Transport but got %T", client.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultHTTPPoolSettings.MaxIdleConnectionsPerHost {
+		t.Errorf("expected %d idle connections per host but got %d", DefaultHTTPPoolSettings.MaxIdleConnectionsPerHost, transport.MaxIdleConnsPerHost)
+	}
+
+	configured := InjectResolverConfigToContext(ctx, map[string]string{ConfigMaxIdleConnectionsPerHost: "50"})
+	other := HTTPClient(configured)
+	if other == client {
+		t.Errorf("expected a different client for different settings")
+	}
+	if got := other.Transport.(*http.Transport).MaxIdleConnsPerHost; got != 50 {
+		t.Errorf("expected 50 idle connections per host but got %d", got)
+	}
+}
+
+func TestHTTPClientDNSServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := InjectResolverConfigToContext(context.Background(), map[string]string{ConfigDNSServer: conn.LocalAddr().String()})
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	queried := make(chan struct{})
+	go func() {
+		buf := make([]byte, 512)
+		if _, _, err := conn.ReadFrom(buf); err == nil {
+			close(queried)
+			cancel()
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, "http://resolver-dns-test.invalid/", nil)
+	if err != nil {
+		t.Fatalf("error constructing request: %v", err)
+	}
+	// The server never answers, so the request fails either way, as soon
+	// as the query is received.
+	if resp, err := HTTPClient(ctx).Do(req); err == nil {
+		resp.Body.Close()
+	}
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Errorf("expected the host to be looked up with the configured DNS server")
+	}
+}
+
+// BenchmarkHTTPClientConnectionReuse compares the connections opened by
+// sequential requests sent with the shared client against ones sent
+// with a client constructed for each request, as resolvers used to.
+func BenchmarkHTTPClientConnectionReuse(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		client func() *http.Client
+	}{{
+		name:   "shared client",
+		client: func() *http.Client { return HTTPClient(context.Background()) },
+	}, {
+		name: "client per request",
+		client: func() *http.Client {
+			return &http.Client{Transport: DefaultHTTPPoolSettings.NewTransport()}
+		},
+	}} {
+		b.Run(bc.name, func(b *testing.B) {
+			var conns int64
+			svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "some content")
+			}))
+			svr.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt64(&conns, 1)
+				}
+			}
+			svr.Start()
+			defer svr.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				client := bc.client()
+				resp, err := client.Get(svr.URL)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "conns/op")
+		})
+	}
+}
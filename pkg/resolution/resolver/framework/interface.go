@@ -42,6 +42,13 @@ type Resolver interface {
 
 	// ValidateParams is given the parameters from a resource
 	// request and should return an error if any are missing or invalid.
+	//
+	// ValidateParams must not contact the service the resolver
+	// resolves from, e.g. a hub, registry or git server, nor read
+	// objects such as secrets through the resolver's Kubernetes
+	// clients, so that params can be validated offline, e.g. by
+	// linters. Such lookups belong in Resolve, and checks that need
+	// the remote service in Preflight, see Preflighter.
 	ValidateParams(context.Context, []pipelinev1beta1.Param) error
 
 	// Resolve receives the parameters passed via a resource request
@@ -70,6 +77,19 @@ type ConfigWatcher interface {
 	GetConfigName(context.Context) string
 }
 
+// ClusterScopedConfig is an optional interface that a resolver
+// implementing ConfigWatcher can implement to list the keys of its config
+// that can only be set for the whole cluster, and not for a namespace with
+// namespace-overrides. These are typically the settings of state shared
+// by the resolutions of every namespace, such as the size of a cache,
+// which would otherwise be rebuilt whenever requests from namespaces
+// with different settings alternate.
+type ClusterScopedConfig interface {
+	// ClusterScopedConfigKeys returns the keys of the resolver's config
+	// that namespace-overrides can't set.
+	ClusterScopedConfigKeys(context.Context) []string
+}
+
 // TimedResolution is an optional interface that a resolver can
 // implement to override the default resolution request timeout.
 //
@@ -92,6 +112,118 @@ type TimedResolution interface {
 	GetResolutionTimeout(context.Context, time.Duration) time.Duration
 }
 
+// ParamDefaulter is an optional interface that a resolver can implement
+// to declare defaults for its optional params in one place rather than
+// in both ValidateParams and Resolve.
+//
+// The defaults are added to a request's params before ValidateParams
+// and Resolve are called, but only for params that are absent from the
+// request. A param that is given explicitly always wins, even when its
+// value is empty.
+type ParamDefaulter interface {
+	// DefaultParams receives the current request's context object,
+	// which includes any request-scoped data like resolver config, and
+	// returns the default string values of params keyed by name.
+	DefaultParams(context.Context) map[string]string
+}
+
+// ParamAliaser is an optional interface that a resolver can implement
+// to keep accepting params under deprecated names, e.g. while params
+// are renamed to match the names other resolvers use for the same
+// input.
+//
+// Aliases are replaced with the canonical param names before defaults
+// are added and ValidateParams and Resolve are called, so neither ever
+// sees an alias. A deprecation warning is emitted for each alias used,
+// and a request that sets both an alias and its canonical param is
+// invalid.
+type ParamAliaser interface {
+	// ParamAliases receives the current request's context object and
+	// returns the canonical param names keyed by their aliases.
+	ParamAliases(context.Context) map[string]string
+}
+
+// Preflighter is an optional interface that a resolver can implement
+// to check a request against the service it resolves from before
+// resolving it, e.g. that a requested catalog exists on a hub, so that
+// such requests fail as invalid rather than when fetching. Unlike
+// ValidateParams, Preflight may use the network, so it is kept separate
+// to leave ValidateParams safe to run offline.
+//
+// Preflight is called after ValidateParams succeeds and before Resolve,
+// with the same params. An error fails the request as invalid, just
+// like one from ValidateParams. Since it costs a round trip for every
+// request, resolvers should only check anything when an operator has
+// opted in through their config.
+type Preflighter interface {
+	// Preflight receives the current request's context object, which
+	// includes any request-scoped data like resolver config, and the
+	// validated params, and returns an error if the request can't be
+	// resolved.
+	Preflight(context.Context, []pipelinev1beta1.Param) error
+}
+
+// ParamTracer is an optional interface that a resolver can implement
+// to have the values of some of its params recorded as attributes of
+// the span of each call to Resolve, e.g. the kind and catalog of a
+// resource requested from a hub. Only params that are safe to export
+// to a tracing backend should be listed; params naming secrets, or
+// that may hold credentials such as urls, never should be.
+type ParamTracer interface {
+	// TracedParams receives the current request's context object and
+	// returns the names of the params whose values are recorded.
+	TracedParams(context.Context) []string
+}
+
+// ParamSchemaProvider is an optional interface that a resolver can
+// implement to describe the params it accepts in a machine-readable
+// form, e.g. for UIs and linters checking a resolution's params without
+// a cluster. See GetParamSchema.
+//
+// The schema must be kept in sync with ValidateParams: a param declared
+// as required must fail validation when omitted, and a param with an
+// Enum must only validate with one of its values, or an equivalent
+// spelling of one such as "1" for "true". The params handled by the
+// framework, such as fallback, are added by GetParamSchema and
+// shouldn't be declared by the resolver.
+type ParamSchemaProvider interface {
+	// GetParamSchema receives the current request's context object,
+	// which includes any request-scoped data like resolver config, and
+	// returns the schema of each param the resolver accepts.
+	GetParamSchema(context.Context) []ParamSchema
+}
+
+// BackendIdentifier is an optional interface that a resolver can
+// implement to have a circuit breaker per backend it resolves from, e.g.
+// per registry, rather than a single one for all its resolutions, so
+// that one backend being down doesn't fail resolutions from the others.
+// The breaker is configured with the circuit-breaker-* keys of the
+// resolver's config.
+type BackendIdentifier interface {
+	// Backend receives the current request's context object and the
+	// validated params, and returns the backend, e.g. a host, the
+	// resolution is sent to. It mustn't make any network requests.
+	Backend(context.Context, []pipelinev1beta1.Param) string
+}
+
+// HealthChecker is an optional interface that a resolver can implement
+// to have the reachability of its backend, e.g. a hub or a registry,
+// checked periodically. The outcome of the latest check is reported per
+// resolver type by the HealthChecks the resolver's controller records
+// them in. A failing check doesn't stop the resolver from handling
+// requests.
+//
+// The interval between checks and the timeout of each can be set with
+// the health-check-interval and health-check-timeout keys of the
+// resolver's config.
+type HealthChecker interface {
+	// CheckHealth receives a context object holding the resolver's
+	// config, along with a deadline, and should perform a cheap probe
+	// of the resolver's backend, e.g. a HEAD request, returning an
+	// error if it can't be reached.
+	CheckHealth(context.Context) error
+}
+
 // ResolvedResource returns the data and annotations of a successful
 // resource fetch.
 type ResolvedResource interface {
@@ -99,3 +231,17 @@ type ResolvedResource interface {
 	Annotations() map[string]string
 	Source() *v1beta1.ConfigSource
 }
+
+// MultiDocumentResource is an optional interface that a ResolvedResource
+// can implement when its data may hold several YAML documents, e.g. a
+// Task along with a ConfigMap it needs. The resolver framework splits
+// the data of resources that don't implement it itself.
+//
+// Resolutions only return resources with several documents when the
+// request's documents param is "multiple".
+type MultiDocumentResource interface {
+	ResolvedResource
+	// Documents returns each of the documents in the data decoded to
+	// JSON, in the order they appear.
+	Documents() ([][]byte, error)
+}
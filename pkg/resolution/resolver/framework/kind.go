@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DetectKind returns the kind of the resolved YAML or JSON object,
+// lowercased, e.g. "task" for a Task, for resolvers that detect the kind
+// of a resource when the request doesn't give it. It returns an error if
+// the object has no kind or its kind isn't one of the allowed ones.
+func DetectKind(content []byte, allowed []string) (string, error) {
+	var obj struct {
+		Kind string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(content, &obj); err != nil {
+		return "", fmt.Errorf("could not parse resource: %w", err)
+	}
+	if obj.Kind == "" {
+		return "", errors.New("resource has no kind")
+	}
+	kind := strings.ToLower(obj.Kind)
+	for _, a := range allowed {
+		if a == kind {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("detected kind %s is not one of the allowed kinds: %s", obj.Kind, strings.Join(allowed, ", "))
+}
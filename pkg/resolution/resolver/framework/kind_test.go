@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import "testing"
+
+func TestDetectKind(t *testing.T) {
+	allowed := []string{"task", "pipeline"}
+	for _, tc := range []struct {
+		name         string
+		content      string
+		expectedKind string
+		expectedErr  string
+	}{{
+		name:         "task",
+		content:      "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: foo\n",
+		expectedKind: "task",
+	}, {
+		name:         "json pipeline",
+		content:      `{"apiVersion":"tekton.dev/v1beta1","kind":"Pipeline","metadata":{"name":"foo"}}`,
+		expectedKind: "pipeline",
+	}, {
+		name:        "unexpected kind",
+		content:     "apiVersion: tekton.dev/v1alpha1\nkind: StepAction\nmetadata:\n  name: foo\n",
+		expectedErr: "detected kind StepAction is not one of the allowed kinds: task, pipeline",
+	}, {
+		name:        "no kind",
+		content:     "apiVersion: tekton.dev/v1beta1\nmetadata:\n  name: foo\n",
+		expectedErr: "resource has no kind",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, err := DetectKind([]byte(tc.content), allowed)
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kind != tc.expectedKind {
+				t.Errorf("expected kind %q but got %q", tc.expectedKind, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// ConfigMaxParams is the key in a resolver's ConfigMap for the
+	// maximum number of params a request may have. Zero means no limit.
+	ConfigMaxParams = "max-params"
+
+	// ConfigMaxParamsSize is the key in a resolver's ConfigMap for the
+	// maximum total size of the names and values of a request's params,
+	// e.g. "256Ki". Zero means no limit.
+	ConfigMaxParamsSize = "max-params-size"
+
+	// DefaultMaxParams is the maximum number of params a request may
+	// have when the max-params config isn't set, well above the number
+	// of params any of the built-in resolvers accepts.
+	DefaultMaxParams = 100
+
+	// DefaultMaxParamsSize is the maximum total size in bytes of a
+	// request's params when the max-params-size config isn't set. It
+	// leaves room for inline content such as the fallback param.
+	DefaultMaxParamsSize = 256 << 10
+)
+
+// maxParams returns the limit on the number of params set in the
+// resolver's config. Invalid values are ignored so that a typo doesn't
+// stop every resolution.
+func maxParams(ctx context.Context) int {
+	value, ok := GetResolverConfigFromContext(ctx)[ConfigMaxParams]
+	if !ok || value == "" {
+		return DefaultMaxParams
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a non-negative integer", ConfigMaxParams, value)
+		return DefaultMaxParams
+	}
+	return limit
+}
+
+// maxParamsSize returns the limit on the total size of params set in
+// the resolver's config. Invalid values are ignored so that a typo
+// doesn't stop every resolution.
+func maxParamsSize(ctx context.Context) int64 {
+	value, ok := GetResolverConfigFromContext(ctx)[ConfigMaxParamsSize]
+	if !ok || value == "" {
+		return DefaultMaxParamsSize
+	}
+	size, err := resource.ParseQuantity(value)
+	if err != nil || size.Sign() < 0 {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a non-negative quantity", ConfigMaxParamsSize, value)
+		return DefaultMaxParamsSize
+	}
+	return size.Value()
+}
+
+// paramsSize returns the total size in bytes of the names and values of
+// the params, including the keys of object values.
+func paramsSize(params []pipelinev1beta1.Param) int64 {
+	var size int64
+	for _, p := range params {
+		size += int64(len(p.Name) + len(p.Value.StringVal))
+		for _, v := range p.Value.ArrayVal {
+			size += int64(len(v))
+		}
+		for k, v := range p.Value.ObjectVal {
+			size += int64(len(k) + len(v))
+		}
+	}
+	return size
+}
+
+// checkParamLimits returns an error if a request has more params, or
+// larger ones, than the resolver's config allows, so that pathologically
+// large requests are rejected before any resolver looks at them.
+func checkParamLimits(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if limit := maxParams(ctx); limit > 0 && len(params) > limit {
+		return fmt.Errorf("request has %d params, more than the %s of %d", len(params), ConfigMaxParams, limit)
+	}
+	if limit := maxParamsSize(ctx); limit > 0 {
+		if size := paramsSize(params); size > limit {
+			return fmt.Errorf("request params total %d bytes, more than the %s of %d bytes", size, ConfigMaxParamsSize, limit)
+		}
+	}
+	return nil
+}
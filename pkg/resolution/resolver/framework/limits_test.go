@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/system"
+)
+
+// numberedParams returns n params named p0, p1 and so on, with empty
+// values.
+func numberedParams(n int) []pipelinev1beta1.Param {
+	params := make([]pipelinev1beta1.Param, 0, n)
+	for i := 0; i < n; i++ {
+		params = append(params, pipelinev1beta1.Param{
+			Name:  fmt.Sprintf("p%d", i),
+			Value: *pipelinev1beta1.NewStructuredValues(""),
+		})
+	}
+	return params
+}
+
+// sizedParams returns params totalling the given size in bytes, split
+// between a string, an array and an object value.
+func sizedParams(size int) []pipelinev1beta1.Param {
+	// The names, the array's item and the object's key and value take
+	// 10 bytes, the rest goes to the string value.
+	return []pipelinev1beta1.Param{{
+		Name:  "s",
+		Value: *pipelinev1beta1.NewStructuredValues(strings.Repeat("a", size-10)),
+	}, {
+		Name:  "a",
+		Value: *pipelinev1beta1.NewStructuredValues("bb", "cc"),
+	}, {
+		Name:  "o",
+		Value: *pipelinev1beta1.NewObject(map[string]string{"k": "vv"}),
+	}}
+}
+
+func TestCheckParamLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		config      map[string]string
+		params      []pipelinev1beta1.Param
+		expectedErr string
+	}{{
+		name:   "below max params",
+		config: map[string]string{ConfigMaxParams: "3"},
+		params: numberedParams(2),
+	}, {
+		name:   "at max params",
+		config: map[string]string{ConfigMaxParams: "3"},
+		params: numberedParams(3),
+	}, {
+		name:        "above max params",
+		config:      map[string]string{ConfigMaxParams: "3"},
+		params:      numberedParams(4),
+		expectedErr: "request has 4 params, more than the max-params of 3",
+	}, {
+		name:   "at default max params",
+		params: numberedParams(DefaultMaxParams),
+	}, {
+		name:        "above default max params",
+		params:      numberedParams(DefaultMaxParams + 1),
+		expectedErr: fmt.Sprintf("request has %d params, more than the max-params of %d", DefaultMaxParams+1, DefaultMaxParams),
+	}, {
+		name:   "zero disables max params",
+		config: map[string]string{ConfigMaxParams: "0"},
+		params: numberedParams(DefaultMaxParams + 1),
+	}, {
+		name:   "invalid max params is ignored",
+		config: map[string]string{ConfigMaxParams: "lots"},
+		params: numberedParams(DefaultMaxParams),
+	}, {
+		name:   "below max params size",
+		config: map[string]string{ConfigMaxParamsSize: "64"},
+		params: sizedParams(63),
+	}, {
+		name:   "at max params size",
+		config: map[string]string{ConfigMaxParamsSize: "64"},
+		params: sizedParams(64),
+	}, {
+		name:        "above max params size",
+		config:      map[string]string{ConfigMaxParamsSize: "64"},
+		params:      sizedParams(65),
+		expectedErr: "request params total 65 bytes, more than the max-params-size of 64 bytes",
+	}, {
+		name:   "at default max params size",
+		params: sizedParams(DefaultMaxParamsSize),
+	}, {
+		name:        "above default max params size",
+		params:      sizedParams(DefaultMaxParamsSize + 1),
+		expectedErr: fmt.Sprintf("request params total %d bytes, more than the max-params-size of %d bytes", DefaultMaxParamsSize+1, DefaultMaxParamsSize),
+	}, {
+		name:   "zero disables max params size",
+		config: map[string]string{ConfigMaxParamsSize: "0"},
+		params: sizedParams(DefaultMaxParamsSize + 1),
+	}, {
+		name:   "invalid max params size is ignored",
+		config: map[string]string{ConfigMaxParamsSize: "-1"},
+		params: sizedParams(DefaultMaxParamsSize),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := InjectResolverConfigToContext(context.Background(), tc.config)
+			err := checkParamLimits(ctx, tc.params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestDryValidateParamLimits(t *testing.T) {
+	// The params would also fail the resolver's own validation, since
+	// they lack its param, but the limit is checked first.
+	ctx := InjectResolverConfigToContext(context.Background(), map[string]string{ConfigMaxParams: "3"})
+	err := DryValidate(ctx, &FakeResolver{}, numberedParams(4))
+	var invalidErr *resolutioncommon.InvalidParamsError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected an InvalidParamsError but got %v", err)
+	}
+	if expected := "invalid params for Fake resolver: request has 4 params, more than the max-params of 3"; err.Error() != expected {
+		t.Errorf("expected error %q but got %q", expected, err.Error())
+	}
+}
+
+func TestReconcileParamLimits(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: time.Now()},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: append(fakeParams("foo"), numberedParams(3)...),
+		},
+	}
+	d := test.Data{
+		ConfigMaps: []*corev1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fake-resolver-config",
+				Namespace: system.Namespace(),
+			},
+			Data: map[string]string{ConfigMaxParams: "3"},
+		}, {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resolverconfig.GetFeatureFlagsConfigName(),
+				Namespace: system.Namespace(),
+			},
+		}},
+		ResolutionRequests: []*v1beta1.ResolutionRequest{rr},
+	}
+	resolver := &configWatchingResolver{FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"foo": {Content: "some content"},
+	}}}
+
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, d, resolver, setClockOnReconciler)
+	defer cancel()
+
+	if err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr)); err == nil {
+		t.Fatalf("expected an error reconciling a request with too many params")
+	}
+	reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting updated ResolutionRequest: %v", err)
+	}
+	cond := reconciledRR.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil || !cond.IsFalse() || !strings.Contains(cond.Message, "request has 4 params, more than the max-params of 3") {
+		t.Errorf("expected failed condition reporting the param limit but got %v", cond)
+	}
+	if reconciledRR.Status.Data != "" {
+		t.Errorf("expected no data to be written but got %q", reconciledRR.Status.Data)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+// The coarse outcomes of a resolution that are recorded in the result
+// tag of the resolution metrics.
+const (
+	ResultSuccess     = "success"
+	ResultFallback    = "fallback"
+	ResultNotFound    = "not-found"
+	ResultInvalid     = "invalid"
+	ResultTimeout     = "timeout"
+	ResultDisabled    = "disabled"
+	ResultRateLimited = "rate-limited"
+	ResultDenied      = "permission-denied"
+	ResultTransient   = "transient"
+	ResultUnverified  = "verification-failed"
+	ResultUndecodable = "decode-failed"
+	ResultCancelled   = "cancelled"
+	ResultError       = "error"
+)
+
+var (
+	resolverTypeTag = tag.MustNewKey("resolver_type")
+	resultTag       = tag.MustNewKey("result")
+
+	resolutionDuration = stats.Float64(
+		"resolution_request_duration_seconds",
+		"The time taken to resolve a resolution request in seconds",
+		stats.UnitDimensionless)
+
+	resolutionCount = stats.Float64(
+		"resolution_request_count",
+		"number of resolution requests handled",
+		stats.UnitDimensionless)
+
+	resolutionDurationView = &view.View{
+		Description: resolutionDuration.Description(),
+		Measure:     resolutionDuration,
+		Aggregation: view.Distribution(0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60),
+		TagKeys:     []tag.Key{resolverTypeTag, resultTag},
+	}
+
+	resolutionCountView = &view.View{
+		Description: resolutionCount.Description(),
+		Measure:     resolutionCount,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{resolverTypeTag, resultTag},
+	}
+
+	// The views can only be registered once per process while many
+	// resolvers can share a process.
+	registerViewsOnce sync.Once
+)
+
+// registerMetrics registers the views of the resolution metrics so that
+// they're exported through the knative metrics plumbing.
+func registerMetrics(ctx context.Context) {
+	registerViewsOnce.Do(func() {
+		if err := view.Register(resolutionDurationView, resolutionCountView); err != nil {
+			logging.FromContext(ctx).Errorf("Failed to register resolution metrics views: %v", err)
+		}
+	})
+}
+
+// recordResolution records the duration and result of a single
+// resolution. Only the resolver type and the coarse result are used as
+// tags so that the number of series stays bounded.
+func recordResolution(ctx context.Context, resolverType, result string, duration time.Duration) {
+	ctx, err := tag.New(ctx, tag.Insert(resolverTypeTag, resolverType), tag.Insert(resultTag, result))
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Failed to tag resolution metrics: %v", err)
+		return
+	}
+	metrics.Record(ctx, resolutionDuration.M(duration.Seconds()))
+	metrics.Record(ctx, resolutionCount.M(1))
+}
+
+// resultFromError returns the coarse result of a resolution that
+// failed with the given error, using the type of the structured errors
+// in resolutioncommon or the reason of a resolutioncommon.Error when
+// the resolver returned one.
+func resultFromError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ResultTimeout
+	}
+	var notFound *resolutioncommon.ResolutionNotFoundError
+	if errors.As(err, &notFound) {
+		return ResultNotFound
+	}
+	var timeout *resolutioncommon.ResolutionTimeoutError
+	if errors.As(err, &timeout) {
+		return ResultTimeout
+	}
+	var rateLimited *resolutioncommon.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return ResultRateLimited
+	}
+	var denied *resolutioncommon.PermissionDeniedError
+	if errors.As(err, &denied) {
+		return ResultDenied
+	}
+	var transient *resolutioncommon.TransientError
+	if errors.As(err, &transient) {
+		return ResultTransient
+	}
+	var unverified *resolutioncommon.VerificationError
+	if errors.As(err, &unverified) {
+		return ResultUnverified
+	}
+	var undecodable *DecodeError
+	if errors.As(err, &undecodable) {
+		return ResultUndecodable
+	}
+	var invalid *resolutioncommon.InvalidParamsError
+	if errors.As(err, &invalid) {
+		return ResultInvalid
+	}
+	var e *resolutioncommon.Error
+	if errors.As(err, &e) {
+		switch e.Reason {
+		case resolutioncommon.ReasonResolverDisabled:
+			return ResultDisabled
+		case resolutioncommon.ReasonResourceNotFound:
+			return ResultNotFound
+		case resolutioncommon.ReasonResolutionTimedOut:
+			return ResultTimeout
+		}
+	}
+	return ResultError
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"go.opencensus.io/stats/view"
+	"knative.dev/pkg/metrics/metricstest"
+	_ "knative.dev/pkg/metrics/testing"
+)
+
+func TestResultFromError(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected string
+	}{{
+		err:      errors.New("something went wrong"),
+		expected: ResultError,
+	}, {
+		err:      resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New("disabled")),
+		expected: ResultDisabled,
+	}, {
+		err:      fmt.Errorf("wrapped: %w", resolutioncommon.NewError(resolutioncommon.ReasonResourceNotFound, errors.New("not found"))),
+		expected: ResultNotFound,
+	}, {
+		err:      fmt.Errorf("wrapped: %w", &resolutioncommon.ResolutionNotFoundError{Resource: "foo", Original: errors.New("not found")}),
+		expected: ResultNotFound,
+	}, {
+		err:      &resolutioncommon.ResolutionTimeoutError{Resource: "foo", Timeout: time.Second, Original: errors.New("timed out")},
+		expected: ResultTimeout,
+	}, {
+		err:      &resolutioncommon.InvalidParamsError{ResolverName: "Fake", Original: errors.New("missing fake-key")},
+		expected: ResultInvalid,
+	}, {
+		err:      fmt.Errorf("wrapped: %w", &resolutioncommon.RateLimitedError{Resource: "foo", RetryAfter: time.Second, Original: errors.New("rate limited")}),
+		expected: ResultRateLimited,
+	}, {
+		err:      fmt.Errorf("wrapped: %w", &resolutioncommon.PermissionDeniedError{Resource: "foo", Original: errors.New("denied")}),
+		expected: ResultDenied,
+	}, {
+		err:      &resolutioncommon.TransientError{Resource: "foo", Original: errors.New("server error")},
+		expected: ResultTransient,
+	}, {
+		err:      fmt.Errorf("wrapped: %w", &resolutioncommon.VerificationError{ResolverType: "fake", Original: errors.New("bad signature")}),
+		expected: ResultUnverified,
+	}, {
+		err:      fmt.Errorf("wrapped: %w", &DecodeError{Mode: "base64", Original: errors.New("invalid base64")}),
+		expected: ResultUndecodable,
+	}, {
+		err:      fmt.Errorf("fetching: %w", context.DeadlineExceeded),
+		expected: ResultTimeout,
+	}, {
+		err:      resolutioncommon.NewError(resolutioncommon.ReasonResolutionFailed, errors.New("failed")),
+		expected: ResultError,
+	}} {
+		if result := resultFromError(tc.err); result != tc.expected {
+			t.Errorf("expected result %q for error %q but got %q", tc.expected, tc.err, result)
+		}
+	}
+}
+
+func TestRecordResolution(t *testing.T) {
+	for _, tc := range []struct {
+		result    string
+		durations []time.Duration
+	}{{
+		result:    ResultSuccess,
+		durations: []time.Duration{time.Second, 2 * time.Second},
+	}, {
+		result:    ResultNotFound,
+		durations: []time.Duration{500 * time.Millisecond},
+	}} {
+		t.Run(tc.result, func(t *testing.T) {
+			// Registering the views again resets their data. They're
+			// unregistered first in case an earlier test reconciled a
+			// request, which registers them.
+			view.Unregister(resolutionDurationView, resolutionCountView)
+			if err := view.Register(resolutionDurationView, resolutionCountView); err != nil {
+				t.Fatalf("failed to register views: %v", err)
+			}
+			defer view.Unregister(resolutionDurationView, resolutionCountView)
+
+			min, max := tc.durations[0].Seconds(), tc.durations[0].Seconds()
+			for _, d := range tc.durations {
+				recordResolution(context.Background(), "hub", tc.result, d)
+				if d.Seconds() < min {
+					min = d.Seconds()
+				}
+				if d.Seconds() > max {
+					max = d.Seconds()
+				}
+			}
+
+			tags := map[string]string{"resolver_type": "hub", "result": tc.result}
+			metricstest.CheckCountData(t, "resolution_request_count", tags, int64(len(tc.durations)))
+			metricstest.CheckDistributionData(t, "resolution_request_duration_seconds", tags, int64(len(tc.durations)), min, max)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigNamespaceOverrides is the key of a resolver's ConfigMap holding
+// settings that override the rest of the ConfigMap for requests from
+// specific namespaces, as a YAML map from a namespace to its settings:
+//
+//	namespace-overrides: |
+//	  team-a:
+//	    default-catalog: "team-a"
+//
+// It lets tenants of a cluster use e.g. their own hub or registry mirror.
+const ConfigNamespaceOverrides = "namespace-overrides"
+
+// sharedConfigKeys are the keys of the framework's config that set up
+// state shared by the resolutions of every namespace, the concurrency
+// limit, the circuit breaker and the HTTP client's connection pool, so
+// that namespace-overrides can't set them for any resolver.
+var sharedConfigKeys = []string{
+	ConfigMaxConcurrentResolutions,
+	ConfigCircuitBreakerFailureThreshold,
+	ConfigCircuitBreakerCooldown,
+	ConfigCircuitBreakerProbes,
+	ConfigMaxIdleConnections,
+	ConfigMaxIdleConnectionsPerHost,
+	ConfigIdleConnectionTimeout,
+	ConfigDNSServer,
+}
+
+// ApplyNamespaceOverrides returns the resolver's config with the
+// settings that the namespace-overrides config sets for the given
+// namespace layered over it, so that the namespace's settings take
+// precedence over the global ones. The namespace-overrides key itself is
+// left out of the returned config, and so are the overrides of the given
+// cluster-scoped keys and of the framework's shared settings, such as
+// max-concurrent-resolutions, which can only be set for the whole cluster. The
+// config is returned as is, without namespace-overrides, if it is
+// invalid, after logging why.
+func ApplyNamespaceOverrides(ctx context.Context, conf map[string]string, namespace string, clusterScopedKeys ...string) map[string]string {
+	raw, ok := conf[ConfigNamespaceOverrides]
+	if !ok {
+		return conf
+	}
+	merged := make(map[string]string, len(conf))
+	for key, value := range conf {
+		if key != ConfigNamespaceOverrides {
+			merged[key] = value
+		}
+	}
+	if namespace == "" {
+		return merged
+	}
+	overrides := map[string]map[string]string{}
+	if err := yaml.Unmarshal([]byte(raw), &overrides); err != nil {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config: %v", ConfigNamespaceOverrides, err)
+		return merged
+	}
+	clusterScoped := sets.NewString(sharedConfigKeys...).Insert(clusterScopedKeys...)
+	for key, value := range overrides[namespace] {
+		if key == ConfigNamespaceOverrides {
+			continue
+		}
+		if clusterScoped.Has(key) {
+			logging.FromContext(ctx).Warnf("ignoring %s config set for namespace %s in %s: it can only be set for the whole cluster", key, namespace, ConfigNamespaceOverrides)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
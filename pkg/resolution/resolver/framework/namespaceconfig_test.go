@@ -0,0 +1,242 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+func TestApplyNamespaceOverrides(t *testing.T) {
+	const overrides = `
+team-a:
+  hub-url: https://hub.team-a.example.com
+  default-catalog: team-a
+team-b:
+  default-kind: pipeline
+`
+	for _, tc := range []struct {
+		name      string
+		conf      map[string]string
+		namespace string
+		expected  map[string]string
+	}{{
+		name: "no overrides",
+		conf: map[string]string{
+			"default-catalog": "tekton",
+		},
+		namespace: "team-a",
+		expected: map[string]string{
+			"default-catalog": "tekton",
+		},
+	}, {
+		name: "namespace overrides global",
+		conf: map[string]string{
+			"default-catalog":        "tekton",
+			"default-kind":           "task",
+			ConfigNamespaceOverrides: overrides,
+		},
+		namespace: "team-a",
+		expected: map[string]string{
+			"default-catalog": "team-a",
+			"default-kind":    "task",
+			"hub-url":         "https://hub.team-a.example.com",
+		},
+	}, {
+		name: "other namespace",
+		conf: map[string]string{
+			"default-catalog":        "tekton",
+			"default-kind":           "task",
+			ConfigNamespaceOverrides: overrides,
+		},
+		namespace: "team-b",
+		expected: map[string]string{
+			"default-catalog": "tekton",
+			"default-kind":    "pipeline",
+		},
+	}, {
+		name: "namespace without overrides",
+		conf: map[string]string{
+			"default-catalog":        "tekton",
+			ConfigNamespaceOverrides: overrides,
+		},
+		namespace: "team-c",
+		expected: map[string]string{
+			"default-catalog": "tekton",
+		},
+	}, {
+		name: "no namespace",
+		conf: map[string]string{
+			"default-catalog":        "tekton",
+			ConfigNamespaceOverrides: overrides,
+		},
+		expected: map[string]string{
+			"default-catalog": "tekton",
+		},
+	}, {
+		name: "overrides can't be nested",
+		conf: map[string]string{
+			ConfigNamespaceOverrides: "team-a:\n  namespace-overrides: 'team-a: {}'\n",
+		},
+		namespace: "team-a",
+		expected:  map[string]string{},
+	}, {
+		name: "invalid overrides are ignored",
+		conf: map[string]string{
+			"default-catalog":        "tekton",
+			ConfigNamespaceOverrides: "team-a: [not, a, map]",
+		},
+		namespace: "team-a",
+		expected: map[string]string{
+			"default-catalog": "tekton",
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ApplyNamespaceOverrides(logtesting.TestContextWithLogger(t), tc.conf, tc.namespace)
+			if d := cmp.Diff(tc.expected, got); d != "" {
+				t.Errorf("unexpected config: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestApplyNamespaceOverridesClusterScopedKeys(t *testing.T) {
+	conf := map[string]string{
+		"cache-size":             "1024",
+		"default-catalog":        "tekton",
+		ConfigNamespaceOverrides: "team-a:\n  cache-size: '16'\n  default-catalog: team-a\n",
+	}
+	got := ApplyNamespaceOverrides(logtesting.TestContextWithLogger(t), conf, "team-a", "cache-size")
+	expected := map[string]string{
+		"cache-size":      "1024",
+		"default-catalog": "team-a",
+	}
+	if d := cmp.Diff(expected, got); d != "" {
+		t.Errorf("unexpected config: %s", diff.PrintWantGot(d))
+	}
+}
+
+// TestApplyNamespaceOverridesSharedKeys checks that the settings of the
+// state the framework shares between namespaces are scoped to the
+// cluster for every resolver, even one that scopes none of its own.
+func TestApplyNamespaceOverridesSharedKeys(t *testing.T) {
+	conf := map[string]string{
+		ConfigMaxConcurrentResolutions:       "10",
+		ConfigCircuitBreakerFailureThreshold: "5",
+		ConfigNamespaceOverrides: `
+team-a:
+  max-concurrent-resolutions: "0"
+  circuit-breaker-failure-threshold: "1000"
+  circuit-breaker-cooldown: 1s
+  circuit-breaker-probes: "100"
+  max-idle-connections: "1"
+  max-idle-connections-per-host: "1"
+  idle-connection-timeout: 1s
+  dns-server: 10.0.0.10:53
+  default-catalog: team-a
+`,
+	}
+	got := ApplyNamespaceOverrides(logtesting.TestContextWithLogger(t), conf, "team-a")
+	expected := map[string]string{
+		ConfigMaxConcurrentResolutions:       "10",
+		ConfigCircuitBreakerFailureThreshold: "5",
+		"default-catalog":                    "team-a",
+	}
+	if d := cmp.Diff(expected, got); d != "" {
+		t.Errorf("unexpected config: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestApplyNamespaceOverridesLeavesConfigUnchanged(t *testing.T) {
+	conf := map[string]string{
+		"default-catalog":        "tekton",
+		ConfigNamespaceOverrides: "team-a:\n  default-catalog: team-a\n",
+	}
+	ApplyNamespaceOverrides(context.Background(), conf, "team-a")
+	expected := map[string]string{
+		"default-catalog":        "tekton",
+		ConfigNamespaceOverrides: "team-a:\n  default-catalog: team-a\n",
+	}
+	if d := cmp.Diff(expected, conf); d != "" {
+		t.Errorf("unexpected change to the global config: %s", diff.PrintWantGot(d))
+	}
+}
+
+// TestConfigStoreNamespaceOverrides checks that the config stored in the
+// context of a resolution is that of the namespace of its request.
+func TestConfigStoreNamespaceOverrides(t *testing.T) {
+	store := NewConfigStore("test", logtesting.TestLogger(t))
+	store.untyped.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Data: map[string]string{
+			"hub-url":                "https://hub.example.com",
+			ConfigNamespaceOverrides: "team-a:\n  hub-url: https://hub.team-a.example.com\n",
+		},
+	})
+
+	for _, tc := range []struct {
+		namespace string
+		expected  string
+	}{{
+		namespace: "team-a",
+		expected:  "https://hub.team-a.example.com",
+	}, {
+		namespace: "team-b",
+		expected:  "https://hub.example.com",
+	}, {
+		expected: "https://hub.example.com",
+	}} {
+		t.Run(tc.namespace, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.namespace != "" {
+				ctx = resolutioncommon.InjectRequestNamespace(ctx, tc.namespace)
+			}
+			conf := GetResolverConfigFromContext(store.ToContext(ctx))
+			if d := cmp.Diff(tc.expected, conf["hub-url"]); d != "" {
+				t.Errorf("unexpected hub-url: %s", diff.PrintWantGot(d))
+			}
+			if _, ok := conf[ConfigNamespaceOverrides]; ok {
+				t.Errorf("expected the %s config to be left out", ConfigNamespaceOverrides)
+			}
+		})
+	}
+}
+
+// TestConfigStoreClusterScopedKeys checks that namespaces can't override
+// the config the resolver scopes to the cluster.
+func TestConfigStoreClusterScopedKeys(t *testing.T) {
+	store := NewConfigStore("test", logtesting.TestLogger(t))
+	store.clusterScopedKeys = []string{"cache-size"}
+	store.untyped.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Data: map[string]string{
+			"cache-size":             "1024",
+			ConfigNamespaceOverrides: "team-a:\n  cache-size: '16'\n",
+		},
+	})
+	ctx := resolutioncommon.InjectRequestNamespace(logtesting.TestContextWithLogger(t), "team-a")
+	if got := GetResolverConfigFromContext(store.ToContext(ctx))["cache-size"]; got != "1024" {
+		t.Errorf("expected the cluster's cache-size to be used but got %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// ApplyDefaultParams returns the given params with the defaults of the
+// resolver added for any param that is absent. Params that are present
+// are kept as they are, even when their value is empty, and the given
+// slice is never modified. Resolvers that don't implement
+// ParamDefaulter get their params back unchanged.
+func ApplyDefaultParams(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) []pipelinev1beta1.Param {
+	defaulter, ok := resolver.(ParamDefaulter)
+	if !ok {
+		return params
+	}
+	defaults := defaulter.DefaultParams(ctx)
+	if len(defaults) == 0 {
+		return params
+	}
+
+	present := make(map[string]bool, len(params))
+	for _, p := range params {
+		present[p.Name] = true
+	}
+	// The defaults are added in a stable order so that the resulting
+	// params, and anything derived from them, don't change between
+	// calls.
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		if !present[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return params
+	}
+	sort.Strings(names)
+
+	withDefaults := make([]pipelinev1beta1.Param, 0, len(params)+len(names))
+	withDefaults = append(withDefaults, params...)
+	for _, name := range names {
+		withDefaults = append(withDefaults, pipelinev1beta1.Param{
+			Name:  name,
+			Value: *pipelinev1beta1.NewStructuredValues(defaults[name]),
+		})
+	}
+	return withDefaults
+}
+
+// NormalizeParamAliases returns the given params with any alias declared
+// by the resolver replaced by its canonical name, along with a
+// deprecation warning for each alias that was used. Params keep their
+// order and values, and the given slice is never modified. It is an
+// error for the params to include both an alias and its canonical name,
+// or two different aliases of the same name. Resolvers that don't implement
+// ParamAliaser get their params back unchanged.
+func NormalizeParamAliases(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) ([]pipelinev1beta1.Param, []string, error) {
+	aliaser, ok := resolver.(ParamAliaser)
+	if !ok {
+		return params, nil, nil
+	}
+	aliases := aliaser.ParamAliases(ctx)
+	if len(aliases) == 0 {
+		return params, nil, nil
+	}
+
+	// given records the name each canonical param was given under.
+	given := make(map[string]string, len(params))
+	normalized := make([]pipelinev1beta1.Param, 0, len(params))
+	var warnings []string
+	for _, p := range params {
+		name := p.Name
+		if canonical, ok := aliases[name]; ok && canonical != name {
+			warnings = append(warnings, fmt.Sprintf("param %q is deprecated, use %q instead", name, canonical))
+			name = canonical
+		}
+		if previous, ok := given[name]; ok && previous != p.Name {
+			return nil, warnings, fmt.Errorf("params %q and %q can't both be set, they're both %q", previous, p.Name, name)
+		}
+		given[name] = p.Name
+		p.Name = name
+		normalized = append(normalized, p)
+	}
+	return normalized, warnings, nil
+}
+
+// preflight calls the resolver's Preflight method if it implements
+// Preflighter.
+func preflight(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) error {
+	if p, ok := resolver.(Preflighter); ok {
+		return p.Preflight(ctx, params)
+	}
+	return nil
+}
+
+// variableReferenceRegex matches a Tekton variable reference such as
+// $(params.foo), $(params['foo']) or $(tasks.a.results.b). Only the
+// roots that Tekton substitutes are matched so that values which merely
+// contain a "$", like pa$$word or $(date), aren't mistaken for one.
+var variableReferenceRegex = regexp.MustCompile(`\$\((?:params|context|tasks|finally|workspaces|results|steps|step|resources|inputs|outputs)(?:\.|\[)[^()]*\)`)
+
+// ValidateNoVariableReferences returns an error if the value of any of
+// the params still contains a Tekton variable reference. The references
+// in a resolution's params are substituted before the ResolutionRequest
+// is created, so one that is left over is almost always an authoring
+// mistake, such as referencing a param that isn't declared, which would
+// otherwise fail obscurely once the value reaches the remote source.
+func ValidateNoVariableReferences(params []pipelinev1beta1.Param) error {
+	for _, p := range params {
+		values := append([]string{p.Value.StringVal}, p.Value.ArrayVal...)
+		keys := make([]string, 0, len(p.Value.ObjectVal))
+		for k := range p.Value.ObjectVal {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			values = append(values, p.Value.ObjectVal[k])
+		}
+		for _, v := range values {
+			if ref := variableReferenceRegex.FindString(v); ref != "" {
+				return fmt.Errorf("param %q still contains an unresolved variable reference %q", p.Name, ref)
+			}
+		}
+	}
+	return nil
+}
+
+// StringParams returns a map of the string values of the given params,
+// keyed by their names.
+func StringParams(params []pipelinev1beta1.Param) map[string]string {
+	paramsMap := make(map[string]string, len(params))
+	for _, p := range params {
+		paramsMap[p.Name] = p.Value.StringVal
+	}
+	return paramsMap
+}
+
+// digestRegex matches a digest of the form sha256:<hex>.
+var digestRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// ValidateDigest returns an error if the value of the given digest
+// param isn't of the form sha256:<hex>.
+func ValidateDigest(param, digest string) error {
+	if !digestRegex.MatchString(digest) {
+		return fmt.Errorf("invalid %s param %q: must be of the form sha256:<hex>", param, digest)
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/test/diff"
+)
+
+// defaultingResolver is a FakeResolver with default params.
+type defaultingResolver struct {
+	FakeResolver
+	defaults map[string]string
+}
+
+var _ ParamDefaulter = &defaultingResolver{}
+
+func (r *defaultingResolver) DefaultParams(context.Context) map[string]string {
+	return r.defaults
+}
+
+func TestApplyDefaultParams(t *testing.T) {
+	defaults := map[string]string{"catalog": "tekton", "type": "tekton"}
+	for _, tc := range []struct {
+		name     string
+		resolver Resolver
+		params   []pipelinev1beta1.Param
+		expected []pipelinev1beta1.Param
+	}{{
+		name:     "absent params are defaulted",
+		resolver: &defaultingResolver{defaults: defaults},
+		params:   []pipelinev1beta1.Param{stringParam("name", "foo")},
+		expected: []pipelinev1beta1.Param{
+			stringParam("name", "foo"),
+			stringParam("catalog", "tekton"),
+			stringParam("type", "tekton"),
+		},
+	}, {
+		name:     "present params win over defaults",
+		resolver: &defaultingResolver{defaults: defaults},
+		params:   []pipelinev1beta1.Param{stringParam("type", "artifact"), stringParam("catalog", "foo")},
+		expected: []pipelinev1beta1.Param{stringParam("type", "artifact"), stringParam("catalog", "foo")},
+	}, {
+		name:     "present but empty params aren't defaulted",
+		resolver: &defaultingResolver{defaults: defaults},
+		params:   []pipelinev1beta1.Param{stringParam("catalog", "")},
+		expected: []pipelinev1beta1.Param{stringParam("catalog", ""), stringParam("type", "tekton")},
+	}, {
+		name:     "no defaults",
+		resolver: &defaultingResolver{},
+		params:   []pipelinev1beta1.Param{stringParam("name", "foo")},
+		expected: []pipelinev1beta1.Param{stringParam("name", "foo")},
+	}, {
+		name:     "resolver without defaulter",
+		resolver: &FakeResolver{},
+		params:   []pipelinev1beta1.Param{stringParam("name", "foo")},
+		expected: []pipelinev1beta1.Param{stringParam("name", "foo")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			original := append([]pipelinev1beta1.Param{}, tc.params...)
+			params := ApplyDefaultParams(context.Background(), tc.resolver, tc.params)
+			if d := cmp.Diff(tc.expected, params); d != "" {
+				t.Errorf("unexpected params: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(original, tc.params); d != "" {
+				t.Errorf("given params were modified: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestDryRunDefaultParams(t *testing.T) {
+	resolver := &defaultingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"default": {Content: "default content"},
+			"given":   {Content: "given content"},
+		}},
+		defaults: map[string]string{FakeParamName: "default"},
+	}
+
+	resource, err := DryRun(context.Background(), resolver, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resource.Data()) != "default content" {
+		t.Errorf("expected default content but got %q", string(resource.Data()))
+	}
+
+	resource, err = DryRun(context.Background(), resolver, fakeParams("given"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resource.Data()) != "given content" {
+		t.Errorf("expected given content but got %q", string(resource.Data()))
+	}
+}
+
+// aliasingResolver is a FakeResolver with param aliases.
+type aliasingResolver struct {
+	FakeResolver
+	aliases map[string]string
+}
+
+var _ ParamAliaser = &aliasingResolver{}
+
+func (r *aliasingResolver) ParamAliases(context.Context) map[string]string {
+	return r.aliases
+}
+
+func TestNormalizeParamAliases(t *testing.T) {
+	aliases := map[string]string{"bundle": "image", "old-bundle": "image", "name": "name"}
+	for _, tc := range []struct {
+		name             string
+		resolver         Resolver
+		params           []pipelinev1beta1.Param
+		expected         []pipelinev1beta1.Param
+		expectedWarnings []string
+		expectedErr      string
+	}{{
+		name:             "alias is replaced with canonical name",
+		resolver:         &aliasingResolver{aliases: aliases},
+		params:           []pipelinev1beta1.Param{stringParam("bundle", "gcr.io/foo"), stringParam("name", "foo")},
+		expected:         []pipelinev1beta1.Param{stringParam("image", "gcr.io/foo"), stringParam("name", "foo")},
+		expectedWarnings: []string{`param "bundle" is deprecated, use "image" instead`},
+	}, {
+		name:     "canonical name is kept",
+		resolver: &aliasingResolver{aliases: aliases},
+		params:   []pipelinev1beta1.Param{stringParam("image", "gcr.io/foo")},
+		expected: []pipelinev1beta1.Param{stringParam("image", "gcr.io/foo")},
+	}, {
+		name:             "alias and canonical name conflict",
+		resolver:         &aliasingResolver{aliases: aliases},
+		params:           []pipelinev1beta1.Param{stringParam("image", "gcr.io/foo"), stringParam("bundle", "gcr.io/bar")},
+		expectedWarnings: []string{`param "bundle" is deprecated, use "image" instead`},
+		expectedErr:      `params "image" and "bundle" can't both be set, they're both "image"`,
+	}, {
+		name:     "two aliases conflict",
+		resolver: &aliasingResolver{aliases: aliases},
+		params:   []pipelinev1beta1.Param{stringParam("bundle", "gcr.io/foo"), stringParam("old-bundle", "gcr.io/bar")},
+		expectedWarnings: []string{
+			`param "bundle" is deprecated, use "image" instead`,
+			`param "old-bundle" is deprecated, use "image" instead`,
+		},
+		expectedErr: `params "bundle" and "old-bundle" can't both be set, they're both "image"`,
+	}, {
+		name:     "resolver without aliaser",
+		resolver: &FakeResolver{},
+		params:   []pipelinev1beta1.Param{stringParam("bundle", "gcr.io/foo")},
+		expected: []pipelinev1beta1.Param{stringParam("bundle", "gcr.io/foo")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			original := append([]pipelinev1beta1.Param{}, tc.params...)
+			params, warnings, err := NormalizeParamAliases(context.Background(), tc.resolver, tc.params)
+			if d := cmp.Diff(tc.expectedWarnings, warnings); d != "" {
+				t.Errorf("unexpected warnings: %s", diff.PrintWantGot(d))
+			}
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err %q but didn't get one", tc.expectedErr)
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(tc.expected, params); d != "" {
+				t.Errorf("unexpected params: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(original, tc.params); d != "" {
+				t.Errorf("given params were modified: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestDryRunParamAliases(t *testing.T) {
+	resolver := &aliasingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"bar": {Content: "some content"},
+		}},
+		aliases: map[string]string{"old-key": FakeParamName},
+	}
+
+	resource, err := DryRun(context.Background(), resolver, []pipelinev1beta1.Param{stringParam("old-key", "bar")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resource.Data()) != "some content" {
+		t.Errorf("expected some content but got %q", string(resource.Data()))
+	}
+
+	_, err = DryRun(context.Background(), resolver, append(fakeParams("bar"), stringParam("old-key", "bar")))
+	expectedErr := `invalid params for Fake resolver: params "fake-key" and "old-key" can't both be set, they're both "fake-key"`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q but got %v", expectedErr, err)
+	}
+}
+
+func TestValidateNoVariableReferences(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		params      []pipelinev1beta1.Param
+		expectedErr string
+	}{{
+		name:   "no references",
+		params: []pipelinev1beta1.Param{stringParam("name", "git-clone"), stringParam("bundle", "gcr.io/foo/bar:v1")},
+	}, {
+		name: "legitimate dollar signs",
+		params: []pipelinev1beta1.Param{
+			stringParam("password", "pa$$word"),
+			stringParam("path", "$HOME/$(date)/${params.foo}"),
+			stringParam("price", "$(5)"),
+			stringParam("unclosed", "$(params.foo"),
+		},
+	}, {
+		name:        "param reference",
+		params:      []pipelinev1beta1.Param{stringParam("version", "0.6"), stringParam("name", "$(params.task-name)")},
+		expectedErr: `param "name" still contains an unresolved variable reference "$(params.task-name)"`,
+	}, {
+		name:        "bracketed param reference within a value",
+		params:      []pipelinev1beta1.Param{stringParam("bundle", `gcr.io/foo/bar:$(params["tag"])`)},
+		expectedErr: `param "bundle" still contains an unresolved variable reference "$(params[\"tag\"])"`,
+	}, {
+		name:        "result reference",
+		params:      []pipelinev1beta1.Param{stringParam("revision", "$(tasks.fetch.results.sha)")},
+		expectedErr: `param "revision" still contains an unresolved variable reference "$(tasks.fetch.results.sha)"`,
+	}, {
+		name: "array value",
+		params: []pipelinev1beta1.Param{{
+			Name:  "paths",
+			Value: *pipelinev1beta1.NewStructuredValues("a.yaml", "$(context.pipelineRun.name).yaml"),
+		}},
+		expectedErr: `param "paths" still contains an unresolved variable reference "$(context.pipelineRun.name)"`,
+	}, {
+		name: "object value",
+		params: []pipelinev1beta1.Param{{
+			Name:  "headers",
+			Value: *pipelinev1beta1.NewObject(map[string]string{"a": "b", "c": "$(params.token)"}),
+		}},
+		expectedErr: `param "headers" still contains an unresolved variable reference "$(params.token)"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateNoVariableReferences(tc.params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Errorf("expected error %q but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func stringParam(name, value string) pipelinev1beta1.Param {
+	return pipelinev1beta1.Param{Name: name, Value: *pipelinev1beta1.NewStructuredValues(value)}
+}
+
+func TestStringParams(t *testing.T) {
+	params := []pipelinev1beta1.Param{{
+		Name:  "url",
+		Value: *pipelinev1beta1.NewStructuredValues("https://example.com"),
+	}, {
+		Name:  "empty",
+		Value: *pipelinev1beta1.NewStructuredValues(""),
+	}}
+	expected := map[string]string{
+		"url":   "https://example.com",
+		"empty": "",
+	}
+	if d := cmp.Diff(expected, StringParams(params)); d != "" {
+		t.Errorf("unexpected params: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestValidateDigest(t *testing.T) {
+	valid := "sha256:" + strings.Repeat("a1", 32)
+	if err := ValidateDigest("digest", valid); err != nil {
+		t.Errorf("unexpected error validating %q: %v", valid, err)
+	}
+	for _, digest := range []string{
+		"",
+		"a1a1",
+		"sha256:" + strings.Repeat("A1", 32),
+		"sha512:" + strings.Repeat("a1", 32),
+		valid + "a1",
+	} {
+		err := ValidateDigest("digest", digest)
+		if err == nil {
+			t.Errorf("expected an error validating %q", digest)
+			continue
+		}
+		expected := fmt.Sprintf("invalid digest param %q: must be of the form sha256:<hex>", digest)
+		if d := cmp.Diff(expected, err.Error()); d != "" {
+			t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+		}
+	}
+}
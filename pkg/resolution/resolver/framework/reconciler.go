@@ -24,17 +24,22 @@ import (
 	"fmt"
 	"time"
 
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
 	rrclient "github.com/tektoncd/pipeline/pkg/client/resolution/clientset/versioned"
 	rrv1beta1 "github.com/tektoncd/pipeline/pkg/client/resolution/listers/resolution/v1beta1"
 	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
+	"knative.dev/pkg/logging/logkey"
 	"knative.dev/pkg/reconciler"
 )
 
@@ -50,11 +55,50 @@ type Reconciler struct {
 	Clock clock.PassiveClock
 
 	resolver                   Resolver
+	recorder                   record.EventRecorder
 	kubeClientSet              kubernetes.Interface
 	resolutionRequestLister    rrv1beta1.ResolutionRequestLister
 	resolutionRequestClientSet rrclient.Interface
 
 	configStore *ConfigStore
+
+	// credentialProvider, if set, is injected into the context of each
+	// resolution for the resolver to consult for auth material.
+	credentialProvider CredentialProvider
+
+	// verifier, if set, checks each resolved resource before it is
+	// written to the request.
+	verifier Verifier
+
+	// logger, if set, is used instead of the logger in the context of
+	// each reconciliation.
+	logger *zap.SugaredLogger
+
+	// gatedType is the resolver type whose enable-<type>-resolver
+	// feature flag is checked before a request is validated. It is only
+	// set for resolvers started from a Registry, since the built-in
+	// resolvers check their own feature flags.
+	gatedType string
+
+	// inflight coalesces identical resolutions that run at the same
+	// time.
+	inflight coalescer
+
+	// resolutions tracks the resolutions in flight so that they're
+	// cancelled when their ResolutionRequest is deleted.
+	resolutions resolutionTracker
+
+	// concurrency limits the number of calls to the resolver's Resolve
+	// method in flight at once.
+	concurrency concurrencyLimiter
+
+	// breaker fails resolutions from backends that keep failing
+	// straight away.
+	breaker circuitBreaker
+
+	// healthChecks records the outcome of the health checks of
+	// resolvers implementing HealthChecker.
+	healthChecks *HealthChecks
 }
 
 var _ reconciler.LeaderAware = &Reconciler{}
@@ -67,6 +111,12 @@ var _ reconciler.LeaderAware = &Reconciler{}
 // the framework.TimedResolution interface.
 const defaultMaximumResolutionDuration = time.Minute
 
+// maximumRateLimitedDuration is how long after a ResolutionRequest is
+// created it may still be requeued because its resolution was rate
+// limited or failed transiently. Once a retry would happen later than
+// that the request fails.
+const maximumRateLimitedDuration = 10 * time.Minute
+
 // Reconcile receives the string key of a ResolutionRequest object, looks
 // it up, checks it for common errors, and then delegates
 // resolver-specific functionality to the reconciler's embedded
@@ -92,40 +142,121 @@ func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
 	// Inject request-scoped information into the context, such as
 	// the namespace that the request originates from and the
 	// configuration from the configmap this resolver is watching.
+	// Everything logged from here on, by the resolver too, is redacted.
+	if r.logger != nil {
+		ctx = logging.WithLogger(ctx, r.logger.With(zap.String(logkey.Key, key)))
+	}
+	ctx = WithRedaction(ctx)
 	ctx = resolutioncommon.InjectRequestNamespace(ctx, namespace)
 	if r.configStore != nil {
 		ctx = r.configStore.ToContext(ctx)
 	}
+	if r.credentialProvider != nil {
+		ctx = InjectCredentialProviderToContext(ctx, r.credentialProvider)
+	}
+	if r.verifier != nil {
+		ctx = InjectVerifierToContext(ctx, r.verifier)
+	}
 
-	return r.resolve(ctx, key, rr)
+	ctx, span := StartSpan(ctx, SpanReconcile,
+		trace.StringAttribute(AttributeResolverType, r.resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType]),
+		trace.StringAttribute(AttributeRequestNamespace, namespace),
+		trace.StringAttribute(AttributeRequestName, name))
+	err = r.resolve(ctx, key, rr)
+	EndSpan(span, err)
+	return err
 }
 
 func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1beta1.ResolutionRequest) error {
-	errChan := make(chan error)
-	resourceChan := make(chan ResolvedResource)
-
-	timeoutDuration := defaultMaximumResolutionDuration
-	if timed, ok := r.resolver.(TimedResolution); ok {
-		timeoutDuration = timed.GetResolutionTimeout(ctx, defaultMaximumResolutionDuration)
-	}
+	// The channels are buffered so that the goroutine below can exit
+	// even if the resolution was abandoned because its deadline passed.
+	errChan := make(chan error, 1)
+	resourceChan := make(chan ResolvedResource, 1)
 
 	// A new context is created for resolution so that timeouts can
 	// be enforced without affecting other uses of ctx (e.g. sending
 	// Updates to ResolutionRequest objects).
-	resolutionCtx, cancelFn := context.WithTimeout(ctx, timeoutDuration)
+	timeout := resolutionTimeout(ctx, r.resolver)
+	resolutionCtx, cancelFn := context.WithTimeout(ctx, timeout)
 	defer cancelFn()
+	defer r.resolutions.track(key, rr.UID, cancelFn)()
+
+	start := r.now()
+	resolverType := r.resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType]
+	// result is only written by the goroutine below before it sends an
+	// error, so it's safe to read once the error has been received.
+	var result string
 
 	go func() {
-		validationError := r.resolver.ValidateParams(resolutionCtx, rr.Spec.Params)
+		var params []pipelinev1beta1.Param
+		var fp frameworkParams
+		validationError := checkEnabled(resolutionCtx, r.gatedType)
+		if validationError == nil {
+			validationError = checkParamLimits(resolutionCtx, rr.Spec.Params)
+		}
+		if validationError == nil {
+			var warnings []string
+			params, warnings, validationError = NormalizeParamAliases(resolutionCtx, r.resolver, rr.Spec.Params)
+			r.emitDeprecationWarnings(ctx, rr, warnings)
+		}
+		if validationError == nil {
+			params, fp, validationError = extractFrameworkParams(params)
+		}
+		if validationError == nil {
+			params = ApplyDefaultParams(resolutionCtx, r.resolver, params)
+			validationError = r.resolver.ValidateParams(resolutionCtx, params)
+		}
+		if validationError == nil {
+			validationError = preflight(resolutionCtx, r.resolver, params)
+		}
+		// A resolver that gives up because the resolution was aborted
+		// is reported consistently rather than with whatever error its
+		// client happened to return.
+		if validationError != nil && resolutionCtx.Err() != nil {
+			err := resolutionContextError(resolutionCtx, resolverType, timeout)
+			result = resultFromError(err)
+			errChan <- err
+			return
+		}
 		if validationError != nil {
+			result = ResultInvalid
+			if resultFromError(validationError) == ResultDisabled {
+				result = ResultDisabled
+			}
 			errChan <- &resolutioncommon.ErrorInvalidRequest{
 				ResolutionRequestKey: key,
 				Message:              validationError.Error(),
 			}
 			return
 		}
-		resource, resolveErr := r.resolver.Resolve(resolutionCtx, rr.Spec.Params)
+		resource, resolveErr := r.resolveCoalesced(resolutionCtx, resolverType, timeout, params)
+		if resolveErr == nil {
+			// A signature covers the content as the resolver returned
+			// it, so it is verified before being decoded.
+			resolveErr = verifyResolved(resolutionCtx, resolverType, resource)
+		}
+		if resolveErr == nil {
+			resource, resolveErr = applyDecodeMode(resolutionCtx, resource, fp.decode)
+		}
+		if resolveErr != nil && fp.fallback != nil && isBackendUnavailable(resolveErr) {
+			logging.FromContext(ctx).Warnf("Resolving %s/%s to its fallback content, the %s resolver's backend is unavailable: %v", rr.Namespace, rr.Name, resolverType, resolveErr)
+			resource = newFallbackResource(fp.fallback, resolveErr)
+			resolveErr = verifyResolved(resolutionCtx, resolverType, resource)
+		}
+		if resolveErr == nil {
+			resource, resolveErr = applyDocumentsMode(resource, fp.documents)
+		}
+		if resolveErr == nil {
+			resolveErr = checkResolvedSize(resolutionCtx, resource)
+		}
+		if resolveErr != nil && resolutionCtx.Err() != nil {
+			err := resolutionContextError(resolutionCtx, resolverType, timeout)
+			result = resultFromError(err)
+			errChan <- err
+			return
+		}
 		if resolveErr != nil {
+			result = resultFromError(resolveErr)
 			errChan <- &resolutioncommon.ErrorGettingResource{
 				ResolverName: r.resolver.GetName(resolutionCtx),
 				Key:          key,
@@ -138,20 +269,115 @@ func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1beta1.Resolu
 
 	select {
 	case err := <-errChan:
+		if r.resolutions.deleted(key, rr.UID) {
+			return r.onDeleted(ctx, rr, resolverType, start)
+		}
+		recordResolution(ctx, resolverType, result, r.now().Sub(start))
 		if err != nil {
+			logging.FromContext(ctx).Debugf("Resolution of %s/%s by the %s resolver failed with result %s: %v", rr.Namespace, rr.Name, resolverType, result, err)
+			if requeueErr := r.requeueRetryable(ctx, rr, err); requeueErr != nil {
+				return requeueErr
+			}
+			r.emitResolutionEvent(ctx, rr, resolverType, result, err)
 			return r.OnError(ctx, rr, err)
 		}
 	case <-resolutionCtx.Done():
-		if err := resolutionCtx.Err(); err != nil {
-			return r.OnError(ctx, rr, err)
+		if r.resolutions.deleted(key, rr.UID) {
+			return r.onDeleted(ctx, rr, resolverType, start)
 		}
+		err := resolutionContextError(resolutionCtx, resolverType, timeout)
+		// The goroutine may still write result, so it can't be used here.
+		abortedResult := resultFromError(err)
+		recordResolution(ctx, resolverType, abortedResult, r.now().Sub(start))
+		r.emitResolutionEvent(ctx, rr, resolverType, abortedResult, err)
+		return r.OnError(ctx, rr, err)
 	case resource := <-resourceChan:
-		return r.writeResolvedData(ctx, rr, resource)
+		result := ResultSuccess
+		if isFallback(resource) {
+			result = ResultFallback
+		}
+		recordResolution(ctx, resolverType, result, r.now().Sub(start))
+		logging.FromContext(ctx).Debugf("Resolution of %s/%s by the %s resolver finished with result %s", rr.Namespace, rr.Name, resolverType, result)
+		if err := r.writeResolvedData(ctx, rr, resolverType, resource); err != nil {
+			return err
+		}
+		r.emitResolutionEvent(ctx, rr, resolverType, result, nil)
+		return nil
 	}
 
 	return errors.New("unknown error")
 }
 
+// onDeleted records a resolution that was cancelled because its
+// ResolutionRequest was deleted. There's no request left to update with
+// the outcome, so it isn't treated as an error.
+func (r *Reconciler) onDeleted(ctx context.Context, rr *v1beta1.ResolutionRequest, resolverType string, start time.Time) error {
+	recordResolution(ctx, resolverType, ResultCancelled, r.now().Sub(start))
+	logging.FromContext(ctx).Infof("Cancelled the %s resolution of %s/%s, the ResolutionRequest was deleted", resolverType, rr.Namespace, rr.Name)
+	return nil
+}
+
+// requeueRetryable returns the error that requeues a request whose
+// resolution was rate limited or failed with a transient error, or nil
+// if the resolution failed for another reason or retrying it would take
+// the request past maximumRateLimitedDuration. A rate limited request
+// is requeued after the delay suggested by the resolver, and otherwise
+// with the controller's exponential backoff.
+func (r *Reconciler) requeueRetryable(ctx context.Context, rr *v1beta1.ResolutionRequest, err error) error {
+	var retryAfter time.Duration
+	var rateLimited *resolutioncommon.RateLimitedError
+	var transient *resolutioncommon.TransientError
+	switch {
+	case errors.As(err, &rateLimited):
+		retryAfter = rateLimited.RetryAfter
+	case errors.As(err, &transient):
+	default:
+		return nil
+	}
+	if r.now().Add(retryAfter).After(rr.CreationTimestamp.Add(maximumRateLimitedDuration)) {
+		return nil
+	}
+	logging.FromContext(ctx).Infof("Resolution of %s/%s failed transiently, retrying: %v", rr.Namespace, rr.Name, err)
+	if retryAfter > 0 {
+		return controller.NewRequeueAfter(retryAfter)
+	}
+	return err
+}
+
+// resolutionTimeout returns the maximum duration of a single
+// resolution by the given resolver.
+func resolutionTimeout(ctx context.Context, resolver Resolver) time.Duration {
+	if timed, ok := resolver.(TimedResolution); ok {
+		return timed.GetResolutionTimeout(ctx, defaultMaximumResolutionDuration)
+	}
+	return defaultMaximumResolutionDuration
+}
+
+// resolutionContextError returns the error for a resolution by the
+// given type of resolver that was aborted because its context is done.
+// A passed deadline is reported as a
+// resolutioncommon.ResolutionTimeoutError carrying the resolver type so
+// that it reads the same whichever resolver was running.
+func resolutionContextError(ctx context.Context, resolverType string, timeout time.Duration) error {
+	err := ctx.Err()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s resolution cancelled: %w", resolverType, err)
+	}
+	return &resolutioncommon.ResolutionTimeoutError{
+		ResolverType: resolverType,
+		Timeout:      timeout,
+		Original:     fmt.Errorf("%s resolution deadline exceeded after %s: %w", resolverType, timeout, err),
+	}
+}
+
+// now returns the current time from the reconciler's clock.
+func (r *Reconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
 // OnError is used to handle any situation where a ResolutionRequest has
 // reached a terminal situation that cannot be recovered from.
 func (r *Reconciler) OnError(ctx context.Context, rr *v1beta1.ResolutionRequest, err error) error {
@@ -197,12 +423,27 @@ type statusDataPatch struct {
 	Source      *v1beta1.ConfigSource `json:"source"`
 }
 
-func (r *Reconciler) writeResolvedData(ctx context.Context, rr *v1beta1.ResolutionRequest, resource ResolvedResource) error {
+// withProvenance returns a copy of the annotations of a resolved
+// resource that always includes the provenance annotation. Resolvers
+// that don't record their own provenance get one naming only their
+// resolver type.
+func withProvenance(annotations map[string]string, resolverType string) map[string]string {
+	out := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		out[k] = v
+	}
+	if _, ok := out[resolutioncommon.AnnotationKeyProvenance]; !ok {
+		out[resolutioncommon.AnnotationKeyProvenance] = resolutioncommon.Provenance{ResolverType: resolverType}.AnnotationValue()
+	}
+	return out
+}
+
+func (r *Reconciler) writeResolvedData(ctx context.Context, rr *v1beta1.ResolutionRequest, resolverType string, resource ResolvedResource) error {
 	encodedData := base64.StdEncoding.Strict().EncodeToString(resource.Data())
 	patchBytes, err := json.Marshal(map[string]statusDataPatch{
 		"status": {
 			Data:        encodedData,
-			Annotations: resource.Annotations(),
+			Annotations: withProvenance(resource.Annotations(), resolverType),
 			Source:      resource.Source(),
 		},
 	})
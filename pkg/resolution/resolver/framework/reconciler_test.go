@@ -35,6 +35,7 @@ import (
 	"github.com/tektoncd/pipeline/test/names"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	clock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/apis"
@@ -126,7 +127,8 @@ func TestReconcile(t *testing.T) {
 			expectedStatus: &v1beta1.ResolutionRequestStatus{
 				Status: duckv1.Status{
 					Annotations: map[string]string{
-						"foo": "bar",
+						"foo":                                    "bar",
+						resolutioncommon.AnnotationKeyProvenance: `{"resolverType":"fake"}`,
 					},
 				},
 				ResolutionRequestStatusFields: v1beta1.ResolutionRequestStatusFields{
@@ -198,7 +200,7 @@ func TestReconcile(t *testing.T) {
 				},
 			},
 			reconcilerTimeout: 1 * time.Second,
-			expectedErr:       errors.New("context deadline exceeded"),
+			expectedErr:       errors.New("fake resolution deadline exceeded after 1s: context deadline exceeded"),
 		},
 	}
 
@@ -246,6 +248,412 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
+func TestReconcileCancelledMidResolution(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rr",
+			Namespace: "foo",
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: []pipelinev1beta1.Param{{
+				Name:  FakeParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("slow"),
+			}},
+		},
+	}
+	fakeResolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"slow": {WaitFor: time.Minute},
+	}}
+
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, fakeResolver, setClockOnReconciler)
+	defer cancel()
+
+	reconcileCtx, cancelReconcile := context.WithCancel(testAssets.Ctx)
+	time.AfterFunc(50*time.Millisecond, cancelReconcile)
+
+	start := time.Now()
+	err := testAssets.Controller.Reconciler.Reconcile(reconcileCtx, getRequestName(rr))
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected reconcile to return once cancelled but it took %s", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected error to wrap context.Canceled but got %v", err)
+	}
+	if expected := "fake resolution cancelled: context canceled"; err.Error() != expected {
+		t.Errorf("expected error %q but got %q", expected, err.Error())
+	}
+}
+
+func TestReconcileDeletedMidResolution(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rr",
+			Namespace: "foo",
+			UID:       "rr-uid",
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: []pipelinev1beta1.Param{{
+				Name:  FakeParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("slow"),
+			}},
+		},
+	}
+	resolver := &abortableResolver{aborted: make(chan error, 1)}
+
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+	defer cancel()
+	r, ok := testAssets.Controller.Reconciler.(*Reconciler)
+	if !ok {
+		t.Fatalf("expected a *Reconciler but got %T", testAssets.Controller.Reconciler)
+	}
+
+	// Deleting a different request, or an earlier request with the same
+	// name, doesn't cancel the resolution.
+	other := rr.DeepCopy()
+	other.Name = "other"
+	earlier := rr.DeepCopy()
+	earlier.UID = "earlier-uid"
+	time.AfterFunc(50*time.Millisecond, func() {
+		r.resolutions.cancelDeleted(other)
+		r.resolutions.cancelDeleted(earlier)
+		r.resolutions.cancelDeleted(cache.DeletedFinalStateUnknown{Key: getRequestName(rr), Obj: rr})
+	})
+
+	start := time.Now()
+	err := r.Reconcile(testAssets.Ctx, getRequestName(rr))
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected reconcile to return once the request was deleted but it took %s", elapsed)
+	}
+	if err != nil {
+		t.Fatalf("expected no error for a deleted request but got %v", err)
+	}
+	select {
+	case err := <-resolver.aborted:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the resolution to be cancelled but got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the resolver's context to be cancelled")
+	}
+	updated, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting updated ResolutionRequest: %v", err)
+	}
+	if len(updated.Status.Conditions) != 0 {
+		t.Errorf("expected the deleted request's status not to be updated but got %v", updated.Status.Conditions)
+	}
+}
+
+// rateLimitedResolver is a FakeResolver whose resolutions are always
+// rate limited.
+type rateLimitedResolver struct {
+	FakeResolver
+	retryAfter time.Duration
+}
+
+func (r *rateLimitedResolver) Resolve(context.Context, []pipelinev1beta1.Param) (ResolvedResource, error) {
+	return nil, &resolutioncommon.RateLimitedError{
+		Resource:   "bar",
+		RetryAfter: r.retryAfter,
+		Original:   errors.New("too many requests for bar"),
+	}
+}
+
+func TestReconcileRateLimited(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		created              time.Time
+		retryAfter           time.Duration
+		expectedRequeueAfter time.Duration
+		expectedFailed       bool
+	}{{
+		name:                 "requeued after suggested delay",
+		created:              now,
+		retryAfter:           30 * time.Second,
+		expectedRequeueAfter: 30 * time.Second,
+	}, {
+		name:    "requeued with backoff without suggested delay",
+		created: now,
+	}, {
+		name:           "failed once retrying takes too long",
+		created:        now.Add(-maximumRateLimitedDuration + time.Second),
+		retryAfter:     30 * time.Second,
+		expectedFailed: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: tc.created},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: []pipelinev1beta1.Param{{
+						Name:  FakeParamName,
+						Value: *pipelinev1beta1.NewStructuredValues("bar"),
+					}},
+				},
+			}
+			resolver := &rateLimitedResolver{retryAfter: tc.retryAfter}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+			defer cancel()
+
+			err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+			if err == nil {
+				t.Fatalf("expected an error but got nothing")
+			}
+			if controller.IsPermanentError(err) != tc.expectedFailed {
+				t.Errorf("expected permanent error to be %t but got %v", tc.expectedFailed, err)
+			}
+			requeue, requeueAfter := controller.IsRequeueKey(err)
+			if requeue != (tc.expectedRequeueAfter > 0) || requeueAfter != tc.expectedRequeueAfter {
+				t.Errorf("expected requeue after %s but got %v", tc.expectedRequeueAfter, err)
+			}
+
+			reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			if failed := reconciledRR.Status.GetCondition(apis.ConditionSucceeded).IsFalse(); failed != tc.expectedFailed {
+				t.Errorf("expected request failed to be %t but got status %v", tc.expectedFailed, reconciledRR.Status)
+			}
+		})
+	}
+}
+
+// transientResolver is a FakeResolver whose resolutions always fail
+// with a transient error.
+type transientResolver struct {
+	FakeResolver
+}
+
+func (r *transientResolver) Resolve(context.Context, []pipelinev1beta1.Param) (ResolvedResource, error) {
+	return nil, &resolutioncommon.TransientError{
+		Resource: "bar",
+		Original: errors.New("server error fetching bar"),
+	}
+}
+
+func TestReconcileTransientError(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		created        time.Time
+		expectedFailed bool
+	}{{
+		name:    "requeued with backoff",
+		created: now,
+	}, {
+		name:           "failed once retrying takes too long",
+		created:        now.Add(-maximumRateLimitedDuration - time.Second),
+		expectedFailed: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: tc.created},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: []pipelinev1beta1.Param{{
+						Name:  FakeParamName,
+						Value: *pipelinev1beta1.NewStructuredValues("bar"),
+					}},
+				},
+			}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, &transientResolver{}, setClockOnReconciler)
+			defer cancel()
+
+			err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+			if err == nil {
+				t.Fatalf("expected an error but got nothing")
+			}
+			if controller.IsPermanentError(err) != tc.expectedFailed {
+				t.Errorf("expected permanent error to be %t but got %v", tc.expectedFailed, err)
+			}
+
+			reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			if failed := reconciledRR.Status.GetCondition(apis.ConditionSucceeded).IsFalse(); failed != tc.expectedFailed {
+				t.Errorf("expected request failed to be %t but got status %v", tc.expectedFailed, reconciledRR.Status)
+			}
+		})
+	}
+}
+
+// TestReconcileParamAliases checks that a request using a deprecated
+// param name is resolved with the canonical name and that a warning
+// event is emitted for it.
+func TestReconcileParamAliases(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: now},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: []pipelinev1beta1.Param{{
+				Name:  "old-key",
+				Value: *pipelinev1beta1.NewStructuredValues("bar"),
+			}},
+		},
+	}
+	resolver := &aliasingResolver{
+		FakeResolver: FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"bar": {Content: "some content"},
+		}},
+		aliases: map[string]string{"old-key": FakeParamName},
+	}
+
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+	defer cancel()
+
+	if err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr)); err != nil {
+		t.Fatalf("unexpected error reconciling: %v", err)
+	}
+	reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting updated ResolutionRequest: %v", err)
+	}
+	if d := cmp.Diff(base64.StdEncoding.Strict().EncodeToString([]byte("some content")), reconciledRR.Status.Data); d != "" {
+		t.Errorf("unexpected resolved data: %s", diff.PrintWantGot(d))
+	}
+
+	expectedEvent := `Warning DeprecatedParam Resolution request foo/rr: param "old-key" is deprecated, use "fake-key" instead`
+	select {
+	case event := <-testAssets.Recorder.Events:
+		if event != expectedEvent {
+			t.Errorf("expected event %q but got %q", expectedEvent, event)
+		}
+	default:
+		t.Errorf("expected event %q but got none", expectedEvent)
+	}
+}
+
+func TestReconcilePreflight(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: now},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: fakeParams("bar"),
+		},
+	}
+	resolver := &preflightResolver{
+		FakeResolver: &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+			"bar": {Content: "some content"},
+		}},
+		err: errors.New("catalog not found"),
+	}
+
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+	defer cancel()
+
+	err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+	var invalidErr *resolutioncommon.ErrorInvalidRequest
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected an invalid request error but got %v", err)
+	}
+	if !strings.Contains(err.Error(), "catalog not found") {
+		t.Errorf("expected the preflight error to be reported but got %q", err.Error())
+	}
+	if !resolver.called {
+		t.Errorf("expected Preflight to be called")
+	}
+	reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting updated ResolutionRequest: %v", err)
+	}
+	if !reconciledRR.Status.GetCondition(apis.ConditionSucceeded).IsFalse() {
+		t.Errorf("expected the request to fail but got status %v", reconciledRR.Status)
+	}
+}
+
+func TestResolutionContextError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	err := resolutionContextError(ctx, "hub", time.Millisecond)
+	var timeoutErr *resolutioncommon.ResolutionTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a ResolutionTimeoutError but got %v", err)
+	}
+	if timeoutErr.ResolverType != "hub" || timeoutErr.Timeout != time.Millisecond {
+		t.Errorf("expected resolver type hub and timeout 1ms but got %q and %s", timeoutErr.ResolverType, timeoutErr.Timeout)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded but got %v", err)
+	}
+	if expected := "hub resolution deadline exceeded after 1ms: context deadline exceeded"; err.Error() != expected {
+		t.Errorf("expected error %q but got %q", expected, err.Error())
+	}
+}
+
+// TestWithProvenance checks that resolved resources always carry a
+// provenance annotation without overwriting one set by the resolver.
+func TestWithProvenance(t *testing.T) {
+	own := resolutioncommon.Provenance{ResolverType: "fake", URI: "https://abc.com"}.AnnotationValue()
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		expected    map[string]string
+	}{{
+		name:        "no annotations",
+		annotations: nil,
+		expected: map[string]string{
+			resolutioncommon.AnnotationKeyProvenance: `{"resolverType":"fake"}`,
+		},
+	}, {
+		name: "resolver provenance",
+		annotations: map[string]string{
+			"foo":                                    "bar",
+			resolutioncommon.AnnotationKeyProvenance: own,
+		},
+		expected: map[string]string{
+			"foo":                                    "bar",
+			resolutioncommon.AnnotationKeyProvenance: own,
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := withProvenance(tc.annotations, LabelValueFakeResolverType)
+			if d := cmp.Diff(tc.expected, got); d != "" {
+				t.Errorf("unexpected annotations %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
 func getResolverFrameworkController(ctx context.Context, t *testing.T, d test.Data, resolver Resolver, modifiers ...ReconcilerModifier) (test.Assets, func()) {
 	t.Helper()
 	names.TestingSeed()
@@ -0,0 +1,191 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"knative.dev/pkg/injection"
+)
+
+// DefaultRegistry is the Registry that Register adds resolvers to.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a resolver to the DefaultRegistry under the given
+// resolver type. See Registry.Register.
+func Register(resolverType string, resolver Resolver) error {
+	return DefaultRegistry.Register(resolverType, resolver)
+}
+
+// Registry holds resolvers keyed by the value of the
+// resolution.tekton.dev/type label of the requests they handle. It lets
+// resolvers that live outside of this repository be run by the same
+// controllers, with the same selector matching and feature flag gating,
+// as the built-in ones.
+type Registry struct {
+	mu        sync.Mutex
+	resolvers map[string]Resolver
+	// types holds the registered resolver types in the order they were
+	// registered so that controllers are always started in that order.
+	types []string
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{resolvers: map[string]Resolver{}}
+}
+
+// Register adds a resolver for requests with the given type label. The
+// resolver's selector must select that type. Requests are only handed to
+// the resolver while the enable-<type>-resolver feature flag is true.
+// Registering a second resolver for the same type is an error so that
+// conflicting resolvers are caught when the resolvers start.
+func (reg *Registry) Register(resolverType string, resolver Resolver) error {
+	if resolverType == "" {
+		return fmt.Errorf("cannot register resolver with empty type")
+	}
+	if resolver == nil {
+		return fmt.Errorf("cannot register nil resolver for type %q", resolverType)
+	}
+	if selected := resolver.GetSelector(context.Background())[resolutioncommon.LabelKeyResolverType]; selected != resolverType {
+		return fmt.Errorf("cannot register resolver for type %q: its selector matches %s %q", resolverType, resolutioncommon.LabelKeyResolverType, selected)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.resolvers[resolverType]; ok {
+		return fmt.Errorf("a resolver is already registered for type %q", resolverType)
+	}
+	reg.resolvers[resolverType] = resolver
+	reg.types = append(reg.types, resolverType)
+	return nil
+}
+
+// Get returns the resolver registered for the given type, if any.
+func (reg *Registry) Get(resolverType string) (Resolver, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	resolver, ok := reg.resolvers[resolverType]
+	return resolver, ok
+}
+
+// Types returns the registered resolver types in the order they were
+// registered.
+func (reg *Registry) Types() []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return append([]string{}, reg.types...)
+}
+
+// NewControllers returns a controller constructor, as passed to
+// sharedmain, for each registered resolver. Each controller only hands
+// requests to its resolver while the resolver's feature flag is true.
+func (reg *Registry) NewControllers(ctx context.Context, modifiers ...ReconcilerModifier) []injection.ControllerConstructor {
+	var controllers []injection.ControllerConstructor
+	for _, resolverType := range reg.Types() {
+		resolver, _ := reg.Get(resolverType)
+		controllers = append(controllers, NewController(ctx, resolver, append([]ReconcilerModifier{gateOnFeatureFlag(resolverType)}, modifiers...)...))
+	}
+	return controllers
+}
+
+// DryRun resolves the params with the resolver registered for the given
+// type, like DryRun, after checking that the resolver's feature flag is
+// true.
+func (reg *Registry) DryRun(ctx context.Context, resolverType string, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	resolver, ok := reg.Get(resolverType)
+	if !ok {
+		return nil, fmt.Errorf("no resolver for type %q", resolverType)
+	}
+	if err := checkEnabled(ctx, resolverType); err != nil {
+		return nil, err
+	}
+	return DryRun(ctx, resolver, params)
+}
+
+// DryValidate validates the params with the resolver registered for the
+// given type, like DryValidate, after checking that the resolver's
+// feature flag is true.
+func (reg *Registry) DryValidate(ctx context.Context, resolverType string, params []pipelinev1beta1.Param) error {
+	resolver, ok := reg.Get(resolverType)
+	if !ok {
+		return fmt.Errorf("no resolver for type %q", resolverType)
+	}
+	if err := checkEnabled(ctx, resolverType); err != nil {
+		return err
+	}
+	return DryValidate(ctx, resolver, params)
+}
+
+// ParamSchema returns the schema of the params accepted by the resolver
+// registered for the given type, like GetParamSchema.
+func (reg *Registry) ParamSchema(ctx context.Context, resolverType string) ([]ParamSchema, error) {
+	resolver, ok := reg.Get(resolverType)
+	if !ok {
+		return nil, fmt.Errorf("no resolver for type %q", resolverType)
+	}
+	return GetParamSchema(ctx, resolver)
+}
+
+// DryRunBatch resolves the param sets with the resolver registered for
+// the given type, like DryRunBatch, after checking that the resolver's
+// feature flag is true. If it isn't, or no resolver is registered for
+// the type, every result holds the error.
+func (reg *Registry) DryRunBatch(ctx context.Context, resolverType string, paramSets [][]pipelinev1beta1.Param) []BatchResult {
+	resolver, ok := reg.Get(resolverType)
+	err := fmt.Errorf("no resolver for type %q", resolverType)
+	if ok {
+		err = checkEnabled(ctx, resolverType)
+	}
+	if err != nil {
+		results := make([]BatchResult, len(paramSets))
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+	return DryRunBatch(ctx, resolver, paramSets)
+}
+
+// gateOnFeatureFlag returns a ReconcilerModifier that makes the
+// reconciler check the feature flag of the given resolver type before
+// validating a request.
+func gateOnFeatureFlag(resolverType string) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.gatedType = resolverType
+	}
+}
+
+// checkEnabled returns a resolutioncommon.Error with the
+// ReasonResolverDisabled reason if the enable-<type>-resolver feature
+// flag in ctx isn't true for the given resolver type. Nothing is checked
+// when resolverType is empty.
+func checkEnabled(ctx context.Context, resolverType string) error {
+	if resolverType == "" {
+		return nil
+	}
+	if resolverconfig.FromContextOrDefaults(ctx).FeatureFlags.IsResolverEnabled(resolverType) {
+		return nil
+	}
+	return resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled,
+		fmt.Errorf("cannot handle resolution request, %s feature flag not true", resolverconfig.ResolverFeatureFlag(resolverType)))
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	cminformer "knative.dev/pkg/configmap/informer"
+	"knative.dev/pkg/system"
+)
+
+// echoResolver is a trivial resolver that resolves to the value of its
+// message param.
+type echoResolver struct{}
+
+var _ Resolver = &echoResolver{}
+
+func (r *echoResolver) Initialize(context.Context) error { return nil }
+
+func (r *echoResolver) GetName(context.Context) string { return "Echo" }
+
+func (r *echoResolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{resolutioncommon.LabelKeyResolverType: "echo"}
+}
+
+func (r *echoResolver) ValidateParams(_ context.Context, params []pipelinev1beta1.Param) error {
+	for _, p := range params {
+		if p.Name == "message" {
+			return nil
+		}
+	}
+	return errors.New("missing message param")
+}
+
+func (r *echoResolver) Resolve(_ context.Context, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	for _, p := range params {
+		if p.Name == "message" {
+			return &FakeResolvedResource{Content: p.Value.StringVal}, nil
+		}
+	}
+	return nil, errors.New("missing message param")
+}
+
+func TestRegistryRegister(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register("echo", &echoResolver{}); err != nil {
+		t.Fatalf("unexpected error registering resolver: %v", err)
+	}
+	if err := reg.Register(LabelValueFakeResolverType, &FakeResolver{}); err != nil {
+		t.Fatalf("unexpected error registering resolver: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name         string
+		resolverType string
+		resolver     Resolver
+		expectedErr  string
+	}{{
+		name:         "duplicate type",
+		resolverType: "echo",
+		resolver:     &echoResolver{},
+		expectedErr:  `a resolver is already registered for type "echo"`,
+	}, {
+		name:         "empty type",
+		resolverType: "",
+		resolver:     &echoResolver{},
+		expectedErr:  "cannot register resolver with empty type",
+	}, {
+		name:         "nil resolver",
+		resolverType: "other",
+		expectedErr:  `cannot register nil resolver for type "other"`,
+	}, {
+		name:         "selector doesn't match type",
+		resolverType: "other",
+		resolver:     &echoResolver{},
+		expectedErr:  `cannot register resolver for type "other": its selector matches resolution.tekton.dev/type "echo"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := reg.Register(tc.resolverType, tc.resolver)
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	if d := cmp.Diff([]string{"echo", LabelValueFakeResolverType}, reg.Types()); d != "" {
+		t.Errorf("unexpected registered types: %s", diff.PrintWantGot(d))
+	}
+	if resolver, ok := reg.Get("echo"); !ok || resolver == nil {
+		t.Errorf("expected echo resolver to be registered")
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		featureFlags  map[string]string
+		expectedData  string
+		expectedError string
+	}{{
+		name:         "enabled",
+		featureFlags: map[string]string{"enable-echo-resolver": "true"},
+		expectedData: "hello",
+	}, {
+		name:          "disabled",
+		featureFlags:  map[string]string{},
+		expectedError: `invalid resource request "foo/rr": cannot handle resolution request, enable-echo-resolver feature flag not true`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := NewRegistry()
+			if err := reg.Register("echo", &echoResolver{}); err != nil {
+				t.Fatalf("unexpected error registering resolver: %v", err)
+			}
+
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: time.Now()},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: "echo",
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: []pipelinev1beta1.Param{{
+						Name:  "message",
+						Value: *pipelinev1beta1.NewStructuredValues("hello"),
+					}},
+				},
+			}
+			d := test.Data{
+				ConfigMaps: []*corev1.ConfigMap{{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resolverconfig.GetFeatureFlagsConfigName(),
+						Namespace: system.Namespace(),
+					},
+					Data: tc.featureFlags,
+				}},
+				ResolutionRequests: []*v1beta1.ResolutionRequest{rr},
+			}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			c, _ := test.SeedTestData(t, ctx, d)
+			configMapWatcher := cminformer.NewInformedWatcher(c.Kube, system.Namespace())
+			controllers := reg.NewControllers(ctx, setClockOnReconciler)
+			if len(controllers) != 1 {
+				t.Fatalf("expected 1 controller but got %d", len(controllers))
+			}
+			ctl := controllers[0](ctx, configMapWatcher)
+			if err := configMapWatcher.Start(ctx.Done()); err != nil {
+				t.Fatalf("error starting configmap watcher: %v", err)
+			}
+
+			// Failed resolutions are permanent errors, the status is
+			// checked below.
+			_ = ctl.Reconciler.Reconcile(ctx, getRequestName(rr))
+
+			reconciledRR, err := c.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			if tc.expectedError != "" {
+				cond := reconciledRR.Status.GetCondition(apis.ConditionSucceeded)
+				if cond == nil || !cond.IsFalse() || cond.Message != tc.expectedError {
+					t.Fatalf("expected failed condition with message %q but got %v", tc.expectedError, cond)
+				}
+				return
+			}
+			if expected := base64.StdEncoding.Strict().EncodeToString([]byte(tc.expectedData)); reconciledRR.Status.Data != expected {
+				t.Errorf("expected data %q but got %q", expected, reconciledRR.Status.Data)
+			}
+		})
+	}
+}
+
+func TestRegistryDryRun(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register("echo", &echoResolver{}); err != nil {
+		t.Fatalf("unexpected error registering resolver: %v", err)
+	}
+	params := []pipelinev1beta1.Param{{
+		Name:  "message",
+		Value: *pipelinev1beta1.NewStructuredValues("hello"),
+	}}
+
+	featureFlags, err := resolverconfig.NewFeatureFlagsFromMap(map[string]string{"enable-echo-resolver": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error parsing feature flags: %v", err)
+	}
+	ctx := resolverconfig.ToContext(context.Background(), &resolverconfig.Config{FeatureFlags: featureFlags})
+	resource, err := reg.DryRun(ctx, "echo", params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "hello" {
+		t.Errorf("expected data %q but got %q", "hello", resource.Data())
+	}
+
+	_, err = reg.DryRun(context.Background(), "echo", params)
+	if resultFromError(err) != ResultDisabled {
+		t.Errorf("expected disabled error but got %v", err)
+	}
+	if expected := "cannot handle resolution request, enable-echo-resolver feature flag not true"; err == nil || err.Error() != expected {
+		t.Errorf("expected error %q but got %v", expected, err)
+	}
+
+	if _, err := reg.DryRun(ctx, "other", params); err == nil || err.Error() != `no resolver for type "other"` {
+		t.Errorf("expected missing resolver error but got %v", err)
+	}
+}
+
+func TestRegistryDryValidate(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register("echo", &echoResolver{}); err != nil {
+		t.Fatalf("unexpected error registering resolver: %v", err)
+	}
+	params := []pipelinev1beta1.Param{{
+		Name:  "message",
+		Value: *pipelinev1beta1.NewStructuredValues("hello"),
+	}}
+
+	featureFlags, err := resolverconfig.NewFeatureFlagsFromMap(map[string]string{"enable-echo-resolver": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error parsing feature flags: %v", err)
+	}
+	ctx := resolverconfig.ToContext(context.Background(), &resolverconfig.Config{FeatureFlags: featureFlags})
+	if err := reg.DryValidate(ctx, "echo", params); err != nil {
+		t.Fatalf("unexpected error validating: %v", err)
+	}
+
+	err = reg.DryValidate(context.Background(), "echo", params)
+	if resultFromError(err) != ResultDisabled {
+		t.Errorf("expected disabled error but got %v", err)
+	}
+
+	if err := reg.DryValidate(ctx, "other", params); err == nil || err.Error() != `no resolver for type "other"` {
+		t.Errorf("expected missing resolver error but got %v", err)
+	}
+}
+
+func TestRegistryDryRunBatch(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register("echo", &echoResolver{}); err != nil {
+		t.Fatalf("unexpected error registering resolver: %v", err)
+	}
+	paramSets := [][]pipelinev1beta1.Param{{{
+		Name:  "message",
+		Value: *pipelinev1beta1.NewStructuredValues("hello"),
+	}}, {{
+		Name:  "greeting",
+		Value: *pipelinev1beta1.NewStructuredValues("hi"),
+	}}}
+
+	featureFlags, err := resolverconfig.NewFeatureFlagsFromMap(map[string]string{"enable-echo-resolver": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error parsing feature flags: %v", err)
+	}
+	ctx := resolverconfig.ToContext(context.Background(), &resolverconfig.Config{FeatureFlags: featureFlags})
+	results := reg.DryRunBatch(ctx, "echo", paramSets)
+	if results[0].Err != nil || string(results[0].Resource.Data()) != "hello" {
+		t.Errorf("expected data %q but got %v", "hello", results[0])
+	}
+	if expected := "invalid params for Echo resolver: missing message param"; results[1].Err == nil || results[1].Err.Error() != expected {
+		t.Errorf("expected error %q but got %v", expected, results[1].Err)
+	}
+
+	for i, result := range reg.DryRunBatch(context.Background(), "echo", paramSets) {
+		if resultFromError(result.Err) != ResultDisabled {
+			t.Errorf("result %d: expected disabled error but got %v", i, result.Err)
+		}
+	}
+	for i, result := range reg.DryRunBatch(ctx, "other", paramSets) {
+		if result.Err == nil || result.Err.Error() != `no resolver for type "other"` {
+			t.Errorf("result %d: expected missing resolver error but got %v", i, result.Err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxResponseSize is the maximum size, in bytes, of a response
+// read by ReadResponseBody that resolvers use when no limit has been
+// configured.
+const DefaultMaxResponseSize int64 = 10 << 20
+
+// ResponseTooLargeError is returned by ReadResponseBody when a response
+// is larger than the maximum size.
+type ResponseTooLargeError struct {
+	MaxSize int64
+}
+
+var _ error = &ResponseTooLargeError{}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds max size %d bytes", e.MaxSize)
+}
+
+// ReadResponseBody reads the body of the response, failing with a
+// ResponseTooLargeError as soon as more than maxSize bytes have been
+// read so that a misbehaving server can't exhaust the resolver's
+// memory. A response whose Content-Length is already larger fails
+// without reading anything.
+//
+// A gzip encoded body, which the http client only leaves compressed
+// when the request asked for it with an Accept-Encoding header, is
+// decompressed with the limit applied both to the compressed bytes
+// read and to the decompressed ones, guarding against decompression
+// bombs. A maxSize of zero or less disables the limit.
+func ReadResponseBody(resp *http.Response, maxSize int64) ([]byte, error) {
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, &ResponseTooLargeError{MaxSize: maxSize}
+	}
+	var body io.Reader = &limitedReader{r: resp.Body, remaining: maxSize, maxSize: maxSize}
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = &limitedReader{r: gz, remaining: maxSize, maxSize: maxSize}
+	}
+	return io.ReadAll(body)
+}
+
+// LimitReader returns a reader that reads from r until more than maxSize
+// bytes have been read, at which point it fails with a
+// ResponseTooLargeError, e.g. to bound content decompressed from an
+// archive the way ReadResponseBody bounds a response. A maxSize of zero
+// or less disables the limit.
+func LimitReader(r io.Reader, maxSize int64) io.Reader {
+	return &limitedReader{r: r, remaining: maxSize, maxSize: maxSize}
+}
+
+// limitedReader reads from r until more than maxSize bytes have been
+// read, at which point it fails with a ResponseTooLargeError. A maxSize
+// of zero or less disables the limit.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	maxSize   int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.maxSize <= 0 {
+		return l.r.Read(p)
+	}
+	if l.remaining < 0 {
+		return 0, &ResponseTooLargeError{MaxSize: l.maxSize}
+	}
+	// Read one more byte than remains so that a body of exactly maxSize
+	// bytes can be told apart from a larger one.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return 0, &ResponseTooLargeError{MaxSize: l.maxSize}
+	}
+	return n, err
+}
+
+// RetryAfter returns how long the Retry-After header of the response
+// asks clients to wait before retrying, whether it's given in seconds
+// or as a date. Zero is returned if the header is missing, invalid or
+// the date has already passed.
+func RetryAfter(resp *http.Response, now time.Time) time.Duration {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	date, err := http.ParseTime(value)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+	return date.Sub(now)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadResponseBody(t *testing.T) {
+	random := make([]byte, 4096)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatalf("failed to generate random content: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		body          []byte
+		gzip          bool
+		contentLength int64
+		maxSize       int64
+		expectTooBig  bool
+	}{{
+		name:    "smaller than max size",
+		body:    []byte("some content"),
+		maxSize: 100,
+	}, {
+		name:    "exactly max size",
+		body:    []byte("some content"),
+		maxSize: 12,
+	}, {
+		name:         "larger than max size",
+		body:         []byte("some content"),
+		maxSize:      11,
+		expectTooBig: true,
+	}, {
+		name:          "content length larger than max size",
+		body:          []byte("some content"),
+		contentLength: 1 << 30,
+		maxSize:       100,
+		expectTooBig:  true,
+	}, {
+		name:    "no limit",
+		body:    bytes.Repeat([]byte("a"), 1<<20),
+		maxSize: 0,
+	}, {
+		name:    "gzip within max size",
+		body:    []byte("some content"),
+		gzip:    true,
+		maxSize: 100,
+	}, {
+		name:         "gzip decompressed larger than max size",
+		body:         bytes.Repeat([]byte{0}, 1<<20),
+		gzip:         true,
+		maxSize:      1024,
+		expectTooBig: true,
+	}, {
+		name:         "gzip compressed larger than max size",
+		body:         random,
+		gzip:         true,
+		maxSize:      1024,
+		expectTooBig: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, ContentLength: tc.contentLength}
+			if tc.gzip {
+				var buf bytes.Buffer
+				gz := gzip.NewWriter(&buf)
+				if _, err := gz.Write(tc.body); err != nil {
+					t.Fatalf("failed to compress body: %v", err)
+				}
+				if err := gz.Close(); err != nil {
+					t.Fatalf("failed to compress body: %v", err)
+				}
+				resp.Header.Set("Content-Encoding", "gzip")
+				resp.Body = io.NopCloser(&buf)
+			} else {
+				resp.Body = io.NopCloser(bytes.NewReader(tc.body))
+			}
+
+			body, err := ReadResponseBody(resp, tc.maxSize)
+			if tc.expectTooBig {
+				var tooLarge *ResponseTooLargeError
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("expected a ResponseTooLargeError but got %v", err)
+				}
+				if !strings.Contains(err.Error(), "response exceeds max size") {
+					t.Errorf("unexpected error message %q", err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(body, tc.body) {
+				t.Errorf("expected %d bytes of body but got %d", len(tc.body), len(body))
+			}
+		})
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		maxSize int64
+		tooBig  bool
+	}{{
+		name:    "within limit",
+		content: "some content",
+		maxSize: 12,
+	}, {
+		name:    "over limit",
+		content: "some content",
+		maxSize: 11,
+		tooBig:  true,
+	}, {
+		name:    "no limit",
+		content: "some content",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := io.ReadAll(LimitReader(strings.NewReader(tc.content), tc.maxSize))
+			var tooLarge *ResponseTooLargeError
+			if tc.tooBig {
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("expected a ResponseTooLargeError but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tc.content {
+				t.Errorf("expected %q but got %q", tc.content, data)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		header   string
+		expected time.Duration
+	}{{
+		name: "missing",
+	}, {
+		name:     "seconds",
+		header:   "120",
+		expected: 2 * time.Minute,
+	}, {
+		name:     "date",
+		header:   now.Add(30 * time.Second).Format(http.TimeFormat),
+		expected: 30 * time.Second,
+	}, {
+		name:   "date in the past",
+		header: now.Add(-time.Minute).Format(http.TimeFormat),
+	}, {
+		name:   "negative seconds",
+		header: "-1",
+	}, {
+		name:   "invalid",
+		header: "soon",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tc.header != "" {
+				resp.Header.Set("Retry-After", tc.header)
+			}
+			if d := RetryAfter(resp, now); d != tc.expected {
+				t.Errorf("expected %s but got %s", tc.expected, d)
+			}
+		})
+	}
+}
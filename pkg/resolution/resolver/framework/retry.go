@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// ConfigRetryMaxBackoff is the key in a resolver's ConfigMap for the
+	// maximum delay between two retries of a request to its backend,
+	// e.g. "10s". The delay is otherwise only bounded by the number of
+	// retries.
+	ConfigRetryMaxBackoff = "retry-max-backoff"
+
+	// ConfigRetryMaxElapsed is the key in a resolver's ConfigMap for the
+	// maximum total time spent retrying a request to its backend,
+	// measured from its first attempt, e.g. "2m". Retrying is otherwise
+	// only bounded by the number of retries and the resolution timeout.
+	ConfigRetryMaxElapsed = "retry-max-elapsed"
+)
+
+// RetryOptions configures how Retry retries a failing function.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of times the function is
+	// called. Values less than 1 are treated as 1.
+	MaxAttempts int
+	// Backoff is the delay before the first retry. It doubles for
+	// each retry after that.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. Zero means no cap.
+	MaxBackoff time.Duration
+	// MaxElapsed is the maximum time spent retrying, measured from the
+	// first call. No retry is made whose delay would take the total past
+	// it, whatever the number of attempts left. Zero means no limit.
+	MaxElapsed time.Duration
+	// Jitter is the fraction, between 0 and 1, of each delay that is
+	// randomized so that retries from many resolutions don't arrive at
+	// the same time.
+	Jitter float64
+	// Retryable returns true if the given error may go away when
+	// retried. All errors are retried when it is nil.
+	Retryable func(error) bool
+}
+
+// RetryError is returned by Retry when it gives up retrying a function
+// that has been called more than once, or when the context is done
+// before it could be retried.
+type RetryError struct {
+	// Attempts is the number of times the function was called.
+	Attempts int
+	// Err is the error returned by the last call to the function.
+	Err error
+	// ContextErr is the error of the context when it was done, or
+	// would be done, before the function could be retried.
+	ContextErr error
+	// MaxElapsed is the MaxElapsed option when retrying stopped because
+	// the next retry would have exceeded it, rather than because the
+	// attempts ran out, and zero otherwise.
+	MaxElapsed time.Duration
+}
+
+func (e *RetryError) Error() string {
+	if e.ContextErr != nil {
+		return fmt.Sprintf("cancelled after %d attempts: %v", e.Attempts, e.ContextErr)
+	}
+	if e.MaxElapsed > 0 {
+		return fmt.Sprintf("max elapsed time %s reached after %d attempts: %v", e.MaxElapsed, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the context's error if retrying was cancelled and the
+// last error returned by the function otherwise.
+func (e *RetryError) Unwrap() error {
+	if e.ContextErr != nil {
+		return e.ContextErr
+	}
+	return e.Err
+}
+
+// Retry calls fn until it succeeds, it returns an error that isn't
+// retryable, it has been called opts.MaxAttempts times or ctx is done,
+// waiting with exponential backoff between calls. Errors that aren't
+// retryable, and errors from a function that was only called once, are
+// returned as is. Otherwise a *RetryError is returned. Retrying stops
+// early if the context's deadline would pass before the next attempt,
+// or if opts.MaxElapsed would.
+func Retry(ctx context.Context, opts RetryOptions, fn func() error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if opts.Retryable != nil && !opts.Retryable(err) {
+			return err
+		}
+		if attempt >= opts.MaxAttempts {
+			if attempt == 1 {
+				return err
+			}
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		delay := opts.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return &RetryError{Attempts: attempt, Err: err, ContextErr: context.DeadlineExceeded}
+		}
+		if opts.MaxElapsed > 0 && time.Since(start)+delay > opts.MaxElapsed {
+			return &RetryError{Attempts: attempt, Err: err, MaxElapsed: opts.MaxElapsed}
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Attempts: attempt, Err: err, ContextErr: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the delay before the retry following the given
+// attempt: the initial backoff doubled for each previous retry, capped
+// at MaxBackoff, with the Jitter fraction of it randomized.
+func (opts RetryOptions) delay(attempt int) time.Duration {
+	d := opts.Backoff << (attempt - 1)
+	if d <= 0 {
+		// The shift overflowed.
+		d = opts.Backoff
+	}
+	if opts.MaxBackoff > 0 && d > opts.MaxBackoff {
+		d = opts.MaxBackoff
+	}
+	jitter := opts.Jitter
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	random := time.Duration(float64(d) * jitter)
+	if random <= 0 {
+		return d
+	}
+	// #nosec G404 -- jitter does not need a cryptographically secure source.
+	return d - random + time.Duration(rand.Int63n(int64(random)+1))
+}
+
+// RetryOptionsFromContext returns the given options with the
+// MaxBackoff and MaxElapsed set in the resolver's config, if any,
+// overriding theirs. Invalid values are ignored so that a typo doesn't
+// stop every resolution.
+func RetryOptionsFromContext(ctx context.Context, opts RetryOptions) RetryOptions {
+	conf := GetResolverConfigFromContext(ctx)
+	for key, setting := range map[string]*time.Duration{
+		ConfigRetryMaxBackoff: &opts.MaxBackoff,
+		ConfigRetryMaxElapsed: &opts.MaxElapsed,
+	} {
+		value, ok := conf[key]
+		if !ok || value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a positive duration", key, value)
+			continue
+		}
+		*setting = d
+	}
+	return opts
+}
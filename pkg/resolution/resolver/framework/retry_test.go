@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/pipeline/test/diff"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errPermanent = errors.New("permanent")
+)
+
+func TestRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		errs             []error
+		maxAttempts      int
+		expectedAttempts int
+		expectedErr      error
+		expectRetryError bool
+	}{{
+		name:             "succeeds first time",
+		errs:             []error{nil},
+		maxAttempts:      3,
+		expectedAttempts: 1,
+	}, {
+		name:             "succeeds after retries",
+		errs:             []error{errTransient, errTransient, nil},
+		maxAttempts:      3,
+		expectedAttempts: 3,
+	}, {
+		name:             "gives up after max attempts",
+		errs:             []error{errTransient, errTransient, errTransient},
+		maxAttempts:      3,
+		expectedAttempts: 3,
+		expectedErr:      errTransient,
+		expectRetryError: true,
+	}, {
+		name:             "single attempt returns error as is",
+		errs:             []error{errTransient},
+		maxAttempts:      1,
+		expectedAttempts: 1,
+		expectedErr:      errTransient,
+	}, {
+		name:             "zero max attempts calls once",
+		errs:             []error{errTransient},
+		maxAttempts:      0,
+		expectedAttempts: 1,
+		expectedErr:      errTransient,
+	}, {
+		name:             "non-retryable error short-circuits",
+		errs:             []error{errTransient, errPermanent, nil},
+		maxAttempts:      5,
+		expectedAttempts: 2,
+		expectedErr:      errPermanent,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			opts := RetryOptions{
+				MaxAttempts: tc.maxAttempts,
+				Backoff:     time.Millisecond,
+				Retryable: func(err error) bool {
+					return !errors.Is(err, errPermanent)
+				},
+			}
+			err := Retry(context.Background(), opts, func() error {
+				err := tc.errs[attempts]
+				attempts++
+				return err
+			})
+			if attempts != tc.expectedAttempts {
+				t.Errorf("expected %d attempts but got %d", tc.expectedAttempts, attempts)
+			}
+			if !errors.Is(err, tc.expectedErr) || (err == nil) != (tc.expectedErr == nil) {
+				t.Fatalf("expected error %v but got %v", tc.expectedErr, err)
+			}
+			var retryErr *RetryError
+			if isRetryErr := errors.As(err, &retryErr); isRetryErr != tc.expectRetryError {
+				t.Fatalf("expected RetryError to be %t but got %v", tc.expectRetryError, err)
+			}
+			if retryErr != nil && retryErr.Attempts != tc.expectedAttempts {
+				t.Errorf("expected RetryError with %d attempts but got %d", tc.expectedAttempts, retryErr.Attempts)
+			}
+		})
+	}
+}
+
+func TestRetryCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	err := Retry(ctx, RetryOptions{MaxAttempts: 5, Backoff: time.Hour}, func() error {
+		attempts++
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return errTransient
+	})
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected retry to stop once cancelled but it took %s", elapsed)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt but got %d", attempts)
+	}
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected RetryError but got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to wrap context.Canceled but got %v", err)
+	}
+	if !errors.Is(retryErr.Err, errTransient) {
+		t.Errorf("expected last error to be kept but got %v", retryErr.Err)
+	}
+}
+
+func TestRetryStopsBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	err := Retry(ctx, RetryOptions{MaxAttempts: 5, Backoff: time.Hour}, func() error {
+		attempts++
+		return errTransient
+	})
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected retry to give up before the deadline but it took %s", elapsed)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt but got %d", attempts)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded but got %v", err)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		maxAttempts      int
+		maxElapsed       time.Duration
+		expectedAttempts int
+		expectedErr      string
+	}{{
+		name:             "attempts run out first",
+		maxAttempts:      3,
+		maxElapsed:       time.Minute,
+		expectedAttempts: 3,
+		expectedErr:      "failed after 3 attempts: transient",
+	}, {
+		name:             "elapsed time runs out first",
+		maxAttempts:      100,
+		maxElapsed:       200 * time.Millisecond,
+		expectedAttempts: 4,
+		expectedErr:      "max elapsed time 200ms reached after 4 attempts: transient",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			// The delays are 20ms, 40ms and 80ms, so the fourth retry,
+			// after a further 160ms, would take the total past 200ms.
+			opts := RetryOptions{
+				MaxAttempts: tc.maxAttempts,
+				Backoff:     20 * time.Millisecond,
+				MaxElapsed:  tc.maxElapsed,
+			}
+			start := time.Now()
+			err := Retry(context.Background(), opts, func() error {
+				attempts++
+				return errTransient
+			})
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Fatalf("expected retry to give up quickly but it took %s", elapsed)
+			}
+			if attempts != tc.expectedAttempts {
+				t.Errorf("expected %d attempts but got %d", tc.expectedAttempts, attempts)
+			}
+			var retryErr *RetryError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("expected RetryError but got %v", err)
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+			if !errors.Is(err, errTransient) {
+				t.Errorf("expected error to wrap the last error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryMaxElapsedBeforeFirstRetry(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), RetryOptions{MaxAttempts: 5, Backoff: time.Hour, MaxElapsed: time.Minute}, func() error {
+		attempts++
+		return errTransient
+	})
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt but got %d", attempts)
+	}
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.MaxElapsed != time.Minute {
+		t.Fatalf("expected RetryError with the max elapsed time but got %v", err)
+	}
+}
+
+func TestRetryOptionsFromContext(t *testing.T) {
+	defaults := RetryOptions{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for _, tc := range []struct {
+		name     string
+		config   map[string]string
+		expected RetryOptions
+	}{{
+		name:     "not configured",
+		expected: defaults,
+	}, {
+		name: "configured",
+		config: map[string]string{
+			ConfigRetryMaxBackoff: "10s",
+			ConfigRetryMaxElapsed: "2m",
+		},
+		expected: RetryOptions{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 10 * time.Second, MaxElapsed: 2 * time.Minute},
+	}, {
+		name: "invalid values are ignored",
+		config: map[string]string{
+			ConfigRetryMaxBackoff: "soon",
+			ConfigRetryMaxElapsed: "-1m",
+		},
+		expected: defaults,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := InjectResolverConfigToContext(context.Background(), tc.config)
+			got := RetryOptionsFromContext(ctx, defaults)
+			if d := cmp.Diff(tc.expected, got, cmp.Comparer(func(a, b func(error) bool) bool { return (a == nil) == (b == nil) })); d != "" {
+				t.Errorf("unexpected options: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	opts := RetryOptions{Backoff: 100 * time.Millisecond, Jitter: 0.5}
+	for attempt := 1; attempt <= 4; attempt++ {
+		max := 100 * time.Millisecond << (attempt - 1)
+		for i := 0; i < 20; i++ {
+			d := opts.delay(attempt)
+			if d < max/2 || d > max {
+				t.Fatalf("delay for attempt %d out of range [%s, %s]: %s", attempt, max/2, max, d)
+			}
+		}
+	}
+
+	capped := RetryOptions{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	if d := capped.delay(4); d != 300*time.Millisecond {
+		t.Errorf("expected delay to be capped at 300ms but got %s", d)
+	}
+	if d := capped.delay(2); d != 200*time.Millisecond {
+		t.Errorf("expected delay without jitter to be 200ms but got %s", d)
+	}
+}
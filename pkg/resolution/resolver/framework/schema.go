@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// ParamSchema describes a single param a resolver accepts, for tooling
+// such as UIs and linters that want to check or offer a resolution's
+// params without running the resolver.
+type ParamSchema struct {
+	// Name is the name of the param.
+	Name string `json:"name"`
+	// Description is a human-readable description of the param.
+	Description string `json:"description,omitempty"`
+	// Required is true if a resolution without the param is invalid.
+	Required bool `json:"required,omitempty"`
+	// Enum lists the values the param may have, or is empty if it may
+	// have any value ValidateParams accepts.
+	Enum []string `json:"enum,omitempty"`
+	// Default is the value the param has when it is omitted, or empty
+	// if it has none.
+	Default string `json:"default,omitempty"`
+}
+
+// frameworkParamSchema describes the params handled by the resolver
+// framework itself, which every resolver accepts.
+var frameworkParamSchema = []ParamSchema{{
+	Name:        resolutioncommon.ParamFallback,
+	Description: "Inline content, a YAML or JSON object, resolved to when the resolver's backend is unavailable.",
+}, {
+	Name:        resolutioncommon.ParamDocuments,
+	Description: "Whether the resolved content may hold several YAML documents.",
+	Enum:        []string{resolutioncommon.DocumentsSingle, resolutioncommon.DocumentsMultiple},
+	Default:     resolutioncommon.DocumentsSingle,
+}, {
+	Name:        resolutioncommon.ParamDecode,
+	Description: "How the resolved content is decoded when it is stored encoded, e.g. base64 encoded in a ConfigMap.",
+	Enum:        []string{resolutioncommon.DecodeBase64, resolutioncommon.DecodeGzip, resolutioncommon.DecodeGzipBase64},
+}}
+
+// GetParamSchema returns the schema of the params accepted by the
+// resolver, as declared by its GetParamSchema method, followed by the
+// params handled by the framework. Params the resolver declares without
+// a default get the one from its DefaultParams, if any, so that defaults
+// read from the resolver's config in ctx are reported. It is an error
+// for the resolver not to implement ParamSchemaProvider.
+func GetParamSchema(ctx context.Context, resolver Resolver) ([]ParamSchema, error) {
+	provider, ok := resolver.(ParamSchemaProvider)
+	if !ok {
+		return nil, fmt.Errorf("resolver %s doesn't describe its params", resolver.GetName(ctx))
+	}
+	var defaults map[string]string
+	if defaulter, ok := resolver.(ParamDefaulter); ok {
+		defaults = defaulter.DefaultParams(ctx)
+	}
+	declared := provider.GetParamSchema(ctx)
+	schema := make([]ParamSchema, 0, len(declared)+len(frameworkParamSchema))
+	for _, p := range declared {
+		if d, ok := defaults[p.Name]; ok && p.Default == "" {
+			p.Default = d
+		}
+		schema = append(schema, p.copy())
+	}
+	for _, p := range frameworkParamSchema {
+		schema = append(schema, p.copy())
+	}
+	return schema, nil
+}
+
+// copy returns a copy of the schema that doesn't share its Enum.
+func (p ParamSchema) copy() ParamSchema {
+	if p.Enum != nil {
+		p.Enum = append([]string{}, p.Enum...)
+	}
+	return p
+}
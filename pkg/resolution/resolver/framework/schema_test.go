@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test/diff"
+)
+
+// schemaResolver is a FakeResolver with default params that describes
+// its params.
+type schemaResolver struct {
+	defaultingResolver
+	schema []ParamSchema
+}
+
+var _ ParamSchemaProvider = &schemaResolver{}
+
+func (r *schemaResolver) GetParamSchema(context.Context) []ParamSchema {
+	return r.schema
+}
+
+func TestGetParamSchema(t *testing.T) {
+	resolver := &schemaResolver{
+		defaultingResolver: defaultingResolver{defaults: map[string]string{"type": "tekton", "catalog": "tekton"}},
+		schema: []ParamSchema{{
+			Name:     "name",
+			Required: true,
+		}, {
+			Name: "type",
+			Enum: []string{"tekton", "artifact"},
+		}, {
+			Name:    "catalog",
+			Default: "declared",
+		}},
+	}
+	schema, err := GetParamSchema(context.Background(), resolver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ParamSchema{{
+		Name:     "name",
+		Required: true,
+	}, {
+		Name:    "type",
+		Enum:    []string{"tekton", "artifact"},
+		Default: "tekton",
+	}, {
+		Name:    "catalog",
+		Default: "declared",
+	}}
+	expected = append(expected, frameworkParamSchema...)
+	if d := cmp.Diff(expected, schema); d != "" {
+		t.Errorf("unexpected schema: %s", diff.PrintWantGot(d))
+	}
+
+	// The returned schema doesn't share its enums with the resolver's
+	// or the framework's.
+	schema[1].Enum[0] = "changed"
+	schema[len(schema)-1].Enum[0] = "changed"
+	if resolver.schema[1].Enum[0] != "tekton" {
+		t.Errorf("resolver's schema was modified")
+	}
+	if frameworkParamSchema[1].Enum[0] != resolutioncommon.DocumentsSingle {
+		t.Errorf("framework's schema was modified")
+	}
+}
+
+func TestGetParamSchemaUnsupported(t *testing.T) {
+	_, err := GetParamSchema(context.Background(), &FakeResolver{})
+	if err == nil || err.Error() != "resolver Fake doesn't describe its params" {
+		t.Errorf("expected unsupported error but got %v", err)
+	}
+}
+
+func TestRegistryParamSchema(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register(LabelValueFakeResolverType, &schemaResolver{schema: []ParamSchema{{Name: "name", Required: true}}}); err != nil {
+		t.Fatalf("unexpected error registering resolver: %v", err)
+	}
+	schema, err := reg.ParamSchema(context.Background(), LabelValueFakeResolverType)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(schema) == 0 || schema[0].Name != "name" || !schema[0].Required {
+		t.Errorf("expected the resolver's name param first but got %v", schema)
+	}
+
+	if _, err := reg.ParamSchema(context.Background(), "other"); err == nil || err.Error() != `no resolver for type "other"` {
+		t.Errorf("expected missing resolver error but got %v", err)
+	}
+}
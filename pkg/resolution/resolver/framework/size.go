@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"knative.dev/pkg/logging"
+)
+
+// ConfigMaxResolvedSize is the key in a resolver's ConfigMap for the
+// maximum size of the content of a resolved resource, e.g. "1Mi".
+// Larger resources fail with a ResolvedResourceTooLargeError rather
+// than when the ResolutionRequest holding them is updated. Zero, the
+// default, means no limit.
+const ConfigMaxResolvedSize = "max-resolved-size"
+
+// ResolvedResourceTooLargeError is returned when the content of a
+// resolved resource is larger than the max-resolved-size config.
+type ResolvedResourceTooLargeError struct {
+	Size    int
+	MaxSize int64
+}
+
+var _ error = &ResolvedResourceTooLargeError{}
+
+func (e *ResolvedResourceTooLargeError) Error() string {
+	return fmt.Sprintf("resolved resource exceeds limit: its %d bytes are more than the %s of %d bytes", e.Size, ConfigMaxResolvedSize, e.MaxSize)
+}
+
+// maxResolvedSize returns the limit on the size of resolved content set
+// in the resolver's config. Invalid values are ignored so that a typo
+// doesn't stop every resolution.
+func maxResolvedSize(ctx context.Context) int64 {
+	value, ok := GetResolverConfigFromContext(ctx)[ConfigMaxResolvedSize]
+	if !ok || value == "" {
+		return 0
+	}
+	size, err := resource.ParseQuantity(value)
+	if err != nil || size.Sign() < 0 {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a non-negative quantity", ConfigMaxResolvedSize, value)
+		return 0
+	}
+	return size.Value()
+}
+
+// checkResolvedSize returns a ResolvedResourceTooLargeError if the
+// content of the resolved resource is larger than the configured limit.
+func checkResolvedSize(ctx context.Context, resolved ResolvedResource) error {
+	maxSize := maxResolvedSize(ctx)
+	if maxSize <= 0 {
+		return nil
+	}
+	if size := len(resolved.Data()); int64(size) > maxSize {
+		return &ResolvedResourceTooLargeError{Size: size, MaxSize: maxSize}
+	}
+	return nil
+}
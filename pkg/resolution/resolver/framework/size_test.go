@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/system"
+)
+
+// configWatchingResolver is a FakeResolver reading its config from the
+// fake-resolver-config ConfigMap.
+type configWatchingResolver struct {
+	FakeResolver
+}
+
+func (r *configWatchingResolver) GetConfigName(context.Context) string {
+	return "fake-resolver-config"
+}
+
+func TestCheckResolvedSize(t *testing.T) {
+	resolved := &FakeResolvedResource{Content: strings.Repeat("a", 1024)}
+	for _, tc := range []struct {
+		name        string
+		maxSize     string
+		expectedErr string
+	}{{
+		name: "no limit",
+	}, {
+		name:    "zero disables the limit",
+		maxSize: "0",
+	}, {
+		name:    "under the limit",
+		maxSize: "1Ki",
+	}, {
+		name:        "over the limit",
+		maxSize:     "1000",
+		expectedErr: "resolved resource exceeds limit: its 1024 bytes are more than the max-resolved-size of 1000 bytes",
+	}, {
+		name:    "invalid limit is ignored",
+		maxSize: "lots",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.maxSize != "" {
+				ctx = InjectResolverConfigToContext(ctx, map[string]string{ConfigMaxResolvedSize: tc.maxSize})
+			}
+			err := checkResolvedSize(ctx, resolved)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var tooLarge *ResolvedResourceTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("expected a ResolvedResourceTooLargeError but got %v", err)
+			}
+			if err.Error() != tc.expectedErr {
+				t.Errorf("expected error %q but got %q", tc.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestDryRunResolvedResourceTooLarge(t *testing.T) {
+	resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"foo": {Content: "some content"},
+	}}
+	ctx := InjectResolverConfigToContext(context.Background(), map[string]string{ConfigMaxResolvedSize: "8"})
+	_, err := DryRun(ctx, resolver, fakeParams("foo"))
+	var tooLarge *ResolvedResourceTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected a ResolvedResourceTooLargeError but got %v", err)
+	}
+	expected := "error resolving with Fake resolver: resolved resource exceeds limit: its 12 bytes are more than the max-resolved-size of 8 bytes"
+	if err.Error() != expected {
+		t.Errorf("expected error %q but got %q", expected, err.Error())
+	}
+}
+
+func TestReconcileResolvedResourceTooLarge(t *testing.T) {
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: time.Now()},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: []pipelinev1beta1.Param{{
+				Name:  FakeParamName,
+				Value: *pipelinev1beta1.NewStructuredValues("foo"),
+			}},
+		},
+	}
+	d := test.Data{
+		ConfigMaps: []*corev1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fake-resolver-config",
+				Namespace: system.Namespace(),
+			},
+			Data: map[string]string{ConfigMaxResolvedSize: "8"},
+		}, {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resolverconfig.GetFeatureFlagsConfigName(),
+				Namespace: system.Namespace(),
+			},
+		}},
+		ResolutionRequests: []*v1beta1.ResolutionRequest{rr},
+	}
+	resolver := &configWatchingResolver{FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"foo": {Content: "some content"},
+	}}}
+
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, d, resolver, setClockOnReconciler)
+	defer cancel()
+
+	expected := `error getting "Fake" "foo/rr": resolved resource exceeds limit: its 12 bytes are more than the max-resolved-size of 8 bytes`
+	err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected error %q but got %v", expected, err)
+	}
+
+	reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting updated ResolutionRequest: %v", err)
+	}
+	cond := reconciledRR.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil || !cond.IsFalse() || !strings.Contains(cond.Message, "resolved resource exceeds limit") {
+		t.Errorf("expected failed condition reporting the size limit but got %v", cond)
+	}
+	if reconciledRR.Status.Data != "" {
+		t.Errorf("expected no data to be written but got %q", reconciledRR.Status.Data)
+	}
+}
@@ -43,6 +43,36 @@ func ContextWithClusterResolverEnabled(ctx context.Context) context.Context {
 	return contextWithResolverEnabled(ctx, "enable-cluster-resolver")
 }
 
+// ContextWithHTTPResolverEnabled returns a context containing a Config with the enable-http-resolver feature flag enabled.
+func ContextWithHTTPResolverEnabled(ctx context.Context) context.Context {
+	return contextWithResolverEnabled(ctx, "enable-http-resolver")
+}
+
+// ContextWithConfigMapResolverEnabled returns a context containing a Config with the enable-configmap-resolver feature flag enabled.
+func ContextWithConfigMapResolverEnabled(ctx context.Context) context.Context {
+	return contextWithResolverEnabled(ctx, "enable-configmap-resolver")
+}
+
+// ContextWithS3ResolverEnabled returns a context containing a Config with the enable-s3-resolver feature flag enabled.
+func ContextWithS3ResolverEnabled(ctx context.Context) context.Context {
+	return contextWithResolverEnabled(ctx, "enable-s3-resolver")
+}
+
+// ContextWithGCSResolverEnabled returns a context containing a Config with the enable-gcs-resolver feature flag enabled.
+func ContextWithGCSResolverEnabled(ctx context.Context) context.Context {
+	return contextWithResolverEnabled(ctx, "enable-gcs-resolver")
+}
+
+// ContextWithHelmResolverEnabled returns a context containing a Config with the enable-helm-resolver feature flag enabled.
+func ContextWithHelmResolverEnabled(ctx context.Context) context.Context {
+	return contextWithResolverEnabled(ctx, "enable-helm-resolver")
+}
+
+// ContextWithFilesystemResolverEnabled returns a context containing a Config with the enable-filesystem-resolver feature flag enabled.
+func ContextWithFilesystemResolverEnabled(ctx context.Context) context.Context {
+	return contextWithResolverEnabled(ctx, "enable-filesystem-resolver")
+}
+
 func contextWithResolverEnabled(ctx context.Context, resolverFlag string) context.Context {
 	featureFlags, _ := resolverconfig.NewFeatureFlagsFromMap(map[string]string{
 		resolverFlag: "true",
@@ -0,0 +1,122 @@
+/*
+ Copyright 2022 The Tekton Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testing
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NetworkTrap is a TCP listener that counts the connections made to it
+// and closes them straight away. It checks that code which mustn't use
+// the network, such as a resolver's ValidateParams, doesn't: point every
+// remote endpoint the resolver is given at the trap's Addr, run the
+// code, then check Connections.
+type NetworkTrap struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	accepted map[string]bool
+	notify   chan struct{}
+	probes   int
+}
+
+// NewNetworkTrap returns a NetworkTrap listening on the loopback
+// interface, which is closed when the test finishes.
+func NewNetworkTrap(t *testing.T) *NetworkTrap {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	trap := &NetworkTrap{
+		listener: listener,
+		accepted: map[string]bool{},
+		notify:   make(chan struct{}, 1),
+	}
+	t.Cleanup(func() { listener.Close() })
+	go trap.accept()
+	return trap
+}
+
+func (n *NetworkTrap) accept() {
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			return
+		}
+		n.mu.Lock()
+		n.accepted[conn.RemoteAddr().String()] = true
+		n.mu.Unlock()
+		conn.Close()
+		select {
+		case n.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Addr returns the host:port the trap listens on.
+func (n *NetworkTrap) Addr() string {
+	return n.listener.Addr().String()
+}
+
+// URL returns an http URL of the trap.
+func (n *NetworkTrap) URL() string {
+	return "http://" + n.Addr()
+}
+
+// Connections returns the number of connections made to the trap so
+// far. It connects to the trap itself and waits for that connection to
+// be accepted, so that every connection made before it was called is
+// counted.
+func (n *NetworkTrap) Connections(t *testing.T) int {
+	t.Helper()
+	probe, err := net.Dial("tcp", n.Addr())
+	if err != nil {
+		t.Fatalf("error connecting to network trap: %v", err)
+	}
+	defer probe.Close()
+	local := probe.LocalAddr().String()
+	timeout := time.After(5 * time.Second)
+	for {
+		n.mu.Lock()
+		if n.accepted[local] {
+			n.probes++
+			count := len(n.accepted) - n.probes
+			n.mu.Unlock()
+			return count
+		}
+		n.mu.Unlock()
+		select {
+		case <-n.notify:
+		case <-timeout:
+			t.Fatalf("network trap didn't accept its own connection")
+		}
+	}
+}
+
+// ExpectNoConnections fails the test if any connections have been made
+// to the trap.
+func (n *NetworkTrap) ExpectNoConnections(t *testing.T) {
+	t.Helper()
+	if count := n.Connections(t); count != 0 {
+		t.Errorf("expected no network connections but got %d", count)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ Copyright 2022 The Tekton Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/tektoncd/pipeline/test"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FakeBundle is a Tekton Bundle a FakeRegistry is seeded with.
+type FakeBundle struct {
+	// Repository is the repository the bundle is pushed to, e.g.
+	// "tasks".
+	Repository string
+	// Tags are the tags the bundle is pushed with, "latest" if there are
+	// none.
+	Tags []string
+	// Objects are the Tasks, Pipelines or other objects the bundle
+	// holds, one per layer in the order given.
+	Objects []runtime.Object
+}
+
+// FakeRegistry is an in-memory OCI registry, served over plain http,
+// for tests that resolve Tekton Bundles end to end.
+type FakeRegistry struct {
+	// Host is the host:port of the registry, which references to the
+	// bundles it holds start with.
+	Host string
+}
+
+// NewFakeRegistry starts an in-memory OCI registry, shut down when the
+// test ends, and pushes the given bundles to it. It returns the registry
+// along with the reference by digest of each bundle, in the order given.
+func NewFakeRegistry(t testing.TB, bundles ...FakeBundle) (*FakeRegistry, []string) {
+	t.Helper()
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse fake registry url: %v", err)
+	}
+	r := &FakeRegistry{Host: u.Host}
+	refs := make([]string, 0, len(bundles))
+	for _, bundle := range bundles {
+		refs = append(refs, r.Push(t, bundle))
+	}
+	return r, refs
+}
+
+// Push pushes a bundle to the registry, tagged with each of its tags,
+// and returns its reference by digest. Pushing another bundle with the
+// same tag moves the tag to it.
+func (r *FakeRegistry) Push(t testing.TB, bundle FakeBundle) string {
+	t.Helper()
+	tags := bundle.Tags
+	if len(tags) == 0 {
+		tags = []string{"latest"}
+	}
+	digestRef, err := test.CreateImage(r.Reference(bundle.Repository, tags[0]), bundle.Objects...)
+	if err != nil {
+		t.Fatalf("failed to push bundle to %s: %v", r.Reference(bundle.Repository, tags[0]), err)
+	}
+	if len(tags) == 1 {
+		return digestRef
+	}
+	ref, err := name.ParseReference(digestRef)
+	if err != nil {
+		t.Fatalf("failed to parse bundle reference %s: %v", digestRef, err)
+	}
+	desc, err := remote.Get(ref)
+	if err != nil {
+		t.Fatalf("failed to get bundle %s: %v", digestRef, err)
+	}
+	for _, tag := range tags[1:] {
+		if err := remote.Tag(ref.Context().Tag(tag), desc); err != nil {
+			t.Fatalf("failed to tag bundle %s with %s: %v", digestRef, tag, err)
+		}
+	}
+	return digestRef
+}
+
+// Reference returns the reference to the given tag of a repository in
+// the registry, e.g. "127.0.0.1:1234/tasks:latest".
+func (r *FakeRegistry) Reference(repository, tag string) string {
+	return fmt.Sprintf("%s/%s:%s", r.Host, repository, tag)
+}
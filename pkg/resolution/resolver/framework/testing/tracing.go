@@ -0,0 +1,68 @@
+/*
+ Copyright 2022 The Tekton Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testing
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+)
+
+// SpanRecorder is a trace exporter that records the spans it is sent,
+// to check the spans a resolver emits.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+// NewSpanRecorder returns a SpanRecorder registered with the framework
+// and samples every span until the test finishes, when it is
+// unregistered and the default sampler is restored.
+func NewSpanRecorder(t *testing.T) *SpanRecorder {
+	t.Helper()
+	recorder := &SpanRecorder{}
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	framework.RegisterTraceExporter(recorder)
+	t.Cleanup(func() {
+		framework.UnregisterTraceExporter(recorder)
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(1e-4)})
+	})
+	return recorder
+}
+
+// ExportSpan records the span.
+func (r *SpanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+// Spans returns the spans with the given name recorded so far, in the
+// order they ended.
+func (r *SpanRecorder) Spans(name string) []*trace.SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var spans []*trace.SpanData
+	for _, s := range r.spans {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"sync/atomic"
+
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"go.opencensus.io/trace"
+)
+
+const (
+	// SpanReconcile is the name of the span covering the reconciliation
+	// of a ResolutionRequest.
+	SpanReconcile = "resolution.Reconcile"
+
+	// SpanResolve is the name of the span covering a call to a
+	// resolver's Resolve method.
+	SpanResolve = "resolution.Resolve"
+
+	// AttributeResolverType is the span attribute holding the type of
+	// the resolver, e.g. "hub".
+	AttributeResolverType = "resolver.type"
+
+	// AttributeRequestNamespace and AttributeRequestName are the span
+	// attributes holding the namespace and name of the
+	// ResolutionRequest being reconciled.
+	AttributeRequestNamespace = "resolutionrequest.namespace"
+	AttributeRequestName      = "resolutionrequest.name"
+
+	// attributeParamPrefix prefixes the names of the span attributes
+	// holding the values of params.
+	attributeParamPrefix = "param."
+)
+
+// traceExporters counts the registered trace exporters. Spans are only
+// started while there are any.
+var traceExporters int32
+
+// RegisterTraceExporter registers an exporter that receives the spans
+// of resolutions, e.g. one sending them to an OpenTelemetry Collector.
+// Until one is registered tracing is a no-op.
+func RegisterTraceExporter(e trace.Exporter) {
+	trace.RegisterExporter(e)
+	atomic.AddInt32(&traceExporters, 1)
+}
+
+// UnregisterTraceExporter unregisters an exporter registered with
+// RegisterTraceExporter.
+func UnregisterTraceExporter(e trace.Exporter) {
+	trace.UnregisterExporter(e)
+	atomic.AddInt32(&traceExporters, -1)
+}
+
+// StartSpan starts a span with the given name and attributes as a child
+// of the span in ctx, if any, e.g. around a network operation made by a
+// resolver, and returns it along with a context holding it. The span is
+// nil, which is safe to use and end, when no trace exporter is
+// registered.
+func StartSpan(ctx context.Context, name string, attributes ...trace.Attribute) (context.Context, *trace.Span) {
+	if atomic.LoadInt32(&traceExporters) <= 0 {
+		return ctx, nil
+	}
+	ctx, span := trace.StartSpan(ctx, name)
+	span.AddAttributes(attributes...)
+	return ctx, span
+}
+
+// EndSpan ends a span started with StartSpan, marking it as failed with
+// the given error if it isn't nil.
+func EndSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
+
+// tracedResolve calls the resolver's Resolve method within a span
+// recording the resolver's type and the params it allows to be traced.
+func tracedResolve(ctx context.Context, resolver Resolver, params []pipelinev1beta1.Param) (ResolvedResource, error) {
+	attributes := []trace.Attribute{
+		trace.StringAttribute(AttributeResolverType, resolver.GetSelector(ctx)[resolutioncommon.LabelKeyResolverType]),
+	}
+	if tracer, ok := resolver.(ParamTracer); ok {
+		traced := map[string]bool{}
+		for _, name := range tracer.TracedParams(ctx) {
+			traced[name] = true
+		}
+		for _, p := range params {
+			if traced[p.Name] && p.Value.Type == pipelinev1beta1.ParamTypeString {
+				attributes = append(attributes, trace.StringAttribute(attributeParamPrefix+p.Name, p.Value.StringVal))
+			}
+		}
+	}
+	ctx, span := StartSpan(ctx, SpanResolve, attributes...)
+	resource, err := resolver.Resolve(ctx, params)
+	EndSpan(span, err)
+	return resource, err
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	"github.com/tektoncd/pipeline/test/diff"
+	"go.opencensus.io/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// spanRecorder records the spans exported while it is registered. The
+// framework's testing package has the same for resolver tests, which
+// the framework's own tests can't import.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+// span returns the last recorded span with the given name, failing the
+// test if there isn't one.
+func (r *spanRecorder) span(t *testing.T, name string) *trace.SpanData {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.spans) - 1; i >= 0; i-- {
+		if r.spans[i].Name == name {
+			return r.spans[i]
+		}
+	}
+	t.Fatalf("expected a %s span to be exported", name)
+	return nil
+}
+
+// recordSpans registers a spanRecorder and samples every span until the
+// test finishes.
+func recordSpans(t *testing.T) *spanRecorder {
+	t.Helper()
+	recorder := &spanRecorder{}
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	RegisterTraceExporter(recorder)
+	t.Cleanup(func() {
+		UnregisterTraceExporter(recorder)
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(1e-4)})
+	})
+	return recorder
+}
+
+// tracedFakeResolver is a FakeResolver whose param may be traced.
+type tracedFakeResolver struct {
+	FakeResolver
+}
+
+func (r *tracedFakeResolver) TracedParams(context.Context) []string {
+	return []string{FakeParamName}
+}
+
+func TestStartSpanNoExporter(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, "test.Span")
+	if span != nil {
+		t.Errorf("expected no span without an exporter but got %v", span)
+	}
+	if spanCtx != ctx {
+		t.Errorf("expected the context to be returned unchanged")
+	}
+	// A nil span is safe to end.
+	EndSpan(span, nil)
+}
+
+func TestDryRunSpans(t *testing.T) {
+	resolver := &tracedFakeResolver{FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"foo":   {Content: "some content"},
+		"error": {ErrorWith: "something went wrong"},
+	}}}
+	untraced := &FakeResolver{ForParam: resolver.ForParam}
+
+	for _, tc := range []struct {
+		name               string
+		resolver           Resolver
+		param              string
+		expectedAttributes map[string]interface{}
+		expectedStatus     int32
+	}{{
+		name:     "traced params",
+		resolver: resolver,
+		param:    "foo",
+		expectedAttributes: map[string]interface{}{
+			AttributeResolverType:                "fake",
+			attributeParamPrefix + FakeParamName: "foo",
+		},
+	}, {
+		name:               "untraced params",
+		resolver:           untraced,
+		param:              "foo",
+		expectedAttributes: map[string]interface{}{AttributeResolverType: "fake"},
+	}, {
+		name:     "failed resolution",
+		resolver: resolver,
+		param:    "error",
+		expectedAttributes: map[string]interface{}{
+			AttributeResolverType:                "fake",
+			attributeParamPrefix + FakeParamName: "error",
+		},
+		expectedStatus: trace.StatusCodeUnknown,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			ctx, parent := trace.StartSpan(context.Background(), "test.Parent")
+			_, _ = DryRun(ctx, tc.resolver, fakeParams(tc.param))
+			parent.End()
+
+			span := recorder.span(t, SpanResolve)
+			if span.ParentSpanID != parent.SpanContext().SpanID {
+				t.Errorf("expected the %s span to nest under the span in the context", SpanResolve)
+			}
+			if d := cmp.Diff(tc.expectedAttributes, span.Attributes); d != "" {
+				t.Errorf("unexpected attributes: %s", diff.PrintWantGot(d))
+			}
+			if span.Status.Code != tc.expectedStatus {
+				t.Errorf("expected status code %d but got %d", tc.expectedStatus, span.Status.Code)
+			}
+		})
+	}
+}
+
+func TestReconcileSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	rr := &v1beta1.ResolutionRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rr",
+			Namespace:         "foo",
+			CreationTimestamp: metav1.Time{Time: time.Now()},
+			Labels: map[string]string{
+				resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+			},
+		},
+		Spec: v1beta1.ResolutionRequestSpec{
+			Params: fakeParams("foo"),
+		},
+	}
+	resolver := &tracedFakeResolver{FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"foo": {Content: "some content"},
+	}}}
+	ctx, _ := ttesting.SetupFakeContext(t)
+	testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler)
+	defer cancel()
+
+	if err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr)); err != nil {
+		t.Fatalf("unexpected error reconciling: %v", err)
+	}
+
+	reconcileSpan := recorder.span(t, SpanReconcile)
+	expectedAttributes := map[string]interface{}{
+		AttributeResolverType:     "fake",
+		AttributeRequestNamespace: "foo",
+		AttributeRequestName:      "rr",
+	}
+	if d := cmp.Diff(expectedAttributes, reconcileSpan.Attributes); d != "" {
+		t.Errorf("unexpected reconcile span attributes: %s", diff.PrintWantGot(d))
+	}
+	resolveSpan := recorder.span(t, SpanResolve)
+	if resolveSpan.ParentSpanID != reconcileSpan.SpanID {
+		t.Errorf("expected the %s span to nest under the %s span", SpanResolve, SpanReconcile)
+	}
+	if resolveSpan.TraceID != reconcileSpan.TraceID {
+		t.Errorf("expected the %s span to be in the same trace as the %s span", SpanResolve, SpanReconcile)
+	}
+}
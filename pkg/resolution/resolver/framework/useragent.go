@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+	"knative.dev/pkg/changeset"
+	"knative.dev/pkg/logging"
+)
+
+// ConfigUserAgent is the key in a resolver's ConfigMap for the
+// User-Agent header sent with the resolver's outbound HTTP requests, so
+// that the operators of hubs, registries and other servers can tell its
+// traffic apart. It defaults to DefaultUserAgent.
+const ConfigUserAgent = "user-agent"
+
+// DefaultUserAgent identifies requests made by the Tekton resolvers
+// along with the revision they were built from, e.g.
+// "tekton-pipelines-resolvers/1a2b3c4".
+var DefaultUserAgent = "tekton-pipelines-resolvers/" + changeset.Get()
+
+// UserAgent returns the User-Agent set in the resolver's config, or
+// DefaultUserAgent if there isn't one. An invalid value is ignored so
+// that a typo doesn't stop every resolution.
+func UserAgent(ctx context.Context) string {
+	value := strings.TrimSpace(GetResolverConfigFromContext(ctx)[ConfigUserAgent])
+	if value == "" {
+		return DefaultUserAgent
+	}
+	if !httpguts.ValidHeaderFieldValue(value) {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: must be a valid header value", ConfigUserAgent, value)
+		return DefaultUserAgent
+	}
+	return value
+}
+
+// SetUserAgent sets the User-Agent header of the request to the one
+// returned by UserAgent.
+func SetUserAgent(ctx context.Context, req *http.Request) {
+	req.Header.Set("User-Agent", UserAgent(ctx))
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUserAgent(t *testing.T) {
+	if !strings.HasPrefix(DefaultUserAgent, "tekton-pipelines-resolvers/") {
+		t.Errorf("expected the default user agent to identify the resolvers but got %q", DefaultUserAgent)
+	}
+	for _, tc := range []struct {
+		name     string
+		config   map[string]string
+		expected string
+	}{{
+		name:     "default",
+		expected: DefaultUserAgent,
+	}, {
+		name:     "configured",
+		config:   map[string]string{ConfigUserAgent: "acme-ci/1.0 (+https://ci.example.com)"},
+		expected: "acme-ci/1.0 (+https://ci.example.com)",
+	}, {
+		name:     "blank",
+		config:   map[string]string{ConfigUserAgent: "  "},
+		expected: DefaultUserAgent,
+	}, {
+		name:     "invalid is ignored",
+		config:   map[string]string{ConfigUserAgent: "acme\nX-Injected: yes"},
+		expected: DefaultUserAgent,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := InjectResolverConfigToContext(context.Background(), tc.config)
+			if got := UserAgent(ctx); got != tc.expected {
+				t.Errorf("expected user agent %q but got %q", tc.expected, got)
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil)
+			if err != nil {
+				t.Fatalf("unexpected error constructing request: %v", err)
+			}
+			SetUserAgent(ctx, req)
+			if got := req.Header.Get("User-Agent"); got != tc.expected {
+				t.Errorf("expected User-Agent header %q but got %q", tc.expected, got)
+			}
+		})
+	}
+}
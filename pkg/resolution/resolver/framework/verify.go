@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// verifierKey is the context key associated with the Verifier that
+// checks resolved resources.
+var verifierKey = struct{ name string }{"verifier"}
+
+// Verifier checks a resolved resource before it is returned, e.g. by
+// verifying the signature over its content that the resolver passed
+// back in its annotations under resolutioncommon.AnnotationKeySignature.
+// An error fails the resolution with a
+// resolutioncommon.VerificationError.
+//
+// When no Verifier is configured resolved resources are returned
+// without being checked.
+type Verifier interface {
+	// Verify returns an error if the resource resolved by the given
+	// type of resolver, e.g. "hub", must not be used.
+	Verify(ctx context.Context, resolverType string, resource ResolvedResource) error
+}
+
+// WithVerifier returns a ReconcilerModifier that checks every resource
+// the resolver resolves with the given Verifier.
+func WithVerifier(verifier Verifier) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.verifier = verifier
+	}
+}
+
+// InjectVerifierToContext returns a new context with the given Verifier
+// stored in it.
+func InjectVerifierToContext(ctx context.Context, verifier Verifier) context.Context {
+	return context.WithValue(ctx, verifierKey, verifier)
+}
+
+// GetVerifierFromContext returns the Verifier stored in the context, or
+// nil if there isn't one.
+func GetVerifierFromContext(ctx context.Context) Verifier {
+	verifier, _ := ctx.Value(verifierKey).(Verifier)
+	return verifier
+}
+
+// verifyResolved checks the resolved resource with the Verifier in the
+// context, if any, returning its error as a
+// resolutioncommon.VerificationError.
+func verifyResolved(ctx context.Context, resolverType string, resource ResolvedResource) error {
+	verifier := GetVerifierFromContext(ctx)
+	if verifier == nil {
+		return nil
+	}
+	if err := verifier.Verify(ctx, resolverType, resource); err != nil {
+		var verificationErr *resolutioncommon.VerificationError
+		if errors.As(err, &verificationErr) {
+			return err
+		}
+		return &resolutioncommon.VerificationError{
+			ResolverType: resolverType,
+			Original:     fmt.Errorf("%s resolved resource failed verification: %w", resolverType, err),
+		}
+	}
+	return nil
+}
+
+// PublicKeyVerifier is a Verifier requiring every resolved resource to
+// carry a signature over its content, made with the private key of its
+// public key, in its resolutioncommon.AnnotationKeySignature annotation.
+type PublicKeyVerifier struct {
+	verifier signature.Verifier
+}
+
+var _ Verifier = &PublicKeyVerifier{}
+
+// NewPublicKeyVerifier returns a PublicKeyVerifier checking signatures
+// with the given PEM encoded RSA, ECDSA or ED25519 public key. RSA and
+// ECDSA signatures are expected over the SHA-256 digest of the content.
+func NewPublicKeyVerifier(pemKey []byte) (*PublicKeyVerifier, error) {
+	key, err := cryptoutils.UnmarshalPEMToPublicKey(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	verifier, err := signature.LoadVerifier(key, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return &PublicKeyVerifier{verifier: verifier}, nil
+}
+
+// Verify returns an error if the resource has no signature, the
+// signature isn't base64 encoded or it doesn't match the resource's
+// content.
+func (v *PublicKeyVerifier) Verify(_ context.Context, _ string, resource ResolvedResource) error {
+	encoded := resource.Annotations()[resolutioncommon.AnnotationKeySignature]
+	if encoded == "" {
+		return fmt.Errorf("resolved resource has no %s annotation", resolutioncommon.AnnotationKeySignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", resolutioncommon.AnnotationKeySignature, err)
+	}
+	if err := v.verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(resource.Data())); err != nil {
+		return fmt.Errorf("signature doesn't match the resolved content: %w", err)
+	}
+	return nil
+}
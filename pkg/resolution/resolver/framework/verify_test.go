@@ -0,0 +1,272 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/test"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+)
+
+// signedResolver returns a FakeResolver resolving "signed" to content
+// signed with the returned key, "tampered" to other content with the
+// same signature and "unsigned" to content without a signature.
+func signedResolver(t *testing.T) (*FakeResolver, []byte) {
+	t.Helper()
+	sv, _, err := signature.NewDefaultECDSASignerVerifier()
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	sig, err := sv.SignMessage(bytes.NewReader([]byte("some content")))
+	if err != nil {
+		t.Fatalf("error signing content: %v", err)
+	}
+	pub, err := sv.PublicKey()
+	if err != nil {
+		t.Fatalf("error getting public key: %v", err)
+	}
+	pemKey, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+	if err != nil {
+		t.Fatalf("error encoding public key: %v", err)
+	}
+	annotations := map[string]string{resolutioncommon.AnnotationKeySignature: base64.StdEncoding.EncodeToString(sig)}
+	return &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"signed":   {Content: "some content", AnnotationMap: annotations},
+		"tampered": {Content: "other content", AnnotationMap: annotations},
+		"unsigned": {Content: "some content"},
+	}}, pemKey
+}
+
+func TestVerifierContext(t *testing.T) {
+	if verifier := GetVerifierFromContext(context.Background()); verifier != nil {
+		t.Errorf("expected no verifier but got %v", verifier)
+	}
+	verifier := &PublicKeyVerifier{}
+	ctx := InjectVerifierToContext(context.Background(), verifier)
+	if got := GetVerifierFromContext(ctx); got != verifier {
+		t.Errorf("expected the injected verifier but got %v", got)
+	}
+}
+
+func TestNewPublicKeyVerifierInvalid(t *testing.T) {
+	if _, err := NewPublicKeyVerifier([]byte("not a key")); err == nil || !strings.HasPrefix(err.Error(), "invalid public key: ") {
+		t.Errorf("expected an invalid public key error but got %v", err)
+	}
+}
+
+func TestPublicKeyVerifier(t *testing.T) {
+	resolver, pemKey := signedResolver(t)
+	verifier, err := NewPublicKeyVerifier(pemKey)
+	if err != nil {
+		t.Fatalf("unexpected error loading public key: %v", err)
+	}
+	_, otherKey := signedResolver(t)
+	otherVerifier, err := NewPublicKeyVerifier(otherKey)
+	if err != nil {
+		t.Fatalf("unexpected error loading public key: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		param       string
+		verifier    Verifier
+		annotations map[string]string
+		expectedErr string
+	}{{
+		name:     "valid signature",
+		param:    "signed",
+		verifier: verifier,
+	}, {
+		name:        "tampered content",
+		param:       "tampered",
+		verifier:    verifier,
+		expectedErr: "fake resolved resource failed verification: signature doesn't match the resolved content",
+	}, {
+		name:        "other key",
+		param:       "signed",
+		verifier:    otherVerifier,
+		expectedErr: "fake resolved resource failed verification: signature doesn't match the resolved content",
+	}, {
+		name:        "no signature",
+		param:       "unsigned",
+		verifier:    verifier,
+		expectedErr: "fake resolved resource failed verification: resolved resource has no resolution.tekton.dev/signature annotation",
+	}, {
+		name:  "no verifier",
+		param: "tampered",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.verifier != nil {
+				ctx = InjectVerifierToContext(ctx, tc.verifier)
+			}
+			resource, err := DryRun(ctx, resolver, fakeParams(tc.param))
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if resource == nil {
+					t.Fatalf("expected a resolved resource")
+				}
+				return
+			}
+			var verificationErr *resolutioncommon.VerificationError
+			if !errors.As(err, &verificationErr) {
+				t.Fatalf("expected a VerificationError but got %v", err)
+			}
+			if verificationErr.ResolverType != LabelValueFakeResolverType {
+				t.Errorf("expected resolver type %q but got %q", LabelValueFakeResolverType, verificationErr.ResolverType)
+			}
+			if !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q but got %q", tc.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestPublicKeyVerifierDecode(t *testing.T) {
+	sv, _, err := signature.NewDefaultECDSASignerVerifier()
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	// The resource is stored, and signed, base64 encoded.
+	encoded := base64.StdEncoding.EncodeToString([]byte(singleDocument))
+	sig, err := sv.SignMessage(bytes.NewReader([]byte(encoded)))
+	if err != nil {
+		t.Fatalf("error signing content: %v", err)
+	}
+	pub, err := sv.PublicKey()
+	if err != nil {
+		t.Fatalf("error getting public key: %v", err)
+	}
+	pemKey, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+	if err != nil {
+		t.Fatalf("error encoding public key: %v", err)
+	}
+	verifier, err := NewPublicKeyVerifier(pemKey)
+	if err != nil {
+		t.Fatalf("unexpected error creating verifier: %v", err)
+	}
+	resolver := &FakeResolver{ForParam: map[string]*FakeResolvedResource{
+		"encoded": {
+			Content:       encoded,
+			AnnotationMap: map[string]string{resolutioncommon.AnnotationKeySignature: base64.StdEncoding.EncodeToString(sig)},
+		},
+	}}
+
+	resource, err := DryRun(InjectVerifierToContext(context.Background(), verifier), resolver, withDecode(fakeParams("encoded"), "base64"))
+	if err != nil {
+		t.Fatalf("expected the signature to verify against the content before it was decoded but got %v", err)
+	}
+	if string(resource.Data()) != singleDocument {
+		t.Errorf("expected the decoded content but got %q", resource.Data())
+	}
+}
+
+func TestPublicKeyVerifierInvalidSignature(t *testing.T) {
+	_, pemKey := signedResolver(t)
+	verifier, err := NewPublicKeyVerifier(pemKey)
+	if err != nil {
+		t.Fatalf("unexpected error loading public key: %v", err)
+	}
+	resource := &FakeResolvedResource{
+		Content:       "some content",
+		AnnotationMap: map[string]string{resolutioncommon.AnnotationKeySignature: "not base64!"},
+	}
+	err = verifier.Verify(context.Background(), LabelValueFakeResolverType, resource)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid resolution.tekton.dev/signature annotation: ") {
+		t.Errorf("expected an invalid signature annotation error but got %v", err)
+	}
+}
+
+func TestReconcileWithVerifier(t *testing.T) {
+	resolver, pemKey := signedResolver(t)
+	verifier, err := NewPublicKeyVerifier(pemKey)
+	if err != nil {
+		t.Fatalf("unexpected error loading public key: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name           string
+		param          string
+		expectedFailed bool
+	}{{
+		name:  "valid signature",
+		param: "signed",
+	}, {
+		name:           "invalid signature",
+		param:          "tampered",
+		expectedFailed: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := &v1beta1.ResolutionRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rr",
+					Namespace:         "foo",
+					CreationTimestamp: metav1.Time{Time: now},
+					Labels: map[string]string{
+						resolutioncommon.LabelKeyResolverType: LabelValueFakeResolverType,
+					},
+				},
+				Spec: v1beta1.ResolutionRequestSpec{
+					Params: fakeParams(tc.param),
+				},
+			}
+
+			ctx, _ := ttesting.SetupFakeContext(t)
+			testAssets, cancel := getResolverFrameworkController(ctx, t, test.Data{ResolutionRequests: []*v1beta1.ResolutionRequest{rr}}, resolver, setClockOnReconciler, WithVerifier(verifier))
+			defer cancel()
+
+			err := testAssets.Controller.Reconciler.Reconcile(testAssets.Ctx, getRequestName(rr))
+			if tc.expectedFailed {
+				if !controller.IsPermanentError(err) {
+					t.Fatalf("expected a permanent error but got %v", err)
+				}
+				var verificationErr *resolutioncommon.VerificationError
+				if !errors.As(err, &verificationErr) {
+					t.Errorf("expected a VerificationError but got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error reconciling: %v", err)
+			}
+
+			reconciledRR, err := testAssets.Clients.ResolutionRequests.ResolutionV1beta1().ResolutionRequests(rr.Namespace).Get(testAssets.Ctx, rr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("getting updated ResolutionRequest: %v", err)
+			}
+			if failed := reconciledRR.Status.GetCondition(apis.ConditionSucceeded).IsFalse(); failed != tc.expectedFailed {
+				t.Errorf("expected request failed to be %t but got status %v", tc.expectedFailed, reconciledRR.Status)
+			}
+			if !tc.expectedFailed && reconciledRR.Status.Data == "" {
+				t.Errorf("expected the resolved data to be written but got status %v", reconciledRR.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// versionConstraintOperators are the characters that mark a version
+// as a range of versions rather than an exact version.
+const versionConstraintOperators = "<>=!~^, "
+
+// IsVersionConstraint returns true if the given version should be
+// treated as a range of versions rather than an exact version.
+func IsVersionConstraint(v string) bool {
+	return strings.ContainsAny(strings.TrimSpace(v), versionConstraintOperators)
+}
+
+// ParseVersionConstraint parses a version range such as ">=0.2.0 <0.4.0"
+// or "^0.3". Clauses can be separated by commas or whitespace and
+// support the comparison operators (=, !=, >, >=, <, <=) as well as the
+// tilde (~) and caret (^) shorthands.
+func ParseVersionConstraint(constraint string) (goversion.Constraints, error) {
+	var clauses []string
+	operator := ""
+	for _, field := range strings.Fields(strings.ReplaceAll(constraint, ",", " ")) {
+		// Allow a space between an operator and its version, e.g. ">= 0.2".
+		if strings.Trim(field, "<>=!~^") == "" {
+			operator += field
+			continue
+		}
+		clause := operator + field
+		operator = ""
+
+		switch {
+		case strings.HasPrefix(clause, "~>"):
+			clauses = append(clauses, clause)
+		case strings.HasPrefix(clause, "^"), strings.HasPrefix(clause, "~"):
+			expanded, err := expandShorthand(clause)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, expanded...)
+		default:
+			clauses = append(clauses, clause)
+		}
+	}
+	if operator != "" {
+		return nil, fmt.Errorf("operator %q is missing a version", operator)
+	}
+	if len(clauses) == 0 {
+		return nil, fmt.Errorf("no version constraints found")
+	}
+	return goversion.NewConstraint(strings.Join(clauses, ", "))
+}
+
+// expandShorthand converts a caret (^) or tilde (~) clause into the
+// equivalent lower and upper bound clauses.
+func expandShorthand(clause string) ([]string, error) {
+	operator, operand := clause[:1], clause[1:]
+	v, err := goversion.NewVersion(operand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q in constraint %q: %w", operand, clause, err)
+	}
+	segments := v.Segments()
+	// The number of segments the user actually specified determines
+	// which segment is allowed to change, e.g. ~1 vs ~1.2.
+	specified := len(strings.Split(strings.SplitN(strings.SplitN(operand, "-", 2)[0], "+", 2)[0], "."))
+
+	bump := 0
+	switch operator {
+	case "^":
+		// The left-most non-zero segment that was specified may not change.
+		for bump < specified-1 && segments[bump] == 0 {
+			bump++
+		}
+	case "~":
+		if specified > 1 {
+			bump = 1
+		}
+	}
+
+	upper := make([]string, len(segments))
+	for i := range segments {
+		switch {
+		case i < bump:
+			upper[i] = fmt.Sprint(segments[i])
+		case i == bump:
+			upper[i] = fmt.Sprint(segments[i] + 1)
+		default:
+			upper[i] = "0"
+		}
+	}
+	return []string{">= " + operand, "< " + strings.Join(upper, ".")}, nil
+}
+
+// SelectVersion returns the highest of the given versions that satisfies
+// the constraints, or the highest of them all if there are none.
+// Versions that can't be parsed are skipped.
+func SelectVersion(constraints goversion.Constraints, versions []string) (string, bool) {
+	var best *goversion.Version
+	for _, candidate := range versions {
+		v, err := goversion.NewVersion(candidate)
+		if err != nil {
+			// Skip any versions published in a format we can't compare.
+			continue
+		}
+		if constraints.Check(v) && (best == nil || v.GreaterThan(best)) {
+			best = v
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.Original(), true
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import "testing"
+
+func TestSelectVersion(t *testing.T) {
+	versions := []string{"v1.0.0", "v1.2.0", "v1.10.0", "v2.0.0", "latest"}
+	for _, tc := range []struct {
+		constraint string
+		expected   string
+		found      bool
+	}{
+		{constraint: "", expected: "v2.0.0", found: true},
+		{constraint: "^1.0", expected: "v1.10.0", found: true},
+		{constraint: "~1.2", expected: "v1.2.0", found: true},
+		{constraint: ">= 1.0, <1.5", expected: "v1.2.0", found: true},
+		{constraint: "^3", found: false},
+	} {
+		t.Run(tc.constraint, func(t *testing.T) {
+			var version string
+			var found bool
+			if tc.constraint == "" {
+				version, found = SelectVersion(nil, versions)
+			} else {
+				if !IsVersionConstraint(tc.constraint) {
+					t.Fatalf("expected %q to be a version constraint", tc.constraint)
+				}
+				constraints, err := ParseVersionConstraint(tc.constraint)
+				if err != nil {
+					t.Fatalf("unexpected error parsing constraint: %v", err)
+				}
+				version, found = SelectVersion(constraints, versions)
+			}
+			if version != tc.expected || found != tc.found {
+				t.Errorf("expected %q, %t but got %q, %t", tc.expected, tc.found, version, found)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{">=", "^v.1", ","} {
+		if _, err := ParseVersionConstraint(constraint); err == nil {
+			t.Errorf("expected an error parsing %q", constraint)
+		}
+	}
+	if IsVersionConstraint("1.2.3") {
+		t.Errorf("expected 1.2.3 to be an exact version")
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+
+// ConfigEndpoint is the configuration field name for the endpoint of
+// the Cloud Storage JSON API, e.g. a Private Service Connect endpoint.
+// Defaults to https://storage.googleapis.com.
+const ConfigEndpoint = "endpoint"
+
+// ConfigFetchTimeout is the configuration field name for the maximum
+// duration of a single request for an object. Defaults to 1m.
+const ConfigFetchTimeout = framework.ConfigFetchTimeout
+
+// ConfigMaxResponseSize is the configuration field name for the maximum
+// size of a fetched object, e.g. "10Mi". Defaults to 10Mi.
+const ConfigMaxResponseSize = framework.ConfigMaxResponseSize
+
+// ConfigWorkloadIdentityBuckets is the configuration field name for the
+// buckets, as a comma-separated list of names or patterns such as
+// "tekton-*", whose objects are fetched with the resolver's own
+// identity, e.g. its GKE workload identity, when a request doesn't set
+// the secret param. Objects in other buckets are then fetched
+// anonymously, so that a request can't read what only the resolver's
+// identity has access to. "*" lets every bucket be fetched with it. It
+// can only be set for the whole cluster.
+const ConfigWorkloadIdentityBuckets = "workload-identity-buckets"
@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+// ParamBucket is the parameter defining the bucket holding the object.
+const ParamBucket = "bucket"
+
+// ParamObject is the parameter defining the name of the object to
+// fetch.
+const ParamObject = "object"
+
+// ParamGeneration is the parameter pinning the fetch to a specific
+// generation of the object, for reproducible resolutions from buckets
+// with object versioning. The live version is fetched when it isn't
+// given.
+const ParamGeneration = "generation"
+
+// ParamSecret is the parameter defining the name of a secret, in the
+// namespace of the resolution request, holding the key of the service
+// account used to authenticate requests. When it isn't given the
+// resolver's own workload identity is used for the buckets in the
+// workload-identity-buckets config, and requests are sent anonymously
+// otherwise.
+const ParamSecret = "secret"
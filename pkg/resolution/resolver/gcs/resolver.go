@@ -0,0 +1,626 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// LabelValueGCSResolverType is the value to use for the
+	// resolution.tekton.dev/type label on resource requests
+	LabelValueGCSResolverType string = "gcs"
+
+	disabledError = "cannot handle resolution request, enable-gcs-resolver feature flag not true"
+
+	// defaultEndpoint is the endpoint of the Cloud Storage JSON API used
+	// when the endpoint config isn't set.
+	defaultEndpoint = "https://storage.googleapis.com"
+
+	// serviceAccountKeySecretKey is the key in the secret named by the
+	// secret param holding the JSON key of a service account.
+	serviceAccountKeySecretKey = "key.json"
+
+	// readOnlyScope is the OAuth scope of the tokens used to fetch
+	// objects.
+	readOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+	// maxObjectNameLength is the maximum length in bytes of an object
+	// name.
+	maxObjectNameLength = 1024
+)
+
+// bucketRegex matches valid bucket names, including those with dots
+// that may be up to 222 characters long.
+var bucketRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,220}[a-z0-9]$`)
+
+var _ framework.Resolver = &Resolver{}
+
+// Resolver implements a framework.Resolver that can fetch objects from
+// Google Cloud Storage buckets.
+type Resolver struct {
+	kubeClientSet kubernetes.Interface
+	// httpClient is the client used to request objects. Defaults to
+	// the framework's shared client.
+	httpClient *http.Client
+	// defaultTokenSource returns the token source of the resolver's own
+	// identity, e.g. its GKE workload identity, or nil if it has none.
+	// Defaults to looking up Application Default Credentials.
+	defaultTokenSource func(context.Context) (oauth2.TokenSource, error)
+
+	// tokenSourceMu guards the default token source, which is only
+	// looked up once.
+	tokenSourceMu     sync.Mutex
+	tokenSourceLoaded bool
+	tokenSource       oauth2.TokenSource
+}
+
+// Initialize sets up the kubernetes client used to read service account
+// keys.
+func (r *Resolver) Initialize(ctx context.Context) error {
+	r.kubeClientSet = client.Get(ctx)
+	return nil
+}
+
+// GetName returns a string name to refer to this resolver by.
+func (r *Resolver) GetName(context.Context) string {
+	return "GCS"
+}
+
+// GetConfigName returns the name of the gcs resolver's configmap.
+func (r *Resolver) GetConfigName(context.Context) string {
+	return "gcs-resolver-config"
+}
+
+// GetSelector returns a map of labels to match requests to this resolver.
+func (r *Resolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{
+		common.LabelKeyResolverType: LabelValueGCSResolverType,
+	}
+}
+
+var _ framework.ClusterScopedConfig = &Resolver{}
+
+// ClusterScopedConfigKeys returns the workload-identity-buckets config,
+// which namespaces mustn't be able to extend to buckets only the
+// resolver's own identity can read.
+func (r *Resolver) ClusterScopedConfigKeys(context.Context) []string {
+	return []string{ConfigWorkloadIdentityBuckets}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver.
+func (r *Resolver) GetParamSchema(context.Context) []framework.ParamSchema {
+	return []framework.ParamSchema{{
+		Name:        ParamBucket,
+		Description: "The bucket holding the object.",
+		Required:    true,
+	}, {
+		Name:        ParamObject,
+		Description: "The name of the object to fetch.",
+		Required:    true,
+	}, {
+		Name:        ParamGeneration,
+		Description: "The generation of the object to fetch. The live version is fetched when omitted.",
+	}, {
+		Name:        ParamSecret,
+		Description: "The name of a secret, in the namespace of the request, holding the key of the service account used to authenticate requests.",
+	}}
+}
+
+// ValidateParams ensures parameters from a request are as expected. The
+// service account key secret they reference is only read when the object
+// is resolved.
+func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if r.isDisabled(ctx) {
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	_, err := newRequestOptions(ctx, framework.StringParams(params))
+	return err
+}
+
+// Resolve uses the given params to fetch the requested object.
+func (r *Resolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	if r.isDisabled(ctx) {
+		return nil, common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	opts, err := newRequestOptions(ctx, framework.StringParams(params))
+	if err != nil {
+		return nil, err
+	}
+	ts, err := r.getTokenSource(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.fetch(ctx, opts, ts)
+}
+
+// requestOptions are the settings used to fetch a single object.
+type requestOptions struct {
+	bucket string
+	object string
+	// generation is the generation of the object to fetch, or zero for
+	// the live version.
+	generation int64
+	endpoint   *url.URL
+	secret     string
+	// workloadIdentity is whether the object may be fetched with the
+	// resolver's own identity when no secret is given.
+	workloadIdentity bool
+	timeout          time.Duration
+	// maxResponseSize is the maximum size in bytes of the object.
+	maxResponseSize int64
+}
+
+// newRequestOptions returns the settings for fetching an object given
+// the resolution's params and the resolver's config.
+func newRequestOptions(ctx context.Context, params map[string]string) (requestOptions, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	opts := requestOptions{
+		bucket: params[ParamBucket],
+		object: params[ParamObject],
+	}
+
+	var missingParams []string
+	for _, p := range []struct {
+		name  string
+		value string
+	}{{ParamBucket, opts.bucket}, {ParamObject, opts.object}} {
+		if p.value == "" {
+			missingParams = append(missingParams, p.name)
+		}
+	}
+	if len(missingParams) > 0 {
+		return opts, fmt.Errorf("missing required gcs resolver params: %s", strings.Join(missingParams, ", "))
+	}
+	if !bucketRegex.MatchString(opts.bucket) || strings.Contains(opts.bucket, "..") {
+		return opts, fmt.Errorf("invalid %s param %q: must be 3 to 222 lowercase letters, numbers, dots, hyphens or underscores, starting and ending with a letter or number", ParamBucket, opts.bucket)
+	}
+	if len(opts.object) > maxObjectNameLength {
+		return opts, fmt.Errorf("invalid %s param: must be at most %d bytes long", ParamObject, maxObjectNameLength)
+	}
+	if strings.ContainsAny(opts.object, "\r\n") || opts.object == "." || opts.object == ".." {
+		return opts, fmt.Errorf("invalid %s param %q: not a valid object name", ParamObject, opts.object)
+	}
+	if generation, ok := params[ParamGeneration]; ok {
+		g, err := strconv.ParseInt(generation, 10, 64)
+		if err != nil || g <= 0 {
+			return opts, fmt.Errorf("invalid %s param %q: must be a positive integer", ParamGeneration, generation)
+		}
+		opts.generation = g
+	}
+	if secret, ok := params[ParamSecret]; ok {
+		if secret == "" {
+			return opts, fmt.Errorf("%s param must not be empty", ParamSecret)
+		}
+		opts.secret = secret
+	}
+
+	endpoint := conf[ConfigEndpoint]
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return opts, fmt.Errorf("invalid %s config: %w", ConfigEndpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return opts, fmt.Errorf("invalid %s config %q: scheme must be http or https", ConfigEndpoint, endpoint)
+	}
+	if u.Host == "" {
+		return opts, fmt.Errorf("invalid %s config %q: missing host", ConfigEndpoint, endpoint)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return opts, fmt.Errorf("invalid %s config %q: must not include a path or query", ConfigEndpoint, endpoint)
+	}
+	opts.endpoint = u
+
+	workloadIdentity, err := matchesBucket(opts.bucket, conf[ConfigWorkloadIdentityBuckets])
+	if err != nil {
+		return opts, err
+	}
+	opts.workloadIdentity = workloadIdentity
+
+	fetchOpts, err := framework.FetchOptionsFromConfig(conf)
+	if err != nil {
+		return opts, err
+	}
+	opts.timeout, opts.maxResponseSize = fetchOpts.Timeout, fetchOpts.MaxResponseSize
+	return opts, nil
+}
+
+// matchesBucket returns whether the bucket matches one of the
+// comma-separated bucket names or patterns of the
+// workload-identity-buckets config.
+func matchesBucket(bucket, config string) (bool, error) {
+	matched := false
+	for _, pattern := range strings.Split(config, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		ok, err := path.Match(pattern, bucket)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s config: invalid bucket pattern %q: %w", ConfigWorkloadIdentityBuckets, pattern, err)
+		}
+		matched = matched || ok
+	}
+	return matched, nil
+}
+
+// objectURI returns the gs:// uri of the object, with the generation
+// appended if one was requested, used to refer to it in errors
+// regardless of the endpoint it's fetched from.
+func (o requestOptions) objectURI() string {
+	uri := fmt.Sprintf("gs://%s/%s", o.bucket, o.object)
+	if o.generation != 0 {
+		uri += fmt.Sprintf("#%d", o.generation)
+	}
+	return uri
+}
+
+// objectURL returns the url of the object's content in the JSON API.
+func (o requestOptions) objectURL() *url.URL {
+	u := *o.endpoint
+	prefix := "/storage/v1/b/" + o.bucket + "/o/"
+	u.Path = prefix + o.object
+	// The object name is a single path segment however many slashes it
+	// holds.
+	u.RawPath = prefix + url.PathEscape(o.object)
+	query := url.Values{"alt": []string{"media"}}
+	if o.generation != 0 {
+		query.Set("generation", strconv.FormatInt(o.generation, 10))
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// getTokenSource returns the source of the tokens used to authenticate
+// requests: from the configured credential provider if there is one,
+// otherwise from the service account key in the secret named by the
+// secret param, or the resolver's own identity when no secret is given
+// and the bucket is in the workload-identity-buckets config. A nil token
+// source means requests are sent anonymously.
+func (r *Resolver) getTokenSource(ctx context.Context, opts requestOptions) (oauth2.TokenSource, error) {
+	if provider := framework.GetCredentialProviderFromContext(ctx); provider != nil {
+		token, err := provider.Token(ctx, opts.endpoint.String(), framework.CredentialRequest{
+			Namespace: common.RequestNamespace(ctx),
+			Secret:    opts.secret,
+			SecretKey: secretKey(opts),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting token for %s: %w", opts.endpoint, err)
+		}
+		if token == "" {
+			return nil, nil
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token, TokenType: "Bearer"}), nil
+	}
+	if opts.secret != "" {
+		return r.secretTokenSource(ctx, opts)
+	}
+	if !opts.workloadIdentity {
+		return nil, nil
+	}
+	return r.workloadTokenSource(ctx)
+}
+
+// secretKey returns the key of the secret named by the secret param
+// holding the service account key, or an empty string if there isn't
+// one.
+func secretKey(opts requestOptions) string {
+	if opts.secret == "" {
+		return ""
+	}
+	return serviceAccountKeySecretKey
+}
+
+// secretTokenSource returns the token source of the service account
+// whose key is held by the secret named by the secret param. The key
+// itself is never included in returned errors.
+func (r *Resolver) secretTokenSource(ctx context.Context, opts requestOptions) (oauth2.TokenSource, error) {
+	if r.kubeClientSet == nil {
+		return nil, fmt.Errorf("cannot read service account key secret %s: no kubernetes client available", opts.secret)
+	}
+	namespace := common.RequestNamespace(ctx)
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClientSet, namespace, opts.secret, "gcs credentials")
+	if err != nil {
+		return nil, err
+	}
+	key, ok := secret.Data[serviceAccountKeySecretKey]
+	if !ok {
+		return nil, fmt.Errorf("cannot get gcs credentials, key %s not found in secret %s in namespace %s", serviceAccountKeySecretKey, opts.secret, namespace)
+	}
+	creds, err := google.CredentialsFromJSON(ctx, key, readOnlyScope)
+	if err != nil {
+		// The error is deliberately dropped since it may contain part of
+		// the key.
+		return nil, fmt.Errorf("cannot parse service account key in secret %s in namespace %s", opts.secret, namespace)
+	}
+	return creds.TokenSource, nil
+}
+
+// workloadTokenSource returns the token source of the resolver's own
+// identity, looking it up the first time it is needed.
+func (r *Resolver) workloadTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	r.tokenSourceMu.Lock()
+	defer r.tokenSourceMu.Unlock()
+	if r.tokenSourceLoaded {
+		return r.tokenSource, nil
+	}
+	find := r.defaultTokenSource
+	if find == nil {
+		find = findDefaultTokenSource
+	}
+	ts, err := find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.tokenSource, r.tokenSourceLoaded = ts, true
+	return ts, nil
+}
+
+// findDefaultTokenSource returns the token source of the Application
+// Default Credentials, e.g. the GKE workload identity of the resolver's
+// pod, or nil if there aren't any so that requests are sent anonymously,
+// which is enough for public objects.
+func findDefaultTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	// The token source outlives the resolution that looked it up.
+	creds, err := google.FindDefaultCredentials(context.Background(), readOnlyScope)
+	if err != nil {
+		logging.FromContext(ctx).Infof("No default credentials found, fetching gcs objects anonymously: %v", err)
+		return nil, nil
+	}
+	return creds.TokenSource, nil
+}
+
+// gcsError is the body of an error response from the JSON API.
+type gcsError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readGCSError returns the message of a failed response, falling back
+// to its status when the body couldn't be parsed.
+func readGCSError(resp *http.Response) string {
+	var e gcsError
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err == nil {
+		_ = json.Unmarshal(body, &e)
+	}
+	if e.Error.Message == "" {
+		return resp.Status
+	}
+	return e.Error.Message
+}
+
+// fetch performs a GET request for the object, authenticated with a
+// token from the given source if there is one, and returns it.
+func (r *Resolver) fetch(ctx context.Context, opts requestOptions, ts oauth2.TokenSource) (_ *ResolvedGCSResource, err error) {
+	ctx, span := framework.StartSpan(ctx, "gcs.Get", trace.StringAttribute("object", opts.objectURI()))
+	defer func() { framework.EndSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	objectURL := opts.objectURL()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for %s: %w", opts.objectURI(), err)
+	}
+	framework.SetUserAgent(ctx, req)
+	if ts != nil {
+		token, err := ts.Token()
+		if err != nil {
+			return nil, tokenError(ctx, opts, err)
+		}
+		token.SetAuthHeader(req)
+	}
+
+	httpClient := r.httpClient
+	if httpClient == nil {
+		httpClient = framework.HTTPClient(ctx)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, framework.TimeoutError(opts.objectURI(), opts.timeout)
+		}
+		// The connection failed, e.g. it was reset, which is unlikely
+		// to happen again.
+		return nil, &common.TransientError{
+			Resource: opts.objectURI(),
+			Original: fmt.Errorf("error requesting %s from %s: %w", opts.objectURI(), opts.endpoint.Host, err),
+		}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, &common.ResolutionNotFoundError{
+			Resource: opts.objectURI(),
+			Original: fmt.Errorf("object %s does not exist (%s)", opts.objectURI(), readGCSError(resp)),
+		}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, &common.PermissionDeniedError{
+			Resource: opts.objectURI(),
+			Original: fmt.Errorf("access to %s was denied (%s), %s", opts.objectURI(), readGCSError(resp), deniedHint(ctx, opts, ts)),
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &common.RateLimitedError{
+			Resource:   opts.objectURI(),
+			RetryAfter: framework.RetryAfter(resp, time.Now()),
+			Original:   fmt.Errorf("request for %s was rate limited (%s)", opts.objectURI(), readGCSError(resp)),
+		}
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, &common.TransientError{
+			Resource: opts.objectURI(),
+			Original: fmt.Errorf("request for %s failed with status code %d (%s)", opts.objectURI(), resp.StatusCode, readGCSError(resp)),
+		}
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		return nil, fmt.Errorf("request for %s failed with status code %d (%s)", opts.objectURI(), resp.StatusCode, readGCSError(resp))
+	}
+
+	body, err := framework.ReadResponseBody(resp, opts.maxResponseSize)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, framework.TimeoutError(opts.objectURI(), opts.timeout)
+		}
+		return nil, fmt.Errorf("error reading %s: %w", opts.objectURI(), err)
+	}
+	return &ResolvedGCSResource{
+		Content:    body,
+		Bucket:     opts.bucket,
+		Object:     opts.object,
+		Generation: resp.Header.Get("X-Goog-Generation"),
+		URL:        objectURL.String(),
+	}, nil
+}
+
+// tokenError returns the error for a token that couldn't be obtained:
+// a PermissionDeniedError when the token endpoint rejected the
+// credentials, and a TransientError otherwise, e.g. when the metadata
+// server couldn't be reached.
+func tokenError(ctx context.Context, opts requestOptions, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return framework.TimeoutError(opts.objectURI(), opts.timeout)
+	}
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.Response != nil &&
+		retrieveErr.Response.StatusCode >= http.StatusBadRequest && retrieveErr.Response.StatusCode < http.StatusInternalServerError {
+		return &common.PermissionDeniedError{
+			Resource: opts.objectURI(),
+			Original: fmt.Errorf("error getting a token to fetch %s: the credentials were rejected with status code %d, %s", opts.objectURI(), retrieveErr.Response.StatusCode, deniedHint(ctx, opts, nil)),
+		}
+	}
+	return &common.TransientError{
+		Resource: opts.objectURI(),
+		Original: fmt.Errorf("error getting a token to fetch %s: %w", opts.objectURI(), err),
+	}
+}
+
+// deniedHint suggests how to get access to an object given where the
+// credentials that were denied came from.
+func deniedHint(ctx context.Context, opts requestOptions, ts oauth2.TokenSource) string {
+	switch {
+	case framework.GetCredentialProviderFromContext(ctx) != nil:
+		return "check the credentials returned by the resolvers' credential provider"
+	case opts.secret != "":
+		return fmt.Sprintf("check the service account key in secret %s and the permissions granted to the service account", opts.secret)
+	case ts == nil && opts.workloadIdentity:
+		return fmt.Sprintf("anonymous requests aren't allowed, set the %s param to a secret holding a service account key or give the resolver a workload identity", ParamSecret)
+	case ts == nil:
+		return fmt.Sprintf("anonymous requests aren't allowed, set the %s param to a secret holding a service account key or have the bucket added to the %s config", ParamSecret, ConfigWorkloadIdentityBuckets)
+	default:
+		return "check the permissions granted to the resolver's workload identity"
+	}
+}
+
+// ResolvedGCSResource wraps the data we want to return to Pipelines
+type ResolvedGCSResource struct {
+	Content []byte
+	Bucket  string
+	Object  string
+	// Generation is the generation of the object that was fetched, as
+	// reported by Cloud Storage.
+	Generation string
+	// URL is the url the object was fetched from.
+	URL string
+}
+
+var _ framework.ResolvedResource = &ResolvedGCSResource{}
+
+// Data returns the bytes of the fetched object.
+func (rr *ResolvedGCSResource) Data() []byte {
+	return rr.Content
+}
+
+// Annotations returns the url the object was fetched from along with
+// the digest of its content, its generation and its provenance.
+func (rr *ResolvedGCSResource) Annotations() map[string]string {
+	source := rr.Source()
+	coordinates := map[string]string{
+		"bucket": rr.Bucket,
+		"object": rr.Object,
+	}
+	annotations := map[string]string{
+		AnnotationKeyURL:    rr.URL,
+		AnnotationKeyDigest: "sha256:" + source.Digest["sha256"],
+	}
+	if rr.Generation != "" {
+		coordinates["generation"] = rr.Generation
+		annotations[AnnotationKeyGeneration] = rr.Generation
+	}
+	annotations[common.AnnotationKeyProvenance] = common.Provenance{
+		ResolverType: LabelValueGCSResolverType,
+		URI:          source.URI,
+		Digest:       source.Digest,
+		Coordinates:  coordinates,
+	}.AnnotationValue()
+	return annotations
+}
+
+// Source is the source reference of the remote data that records the
+// gs:// uri of the object and the digest of its content.
+func (rr *ResolvedGCSResource) Source() *v1beta1.ConfigSource {
+	sum := sha256.Sum256(rr.Content)
+	return &v1beta1.ConfigSource{
+		URI: fmt.Sprintf("gs://%s/%s", rr.Bucket, rr.Object),
+		Digest: map[string]string{
+			"sha256": hex.EncodeToString(sum[:]),
+		},
+	}
+}
+
+func (r *Resolver) isDisabled(ctx context.Context) bool {
+	cfg := resolverconfig.FromContextOrDefaults(ctx)
+	if cfg.FeatureFlags.EnableGCSResolver {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,664 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
+	"github.com/tektoncd/pipeline/test/diff"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testContent = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: foo\n"
+
+	// workloadToken is the token of the resolver's own identity in
+	// tests.
+	workloadToken = "workload-token"
+)
+
+func TestGetSelector(t *testing.T) {
+	resolver := Resolver{}
+	sel := resolver.GetSelector(resolverContext())
+	if typ, has := sel[resolutioncommon.LabelKeyResolverType]; !has {
+		t.Fatalf("unexpected selector: %v", sel)
+	} else if typ != LabelValueGCSResolverType {
+		t.Fatalf("unexpected type: %q", typ)
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcs-key", Namespace: "foo-ns"},
+		Data:       map[string][]byte{serviceAccountKeySecretKey: serviceAccountKey(t, "https://oauth2.example.com/token")},
+	}
+	incompleteSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "incomplete", Namespace: "foo-ns"},
+		Data:       map[string][]byte{"credentials.json": []byte("{}")},
+	}
+	invalidSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "foo-ns"},
+		Data:       map[string][]byte{serviceAccountKeySecretKey: []byte(`{"private_key": "not json`)},
+	}
+	validParams := map[string]string{ParamBucket: "tasks", ParamObject: "tasks/task.yaml"}
+
+	testCases := []struct {
+		name         string
+		params       map[string]string
+		config       map[string]string
+		expectedErr  string
+		errOnResolve bool
+	}{
+		{
+			name:   "valid",
+			params: validParams,
+		},
+		{
+			name:   "with generation and secret",
+			params: map[string]string{ParamBucket: "tasks.example.com", ParamObject: "task.yaml", ParamGeneration: "1700000000000000", ParamSecret: "gcs-key"},
+			config: map[string]string{ConfigEndpoint: "http://localhost:4443"},
+		},
+		{
+			name:        "missing everything",
+			params:      map[string]string{},
+			expectedErr: "missing required gcs resolver params: bucket, object",
+		},
+		{
+			name:        "invalid bucket",
+			params:      map[string]string{ParamBucket: "My Bucket", ParamObject: "task.yaml"},
+			expectedErr: `invalid bucket param "My Bucket": must be 3 to 222 lowercase letters, numbers, dots, hyphens or underscores, starting and ending with a letter or number`,
+		},
+		{
+			name:        "object with newline",
+			params:      map[string]string{ParamBucket: "tasks", ParamObject: "task\n.yaml"},
+			expectedErr: `invalid object param "task\n.yaml": not a valid object name`,
+		},
+		{
+			name:        "object too long",
+			params:      map[string]string{ParamBucket: "tasks", ParamObject: strings.Repeat("a", 1025)},
+			expectedErr: "invalid object param: must be at most 1024 bytes long",
+		},
+		{
+			name:        "invalid generation",
+			params:      map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml", ParamGeneration: "latest"},
+			expectedErr: `invalid generation param "latest": must be a positive integer`,
+		},
+		{
+			name:        "endpoint with path",
+			params:      validParams,
+			config:      map[string]string{ConfigEndpoint: "https://storage.example.com/storage/v1"},
+			expectedErr: `invalid endpoint config "https://storage.example.com/storage/v1": must not include a path or query`,
+		},
+		{
+			name:        "invalid max response size",
+			params:      validParams,
+			config:      map[string]string{ConfigMaxResponseSize: "0"},
+			expectedErr: `invalid max-response-size config: must be greater than zero, got "0"`,
+		},
+		{
+			name:        "invalid workload identity bucket pattern",
+			params:      validParams,
+			config:      map[string]string{ConfigWorkloadIdentityBuckets: "tasks,[tasks"},
+			expectedErr: `invalid workload-identity-buckets config: invalid bucket pattern "[tasks": syntax error in pattern`,
+		},
+		{
+			name:         "missing secret",
+			params:       map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml", ParamSecret: "other"},
+			expectedErr:  "cannot get gcs credentials, secret other not found in namespace foo-ns",
+			errOnResolve: true,
+		},
+		{
+			name:         "incomplete secret",
+			params:       map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml", ParamSecret: "incomplete"},
+			expectedErr:  "cannot get gcs credentials, key key.json not found in secret incomplete in namespace foo-ns",
+			errOnResolve: true,
+		},
+		{
+			name:         "invalid key",
+			params:       map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml", ParamSecret: "invalid"},
+			expectedErr:  "cannot parse service account key in secret invalid in namespace foo-ns",
+			errOnResolve: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(keySecret, incompleteSecret, invalidSecret)}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+			ctx = framework.InjectResolverConfigToContext(ctx, tc.config)
+			err := resolver.ValidateParams(ctx, toParams(tc.params))
+			if tc.errOnResolve {
+				// The secret is only read when resolving.
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				_, err = resolver.Resolve(ctx, toParams(tc.params))
+			}
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestValidateParamsNoNetwork(t *testing.T) {
+	trap := frtesting.NewNetworkTrap(t)
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcs-key", Namespace: "foo-ns"},
+		Data:       map[string][]byte{serviceAccountKeySecretKey: serviceAccountKey(t, trap.URL()+"/token")},
+	}
+	resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(keySecret)}
+	ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+	ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{ConfigEndpoint: trap.URL()})
+	params := map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml", ParamSecret: "gcs-key"}
+	if err := resolver.ValidateParams(ctx, toParams(params)); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	trap.ExpectNoConnections(t)
+}
+
+func TestValidateParamsDisabled(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), toParams(map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml"}))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolveDisabled(t *testing.T) {
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), toParams(map[string]string{ParamBucket: "tasks", ParamObject: "task.yaml"}))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.Form.Get("assertion") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "key-token", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	defer tokenServer.Close()
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcs-key", Namespace: "foo-ns"},
+		Data:       map[string][]byte{serviceAccountKeySecretKey: serviceAccountKey(t, tokenServer.URL)},
+	}
+
+	testCases := []struct {
+		name       string
+		object     string
+		generation string
+		secret     string
+		anonymous  bool
+		// buckets is the workload-identity-buckets config.
+		buckets       string
+		expectedPath  string
+		expectedQuery string
+		expectedToken string
+	}{
+		{
+			name:          "workload identity",
+			object:        "task.yaml",
+			buckets:       "other-tasks, shared-tasks",
+			expectedPath:  "/storage/v1/b/shared-tasks/o/task.yaml",
+			expectedQuery: "alt=media",
+			expectedToken: workloadToken,
+		},
+		{
+			name:          "bucket not in workload-identity-buckets",
+			object:        "task.yaml",
+			buckets:       "other-tasks",
+			expectedPath:  "/storage/v1/b/shared-tasks/o/task.yaml",
+			expectedQuery: "alt=media",
+		},
+		{
+			name:          "anonymous",
+			object:        "task.yaml",
+			anonymous:     true,
+			buckets:       "*",
+			expectedPath:  "/storage/v1/b/shared-tasks/o/task.yaml",
+			expectedQuery: "alt=media",
+		},
+		{
+			name:          "service account key",
+			object:        "task.yaml",
+			secret:        "gcs-key",
+			expectedPath:  "/storage/v1/b/shared-tasks/o/task.yaml",
+			expectedQuery: "alt=media",
+			expectedToken: "key-token",
+		},
+		{
+			name:          "escaped object with generation",
+			object:        "tasks/my task+v1.yaml",
+			generation:    "1700000000000000",
+			buckets:       "shared-*",
+			expectedPath:  "/storage/v1/b/shared-tasks/o/tasks%2Fmy%20task+v1.yaml",
+			expectedQuery: "alt=media&generation=1700000000000000",
+			expectedToken: workloadToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.EscapedPath() != tc.expectedPath || r.URL.RawQuery != tc.expectedQuery {
+					gcsErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unexpected request %s?%s", r.URL.EscapedPath(), r.URL.RawQuery))
+					return
+				}
+				if ua := r.Header.Get("User-Agent"); ua != framework.DefaultUserAgent {
+					gcsErrorResponse(w, http.StatusBadRequest, "unexpected user agent "+ua)
+					return
+				}
+				expectedAuth := ""
+				if tc.expectedToken != "" {
+					expectedAuth = "Bearer " + tc.expectedToken
+				}
+				if auth := r.Header.Get("Authorization"); auth != expectedAuth {
+					gcsErrorResponse(w, http.StatusUnauthorized, "unexpected authorization "+auth)
+					return
+				}
+				w.Header().Set("X-Goog-Generation", "1700000000000000")
+				fmt.Fprint(w, testContent)
+			}))
+			defer svr.Close()
+
+			resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(keySecret)}
+			if tc.anonymous {
+				resolver.defaultTokenSource = anonymous
+			} else {
+				resolver.defaultTokenSource = workloadIdentity
+			}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+			ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{ConfigEndpoint: svr.URL, ConfigWorkloadIdentityBuckets: tc.buckets})
+			params := map[string]string{ParamBucket: "shared-tasks", ParamObject: tc.object}
+			if tc.generation != "" {
+				params[ParamGeneration] = tc.generation
+			}
+			if tc.secret != "" {
+				params[ParamSecret] = tc.secret
+			}
+			output, err := resolver.Resolve(ctx, toParams(params))
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(testContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+			}
+
+			annotations := output.Annotations()
+			if d := cmp.Diff(svr.URL+tc.expectedPath+"?"+tc.expectedQuery, annotations[AnnotationKeyURL]); d != "" {
+				t.Errorf("unexpected url annotation: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff("sha256:"+sha256Of(testContent), annotations[AnnotationKeyDigest]); d != "" {
+				t.Errorf("unexpected digest annotation: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff("1700000000000000", annotations[AnnotationKeyGeneration]); d != "" {
+				t.Errorf("unexpected generation annotation: %s", diff.PrintWantGot(d))
+			}
+			provenance, err := resolutioncommon.ParseProvenance(annotations[resolutioncommon.AnnotationKeyProvenance])
+			if err != nil {
+				t.Fatalf("unexpected error parsing provenance: %v", err)
+			}
+			expectedProvenance := &resolutioncommon.Provenance{
+				ResolverType: LabelValueGCSResolverType,
+				URI:          "gs://shared-tasks/" + tc.object,
+				Digest:       map[string]string{"sha256": sha256Of(testContent)},
+				Coordinates:  map[string]string{"bucket": "shared-tasks", "object": tc.object, "generation": "1700000000000000"},
+			}
+			if d := cmp.Diff(expectedProvenance, provenance); d != "" {
+				t.Errorf("unexpected provenance: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveWithCredentialProvider(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer provided-token" {
+			gcsErrorResponse(w, http.StatusUnauthorized, "unexpected authorization "+auth)
+			return
+		}
+		fmt.Fprint(w, testContent)
+	}))
+	defer svr.Close()
+
+	provider := &fakeCredentialProvider{token: "provided-token"}
+	resolver := Resolver{defaultTokenSource: workloadIdentity}
+	ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+	ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{ConfigEndpoint: svr.URL})
+	ctx = framework.InjectCredentialProviderToContext(ctx, provider)
+	params := toParams(map[string]string{ParamBucket: "shared-tasks", ParamObject: "task.yaml", ParamSecret: "external-key"})
+
+	// The secret is resolved by the provider so it needn't exist.
+	if err := resolver.ValidateParams(ctx, params); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	output, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if d := cmp.Diff(testContent, string(output.Data())); d != "" {
+		t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+	}
+	expectedRequest := framework.CredentialRequest{Namespace: "foo-ns", Secret: "external-key", SecretKey: serviceAccountKeySecretKey}
+	if d := cmp.Diff(expectedRequest, provider.request); d != "" {
+		t.Errorf("unexpected credential request: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		body      string
+		anonymous bool
+		// buckets is the workload-identity-buckets config, shared-tasks
+		// when unset.
+		buckets             string
+		timeout             bool
+		expectedErr         string
+		expectedNotFound    bool
+		expectedDenied      bool
+		expectedTransient   bool
+		expectedRateLimited bool
+		expectedTimeout     bool
+	}{
+		{
+			name:             "missing object",
+			status:           http.StatusNotFound,
+			body:             `{"error": {"code": 404, "message": "No such object: shared-tasks/task.yaml"}}`,
+			expectedErr:      "object gs://shared-tasks/task.yaml does not exist (No such object: shared-tasks/task.yaml)",
+			expectedNotFound: true,
+		},
+		{
+			name:           "access denied",
+			status:         http.StatusForbidden,
+			body:           `{"error": {"code": 403, "message": "resolver@example.iam.gserviceaccount.com does not have storage.objects.get access"}}`,
+			expectedErr:    "access to gs://shared-tasks/task.yaml was denied (resolver@example.iam.gserviceaccount.com does not have storage.objects.get access), check the permissions granted to the resolver's workload identity",
+			expectedDenied: true,
+		},
+		{
+			name:           "anonymous access denied",
+			status:         http.StatusUnauthorized,
+			body:           `{"error": {"code": 401, "message": "Anonymous caller does not have storage.objects.get access"}}`,
+			anonymous:      true,
+			expectedErr:    "access to gs://shared-tasks/task.yaml was denied (Anonymous caller does not have storage.objects.get access), anonymous requests aren't allowed, set the secret param to a secret holding a service account key or give the resolver a workload identity",
+			expectedDenied: true,
+		},
+		{
+			name:           "bucket not in workload-identity-buckets denied",
+			status:         http.StatusUnauthorized,
+			body:           `{"error": {"code": 401, "message": "Anonymous caller does not have storage.objects.get access"}}`,
+			buckets:        "other-tasks",
+			expectedErr:    "access to gs://shared-tasks/task.yaml was denied (Anonymous caller does not have storage.objects.get access), anonymous requests aren't allowed, set the secret param to a secret holding a service account key or have the bucket added to the workload-identity-buckets config",
+			expectedDenied: true,
+		},
+		{
+			name:                "rate limited",
+			status:              http.StatusTooManyRequests,
+			body:                `{"error": {"code": 429, "message": "The rate of change requests to the object is too high"}}`,
+			expectedErr:         "request for gs://shared-tasks/task.yaml was rate limited (The rate of change requests to the object is too high)",
+			expectedRateLimited: true,
+		},
+		{
+			name:              "unavailable",
+			status:            http.StatusServiceUnavailable,
+			body:              "oops",
+			expectedErr:       "request for gs://shared-tasks/task.yaml failed with status code 503 (503 Service Unavailable)",
+			expectedTransient: true,
+		},
+		{
+			name:        "bad request",
+			status:      http.StatusBadRequest,
+			body:        `{"error": {"code": 400, "message": "Invalid argument"}}`,
+			expectedErr: "request for gs://shared-tasks/task.yaml failed with status code 400 (Invalid argument)",
+		},
+		{
+			name:            "timeout",
+			timeout:         true,
+			expectedErr:     "request for gs://shared-tasks/task.yaml timed out after 10ms",
+			expectedTimeout: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			release := make(chan struct{})
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.timeout {
+					<-release
+					return
+				}
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer svr.Close()
+			defer close(release)
+
+			resolver := Resolver{defaultTokenSource: workloadIdentity}
+			if tc.anonymous {
+				resolver.defaultTokenSource = anonymous
+			}
+			config := map[string]string{ConfigEndpoint: svr.URL, ConfigWorkloadIdentityBuckets: "shared-tasks"}
+			if tc.buckets != "" {
+				config[ConfigWorkloadIdentityBuckets] = tc.buckets
+			}
+			if tc.timeout {
+				config[ConfigFetchTimeout] = "10ms"
+			}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+			ctx = framework.InjectResolverConfigToContext(ctx, config)
+			_, err := resolver.Resolve(ctx, toParams(map[string]string{ParamBucket: "shared-tasks", ParamObject: "task.yaml"}))
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+
+			var notFoundErr *resolutioncommon.ResolutionNotFoundError
+			if errors.As(err, &notFoundErr) != tc.expectedNotFound {
+				t.Errorf("expected ResolutionNotFoundError %t but got %T", tc.expectedNotFound, err)
+			}
+			var deniedErr *resolutioncommon.PermissionDeniedError
+			if errors.As(err, &deniedErr) != tc.expectedDenied {
+				t.Errorf("expected PermissionDeniedError %t but got %T", tc.expectedDenied, err)
+			}
+			var transientErr *resolutioncommon.TransientError
+			if errors.As(err, &transientErr) != tc.expectedTransient {
+				t.Errorf("expected TransientError %t but got %T", tc.expectedTransient, err)
+			}
+			var rateLimitedErr *resolutioncommon.RateLimitedError
+			if errors.As(err, &rateLimitedErr) != tc.expectedRateLimited {
+				t.Errorf("expected RateLimitedError %t but got %T", tc.expectedRateLimited, err)
+			}
+			var timeoutErr *resolutioncommon.ResolutionTimeoutError
+			if errors.As(err, &timeoutErr) != tc.expectedTimeout {
+				t.Errorf("expected ResolutionTimeoutError %t but got %T", tc.expectedTimeout, err)
+			}
+		})
+	}
+}
+
+func TestResolveRejectedKey(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "Invalid JWT Signature."}`)
+	}))
+	defer tokenServer.Close()
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcs-key", Namespace: "foo-ns"},
+		Data:       map[string][]byte{serviceAccountKeySecretKey: serviceAccountKey(t, tokenServer.URL)},
+	}
+
+	resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(keySecret)}
+	ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+	ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{ConfigEndpoint: "http://storage.invalid"})
+	_, err := resolver.Resolve(ctx, toParams(map[string]string{ParamBucket: "shared-tasks", ParamObject: "task.yaml", ParamSecret: "gcs-key"}))
+	var deniedErr *resolutioncommon.PermissionDeniedError
+	if !errors.As(err, &deniedErr) {
+		t.Fatalf("expected a PermissionDeniedError but got %v", err)
+	}
+	expectedErr := "error getting a token to fetch gs://shared-tasks/task.yaml: the credentials were rejected with status code 400, check the service account key in secret gcs-key and the permissions granted to the service account"
+	if d := cmp.Diff(expectedErr, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestDefaultTokenSourceLookedUpOnce(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testContent)
+	}))
+	defer svr.Close()
+
+	lookups := 0
+	resolver := Resolver{defaultTokenSource: func(ctx context.Context) (oauth2.TokenSource, error) {
+		lookups++
+		return workloadIdentity(ctx)
+	}}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{ConfigEndpoint: svr.URL, ConfigWorkloadIdentityBuckets: "shared-tasks"})
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.Resolve(ctx, toParams(map[string]string{ParamBucket: "shared-tasks", ParamObject: "task.yaml"})); err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected the default token source to be looked up once but it was looked up %d times", lookups)
+	}
+}
+
+type fakeCredentialProvider struct {
+	token   string
+	request framework.CredentialRequest
+}
+
+func (p *fakeCredentialProvider) RegistryKeychain(context.Context, string, framework.CredentialRequest) (authn.Keychain, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fakeCredentialProvider) Token(_ context.Context, _ string, req framework.CredentialRequest) (string, error) {
+	p.request = req
+	return p.token, nil
+}
+
+// workloadIdentity stands in for the token source of the resolver's
+// workload identity.
+func workloadIdentity(context.Context) (oauth2.TokenSource, error) {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: workloadToken, TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}), nil
+}
+
+// anonymous stands in for a resolver without any default credentials.
+func anonymous(context.Context) (oauth2.TokenSource, error) {
+	return nil, nil
+}
+
+// serviceAccountKey returns the JSON key of a service account whose
+// tokens are issued by the given token endpoint.
+func serviceAccountKey(t *testing.T, tokenURI string) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling key: %v", err)
+	}
+	data, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "tekton-test",
+		"private_key_id": "abc123",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "resolver@tekton-test.iam.gserviceaccount.com",
+		"token_uri":      tokenURI,
+	})
+	if err != nil {
+		t.Fatalf("error marshalling service account key: %v", err)
+	}
+	return data
+}
+
+func gcsErrorResponse(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error": {"code": %d, "message": %q}}`, status, message)
+}
+
+func resolverContext() context.Context {
+	return frtesting.ContextWithGCSResolverEnabled(context.Background())
+}
+
+func toParams(m map[string]string) []pipelinev1beta1.Param {
+	var params []pipelinev1beta1.Param
+
+	for k, v := range m {
+		params = append(params, pipelinev1beta1.Param{
+			Name:  k,
+			Value: *pipelinev1beta1.NewStructuredValues(v),
+		})
+	}
+
+	return params
+}
+
+func sha256Of(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -31,4 +31,10 @@ var (
 	AnnotationKeyPath = resolution.GroupName + "/path"
 	// AnnotationKeyURL is the repo URL used
 	AnnotationKeyURL = resolution.GroupName + "/url"
+	// AnnotationKeyCommit is the SHA of the commit the revision resolved
+	// to when cloning the repo
+	AnnotationKeyCommit = resolution.GroupName + "/commit"
+	// AnnotationKeyTag is the name of the tag the revision named, set
+	// only when it named one
+	AnnotationKeyTag = resolution.GroupName + "/tag"
 )
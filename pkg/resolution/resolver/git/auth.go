@@ -0,0 +1,245 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // hashed known_hosts entries are defined in terms of HMAC-SHA1.
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// tokenSecretKey is the key in the clone secret holding the token
+	// used to authenticate over https.
+	tokenSecretKey = "token"
+	// usernameSecretKey is the optional key in the clone secret holding
+	// the username used with the token over https.
+	usernameSecretKey = "username"
+	// defaultGitUsername is the username used to authenticate when none
+	// is given.
+	defaultGitUsername = "git"
+)
+
+// scpLikeURLRegex matches the scp-like syntax for ssh urls, e.g.
+// git@github.com:tektoncd/catalog.git.
+var scpLikeURLRegex = regexp.MustCompile(`^(?:([^@/]+)@)?([^:/]+):[^/]`)
+
+// getCloneAuth returns the credentials to clone the repo in the url
+// param with, read from the secret param, or nil if no secret was given.
+// The credentials are chosen by the scheme of the url: an ssh private
+// key for ssh urls and a token for https urls. They are never included
+// in returned errors.
+func (r *Resolver) getCloneAuth(ctx context.Context, params map[string]string) (transport.AuthMethod, error) {
+	secretName := params[secretParam]
+	if secretName == "" {
+		return nil, nil
+	}
+	if err := validateCloneAuthParams(params); err != nil {
+		return nil, err
+	}
+	sshUser, isSSH := sshURLUser(params[urlParam])
+
+	namespace := resolutioncommon.RequestNamespace(ctx)
+	if r.kubeClient == nil {
+		return nil, fmt.Errorf("cannot read secret %s: no kubernetes client available", secretName)
+	}
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClient, namespace, secretName, "git credentials")
+	if err != nil {
+		return nil, err
+	}
+
+	if isSSH {
+		privateKey, ok := secret.Data[corev1.SSHAuthPrivateKey]
+		if !ok || len(privateKey) == 0 {
+			return nil, fmt.Errorf("cannot get git credentials, key %s not found in secret %s in namespace %s", corev1.SSHAuthPrivateKey, secretName, namespace)
+		}
+		conf := framework.GetResolverConfigFromContext(ctx)
+		knownHosts := conf[sshKnownHostsKey]
+		if strings.TrimSpace(knownHosts) == "" {
+			return nil, fmt.Errorf("cannot clone over ssh, '%s' is not set in config", sshKnownHostsKey)
+		}
+		hostKeyCallback, err := knownHostsCallback(knownHosts)
+		if err != nil {
+			return nil, err
+		}
+		auth, err := gitssh.NewPublicKeys(sshUser, privateKey, "")
+		if err != nil {
+			// The error is deliberately dropped since it may describe
+			// the key.
+			return nil, fmt.Errorf("cannot parse ssh private key in secret %s in namespace %s", secretName, namespace)
+		}
+		auth.HostKeyCallback = hostKeyCallback
+		return auth, nil
+	}
+
+	token, ok := secret.Data[tokenSecretKey]
+	if !ok || len(token) == 0 {
+		return nil, fmt.Errorf("cannot get git credentials, key %s not found in secret %s in namespace %s", tokenSecretKey, secretName, namespace)
+	}
+	username := defaultGitUsername
+	if u, ok := secret.Data[usernameSecretKey]; ok && len(u) > 0 {
+		username = strings.TrimSpace(string(u))
+	}
+	return &githttp.BasicAuth{
+		Username: username,
+		Password: strings.TrimSpace(string(token)),
+	}, nil
+}
+
+// validateCloneAuthParams checks that the secret param, if given, is used
+// with an https or ssh url, without reading the secret.
+func validateCloneAuthParams(params map[string]string) error {
+	if params[secretParam] == "" {
+		return nil
+	}
+	repoURL := params[urlParam]
+	if repoURL == "" {
+		return fmt.Errorf("'%s' can only be used with '%s'", secretParam, urlParam)
+	}
+	if _, isSSH := sshURLUser(repoURL); !isSSH && !strings.HasPrefix(repoURL, "https://") {
+		return fmt.Errorf("'%s' can only be used with https or ssh urls", secretParam)
+	}
+	return nil
+}
+
+// sshURLUser returns the user of the given url and true if it is an ssh
+// url, either in the ssh:// or the scp-like form.
+func sshURLUser(repoURL string) (string, bool) {
+	if strings.HasPrefix(repoURL, "ssh://") {
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return "", false
+		}
+		if u.User != nil && u.User.Username() != "" {
+			return u.User.Username(), true
+		}
+		return defaultGitUsername, true
+	}
+	if strings.Contains(repoURL, "://") {
+		return "", false
+	}
+	m := scpLikeURLRegex.FindStringSubmatch(repoURL)
+	if m == nil {
+		return "", false
+	}
+	if m[1] != "" {
+		return m[1], true
+	}
+	return defaultGitUsername, true
+}
+
+type knownHost struct {
+	patterns []string
+	key      ssh.PublicKey
+}
+
+// knownHostsCallback returns a host key callback that only accepts the
+// host keys listed in the given known_hosts content. Hashed hostnames
+// and wildcard patterns are supported, @cert-authority and @revoked
+// lines are ignored.
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	var hosts []knownHost
+	rest := []byte(knownHosts)
+	for {
+		marker, patterns, key, _, next, err := ssh.ParseKnownHosts(rest)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' config: %w", sshKnownHostsKey, err)
+		}
+		rest = next
+		if marker != "" {
+			continue
+		}
+		hosts = append(hosts, knownHost{patterns: patterns, key: key})
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		host := knownhosts.Normalize(hostname)
+		for _, h := range hosts {
+			if bytes.Equal(h.key.Marshal(), key.Marshal()) && matchesHost(h.patterns, host) {
+				return nil
+			}
+		}
+		return fmt.Errorf("ssh host key for %s is not listed in '%s' config", hostname, sshKnownHostsKey)
+	}, nil
+}
+
+// matchesHost returns true if the normalized host matches any of the
+// known_hosts patterns and none of the negated ones.
+func matchesHost(patterns []string, host string) bool {
+	matched := false
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if !matchesPattern(p, host) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func matchesPattern(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "|1|") {
+		parts := strings.Split(pattern[len("|1|"):], "|")
+		if len(parts) != 2 {
+			return false
+		}
+		salt, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return false
+		}
+		hash, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == host
+	}
+	// Brackets in known_hosts patterns delimit a host with a non-standard
+	// port rather than a character class.
+	matched, err := path.Match(strings.NewReplacer("[", `\[`, "]", `\]`).Replace(pattern), host)
+	return err == nil && matched
+}
@@ -32,6 +32,14 @@ const (
 	// defaultOrgKey is the configuration field name for setting a default organization when using the SCM API.
 	defaultOrgKey = "default-org"
 
+	// sshKnownHostsKey is the configuration field name for the known_hosts entries used to verify the host keys of
+	// servers when cloning over ssh.
+	sshKnownHostsKey = "ssh-known-hosts"
+
+	// shallowCloneKey is the configuration field name for controlling whether anonymous clones only fetch the
+	// single commit a branch or tag revision points to. Defaults to true.
+	shallowCloneKey = "shallow-clone"
+
 	// ServerURLKey is the config map key for the SCM provider URL
 	ServerURLKey = "server-url"
 	// SCMTypeKey is the config map key for the SCM provider type
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+const (
+	// directoryModeSingle resolves a directory only when the YAML files
+	// in it hold a single document between them.
+	directoryModeSingle = "single"
+	// directoryModeConcatenate resolves a directory to all the documents
+	// of the YAML files in it, as a multi-document stream.
+	directoryModeConcatenate = "concatenate"
+
+	// maxDirectoryFiles is the maximum number of YAML files a resolved
+	// directory may hold.
+	maxDirectoryFiles = 100
+)
+
+// errNotDirectory is returned by readAPIDirectory when the path can't
+// be listed as a directory.
+var errNotDirectory = errors.New("not a directory")
+
+// documentSeparatorRegex matches the lines separating YAML documents.
+var documentSeparatorRegex = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// validateDirectoryMode returns an error if the given directoryMode
+// param isn't one of the supported modes.
+func validateDirectoryMode(mode string) error {
+	switch mode {
+	case directoryModeSingle, directoryModeConcatenate:
+		return nil
+	}
+	return fmt.Errorf("must be %q or %q", directoryModeSingle, directoryModeConcatenate)
+}
+
+// yamlFile is a YAML file found in a directory being resolved.
+type yamlFile struct {
+	path    string
+	content []byte
+}
+
+// isYAMLFile returns true for the names of the files resolved from a
+// directory.
+func isYAMLFile(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+}
+
+// yamlDocuments splits the content of a YAML file into its documents,
+// leaving out documents holding nothing but comments and whitespace.
+func yamlDocuments(content []byte) [][]byte {
+	var docs [][]byte
+	for _, doc := range documentSeparatorRegex.Split(string(content), -1) {
+		for _, line := range strings.Split(doc, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				docs = append(docs, []byte(strings.Trim(doc, "\n")+"\n"))
+				break
+			}
+		}
+	}
+	return docs
+}
+
+// combineDirectory returns the content resolved from the YAML files of
+// the given directory according to the directory mode. Files are
+// combined in the lexical order of their paths. An error listing the
+// files is returned rather than picking one of them when the mode is
+// "single" and they hold more than one document.
+func combineDirectory(dir, mode string, files []yamlFile) ([]byte, error) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	var docs [][]byte
+	var docFiles []string
+	for _, f := range files {
+		fileDocs := yamlDocuments(f.content)
+		if len(fileDocs) > 0 {
+			docFiles = append(docFiles, fmt.Sprintf("%s (%d)", f.path, len(fileDocs)))
+		}
+		docs = append(docs, fileDocs...)
+	}
+
+	switch {
+	case len(docs) == 0:
+		return nil, fmt.Errorf("directory %q holds no YAML documents in .yaml or .yml files", dir)
+	case len(docs) > 1 && mode != directoryModeConcatenate:
+		return nil, fmt.Errorf("directory %q holds %d YAML documents, in %s: set the '%s' param to %q to resolve all of them as a multi-document stream, or set '%s' to a single file",
+			dir, len(docs), strings.Join(docFiles, ", "), directoryModeParam, directoryModeConcatenate, pathParam)
+	}
+	return bytes.Join(docs, []byte("---\n")), nil
+}
+
+// readClonedDirectory returns the YAML files in the tree of the given
+// directory of a cloned repository.
+func readClonedDirectory(filesystem billy.Filesystem, dir string) ([]yamlFile, error) {
+	var files []yamlFile
+	var walk func(string) error
+	walk = func(current string) error {
+		infos, err := filesystem.ReadDir(current)
+		if err != nil {
+			return fmt.Errorf("error listing directory %q: %v", current, err)
+		}
+		for _, info := range infos {
+			p := path.Join(current, info.Name())
+			if info.IsDir() {
+				if err := walk(p); err != nil {
+					return err
+				}
+				continue
+			}
+			if !isYAMLFile(info.Name()) {
+				continue
+			}
+			if len(files) == maxDirectoryFiles {
+				return tooManyFilesError(dir)
+			}
+			content, err := util.ReadFile(filesystem, p)
+			if err != nil {
+				return fmt.Errorf("error reading file %q: %v", p, err)
+			}
+			files = append(files, yamlFile{path: p, content: content})
+		}
+		return nil
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// readAPIDirectory returns the YAML files in the tree of the given
+// directory of a repository, fetched one by one from the SCM provider's
+// API. errNotDirectory is returned if the directory itself can't be
+// listed.
+func readAPIDirectory(ctx context.Context, client *scm.Client, repo, dir, ref string) ([]yamlFile, error) {
+	var files []yamlFile
+	var walk func(string) error
+	walk = func(current string) error {
+		entries, _, err := client.Contents.List(ctx, repo, current, ref)
+		if current == dir && (err != nil || len(entries) == 0) {
+			return errNotDirectory
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't list directory %q: %w", current, err)
+		}
+		for _, entry := range entries {
+			p := path.Join(current, entry.Name)
+			if entry.Type == "dir" {
+				if err := walk(p); err != nil {
+					return err
+				}
+				continue
+			}
+			if entry.Type != "file" || !isYAMLFile(entry.Name) {
+				continue
+			}
+			if len(files) == maxDirectoryFiles {
+				return tooManyFilesError(dir)
+			}
+			content, _, err := client.Contents.Find(ctx, repo, p, ref)
+			if err != nil {
+				return fmt.Errorf("couldn't fetch resource content of %q: %w", p, err)
+			}
+			files = append(files, yamlFile{path: p, content: content.Data})
+		}
+		return nil
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// tooManyFilesError returns the error for a directory holding more YAML
+// files than can be resolved.
+func tooManyFilesError(dir string) error {
+	return fmt.Errorf("directory %q holds more than %d YAML files, point '%s' at a smaller directory", dir, maxDirectoryFiles, pathParam)
+}
@@ -27,4 +27,15 @@ const (
 	pathParam string = "pathInRepo"
 	// revisionParam is the git revision that a file should be fetched from. This is used with both approaches.
 	revisionParam string = "revision"
+	// secretParam is the name of a secret in the namespace of the request holding the credentials to clone the
+	// repo with when using the anonymous/full clone approach: an ssh private key for ssh urls or a token for https urls.
+	secretParam string = "secret"
+	// directoryModeParam is how a pathInRepo pointing at a directory is resolved: "single", the default, resolves it
+	// only when its YAML files hold a single document between them and "concatenate" resolves all their documents as
+	// a multi-document stream. This is used with both approaches.
+	directoryModeParam string = "directoryMode"
+	// submodulesParam is whether the submodules of the repo, and theirs in turn, are initialized and updated before
+	// pathInRepo is read, so that a file in a submodule can be resolved: "true" or "false", the default. This is only
+	// used with the anonymous/full clone approach.
+	submodulesParam string = "submodules"
 )
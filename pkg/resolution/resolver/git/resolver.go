@@ -23,13 +23,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
 	gitcfg "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/factory"
@@ -38,9 +41,7 @@ import (
 	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
 	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
-	"go.uber.org/zap"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"go.opencensus.io/trace"
 	"k8s.io/apimachinery/pkg/util/cache"
 	"k8s.io/client-go/kubernetes"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
@@ -75,7 +76,6 @@ var _ framework.Resolver = &Resolver{}
 // Resolver implements a framework.Resolver that can fetch files from git.
 type Resolver struct {
 	kubeClient kubernetes.Interface
-	logger     *zap.SugaredLogger
 	cache      *cache.LRUExpireCache
 	ttl        time.Duration
 
@@ -86,7 +86,6 @@ type Resolver struct {
 // Initialize performs any setup required by the gitresolver.
 func (r *Resolver) Initialize(ctx context.Context) error {
 	r.kubeClient = kubeclient.Get(ctx)
-	r.logger = logging.FromContext(ctx)
 	r.cache = cache.NewLRUExpireCache(cacheSize)
 	r.ttl = ttl
 	if r.clientFunc == nil {
@@ -109,25 +108,78 @@ func (r *Resolver) GetSelector(_ context.Context) map[string]string {
 	}
 }
 
+var _ framework.ParamTracer = &Resolver{}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver, with the
+// url and revision defaulting to those set in the resolver's config.
+func (r *Resolver) GetParamSchema(ctx context.Context) []framework.ParamSchema {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	return []framework.ParamSchema{{
+		Name:        urlParam,
+		Description: "The url of the repository to clone. Can't be combined with the repo param.",
+		Default:     conf[defaultURLKey],
+	}, {
+		Name:        orgParam,
+		Description: "The organization of the repository read through the SCM provider's API. Required with the repo param.",
+	}, {
+		Name:        repoParam,
+		Description: "The repository read through the SCM provider's API. Can't be combined with the url param.",
+	}, {
+		Name:        pathParam,
+		Description: "The path of the file, or directory of YAML files, in the repository.",
+		Required:    true,
+	}, {
+		Name:        revisionParam,
+		Description: "The branch, tag or commit the file is read at.",
+		Required:    conf[defaultRevisionKey] == "",
+		Default:     conf[defaultRevisionKey],
+	}, {
+		Name:        secretParam,
+		Description: "The name of a secret, in the namespace of the request, holding the credentials to clone the url with.",
+	}, {
+		Name:        directoryModeParam,
+		Description: "How a pathInRepo pointing at a directory is resolved.",
+		Enum:        []string{directoryModeSingle, directoryModeConcatenate},
+		Default:     directoryModeSingle,
+	}, {
+		Name:        submodulesParam,
+		Description: "Whether the submodules of the cloned repository are initialized before the file is read.",
+		Enum:        []string{"true", "false"},
+		Default:     "false",
+	}}
+}
+
+// TracedParams returns the params recorded on the spans of resolutions.
+// The url isn't recorded since it may hold credentials.
+func (r *Resolver) TracedParams(context.Context) []string {
+	return []string{orgParam, repoParam, revisionParam, pathParam}
+}
+
 // ValidateParams returns an error if the given parameter map is not
 // valid for a resource request targeting the gitresolver.
 func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
 	if r.isDisabled(ctx) {
-		return errors.New(disabledError)
+		return resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
+	}
+
+	if err := framework.ValidateNoVariableReferences(params); err != nil {
+		return err
 	}
 
-	_, err := populateDefaultParams(ctx, params)
+	paramsMap, err := populateDefaultParams(ctx, params)
 	if err != nil {
 		return err
 	}
-	return nil
+	return validateCloneAuthParams(paramsMap)
 }
 
 // Resolve performs the work of fetching a file from git given a map of
 // parameters.
 func (r *Resolver) Resolve(ctx context.Context, origParams []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
 	if r.isDisabled(ctx) {
-		return nil, errors.New(disabledError)
+		return nil, resolutioncommon.NewError(resolutioncommon.ReasonResolverDisabled, errors.New(disabledError))
 	}
 
 	params, err := populateDefaultParams(ctx, origParams)
@@ -157,15 +209,29 @@ func (r *Resolver) resolveAPIGit(ctx context.Context, params map[string]string)
 		return nil, fmt.Errorf("failed to create SCM client: %w", err)
 	}
 
-	content, _, err := scmClient.Contents.Find(ctx, fmt.Sprintf("%s/%s", params[orgParam], params[repoParam]), params[pathParam], params[revisionParam])
+	repoName := fmt.Sprintf("%s/%s", params[orgParam], params[repoParam])
+	content, _, err := scmClient.Contents.Find(ctx, repoName, params[pathParam], params[revisionParam])
 	if err != nil {
-		return nil, fmt.Errorf("couldn't fetch resource content: %w", err)
+		// Providers fail to find the content of directories, so the
+		// path may still be one.
+		files, listErr := readAPIDirectory(ctx, scmClient, repoName, params[pathParam], params[revisionParam])
+		if errors.Is(listErr, errNotDirectory) {
+			return nil, fmt.Errorf("couldn't fetch resource content: %w", err)
+		}
+		if listErr != nil {
+			return nil, listErr
+		}
+		data, err := combineDirectory(params[pathParam], params[directoryModeParam], files)
+		if err != nil {
+			return nil, err
+		}
+		content = &scm.Content{Path: params[pathParam], Data: data, Sha: params[revisionParam]}
 	}
 	if content == nil || len(content.Data) == 0 {
 		return nil, fmt.Errorf("no content for resource in %s/%s %s", params[orgParam], params[repoParam], params[pathParam])
 	}
 
-	repo, _, err := scmClient.Repositories.Find(ctx, fmt.Sprintf("%s/%s", params[orgParam], params[repoParam]))
+	repo, _, err := scmClient.Repositories.Find(ctx, repoName)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't fetch repository: %w", err)
 	}
@@ -173,6 +239,7 @@ func (r *Resolver) resolveAPIGit(ctx context.Context, params map[string]string)
 	return &resolvedGitResource{
 		Content:  content.Data,
 		Revision: content.Sha,
+		Commit:   findAPICommit(ctx, scmClient, repoName, params[revisionParam]),
 		Org:      params[orgParam],
 		Repo:     params[repoParam],
 		Path:     content.Path,
@@ -180,6 +247,22 @@ func (r *Resolver) resolveAPIGit(ctx context.Context, params map[string]string)
 	}, nil
 }
 
+// findAPICommit returns the SHA of the commit the revision resolves to
+// in the repository, peeling tags, whether annotated or lightweight, to
+// the commit they point to. An empty string is returned if the provider
+// can't tell, since the content was already fetched by the revision.
+func findAPICommit(ctx context.Context, scmClient *scm.Client, repoName, revision string) string {
+	commit, _, err := scmClient.Git.FindCommit(ctx, repoName, revision)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("couldn't resolve revision %q of %s to a commit: %v", revision, repoName, err)
+		return ""
+	}
+	if commit == nil {
+		return ""
+	}
+	return commit.Sha
+}
+
 func (r *Resolver) resolveAnonymousGit(ctx context.Context, params map[string]string) (framework.ResolvedResource, error) {
 	conf := framework.GetResolverConfigFromContext(ctx)
 	repo := params[urlParam]
@@ -199,25 +282,15 @@ func (r *Resolver) resolveAnonymousGit(ctx context.Context, params map[string]st
 		}
 	}
 
-	cloneOpts := &git.CloneOptions{
-		URL: repo,
-	}
-	filesystem := memfs.New()
-	repository, err := git.Clone(memory.NewStorage(), filesystem, cloneOpts)
+	auth, err := r.getCloneAuth(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("clone error: %w", err)
+		return nil, err
 	}
 
-	// try fetch the branch when the given revision refers to a branch name
-	refSpec := gitcfg.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", revision, revision))
-	err = repository.Fetch(&git.FetchOptions{
-		RefSpecs: []gitcfg.RefSpec{refSpec},
-	})
+	filesystem := memfs.New()
+	repository, err := cloneRevision(ctx, repo, revision, auth, filesystem)
 	if err != nil {
-		var fetchErr git.NoMatchingRefSpecError
-		if !errors.As(err, &fetchErr) {
-			return nil, fmt.Errorf("unexpected fetch error: %v", err)
-		}
+		return nil, err
 	}
 
 	w, err := repository.Worktree()
@@ -225,10 +298,13 @@ func (r *Resolver) resolveAnonymousGit(ctx context.Context, params map[string]st
 		return nil, fmt.Errorf("worktree error: %v", err)
 	}
 
+	// Tags, whether annotated or lightweight, resolve to the commit they
+	// point to rather than to an annotated tag's own object.
 	h, err := repository.ResolveRevision(plumbing.Revision(revision))
 	if err != nil {
 		return nil, fmt.Errorf("revision error: %v", err)
 	}
+	tag := revisionTag(repository, revision)
 
 	err = w.Checkout(&git.CheckoutOptions{
 		Hash: *h,
@@ -239,9 +315,40 @@ func (r *Resolver) resolveAnonymousGit(ctx context.Context, params map[string]st
 
 	path := params[pathParam]
 
+	if params[submodulesParam] == "true" {
+		if err := updateSubmodules(ctx, w, repo, auth); err != nil {
+			return nil, err
+		}
+	} else if err := checkNotInSubmodule(w, path); err != nil {
+		return nil, err
+	}
+
+	if info, err := filesystem.Stat(path); err == nil && info.IsDir() {
+		files, err := readClonedDirectory(filesystem, path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := combineDirectory(path, params[directoryModeParam], files)
+		if err != nil {
+			return nil, err
+		}
+		return &resolvedGitResource{
+			Revision: revision,
+			Commit:   h.String(),
+			Tag:      tag,
+			Content:  data,
+			URL:      params[urlParam],
+			Path:     path,
+		}, nil
+	}
+
 	f, err := filesystem.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file %q: %v", path, err)
+		openErr := fmt.Errorf("error opening file %q: %v", path, err)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &resolutioncommon.ResolutionNotFoundError{Resource: path, Original: openErr}
+		}
+		return nil, openErr
 	}
 
 	buf := &bytes.Buffer{}
@@ -252,6 +359,8 @@ func (r *Resolver) resolveAnonymousGit(ctx context.Context, params map[string]st
 
 	return &resolvedGitResource{
 		Revision: revision,
+		Commit:   h.String(),
+		Tag:      tag,
 		Content:  buf.Bytes(),
 		URL:      params[urlParam],
 		Path:     params[pathParam],
@@ -259,6 +368,135 @@ func (r *Resolver) resolveAnonymousGit(ctx context.Context, params map[string]st
 
 }
 
+// cloneRevision clones the repository into filesystem so that the
+// revision can be resolved and checked out. Unless the shallow-clone config
+// is false, branches and tags are cloned shallowly, fetching only the
+// commit they point to; other revisions need a full clone.
+func cloneRevision(ctx context.Context, repo, revision string, auth transport.AuthMethod, filesystem billy.Filesystem) (*git.Repository, error) {
+	cloneOpts := &git.CloneOptions{
+		URL:  repo,
+		Auth: auth,
+	}
+	// The url isn't recorded since it may hold credentials.
+	cloneCtx, span := framework.StartSpan(ctx, "git.Clone", trace.StringAttribute("git.revision", revision))
+	var shallowRef plumbing.ReferenceName
+	var err error
+	if shallowCloneEnabled(ctx) {
+		shallowRef, err = shallowCloneReference(cloneCtx, repo, revision, auth)
+		if err != nil {
+			framework.EndSpan(span, err)
+			return nil, fmt.Errorf("clone error: %w", err)
+		}
+		if shallowRef == "" {
+			logging.FromContext(ctx).Infof("revision %q isn't a branch or tag, falling back to a full clone", revision)
+		}
+	}
+	if shallowRef != "" {
+		// Only the single commit the branch or tag points to is fetched.
+		cloneOpts.ReferenceName = shallowRef
+		cloneOpts.SingleBranch = true
+		cloneOpts.Depth = 1
+		cloneOpts.Tags = git.NoTags
+	}
+	// The url's password, if any, is redacted from the log line.
+	logging.FromContext(ctx).Debugf("cloning %s at revision %q, shallowly: %t", repo, revision, shallowRef != "")
+	repository, err := git.CloneContext(cloneCtx, memory.NewStorage(), filesystem, cloneOpts)
+	if err != nil {
+		framework.EndSpan(span, err)
+		return nil, fmt.Errorf("clone error: %w", err)
+	}
+
+	if shallowRef == "" {
+		// try fetch the branch when the given revision refers to a branch name
+		refSpec := gitcfg.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", revision, revision))
+		err = repository.FetchContext(cloneCtx, &git.FetchOptions{
+			RefSpecs: []gitcfg.RefSpec{refSpec},
+			Auth:     auth,
+		})
+		if err != nil {
+			var fetchErr git.NoMatchingRefSpecError
+			if !errors.As(err, &fetchErr) {
+				framework.EndSpan(span, err)
+				return nil, fmt.Errorf("unexpected fetch error: %v", err)
+			}
+		}
+	}
+	framework.EndSpan(span, nil)
+	return repository, nil
+}
+
+// shallowCloneEnabled returns whether anonymous clones of branches and
+// tags should be shallow, which they are unless the shallow-clone config
+// is set to false.
+func shallowCloneEnabled(ctx context.Context) bool {
+	value, ok := framework.GetResolverConfigFromContext(ctx)[shallowCloneKey]
+	if !ok || value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("ignoring invalid %s config %q: %v", shallowCloneKey, value, err)
+		return true
+	}
+	return enabled
+}
+
+// shallowCloneReference returns the name of the tag or branch on the remote
+// that the revision names, so that just that reference can be cloned, or
+// an empty name if it names neither, e.g. because it is a commit SHA,
+// in which case the whole repository has to be cloned. Tags take
+// precedence over branches of the same name just as they do when the
+// revision is resolved.
+func shallowCloneReference(ctx context.Context, repo, revision string, auth transport.AuthMethod) (plumbing.ReferenceName, error) {
+	if plumbing.IsHash(revision) {
+		return "", nil
+	}
+	var candidates []plumbing.ReferenceName
+	if strings.HasPrefix(revision, "refs/") {
+		candidates = []plumbing.ReferenceName{plumbing.ReferenceName(revision)}
+	} else {
+		candidates = []plumbing.ReferenceName{
+			plumbing.NewTagReferenceName(revision),
+			plumbing.NewBranchReferenceName(revision),
+		}
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &gitcfg.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{repo},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", err
+	}
+	listed := make(map[plumbing.ReferenceName]bool, len(refs))
+	for _, ref := range refs {
+		listed[ref.Name()] = true
+	}
+	for _, name := range candidates {
+		if listed[name] && (name.IsTag() || name.IsBranch()) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// revisionTag returns the name of the tag the revision names, e.g. "v1"
+// for either "v1" or "refs/tags/v1", or an empty string if it doesn't
+// name a tag, e.g. because it is a branch or a commit SHA. Tags take
+// precedence over branches of the same name just as they do when the
+// revision is resolved.
+func revisionTag(repository *git.Repository, revision string) string {
+	if plumbing.IsHash(revision) {
+		return ""
+	}
+	name := strings.TrimPrefix(revision, "refs/tags/")
+	if _, err := repository.Tag(name); err != nil {
+		return ""
+	}
+	return name
+}
+
 var _ framework.ConfigWatcher = &Resolver{}
 
 // GetConfigName returns the name of the git resolver's configmap.
@@ -300,6 +538,10 @@ type resolvedGitResource struct {
 	Repo     string
 	Path     string
 	URL      string
+	// Commit is the SHA of the commit the revision resolved to, if known.
+	Commit string
+	// Tag is the name of the tag the revision named, if it named one.
+	Tag string
 }
 
 var _ framework.ResolvedResource = &resolvedGitResource{}
@@ -327,10 +569,44 @@ func (r *resolvedGitResource) Annotations() map[string]string {
 	if r.URL != "" {
 		m[AnnotationKeyURL] = r.URL
 	}
+	if r.Commit != "" {
+		m[AnnotationKeyCommit] = r.Commit
+	}
+	if r.Tag != "" {
+		m[AnnotationKeyTag] = r.Tag
+	}
+	m[resolutioncommon.AnnotationKeyProvenance] = r.provenance().AnnotationValue()
 
 	return m
 }
 
+// provenance records the repository, commit, path and, if the revision
+// named one, tag the file was resolved from. The commit is only known,
+// and so only recorded as the digest, when the revision could be
+// resolved to one.
+func (r *resolvedGitResource) provenance() resolutioncommon.Provenance {
+	p := resolutioncommon.Provenance{
+		ResolverType: labelValueGitResolverType,
+		URI:          r.URL,
+		Coordinates:  map[string]string{},
+	}
+	if r.Commit != "" {
+		p.Digest = map[string]string{"sha1": r.Commit}
+	}
+	for k, v := range map[string]string{
+		"revision": r.Revision,
+		"path":     r.Path,
+		"org":      r.Org,
+		"repo":     r.Repo,
+		"tag":      r.Tag,
+	} {
+		if v != "" {
+			p.Coordinates[k] = v
+		}
+	}
+	return p
+}
+
 // Source is the source reference of the remote data that records where the remote
 // file came from including the url, digest and the entrypoint.
 func (r *resolvedGitResource) Source() *v1beta1.ConfigSource {
@@ -358,6 +634,8 @@ func (r *Resolver) getSCMTypeAndServerURL(ctx context.Context) (string, string,
 
 func (r *Resolver) getAPIToken(ctx context.Context) ([]byte, error) {
 	conf := framework.GetResolverConfigFromContext(ctx)
+	// The logger of the resolution redacts the secrets it reads.
+	logger := logging.FromContext(ctx)
 
 	cacheKey := secretCacheKey{}
 
@@ -365,12 +643,12 @@ func (r *Resolver) getAPIToken(ctx context.Context) ([]byte, error) {
 
 	if cacheKey.name, ok = conf[APISecretNameKey]; !ok || cacheKey.name == "" {
 		err := fmt.Errorf("cannot get API token, required when specifying '%s' param, '%s' not specified in config", repoParam, APISecretNameKey)
-		r.logger.Info(err)
+		logger.Info(err)
 		return nil, err
 	}
 	if cacheKey.key, ok = conf[APISecretKeyKey]; !ok || cacheKey.key == "" {
 		err := fmt.Errorf("cannot get API token, required when specifying '%s' param, '%s' not specified in config", repoParam, APISecretKeyKey)
-		r.logger.Info(err)
+		logger.Info(err)
 		return nil, err
 	}
 	if cacheKey.ns, ok = conf[APISecretNamespaceKey]; !ok {
@@ -379,25 +657,23 @@ func (r *Resolver) getAPIToken(ctx context.Context) ([]byte, error) {
 
 	val, ok := r.cache.Get(cacheKey)
 	if ok {
-		return val.([]byte), nil
+		// A cached token wasn't read in this resolution, so it's
+		// registered for redaction here instead.
+		token := val.([]byte)
+		framework.RedactSecret(ctx, string(token))
+		return token, nil
 	}
 
-	secret, err := r.kubeClient.CoreV1().Secrets(cacheKey.ns).Get(ctx, cacheKey.name, metav1.GetOptions{})
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClient, cacheKey.ns, cacheKey.name, "API token")
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			notFoundErr := fmt.Errorf("cannot get API token, secret %s not found in namespace %s", cacheKey.name, cacheKey.ns)
-			r.logger.Info(notFoundErr)
-			return nil, notFoundErr
-		}
-		wrappedErr := fmt.Errorf("error reading API token from secret %s in namespace %s: %w", cacheKey.name, cacheKey.ns, err)
-		r.logger.Info(wrappedErr)
-		return nil, wrappedErr
+		logger.Info(err)
+		return nil, err
 	}
 
 	secretVal, ok := secret.Data[cacheKey.key]
 	if !ok {
 		err := fmt.Errorf("cannot get API token, key %s not found in secret %s in namespace %s", cacheKey.key, cacheKey.name, cacheKey.ns)
-		r.logger.Info(err)
+		logger.Info(err)
 		return nil, err
 	}
 	r.cache.Add(cacheKey, secretVal, r.ttl)
@@ -407,10 +683,7 @@ func (r *Resolver) getAPIToken(ctx context.Context) ([]byte, error) {
 func populateDefaultParams(ctx context.Context, params []pipelinev1beta1.Param) (map[string]string, error) {
 	conf := framework.GetResolverConfigFromContext(ctx)
 
-	paramsMap := make(map[string]string)
-	for _, p := range params {
-		paramsMap[p.Name] = p.Value.StringVal
-	}
+	paramsMap := framework.StringParams(params)
 
 	var missingParams []string
 
@@ -421,7 +694,7 @@ func populateDefaultParams(ctx context.Context, params []pipelinev1beta1.Param)
 			missingParams = append(missingParams, revisionParam)
 		}
 	}
-	if _, ok := paramsMap[pathParam]; !ok {
+	if paramsMap[pathParam] == "" {
 		missingParams = append(missingParams, pathParam)
 	}
 
@@ -437,6 +710,21 @@ func populateDefaultParams(ctx context.Context, params []pipelinev1beta1.Param)
 		}
 	}
 
+	if paramsMap[repoParam] != "" && paramsMap[secretParam] != "" {
+		return nil, fmt.Errorf("'%s' can only be used with '%s'", secretParam, urlParam)
+	}
+
+	if value, ok := paramsMap[submodulesParam]; ok {
+		submodules, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' param %q: must be true or false", submodulesParam, value)
+		}
+		if submodules && paramsMap[repoParam] != "" {
+			return nil, fmt.Errorf("'%s' can only be used with '%s'", submodulesParam, urlParam)
+		}
+		paramsMap[submodulesParam] = strconv.FormatBool(submodules)
+	}
+
 	if paramsMap[repoParam] != "" {
 		if _, ok := paramsMap[orgParam]; !ok {
 			if defaultOrg, ok := conf[defaultOrgKey]; ok {
@@ -450,7 +738,44 @@ func populateDefaultParams(ctx context.Context, params []pipelinev1beta1.Param)
 		return nil, fmt.Errorf("missing required git resolver params: %s", strings.Join(missingParams, ", "))
 	}
 
+	if revision := paramsMap[revisionParam]; revision != "" {
+		if err := validateRevision(revision); err != nil {
+			return nil, fmt.Errorf("invalid '%s' param %q: %w", revisionParam, revision, err)
+		}
+	}
+	if mode, ok := paramsMap[directoryModeParam]; ok {
+		if err := validateDirectoryMode(mode); err != nil {
+			return nil, fmt.Errorf("invalid '%s' param %q: %w", directoryModeParam, mode, err)
+		}
+	} else {
+		paramsMap[directoryModeParam] = directoryModeSingle
+	}
+
 	// TODO(sbwsg): validate repo url is well-formed, git:// or https://
 	// TODO(sbwsg): validate pathInRepo is valid relative pathInRepo
 	return paramsMap, nil
 }
+
+// validateRevision returns an error if the given revision can't be a
+// branch name, tag name or commit SHA, following the rules of
+// git-check-ref-format.
+func validateRevision(revision string) error {
+	switch {
+	case strings.HasPrefix(revision, "-"):
+		return errors.New("must not start with '-'")
+	case strings.HasPrefix(revision, "/"), strings.HasSuffix(revision, "/"):
+		return errors.New("must not start or end with '/'")
+	case strings.HasSuffix(revision, "."), strings.HasSuffix(revision, ".lock"):
+		return errors.New("must not end with '.' or '.lock'")
+	case strings.Contains(revision, ".."), strings.Contains(revision, "//"), strings.Contains(revision, "@{"), strings.Contains(revision, "/."):
+		return errors.New("must not contain '..', '//', '@{' or '/.'")
+	case revision == "@":
+		return errors.New("must not be '@'")
+	}
+	for _, c := range revision {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(" ~^:?*[\\", c) {
+			return fmt.Errorf("must not contain %q", c)
+		}
+	}
+	return nil
+}
@@ -18,18 +18,26 @@ package git
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/google/go-cmp/cmp"
 	"github.com/jenkins-x/go-scm/scm"
@@ -44,10 +52,18 @@ import (
 	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
 	"github.com/tektoncd/pipeline/test"
 	"github.com/tektoncd/pipeline/test/diff"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/system"
 
 	_ "knative.dev/pkg/system/testing"
@@ -75,6 +91,57 @@ func TestValidateParams(t *testing.T) {
 	if err := resolver.ValidateParams(resolverContext(), toParams(paramsWithRevision)); err != nil {
 		t.Fatalf("unexpected error validating params: %v", err)
 	}
+
+	for _, revision := range []string{"main", "feature/foo", "v1.2.3", "refs/tags/v1.2.3", "release-1.0_rc.1", "0aac385673e1efe00c4c22d13209e0f8c00b0c28"} {
+		params := map[string]string{
+			urlParam:      "http://foo",
+			pathParam:     "bar",
+			revisionParam: revision,
+		}
+		if err := resolver.ValidateParams(resolverContext(), toParams(params)); err != nil {
+			t.Errorf("unexpected error validating revision %q: %v", revision, err)
+		}
+	}
+
+	// The clone secret is only read when resolving, so it's not needed
+	// to validate the params.
+	paramsWithSecret := map[string]string{
+		urlParam:      "https://foo",
+		pathParam:     "bar",
+		revisionParam: "baz",
+		secretParam:   "missing",
+	}
+	if err := resolver.ValidateParams(resolverContext(), toParams(paramsWithSecret)); err != nil {
+		t.Fatalf("unexpected error validating params with secret: %v", err)
+	}
+}
+
+func TestValidateParamsNoNetwork(t *testing.T) {
+	trap := frtesting.NewNetworkTrap(t)
+	resolver := &Resolver{}
+	params := map[string]string{
+		urlParam:      trap.URL() + "/test-org/test-repo.git",
+		pathParam:     "task.yaml",
+		revisionParam: "main",
+	}
+	if err := resolver.ValidateParams(resolverContext(), toParams(params)); err != nil {
+		t.Fatalf("unexpected error validating clone params: %v", err)
+	}
+
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		SCMTypeKey:   "github",
+		ServerURLKey: trap.URL(),
+	})
+	params = map[string]string{
+		orgParam:      "test-org",
+		repoParam:     "test-repo",
+		pathParam:     "task.yaml",
+		revisionParam: "main",
+	}
+	if err := resolver.ValidateParams(ctx, toParams(params)); err != nil {
+		t.Fatalf("unexpected error validating api params: %v", err)
+	}
+	trap.ExpectNoConnections(t)
 }
 
 func TestValidateParamsNotEnabled(t *testing.T) {
@@ -125,6 +192,38 @@ func TestValidateParams_Failure(t *testing.T) {
 				repoParam:     "foo",
 			},
 			expectedErr: "cannot specify both 'url' and 'repo'",
+		}, {
+			name: "empty path",
+			params: map[string]string{
+				revisionParam: "abcd1234",
+				pathParam:     "",
+				urlParam:      "http://foo",
+			},
+			expectedErr: fmt.Sprintf("missing required git resolver params: %s", pathParam),
+		}, {
+			name: "revision with range",
+			params: map[string]string{
+				revisionParam: "main..other",
+				pathParam:     "/foo/bar",
+				urlParam:      "http://foo",
+			},
+			expectedErr: `invalid 'revision' param "main..other": must not contain '..', '//', '@{' or '/.'`,
+		}, {
+			name: "revision with space",
+			params: map[string]string{
+				revisionParam: "my branch",
+				pathParam:     "/foo/bar",
+				urlParam:      "http://foo",
+			},
+			expectedErr: `invalid 'revision' param "my branch": must not contain ' '`,
+		}, {
+			name: "revision starting with dash",
+			params: map[string]string{
+				revisionParam: "--upload-pack=evil",
+				pathParam:     "/foo/bar",
+				urlParam:      "http://foo",
+			},
+			expectedErr: `invalid 'revision' param "--upload-pack=evil": must not start with '-'`,
 		}, {
 			name: "no org with repo",
 			params: map[string]string{
@@ -133,6 +232,43 @@ func TestValidateParams_Failure(t *testing.T) {
 				repoParam:     "foo",
 			},
 			expectedErr: "'org' is required when 'repo' is specified",
+		}, {
+			name: "unknown directory mode",
+			params: map[string]string{
+				revisionParam:      "abcd1234",
+				pathParam:          "/foo/bar",
+				urlParam:           "http://foo",
+				directoryModeParam: "kustomize",
+			},
+			expectedErr: `invalid 'directoryMode' param "kustomize": must be "single" or "concatenate"`,
+		}, {
+			name: "secret with plain http url",
+			params: map[string]string{
+				revisionParam: "abcd1234",
+				pathParam:     "/foo/bar",
+				urlParam:      "http://foo",
+				secretParam:   "git-creds",
+			},
+			expectedErr: "'secret' can only be used with https or ssh urls",
+		}, {
+			name: "invalid submodules",
+			params: map[string]string{
+				revisionParam:   "abcd1234",
+				pathParam:       "/foo/bar",
+				urlParam:        "http://foo",
+				submodulesParam: "maybe",
+			},
+			expectedErr: `invalid 'submodules' param "maybe": must be true or false`,
+		}, {
+			name: "submodules with repo",
+			params: map[string]string{
+				revisionParam:   "abcd1234",
+				pathParam:       "/foo/bar",
+				orgParam:        "abcd1234",
+				repoParam:       "foo",
+				submodulesParam: "true",
+			},
+			expectedErr: "'submodules' can only be used with 'url'",
 		},
 	}
 
@@ -150,6 +286,212 @@ func TestValidateParams_Failure(t *testing.T) {
 	}
 }
 
+func TestSSHURLUser(t *testing.T) {
+	for _, tc := range []struct {
+		url          string
+		expectedUser string
+		expectedSSH  bool
+	}{
+		{url: "git@github.com:tektoncd/catalog.git", expectedUser: "git", expectedSSH: true},
+		{url: "bot@git.example.com:org/repo.git", expectedUser: "bot", expectedSSH: true},
+		{url: "github.com:tektoncd/catalog.git", expectedUser: "git", expectedSSH: true},
+		{url: "ssh://bot@github.com/tektoncd/catalog.git", expectedUser: "bot", expectedSSH: true},
+		{url: "ssh://github.com:2222/tektoncd/catalog.git", expectedUser: "git", expectedSSH: true},
+		{url: "https://github.com/tektoncd/catalog.git", expectedSSH: false},
+		{url: "/local/path/repo", expectedSSH: false},
+	} {
+		user, isSSH := sshURLUser(tc.url)
+		if isSSH != tc.expectedSSH || user != tc.expectedUser {
+			t.Errorf("sshURLUser(%q) = %q, %t, expected %q, %t", tc.url, user, isSSH, tc.expectedUser, tc.expectedSSH)
+		}
+	}
+}
+
+func TestKnownHostsCallback(t *testing.T) {
+	newKey := func() ssh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	githubKey, hashedKey, wildcardKey, portKey, otherKey := newKey(), newKey(), newKey(), newKey(), newKey()
+
+	knownHosts := strings.Join([]string{
+		"# comment",
+		knownhosts.Line([]string{"github.com"}, githubKey),
+		knownhosts.HashHostname("hashed.example.com") + " " + string(ssh.MarshalAuthorizedKey(hashedKey)),
+		"*.example.org,!bad.example.org " + string(ssh.MarshalAuthorizedKey(wildcardKey)),
+		knownhosts.Line([]string{"git.example.com:2222"}, portKey),
+	}, "\n")
+
+	callback, err := knownHostsCallback(knownHosts)
+	if err != nil {
+		t.Fatalf("unexpected error parsing known hosts: %v", err)
+	}
+
+	for _, tc := range []struct {
+		hostname string
+		key      ssh.PublicKey
+		accepted bool
+	}{
+		{hostname: "github.com:22", key: githubKey, accepted: true},
+		{hostname: "github.com:22", key: otherKey, accepted: false},
+		{hostname: "gitlab.com:22", key: githubKey, accepted: false},
+		{hostname: "hashed.example.com:22", key: hashedKey, accepted: true},
+		{hostname: "other.example.com:22", key: hashedKey, accepted: false},
+		{hostname: "git.example.org:22", key: wildcardKey, accepted: true},
+		{hostname: "bad.example.org:22", key: wildcardKey, accepted: false},
+		{hostname: "git.example.com:2222", key: portKey, accepted: true},
+		{hostname: "git.example.com:22", key: portKey, accepted: false},
+	} {
+		err := callback(tc.hostname, nil, tc.key)
+		if accepted := err == nil; accepted != tc.accepted {
+			t.Errorf("expected host key for %s accepted to be %t but got err: %v", tc.hostname, tc.accepted, err)
+		}
+	}
+
+	if _, err := knownHostsCallback("github.com not-a-key"); err == nil {
+		t.Errorf("expected error parsing invalid known hosts")
+	}
+}
+
+func TestGetCloneAuth(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	hostKey, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := knownhosts.Line([]string{"github.com"}, hostKey)
+
+	newSecret := func(name string, data map[string]string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "foo"},
+			Data:       map[string][]byte{},
+		}
+		for k, v := range data {
+			secret.Data[k] = []byte(v)
+		}
+		return secret
+	}
+	resolver := &Resolver{kubeClient: fakek8s.NewSimpleClientset(
+		newSecret("token", map[string]string{tokenSecretKey: "s3cr3t-token\n"}),
+		newSecret("token-with-user", map[string]string{tokenSecretKey: "s3cr3t-token", usernameSecretKey: "bot"}),
+		newSecret("ssh", map[string]string{corev1.SSHAuthPrivateKey: string(privateKey)}),
+		newSecret("bad-ssh", map[string]string{corev1.SSHAuthPrivateKey: "s3cr3t-not-a-key"}),
+		newSecret("empty", nil),
+	)}
+
+	testCases := []struct {
+		name         string
+		url          string
+		secret       string
+		config       map[string]string
+		expectedAuth string
+		expectedErr  string
+	}{
+		{
+			name:         "no secret",
+			url:          "https://github.com/tektoncd/catalog.git",
+			expectedAuth: "<nil>",
+		},
+		{
+			name:         "https token",
+			url:          "https://github.com/tektoncd/catalog.git",
+			secret:       "token",
+			expectedAuth: "http-basic-auth - git:*******",
+		},
+		{
+			name:         "https token with username",
+			url:          "https://github.com/tektoncd/catalog.git",
+			secret:       "token-with-user",
+			expectedAuth: "http-basic-auth - bot:*******",
+		},
+		{
+			name:         "ssh private key",
+			url:          "git@github.com:tektoncd/catalog.git",
+			secret:       "ssh",
+			config:       map[string]string{sshKnownHostsKey: knownHosts},
+			expectedAuth: "user: git, name: ssh-public-keys",
+		},
+		{
+			name:        "ssh without known hosts",
+			url:         "git@github.com:tektoncd/catalog.git",
+			secret:      "ssh",
+			expectedErr: "cannot clone over ssh, 'ssh-known-hosts' is not set in config",
+		},
+		{
+			name:        "ssh with invalid private key",
+			url:         "ssh://git@github.com/tektoncd/catalog.git",
+			secret:      "bad-ssh",
+			config:      map[string]string{sshKnownHostsKey: knownHosts},
+			expectedErr: "cannot parse ssh private key in secret bad-ssh in namespace foo",
+		},
+		{
+			name:        "ssh secret without private key",
+			url:         "git@github.com:tektoncd/catalog.git",
+			secret:      "empty",
+			config:      map[string]string{sshKnownHostsKey: knownHosts},
+			expectedErr: "cannot get git credentials, key ssh-privatekey not found in secret empty in namespace foo",
+		},
+		{
+			name:        "https secret without token",
+			url:         "https://github.com/tektoncd/catalog.git",
+			secret:      "empty",
+			expectedErr: "cannot get git credentials, key token not found in secret empty in namespace foo",
+		},
+		{
+			name:        "plain http",
+			url:         "http://github.com/tektoncd/catalog.git",
+			secret:      "token",
+			expectedErr: "'secret' can only be used with https or ssh urls",
+		},
+		{
+			name:        "secret not found",
+			url:         "https://github.com/tektoncd/catalog.git",
+			secret:      "missing",
+			expectedErr: "cannot get git credentials, secret missing not found in namespace foo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := resolutioncommon.InjectRequestNamespace(framework.InjectResolverConfigToContext(resolverContext(), tc.config), "foo")
+			params := map[string]string{urlParam: tc.url}
+			if tc.secret != "" {
+				params[secretParam] = tc.secret
+			}
+			auth, err := resolver.getCloneAuth(ctx, params)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				if strings.Contains(err.Error(), "s3cr3t") {
+					t.Errorf("error leaked credentials: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedAuth, fmt.Sprint(auth)); d != "" {
+				t.Errorf("unexpected auth: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
 func TestGetResolutionTimeoutDefault(t *testing.T) {
 	resolver := Resolver{}
 	defaultTimeout := 30 * time.Minute
@@ -279,6 +621,37 @@ func TestResolve(t *testing.T) {
 			pathInRepo:     "foo/bar/somefile",
 			revision:       "tag1",
 			expectedStatus: createStatus([]byte("some content")),
+		}, {
+			name: "clone: lightweight tag revision",
+			commits: []commitForRepo{{
+				Dir:         "foo/bar",
+				Filename:    "somefile",
+				Content:     "some content",
+				Tag:         "tag1",
+				Lightweight: true,
+			}, {
+				Dir:      "foo/bar",
+				Filename: "somefile",
+				Content:  "different content",
+			}},
+			pathInRepo:     "foo/bar/somefile",
+			revision:       "tag1",
+			expectedStatus: createStatus([]byte("some content")),
+		}, {
+			name: "clone: full tag ref revision",
+			commits: []commitForRepo{{
+				Dir:      "foo/bar",
+				Filename: "somefile",
+				Content:  "some content",
+				Tag:      "tag1",
+			}, {
+				Dir:      "foo/bar",
+				Filename: "somefile",
+				Content:  "different content",
+			}},
+			pathInRepo:     "foo/bar/somefile",
+			revision:       "refs/tags/tag1",
+			expectedStatus: createStatus([]byte("some content")),
 		}, {
 			name: "clone: file does not exist",
 			commits: []commitForRepo{{
@@ -503,11 +876,18 @@ func TestResolve(t *testing.T) {
 
 					if reqParams[urlParam] != "" {
 						expectedStatus.Annotations[AnnotationKeyURL] = reqParams[urlParam]
+						expectedStatus.Annotations[AnnotationKeyCommit] = resolveTestRevision(t, repoPath, expectedStatus.Annotations[AnnotationKeyRevision])
+						for _, c := range tc.commits {
+							if c.Tag != "" && c.Tag == strings.TrimPrefix(tc.revision, "refs/tags/") {
+								expectedStatus.Annotations[AnnotationKeyTag] = c.Tag
+							}
+						}
 					} else {
 						expectedStatus.Annotations[AnnotationKeyOrg] = reqParams[orgParam]
 						expectedStatus.Annotations[AnnotationKeyRepo] = reqParams[repoParam]
 						expectedStatus.Annotations[AnnotationKeyURL] = fakeClone
 					}
+					expectedStatus.Annotations[resolutioncommon.AnnotationKeyProvenance] = expectedProvenance(expectedStatus.Annotations).AnnotationValue()
 				} else {
 					expectedStatus.Status.Conditions[0].Message = tc.expectedErr.Error()
 				}
@@ -536,8 +916,618 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+func TestResolveDirectory(t *testing.T) {
+	withTemporaryGitConfig(t)
+
+	const (
+		taskYAML     = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: build\n"
+		pipelineYAML = "apiVersion: tekton.dev/v1beta1\nkind: Pipeline\nmetadata:\n  name: release\n"
+	)
+	repoPath, _ := createTestRepo(t, []commitForRepo{{
+		Dir:      "single",
+		Filename: "build.yaml",
+		Content:  "# The build task.\n---\n" + taskYAML,
+	}, {
+		Dir:      "single",
+		Filename: "README.md",
+		Content:  "Not YAML",
+	}, {
+		Dir:      "multiple",
+		Filename: "task.yaml",
+		Content:  taskYAML,
+	}, {
+		Dir:      "multiple/pipelines",
+		Filename: "release.yml",
+		Content:  pipelineYAML + "---\n" + pipelineYAML,
+	}, {
+		Dir:      "empty",
+		Filename: "README.md",
+		Content:  "Not YAML",
+	}})
+
+	testCases := []struct {
+		name            string
+		pathInRepo      string
+		mode            string
+		expectedContent string
+		expectedErr     string
+	}{
+		{
+			name:            "single document",
+			pathInRepo:      "single",
+			expectedContent: taskYAML,
+		},
+		{
+			name:            "single document concatenated",
+			pathInRepo:      "single",
+			mode:            directoryModeConcatenate,
+			expectedContent: taskYAML,
+		},
+		{
+			name:        "multiple documents",
+			pathInRepo:  "multiple",
+			expectedErr: `directory "multiple" holds 3 YAML documents, in multiple/pipelines/release.yml (2), multiple/task.yaml (1): set the 'directoryMode' param to "concatenate" to resolve all of them as a multi-document stream, or set 'pathInRepo' to a single file`,
+		},
+		{
+			name:            "multiple documents concatenated",
+			pathInRepo:      "multiple",
+			mode:            directoryModeConcatenate,
+			expectedContent: pipelineYAML + "---\n" + pipelineYAML + "---\n" + taskYAML,
+		},
+		{
+			name:        "no documents",
+			pathInRepo:  "empty",
+			mode:        directoryModeConcatenate,
+			expectedErr: `directory "empty" holds no YAML documents in .yaml or .yml files`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{}
+			params := map[string]string{
+				urlParam:      repoPath,
+				pathParam:     tc.pathInRepo,
+				revisionParam: plumbing.Master.Short(),
+			}
+			if tc.mode != "" {
+				params[directoryModeParam] = tc.mode
+			}
+			output, err := resolver.Resolve(resolverContext(), toParams(params))
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.pathInRepo, output.Annotations()[AnnotationKeyPath]); d != "" {
+				t.Errorf("unexpected path annotation: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+// TestResolveTags checks that annotated tags, lightweight tags and
+// branches all resolve to the commit they point to, with the tag that
+// was named recorded alongside it.
+func TestResolveTags(t *testing.T) {
+	withTemporaryGitConfig(t)
+
+	repoPath, hashes := createTestRepo(t, []commitForRepo{{
+		Filename: "task.yaml",
+		Content:  "annotated",
+		Tag:      "v1",
+	}, {
+		Filename:    "task.yaml",
+		Content:     "lightweight",
+		Tag:         "v2",
+		Lightweight: true,
+	}, {
+		Filename: "task.yaml",
+		Content:  "branch",
+		Branch:   "release",
+	}, {
+		Filename: "task.yaml",
+		Content:  "latest",
+	}})
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("couldn't open test repo: %v", err)
+	}
+	tagObject, err := repo.Tag("v1")
+	if err != nil {
+		t.Fatalf("couldn't get tag v1: %v", err)
+	}
+	master := plumbing.Master.Short()
+
+	for _, tc := range []struct {
+		name            string
+		revision        string
+		expectedContent string
+		expectedCommit  string
+		expectedTag     string
+	}{{
+		name:            "annotated tag",
+		revision:        "v1",
+		expectedContent: "annotated",
+		expectedCommit:  hashes[master][0],
+		expectedTag:     "v1",
+	}, {
+		name:            "annotated tag ref",
+		revision:        "refs/tags/v1",
+		expectedContent: "annotated",
+		expectedCommit:  hashes[master][0],
+		expectedTag:     "v1",
+	}, {
+		name:            "lightweight tag",
+		revision:        "v2",
+		expectedContent: "lightweight",
+		expectedCommit:  hashes[master][1],
+		expectedTag:     "v2",
+	}, {
+		name:            "branch",
+		revision:        "release",
+		expectedContent: "branch",
+		expectedCommit:  hashes["release"][0],
+	}, {
+		name:            "commit",
+		revision:        hashes[master][2],
+		expectedContent: "latest",
+		expectedCommit:  hashes[master][2],
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				urlParam:      repoPath,
+				pathParam:     "task.yaml",
+				revisionParam: tc.revision,
+			}
+			if err := (&Resolver{}).ValidateParams(resolverContext(), toParams(params)); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			output, err := (&Resolver{}).Resolve(resolverContext(), toParams(params))
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+			annotations := output.Annotations()
+			if d := cmp.Diff(tc.expectedCommit, annotations[AnnotationKeyCommit]); d != "" {
+				t.Errorf("unexpected commit: %s", diff.PrintWantGot(d))
+			}
+			if annotations[AnnotationKeyCommit] == tagObject.Hash().String() {
+				t.Errorf("expected the commit rather than the annotated tag object")
+			}
+			if d := cmp.Diff(tc.expectedTag, annotations[AnnotationKeyTag]); d != "" {
+				t.Errorf("unexpected tag: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.revision, annotations[AnnotationKeyRevision]); d != "" {
+				t.Errorf("unexpected revision: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveSubmodules(t *testing.T) {
+	withTemporaryGitConfig(t)
+
+	subRepoPath, _ := createTestRepo(t, []commitForRepo{{
+		Dir:      "tasks",
+		Filename: "task.yaml",
+		Content:  "in submodule",
+	}})
+	repoPath := createTestRepoWithSubmodule(t, "lib", subRepoPath)
+
+	for _, tc := range []struct {
+		name            string
+		submodules      string
+		path            string
+		expectedContent string
+		expectedErr     string
+	}{{
+		name:            "file in submodule",
+		submodules:      "true",
+		path:            "lib/tasks/task.yaml",
+		expectedContent: "in submodule",
+	}, {
+		name:            "file outside of submodule",
+		submodules:      "true",
+		path:            "task.yaml",
+		expectedContent: "in superproject",
+	}, {
+		name:            "file outside of submodule without submodules",
+		path:            "task.yaml",
+		expectedContent: "in superproject",
+	}, {
+		name:        "file in submodule without submodules",
+		path:        "lib/tasks/task.yaml",
+		expectedErr: `'pathInRepo' "lib/tasks/task.yaml" is in submodule "lib", which is only initialized when the 'submodules' param is "true"`,
+	}, {
+		name:        "submodule itself with submodules disabled",
+		submodules:  "false",
+		path:        "./lib",
+		expectedErr: `'pathInRepo' "./lib" is in submodule "lib", which is only initialized when the 'submodules' param is "true"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				urlParam:      repoPath,
+				pathParam:     tc.path,
+				revisionParam: plumbing.Master.Short(),
+			}
+			if tc.submodules != "" {
+				params[submodulesParam] = tc.submodules
+			}
+			if err := (&Resolver{}).ValidateParams(resolverContext(), toParams(params)); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			output, err := (&Resolver{}).Resolve(resolverContext(), toParams(params))
+			if tc.expectedErr != "" {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("expected error %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected bool
+	}{
+		{a: "https://github.com/org/repo", b: "https://github.com/org/other.git", expected: true},
+		{a: "git@github.com:org/repo.git", b: "ssh://git@github.com/org/other.git", expected: true},
+		{a: "https://github.com/org/repo", b: "git@github.com:org/other.git", expected: false},
+		{a: "https://github.com/org/repo", b: "https://gitlab.com/org/repo", expected: false},
+		{a: "https://github.com/org/repo", b: "https://github.com:443/org/repo", expected: true},
+		{a: "https://github.com/org/repo", b: "https://github.com:8443/org/repo", expected: false},
+		{a: "https://github.com/org/repo", b: "http://github.com/org/repo", expected: false},
+	} {
+		if got := sameHost(tc.a, tc.b); got != tc.expected {
+			t.Errorf("expected sameHost(%q, %q) to be %t", tc.a, tc.b, tc.expected)
+		}
+	}
+}
+
+func TestCloneRevisionShallow(t *testing.T) {
+	withTemporaryGitConfig(t)
+
+	repoPath, hashes := createTestRepo(t, []commitForRepo{{
+		Filename: "task.yaml",
+		Content:  "first",
+		Tag:      "v1",
+	}, {
+		Filename: "task.yaml",
+		Content:  "second",
+	}, {
+		Filename: "task.yaml",
+		Content:  "third",
+	}})
+	master := plumbing.Master.Short()
+	// The README commit the test repo starts with and the three above.
+	const allCommits = 4
+
+	for _, tc := range []struct {
+		name            string
+		revision        string
+		config          map[string]string
+		expectedHead    string
+		expectedCommits int
+	}{{
+		name:            "branch",
+		revision:        master,
+		expectedHead:    hashes[master][2],
+		expectedCommits: 1,
+	}, {
+		name:            "branch ref",
+		revision:        "refs/heads/" + master,
+		expectedHead:    hashes[master][2],
+		expectedCommits: 1,
+	}, {
+		name:            "tag",
+		revision:        "v1",
+		expectedHead:    hashes[master][0],
+		expectedCommits: 1,
+	}, {
+		name:            "commit",
+		revision:        hashes[master][1],
+		expectedHead:    hashes[master][1],
+		expectedCommits: allCommits,
+	}, {
+		name:            "disabled",
+		revision:        master,
+		config:          map[string]string{shallowCloneKey: "false"},
+		expectedHead:    hashes[master][2],
+		expectedCommits: allCommits,
+	}, {
+		name:            "invalid config",
+		revision:        master,
+		config:          map[string]string{shallowCloneKey: "sometimes"},
+		expectedHead:    hashes[master][2],
+		expectedCommits: 1,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			repository, err := cloneRevision(ctx, repoPath, tc.revision, nil, memfs.New())
+			if err != nil {
+				t.Fatalf("unexpected error cloning: %v", err)
+			}
+			h, err := repository.ResolveRevision(plumbing.Revision(tc.revision))
+			if err != nil {
+				t.Fatalf("unexpected error resolving revision: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedHead, h.String()); d != "" {
+				t.Errorf("unexpected commit: %s", diff.PrintWantGot(d))
+			}
+			commits, err := repository.CommitObjects()
+			if err != nil {
+				t.Fatalf("unexpected error listing commits: %v", err)
+			}
+			count := 0
+			if err := commits.ForEach(func(*object.Commit) error {
+				count++
+				return nil
+			}); err != nil {
+				t.Fatalf("unexpected error counting commits: %v", err)
+			}
+			if count != tc.expectedCommits {
+				t.Errorf("expected %d commits to be cloned but got %d", tc.expectedCommits, count)
+			}
+		})
+	}
+}
+
+// BenchmarkResolveClone compares resolving a single file from a
+// repository with a long history using shallow and full clones.
+func BenchmarkResolveClone(b *testing.B) {
+	withTemporaryGitConfig(b)
+
+	commits := make([]commitForRepo, 200)
+	for i := range commits {
+		commits[i] = commitForRepo{
+			Filename: fmt.Sprintf("file-%d.yaml", i%20),
+			Content:  strings.Repeat(fmt.Sprintf("revision %d\n", i), 500),
+		}
+	}
+	repoPath, _ := createTestRepo(b, commits)
+	params := toParams(map[string]string{
+		urlParam:      repoPath,
+		pathParam:     "file-0.yaml",
+		revisionParam: plumbing.Master.Short(),
+	})
+
+	for _, shallow := range []string{"true", "false"} {
+		b.Run("shallow-clone="+shallow, func(b *testing.B) {
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+				shallowCloneKey: shallow,
+			})
+			for i := 0; i < b.N; i++ {
+				if _, err := (&Resolver{}).Resolve(ctx, params); err != nil {
+					b.Fatalf("unexpected error resolving: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveAPICommit(t *testing.T) {
+	const commitSHA = "0aac385673e1efe00c4c22d13209e0f8c00b0c28"
+	for _, tc := range []struct {
+		name           string
+		commits        map[string]*scm.Commit
+		expectedCommit string
+	}{{
+		name:           "commit found",
+		commits:        map[string]*scm.Commit{"main": {Sha: commitSHA}},
+		expectedCommit: commitSHA,
+	}, {
+		name: "commit unknown",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{
+				kubeClient: fakek8s.NewSimpleClientset(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "token-secret", Namespace: "foo"},
+					Data:       map[string][]byte{"token": []byte("some-token")},
+				}),
+				cache: cache.NewLRUExpireCache(cacheSize),
+				clientFunc: func(driver string, serverURL string, token string, opts ...factory.ClientOptionFunc) (*scm.Client, error) {
+					scmClient, scmData := fake.NewDefault()
+					scmData.Repositories = []*scm.Repository{{
+						FullName: "test-org/test-repo",
+						Clone:    "https://fake/test-org/test-repo.git",
+					}}
+					scmData.Commits = tc.commits
+					return scmClient, nil
+				},
+			}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+				SCMTypeKey:            "fake",
+				APISecretNameKey:      "token-secret",
+				APISecretKeyKey:       "token",
+				APISecretNamespaceKey: "foo",
+			})
+			output, err := resolver.Resolve(ctx, toParams(map[string]string{
+				orgParam:      "test-org",
+				repoParam:     "test-repo",
+				pathParam:     "tasks/example-task.yaml",
+				revisionParam: "main",
+			}))
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			commit, ok := output.Annotations()[AnnotationKeyCommit]
+			if tc.expectedCommit == "" && ok {
+				t.Errorf("expected no commit annotation but got %q", commit)
+			}
+			if d := cmp.Diff(tc.expectedCommit, commit); d != "" {
+				t.Errorf("unexpected commit: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveAPIDirectory(t *testing.T) {
+	refsDir := filepath.Join("testdata", "test-org", "test-repo", "refs", "main")
+	mainTaskYAML, err := ioutil.ReadFile(filepath.Join(refsDir, "tasks", "example-task.yaml"))
+	if err != nil {
+		t.Fatalf("couldn't read main task: %v", err)
+	}
+	mainPipelineYAML, err := ioutil.ReadFile(filepath.Join(refsDir, "pipelines", "example-pipeline.yaml"))
+	if err != nil {
+		t.Fatalf("couldn't read main pipeline: %v", err)
+	}
+
+	resolver := &Resolver{
+		kubeClient: fakek8s.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token-secret", Namespace: "foo"},
+			Data:       map[string][]byte{"token": []byte("some-token")},
+		}),
+		cache: cache.NewLRUExpireCache(cacheSize),
+		clientFunc: func(driver string, serverURL string, token string, opts ...factory.ClientOptionFunc) (*scm.Client, error) {
+			scmClient, scmData := fake.NewDefault()
+			scmData.Repositories = []*scm.Repository{{
+				FullName: "test-org/test-repo",
+				Clone:    "https://fake/test-org/test-repo.git",
+			}}
+			return scmClient, nil
+		},
+	}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		SCMTypeKey:            "fake",
+		APISecretNameKey:      "token-secret",
+		APISecretKeyKey:       "token",
+		APISecretNamespaceKey: "foo",
+	})
+
+	for _, tc := range []struct {
+		name            string
+		pathInRepo      string
+		mode            string
+		expectedContent string
+		expectedErr     string
+	}{{
+		name:            "single document",
+		pathInRepo:      "tasks",
+		expectedContent: strings.TrimRight(string(mainTaskYAML), "\n") + "\n",
+	}, {
+		name:        "multiple documents",
+		pathInRepo:  ".",
+		expectedErr: `directory "." holds 2 YAML documents, in pipelines/example-pipeline.yaml (1), tasks/example-task.yaml (1): set the 'directoryMode' param to "concatenate" to resolve all of them as a multi-document stream, or set 'pathInRepo' to a single file`,
+	}, {
+		name:       "multiple documents concatenated",
+		pathInRepo: ".",
+		mode:       directoryModeConcatenate,
+		expectedContent: strings.TrimRight(string(mainPipelineYAML), "\n") + "\n---\n" +
+			strings.TrimRight(string(mainTaskYAML), "\n") + "\n",
+	}, {
+		name:        "missing directory",
+		pathInRepo:  "other",
+		expectedErr: "couldn't fetch resource content: file testdata/test-org/test-repo/refs/main/other does not exist: stat testdata/test-org/test-repo/refs/main/other: no such file or directory",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				orgParam:      "test-org",
+				repoParam:     "test-repo",
+				pathParam:     tc.pathInRepo,
+				revisionParam: "main",
+			}
+			if tc.mode != "" {
+				params[directoryModeParam] = tc.mode
+			}
+			output, err := resolver.Resolve(ctx, toParams(params))
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected content: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestGetAPITokenCachedIsRedacted(t *testing.T) {
+	const token = "cached-api-token"
+	resolver := &Resolver{
+		kubeClient: fakek8s.NewSimpleClientset(),
+		cache:      cache.NewLRUExpireCache(cacheSize),
+		ttl:        time.Minute,
+	}
+	resolver.cache.Add(secretCacheKey{ns: "foo", name: "token-secret", key: "token"}, []byte(token), time.Minute)
+
+	ctx := framework.WithRedaction(framework.InjectResolverConfigToContext(resolverContext(), map[string]string{
+		APISecretNameKey:      "token-secret",
+		APISecretKeyKey:       "token",
+		APISecretNamespaceKey: "foo",
+	}))
+	got, err := resolver.getAPIToken(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error getting the API token: %v", err)
+	}
+	if string(got) != token {
+		t.Fatalf("expected the cached token %q but got %q", token, got)
+	}
+	if redacted := framework.RedactorFromContext(ctx).Redact("using " + token); redacted != "using "+framework.RedactedValue {
+		t.Errorf("expected the cached token to be redacted but got %q", redacted)
+	}
+}
+
+func TestGetAPITokenLogsToContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logging.WithLogger(resolverContext(), zap.New(core).Sugar())
+	ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{
+		APISecretKeyKey: "token",
+	})
+	resolver := &Resolver{kubeClient: fakek8s.NewSimpleClientset(), cache: cache.NewLRUExpireCache(cacheSize)}
+	if _, err := resolver.getAPIToken(ctx); err == nil {
+		t.Fatal("expected an error getting the API token without a secret name")
+	}
+	if logs.FilterMessageSnippet(APISecretNameKey).Len() == 0 {
+		t.Errorf("expected the error to be logged with the context's logger but got %v", logs.All())
+	}
+}
+
+// resolveTestRevision returns the SHA of the commit the revision resolves
+// to in the local test repository.
+func resolveTestRevision(t *testing.T, repoPath, revision string) string {
+	t.Helper()
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("couldn't open test repo: %v", err)
+	}
+	h, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		t.Fatalf("couldn't resolve revision %s in test repo: %v", revision, err)
+	}
+	return h.String()
+}
+
 // createTestRepo is used to instantiate a local test repository with the desired commits.
-func createTestRepo(t *testing.T, commits []commitForRepo) (string, map[string][]string) {
+func createTestRepo(t testing.TB, commits []commitForRepo) (string, map[string][]string) {
 	t.Helper()
 	tempDir := t.TempDir()
 
@@ -584,7 +1574,10 @@ func createTestRepo(t *testing.T, commits []commitForRepo) (string, map[string][
 			hashesByBranch[branch] = append(hashesByBranch[branch], hash.String())
 		}
 
-		if cmt.Tag != "" {
+		switch {
+		case cmt.Tag != "" && cmt.Lightweight:
+			_, err = repo.CreateTag(cmt.Tag, hash, nil)
+		case cmt.Tag != "":
 			_, err = repo.CreateTag(cmt.Tag, hash, &git.CreateTagOptions{
 				Message: cmt.Tag,
 				Tagger: &object.Signature{
@@ -602,6 +1595,49 @@ func createTestRepo(t *testing.T, commits []commitForRepo) (string, map[string][
 	return tempDir, hashesByBranch
 }
 
+// createTestRepoWithSubmodule creates a local test repository with a
+// task.yaml file and the repository at subRepoPath as a submodule at
+// the given path, pinned to the commit its HEAD points to.
+func createTestRepoWithSubmodule(t testing.TB, path, subRepoPath string) string {
+	t.Helper()
+	repoPath, _ := createTestRepo(t, []commitForRepo{{
+		Filename: "task.yaml",
+		Content:  "in superproject",
+	}})
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		t.Fatalf("couldn't open test repo: %v", err)
+	}
+	subRepo, err := git.PlainOpen(subRepoPath)
+	if err != nil {
+		t.Fatalf("couldn't open test submodule repo: %v", err)
+	}
+	subHead, err := subRepo.Head()
+	if err != nil {
+		t.Fatalf("couldn't get HEAD of test submodule repo: %v", err)
+	}
+
+	// The submodule is recorded as a gitlink in the index, which is
+	// committed along with the .gitmodules file.
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("couldn't read index of test repo: %v", err)
+	}
+	entry := idx.Add(path)
+	entry.Mode = filemode.Submodule
+	entry.Hash = subHead.Hash()
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		t.Fatalf("couldn't write index of test repo: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("getting test worktree: %v", err)
+	}
+	gitmodules := fmt.Sprintf("[submodule %q]\n\tpath = %s\n\turl = %s\n", path, path, subRepoPath)
+	writeAndCommitToTestRepo(t, worktree, repoPath, "", ".gitmodules", []byte(gitmodules))
+	return repoPath
+}
+
 // commitForRepo provides the directory, filename, content and revision for a test commit.
 type commitForRepo struct {
 	Dir      string
@@ -609,9 +1645,12 @@ type commitForRepo struct {
 	Content  string
 	Branch   string
 	Tag      string
+	// Lightweight creates Tag as a lightweight tag rather than an
+	// annotated one.
+	Lightweight bool
 }
 
-func writeAndCommitToTestRepo(t *testing.T, worktree *git.Worktree, repoDir string, subPath string, filename string, content []byte) plumbing.Hash {
+func writeAndCommitToTestRepo(t testing.TB, worktree *git.Worktree, repoDir string, subPath string, filename string, content []byte) plumbing.Hash {
 	t.Helper()
 
 	targetDir := repoDir
@@ -655,7 +1694,7 @@ func writeAndCommitToTestRepo(t *testing.T, worktree *git.Worktree, repoDir stri
 }
 
 // withTemporaryGitConfig resets the .gitconfig for the duration of the test.
-func withTemporaryGitConfig(t *testing.T) {
+func withTemporaryGitConfig(t testing.TB) {
 	gitConfigDir := t.TempDir()
 	key := "GIT_CONFIG_GLOBAL"
 	t.Setenv(key, filepath.Join(gitConfigDir, "config"))
@@ -765,3 +1804,27 @@ func toParams(m map[string]string) []pipelinev1beta1.Param {
 
 	return params
 }
+
+// expectedProvenance returns the provenance expected alongside the
+// given annotations of a resolved file.
+func expectedProvenance(annotations map[string]string) resolutioncommon.Provenance {
+	p := resolutioncommon.Provenance{
+		ResolverType: labelValueGitResolverType,
+		URI:          annotations[AnnotationKeyURL],
+		Coordinates: map[string]string{
+			"revision": annotations[AnnotationKeyRevision],
+			"path":     annotations[AnnotationKeyPath],
+		},
+	}
+	if commit := annotations[AnnotationKeyCommit]; commit != "" {
+		p.Digest = map[string]string{"sha1": commit}
+	}
+	if org := annotations[AnnotationKeyOrg]; org != "" {
+		p.Coordinates["org"] = org
+		p.Coordinates["repo"] = annotations[AnnotationKeyRepo]
+	}
+	if tag := annotations[AnnotationKeyTag]; tag != "" {
+		p.Coordinates["tag"] = tag
+	}
+	return p
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// updateSubmodules initializes the submodules of the checked out
+// worktree, and theirs in turn, and checks out the commits the worktree
+// records for them. The credentials used to clone the repo are only
+// used for submodules hosted on the same host, so that they aren't sent
+// to a host the submodules file happens to name.
+func updateSubmodules(ctx context.Context, w *git.Worktree, repoURL string, auth transport.AuthMethod) (err error) {
+	ctx, span := framework.StartSpan(ctx, "git.UpdateSubmodules")
+	defer func() { framework.EndSpan(span, err) }()
+
+	submodules, err := w.Submodules()
+	if err != nil {
+		return fmt.Errorf("error reading submodules: %w", err)
+	}
+	for _, submodule := range submodules {
+		opts := &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		}
+		if sameHost(repoURL, submodule.Config().URL) {
+			opts.Auth = auth
+		}
+		if err := submodule.UpdateContext(ctx, opts); err != nil {
+			return fmt.Errorf("error updating submodule %q: %w", submodule.Config().Path, err)
+		}
+	}
+	return nil
+}
+
+// checkNotInSubmodule returns an error if pathInRepo is within one of
+// the submodules of the worktree, which are left uninitialized, so that
+// it doesn't fail as a file that isn't found or an empty directory.
+func checkNotInSubmodule(w *git.Worktree, pathInRepo string) error {
+	submodules, err := w.Submodules()
+	if err != nil {
+		return fmt.Errorf("error reading submodules: %w", err)
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+pathInRepo), "/")
+	for _, submodule := range submodules {
+		dir := strings.Trim(path.Clean(submodule.Config().Path), "/")
+		if cleaned == dir || strings.HasPrefix(cleaned, dir+"/") {
+			return fmt.Errorf("'%s' %q is in submodule %q, which is only initialized when the '%s' param is \"true\"", pathParam, pathInRepo, dir, submodulesParam)
+		}
+	}
+	return nil
+}
+
+// defaultPorts are the ports of the protocols a url can omit its port
+// for, which transport.NewEndpoint leaves as 0.
+var defaultPorts = map[string]int{
+	"http":  80,
+	"https": 443,
+	"ssh":   22,
+	"git":   9418,
+}
+
+// sameHost returns true if both urls, which may be scp-like ssh urls
+// such as git@host:org/repo, use the same protocol to point to the same
+// host and port, so that credentials for one can be used for the other.
+func sameHost(a, b string) bool {
+	endpointA, err := transport.NewEndpoint(a)
+	if err != nil {
+		return false
+	}
+	endpointB, err := transport.NewEndpoint(b)
+	if err != nil {
+		return false
+	}
+	return endpointA.Protocol == endpointB.Protocol &&
+		endpointA.Host == endpointB.Host &&
+		endpointPort(endpointA) == endpointPort(endpointB)
+}
+
+// endpointPort returns the port of the endpoint, or the default port of
+// its protocol if the url didn't have one.
+func endpointPort(endpoint *transport.Endpoint) int {
+	if endpoint.Port == 0 {
+		return defaultPorts[endpoint.Protocol]
+	}
+	return endpoint.Port
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+
+// ConfigFetchTimeout is the configuration field name for the maximum
+// duration of fetching the repository's index and the chart together.
+// Defaults to 1m.
+const ConfigFetchTimeout = framework.ConfigFetchTimeout
+
+// ConfigMaxResponseSize is the configuration field name for the maximum
+// size of a chart archive, and of the file extracted from it, e.g.
+// "10Mi". Defaults to 10Mi.
+const ConfigMaxResponseSize = framework.ConfigMaxResponseSize
+
+// ConfigMaxIndexSize is the configuration field name for the maximum
+// size of a repository's index.yaml, which lists every version of every
+// chart in the repository and so can be much larger than a chart, e.g.
+// "50Mi". Defaults to 50Mi.
+const ConfigMaxIndexSize = "max-index-size"
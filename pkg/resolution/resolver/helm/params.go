@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+// ParamRepo is the parameter defining the url of the chart repository,
+// the directory serving its index.yaml.
+const ParamRepo = "repo"
+
+// ParamChart is the parameter defining the name of the chart.
+const ParamChart = "chart"
+
+// ParamVersion is the parameter defining the version of the chart to
+// fetch. The highest version that isn't a prerelease is fetched when it
+// isn't given.
+const ParamVersion = "version"
+
+// ParamPath is the parameter defining the path of the file to extract,
+// relative to the root of the chart, e.g. "tekton/task.yaml".
+const ParamPath = "path"
+
+// ParamSecret is the parameter defining the name of a secret, in the
+// namespace of the resolution request, holding the username and
+// password used to authenticate to the chart repository.
+const ParamSecret = "secret"
@@ -0,0 +1,657 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/client/injection/kube/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// LabelValueHelmResolverType is the value to use for the
+	// resolution.tekton.dev/type label on resource requests
+	LabelValueHelmResolverType string = "helm"
+
+	disabledError = "cannot handle resolution request, enable-helm-resolver feature flag not true"
+
+	// defaultMaxIndexSize is the maximum size of a repository's index
+	// when the max-index-size config isn't set.
+	defaultMaxIndexSize = 50 * 1024 * 1024
+
+	// usernameSecretKey and passwordSecretKey are the keys in the secret
+	// named by the secret param holding the credentials used to
+	// authenticate to the repository.
+	usernameSecretKey = "username"
+	passwordSecretKey = "password"
+
+	// maxDecompressionRatio bounds the size of a chart archive once
+	// decompressed, as a multiple of the max response size.
+	maxDecompressionRatio = 10
+)
+
+// chartNameRegex matches valid chart names.
+var chartNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+var _ framework.Resolver = &Resolver{}
+
+// Resolver implements a framework.Resolver that can extract files from
+// charts published to Helm chart repositories.
+type Resolver struct {
+	kubeClientSet kubernetes.Interface
+	// httpClient is the client used to fetch indexes and charts.
+	// Defaults to the framework's shared client.
+	httpClient *http.Client
+}
+
+// Initialize sets up the kubernetes client used to read repository
+// credentials.
+func (r *Resolver) Initialize(ctx context.Context) error {
+	r.kubeClientSet = client.Get(ctx)
+	return nil
+}
+
+// GetName returns a string name to refer to this resolver by.
+func (r *Resolver) GetName(context.Context) string {
+	return "Helm"
+}
+
+// GetConfigName returns the name of the helm resolver's configmap.
+func (r *Resolver) GetConfigName(context.Context) string {
+	return "helm-resolver-config"
+}
+
+// GetSelector returns a map of labels to match requests to this resolver.
+func (r *Resolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{
+		common.LabelKeyResolverType: LabelValueHelmResolverType,
+	}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver.
+func (r *Resolver) GetParamSchema(context.Context) []framework.ParamSchema {
+	return []framework.ParamSchema{{
+		Name:        ParamRepo,
+		Description: "The http or https url of the chart repository, the directory serving its index.yaml.",
+		Required:    true,
+	}, {
+		Name:        ParamChart,
+		Description: "The name of the chart.",
+		Required:    true,
+	}, {
+		Name:        ParamVersion,
+		Description: "The version of the chart. The highest version that isn't a prerelease is fetched when omitted.",
+	}, {
+		Name:        ParamPath,
+		Description: "The path of the file to extract, relative to the root of the chart.",
+		Required:    true,
+	}, {
+		Name:        ParamSecret,
+		Description: "The name of a secret, in the namespace of the request, holding the username and password of the chart repository.",
+	}}
+}
+
+var _ framework.ParamTracer = &Resolver{}
+
+// TracedParams returns the params recorded on the spans of resolutions,
+// which identify the requested file.
+func (r *Resolver) TracedParams(context.Context) []string {
+	return []string{ParamRepo, ParamChart, ParamVersion, ParamPath}
+}
+
+// ValidateParams ensures parameters from a request are as expected. The
+// credentials secret they reference is only read when the chart is
+// resolved.
+func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if r.isDisabled(ctx) {
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	_, err := newRequestOptions(ctx, framework.StringParams(params))
+	return err
+}
+
+// Resolve uses the given params to fetch the requested chart and
+// extract the requested file from it.
+func (r *Resolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	if r.isDisabled(ctx) {
+		return nil, common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	opts, err := newRequestOptions(ctx, framework.StringParams(params))
+	if err != nil {
+		return nil, err
+	}
+	auth, err := r.getAuth(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	indexURL := opts.indexURL()
+	indexData, err := r.get(ctx, opts, auth, indexURL, opts.maxIndexSize)
+	if err != nil {
+		var notFound *common.ResolutionNotFoundError
+		if errors.As(err, &notFound) {
+			notFound.Original = fmt.Errorf("no chart repository index found at %s", indexURL)
+		}
+		return nil, err
+	}
+	var index repoIndex
+	if err := yaml.Unmarshal(indexData, &index); err != nil {
+		return nil, fmt.Errorf("error parsing chart repository index %s: %w", indexURL, err)
+	}
+	entry, err := findChartVersion(opts, index)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s in repository %s has no urls", opts.chartRef(entry.Version), opts.repo)
+	}
+	chartURL, err := opts.repo.Parse(entry.URLs[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q for chart %s in repository %s: %w", entry.URLs[0], opts.chartRef(entry.Version), opts.repo, err)
+	}
+
+	archive, err := r.get(ctx, opts, auth, chartURL, opts.maxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Digest != "" {
+		sum := sha256.Sum256(archive)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, entry.Digest) {
+			return nil, fmt.Errorf("digest mismatch for chart %s from %s: the repository index lists sha256:%s but got sha256:%s", opts.chartRef(entry.Version), chartURL, entry.Digest, got)
+		}
+	}
+	content, err := extractFile(archive, opts, entry.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedHelmResource{
+		Content: content,
+		Repo:    opts.repo.String(),
+		Chart:   opts.chart,
+		Version: entry.Version,
+		Path:    opts.path,
+		URL:     chartURL.String(),
+	}, nil
+}
+
+// GetResolutionTimeout returns a time.Duration for the amount of time
+// fetching a repository's index and chart may take. This can be
+// configured with the fetch-timeout field in the helm-resolver-config
+// configmap.
+func (r *Resolver) GetResolutionTimeout(ctx context.Context, defaultTimeout time.Duration) time.Duration {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if timeoutString, ok := conf[ConfigFetchTimeout]; ok {
+		timeout, err := time.ParseDuration(timeoutString)
+		if err == nil {
+			return timeout
+		}
+	}
+	return defaultTimeout
+}
+
+// requestOptions are the settings used to extract a single file.
+type requestOptions struct {
+	// repo is the url of the repository, always ending in a slash so
+	// that relative chart urls resolve against it.
+	repo  *url.URL
+	chart string
+	// version is the version of the chart to fetch, or empty for the
+	// latest.
+	version string
+	path    string
+	secret  string
+	timeout time.Duration
+	// maxResponseSize is the maximum size in bytes of the chart archive
+	// and of the extracted file.
+	maxResponseSize int64
+	// maxIndexSize is the maximum size in bytes of the index.
+	maxIndexSize int64
+}
+
+// newRequestOptions returns the settings for extracting a file given
+// the resolution's params and the resolver's config.
+func newRequestOptions(ctx context.Context, params map[string]string) (requestOptions, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	opts := requestOptions{
+		chart:        params[ParamChart],
+		version:      params[ParamVersion],
+		path:         params[ParamPath],
+		maxIndexSize: defaultMaxIndexSize,
+	}
+
+	var missingParams []string
+	for _, p := range []string{ParamRepo, ParamChart, ParamPath} {
+		if params[p] == "" {
+			missingParams = append(missingParams, p)
+		}
+	}
+	if len(missingParams) > 0 {
+		return opts, fmt.Errorf("missing required helm resolver params: %s", strings.Join(missingParams, ", "))
+	}
+
+	repo := params[ParamRepo]
+	u, err := url.Parse(repo)
+	if err != nil {
+		return opts, fmt.Errorf("invalid %s param: %w", ParamRepo, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return opts, fmt.Errorf("invalid %s param %q: scheme must be http or https", ParamRepo, repo)
+	}
+	if u.Host == "" {
+		return opts, fmt.Errorf("invalid %s param %q: missing host", ParamRepo, repo)
+	}
+	if u.User != nil {
+		return opts, fmt.Errorf("invalid %s param %q: credentials must be given with the %s param rather than in the url", ParamRepo, u.Redacted(), ParamSecret)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return opts, fmt.Errorf("invalid %s param %q: must not include a query or fragment", ParamRepo, repo)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		if u.RawPath != "" {
+			u.RawPath += "/"
+		}
+	}
+	opts.repo = u
+
+	if !chartNameRegex.MatchString(opts.chart) {
+		return opts, fmt.Errorf("invalid %s param %q: must be letters, numbers, dots, hyphens or underscores, starting with a letter or number", ParamChart, opts.chart)
+	}
+	if opts.version != "" {
+		if _, err := goversion.NewVersion(opts.version); err != nil {
+			return opts, fmt.Errorf("invalid %s param %q: must be a semantic version", ParamVersion, opts.version)
+		}
+	}
+	if path.IsAbs(opts.path) || path.Clean(opts.path) != opts.path || opts.path == "." || opts.path == ".." || strings.HasPrefix(opts.path, "../") {
+		return opts, fmt.Errorf("invalid %s param %q: must be a clean path relative to the root of the chart", ParamPath, opts.path)
+	}
+	if secret, ok := params[ParamSecret]; ok {
+		if secret == "" {
+			return opts, fmt.Errorf("%s param must not be empty", ParamSecret)
+		}
+		opts.secret = secret
+	}
+
+	fetchOpts, err := framework.FetchOptionsFromConfig(conf)
+	if err != nil {
+		return opts, err
+	}
+	opts.timeout, opts.maxResponseSize = fetchOpts.Timeout, fetchOpts.MaxResponseSize
+	if maxSize, ok := conf[ConfigMaxIndexSize]; ok {
+		size, err := resource.ParseQuantity(maxSize)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigMaxIndexSize, err)
+		}
+		if size.Sign() <= 0 {
+			return opts, fmt.Errorf("invalid %s config: must be greater than zero, got %q", ConfigMaxIndexSize, maxSize)
+		}
+		opts.maxIndexSize = size.Value()
+	}
+	return opts, nil
+}
+
+// indexURL returns the url of the repository's index.
+func (o requestOptions) indexURL() *url.URL {
+	u, _ := o.repo.Parse("index.yaml")
+	return u
+}
+
+// chartRef returns the name of the chart at the given version, e.g.
+// "mychart-1.2.3", used to refer to it in errors.
+func (o requestOptions) chartRef(version string) string {
+	return o.chart + "-" + version
+}
+
+// repoIndex is the part of a repository's index.yaml the resolver
+// reads.
+type repoIndex struct {
+	Entries map[string][]chartVersion `json:"entries"`
+}
+
+// chartVersion is an entry of a repository's index for one version of
+// a chart.
+type chartVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Digest is the hex encoded SHA-256 digest of the chart archive.
+	Digest string   `json:"digest"`
+	URLs   []string `json:"urls"`
+}
+
+// findChartVersion returns the index entry of the requested version of
+// the chart, or of its highest version that isn't a prerelease when no
+// version was requested.
+func findChartVersion(opts requestOptions, index repoIndex) (chartVersion, error) {
+	versions, ok := index.Entries[opts.chart]
+	if !ok || len(versions) == 0 {
+		return chartVersion{}, &common.ResolutionNotFoundError{
+			Resource: opts.chart,
+			Original: fmt.Errorf("chart %q not found in repository %s", opts.chart, opts.repo),
+		}
+	}
+
+	var requested, best *goversion.Version
+	if opts.version != "" {
+		// The version param was already validated.
+		requested, _ = goversion.NewVersion(opts.version)
+	}
+	var found chartVersion
+	var available []string
+	for _, entry := range versions {
+		available = append(available, entry.Version)
+		v, err := goversion.NewVersion(entry.Version)
+		if err != nil {
+			// Skip any versions published in a format we can't compare.
+			continue
+		}
+		if requested != nil {
+			if v.Equal(requested) {
+				return entry, nil
+			}
+			continue
+		}
+		if v.Prerelease() == "" && (best == nil || v.GreaterThan(best)) {
+			best, found = v, entry
+		}
+	}
+	if best != nil {
+		return found, nil
+	}
+	sort.Strings(available)
+	if requested != nil {
+		return chartVersion{}, &common.ResolutionNotFoundError{
+			Resource: opts.chartRef(opts.version),
+			Original: fmt.Errorf("version %s of chart %q not found in repository %s, available versions: %s", opts.version, opts.chart, opts.repo, strings.Join(available, ", ")),
+		}
+	}
+	return chartVersion{}, &common.ResolutionNotFoundError{
+		Resource: opts.chart,
+		Original: fmt.Errorf("chart %q in repository %s has no released versions, set the %s param to one of: %s", opts.chart, opts.repo, ParamVersion, strings.Join(available, ", ")),
+	}
+}
+
+// extractFile returns the content of the requested file in the chart
+// archive. Charts are packaged with a single top level directory named
+// after the chart, which the path is relative to.
+func extractFile(archive []byte, opts requestOptions, version string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("error reading chart %s: %w", opts.chartRef(version), err)
+	}
+	defer gz.Close()
+	// Bound everything decompressed while looking for the file, not just
+	// the file itself, so that a small archive can't expand without
+	// limit.
+	tr := tar.NewReader(framework.LimitReader(gz, opts.maxResponseSize*maxDecompressionRatio))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading chart %s: %w", opts.chartRef(version), err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean(hdr.Name), "./")
+		if _, rest, ok := strings.Cut(name, "/"); !ok || rest != opts.path {
+			continue
+		}
+		content, err := io.ReadAll(framework.LimitReader(tr, opts.maxResponseSize))
+		if err != nil {
+			return nil, fmt.Errorf("error extracting %s from chart %s: %w", opts.path, opts.chartRef(version), err)
+		}
+		return content, nil
+	}
+	return nil, &common.ResolutionNotFoundError{
+		Resource: opts.chartRef(version) + "/" + opts.path,
+		Original: fmt.Errorf("file %s not found in chart %s", opts.path, opts.chartRef(version)),
+	}
+}
+
+// repoAuth sets the credentials on requests to the repository.
+type repoAuth func(*http.Request)
+
+// getAuth returns the credentials used to authenticate requests to the
+// repository: from the configured credential provider if there is one,
+// otherwise from the secret named by the secret param. A nil repoAuth
+// means requests are sent anonymously.
+func (r *Resolver) getAuth(ctx context.Context, opts requestOptions) (repoAuth, error) {
+	if provider := framework.GetCredentialProviderFromContext(ctx); provider != nil {
+		req := framework.CredentialRequest{
+			Namespace: common.RequestNamespace(ctx),
+			Secret:    opts.secret,
+		}
+		if opts.secret != "" {
+			req.SecretKey = passwordSecretKey
+		}
+		token, err := provider.Token(ctx, opts.repo.String(), req)
+		if err != nil {
+			return nil, fmt.Errorf("error getting token for %s: %w", opts.repo, err)
+		}
+		if token == "" {
+			return nil, nil
+		}
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }, nil
+	}
+	if opts.secret == "" {
+		return nil, nil
+	}
+	return r.secretCredentials(ctx, opts)
+}
+
+// secretCredentials returns the basic auth credentials held by the
+// secret named by the secret param. They are never included in returned
+// errors.
+func (r *Resolver) secretCredentials(ctx context.Context, opts requestOptions) (repoAuth, error) {
+	if r.kubeClientSet == nil {
+		return nil, fmt.Errorf("cannot read secret %s: no kubernetes client available", opts.secret)
+	}
+	namespace := common.RequestNamespace(ctx)
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClientSet, namespace, opts.secret, "helm repository credentials")
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, key := range []string{usernameSecretKey, passwordSecretKey} {
+		value, ok := secret.Data[key]
+		if !ok || len(value) == 0 {
+			return nil, fmt.Errorf("cannot get helm repository credentials, key %s not found in secret %s in namespace %s", key, opts.secret, namespace)
+		}
+		values = append(values, strings.TrimSpace(string(value)))
+	}
+	username, password := values[0], values[1]
+	return func(req *http.Request) { req.SetBasicAuth(username, password) }, nil
+}
+
+// get performs a GET request for the given url, authenticated if it is
+// on the repository's host, and returns the response body.
+func (r *Resolver) get(ctx context.Context, opts requestOptions, auth repoAuth, u *url.URL, maxSize int64) (_ []byte, err error) {
+	ctx, span := framework.StartSpan(ctx, "helm.Get", trace.StringAttribute("http.url", u.String()))
+	defer func() { framework.EndSpan(span, err) }()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request to %s: %w", u, err)
+	}
+	framework.SetUserAgent(ctx, req)
+	// Charts may be served from another host than the index, e.g. a
+	// release page, which mustn't be sent the repository's credentials.
+	if auth != nil && u.Scheme == opts.repo.Scheme && u.Host == opts.repo.Host {
+		auth(req)
+	}
+
+	httpClient := r.httpClient
+	if httpClient == nil {
+		httpClient = framework.HTTPClient(ctx)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, framework.TimeoutError(u.String(), opts.timeout)
+		}
+		return nil, &common.TransientError{
+			Resource: u.String(),
+			Original: fmt.Errorf("error requesting %s: %w", u, err),
+		}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, &common.ResolutionNotFoundError{
+			Resource: u.String(),
+			Original: fmt.Errorf("%s not found", u),
+		}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, &common.PermissionDeniedError{
+			Resource: u.String(),
+			Original: fmt.Errorf("access to %s was denied with status code %d, %s", u, resp.StatusCode, deniedHint(ctx, opts)),
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &common.RateLimitedError{
+			Resource:   u.String(),
+			RetryAfter: framework.RetryAfter(resp, time.Now()),
+			Original:   fmt.Errorf("request to %s was rate limited", u),
+		}
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, &common.TransientError{
+			Resource: u.String(),
+			Original: fmt.Errorf("request to %s failed with status code %d", u, resp.StatusCode),
+		}
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		return nil, fmt.Errorf("request to %s failed with status code %d", u, resp.StatusCode)
+	}
+
+	body, err := framework.ReadResponseBody(resp, maxSize)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, framework.TimeoutError(u.String(), opts.timeout)
+		}
+		return nil, fmt.Errorf("error reading %s: %w", u, err)
+	}
+	return body, nil
+}
+
+// deniedHint suggests how to get access to a repository given where the
+// credentials that were denied came from.
+func deniedHint(ctx context.Context, opts requestOptions) string {
+	switch {
+	case framework.GetCredentialProviderFromContext(ctx) != nil:
+		return "check the credentials returned by the resolvers' credential provider"
+	case opts.secret != "":
+		return fmt.Sprintf("check the username and password in secret %s", opts.secret)
+	default:
+		return fmt.Sprintf("anonymous requests aren't allowed, set the %s param to a secret holding a username and password", ParamSecret)
+	}
+}
+
+// ResolvedHelmResource wraps the data we want to return to Pipelines
+type ResolvedHelmResource struct {
+	Content []byte
+	// Repo is the url of the chart repository.
+	Repo  string
+	Chart string
+	// Version is the version of the chart the file was extracted from.
+	Version string
+	Path    string
+	// URL is the url the chart archive was fetched from.
+	URL string
+}
+
+var _ framework.ResolvedResource = &ResolvedHelmResource{}
+
+// Data returns the bytes of the extracted file.
+func (rr *ResolvedHelmResource) Data() []byte {
+	return rr.Content
+}
+
+// Annotations returns the url the chart was fetched from, its version
+// and the digest of the extracted file along with its provenance.
+func (rr *ResolvedHelmResource) Annotations() map[string]string {
+	source := rr.Source()
+	return map[string]string{
+		AnnotationKeyURL:          rr.URL,
+		AnnotationKeyChartVersion: rr.Version,
+		AnnotationKeyDigest:       "sha256:" + source.Digest["sha256"],
+		common.AnnotationKeyProvenance: common.Provenance{
+			ResolverType: LabelValueHelmResolverType,
+			URI:          source.URI,
+			Digest:       source.Digest,
+			Coordinates: map[string]string{
+				"repo":    rr.Repo,
+				"chart":   rr.Chart,
+				"version": rr.Version,
+				"path":    rr.Path,
+			},
+		}.AnnotationValue(),
+	}
+}
+
+// Source is the source reference of the remote data that records the
+// url of the chart archive and the digest of the extracted file.
+func (rr *ResolvedHelmResource) Source() *v1beta1.ConfigSource {
+	sum := sha256.Sum256(rr.Content)
+	return &v1beta1.ConfigSource{
+		URI: rr.URL,
+		Digest: map[string]string{
+			"sha256": hex.EncodeToString(sum[:]),
+		},
+		EntryPoint: rr.Path,
+	}
+}
+
+func (r *Resolver) isDisabled(ctx context.Context) bool {
+	cfg := resolverconfig.FromContextOrDefaults(ctx)
+	if cfg.FeatureFlags.EnableHelmResolver {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	// defaultCacheSize is the number of responses kept for revalidation
+	// when the cache-size config isn't set.
+	defaultCacheSize = 1024
+	// defaultCacheTTL is how long a response is kept for revalidation
+	// when the cache-ttl config isn't set.
+	defaultCacheTTL = time.Hour
+)
+
+// cachedResponse is the content of a response along with the url it was
+// finally fetched from and the ETag and Last-Modified date returned for
+// it.
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	finalURL     string
+	body         []byte
+}
+
+// validators returns the ETag and Last-Modified date of the response,
+// without its content.
+func validators(resp *http.Response) *cachedResponse {
+	return &cachedResponse{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// setConditions makes the request conditional on the ETag and
+// Last-Modified date of the response, if any.
+func (c *cachedResponse) setConditions(req *http.Request) {
+	if c == nil {
+		return
+	}
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if c.lastModified != "" {
+		req.Header.Set("If-Modified-Since", c.lastModified)
+	}
+}
+
+// revalidated returns the cached response updated with the ETag and
+// Last-Modified date of a 304 Not Modified response to a conditional
+// request for it, if the server sent new ones.
+func (c *cachedResponse) revalidated(notModified *cachedResponse) *cachedResponse {
+	updated := *c
+	if notModified.etag != "" {
+		updated.etag = notModified.etag
+	}
+	if notModified.lastModified != "" {
+		updated.lastModified = notModified.lastModified
+	}
+	return &updated
+}
+
+// responseCache keeps the responses that carried an ETag or a
+// Last-Modified date, keyed by the url they were requested from.
+type responseCache struct {
+	cache *cache.LRUExpireCache
+	ttl   time.Duration
+}
+
+// get returns the response kept for the given url, or nil if there
+// isn't one.
+func (c *responseCache) get(url string) *cachedResponse {
+	if c == nil {
+		return nil
+	}
+	cached, ok := c.cache.Get(url)
+	if !ok {
+		return nil
+	}
+	resp, _ := cached.(*cachedResponse)
+	return resp
+}
+
+// add keeps the response for the given url, replacing any response kept
+// for it before and restarting its ttl.
+func (c *responseCache) add(url string, resp *cachedResponse) {
+	if c == nil {
+		return
+	}
+	c.cache.Add(url, resp, c.ttl)
+}
+
+// remove drops any response kept for the given url.
+func (c *responseCache) remove(url string) {
+	if c == nil {
+		return
+	}
+	c.cache.Remove(url)
+}
+
+// responseCache returns the cache of responses, replacing it if the
+// configured size has changed since it was created. Nil is returned if
+// conditional requests are disabled.
+func (r *Resolver) responseCache(opts requestOptions) *responseCache {
+	if opts.cacheSize == 0 || opts.cacheTTL == 0 {
+		return nil
+	}
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if r.responses == nil || r.responsesSize != opts.cacheSize {
+		r.responses = cache.NewLRUExpireCache(opts.cacheSize)
+		r.responsesSize = opts.cacheSize
+	}
+	return &responseCache{cache: r.responses, ttl: opts.cacheTTL}
+}
+
+// parseCacheConfig sets the size and ttl of the cache of responses from
+// the resolver's config.
+func parseCacheConfig(conf map[string]string, opts *requestOptions) error {
+	if s, ok := conf[ConfigCacheSize]; ok {
+		size, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid %s config: %w", ConfigCacheSize, err)
+		}
+		if size < 0 {
+			return fmt.Errorf("invalid %s config: must not be negative, got %d", ConfigCacheSize, size)
+		}
+		opts.cacheSize = size
+	}
+	if s, ok := conf[ConfigCacheTTL]; ok {
+		ttl, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid %s config: %w", ConfigCacheTTL, err)
+		}
+		if ttl < 0 {
+			return fmt.Errorf("invalid %s config: must not be negative, got %s", ConfigCacheTTL, s)
+		}
+		opts.cacheTTL = ttl
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+
+// ConfigFetchTimeout is the configuration field name for controlling
+// the maximum duration of a single request, including any redirects.
+const ConfigFetchTimeout = framework.ConfigFetchTimeout
+
+// ConfigMaxRedirects is the configuration field name for controlling
+// the maximum number of redirects followed for a single request.
+const ConfigMaxRedirects = "max-redirects"
+
+// ConfigMaxResponseSize is the configuration field name for controlling
+// the maximum size of a fetched file, e.g. "10Mi", both before and after
+// it is decompressed. Defaults to 10Mi.
+const ConfigMaxResponseSize = framework.ConfigMaxResponseSize
+
+// ConfigCacheSize is the configuration field name for controlling the
+// maximum number of responses kept in memory to be revalidated with a
+// conditional request. Setting it to "0" disables conditional requests.
+const ConfigCacheSize = "cache-size"
+
+// ConfigCacheTTL is the configuration field name for controlling how
+// long a response that carried an ETag or a Last-Modified date is kept
+// in memory so that it can be revalidated with a conditional request
+// instead of being downloaded again. Setting it to "0" disables
+// conditional requests.
+const ConfigCacheTTL = "cache-ttl"
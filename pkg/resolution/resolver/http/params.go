@@ -0,0 +1,21 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+// ParamURL is the parameter defining the url of the resource to fetch.
+const ParamURL = "url"
+
+// ParamDigest is the parameter defining the expected SHA-256 digest of
+// the fetched resource, in the form "sha256:<hex>".
+const ParamDigest = "digest"
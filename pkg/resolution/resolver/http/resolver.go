@@ -0,0 +1,401 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// LabelValueHTTPResolverType is the value to use for the
+	// resolution.tekton.dev/type label on resource requests
+	LabelValueHTTPResolverType string = "http"
+
+	disabledError = "cannot handle resolution request, enable-http-resolver feature flag not true"
+
+	// defaultMaxRedirects is the maximum number of redirects followed
+	// when the max-redirects config isn't set.
+	defaultMaxRedirects = 10
+)
+
+// tooManyRedirectsError is returned when a fetch is redirected more
+// than the max-redirects config allows.
+type tooManyRedirectsError struct {
+	maxRedirects int
+}
+
+func (e *tooManyRedirectsError) Error() string {
+	return fmt.Sprintf("stopped after %d redirects", e.maxRedirects)
+}
+
+var _ framework.Resolver = &Resolver{}
+
+// Resolver implements a framework.Resolver that can fetch files from
+// http(s) urls.
+type Resolver struct {
+	// cacheMu guards the creation of the cache of responses.
+	cacheMu sync.Mutex
+	// responses keeps the responses that carried an ETag or a
+	// Last-Modified date so that they can be revalidated.
+	responses     *cache.LRUExpireCache
+	responsesSize int
+}
+
+// Initialize sets up any dependencies needed by the resolver. None atm.
+func (r *Resolver) Initialize(context.Context) error {
+	return nil
+}
+
+// GetName returns a string name to refer to this resolver by.
+func (r *Resolver) GetName(context.Context) string {
+	return "HTTP"
+}
+
+// GetConfigName returns the name of the http resolver's configmap.
+func (r *Resolver) GetConfigName(context.Context) string {
+	return "http-resolver-config"
+}
+
+var _ framework.ClusterScopedConfig = &Resolver{}
+
+// ClusterScopedConfigKeys returns the cache-size config, which sizes the
+// cache of responses shared by the resolutions of every namespace.
+func (r *Resolver) ClusterScopedConfigKeys(context.Context) []string {
+	return []string{ConfigCacheSize}
+}
+
+// GetSelector returns a map of labels to match requests to this resolver.
+func (r *Resolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{
+		common.LabelKeyResolverType: LabelValueHTTPResolverType,
+	}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver.
+func (r *Resolver) GetParamSchema(context.Context) []framework.ParamSchema {
+	return []framework.ParamSchema{{
+		Name:        ParamURL,
+		Description: "The http or https url of the resource to fetch.",
+		Required:    true,
+	}, {
+		Name:        ParamDigest,
+		Description: "The expected SHA-256 digest of the fetched resource, in the form sha256:<hex>.",
+	}}
+}
+
+var _ framework.BackendIdentifier = &Resolver{}
+
+// Backend returns the host the resource is fetched from.
+func (r *Resolver) Backend(ctx context.Context, params []pipelinev1beta1.Param) string {
+	opts, err := newRequestOptions(ctx, framework.StringParams(params))
+	if err != nil {
+		return ""
+	}
+	return opts.host()
+}
+
+// ValidateParams ensures parameters from a request are as expected.
+func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if r.isDisabled(ctx) {
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	if err := framework.ValidateNoVariableReferences(params); err != nil {
+		return err
+	}
+	_, err := newRequestOptions(ctx, framework.StringParams(params))
+	return err
+}
+
+// Resolve uses the given params to resolve the requested file or resource.
+func (r *Resolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	if r.isDisabled(ctx) {
+		return nil, common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	opts, err := newRequestOptions(ctx, framework.StringParams(params))
+	if err != nil {
+		return nil, err
+	}
+	content, finalURL, err := fetch(ctx, opts, r.responseCache(opts))
+	if err != nil {
+		return nil, err
+	}
+	resolved := &ResolvedHTTPResource{URL: finalURL, Content: content}
+	if opts.digest != "" && resolved.Digest() != opts.digest {
+		return nil, fmt.Errorf("digest mismatch for '%s': expected %s but got %s", opts.url, opts.digest, resolved.Digest())
+	}
+	return resolved, nil
+}
+
+// GetResolutionTimeout returns a time.Duration for the amount of time a
+// single http fetch may take. This can be configured with the
+// fetch-timeout field in the http-resolver-config configmap. An invalid
+// config is logged and the default timeout used instead.
+func (r *Resolver) GetResolutionTimeout(ctx context.Context, defaultTimeout time.Duration) time.Duration {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if _, ok := conf[ConfigFetchTimeout]; !ok {
+		return defaultTimeout
+	}
+	opts, err := framework.FetchOptionsFromConfig(conf)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("using the default resolution timeout of %s: %v", defaultTimeout, err)
+		return defaultTimeout
+	}
+	return opts.Timeout
+}
+
+// requestOptions are the settings used to fetch a single resource.
+type requestOptions struct {
+	url          string
+	digest       string
+	timeout      time.Duration
+	maxRedirects int
+	// maxResponseSize is the maximum size in bytes of the response
+	// body, before and after decompression.
+	maxResponseSize int64
+	// cacheSize and cacheTTL configure the cache of responses kept for
+	// revalidation.
+	cacheSize int
+	cacheTTL  time.Duration
+}
+
+// newRequestOptions returns the settings for fetching a resource given
+// the resolution's params and the resolver's config.
+func newRequestOptions(ctx context.Context, params map[string]string) (requestOptions, error) {
+	opts := requestOptions{
+		maxRedirects: defaultMaxRedirects,
+		cacheSize:    defaultCacheSize,
+		cacheTTL:     defaultCacheTTL,
+	}
+
+	resourceURL, ok := params[ParamURL]
+	if !ok || resourceURL == "" {
+		return opts, fmt.Errorf("must include %s param", ParamURL)
+	}
+	u, err := url.Parse(resourceURL)
+	if err != nil {
+		return opts, fmt.Errorf("invalid %s param: %w", ParamURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return opts, fmt.Errorf("invalid %s param %q: scheme must be http or https", ParamURL, resourceURL)
+	}
+	if u.Host == "" {
+		return opts, fmt.Errorf("invalid %s param %q: missing host", ParamURL, resourceURL)
+	}
+	opts.url = resourceURL
+
+	if digest, ok := params[ParamDigest]; ok {
+		if err := framework.ValidateDigest(ParamDigest, digest); err != nil {
+			return opts, err
+		}
+		opts.digest = digest
+	}
+
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if maxRedirects, ok := conf[ConfigMaxRedirects]; ok {
+		n, err := strconv.Atoi(maxRedirects)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid %s config: must be a non-negative integer, got %q", ConfigMaxRedirects, maxRedirects)
+		}
+		opts.maxRedirects = n
+	}
+	fetchOpts, err := framework.FetchOptionsFromConfig(conf)
+	if err != nil {
+		return opts, err
+	}
+	opts.timeout, opts.maxResponseSize = fetchOpts.Timeout, fetchOpts.MaxResponseSize
+	if err := parseCacheConfig(conf, &opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// host returns the host of the resource's url.
+func (o requestOptions) host() string {
+	u, err := url.Parse(o.url)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// fetch performs a GET request for the resource, following at most the
+// configured number of redirects, and returns its content along with
+// the url it was finally fetched from. When a response for the same url
+// carrying an ETag or a Last-Modified date was kept, the request is made
+// conditional on them and the kept response is returned again if the
+// server responds that it hasn't been modified.
+func fetch(ctx context.Context, opts requestOptions, responses *responseCache) (_ []byte, _ string, err error) {
+	// Only the host is recorded since the url may hold credentials.
+	ctx, span := framework.StartSpan(ctx, "http.Get", trace.StringAttribute("http.host", opts.host()))
+	defer func() { framework.EndSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	// A copy of the shared client still shares its connections.
+	client := *framework.HTTPClient(ctx)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > opts.maxRedirects {
+			return &tooManyRedirectsError{maxRedirects: opts.maxRedirects}
+		}
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, opts.url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error constructing request to '%s': %w", opts.url, err)
+	}
+	framework.SetUserAgent(ctx, req)
+	cached := responses.get(opts.url)
+	if cached != nil && int64(len(cached.body)) > opts.maxResponseSize {
+		// The max response size may be lower than when the response
+		// was kept, e.g. for a namespace with its own config.
+		cached = nil
+	}
+	cached.setConditions(req)
+	// Asking for gzip explicitly stops the client from transparently
+	// decompressing the response, so that the max response size can be
+	// enforced on the compressed body as well.
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, "", framework.TimeoutError(opts.url, opts.timeout)
+		}
+		err = fmt.Errorf("error requesting '%s': %w", opts.url, err)
+		var redirects *tooManyRedirectsError
+		if ctx.Err() != nil || errors.As(err, &redirects) {
+			return nil, "", err
+		}
+		// The host couldn't be reached, e.g. it refused the connection
+		// or its name couldn't be resolved.
+		return nil, "", &common.TransientError{Resource: opts.url, Original: err}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		responses.add(opts.url, cached.revalidated(validators(resp)))
+		return cached.body, cached.finalURL, nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		err := fmt.Errorf("request to '%s' failed with status code %d", opts.url, resp.StatusCode)
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, "", &common.ResolutionNotFoundError{Resource: opts.url, Original: err}
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return nil, "", &common.RateLimitedError{
+				Resource:   opts.url,
+				RetryAfter: framework.RetryAfter(resp, time.Now()),
+				Original:   fmt.Errorf("request to '%s' was rate limited", opts.url),
+			}
+		case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= http.StatusInternalServerError:
+			return nil, "", &common.TransientError{Resource: opts.url, Original: err}
+		}
+		return nil, "", err
+	}
+	body, err := framework.ReadResponseBody(resp, opts.maxResponseSize)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, "", framework.TimeoutError(opts.url, opts.timeout)
+		}
+		return nil, "", fmt.Errorf("error reading response body from '%s': %w", opts.url, err)
+	}
+	finalURL := resp.Request.URL.String()
+	if fetched := validators(resp); fetched.etag != "" || fetched.lastModified != "" {
+		fetched.finalURL = finalURL
+		fetched.body = body
+		responses.add(opts.url, fetched)
+	} else {
+		// The kept response is out of date and can't be revalidated.
+		responses.remove(opts.url)
+	}
+	return body, finalURL, nil
+}
+
+// ResolvedHTTPResource wraps the data we want to return to Pipelines
+type ResolvedHTTPResource struct {
+	// URL is the url the content was fetched from, after following any
+	// redirects.
+	URL     string
+	Content []byte
+}
+
+var _ framework.ResolvedResource = &ResolvedHTTPResource{}
+
+// Data returns the bytes of the fetched resource.
+func (rr *ResolvedHTTPResource) Data() []byte {
+	return rr.Content
+}
+
+// Annotations returns the url the resource was fetched from along with
+// the digest of its content and its provenance.
+func (rr *ResolvedHTTPResource) Annotations() map[string]string {
+	source := rr.Source()
+	return map[string]string{
+		AnnotationKeyURL:    rr.URL,
+		AnnotationKeyDigest: rr.Digest(),
+		common.AnnotationKeyProvenance: common.Provenance{
+			ResolverType: LabelValueHTTPResolverType,
+			URI:          source.URI,
+			Digest:       source.Digest,
+		}.AnnotationValue(),
+	}
+}
+
+// Digest returns the SHA-256 digest of the resource's content in the
+// form "sha256:<hex>".
+func (rr *ResolvedHTTPResource) Digest() string {
+	sum := sha256.Sum256(rr.Content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Source is the source reference of the remote data that records the
+// url the resource was fetched from and the digest of its content.
+func (rr *ResolvedHTTPResource) Source() *v1beta1.ConfigSource {
+	sum := sha256.Sum256(rr.Content)
+	return &v1beta1.ConfigSource{
+		URI: rr.URL,
+		Digest: map[string]string{
+			"sha256": hex.EncodeToString(sum[:]),
+		},
+	}
+}
+
+func (r *Resolver) isDisabled(ctx context.Context) bool {
+	cfg := resolverconfig.FromContextOrDefaults(ctx)
+	if cfg.FeatureFlags.EnableHTTPResolver {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,713 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
+	"github.com/tektoncd/pipeline/test/diff"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"knative.dev/pkg/logging"
+)
+
+const testContent = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: foo\n"
+
+func TestGetSelector(t *testing.T) {
+	resolver := Resolver{}
+	sel := resolver.GetSelector(resolverContext())
+	if typ, has := sel[resolutioncommon.LabelKeyResolverType]; !has {
+		t.Fatalf("unexpected selector: %v", sel)
+	} else if typ != LabelValueHTTPResolverType {
+		t.Fatalf("unexpected type: %q", typ)
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	testCases := []struct {
+		name        string
+		params      map[string]string
+		config      map[string]string
+		expectedErr string
+	}{
+		{
+			name:   "https url",
+			params: map[string]string{ParamURL: "https://example.com/task.yaml"},
+		},
+		{
+			name:   "http url with digest",
+			params: map[string]string{ParamURL: "http://example.com/task.yaml", ParamDigest: digestOf(testContent)},
+		},
+		{
+			name:        "missing url",
+			params:      map[string]string{},
+			expectedErr: "must include url param",
+		},
+		{
+			name:        "unsupported scheme",
+			params:      map[string]string{ParamURL: "file:///etc/passwd"},
+			expectedErr: `invalid url param "file:///etc/passwd": scheme must be http or https`,
+		},
+		{
+			name:        "relative url",
+			params:      map[string]string{ParamURL: "task.yaml"},
+			expectedErr: `invalid url param "task.yaml": scheme must be http or https`,
+		},
+		{
+			name:        "missing host",
+			params:      map[string]string{ParamURL: "https:///task.yaml"},
+			expectedErr: `invalid url param "https:///task.yaml": missing host`,
+		},
+		{
+			name:        "invalid digest",
+			params:      map[string]string{ParamURL: "https://example.com/task.yaml", ParamDigest: "sha1:abc"},
+			expectedErr: `invalid digest param "sha1:abc": must be of the form sha256:<hex>`,
+		},
+		{
+			name:        "invalid timeout config",
+			params:      map[string]string{ParamURL: "https://example.com/task.yaml"},
+			config:      map[string]string{ConfigFetchTimeout: "0s"},
+			expectedErr: "invalid fetch-timeout config: timeout must be greater than zero, got 0s",
+		},
+		{
+			name:        "invalid max redirects config",
+			params:      map[string]string{ParamURL: "https://example.com/task.yaml"},
+			config:      map[string]string{ConfigMaxRedirects: "-1"},
+			expectedErr: `invalid max-redirects config: must be a non-negative integer, got "-1"`,
+		},
+		{
+			name:        "invalid max response size config",
+			params:      map[string]string{ParamURL: "https://example.com/task.yaml"},
+			config:      map[string]string{ConfigMaxResponseSize: "0"},
+			expectedErr: `invalid max-response-size config: must be greater than zero, got "0"`,
+		},
+		{
+			name:        "invalid cache size config",
+			params:      map[string]string{ParamURL: "https://example.com/task.yaml"},
+			config:      map[string]string{ConfigCacheSize: "-1"},
+			expectedErr: "invalid cache-size config: must not be negative, got -1",
+		},
+		{
+			name:        "invalid cache ttl config",
+			params:      map[string]string{ParamURL: "https://example.com/task.yaml"},
+			config:      map[string]string{ConfigCacheTTL: "-1m"},
+			expectedErr: "invalid cache-ttl config: must not be negative, got -1m",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			err := resolver.ValidateParams(ctx, toParams(tc.params))
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestValidateParamsNoNetwork(t *testing.T) {
+	trap := frtesting.NewNetworkTrap(t)
+	params := map[string]string{ParamURL: trap.URL() + "/task.yaml", ParamDigest: digestOf(testContent)}
+	if err := (&Resolver{}).ValidateParams(resolverContext(), toParams(params)); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	trap.ExpectNoConnections(t)
+}
+
+func TestValidateParamsDisabled(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), toParams(map[string]string{ParamURL: "https://example.com/task.yaml"}))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolveDisabled(t *testing.T) {
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), toParams(map[string]string{ParamURL: "https://example.com/task.yaml"}))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/task.yaml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testContent)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/task.yaml", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	svr := httptest.NewServer(mux)
+	defer svr.Close()
+
+	testCases := []struct {
+		name        string
+		path        string
+		digest      string
+		config      map[string]string
+		expectedURL string
+		expectedErr string
+	}{
+		{
+			name:        "fetch",
+			path:        "/task.yaml",
+			expectedURL: svr.URL + "/task.yaml",
+		},
+		{
+			name:        "matching digest",
+			path:        "/task.yaml",
+			digest:      digestOf(testContent),
+			expectedURL: svr.URL + "/task.yaml",
+		},
+		{
+			name:        "mismatched digest",
+			path:        "/task.yaml",
+			digest:      digestOf("something else"),
+			expectedErr: fmt.Sprintf("digest mismatch for '%s/task.yaml': expected %s but got %s", svr.URL, digestOf("something else"), digestOf(testContent)),
+		},
+		{
+			name:        "follows redirect",
+			path:        "/redirect",
+			expectedURL: svr.URL + "/task.yaml",
+		},
+		{
+			name:        "redirects disabled",
+			path:        "/redirect",
+			config:      map[string]string{ConfigMaxRedirects: "0"},
+			expectedErr: fmt.Sprintf(`error requesting '%s/redirect': Get "/task.yaml": stopped after 0 redirects`, svr.URL),
+		},
+		{
+			name:        "too many redirects",
+			path:        "/loop",
+			config:      map[string]string{ConfigMaxRedirects: "3"},
+			expectedErr: fmt.Sprintf(`error requesting '%s/loop': Get "/loop": stopped after 3 redirects`, svr.URL),
+		},
+		{
+			name:        "error status",
+			path:        "/error",
+			expectedErr: fmt.Sprintf("request to '%s/error' failed with status code 403", svr.URL),
+		},
+		{
+			name:        "not found",
+			path:        "/missing.yaml",
+			expectedErr: fmt.Sprintf("request to '%s/missing.yaml' failed with status code 404", svr.URL),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			params := map[string]string{ParamURL: svr.URL + tc.path}
+			if tc.digest != "" {
+				params[ParamDigest] = tc.digest
+			}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			output, err := resolver.Resolve(ctx, toParams(params))
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected err but didn't get one")
+				}
+				if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+					t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(testContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+			}
+			expectedAnnotations := map[string]string{
+				AnnotationKeyURL:    tc.expectedURL,
+				AnnotationKeyDigest: digestOf(testContent),
+				resolutioncommon.AnnotationKeyProvenance: `{"resolverType":"http","uri":"` + tc.expectedURL + `",` +
+					`"digest":{"sha256":"` + strings.TrimPrefix(digestOf(testContent), "sha256:") + `"}}`,
+			}
+			if d := cmp.Diff(expectedAnnotations, output.Annotations()); d != "" {
+				t.Errorf("unexpected annotations: %s", diff.PrintWantGot(d))
+			}
+			expectedSource := &v1beta1.ConfigSource{
+				URI:    tc.expectedURL,
+				Digest: map[string]string{"sha256": strings.TrimPrefix(digestOf(testContent), "sha256:")},
+			}
+			if d := cmp.Diff(expectedSource, output.Source()); d != "" {
+				t.Errorf("unexpected source: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveTimeout(t *testing.T) {
+	done := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer svr.Close()
+	defer close(done)
+
+	resolver := Resolver{}
+	ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{ConfigFetchTimeout: "100ms"})
+	_, err := resolver.Resolve(ctx, toParams(map[string]string{ParamURL: svr.URL}))
+	if err == nil {
+		t.Fatalf("expected timeout err but didn't get one")
+	}
+	if d := cmp.Diff(fmt.Sprintf("request for %s timed out after 100ms", svr.URL), err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+	var timeoutErr *resolutioncommon.ResolutionTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Resource != svr.URL {
+		t.Errorf("expected a ResolutionTimeoutError for %s but got %v", svr.URL, err)
+	}
+}
+
+func TestResolveCancelled(t *testing.T) {
+	done := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer svr.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(resolverContext())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	resolver := Resolver{}
+	start := time.Now()
+	_, err := resolver.Resolve(ctx, toParams(map[string]string{ParamURL: svr.URL}))
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected resolution to stop once cancelled but it took %s", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to wrap context.Canceled but got %v", err)
+	}
+}
+
+func TestResolveMaxResponseSize(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			fmt.Fprint(gz, large)
+			return
+		}
+		fmt.Fprint(w, large)
+	}))
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		name        string
+		path        string
+		maxSize     string
+		expectedErr string
+	}{{
+		name:    "within max size",
+		path:    "/plain",
+		maxSize: "2Ki",
+	}, {
+		name:    "compressed within max size",
+		path:    "/gzip",
+		maxSize: "2Ki",
+	}, {
+		name:        "larger than max size",
+		path:        "/plain",
+		maxSize:     "1Ki",
+		expectedErr: "response exceeds max size 1024 bytes",
+	}, {
+		name:        "decompressed larger than max size",
+		path:        "/gzip",
+		maxSize:     "1Ki",
+		expectedErr: "response exceeds max size 1024 bytes",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), map[string]string{ConfigMaxResponseSize: tc.maxSize})
+			resource, err := resolver.Resolve(ctx, toParams(map[string]string{ParamURL: svr.URL + tc.path}))
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q but got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resource.Data()) != large {
+				t.Errorf("expected %d bytes of content but got %d", len(large), len(resource.Data()))
+			}
+		})
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	svr := httptest.NewServer(http.NotFoundHandler())
+	defer svr.Close()
+
+	resolver := Resolver{}
+	url := svr.URL + "/missing.yaml"
+	_, err := resolver.Resolve(resolverContext(), toParams(map[string]string{ParamURL: url}))
+	var notFoundErr *resolutioncommon.ResolutionNotFoundError
+	if !errors.As(err, &notFoundErr) || notFoundErr.Resource != url {
+		t.Errorf("expected a ResolutionNotFoundError for %s but got %v", url, err)
+	}
+}
+
+func TestResolveBackendFailures(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/rate-limited":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer svr.Close()
+	// A server that was closed refuses connections.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tc := range []struct {
+		name              string
+		url               string
+		expectedTransient bool
+		expectedRetry     time.Duration
+	}{{
+		name:              "server error",
+		url:               svr.URL + "/unavailable",
+		expectedTransient: true,
+	}, {
+		name:              "connection refused",
+		url:               closed.URL + "/task.yaml",
+		expectedTransient: true,
+	}, {
+		name:          "rate limited",
+		url:           svr.URL + "/rate-limited",
+		expectedRetry: 7 * time.Second,
+	}, {
+		name: "client error",
+		url:  svr.URL + "/forbidden",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			_, err := resolver.Resolve(resolverContext(), toParams(map[string]string{ParamURL: tc.url}))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var transientErr *resolutioncommon.TransientError
+			if errors.As(err, &transientErr) != tc.expectedTransient {
+				t.Errorf("expected the error to be a TransientError: %t, but got %v", tc.expectedTransient, err)
+			}
+			var rateLimitedErr *resolutioncommon.RateLimitedError
+			if isRateLimited := errors.As(err, &rateLimitedErr); isRateLimited != (tc.expectedRetry != 0) {
+				t.Errorf("expected the error to be a RateLimitedError: %t, but got %v", tc.expectedRetry != 0, err)
+			} else if isRateLimited && rateLimitedErr.RetryAfter != tc.expectedRetry {
+				t.Errorf("expected to retry after %s but got %s", tc.expectedRetry, rateLimitedErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestResolveUserAgent(t *testing.T) {
+	var userAgent string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		fmt.Fprint(w, testContent)
+	}))
+	defer svr.Close()
+
+	for _, tc := range []struct {
+		name     string
+		config   map[string]string
+		expected string
+	}{{
+		name:     "default",
+		expected: framework.DefaultUserAgent,
+	}, {
+		name:     "configured",
+		config:   map[string]string{framework.ConfigUserAgent: "acme-ci/1.0"},
+		expected: "acme-ci/1.0",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			userAgent = ""
+			resolver := Resolver{}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			if _, err := resolver.Resolve(ctx, toParams(map[string]string{ParamURL: svr.URL + "/task.yaml"})); err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if userAgent != tc.expected {
+				t.Errorf("expected User-Agent %q but got %q", tc.expected, userAgent)
+			}
+		})
+	}
+}
+
+func TestResolveConditional(t *testing.T) {
+	lastModified := time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	revalidated := time.Date(2022, time.March, 2, 10, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	testCases := []struct {
+		name string
+		// etag makes the server return an ETag instead of a
+		// Last-Modified date.
+		etag bool
+		// changed makes the server return new content after the first
+		// request.
+		changed                bool
+		config                 map[string]string
+		expectedContent        string
+		expectedConditionals   []string
+		expectedNotModifiedHit int
+	}{
+		{
+			name:                   "not modified since",
+			expectedContent:        testContent,
+			expectedConditionals:   []string{"", lastModified, revalidated},
+			expectedNotModifiedHit: 2,
+		},
+		{
+			name:                   "etag not modified",
+			etag:                   true,
+			expectedContent:        testContent,
+			expectedConditionals:   []string{"", `"v1"`, `"v1"`},
+			expectedNotModifiedHit: 2,
+		},
+		{
+			name:                 "modified",
+			changed:              true,
+			expectedContent:      "new content",
+			expectedConditionals: []string{"", lastModified, ""},
+		},
+		{
+			name:                 "disabled with zero ttl",
+			config:               map[string]string{ConfigCacheTTL: "0"},
+			expectedContent:      testContent,
+			expectedConditionals: []string{"", "", ""},
+		},
+		{
+			name:                 "disabled with zero size",
+			config:               map[string]string{ConfigCacheSize: "0"},
+			expectedContent:      testContent,
+			expectedConditionals: []string{"", "", ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var conditionals []string
+			notModified := 0
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.etag {
+					ifNoneMatch := r.Header.Get("If-None-Match")
+					conditionals = append(conditionals, ifNoneMatch)
+					w.Header().Set("ETag", `"v1"`)
+					if ifNoneMatch == `"v1"` {
+						notModified++
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					fmt.Fprint(w, testContent)
+					return
+				}
+				ifModifiedSince := r.Header.Get("If-Modified-Since")
+				conditionals = append(conditionals, ifModifiedSince)
+				if tc.changed && len(conditionals) > 1 {
+					fmt.Fprint(w, "new content")
+					return
+				}
+				if ifModifiedSince != "" {
+					notModified++
+					w.Header().Set("Last-Modified", revalidated)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Last-Modified", lastModified)
+				fmt.Fprint(w, testContent)
+			}))
+			defer svr.Close()
+
+			resolver := Resolver{}
+			ctx := framework.InjectResolverConfigToContext(resolverContext(), tc.config)
+			url := svr.URL + "/task.yaml"
+			var output framework.ResolvedResource
+			for i := 0; i < 3; i++ {
+				var err error
+				output, err = resolver.Resolve(ctx, toParams(map[string]string{ParamURL: url}))
+				if err != nil {
+					t.Fatalf("unexpected error resolving: %v", err)
+				}
+			}
+			if d := cmp.Diff([]byte(tc.expectedContent), output.Data()); d != "" {
+				t.Errorf("unexpected data: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(url, output.Annotations()[AnnotationKeyURL]); d != "" {
+				t.Errorf("unexpected url annotation: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedConditionals, conditionals); d != "" {
+				t.Errorf("unexpected conditional request headers: %s", diff.PrintWantGot(d))
+			}
+			if notModified != tc.expectedNotModifiedHit {
+				t.Errorf("expected %d not modified responses but got %d", tc.expectedNotModifiedHit, notModified)
+			}
+		})
+	}
+}
+
+// TestResolveConditionalPerURL checks that a response kept for one url
+// isn't used to revalidate another.
+func TestResolveConditionalPerURL(t *testing.T) {
+	var conditionals []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditionals = append(conditionals, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer svr.Close()
+
+	resolver := Resolver{}
+	for _, path := range []string{"/a.yaml", "/b.yaml"} {
+		output, err := resolver.Resolve(resolverContext(), toParams(map[string]string{ParamURL: svr.URL + path}))
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+		if d := cmp.Diff([]byte(path), output.Data()); d != "" {
+			t.Errorf("unexpected data: %s", diff.PrintWantGot(d))
+		}
+	}
+	if d := cmp.Diff([]string{"", ""}, conditionals); d != "" {
+		t.Errorf("unexpected If-None-Match headers: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestGetResolutionTimeout(t *testing.T) {
+	resolver := Resolver{}
+	defaultTimeout := 30 * time.Minute
+	if timeout := resolver.GetResolutionTimeout(context.Background(), defaultTimeout); timeout != defaultTimeout {
+		t.Fatalf("expected default timeout to be returned")
+	}
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{ConfigFetchTimeout: "2m"})
+	if timeout := resolver.GetResolutionTimeout(ctx, defaultTimeout); timeout != 2*time.Minute {
+		t.Fatalf("expected timeout from config to be returned, got %s", timeout)
+	}
+}
+
+func TestGetResolutionTimeoutInvalid(t *testing.T) {
+	resolver := Resolver{}
+	defaultTimeout := 30 * time.Minute
+	for _, tc := range []struct {
+		timeout     string
+		expectedLog string
+	}{{
+		timeout:     "0s",
+		expectedLog: "using the default resolution timeout of 30m0s: invalid fetch-timeout config: timeout must be greater than zero, got 0s",
+	}, {
+		timeout:     "-1m",
+		expectedLog: "using the default resolution timeout of 30m0s: invalid fetch-timeout config: timeout must be greater than zero, got -1m",
+	}, {
+		timeout:     "soon",
+		expectedLog: `using the default resolution timeout of 30m0s: invalid fetch-timeout config: time: invalid duration "soon"`,
+	}} {
+		t.Run(tc.timeout, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			ctx := logging.WithLogger(context.Background(), zap.New(core).Sugar())
+			ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{ConfigFetchTimeout: tc.timeout})
+			if timeout := resolver.GetResolutionTimeout(ctx, defaultTimeout); timeout != defaultTimeout {
+				t.Errorf("expected the default timeout to be returned, got %s", timeout)
+			}
+			var messages []string
+			for _, entry := range logs.All() {
+				messages = append(messages, entry.Message)
+			}
+			if d := cmp.Diff([]string{tc.expectedLog}, messages); d != "" {
+				t.Errorf("unexpected warnings: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func resolverContext() context.Context {
+	return frtesting.ContextWithHTTPResolverEnabled(context.Background())
+}
+
+func toParams(m map[string]string) []pipelinev1beta1.Param {
+	var params []pipelinev1beta1.Param
+
+	for k, v := range m {
+		params = append(params, pipelinev1beta1.Param{
+			Name:  k,
+			Value: *pipelinev1beta1.NewStructuredValues(v),
+		})
+	}
+
+	return params
+}
+
+func digestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"github.com/tektoncd/pipeline/pkg/apis/resolution"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+var (
+	// AnnotationKeyVersion is the concrete version of the resource
+	// that was fetched from the hub
+	AnnotationKeyVersion = resolution.GroupName + "/version"
+
+	// AnnotationKeyCatalog is the catalog the resource was fetched from
+	AnnotationKeyCatalog = resolution.GroupName + "/catalog"
+
+	// AnnotationKeyName is the name the resource was requested from the
+	// hub with, set when the lowercase-name config is enabled
+	AnnotationKeyName = resolution.GroupName + "/name"
+
+	// AnnotationKeyDigest is the digest of the resource content that
+	// was fetched from the hub, in the form "sha256:<hex>"
+	AnnotationKeyDigest = resolution.GroupName + "/digest"
+
+	// AnnotationKeyFormat is the format of the resource content, either
+	// "yaml" or "json" as selected by the format param
+	AnnotationKeyFormat = resolution.GroupName + "/format"
+
+	// AnnotationKeySignature is the signature the hub attached to the
+	// resource's yaml, set when the signed param is used
+	AnnotationKeySignature = common.AnnotationKeySignature
+
+	// AnnotationKeyCertificate is the certificate the hub returned to
+	// verify the signature with, if any
+	AnnotationKeyCertificate = common.AnnotationKeyCertificate
+
+	// AnnotationKeyHubVersion is the version of the hub the resource was
+	// fetched from, set when the report-hub-status config is enabled and
+	// the hub reports its version
+	AnnotationKeyHubVersion = resolution.GroupName + "/hub-version"
+
+	// AnnotationKeyCatalogUpdatedAt is when the hub last updated the
+	// catalog the resource was fetched from, set when the
+	// report-hub-status config is enabled and the hub reports it
+	AnnotationKeyCatalogUpdatedAt = resolution.GroupName + "/catalog-updated-at"
+)
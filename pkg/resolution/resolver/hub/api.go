@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+const (
+	// TektonHubType is the type param value for resolving from Tekton Hub.
+	TektonHubType = "tekton"
+
+	// ArtifactHubType is the type param value for resolving from Artifact Hub.
+	ArtifactHubType = "artifact"
+)
+
+// resourceRef identifies a resource on a hub independently of its
+// version.
+type resourceRef struct {
+	hubType string
+	hubURL  string
+	catalog string
+	kind    string
+	name    string
+}
+
+type versionResponse struct {
+	Version string `json:"version"`
+}
+
+// tektonHubDataResponse is a resource's yaml along with the OCI
+// reference the hub serves it from, if any, and the digest that
+// reference pointed to when the hub published it, if the hub reports
+// one.
+type tektonHubDataResponse struct {
+	YAML         string `json:"yaml"`
+	Bundle       string `json:"bundle"`
+	BundleDigest string `json:"bundleDigest"`
+}
+
+// tektonHubResponse is the shape of a resource's yaml from Tekton Hub.
+// Name and Message are only set when the hub responds with an error
+// payload, such as {"name":"not-found","message":"resource not found"}.
+type tektonHubResponse struct {
+	Data    tektonHubDataResponse `json:"data"`
+	Name    string                `json:"name"`
+	Message string                `json:"message"`
+}
+
+// tektonHubNotFound is the name of the error payload Tekton Hub
+// responds with for a resource that doesn't exist.
+const tektonHubNotFound = "not-found"
+
+type tektonHubVersionsDataResponse struct {
+	Latest   versionResponse   `json:"latest"`
+	Versions []versionResponse `json:"versions"`
+}
+
+type tektonHubVersionsResponse struct {
+	Data tektonHubVersionsDataResponse `json:"data"`
+}
+
+// catalogResponse is a catalog listed by Tekton Hub. UpdatedAt is when
+// the hub last synced the catalog, if it reports it.
+type catalogResponse struct {
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// tektonHubCatalogsResponse is the list of catalogs from Tekton Hub.
+// Version is the version of the hub, if it reports it.
+type tektonHubCatalogsResponse struct {
+	Data    []catalogResponse `json:"data"`
+	Version string            `json:"version"`
+}
+
+type artifactHubDataResponse struct {
+	YAML string `json:"manifestRaw"`
+}
+
+// artifactHubResponse is the shape of an Artifact Hub package, which
+// carries both the package's manifest and the versions available.
+type artifactHubResponse struct {
+	Version           string                  `json:"version"`
+	Data              artifactHubDataResponse `json:"data"`
+	AvailableVersions []versionResponse       `json:"available_versions"`
+}
+
+// hubURLs returns the api urls of the given type of hub in the order
+// they should be tried. A url set in the resolver's config, e.g. for the
+// namespace of the request, replaces the resolver's own urls.
+func (r *Resolver) hubURLs(ctx context.Context, hubType string) []string {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if hubType == ArtifactHubType {
+		if url := conf[ConfigArtifactHubURL]; url != "" {
+			return []string{strings.TrimSuffix(url, "/")}
+		}
+		if r.ArtifactHubURL == "" {
+			return []string{DefaultArtifactHubURL}
+		}
+		return []string{strings.TrimSuffix(r.ArtifactHubURL, "/")}
+	}
+	if url := conf[ConfigHubURL]; url != "" {
+		return []string{strings.TrimSuffix(url, "/")}
+	}
+	urls := []string{strings.TrimSuffix(r.HubURL, "/")}
+	for _, url := range r.FallbackHubURLs {
+		urls = append(urls, strings.TrimSuffix(url, "/"))
+	}
+	return urls
+}
+
+// fetchContent returns the yaml of the given version of a resource
+// along with the OCI reference the hub serves it from, if any.
+func (r *Resolver) fetchContent(ctx context.Context, opts requestOptions, ref resourceRef, version string) (*tektonHubDataResponse, error) {
+	switch ref.hubType {
+	case ArtifactHubType:
+		url, err := buildURL(ref.hubURL, ArtifactHubYamlEndpoint, ref.kind, ref.catalog, ref.name, version)
+		if err != nil {
+			return nil, err
+		}
+		ar := artifactHubResponse{}
+		if err := r.fetch(ctx, opts, url, &ar); err != nil {
+			return nil, err
+		}
+		return &tektonHubDataResponse{YAML: ar.Data.YAML}, nil
+	default:
+		url, err := opts.urlTemplates.contentURL(ref, version)
+		if err != nil {
+			return nil, err
+		}
+		hr := tektonHubResponse{}
+		fetch := r.fetch
+		if opts.fields == FieldsYAML {
+			fetch = r.fetchYAMLField
+		}
+		if err := fetch(ctx, opts, url, &hr); err != nil {
+			return nil, err
+		}
+		if hr.Name == tektonHubNotFound && hr.Data.YAML == "" && !opts.emptyContentOnNotFound {
+			return nil, &common.ResolutionNotFoundError{
+				Resource: url,
+				Original: fmt.Errorf("requested resource '%s' not found on hub: %s", url, hr.Message),
+			}
+		}
+		return &hr.Data, nil
+	}
+}
+
+// fetchVersions returns the latest version of a resource, if the hub
+// reports one, along with all of its available versions.
+func (r *Resolver) fetchVersions(ctx context.Context, opts requestOptions, ref resourceRef) (string, []string, error) {
+	var latest string
+	var listed []versionResponse
+	switch ref.hubType {
+	case ArtifactHubType:
+		url, err := buildURL(ref.hubURL, ArtifactHubVersionsEndpoint, ref.kind, ref.catalog, ref.name)
+		if err != nil {
+			return "", nil, err
+		}
+		ar := artifactHubResponse{}
+		if err := r.fetch(ctx, opts, url, &ar); err != nil {
+			return "", nil, err
+		}
+		latest, listed = ar.Version, ar.AvailableVersions
+	default:
+		url, err := opts.urlTemplates.versionsURL(ref)
+		if err != nil {
+			return "", nil, err
+		}
+		vr := tektonHubVersionsResponse{}
+		if err := r.fetch(ctx, opts, url, &vr); err != nil {
+			return "", nil, err
+		}
+		latest, listed = vr.Data.Latest.Version, vr.Data.Versions
+	}
+
+	versions := make([]string, 0, len(listed))
+	for _, v := range listed {
+		versions = append(versions, v.Version)
+	}
+	return latest, versions, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// defaultTokenSecretKey is the key in the token secret holding the
+// bearer token when the token-secret-key param isn't given.
+const defaultTokenSecretKey = "token"
+
+// validateSecretParams checks the names given in the token-secret and
+// headers-secret params without reading the secrets, which are only
+// fetched when the resource is resolved.
+func validateSecretParams(params map[string]string) error {
+	for _, param := range []string{ParamTokenSecret, ParamHeadersSecret} {
+		if name, ok := params[param]; ok && name == "" {
+			return fmt.Errorf("%s param must not be empty", param)
+		}
+	}
+	return nil
+}
+
+// getToken returns the bearer token referenced by the token-secret
+// params, or an empty string if no token secret was given. The token
+// itself is never included in returned errors.
+func (r *Resolver) getToken(ctx context.Context, params map[string]string) (string, error) {
+	secretName, ok := params[ParamTokenSecret]
+	if !ok {
+		return "", nil
+	}
+	if secretName == "" {
+		return "", fmt.Errorf("%s param must not be empty", ParamTokenSecret)
+	}
+	key := defaultTokenSecretKey
+	if k, ok := params[ParamTokenSecretKey]; ok && k != "" {
+		key = k
+	}
+	if r.kubeClientSet == nil {
+		return "", fmt.Errorf("cannot read token secret %s: no kubernetes client available", secretName)
+	}
+
+	namespace := common.RequestNamespace(ctx)
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClientSet, namespace, secretName, "hub token")
+	if err != nil {
+		return "", err
+	}
+	token, ok := secret.Data[key]
+	if !ok || len(token) == 0 {
+		return "", fmt.Errorf("cannot get hub token, key %s not found in secret %s in namespace %s", key, secretName, namespace)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// credentialRequest returns the credentials a resolution asks a
+// credential provider for, named by its token secret params.
+func credentialRequest(ctx context.Context, params map[string]string) framework.CredentialRequest {
+	req := framework.CredentialRequest{
+		Namespace: common.RequestNamespace(ctx),
+		Secret:    params[ParamTokenSecret],
+	}
+	if req.Secret != "" {
+		req.SecretKey = defaultTokenSecretKey
+		if k, ok := params[ParamTokenSecretKey]; ok && k != "" {
+			req.SecretKey = k
+		}
+	}
+	return req
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"knative.dev/pkg/logging"
+)
+
+// pinnedBundle returns the OCI reference the hub serves a resource from
+// pinned to the digest the hub reported for it, e.g.
+// registry/foo@sha256:..., just like the bundle resolver reports the
+// bundles it pulls. A reference that is already pinned is returned as
+// is. An empty string is returned if the hub didn't report a digest,
+// rather than guessing one, or if the reference or digest it reported
+// are invalid, which is logged but doesn't fail the resolution since
+// the content itself came from the hub.
+func pinnedBundle(ctx context.Context, data *tektonHubDataResponse) string {
+	if data.Bundle == "" {
+		return ""
+	}
+	ref, err := name.ParseReference(data.Bundle)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("ignoring invalid bundle reference %q returned by the hub: %v", data.Bundle, err)
+		return ""
+	}
+	if digest, ok := ref.(name.Digest); ok {
+		if data.BundleDigest != "" && data.BundleDigest != digest.DigestStr() {
+			logging.FromContext(ctx).Warnf("ignoring bundle reference %q returned by the hub: it doesn't match bundle digest %s", data.Bundle, data.BundleDigest)
+			return ""
+		}
+		return ref.Context().Digest(digest.DigestStr()).String()
+	}
+	if data.BundleDigest == "" {
+		return ""
+	}
+	pinned, err := name.NewDigest(ref.Context().String() + "@" + data.BundleDigest)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("ignoring invalid bundle digest %q returned by the hub: %v", data.BundleDigest, err)
+		return ""
+	}
+	return pinned.String()
+}
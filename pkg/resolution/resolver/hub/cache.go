@@ -0,0 +1,343 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	// defaultCacheSize is the number of resolved resources cached when
+	// the cache-size config isn't set.
+	defaultCacheSize = 1024
+	// defaultCacheTTL is how long a resolved resource is cached when the
+	// cache-ttl config isn't set.
+	defaultCacheTTL = 5 * time.Minute
+	// defaultNegativeCacheTTL is how long a resource that wasn't found
+	// is cached when the negative-cache-ttl config isn't set.
+	defaultNegativeCacheTTL = 10 * time.Second
+	// defaultETagCacheTTL is how long a response with an ETag or a
+	// Last-Modified date is kept for revalidation when the
+	// etag-cache-ttl config isn't set.
+	defaultETagCacheTTL = time.Hour
+	// defaultCacheTTLJitter is the fraction of each ttl that is
+	// randomized when the cache-ttl-jitter config isn't set.
+	defaultCacheTTLJitter = 0.1
+)
+
+// cacheSettings are the configured settings of the cache of resolved
+// resources.
+type cacheSettings struct {
+	size int
+	ttl  time.Duration
+	// negativeTTL is how long resources that weren't found are cached.
+	negativeTTL time.Duration
+	// etagTTL is how long responses with an ETag or a Last-Modified
+	// date are kept so that they can be revalidated.
+	etagTTL time.Duration
+	// ttlJitter is the fraction, between 0 and 1, of each ttl that is
+	// randomized.
+	ttlJitter float64
+}
+
+// jitter returns the given ttl shortened by a random amount of up to
+// its ttlJitter fraction, so that entries cached together expire over a
+// spread of time instead of all being fetched again at once. An entry
+// is never kept for longer than the configured ttl.
+func (s cacheSettings) jitter(ttl time.Duration) time.Duration {
+	random := time.Duration(float64(ttl) * s.ttlJitter)
+	if random <= 0 {
+		return ttl
+	}
+	if random >= ttl {
+		// Keep every entry for at least a moment.
+		random = ttl - 1
+	}
+	// #nosec G404 -- jitter does not need a cryptographically secure source.
+	return ttl - time.Duration(rand.Int63n(int64(random)+1))
+}
+
+// notFoundEntry is cached for a resource that wasn't found so that the
+// hub isn't asked for it again until the entry expires.
+type notFoundEntry struct {
+	err error
+}
+
+// cacheKey identifies a resolved resource in the cache. The version is
+// the version requested, which may be empty or a version constraint.
+type cacheKey struct {
+	hubType string
+	// hubURL is the url of the hub set in the resolver's config, if any,
+	// since it may differ between namespaces.
+	hubURL  string
+	catalog string
+	kind    string
+	name    string
+	version string
+	// tokenSecret is the namespaced name of the secret used to
+	// authenticate with the hub, if any, so that resources resolved with
+	// one namespace's credentials are never served to another.
+	tokenSecret string
+	// headers are the extra headers of the headers param and the
+	// namespaced name of the headers-secret, if any, since they may
+	// select tenant-specific content.
+	headers       string
+	headersSecret string
+	// signed is set for resources fetched along with their signature,
+	// so that a resource resolved without one is never served to a
+	// request asking for it.
+	signed bool
+	// fields is set to the fields param so that a resource fetched
+	// without its metadata is never served to a request asking for it.
+	fields string
+}
+
+// newCacheKey returns the cache key for resolving the given version of
+// a resource.
+func newCacheKey(ctx context.Context, ref resourceRef, version string, params map[string]string) cacheKey {
+	key := cacheKey{
+		hubType: ref.hubType,
+		catalog: ref.catalog,
+		kind:    ref.kind,
+		name:    ref.name,
+		version: version,
+	}
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if ref.hubType == ArtifactHubType {
+		key.hubURL = conf[ConfigArtifactHubURL]
+	} else {
+		key.hubURL = conf[ConfigHubURL]
+	}
+	// A credential provider may hand out different tokens to each
+	// namespace, so resources are always cached per namespace then.
+	if secretName, ok := params[ParamTokenSecret]; ok || framework.GetCredentialProviderFromContext(ctx) != nil {
+		key.tokenSecret = fmt.Sprintf("%s/%s/%s", common.RequestNamespace(ctx), secretName, params[ParamTokenSecretKey])
+	}
+	key.signed, _ = strconv.ParseBool(params[ParamSigned])
+	key.fields, _ = fieldsParam(params)
+	key.headers = params[ParamHeaders]
+	if secretName, ok := params[ParamHeadersSecret]; ok {
+		key.headersSecret = fmt.Sprintf("%s/%s", common.RequestNamespace(ctx), secretName)
+	}
+	return key
+}
+
+// cacheConfig returns the configured settings of the cache of resolved
+// resources. A size or ttl of zero disables caching and a negative ttl
+// of zero disables caching of resources that weren't found.
+func cacheConfig(ctx context.Context) (cacheSettings, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	settings := cacheSettings{
+		size:        defaultCacheSize,
+		ttl:         defaultCacheTTL,
+		negativeTTL: defaultNegativeCacheTTL,
+		etagTTL:     defaultETagCacheTTL,
+		ttlJitter:   defaultCacheTTLJitter,
+	}
+	if s, ok := conf[ConfigCacheSize]; ok {
+		size, err := strconv.Atoi(s)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s config: %w", ConfigCacheSize, err)
+		}
+		if size < 0 {
+			return settings, fmt.Errorf("invalid %s config: must not be negative, got %d", ConfigCacheSize, size)
+		}
+		settings.size = size
+	}
+	var err error
+	if settings.ttl, err = parseCacheTTL(conf, ConfigCacheTTL, settings.ttl); err != nil {
+		return settings, err
+	}
+	if settings.negativeTTL, err = parseCacheTTL(conf, ConfigNegativeCacheTTL, settings.negativeTTL); err != nil {
+		return settings, err
+	}
+	if settings.etagTTL, err = parseCacheTTL(conf, ConfigETagCacheTTL, settings.etagTTL); err != nil {
+		return settings, err
+	}
+	if j, ok := conf[ConfigCacheTTLJitter]; ok {
+		jitter, err := strconv.ParseFloat(j, 64)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s config: %w", ConfigCacheTTLJitter, err)
+		}
+		if !(jitter >= 0 && jitter <= 1) {
+			return settings, fmt.Errorf("invalid %s config: must be between 0 and 1, got %s", ConfigCacheTTLJitter, j)
+		}
+		settings.ttlJitter = jitter
+	}
+	return settings, nil
+}
+
+// parseCacheTTL returns the ttl set in the given config field, or the
+// default if it isn't set.
+func parseCacheTTL(conf map[string]string, field string, defaultTTL time.Duration) (time.Duration, error) {
+	t, ok := conf[field]
+	if !ok {
+		return defaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(t)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s config: %w", field, err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("invalid %s config: must not be negative, got %s", field, t)
+	}
+	return ttl, nil
+}
+
+// isNotFound returns true if the given error means the resource doesn't
+// exist on the hub.
+func isNotFound(err error) bool {
+	var e *common.ResolutionNotFoundError
+	return errors.As(err, &e)
+}
+
+// resourceCache returns the cache of resolved resources, replacing it
+// if the configured size has changed since it was created. Nil is
+// returned if caching is disabled.
+func (r *Resolver) resourceCache(size int, ttl time.Duration) *cache.LRUExpireCache {
+	if size == 0 || ttl == 0 {
+		return nil
+	}
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if r.cache == nil || r.cacheSize != size {
+		r.cache = cache.NewLRUExpireCache(size)
+		r.cacheSize = size
+	}
+	return r.cache
+}
+
+// responseKey identifies a response from the hub kept for revalidation.
+// The url is combined with the key of the resource being resolved so
+// that a response fetched with one namespace's credentials is never
+// revalidated with another's.
+type responseKey struct {
+	resource cacheKey
+	url      string
+}
+
+// cachedResponse is the body of a response from the hub along with the
+// ETag and Last-Modified date the hub returned for it.
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// validators returns the ETag and Last-Modified date of the response,
+// without its body.
+func validators(resp *http.Response) *cachedResponse {
+	return &cachedResponse{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// setConditions makes the request conditional on the ETag and
+// Last-Modified date of the response, if any. If-Modified-Since is sent
+// along with If-None-Match so that servers which only support one of
+// them can still respond that the resource hasn't been modified.
+func (c *cachedResponse) setConditions(req *http.Request) {
+	if c == nil {
+		return
+	}
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if c.lastModified != "" {
+		req.Header.Set("If-Modified-Since", c.lastModified)
+	}
+}
+
+// revalidated returns the cached response updated with the ETag and
+// Last-Modified date of a 304 Not Modified response to a conditional
+// request for it, if the hub sent new ones.
+func (c *cachedResponse) revalidated(notModified *cachedResponse) *cachedResponse {
+	updated := *c
+	if notModified.etag != "" {
+		updated.etag = notModified.etag
+	}
+	if notModified.lastModified != "" {
+		updated.lastModified = notModified.lastModified
+	}
+	return &updated
+}
+
+// responseCache holds the responses from the hub for the requests made
+// while resolving a single resource.
+type responseCache struct {
+	cache    *cache.LRUExpireCache
+	resource cacheKey
+	settings cacheSettings
+}
+
+// get returns the response kept for the given url, if any.
+func (c *responseCache) get(url string) (*cachedResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	cached, ok := c.cache.Get(responseKey{resource: c.resource, url: url})
+	if !ok {
+		return nil, false
+	}
+	resp, ok := cached.(*cachedResponse)
+	return resp, ok
+}
+
+// add keeps the response for the given url, replacing any response kept
+// for it before and restarting its ttl.
+func (c *responseCache) add(url string, resp *cachedResponse) {
+	if c == nil {
+		return
+	}
+	c.cache.Add(responseKey{resource: c.resource, url: url}, resp, c.settings.jitter(c.settings.etagTTL))
+}
+
+// remove drops any response kept for the given url.
+func (c *responseCache) remove(url string) {
+	if c == nil {
+		return
+	}
+	c.cache.Remove(responseKey{resource: c.resource, url: url})
+}
+
+// responseCache returns the cache of responses for the requests made
+// while resolving the resource with the given key, replacing it if the
+// configured size has changed since it was created. Unlike the cache of
+// resolved resources it is used even when cache-ttl is zero, so that
+// every resolution is revalidated with the hub without downloading
+// unchanged content again. Nil is returned if conditional requests are
+// disabled.
+func (r *Resolver) responseCache(settings cacheSettings, key cacheKey) *responseCache {
+	if settings.size == 0 || settings.etagTTL == 0 {
+		return nil
+	}
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if r.responses == nil || r.responsesSize != settings.size {
+		r.responses = cache.NewLRUExpireCache(settings.size)
+		r.responsesSize = settings.size
+	}
+	return &responseCache{cache: r.responses, resource: key, settings: settings}
+}
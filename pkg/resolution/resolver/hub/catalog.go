@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// shouldValidateCatalog returns true if the validate-catalog config is
+// enabled.
+func shouldValidateCatalog(ctx context.Context) (bool, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	v, ok := conf[ConfigValidateCatalog]
+	if !ok {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s config: %w", ConfigValidateCatalog, err)
+	}
+	return enabled, nil
+}
+
+// validateCatalog checks that the given catalog is listed by at least
+// one of the hubs of the given type. Artifact Hub doesn't list catalogs
+// so only Tekton Hub catalogs are checked. A catalog is only reported
+// missing if a hub could be asked for its catalogs, otherwise resolving
+// the resource reports why the hubs couldn't be reached.
+func (r *Resolver) validateCatalog(ctx context.Context, opts requestOptions, hubType, catalog string) error {
+	if hubType == ArtifactHubType {
+		return nil
+	}
+	listed := false
+	for _, hubURL := range r.hubURLs(ctx, hubType) {
+		url, err := joinURL(hubURL, CatalogsEndpoint)
+		if err != nil {
+			return err
+		}
+		cr := tektonHubCatalogsResponse{}
+		if err := r.fetch(ctx, opts, url, &cr); err != nil {
+			continue
+		}
+		listed = true
+		for _, c := range cr.Data {
+			// The hub matches catalog names regardless of case.
+			if strings.EqualFold(c.Name, catalog) {
+				return nil
+			}
+		}
+	}
+	if !listed {
+		return nil
+	}
+	return fmt.Errorf("catalog '%s' not found on hub", catalog)
+}
+
+// catalogList returns the catalogs to resolve a resource from, in the
+// order they're tried, given the value of the catalog param. The param
+// holds either a single catalog or a comma-separated list of catalogs,
+// which is also what an array catalog param is turned into.
+func catalogList(catalogParam string) ([]string, error) {
+	if !strings.Contains(catalogParam, ",") {
+		if err := validatePathSegment(ParamCatalog, catalogParam); err != nil {
+			return nil, err
+		}
+		return []string{catalogParam}, nil
+	}
+	var catalogs []string
+	for _, catalog := range strings.Split(catalogParam, ",") {
+		catalog = strings.TrimSpace(catalog)
+		if catalog == "" {
+			return nil, fmt.Errorf("invalid %s param %q: catalog names can't be empty", ParamCatalog, catalogParam)
+		}
+		if err := validatePathSegment(ParamCatalog, catalog); err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, catalog)
+	}
+	return catalogs, nil
+}
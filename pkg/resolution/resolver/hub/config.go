@@ -23,3 +23,143 @@ const ConfigCatalog = "default-catalog"
 // ConfigKind is the configuration field name for controlling
 // what the layer name in the hub image is.
 const ConfigKind = "default-kind"
+
+// ConfigHubURL is the configuration field name for controlling the url
+// of the Tekton Hub api, overriding the resolver's HubURL and its
+// FallbackHubURLs. It is typically set for some namespaces only through
+// the namespace-overrides config.
+const ConfigHubURL = "hub-url"
+
+// ConfigArtifactHubURL is the configuration field name for controlling
+// the url of the Artifact Hub api, overriding the resolver's
+// ArtifactHubURL.
+const ConfigArtifactHubURL = "artifact-hub-url"
+
+// ConfigFetchTimeout is the configuration field name for controlling
+// the maximum duration of a single request to the hub.
+const ConfigFetchTimeout = "fetch-timeout"
+
+// ConfigCacheSize is the configuration field name for controlling the
+// maximum number of resolved resources kept in memory. Setting it to
+// "0" disables caching.
+const ConfigCacheSize = "cache-size"
+
+// ConfigCacheTTL is the configuration field name for controlling how
+// long a resolved resource is kept in memory. Setting it to "0" disables
+// caching.
+const ConfigCacheTTL = "cache-ttl"
+
+// ConfigNegativeCacheTTL is the configuration field name for controlling
+// how long a resource that wasn't found on the hub is kept in memory.
+// Setting it to "0" disables caching of resources that weren't found.
+const ConfigNegativeCacheTTL = "negative-cache-ttl"
+
+// ConfigETagCacheTTL is the configuration field name for controlling
+// how long a response from the hub that carried an ETag or a
+// Last-Modified date is kept in memory so that it can be revalidated
+// with a conditional request instead of being downloaded again. Setting
+// it to "0" disables conditional requests.
+const ConfigETagCacheTTL = "etag-cache-ttl"
+
+// ConfigCacheTTLJitter is the configuration field name for controlling
+// the fraction, between 0 and 1, of each cache ttl that is randomized so
+// that entries cached at the same time, e.g. right after the resolvers
+// start, don't all expire at once. Defaults to "0.1", "0" disables it.
+const ConfigCacheTTLJitter = "cache-ttl-jitter"
+
+// ConfigValidateCatalog is the configuration field name for controlling
+// whether the catalog of a request is checked against the catalogs
+// listed by the hub when its params are validated. Defaults to "false".
+const ConfigValidateCatalog = "validate-catalog"
+
+// ConfigReportHubStatus is the configuration field name for controlling
+// whether the status of the hub a resource was resolved from, such as
+// its version and when the resource's catalog was last updated, is
+// recorded in the annotations of the resolved resource. Getting it
+// costs an extra request to the hub for every resolution. Defaults to
+// "false".
+const ConfigReportHubStatus = "report-hub-status"
+
+// ConfigDetectKind is the configuration field name for controlling
+// whether the kind of a resource is detected when the kind param is
+// omitted, by trying each allowed kind in turn and checking the kind of
+// the yaml the hub serves, instead of defaulting to default-kind.
+// Defaults to "false".
+const ConfigDetectKind = "detect-kind"
+
+// ConfigLowercaseName is the configuration field name for controlling
+// whether the name param is lowercased before the resource is requested
+// from the hub, for hubs that only match names in lowercase. The
+// lowercased name is recorded in the annotations of the resolved
+// resource. Defaults to "false", which requests the name as given.
+const ConfigLowercaseName = "lowercase-name"
+
+// ConfigProxyURL is the configuration field name for controlling the
+// proxy that requests to the hub are sent through. When it isn't set
+// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are
+// used.
+const ConfigProxyURL = "proxy-url"
+
+// ConfigCABundle is the configuration field name for supplying a PEM
+// bundle of certificate authorities, in addition to the system ones,
+// that are trusted when connecting to the hub.
+const ConfigCABundle = "ca-bundle"
+
+// ConfigMaxRedirects is the configuration field name for controlling the
+// maximum number of redirects followed by a single request to the hub,
+// e.g. to the signed urls of a CDN in front of it. Defaults to 10.
+const ConfigMaxRedirects = "max-redirects"
+
+// ConfigForwardAuthorizationOnRedirect is the configuration field name
+// for sending the token's Authorization header along when the hub
+// redirects a request to another host. By default the header is only
+// sent to the hub's host and its subdomains so that the token isn't
+// leaked to e.g. a CDN. Defaults to "false".
+const ConfigForwardAuthorizationOnRedirect = "forward-authorization-on-redirect"
+
+// ConfigEmptyContentOnNotFound is the configuration field name for
+// restoring the old behavior of resolving a resource that Tekton Hub
+// reports as not found to empty content instead of failing with a not
+// found error. Defaults to "false".
+const ConfigEmptyContentOnNotFound = "empty-content-on-not-found"
+
+// ConfigExtraKinds is the configuration field name for a comma-separated
+// list of kinds, e.g. "stepaction", that are allowed in the kind param in
+// addition to "task" and "pipeline". This lets the resolver fetch new
+// kinds of resources as soon as the hub supports them.
+const ConfigExtraKinds = "extra-kinds"
+
+// ConfigMaxResponseSize is the configuration field name for controlling
+// the maximum size of a response from the hub, e.g. "10Mi", both before
+// and after it is decompressed. Defaults to 10Mi.
+const ConfigMaxResponseSize = "max-response-size"
+
+// ConfigMaxJSONDepth is the configuration field name for controlling the
+// maximum nesting depth of the json responses from the hub. Deeper
+// responses are rejected before they are unmarshalled. Defaults to 32.
+const ConfigMaxJSONDepth = "max-json-depth"
+
+// ConfigURLTemplate is the configuration field name for the path,
+// relative to the Tekton Hub api, of the yaml of a specific version of a
+// resource. The {catalog}, {kind}, {name} and {version} placeholders are
+// replaced with the resource's values. Defaults to DefaultURLTemplate.
+const ConfigURLTemplate = "url-template"
+
+// ConfigVersionsURLTemplate is the configuration field name for the
+// path, relative to the Tekton Hub api, listing the versions of a
+// resource. It takes the same placeholders as url-template except
+// {version}. Defaults to DefaultVersionsURLTemplate.
+const ConfigVersionsURLTemplate = "versions-url-template"
+
+// ConfigSignedURLTemplate is the configuration field name for the path,
+// relative to the Tekton Hub api, of the yaml of a specific version of a
+// resource along with its signature, used when the signed param is set.
+// It takes the same placeholders as url-template. Defaults to
+// DefaultSignedURLTemplate.
+const ConfigSignedURLTemplate = "signed-url-template"
+
+// ConfigExtraHeaders is the configuration field name for extra headers
+// sent with each request to the hub, e.g. for an api gateway in front of
+// it, as "Name: value" lines. The headers and headers-secret params
+// override headers of the same name.
+const ConfigExtraHeaders = "extra-headers"
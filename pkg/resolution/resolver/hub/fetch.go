@@ -0,0 +1,523 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// defaultTimeout is the maximum duration of a single request to the
+	// hub when no other timeout has been configured.
+	defaultTimeout = 30 * time.Second
+
+	// defaultRetries is the number of times a request to the hub is
+	// retried after a transient failure.
+	defaultRetries = 2
+
+	// defaultRetryBackoff is the delay before the first retry of a
+	// request to the hub.
+	defaultRetryBackoff = 500 * time.Millisecond
+
+	// defaultMaxRedirects is the maximum number of redirects followed by
+	// a request to the hub when no other limit has been configured. It
+	// matches the limit of the default http client.
+	defaultMaxRedirects = 10
+
+	// defaultMaxJSONDepth is the maximum nesting depth of a json response
+	// from the hub when no other limit has been configured. The hub's
+	// responses are only a few levels deep.
+	defaultMaxJSONDepth = 32
+)
+
+// requestOptions are the settings used for the requests made to the
+// hub during a single resolution.
+type requestOptions struct {
+	timeout      time.Duration
+	retries      int
+	retryBackoff time.Duration
+	// token is sent as a bearer token with each request when set.
+	token string
+	// credentials, when set, is asked for the token of each hub instead
+	// of reading it from the token secret.
+	credentials       framework.CredentialProvider
+	credentialRequest framework.CredentialRequest
+	// client sends the requests, the framework's shared client is used
+	// when nil.
+	client *http.Client
+	// emptyContentOnNotFound resolves a resource the hub reports as not
+	// found to empty content instead of returning an error.
+	emptyContentOnNotFound bool
+	// maxResponseSize is the maximum size in bytes of a response body,
+	// before and after decompression.
+	maxResponseSize int64
+	// maxJSONDepth is the maximum nesting depth of a json response.
+	maxJSONDepth int
+	// urlTemplates build the paths of the requests to Tekton Hub.
+	urlTemplates urlTemplates
+	// maxRedirects is the maximum number of redirects followed by a
+	// single request.
+	maxRedirects int
+	// forwardAuthorization sends the token to every host a request is
+	// redirected to instead of only the hub's host and its subdomains.
+	forwardAuthorization bool
+	// responses holds earlier responses that are revalidated with
+	// conditional requests. No conditional requests are made when nil.
+	responses *responseCache
+	// headers are the extra headers sent with each request.
+	headers extraHeaders
+	// signed fetches resources from the signed endpoint along with
+	// their signature.
+	signed bool
+	// fields selects the fields of a resource requested from Tekton Hub.
+	fields string
+}
+
+// newRequestOptions returns the settings for the requests made to the
+// hub given the resolution's params and the resolver's config.
+func (r *Resolver) newRequestOptions(ctx context.Context, params map[string]string) (requestOptions, error) {
+	opts := requestOptions{
+		retries:         defaultRetries,
+		retryBackoff:    defaultRetryBackoff,
+		maxResponseSize: framework.DefaultMaxResponseSize,
+		maxJSONDepth:    defaultMaxJSONDepth,
+		maxRedirects:    defaultMaxRedirects,
+	}
+
+	timeout, err := r.fetchTimeout(ctx, params)
+	if err != nil {
+		return opts, err
+	}
+	opts.timeout = timeout
+
+	opts.client, err = r.httpClient(ctx)
+	if err != nil {
+		return opts, err
+	}
+
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if v, ok := conf[ConfigEmptyContentOnNotFound]; ok {
+		opts.emptyContentOnNotFound, err = strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigEmptyContentOnNotFound, err)
+		}
+	}
+
+	if v, ok := conf[ConfigMaxResponseSize]; ok {
+		size, err := resource.ParseQuantity(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigMaxResponseSize, err)
+		}
+		if size.Sign() <= 0 {
+			return opts, fmt.Errorf("invalid %s config: must be greater than zero, got %q", ConfigMaxResponseSize, v)
+		}
+		opts.maxResponseSize = size.Value()
+	}
+
+	if v, ok := conf[ConfigMaxJSONDepth]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("invalid %s config: must be a positive integer, got %q", ConfigMaxJSONDepth, v)
+		}
+		opts.maxJSONDepth = n
+	}
+
+	opts.urlTemplates, err = newURLTemplates(ctx)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.headers, err = configHeaders(ctx)
+	if err != nil {
+		return opts, err
+	}
+	if v, ok := params[ParamHeaders]; ok {
+		paramHeaders, err := parseHeaders(v, ParamHeaders+" param")
+		if err != nil {
+			return opts, err
+		}
+		opts.headers = opts.headers.merge(paramHeaders)
+	}
+
+	if v, ok := conf[ConfigMaxRedirects]; ok {
+		opts.maxRedirects, err = parseMaxRedirects(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigMaxRedirects, err)
+		}
+	}
+	if v, ok := params[ParamMaxRedirects]; ok {
+		opts.maxRedirects, err = parseMaxRedirects(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s param: %w", ParamMaxRedirects, err)
+		}
+	}
+	if v, ok := conf[ConfigForwardAuthorizationOnRedirect]; ok {
+		opts.forwardAuthorization, err = strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s config: %w", ConfigForwardAuthorizationOnRedirect, err)
+		}
+	}
+
+	if retries, ok := params[ParamRetries]; ok {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid %s param: must be a non-negative integer, got %q", ParamRetries, retries)
+		}
+		opts.retries = n
+	}
+
+	if backoff, ok := params[ParamRetryBackoff]; ok {
+		d, err := framework.ParseTimeout(backoff)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s param: %w", ParamRetryBackoff, err)
+		}
+		opts.retryBackoff = d
+	}
+
+	return opts, nil
+}
+
+// fetchTimeout returns the timeout to apply to each hub request, taking
+// the timeout param over the fetch-timeout config over the resolver's
+// Timeout field.
+func (r *Resolver) fetchTimeout(ctx context.Context, params map[string]string) (time.Duration, error) {
+	if timeout, ok := params[ParamTimeout]; ok {
+		d, err := framework.ParseTimeout(timeout)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s param: %w", ParamTimeout, err)
+		}
+		return d, nil
+	}
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if timeout, ok := conf[ConfigFetchTimeout]; ok {
+		d, err := framework.ParseTimeout(timeout)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s config: %w", ConfigFetchTimeout, err)
+		}
+		return d, nil
+	}
+	if r.Timeout > 0 {
+		return r.Timeout, nil
+	}
+	return defaultTimeout, nil
+}
+
+func parseMaxRedirects(maxRedirects string) (int, error) {
+	n, err := strconv.Atoi(maxRedirects)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("must be a non-negative integer, got %q", maxRedirects)
+	}
+	return n, nil
+}
+
+// fetch requests the given url from the hub api and unmarshals the json
+// response into v. Requests that fail with a connection error or a
+// server error are retried with exponential backoff. When an earlier
+// response to the same request carried an ETag or a Last-Modified date
+// the request is made conditional on them, and the earlier response is
+// used again if the hub responds that it hasn't been modified.
+func (r *Resolver) fetch(ctx context.Context, opts requestOptions, url string, v interface{}) error {
+	var resp *cachedResponse
+	var statusCode int
+	cached, _ := opts.responses.get(url)
+	retryOpts := framework.RetryOptionsFromContext(ctx, framework.RetryOptions{
+		MaxAttempts: opts.retries + 1,
+		Backoff:     opts.retryBackoff,
+		Jitter:      0.5,
+		Retryable: func(err error) bool {
+			return isRetryable(statusCode, err)
+		},
+	})
+	err := framework.Retry(ctx, retryOpts, func() error {
+		var err error
+		resp, statusCode, err = r.get(ctx, opts, url, cached)
+		return err
+	})
+	var retryErr *framework.RetryError
+	if errors.As(err, &retryErr) {
+		if retryErr.ContextErr != nil {
+			return fmt.Errorf("hub request to '%s' cancelled after %d attempts: %w", url, retryErr.Attempts, retryErr.ContextErr)
+		}
+		gaveUp := fmt.Sprintf("failed after %d attempts", retryErr.Attempts)
+		if retryErr.MaxElapsed > 0 {
+			gaveUp = fmt.Sprintf("failed after %d attempts, retrying reached the %s config of %s", retryErr.Attempts, framework.ConfigRetryMaxElapsed, retryErr.MaxElapsed)
+		}
+		var cte *contentTypeError
+		if statusCode != 0 && !errors.As(retryErr.Err, &cte) {
+			return fmt.Errorf("hub request to '%s' %s, last status code %d", url, gaveUp, statusCode)
+		}
+		return fmt.Errorf("hub request to '%s' %s: %w", url, gaveUp, retryErr.Err)
+	}
+	if err != nil {
+		return err
+	}
+
+	body := resp.body
+	switch {
+	case statusCode == http.StatusNotModified:
+		body = cached.body
+		opts.responses.add(url, cached.revalidated(resp))
+	case resp.etag != "" || resp.lastModified != "":
+		opts.responses.add(url, resp)
+	default:
+		// The kept response is out of date and can't be revalidated.
+		opts.responses.remove(url)
+	}
+
+	if err := checkJSONDepth(body, opts.maxJSONDepth); err != nil {
+		return fmt.Errorf("error unmarshalling json response: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error unmarshalling json response: %w", err)
+	}
+	return nil
+}
+
+// checkJSONDepth returns an error if the objects and arrays of the json
+// document are nested more than maxDepth levels deep. It only scans the
+// document, so that a pathological response from an untrusted hub is
+// rejected before unmarshalling it allocates anything.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	depth := 0
+	inString, escaped := false, false
+	for i, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			if depth++; depth > maxDepth {
+				return fmt.Errorf("json nested more than %d levels deep at offset %d", maxDepth, i)
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// get performs a single GET request against the hub, returning the
+// response body along with its ETag and Last-Modified date, or an
+// error, and the status code of the response if one was received. When
+// an earlier response is given the request is conditional on its ETag
+// and Last-Modified date, and a 304 Not Modified response is returned
+// without a body.
+func (r *Resolver) get(ctx context.Context, opts requestOptions, url string, earlier *cachedResponse) (_ *cachedResponse, _ int, err error) {
+	ctx, span := framework.StartSpan(ctx, "hub.Get", trace.StringAttribute("http.url", url))
+	defer func() { framework.EndSpan(span, err) }()
+	reqCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	timedOut := func(err error) bool {
+		return errors.Is(reqCtx.Err(), context.DeadlineExceeded) && errors.Is(err, context.DeadlineExceeded)
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error constructing request to hub: %w", err)
+	}
+	framework.SetUserAgent(ctx, req)
+	opts.headers.apply(req, true)
+	if opts.token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.token)
+	}
+	earlier.setConditions(req)
+	// Asking for gzip explicitly stops the client from transparently
+	// decompressing the response, so that the max response size can be
+	// enforced on the compressed body as well.
+	req.Header.Set("Accept-Encoding", "gzip")
+	logger := logging.FromContext(ctx)
+	logger.Debugf("sending hub request to %s with headers %v", url, framework.RedactHeaders(req.Header))
+	resp, err := redirectingClient(ctx, opts).Do(req)
+	if err != nil {
+		logger.Debugf("hub request to %s failed: %v", url, err)
+		if timedOut(err) {
+			return nil, 0, newTimeoutError(url, opts.timeout)
+		}
+		var rle *redirectLimitError
+		if errors.As(err, &rle) {
+			return nil, 0, fmt.Errorf("hub request to '%s' failed: %w", url, rle)
+		}
+		return nil, 0, fmt.Errorf("error requesting resource from hub: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	logger.Debugf("hub request to %s returned status code %d", url, resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && earlier != nil {
+		return validators(resp), resp.StatusCode, nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, resp.StatusCode, &common.RateLimitedError{
+			Resource:   url,
+			RetryAfter: framework.RetryAfter(resp, time.Now()),
+			Original:   fmt.Errorf("hub request to '%s' was rate limited", url),
+		}
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode < http.StatusInternalServerError {
+		err := fmt.Errorf("requested resource '%s' not found on hub", url)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, resp.StatusCode, &common.ResolutionNotFoundError{Resource: url, Original: err}
+		}
+		return nil, resp.StatusCode, &clientError{statusCode: resp.StatusCode, err: err}
+	}
+	// Check the content type before anything else so that an error page
+	// from a misconfigured proxy in front of the hub is easy to spot.
+	if ct := resp.Header.Get("Content-Type"); !isJSONContentType(ct) {
+		return nil, resp.StatusCode, &contentTypeError{contentType: ct, statusCode: resp.StatusCode}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, resp.StatusCode, fmt.Errorf("hub request to '%s' failed with status code %d", url, resp.StatusCode)
+	}
+	body, err := framework.ReadResponseBody(resp, opts.maxResponseSize)
+	if err != nil {
+		if timedOut(err) {
+			return nil, 0, newTimeoutError(url, opts.timeout)
+		}
+		var tooLarge *framework.ResponseTooLargeError
+		if errors.As(err, &tooLarge) {
+			// The same response would be returned again, so it isn't
+			// worth retrying.
+			return nil, resp.StatusCode, fmt.Errorf("hub request to '%s' failed: %w", url, err)
+		}
+		return nil, 0, fmt.Errorf("error reading response body: %w", err)
+	}
+	cached := validators(resp)
+	cached.body = body
+	return cached, resp.StatusCode, nil
+}
+
+// redirectingClient returns a copy of the client in opts that follows
+// at most opts.maxRedirects redirects. The Authorization header is
+// dropped by the http client when a request is redirected to a host
+// other than the hub's host or one of its subdomains, and so are the
+// extra headers read from a secret, unless opts.forwardAuthorization is
+// set.
+func redirectingClient(ctx context.Context, opts requestOptions) *http.Client {
+	client := opts.client
+	if client == nil {
+		client = framework.HTTPClient(ctx)
+	}
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > opts.maxRedirects {
+			return &redirectLimitError{maxRedirects: opts.maxRedirects}
+		}
+		if opts.forwardAuthorization {
+			if opts.token != "" {
+				req.Header.Set("Authorization", "Bearer "+opts.token)
+			}
+		} else if !isSameHostOrSubdomain(req.URL.Hostname(), via[0].URL.Hostname()) {
+			opts.headers.apply(req, false)
+		}
+		return nil
+	}
+	return &c
+}
+
+// redirectLimitError is returned when a request to the hub is
+// redirected more times than allowed.
+type redirectLimitError struct {
+	maxRedirects int
+}
+
+func (e *redirectLimitError) Error() string {
+	return fmt.Sprintf("stopped after %d redirects, the maximum set by %s", e.maxRedirects, ParamMaxRedirects)
+}
+
+// newTimeoutError returns the error for a single request to the hub
+// that exceeded its timeout.
+func newTimeoutError(url string, timeout time.Duration) error {
+	return &common.ResolutionTimeoutError{
+		Resource: url,
+		Timeout:  timeout,
+		Original: fmt.Errorf("hub request to '%s' timed out after %s", url, timeout),
+	}
+}
+
+// clientError is returned for a response with a client error status
+// code other than 404 Not Found and 429 Too Many Requests.
+type clientError struct {
+	statusCode int
+	err        error
+}
+
+func (e *clientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *clientError) Unwrap() error {
+	return e.err
+}
+
+// contentTypeError is returned when the hub responds with something
+// other than json.
+type contentTypeError struct {
+	contentType string
+	statusCode  int
+}
+
+func (e *contentTypeError) Error() string {
+	return fmt.Sprintf("expected application/json from hub but got %s (status %d)", e.contentType, e.statusCode)
+}
+
+// isJSONContentType returns true if the given Content-Type header value
+// is a json media type. A missing header is accepted since the body may
+// still be json.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isRetryable returns true if a failed request may succeed when retried.
+// A zero status code means no response was received at all, e.g.
+// because the connection was reset. Timeouts are not retried since each
+// attempt would likely wait out the full timeout again, and neither are
+// rate limited requests, which the resolution framework requeues after
+// the delay the hub asked for. A request that was redirected too many
+// times would be redirected the same way again.
+func isRetryable(statusCode int, err error) bool {
+	var te *common.ResolutionTimeoutError
+	if errors.As(err, &te) {
+		return false
+	}
+	var rle *redirectLimitError
+	if errors.As(err, &rle) {
+		return false
+	}
+	return statusCode == 0 || statusCode >= http.StatusInternalServerError
+}
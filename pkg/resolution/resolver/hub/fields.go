@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// FieldsAll is the fields param value requesting a resource along
+	// with all of its metadata, such as the bundle it's served from.
+	FieldsAll = "all"
+	// FieldsYAML is the fields param value requesting only the yaml of
+	// a resource.
+	FieldsYAML = "yaml"
+
+	// fieldsQueryParam is the query parameter of the content url
+	// selecting the fields of the response.
+	fieldsQueryParam = "fields"
+)
+
+// fieldsParam returns the value of the fields param, which defaults to
+// all, or an error if it isn't one of the supported values or the
+// resource is signed, since the signature is one of the fields left out.
+func fieldsParam(params map[string]string) (string, error) {
+	fields, ok := params[ParamFields]
+	if !ok {
+		return FieldsAll, nil
+	}
+	switch fields {
+	case FieldsAll:
+		return fields, nil
+	case FieldsYAML:
+		if signed, _ := strconv.ParseBool(params[ParamSigned]); signed {
+			return "", fmt.Errorf("%s param %s can't be used with the %s param", ParamFields, FieldsYAML, ParamSigned)
+		}
+		return fields, nil
+	}
+	return "", fmt.Errorf("invalid %s param %q: must be %s or %s", ParamFields, fields, FieldsAll, FieldsYAML)
+}
+
+// fieldsURL returns the content url with the query parameter asking the
+// hub to only respond with the yaml of the resource.
+func fieldsURL(contentURL string) (string, error) {
+	u, err := url.Parse(contentURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid hub url: %w", err)
+	}
+	query := u.Query()
+	query.Set(fieldsQueryParam, FieldsYAML)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// fetchYAMLField fetches the content url asking the hub for only the
+// yaml of the resource. A hub that doesn't support selecting fields
+// either ignores the query parameter, responding with the full
+// resource, or rejects it, in which case the full resource is fetched
+// instead.
+func (r *Resolver) fetchYAMLField(ctx context.Context, opts requestOptions, contentURL string, v interface{}) error {
+	sparseURL, err := fieldsURL(contentURL)
+	if err != nil {
+		return err
+	}
+	err = r.fetch(ctx, opts, sparseURL, v)
+	var ce *clientError
+	if !errors.As(err, &ce) || (ce.statusCode != http.StatusBadRequest && ce.statusCode != http.StatusUnprocessableEntity) {
+		return err
+	}
+	logging.FromContext(ctx).Infof("hub rejected the %s query parameter with status code %d, fetching the full resource", fieldsQueryParam, ce.statusCode)
+	return r.fetch(ctx, opts, contentURL, v)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"fmt"
+	"strconv"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FormatYAML is the format param value resolving a resource to the
+	// yaml the hub serves it as.
+	FormatYAML = "yaml"
+	// FormatJSON is the format param value resolving a resource to its
+	// json representation.
+	FormatJSON = "json"
+)
+
+// formatParam returns the value of the format param, which defaults to
+// yaml, or an error if it isn't one of the supported formats or the
+// resource is signed as json, since the signature only verifies against
+// the yaml the hub served.
+func formatParam(params map[string]string) (string, error) {
+	format, ok := params[ParamFormat]
+	if !ok {
+		return FormatYAML, nil
+	}
+	switch format {
+	case FormatYAML:
+		return format, nil
+	case FormatJSON:
+		if signed, _ := strconv.ParseBool(params[ParamSigned]); signed {
+			return "", fmt.Errorf("%s param %s can't be used with the %s param", ParamFormat, FormatJSON, ParamSigned)
+		}
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid %s param %q: must be %s or %s", ParamFormat, format, FormatYAML, FormatJSON)
+}
+
+// convertFormat returns the resource, whose content is the yaml fetched
+// from the hub, in the given format. The resource itself is left
+// unchanged since it may be cached.
+func convertFormat(resource *ResolvedHubResource, format string) (*ResolvedHubResource, error) {
+	if format != FormatJSON {
+		return resource, nil
+	}
+	converted := *resource
+	converted.Format = FormatJSON
+	// Empty content, returned when empty-content-on-not-found is set,
+	// stays empty rather than becoming "null".
+	if len(resource.Content) > 0 {
+		content, err := yaml.YAMLToJSON(resource.Content)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert %s %q to json: %w", resource.Kind, resource.Name, err)
+		}
+		converted.Content = content
+		converted.ServedYAML = resource.Content
+	}
+	return &converted, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"golang.org/x/net/http/httpguts"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// reservedHeaders are the headers the resolver sets itself, which can't
+// be set as extra headers. The token-secret param sets Authorization.
+var reservedHeaders = sets.NewString("Accept-Encoding", "Authorization", "Content-Length", "Host", "If-None-Match")
+
+// header is an extra header sent with each request to the hub.
+type header struct {
+	name  string
+	value string
+	// secret is set for values read from a secret, which are redacted
+	// when the headers are logged and aren't sent along when a request
+	// is redirected to another host.
+	secret bool
+}
+
+// extraHeaders are the extra headers sent with each request to the hub
+// during a resolution.
+type extraHeaders []header
+
+// String returns the headers as they can be logged, with the values read
+// from secrets redacted.
+func (h extraHeaders) String() string {
+	lines := make([]string, 0, len(h))
+	for _, hdr := range h {
+		value := hdr.value
+		if hdr.secret {
+			value = framework.RedactedValue
+		}
+		lines = append(lines, hdr.name+": "+value)
+	}
+	return strings.Join(lines, ", ")
+}
+
+// apply sets the headers on the request, leaving out the ones read from
+// secrets unless withSecrets is set.
+func (h extraHeaders) apply(req *http.Request, withSecrets bool) {
+	for _, hdr := range h {
+		if hdr.secret && !withSecrets {
+			req.Header.Del(hdr.name)
+			continue
+		}
+		req.Header.Set(hdr.name, hdr.value)
+	}
+}
+
+// merge returns the headers with those in overrides added, replacing
+// any header of the same name.
+func (h extraHeaders) merge(overrides extraHeaders) extraHeaders {
+	merged := extraHeaders{}
+	for _, hdr := range h {
+		if !overrides.has(hdr.name) {
+			merged = append(merged, hdr)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+func (h extraHeaders) has(name string) bool {
+	for _, hdr := range h {
+		if http.CanonicalHeaderKey(hdr.name) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// configHeaders returns the headers set by the extra-headers config.
+func configHeaders(ctx context.Context) (extraHeaders, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	return parseHeaders(conf[ConfigExtraHeaders], ConfigExtraHeaders+" config")
+}
+
+// parseHeaders parses newline-separated "Name: value" lines, ignoring
+// blank lines. source names the config or param the lines come from in
+// returned errors.
+func parseHeaders(lines, source string) (extraHeaders, error) {
+	var headers extraHeaders
+	for _, line := range strings.Split(lines, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: %q must be of the form \"Name: value\"", source, strings.TrimSpace(line))
+		}
+		hdr, err := newHeader(strings.TrimSpace(name), strings.TrimSpace(value), false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", source, err)
+		}
+		if headers.has(hdr.name) {
+			return nil, fmt.Errorf("invalid %s: header %s is set more than once", source, hdr.name)
+		}
+		headers = append(headers, hdr)
+	}
+	return headers, nil
+}
+
+// newHeader returns the header with the given name and value, or an
+// error if either isn't valid or the header is reserved. The value is
+// left out of errors for headers read from a secret.
+func newHeader(name, value string, secret bool) (header, error) {
+	if !httpguts.ValidHeaderFieldName(name) {
+		return header{}, fmt.Errorf("%q is not a valid header name", name)
+	}
+	name = http.CanonicalHeaderKey(name)
+	if reservedHeaders.Has(name) {
+		return header{}, fmt.Errorf("header %s is set by the resolver and can't be overridden", name)
+	}
+	if !httpguts.ValidHeaderFieldValue(value) {
+		if secret {
+			return header{}, fmt.Errorf("the value of header %s is not a valid header value", name)
+		}
+		return header{}, fmt.Errorf("%q is not a valid value for header %s", value, name)
+	}
+	return header{name: name, value: value, secret: secret}, nil
+}
+
+// getSecretHeaders returns the headers read from the secret named by the
+// headers-secret param, one per key of the secret, or nil if the param
+// isn't given. The values are never included in returned errors.
+func (r *Resolver) getSecretHeaders(ctx context.Context, params map[string]string) (extraHeaders, error) {
+	secretName, ok := params[ParamHeadersSecret]
+	if !ok {
+		return nil, nil
+	}
+	if secretName == "" {
+		return nil, fmt.Errorf("%s param must not be empty", ParamHeadersSecret)
+	}
+	if r.kubeClientSet == nil {
+		return nil, fmt.Errorf("cannot read headers secret %s: no kubernetes client available", secretName)
+	}
+
+	namespace := common.RequestNamespace(ctx)
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClientSet, namespace, secretName, "hub headers")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secret.Data))
+	for name := range secret.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var headers extraHeaders
+	for _, name := range names {
+		hdr, err := newHeader(name, strings.TrimSpace(string(secret.Data[name])), true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header in secret %s in namespace %s: %w", secretName, namespace, err)
+		}
+		headers = append(headers, hdr)
+	}
+	return headers, nil
+}
+
+// isSameHostOrSubdomain returns true if host is the same as origin or
+// one of its subdomains.
+func isSameHostOrSubdomain(host, origin string) bool {
+	return host == origin || strings.HasSuffix(host, "."+origin)
+}
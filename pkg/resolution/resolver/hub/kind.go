@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// defaultKinds are the kinds that are always allowed in the kind param.
+var defaultKinds = []string{"task", "pipeline"}
+
+// kindRegex matches the kinds that may be listed in the extra-kinds
+// config. Kinds become part of the hub urls so they're kept simple.
+var kindRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// allowedKinds returns the kinds allowed in the kind param: the default
+// kinds followed by any listed in the extra-kinds config.
+func allowedKinds(ctx context.Context) ([]string, error) {
+	kinds := append([]string{}, defaultKinds...)
+	conf := framework.GetResolverConfigFromContext(ctx)
+	extra, ok := conf[ConfigExtraKinds]
+	if !ok {
+		return kinds, nil
+	}
+	for _, kind := range strings.Split(extra, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" || containsKind(kinds, kind) {
+			continue
+		}
+		if !kindRegex.MatchString(kind) {
+			return nil, fmt.Errorf("invalid %s config: kind %q must be lowercase letters, digits and dashes", ConfigExtraKinds, kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// validateKind returns an error listing the allowed kinds if the given
+// kind isn't one of them.
+func validateKind(ctx context.Context, kind string) error {
+	kinds, err := allowedKinds(ctx)
+	if err != nil {
+		return err
+	}
+	if containsKind(kinds, kind) {
+		return nil
+	}
+	last := len(kinds) - 1
+	return fmt.Errorf("kind param must be %s or %s", strings.Join(kinds[:last], ", "), kinds[last])
+}
+
+// shouldDetectKind returns whether the detect-kind config is enabled.
+func shouldDetectKind(ctx context.Context) (bool, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	v, ok := conf[ConfigDetectKind]
+	if !ok {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s config: %w", ConfigDetectKind, err)
+	}
+	return enabled, nil
+}
+
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// shouldLowercaseName returns true if the lowercase-name config is
+// enabled.
+func shouldLowercaseName(ctx context.Context) (bool, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	v, ok := conf[ConfigLowercaseName]
+	if !ok {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s config: %w", ConfigLowercaseName, err)
+	}
+	return enabled, nil
+}
+
+// normalizeName returns the name a resource is requested from the hub
+// with, which is the name param lowercased when the lowercase-name
+// config is enabled and the name param as given otherwise.
+func normalizeName(name string, lowercase bool) string {
+	if !lowercase {
+		return name
+	}
+	return strings.ToLower(name)
+}
@@ -13,24 +13,121 @@ limitations under the License.
 
 package hub
 
+import "github.com/tektoncd/pipeline/pkg/resolution/common"
+
 // DefaultHubURL is de default url for the Tekton hub api
-const DefaultHubURL = "https://api.hub.tekton.dev/v1/resource/%s/%s/%s/%s/yaml"
+const DefaultHubURL = "https://api.hub.tekton.dev"
 
-// YamlEndpoint is the suffix for a private custom hub instance
+// YamlEndpoint is the path, relative to the hub api, of the yaml for
+// a specific version of a resource
 const YamlEndpoint = "v1/resource/%s/%s/%s/%s/yaml"
 
+// VersionsEndpoint is the path, relative to the hub api, listing the
+// available versions of a resource
+const VersionsEndpoint = "v1/resource/%s/%s/%s/versions"
+
+// CatalogsEndpoint is the path, relative to the hub api, listing the
+// catalogs available on the hub
+const CatalogsEndpoint = "v1/catalogs"
+
+// DefaultArtifactHubURL is the default url for the Artifact Hub api
+const DefaultArtifactHubURL = "https://artifacthub.io"
+
+// ArtifactHubYamlEndpoint is the path, relative to the Artifact Hub api,
+// of a specific version of a resource
+const ArtifactHubYamlEndpoint = "api/v1/packages/tekton-%s/%s/%s/%s"
+
+// ArtifactHubVersionsEndpoint is the path, relative to the Artifact Hub
+// api, of the latest version of a resource along with its available
+// versions
+const ArtifactHubVersionsEndpoint = "api/v1/packages/tekton-%s/%s/%s"
+
 // ParamName is the parameter defining what the layer name in the bundle
 // image is.
-const ParamName = "name"
+const ParamName = common.ParamName
 
 // ParamKind is the parameter defining what the layer kind in the bundle
 // image is.
-const ParamKind = "kind"
+const ParamKind = common.ParamKind
 
 // ParamVersion is the parameter defining what the layer version in the bundle
-// image is.
+// image is. It can either be an exact version or a range of versions,
+// e.g. ">=0.2.0 <0.4.0" or "^0.3", in which case the highest matching
+// version is resolved. When omitted the latest version is resolved.
 const ParamVersion = "version"
 
 // ParamCatalog is the parameter defining what the catalog in the bundle
-// image is.
+// image is. It can also list several catalogs, either comma-separated or
+// as an array, in which case they're tried in order and the resource is
+// resolved from the first catalog that has it.
 const ParamCatalog = "catalog"
+
+// ParamTimeout is the parameter defining the maximum duration of a
+// single request to the hub, overriding the fetch-timeout config.
+const ParamTimeout = common.ParamTimeout
+
+// ParamRetries is the parameter defining how many times a request to
+// the hub is retried after a transient failure.
+const ParamRetries = "retries"
+
+// ParamRetryBackoff is the parameter defining the initial delay between
+// retries of a request to the hub. The delay doubles with each retry.
+const ParamRetryBackoff = "retry-backoff"
+
+// ParamMaxRedirects is the parameter defining the maximum number of
+// redirects followed by a single request to the hub, overriding the
+// max-redirects config.
+const ParamMaxRedirects = "max-redirects"
+
+// ParamTokenSecret is the parameter defining the name of a secret, in
+// the namespace of the resolution request, holding a bearer token used
+// to authenticate requests to the hub.
+const ParamTokenSecret = "token-secret"
+
+// ParamTokenSecretKey is the parameter defining the key within the
+// token secret that holds the bearer token. Defaults to "token".
+const ParamTokenSecretKey = "token-secret-key"
+
+// ParamType is the parameter defining which type of hub to resolve the
+// resource from, either "tekton" for Tekton Hub or "artifact" for
+// Artifact Hub. Defaults to "tekton".
+const ParamType = "type"
+
+// ParamDigest is the parameter defining the expected SHA-256 digest of
+// the resolved YAML, in the form "sha256:<hex>". Resolution fails if
+// the hub returns different content, e.g. because the version was
+// re-published.
+const ParamDigest = common.ParamDigest
+
+// ParamHeaders is the parameter defining extra headers sent with each
+// request to the hub, as "Name: value" strings. It is either an array
+// with one header per item or a string with one header per line.
+// Headers set here override the extra-headers config of the same name.
+const ParamHeaders = "headers"
+
+// ParamHeadersSecret is the parameter defining the name of a secret, in
+// the namespace of the resolution request, whose keys are the names of
+// extra headers sent with each request to the hub and whose values are
+// their values. These headers override those of the headers param and
+// the extra-headers config, and their values are never logged.
+const ParamHeadersSecret = "headers-secret"
+
+// ParamFormat is the parameter defining the format the resource is
+// resolved to, either "yaml" or "json". Defaults to "yaml", the format
+// the hub serves resources in.
+const ParamFormat = "format"
+
+// ParamSigned is the parameter defining whether the resource is fetched
+// from the hub's signed endpoint, set by the signed-url-template config,
+// along with the signature attached to it, so that it can be verified
+// downstream without fetching it again. It requires the catalog and
+// version of the resource. Defaults to "false".
+const ParamSigned = "signed"
+
+// ParamFields is the parameter defining which fields of a resource are
+// requested from the hub, either "all" for the resource along with its
+// metadata, such as the bundle it's served from, or "yaml" for only its
+// yaml, which makes for smaller responses. Hubs that can't select fields
+// respond with the full resource instead. Only Tekton Hub is asked for
+// the selected fields. Defaults to "all".
+const ParamFields = "fields"
@@ -15,17 +15,24 @@ package hub
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/resolution/common"
 	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/logging"
 )
 
 const (
@@ -40,10 +47,36 @@ const (
 type Resolver struct {
 	// HubURL is the URL for hub resolver
 	HubURL string
+	// FallbackHubURLs are tried in order when a resource can't be
+	// resolved from HubURL, e.g. because it is unreachable or doesn't
+	// have the resource.
+	FallbackHubURLs []string
+	// ArtifactHubURL is the URL of the Artifact Hub api used for
+	// requests with the artifact type. Defaults to DefaultArtifactHubURL.
+	ArtifactHubURL string
+	// Timeout bounds each request made to the hub. It can be
+	// overridden by the fetch-timeout config and the timeout param,
+	// and defaults to defaultTimeout when unset.
+	Timeout time.Duration
+
+	kubeClientSet kubernetes.Interface
+
+	cacheMu   sync.Mutex
+	cache     *cache.LRUExpireCache
+	cacheSize int
+	// responses holds the responses from the hub that carried an ETag,
+	// keyed by responseKey.
+	responses     *cache.LRUExpireCache
+	responsesSize int
+
+	clientMu     sync.Mutex
+	client       *http.Client
+	clientConfig transportConfig
 }
 
-// Initialize sets up any dependencies needed by the resolver. None atm.
-func (r *Resolver) Initialize(context.Context) error {
+// Initialize sets up any dependencies needed by the resolver.
+func (r *Resolver) Initialize(ctx context.Context) error {
+	r.kubeClientSet = client.Get(ctx)
 	return nil
 }
 
@@ -57,6 +90,14 @@ func (r *Resolver) GetConfigName(context.Context) string {
 	return "hubresolver-config"
 }
 
+var _ framework.ClusterScopedConfig = &Resolver{}
+
+// ClusterScopedConfigKeys returns the cache-size config, which sizes the
+// cache shared by the resolutions of every namespace.
+func (r *Resolver) ClusterScopedConfigKeys(context.Context) []string {
+	return []string{ConfigCacheSize}
+}
+
 // GetSelector returns a map of labels to match requests to this resolver.
 func (r *Resolver) GetSelector(context.Context) map[string]string {
 	return map[string]string{
@@ -64,112 +105,796 @@ func (r *Resolver) GetSelector(context.Context) map[string]string {
 	}
 }
 
+var _ framework.ParamTracer = &Resolver{}
+
+// TracedParams returns the params recorded on the spans of resolutions,
+// which identify the requested resource.
+func (r *Resolver) TracedParams(context.Context) []string {
+	return []string{ParamType, ParamCatalog, ParamKind, ParamName, ParamVersion}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver. The
+// kinds allowed include any listed in the extra-kinds config, and the
+// defaults read from the resolver's config are added by the framework
+// from DefaultParams.
+func (r *Resolver) GetParamSchema(ctx context.Context) []framework.ParamSchema {
+	kinds, err := allowedKinds(ctx)
+	if err != nil {
+		// An invalid extra-kinds config fails validation anyway, so
+		// only the kinds that are always allowed are reported.
+		kinds = append([]string{}, defaultKinds...)
+	}
+	return []framework.ParamSchema{{
+		Name:        ParamName,
+		Description: "The name of the resource.",
+		Required:    true,
+	}, {
+		Name:        ParamKind,
+		Description: "The kind of the resource.",
+		Enum:        kinds,
+	}, {
+		Name:        ParamVersion,
+		Description: "The version of the resource, or a range of versions whose highest is resolved. The latest version is resolved when omitted.",
+	}, {
+		Name:        ParamCatalog,
+		Description: "The catalog the resource is resolved from, or a comma-separated list of catalogs tried in order.",
+	}, {
+		Name:        ParamType,
+		Description: "The type of hub the resource is resolved from.",
+		Enum:        []string{TektonHubType, ArtifactHubType},
+	}, {
+		Name:        ParamDigest,
+		Description: "The expected SHA-256 digest of the YAML the hub serves, whatever the format, in the form sha256:<hex>.",
+	}, {
+		Name:        ParamFormat,
+		Description: "The format the resource is resolved to.",
+		Enum:        []string{FormatYAML, FormatJSON},
+		Default:     FormatYAML,
+	}, {
+		Name:        ParamSigned,
+		Description: "Whether the resource is fetched along with its signature. Requires the catalog and version.",
+		Enum:        []string{"true", "false"},
+		Default:     "false",
+	}, {
+		Name:        ParamFields,
+		Description: "Which fields of the resource are requested from the hub.",
+		Enum:        []string{FieldsAll, FieldsYAML},
+		Default:     FieldsAll,
+	}, {
+		Name:        ParamTimeout,
+		Description: "The maximum duration of a single request to the hub, overriding the fetch-timeout config.",
+	}, {
+		Name:        ParamRetries,
+		Description: "How many times a request to the hub is retried after a transient failure.",
+	}, {
+		Name:        ParamRetryBackoff,
+		Description: "The initial delay between retries of a request to the hub, doubling with each retry.",
+	}, {
+		Name:        ParamMaxRedirects,
+		Description: "The maximum number of redirects followed by a single request to the hub, overriding the max-redirects config.",
+	}, {
+		Name:        ParamTokenSecret,
+		Description: "The name of a secret, in the namespace of the request, holding a bearer token for the hub.",
+	}, {
+		Name:        ParamTokenSecretKey,
+		Description: "The key within the token secret that holds the bearer token.",
+		Default:     defaultTokenSecretKey,
+	}, {
+		Name:        ParamHeaders,
+		Description: "Extra headers sent with each request to the hub, as \"Name: value\" strings.",
+	}, {
+		Name:        ParamHeadersSecret,
+		Description: "The name of a secret, in the namespace of the request, whose keys and values are extra headers sent with each request to the hub.",
+	}}
+}
+
+var _ framework.BackendIdentifier = &Resolver{}
+
+// Backend returns the url of the hub the resource is resolved from, as
+// set for the namespace of the request, so that one hub being down
+// doesn't stop resolutions from the others, e.g. a tenant's own hub.
+// The fallback hubs are tried by the resolution itself and so share the
+// circuit of the first one.
+func (r *Resolver) Backend(ctx context.Context, params []pipelinev1beta1.Param) string {
+	hubType := stringParams(framework.ApplyDefaultParams(ctx, r, params))[ParamType]
+	return r.hubURLs(ctx, hubType)[0]
+}
+
 // ValidateParams ensures parameters from a request are as expected.
 func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
 	if r.isDisabled(ctx) {
-		return errors.New(disabledError)
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	if err := framework.ValidateNoVariableReferences(params); err != nil {
+		return err
 	}
+	given := stringParams(params)
+	_, hasKind := given[ParamKind]
+	_, hasCatalog := given[ParamCatalog]
+	params = framework.ApplyDefaultParams(ctx, r, params)
 	paramsMap := make(map[string]pipelinev1beta1.ParamValue)
 	for _, p := range params {
 		paramsMap[p.Name] = p.Value
 	}
-	if _, ok := paramsMap[ParamName]; !ok {
+	name, ok := paramsMap[ParamName]
+	if !ok {
 		return errors.New("must include name param")
 	}
-	if _, ok := paramsMap[ParamVersion]; !ok {
-		return errors.New("must include version param")
+	if err := validatePathSegment(ParamName, name.StringVal); err != nil {
+		return err
 	}
+	// The effective kind is validated whether it was given or defaulted,
+	// so that a default-kind config the resolver doesn't support is
+	// reported as such. The same goes for the catalog below.
 	if kind, ok := paramsMap[ParamKind]; ok {
-		if kind.StringVal != "task" && kind.StringVal != "pipeline" {
-			return errors.New("kind param must be task or pipeline")
+		if err := validateKind(ctx, kind.StringVal); err != nil {
+			if !hasKind {
+				return fmt.Errorf("invalid %s config: %w", ConfigKind, err)
+			}
+			return err
 		}
 	}
+	opts, err := r.newRequestOptions(ctx, stringParams(params))
+	if err != nil {
+		return err
+	}
+	if err := validateHubURLConfig(ctx); err != nil {
+		return err
+	}
+	if err := validateSecretParams(stringParams(params)); err != nil {
+		return err
+	}
+	if version, ok := paramsMap[ParamVersion]; ok {
+		if err := validateVersion(version.StringVal); err != nil {
+			return err
+		}
+	}
+	if _, err := digestParam(stringParams(params)); err != nil {
+		return err
+	}
+	if _, err := formatParam(stringParams(params)); err != nil {
+		return err
+	}
+	hubType := paramsMap[ParamType].StringVal
+	if hubType != TektonHubType && hubType != ArtifactHubType {
+		return fmt.Errorf("type param must be %s or %s", TektonHubType, ArtifactHubType)
+	}
+	signed, err := signedParam(stringParams(params))
+	if err != nil {
+		return err
+	}
+	if _, err := fieldsParam(stringParams(params)); err != nil {
+		return err
+	}
+	if hubType == TektonHubType {
+		if err := opts.urlTemplates.checkParams(stringParams(params), signed); err != nil {
+			return err
+		}
+	}
+	if _, err := catalogList(stringParams(params)[ParamCatalog]); err != nil {
+		if !hasCatalog {
+			return fmt.Errorf("invalid %s config: %w", ConfigCatalog, err)
+		}
+		return err
+	}
+	// Whether the catalogs exist is only checked by Preflight, since it
+	// queries the hub.
+	if _, err := shouldValidateCatalog(ctx); err != nil {
+		return err
+	}
+	if _, err := shouldDetectKind(ctx); err != nil {
+		return err
+	}
+	if _, err := shouldReportHubStatus(ctx); err != nil {
+		return err
+	}
+	if _, err := shouldLowercaseName(ctx); err != nil {
+		return err
+	}
 	return nil
 }
 
-type dataResponse struct {
-	YAML string `json:"yaml"`
+var _ framework.Preflighter = &Resolver{}
+
+// Preflight checks that the requested catalogs exist on the hub when
+// the validate-catalog config is enabled, and does nothing otherwise.
+func (r *Resolver) Preflight(ctx context.Context, params []pipelinev1beta1.Param) error {
+	validate, err := shouldValidateCatalog(ctx)
+	if err != nil || !validate {
+		return err
+	}
+	paramsMap := stringParams(framework.ApplyDefaultParams(ctx, r, params))
+	catalogs, err := catalogList(paramsMap[ParamCatalog])
+	if err != nil {
+		return err
+	}
+	opts, err := r.newRequestOptions(ctx, paramsMap)
+	if err != nil {
+		return err
+	}
+	if provider := framework.GetCredentialProviderFromContext(ctx); provider != nil {
+		opts.credentials = provider
+		opts.credentialRequest = credentialRequest(ctx, paramsMap)
+	} else if opts.token, err = r.getToken(ctx, paramsMap); err != nil {
+		return err
+	}
+	secretHeaders, err := r.getSecretHeaders(ctx, paramsMap)
+	if err != nil {
+		return err
+	}
+	opts.headers = opts.headers.merge(secretHeaders)
+	for _, catalog := range catalogs {
+		if catalog != "" {
+			if err := r.validateCatalog(ctx, opts, paramsMap[ParamType], catalog); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-type hubResponse struct {
-	Data dataResponse `json:"data"`
+// DefaultParams returns the defaults of the optional params: the hub
+// type defaults to the Tekton Hub while the catalog and kind default to
+// the default-catalog and default-kind set in the resolver's config.
+// The kind has no default when detect-kind is enabled, so that it is
+// detected instead.
+func (r *Resolver) DefaultParams(ctx context.Context) map[string]string {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	defaults := map[string]string{
+		ParamType: TektonHubType,
+	}
+	if catalog, ok := conf[ConfigCatalog]; ok {
+		defaults[ParamCatalog] = catalog
+	}
+	if detect, _ := shouldDetectKind(ctx); detect {
+		return defaults
+	}
+	if kind, ok := conf[ConfigKind]; ok {
+		defaults[ParamKind] = kind
+	}
+	return defaults
 }
 
 // Resolve uses the given params to resolve the requested file or resource.
 func (r *Resolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
 	if r.isDisabled(ctx) {
-		return nil, errors.New(disabledError)
+		return nil, common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
 	}
 
-	conf := framework.GetResolverConfigFromContext(ctx)
+	paramsMap := stringParams(framework.ApplyDefaultParams(ctx, r, params))
 
-	paramsMap := make(map[string]string)
-	for _, p := range params {
-		paramsMap[p.Name] = p.Value.StringVal
+	if _, ok := paramsMap[ParamCatalog]; !ok {
+		return nil, fmt.Errorf("default catalog was not set during installation of the hub resolver")
 	}
 
-	if _, ok := paramsMap[ParamCatalog]; !ok {
-		if catalogString, ok := conf[ConfigCatalog]; ok {
-			paramsMap[ParamCatalog] = catalogString
-		} else {
-			return nil, fmt.Errorf("default catalog was not set during installation of the hub resolver")
+	detectKind, err := shouldDetectKind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kind, ok := paramsMap[ParamKind]
+	switch {
+	case !ok && !detectKind:
+		return nil, fmt.Errorf("default resource Kind was not set during installation of the hub resolver")
+	case ok:
+		if err := validateKind(ctx, kind); err != nil {
+			return nil, err
 		}
 	}
 
-	kind, ok := paramsMap[ParamKind]
+	hubType := paramsMap[ParamType]
+	if hubType != TektonHubType && hubType != ArtifactHubType {
+		return nil, fmt.Errorf("type param must be %s or %s", TektonHubType, ArtifactHubType)
+	}
+	digest, err := digestParam(paramsMap)
+	if err != nil {
+		return nil, err
+	}
+	format, err := formatParam(paramsMap)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := signedParam(paramsMap)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldsParam(paramsMap)
+	if err != nil {
+		return nil, err
+	}
+	reportStatus, err := shouldReportHubStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lowercaseName, err := shouldLowercaseName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs, err := catalogList(paramsMap[ParamCatalog])
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := r.newRequestOptions(ctx, paramsMap)
+	if err != nil {
+		return nil, err
+	}
+	opts.signed = signed
+	opts.fields = fields
+	if provider := framework.GetCredentialProviderFromContext(ctx); provider != nil {
+		opts.credentials = provider
+		opts.credentialRequest = credentialRequest(ctx, paramsMap)
+	} else {
+		opts.token, err = r.getToken(ctx, paramsMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	secretHeaders, err := r.getSecretHeaders(ctx, paramsMap)
+	if err != nil {
+		return nil, err
+	}
+	opts.headers = opts.headers.merge(secretHeaders)
+	if len(opts.headers) > 0 {
+		logging.FromContext(ctx).Debugf("sending extra headers with hub requests: %s", opts.headers)
+	}
+
+	ref := resourceRef{
+		hubType: hubType,
+		catalog: paramsMap[ParamCatalog],
+		kind:    kind,
+		name:    normalizeName(paramsMap[ParamName], lowercaseName),
+	}
+
+	var resource *ResolvedHubResource
+	if ref.kind == "" {
+		resource, err = r.resolveDetectingKind(ctx, opts, ref, catalogs, paramsMap)
+	} else {
+		resource, err = r.resolveCached(ctx, opts, ref, catalogs, paramsMap)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ref.kind = resource.Kind
+	if reportStatus {
+		resource = r.withHubStatus(ctx, opts, resource)
+	}
+	if lowercaseName {
+		// The resource is copied since it may be shared through the
+		// cache with requests made before the config was enabled.
+		normalized := *resource
+		normalized.NameNormalized = true
+		resource = &normalized
+	}
+	return resolvedResource(resource, ref, digest, format)
+}
+
+// resolveCached resolves the referenced resource from the resolver's
+// cache if it holds it, otherwise from the first of the given catalogs
+// that has it, adding it to the cache.
+func (r *Resolver) resolveCached(ctx context.Context, opts requestOptions, ref resourceRef, catalogs []string, paramsMap map[string]string) (*ResolvedHubResource, error) {
+	settings, err := cacheConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resourceCache := r.resourceCache(settings.size, settings.ttl)
+	key := newCacheKey(ctx, ref, paramsMap[ParamVersion], paramsMap)
+	opts.responses = r.responseCache(settings, key)
+	if resourceCache != nil {
+		if cached, ok := resourceCache.Get(key); ok {
+			switch entry := cached.(type) {
+			case *ResolvedHubResource:
+				return entry, nil
+			case *notFoundEntry:
+				return nil, entry.err
+			}
+		}
+	}
+
+	resource, err := r.resolveFromCatalogs(ctx, opts, ref, catalogs, paramsMap[ParamVersion])
+	if err != nil {
+		// Resources that weren't found are cached briefly to protect the
+		// hub from repeated requests for a misspelled name while still
+		// picking up newly published resources soon. Other failures are
+		// never cached so that they're retried on the next resolution.
+		if resourceCache != nil && settings.negativeTTL > 0 && isNotFound(err) {
+			resourceCache.Add(key, &notFoundEntry{err: err}, settings.jitter(settings.negativeTTL))
+		}
+		return nil, err
+	}
+	if resourceCache != nil {
+		ttl := settings.ttl
+		if len(resource.Content) == 0 {
+			// The hub responded without any content for the resource, so
+			// treat it like a resource that wasn't found.
+			ttl = settings.negativeTTL
+		}
+		if ttl > 0 {
+			resourceCache.Add(key, resource, settings.jitter(ttl))
+		}
+	}
+	return resource, nil
+}
+
+// resolveDetectingKind resolves a resource whose kind wasn't given by
+// trying each allowed kind in turn until the hub has a resource of that
+// kind and name, then checks that the yaml the hub serves is of that
+// kind. A resource the hub serves without any content, when
+// empty-content-on-not-found is enabled, only resolves if no kind has
+// one with content.
+func (r *Resolver) resolveDetectingKind(ctx context.Context, opts requestOptions, ref resourceRef, catalogs []string, paramsMap map[string]string) (*ResolvedHubResource, error) {
+	kinds, err := allowedKinds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var empty *ResolvedHubResource
+	var errs []string
+	for _, kind := range kinds {
+		ref.kind = kind
+		resource, err := r.resolveCached(ctx, opts, ref, catalogs, paramsMap)
+		if isNotFound(err) {
+			errs = append(errs, fmt.Sprintf("%s: %v", kind, err))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(resource.Content) == 0 {
+			if empty == nil {
+				empty = resource
+			}
+			continue
+		}
+		detected, err := framework.DetectKind(resource.Content, kinds)
+		if err != nil {
+			return nil, fmt.Errorf("could not detect the kind of %q: %w", ref.name, err)
+		}
+		if detected != kind {
+			return nil, fmt.Errorf("%s %q served by the hub is a %s", kind, ref.name, detected)
+		}
+		// The cached resource is shared, so it is copied rather than
+		// flagged in place.
+		withKind := *resource
+		withKind.KindDetected = true
+		return &withKind, nil
+	}
+	if empty != nil {
+		return empty, nil
+	}
+	return nil, &common.ResolutionNotFoundError{
+		Resource: fmt.Sprintf("%q", ref.name),
+		Original: fmt.Errorf("failed to resolve %q as any of the kinds %s: %s", ref.name, strings.Join(kinds, ", "), strings.Join(errs, "; ")),
+	}
+}
+
+// digestParam returns the value of the digest param, which is empty
+// when it isn't set, or an error if it isn't of the form
+// "sha256:<hex>".
+func digestParam(params map[string]string) (string, error) {
+	digest, ok := params[ParamDigest]
 	if !ok {
-		if kindString, ok := conf[ConfigKind]; ok {
-			kind = kindString
-		} else {
-			return nil, fmt.Errorf("default resource Kind was not set during installation of the hub resolver")
+		return "", nil
+	}
+	if err := framework.ValidateDigest(ParamDigest, digest); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// resolvedResource returns the resource fetched from the hub in the
+// given format, after checking its digest. The digest is that of the
+// yaml served by the hub whatever the format.
+func resolvedResource(resource *ResolvedHubResource, ref resourceRef, digest, format string) (framework.ResolvedResource, error) {
+	if digest != "" && resource.Digest() != digest {
+		return nil, fmt.Errorf("digest mismatch for %s %q: expected %s but got %s", ref.kind, ref.name, digest, resource.Digest())
+	}
+	converted, err := convertFormat(resource, format)
+	if err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// resolveFromCatalogs resolves a resource from the first of the given
+// catalogs that has it. Catalogs that don't have the resource are
+// skipped while any other failure is returned straight away, so that a
+// catalog that's temporarily unavailable isn't silently bypassed.
+func (r *Resolver) resolveFromCatalogs(ctx context.Context, opts requestOptions, ref resourceRef, catalogs []string, version string) (*ResolvedHubResource, error) {
+	if len(catalogs) == 1 {
+		ref.catalog = catalogs[0]
+		return r.resolveFromHubs(ctx, opts, ref, version)
+	}
+	var errs []string
+	for _, catalog := range catalogs {
+		ref.catalog = catalog
+		resource, err := r.resolveFromHubs(ctx, opts, ref, version)
+		if err == nil {
+			return resource, nil
+		}
+		if !isNotFound(err) {
+			return nil, err
 		}
+		errs = append(errs, fmt.Sprintf("catalog '%s': %v", catalog, err))
+	}
+	return nil, &common.ResolutionNotFoundError{
+		Resource: fmt.Sprintf("%s %q", ref.kind, ref.name),
+		Original: fmt.Errorf("failed to resolve %s %q from any catalog: %s", ref.kind, ref.name, strings.Join(errs, "; ")),
+	}
+}
+
+// resolveFromHubs resolves a resource from the first hub, of the type
+// set in the ref, that has it.
+func (r *Resolver) resolveFromHubs(ctx context.Context, opts requestOptions, ref resourceRef, version string) (*ResolvedHubResource, error) {
+	urls := r.hubURLs(ctx, ref.hubType)
+	var errs []string
+	notFound := true
+	var rateLimited *common.RateLimitedError
+	for _, hubURL := range urls {
+		ref.hubURL = hubURL
+		if opts.credentials != nil {
+			token, err := opts.credentials.Token(ctx, hubURL, opts.credentialRequest)
+			if err != nil {
+				return nil, fmt.Errorf("error getting token for hub '%s': %w", hubURL, err)
+			}
+			framework.RedactSecret(ctx, token)
+			opts.token = token
+		}
+		resource, err := r.resolveFromHub(ctx, opts, ref, version)
+		if err == nil {
+			return resource, nil
+		}
+		if len(urls) == 1 || ctx.Err() != nil {
+			return nil, err
+		}
+		notFound = notFound && isNotFound(err)
+		var rle *common.RateLimitedError
+		if errors.As(err, &rle) && (rateLimited == nil || rle.RetryAfter < rateLimited.RetryAfter) {
+			rateLimited = rle
+		}
+		errs = append(errs, fmt.Sprintf("hub '%s': %v", hubURL, err))
+	}
+	resource := fmt.Sprintf("%s %q", ref.kind, ref.name)
+	err := fmt.Errorf("failed to resolve %s from any hub: %s", resource, strings.Join(errs, "; "))
+	if notFound {
+		return nil, &common.ResolutionNotFoundError{Resource: resource, Original: err}
 	}
-	if kind != "task" && kind != "pipeline" {
-		return nil, fmt.Errorf("kind param must be task or pipeline")
+	// A hub that rate limited the request may have the resource, so the
+	// resolution is retried once the first such hub allows it.
+	if rateLimited != nil {
+		return nil, &common.RateLimitedError{Resource: resource, RetryAfter: rateLimited.RetryAfter, Original: err}
 	}
+	return nil, err
+}
 
-	paramsMap[ParamKind] = kind
-	url := fmt.Sprintf(r.HubURL, paramsMap[ParamCatalog], paramsMap[ParamKind], paramsMap[ParamName], paramsMap[ParamVersion])
-	// #nosec G107 -- URL cannot be constant in this case.
-	resp, err := http.Get(url)
+// resolveFromHub resolves the given version of a resource, which may be
+// empty or a version constraint, from the hub set in the ref.
+func (r *Resolver) resolveFromHub(ctx context.Context, opts requestOptions, ref resourceRef, version string) (*ResolvedHubResource, error) {
+	switch {
+	case version == "":
+		var err error
+		version, err = r.resolveLatestVersion(ctx, opts, ref)
+		if err != nil {
+			return nil, err
+		}
+	case framework.IsVersionConstraint(version):
+		var err error
+		version, err = r.resolveVersionConstraint(ctx, opts, ref, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resource := &ResolvedHubResource{
+		Version: version,
+		Catalog: ref.catalog,
+		HubType: ref.hubType,
+		HubURL:  ref.hubURL,
+		Kind:    ref.kind,
+		Name:    ref.name,
+	}
+	if opts.signed {
+		signed, err := r.fetchSignedContent(ctx, opts, ref, version)
+		if err != nil {
+			return nil, err
+		}
+		resource.Signature = signed.Signature
+		resource.Certificate = signed.Certificate
+		resource.setContent(ctx, &signed.tektonHubDataResponse)
+		return resource, nil
+	}
+	data, err := r.fetchContent(ctx, opts, ref, version)
 	if err != nil {
-		return nil, fmt.Errorf("error requesting resource from hub: %w", err)
+		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("requested resource '%s' not found on hub", url)
+	resource.setContent(ctx, data)
+	return resource, nil
+}
+
+// resolveVersionConstraint queries the hub for the available versions
+// of a resource and returns the highest one satisfying the constraint.
+func (r *Resolver) resolveVersionConstraint(ctx context.Context, opts requestOptions, ref resourceRef, constraint string) (string, error) {
+	constraints, err := framework.ParseVersionConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	body, err := io.ReadAll(resp.Body)
+	_, versions, err := r.fetchVersions(ctx, opts, ref)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		return "", err
+	}
+	version, ok := framework.SelectVersion(constraints, versions)
+	if !ok {
+		return "", fmt.Errorf("no version of %s %q in catalog %q satisfies constraint %q", ref.kind, ref.name, ref.catalog, constraint)
 	}
-	hr := hubResponse{}
-	err = json.Unmarshal(body, &hr)
+	return version, nil
+}
+
+// resolveLatestVersion queries the hub for the latest available version
+// of a resource.
+func (r *Resolver) resolveLatestVersion(ctx context.Context, opts requestOptions, ref resourceRef) (string, error) {
+	latest, versions, err := r.fetchVersions(ctx, opts, ref)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling json response: %w", err)
+		return "", err
 	}
-	return &ResolvedHubResource{
-		Content: []byte(hr.Data.YAML),
-	}, nil
+	if latest != "" {
+		return latest, nil
+	}
+	// Not every hub reports the latest version explicitly so fall back
+	// to the highest version it lists.
+	version, ok := framework.SelectVersion(nil, versions)
+	if !ok {
+		return "", fmt.Errorf("no versions of %s %q found in catalog %q", ref.kind, ref.name, ref.catalog)
+	}
+	return version, nil
 }
 
 // ResolvedHubResource wraps the data we want to return to Pipelines
 type ResolvedHubResource struct {
 	Content []byte
+	// Version is the concrete version that was resolved, which differs
+	// from the requested version when a version range was given.
+	Version string
+	// Catalog is the catalog the resource was fetched from.
+	Catalog string
+	// HubType and HubURL identify the hub the resource was fetched from.
+	HubType string
+	HubURL  string
+	// Kind and Name identify the resource within the catalog.
+	Kind string
+	Name string
+	// KindDetected is set when the request didn't give the kind and it
+	// was detected from Content instead.
+	KindDetected bool
+	// Format is the format of Content, FormatYAML when empty.
+	Format string
+	// Signature is the signature the hub attached to the yaml it
+	// served, and Certificate the certificate to verify it with, when
+	// the resource was fetched with the signed param.
+	Signature   string
+	Certificate string
+	// Bundle is the OCI reference the hub serves the resource from, if
+	// any, and ResolvedBundle that reference pinned to the digest the
+	// hub reported for it, empty if it didn't report one.
+	Bundle         string
+	ResolvedBundle string
+	// HubVersion is the version of the hub and CatalogUpdatedAt when it
+	// last updated Catalog, set when the report-hub-status config is
+	// enabled and the hub reports them.
+	HubVersion       string
+	CatalogUpdatedAt string
+	// NameNormalized is set when Name is the name param lowercased by
+	// the lowercase-name config.
+	NameNormalized bool
+	// ServedYAML is the yaml the hub served when Content was converted
+	// to another format, which the digest is reported for.
+	ServedYAML []byte
 }
 
 var _ framework.ResolvedResource = &ResolvedHubResource{}
 
+// setContent sets the resource's content, along with the OCI reference
+// it is served from, to the data the hub returned.
+func (rr *ResolvedHubResource) setContent(ctx context.Context, data *tektonHubDataResponse) {
+	rr.Content = []byte(data.YAML)
+	rr.Bundle = data.Bundle
+	rr.ResolvedBundle = pinnedBundle(ctx, data)
+}
+
 // Data returns the bytes of our hard-coded Pipeline
 func (rr *ResolvedHubResource) Data() []byte {
 	return rr.Content
 }
 
-// Annotations returns any metadata needed alongside the data. None atm.
-func (*ResolvedHubResource) Annotations() map[string]string {
-	return nil
+// Annotations returns the version and catalog the resource was
+// resolved from along with the digest and format of its content, its
+// provenance, for signed resources its signature and, for resources the
+// hub serves from an OCI reference, that reference pinned to its digest
+// and, when reported, the status of the hub.
+func (rr *ResolvedHubResource) Annotations() map[string]string {
+	format := rr.Format
+	if format == "" {
+		format = FormatYAML
+	}
+	m := map[string]string{
+		AnnotationKeyDigest:            rr.Digest(),
+		AnnotationKeyFormat:            format,
+		common.AnnotationKeyProvenance: rr.provenance().AnnotationValue(),
+	}
+	if rr.Version != "" {
+		m[AnnotationKeyVersion] = rr.Version
+	}
+	if rr.Catalog != "" {
+		m[AnnotationKeyCatalog] = rr.Catalog
+	}
+	if rr.Signature != "" {
+		m[AnnotationKeySignature] = rr.Signature
+	}
+	if rr.Certificate != "" {
+		m[AnnotationKeyCertificate] = rr.Certificate
+	}
+	if rr.ResolvedBundle != "" {
+		m[common.AnnotationKeyResolvedBundle] = rr.ResolvedBundle
+	}
+	if rr.KindDetected {
+		m[common.AnnotationKeyDetectedKind] = rr.Kind
+	}
+	if rr.NameNormalized {
+		m[AnnotationKeyName] = rr.Name
+	}
+	if rr.HubVersion != "" {
+		m[AnnotationKeyHubVersion] = rr.HubVersion
+	}
+	if rr.CatalogUpdatedAt != "" {
+		m[AnnotationKeyCatalogUpdatedAt] = rr.CatalogUpdatedAt
+	}
+	return m
+}
+
+// provenance records the hub, catalog and version the resource was
+// resolved from, and the OCI reference the hub serves it from, if any,
+// pinned to its digest when the hub reported one.
+func (rr *ResolvedHubResource) provenance() common.Provenance {
+	sum := sha256.Sum256(rr.servedContent())
+	bundle := rr.ResolvedBundle
+	if bundle == "" {
+		bundle = rr.Bundle
+	}
+	coordinates := map[string]string{}
+	for k, v := range map[string]string{
+		"type":    rr.HubType,
+		"catalog": rr.Catalog,
+		"kind":    rr.Kind,
+		"name":    rr.Name,
+		"version": rr.Version,
+		"bundle":  bundle,
+	} {
+		if v != "" {
+			coordinates[k] = v
+		}
+	}
+	return common.Provenance{
+		ResolverType: LabelValueHubResolverType,
+		URI:          rr.HubURL,
+		Digest:       map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		Coordinates:  coordinates,
+	}
+}
+
+// Digest returns the SHA-256 digest of the yaml the hub served in the
+// form "sha256:<hex>", whatever the format of the resource's content, so
+// that it can be passed back as the digest param.
+func (rr *ResolvedHubResource) Digest() string {
+	sum := sha256.Sum256(rr.servedContent())
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// servedContent returns the yaml the hub served for the resource.
+func (rr *ResolvedHubResource) servedContent() []byte {
+	if rr.ServedYAML != nil {
+		return rr.ServedYAML
+	}
+	return rr.Content
 }
 
 // Source is the source reference of the remote data that records where the remote
@@ -178,6 +903,60 @@ func (rr *ResolvedHubResource) Source() *v1beta1.ConfigSource {
 	return nil
 }
 
+// stringParams returns a map of the string values of the given params.
+func stringParams(params []pipelinev1beta1.Param) map[string]string {
+	paramsMap := make(map[string]string)
+	for _, p := range params {
+		paramsMap[p.Name] = p.Value.StringVal
+		// An array catalog param lists catalogs to try in order, just
+		// like a comma-separated one, and an array headers param lists
+		// headers just like one with a header per line.
+		if p.Value.Type == pipelinev1beta1.ParamTypeArray {
+			switch p.Name {
+			case ParamCatalog:
+				paramsMap[p.Name] = strings.Join(p.Value.ArrayVal, ",")
+			case ParamHeaders:
+				paramsMap[p.Name] = strings.Join(p.Value.ArrayVal, "\n")
+			}
+		}
+	}
+	return paramsMap
+}
+
+// CheckHealth sends a HEAD request, with the headers of the
+// extra-headers config, to the Tekton Hub api that requests are
+// resolved from first. Any response without a server error counts as
+// healthy since the api's root doesn't serve a resource.
+func (r *Resolver) CheckHealth(ctx context.Context) error {
+	if r.isDisabled(ctx) {
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	client, err := r.httpClient(ctx)
+	if err != nil {
+		return err
+	}
+	headers, err := configHeaders(ctx)
+	if err != nil {
+		return err
+	}
+	hubURL := r.hubURLs(ctx, TektonHubType)[0]
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, hubURL, nil)
+	if err != nil {
+		return fmt.Errorf("constructing request to hub %s: %w", hubURL, err)
+	}
+	framework.SetUserAgent(ctx, req)
+	headers.apply(req, true)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("hub %s is unreachable: %w", hubURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("hub %s responded with status %d", hubURL, resp.StatusCode)
+	}
+	return nil
+}
+
 func (r *Resolver) isDisabled(ctx context.Context) bool {
 	cfg := resolverconfig.FromContextOrDefaults(ctx)
 	if cfg.FeatureFlags.EnableHubResolver {
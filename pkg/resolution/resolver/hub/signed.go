@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+)
+
+// tektonHubSignedDataResponse is a resource's yaml along with the
+// signature, and optionally the certificate to verify it with, that the
+// hub attached to it.
+type tektonHubSignedDataResponse struct {
+	tektonHubDataResponse
+	Signature   string `json:"signature"`
+	Certificate string `json:"certificate"`
+}
+
+// tektonHubSignedResponse is the shape of a resource's signed yaml from
+// Tekton Hub.
+type tektonHubSignedResponse struct {
+	Data    tektonHubSignedDataResponse `json:"data"`
+	Name    string                      `json:"name"`
+	Message string                      `json:"message"`
+}
+
+// signedParam returns whether the signed param asks for the resource
+// to be fetched from the signed endpoint, or an error if it is set
+// without the catalog and version that the signature attests or for a
+// hub without a signed endpoint. The params must already have their
+// defaults applied.
+func signedParam(params map[string]string) (bool, error) {
+	v, ok := params[ParamSigned]
+	if !ok {
+		return false, nil
+	}
+	signed, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s param %q: must be true or false", ParamSigned, v)
+	}
+	if !signed {
+		return false, nil
+	}
+	if hubType := params[ParamType]; hubType != TektonHubType {
+		return false, fmt.Errorf("%s param is only supported with type %s, got %s", ParamSigned, TektonHubType, hubType)
+	}
+	if params[ParamCatalog] == "" {
+		return false, fmt.Errorf("%s param requires a catalog, set the %s param or the %s config", ParamSigned, ParamCatalog, ConfigCatalog)
+	}
+	if params[ParamVersion] == "" {
+		return false, fmt.Errorf("%s param requires the %s param", ParamSigned, ParamVersion)
+	}
+	return true, nil
+}
+
+// fetchSignedContent returns the yaml of the given version of a
+// resource along with its signature from the signed endpoint of the
+// Tekton Hub set in the ref. Resources the hub doesn't return a
+// signature for fail the resolution, since the caller asked for one.
+func (r *Resolver) fetchSignedContent(ctx context.Context, opts requestOptions, ref resourceRef, version string) (*tektonHubSignedDataResponse, error) {
+	url, err := opts.urlTemplates.signedContentURL(ref, version)
+	if err != nil {
+		return nil, err
+	}
+	sr := tektonHubSignedResponse{}
+	if err := r.fetch(ctx, opts, url, &sr); err != nil {
+		return nil, err
+	}
+	if sr.Name == tektonHubNotFound && sr.Data.YAML == "" {
+		return nil, &common.ResolutionNotFoundError{
+			Resource: url,
+			Original: fmt.Errorf("requested resource '%s' not found on hub: %s", url, sr.Message),
+		}
+	}
+	if sr.Data.Signature == "" {
+		return nil, fmt.Errorf("hub returned no signature for %s %q version %s from '%s'", ref.kind, ref.name, version, url)
+	}
+	return &sr.Data, nil
+}
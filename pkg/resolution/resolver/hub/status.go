@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"knative.dev/pkg/logging"
+)
+
+// shouldReportHubStatus returns true if the report-hub-status config is
+// enabled.
+func shouldReportHubStatus(ctx context.Context) (bool, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	v, ok := conf[ConfigReportHubStatus]
+	if !ok {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s config: %w", ConfigReportHubStatus, err)
+	}
+	return enabled, nil
+}
+
+// withHubStatus returns a copy of the resource with the status of the
+// hub it was resolved from, as reported by the hub's list of catalogs.
+// The resource itself is left untouched since it may be shared through
+// the cache. Artifact Hub doesn't list catalogs so resources resolved
+// from it are returned as they are. Failing to get the status doesn't
+// fail the resolution, the status is omitted instead.
+func (r *Resolver) withHubStatus(ctx context.Context, opts requestOptions, resource *ResolvedHubResource) *ResolvedHubResource {
+	if resource.HubType == ArtifactHubType || resource.HubURL == "" {
+		return resource
+	}
+	url, err := joinURL(resource.HubURL, CatalogsEndpoint)
+	if err != nil {
+		return resource
+	}
+	// The status is only checked once, a hub that's down isn't worth
+	// waiting for since the resource has been resolved already.
+	opts.retries = 0
+	cr := tektonHubCatalogsResponse{}
+	if err := r.fetch(ctx, opts, url, &cr); err != nil {
+		logging.FromContext(ctx).Warnf("failed to get the status of hub %s: %v", resource.HubURL, err)
+		return resource
+	}
+	withStatus := *resource
+	withStatus.HubVersion = cr.Version
+	for _, c := range cr.Data {
+		// The hub matches catalog names regardless of case.
+		if strings.EqualFold(c.Name, resource.Catalog) {
+			withStatus.CatalogUpdatedAt = c.UpdatedAt
+			break
+		}
+	}
+	return &withStatus
+}
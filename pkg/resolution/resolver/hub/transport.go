@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// transportConfig is the configuration the http client used for
+// requests to the hub is built from.
+type transportConfig struct {
+	proxyURL string
+	caBundle string
+	pool     framework.HTTPPoolSettings
+}
+
+// httpClient returns the client used for requests to the hub. The
+// framework's shared client, which honors the proxy environment
+// variables and trusts the system certificate authorities, is used
+// unless a proxy or CA bundle has been configured. Otherwise the client
+// is reused until the configuration changes so that connections to the
+// hub are pooled all the same.
+func (r *Resolver) httpClient(ctx context.Context) (*http.Client, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if conf[ConfigProxyURL] == "" && conf[ConfigCABundle] == "" {
+		return framework.HTTPClient(ctx), nil
+	}
+	tc := transportConfig{
+		proxyURL: conf[ConfigProxyURL],
+		caBundle: conf[ConfigCABundle],
+		pool:     framework.HTTPPoolSettingsFromContext(ctx),
+	}
+
+	r.clientMu.Lock()
+	defer r.clientMu.Unlock()
+	if r.client != nil && r.clientConfig == tc {
+		return r.client, nil
+	}
+	transport, err := newTransport(tc)
+	if err != nil {
+		return nil, err
+	}
+	r.client = &http.Client{Transport: transport}
+	r.clientConfig = tc
+	return r.client, nil
+}
+
+// newTransport returns a transport with the configured connection pool
+// that sends requests through the configured proxy and trusts the
+// configured CA bundle.
+func newTransport(tc transportConfig) (*http.Transport, error) {
+	transport := tc.pool.NewTransport()
+	if tc.proxyURL != "" {
+		// The parse error isn't included since it repeats the url,
+		// which may hold the proxy's credentials.
+		u, err := url.Parse(tc.proxyURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return nil, fmt.Errorf("invalid %s config: must be an http, https or socks5 url", ConfigProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if tc.caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(tc.caBundle)) {
+			return nil, fmt.Errorf("invalid %s config: no PEM encoded certificates could be parsed", ConfigCABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return transport, nil
+}
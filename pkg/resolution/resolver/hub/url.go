@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// buildURL returns the url of a path on the hub at hubURL. The path is
+// a format such as ArtifactHubYamlEndpoint whose verbs are replaced with
+// the given segments, each of them path escaped so that e.g. a slash in
+// a name can't change which resource is requested.
+func buildURL(hubURL, path string, segments ...string) (string, error) {
+	escaped := make([]interface{}, 0, len(segments))
+	for _, segment := range segments {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	return joinURL(hubURL, fmt.Sprintf(path, escaped...))
+}
+
+// joinURL returns the url of the given path, whose segments must
+// already be escaped, on the hub at hubURL. It returns an error if the
+// result isn't an absolute http or https url.
+func joinURL(hubURL, path string) (string, error) {
+	raw := strings.TrimSuffix(hubURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid hub url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid hub url %q: must be an absolute http or https url", raw)
+	}
+	return raw, nil
+}
+
+// validatePathSegment returns an error if the value of the given param
+// can't safely be used as a segment of a hub url even once escaped:
+// dot segments are resolved away by the hub or a proxy in front of it,
+// changing which resource is requested, and control characters and
+// invalid UTF-8 are rejected by many servers.
+func validatePathSegment(param, value string) error {
+	switch {
+	case value == "." || value == "..":
+		return fmt.Errorf("invalid %s param %q: must not be a dot segment", param, value)
+	case !utf8.ValidString(value):
+		return fmt.Errorf("invalid %s param %q: must be valid UTF-8", param, value)
+	case strings.IndexFunc(value, unicode.IsControl) >= 0:
+		return fmt.Errorf("invalid %s param %q: must not contain control characters", param, value)
+	}
+	return nil
+}
+
+// validateHubURLConfig returns an error if a hub url set in the
+// resolver's config isn't an absolute http or https url.
+func validateHubURLConfig(ctx context.Context) error {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	for _, key := range []string{ConfigHubURL, ConfigArtifactHubURL} {
+		if hubURL := conf[key]; hubURL != "" {
+			if _, err := joinURL(hubURL, ""); err != nil {
+				return fmt.Errorf("invalid %s config: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
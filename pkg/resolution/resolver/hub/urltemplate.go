@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// DefaultURLTemplate is the path, relative to the hub api, of the yaml
+// for a specific version of a resource when the url-template config
+// isn't set. It is equivalent to YamlEndpoint.
+const DefaultURLTemplate = "v1/resource/{catalog}/{kind}/{name}/{version}/yaml"
+
+// DefaultVersionsURLTemplate is the path, relative to the hub api,
+// listing the available versions of a resource when the
+// versions-url-template config isn't set. It is equivalent to
+// VersionsEndpoint.
+const DefaultVersionsURLTemplate = "v1/resource/{catalog}/{kind}/{name}/versions"
+
+// DefaultSignedURLTemplate is the path, relative to the hub api, of the
+// yaml for a specific version of a resource along with its signature
+// when the signed-url-template config isn't set.
+const DefaultSignedURLTemplate = "v1/resource/{catalog}/{kind}/{name}/{version}/signed"
+
+// placeholderRegex matches the placeholders of a url template, such as
+// {name}.
+var placeholderRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// urlTemplates are the templates of the Tekton Hub paths used to fetch a
+// resource, signed or not, and to list its versions.
+type urlTemplates struct {
+	content  string
+	versions string
+	signed   string
+}
+
+// templatePlaceholders maps the placeholders allowed in url templates
+// to the params they're filled from.
+var templatePlaceholders = map[string]string{
+	"catalog": ParamCatalog,
+	"kind":    ParamKind,
+	"name":    ParamName,
+	"version": ParamVersion,
+}
+
+// newURLTemplates returns the url templates set in the resolver's
+// config, or the defaults that match the Tekton Hub v1 api, and an
+// error if they use an unknown placeholder.
+func newURLTemplates(ctx context.Context) (urlTemplates, error) {
+	templates := urlTemplates{
+		content:  DefaultURLTemplate,
+		versions: DefaultVersionsURLTemplate,
+		signed:   DefaultSignedURLTemplate,
+	}
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if t, ok := conf[ConfigURLTemplate]; ok && t != "" {
+		templates.content = t
+	}
+	if t, ok := conf[ConfigVersionsURLTemplate]; ok && t != "" {
+		templates.versions = t
+	}
+	if t, ok := conf[ConfigSignedURLTemplate]; ok && t != "" {
+		templates.signed = t
+	}
+
+	for _, template := range []struct{ config, value string }{
+		{ConfigURLTemplate, templates.content},
+		{ConfigSignedURLTemplate, templates.signed},
+	} {
+		for _, placeholder := range placeholders(template.value) {
+			if _, ok := templatePlaceholders[placeholder]; !ok {
+				return templates, fmt.Errorf("invalid %s config: unknown placeholder {%s}", template.config, placeholder)
+			}
+		}
+	}
+	for _, placeholder := range placeholders(templates.versions) {
+		if _, ok := templatePlaceholders[placeholder]; !ok {
+			return templates, fmt.Errorf("invalid %s config: unknown placeholder {%s}", ConfigVersionsURLTemplate, placeholder)
+		}
+		if placeholder == "version" {
+			return templates, fmt.Errorf("invalid %s config: versions are listed before the version is known, so {version} can't be used", ConfigVersionsURLTemplate)
+		}
+	}
+	return templates, nil
+}
+
+// checkParams returns an error if a placeholder of the configured
+// templates can't be filled from the given params. The version
+// placeholder is always satisfiable since the latest version is resolved
+// when the version param isn't set. The default templates aren't checked,
+// a missing default catalog or kind is reported when resolving instead.
+// The signed template is only checked for signed requests.
+func (t urlTemplates) checkParams(params map[string]string, signed bool) error {
+	templates := []struct{ config, value, defaultValue string }{
+		{ConfigURLTemplate, t.content, DefaultURLTemplate},
+		{ConfigVersionsURLTemplate, t.versions, DefaultVersionsURLTemplate},
+	}
+	if signed {
+		templates = append(templates, struct{ config, value, defaultValue string }{ConfigSignedURLTemplate, t.signed, DefaultSignedURLTemplate})
+	}
+	for _, template := range templates {
+		if template.value == template.defaultValue {
+			continue
+		}
+		for _, placeholder := range placeholders(template.value) {
+			param := templatePlaceholders[placeholder]
+			if param == ParamVersion {
+				continue
+			}
+			if _, ok := params[param]; !ok {
+				return fmt.Errorf("%s config placeholder {%s} can't be satisfied: missing %s param", template.config, placeholder, param)
+			}
+		}
+	}
+	return nil
+}
+
+// contentURL returns the url of the yaml of the given version of a
+// resource on the hub set in the ref.
+func (t urlTemplates) contentURL(ref resourceRef, version string) (string, error) {
+	return joinURL(ref.hubURL, expandURLTemplate(t.content, ref, version))
+}
+
+// signedContentURL returns the url of the yaml and signature of the
+// given version of a resource on the hub set in the ref.
+func (t urlTemplates) signedContentURL(ref resourceRef, version string) (string, error) {
+	return joinURL(ref.hubURL, expandURLTemplate(t.signed, ref, version))
+}
+
+// versionsURL returns the url listing the versions of a resource on the
+// hub set in the ref.
+func (t urlTemplates) versionsURL(ref resourceRef) (string, error) {
+	return joinURL(ref.hubURL, expandURLTemplate(t.versions, ref, ""))
+}
+
+// expandURLTemplate replaces the placeholders of the template with the
+// path escaped values of the resource and version.
+func expandURLTemplate(template string, ref resourceRef, version string) string {
+	values := map[string]string{
+		"catalog": ref.catalog,
+		"kind":    ref.kind,
+		"name":    ref.name,
+		"version": version,
+	}
+	return placeholderRegex.ReplaceAllStringFunc(template, func(match string) string {
+		return url.PathEscape(values[match[1:len(match)-1]])
+	})
+}
+
+// placeholders returns the names of the placeholders in the template.
+func placeholders(template string) []string {
+	var names []string
+	for _, match := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The Tekton Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"fmt"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+)
+
+// validateVersion returns an error if the given version param is
+// neither empty, for the latest version, nor a version or range of
+// versions that can be parsed. Versions are accepted in the lenient
+// format the hubs publish them in, e.g. "0.6", "v1.2.3" or "0.1.0-rc1",
+// so that a typo such as "v.0.3" fails when the request is validated
+// rather than as a resource that isn't found.
+func validateVersion(v string) error {
+	if v == "" {
+		return nil
+	}
+	if framework.IsVersionConstraint(v) {
+		if _, err := framework.ParseVersionConstraint(v); err != nil {
+			return fmt.Errorf("invalid version constraint %q: %w", v, err)
+		}
+		return nil
+	}
+	if _, err := goversion.NewVersion(v); err != nil {
+		return fmt.Errorf("invalid version %q: must be a version such as \"0.6\" or \"1.2.3\", or a range of versions such as \"^0.6\" or \">=0.6 <0.8\"", v)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+
+// ConfigDefaultRegion is the configuration field name for the region
+// used when a request doesn't include the region param.
+const ConfigDefaultRegion = "default-region"
+
+// ConfigDefaultEndpoint is the configuration field name for the
+// endpoint used when a request doesn't include the endpoint param.
+const ConfigDefaultEndpoint = "default-endpoint"
+
+// ConfigAddressingStyle is the configuration field name for how the
+// bucket is addressed in requests, either "virtual-hosted" for
+// https://<bucket>.<endpoint>/<key> or "path" for
+// https://<endpoint>/<bucket>/<key>. Defaults to "virtual-hosted".
+const ConfigAddressingStyle = "addressing-style"
+
+// ConfigFetchTimeout is the configuration field name for the maximum
+// duration of a single request for an object.
+const ConfigFetchTimeout = framework.ConfigFetchTimeout
+
+// ConfigMaxResponseSize is the configuration field name for the maximum
+// size of a fetched object, as a Kubernetes quantity, e.g. "5Mi".
+const ConfigMaxResponseSize = framework.ConfigMaxResponseSize
+
+const (
+	// AddressingStyleVirtualHosted addresses the bucket as a subdomain
+	// of the endpoint.
+	AddressingStyleVirtualHosted = "virtual-hosted"
+	// AddressingStylePath addresses the bucket as the first segment of
+	// the path.
+	AddressingStylePath = "path"
+)
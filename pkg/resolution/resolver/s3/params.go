@@ -0,0 +1,37 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+// ParamBucket is the parameter defining the bucket holding the object.
+const ParamBucket = "bucket"
+
+// ParamKey is the parameter defining the key of the object to fetch.
+const ParamKey = "key"
+
+// ParamRegion is the parameter defining the region of the bucket,
+// overriding the default-region config.
+const ParamRegion = "region"
+
+// ParamEndpoint is the parameter defining the http(s) endpoint of the
+// S3-compatible store, e.g. a MinIO server, overriding the
+// default-endpoint config. Defaults to the AWS endpoint of the region.
+const ParamEndpoint = "endpoint"
+
+// ParamSecret is the parameter defining the name of a secret, in the
+// namespace of the resolution request, holding the credentials used to
+// sign requests. Requests are sent anonymously when it isn't given.
+const ParamSecret = "secret"
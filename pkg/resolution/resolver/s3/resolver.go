@@ -0,0 +1,501 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	resolverconfig "github.com/tektoncd/pipeline/pkg/apis/config/resolver"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/apis/resolution/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	"go.opencensus.io/trace"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/client/injection/kube/client"
+)
+
+const (
+	// LabelValueS3ResolverType is the value to use for the
+	// resolution.tekton.dev/type label on resource requests
+	LabelValueS3ResolverType string = "s3"
+
+	disabledError = "cannot handle resolution request, enable-s3-resolver feature flag not true"
+
+	// accessKeyIDSecretKey is the key in the credentials secret holding
+	// the access key id.
+	accessKeyIDSecretKey = "aws_access_key_id"
+	// secretAccessKeySecretKey is the key in the credentials secret
+	// holding the secret access key.
+	secretAccessKeySecretKey = "aws_secret_access_key"
+	// sessionTokenSecretKey is the optional key in the credentials
+	// secret holding a session token for temporary credentials.
+	sessionTokenSecretKey = "aws_session_token"
+
+	// emptyPayloadHash is the SHA-256 digest of the empty body of a GET
+	// request, which S3 requires in the signature.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+var (
+	// bucketRegex matches bucket names that can be used with both
+	// addressing styles.
+	bucketRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	// regionRegex matches region names, which become part of the
+	// default endpoint's host.
+	regionRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+var _ framework.Resolver = &Resolver{}
+
+// Resolver implements a framework.Resolver that can fetch objects from
+// S3-compatible object stores.
+type Resolver struct {
+	kubeClientSet kubernetes.Interface
+	// httpClient is the client used to request objects. Defaults to
+	// the framework's shared client.
+	httpClient *http.Client
+}
+
+// Initialize sets up the kubernetes client used to read credentials.
+func (r *Resolver) Initialize(ctx context.Context) error {
+	r.kubeClientSet = client.Get(ctx)
+	return nil
+}
+
+// GetName returns a string name to refer to this resolver by.
+func (r *Resolver) GetName(context.Context) string {
+	return "S3"
+}
+
+// GetConfigName returns the name of the s3 resolver's configmap.
+func (r *Resolver) GetConfigName(context.Context) string {
+	return "s3-resolver-config"
+}
+
+// GetSelector returns a map of labels to match requests to this resolver.
+func (r *Resolver) GetSelector(context.Context) map[string]string {
+	return map[string]string{
+		common.LabelKeyResolverType: LabelValueS3ResolverType,
+	}
+}
+
+var _ framework.ParamSchemaProvider = &Resolver{}
+
+// GetParamSchema describes the params accepted by the resolver, with the
+// region and endpoint defaulting to those set in the resolver's config.
+func (r *Resolver) GetParamSchema(ctx context.Context) []framework.ParamSchema {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	return []framework.ParamSchema{{
+		Name:        ParamBucket,
+		Description: "The bucket holding the object.",
+		Required:    true,
+	}, {
+		Name:        ParamKey,
+		Description: "The key of the object to fetch.",
+		Required:    true,
+	}, {
+		Name:        ParamRegion,
+		Description: "The region of the bucket.",
+		Required:    conf[ConfigDefaultRegion] == "",
+		Default:     conf[ConfigDefaultRegion],
+	}, {
+		Name:        ParamEndpoint,
+		Description: "The http or https endpoint of the S3-compatible store. Defaults to the AWS endpoint of the region.",
+		Default:     conf[ConfigDefaultEndpoint],
+	}, {
+		Name:        ParamSecret,
+		Description: "The name of a secret, in the namespace of the request, holding the credentials used to sign requests.",
+	}}
+}
+
+// ValidateParams ensures parameters from a request are as expected. The
+// credentials secret they reference is only read when the object is
+// resolved.
+func (r *Resolver) ValidateParams(ctx context.Context, params []pipelinev1beta1.Param) error {
+	if r.isDisabled(ctx) {
+		return common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	_, err := newRequestOptions(ctx, framework.StringParams(params))
+	return err
+}
+
+// Resolve uses the given params to fetch the requested object.
+func (r *Resolver) Resolve(ctx context.Context, params []pipelinev1beta1.Param) (framework.ResolvedResource, error) {
+	if r.isDisabled(ctx) {
+		return nil, common.NewError(common.ReasonResolverDisabled, errors.New(disabledError))
+	}
+	opts, err := newRequestOptions(ctx, framework.StringParams(params))
+	if err != nil {
+		return nil, err
+	}
+	creds, err := r.getCredentials(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.fetch(ctx, opts, creds)
+}
+
+// requestOptions are the settings used to fetch a single object.
+type requestOptions struct {
+	bucket   string
+	key      string
+	region   string
+	endpoint *url.URL
+	// pathStyle is true when the bucket is addressed in the path rather
+	// than as a subdomain of the endpoint.
+	pathStyle bool
+	secret    string
+	timeout   time.Duration
+	// maxResponseSize is the maximum size in bytes of the object.
+	maxResponseSize int64
+}
+
+// newRequestOptions returns the settings for fetching an object given
+// the resolution's params and the resolver's config.
+func newRequestOptions(ctx context.Context, params map[string]string) (requestOptions, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	opts := requestOptions{
+		bucket: params[ParamBucket],
+		key:    params[ParamKey],
+		region: params[ParamRegion],
+	}
+	if opts.region == "" {
+		opts.region = conf[ConfigDefaultRegion]
+	}
+
+	var missingParams []string
+	for _, p := range []struct {
+		name  string
+		value string
+	}{{ParamBucket, opts.bucket}, {ParamKey, opts.key}, {ParamRegion, opts.region}} {
+		if p.value == "" {
+			missingParams = append(missingParams, p.name)
+		}
+	}
+	if len(missingParams) > 0 {
+		return opts, fmt.Errorf("missing required s3 resolver params: %s", strings.Join(missingParams, ", "))
+	}
+	if !bucketRegex.MatchString(opts.bucket) || strings.Contains(opts.bucket, "..") {
+		return opts, fmt.Errorf("invalid %s param %q: must be 3 to 63 lowercase letters, numbers, dots or hyphens, starting and ending with a letter or number", ParamBucket, opts.bucket)
+	}
+	if strings.HasPrefix(opts.key, "/") {
+		return opts, fmt.Errorf("invalid %s param %q: must not start with a /", ParamKey, opts.key)
+	}
+	if !regionRegex.MatchString(opts.region) {
+		return opts, fmt.Errorf("invalid %s param %q: must be lowercase letters, numbers or hyphens", ParamRegion, opts.region)
+	}
+
+	endpoint, ok := params[ParamEndpoint]
+	if !ok || endpoint == "" {
+		endpoint = conf[ConfigDefaultEndpoint]
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return opts, fmt.Errorf("invalid %s param: %w", ParamEndpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return opts, fmt.Errorf("invalid %s param %q: scheme must be http or https", ParamEndpoint, endpoint)
+	}
+	if u.Host == "" {
+		return opts, fmt.Errorf("invalid %s param %q: missing host", ParamEndpoint, endpoint)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return opts, fmt.Errorf("invalid %s param %q: must not include a path or query", ParamEndpoint, endpoint)
+	}
+	opts.endpoint = u
+
+	if secret, ok := params[ParamSecret]; ok {
+		if secret == "" {
+			return opts, fmt.Errorf("%s param must not be empty", ParamSecret)
+		}
+		opts.secret = secret
+	}
+
+	switch style := conf[ConfigAddressingStyle]; style {
+	case "", AddressingStyleVirtualHosted:
+	case AddressingStylePath:
+		opts.pathStyle = true
+	default:
+		return opts, fmt.Errorf("invalid %s config %q: must be %s or %s", ConfigAddressingStyle, style, AddressingStyleVirtualHosted, AddressingStylePath)
+	}
+	fetchOpts, err := framework.FetchOptionsFromConfig(conf)
+	if err != nil {
+		return opts, err
+	}
+	opts.timeout, opts.maxResponseSize = fetchOpts.Timeout, fetchOpts.MaxResponseSize
+	return opts, nil
+}
+
+// objectURI returns the s3:// uri of the object, used to refer to it in
+// errors and provenance regardless of the endpoint it's fetched from.
+func (o requestOptions) objectURI() string {
+	return fmt.Sprintf("s3://%s/%s", o.bucket, o.key)
+}
+
+// objectURL returns the url of the object using the configured
+// addressing style.
+func (o requestOptions) objectURL() *url.URL {
+	u := *o.endpoint
+	if o.pathStyle {
+		u.Path = "/" + o.bucket + "/" + o.key
+		u.RawPath = "/" + o.bucket + "/" + escapeKey(o.key)
+	} else {
+		u.Host = o.bucket + "." + u.Host
+		u.Path = "/" + o.key
+		u.RawPath = "/" + escapeKey(o.key)
+	}
+	return &u
+}
+
+// escapeKey percent-encodes every byte of the key except unreserved
+// characters and slashes, which is the encoding S3 expects in the
+// canonical request of a signature.
+func escapeKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// getCredentials returns the credentials held by the secret referenced
+// by the secret param, or nil if no secret was given. The credentials
+// themselves are never included in returned errors.
+func (r *Resolver) getCredentials(ctx context.Context, opts requestOptions) (*aws.Credentials, error) {
+	if opts.secret == "" {
+		return nil, nil
+	}
+	if r.kubeClientSet == nil {
+		return nil, fmt.Errorf("cannot read credentials secret %s: no kubernetes client available", opts.secret)
+	}
+
+	namespace := common.RequestNamespace(ctx)
+	secret, err := framework.GetCredentialSecret(ctx, r.kubeClientSet, namespace, opts.secret, "s3 credentials")
+	if err != nil {
+		return nil, err
+	}
+	creds := &aws.Credentials{
+		AccessKeyID:     strings.TrimSpace(string(secret.Data[accessKeyIDSecretKey])),
+		SecretAccessKey: strings.TrimSpace(string(secret.Data[secretAccessKeySecretKey])),
+		SessionToken:    strings.TrimSpace(string(secret.Data[sessionTokenSecretKey])),
+	}
+	for key, value := range map[string]string{
+		accessKeyIDSecretKey:     creds.AccessKeyID,
+		secretAccessKeySecretKey: creds.SecretAccessKey,
+	} {
+		if value == "" {
+			return nil, fmt.Errorf("cannot get s3 credentials, key %s not found in secret %s in namespace %s", key, opts.secret, namespace)
+		}
+	}
+	return creds, nil
+}
+
+// s3Error is the body of an error response from S3.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// String describes the error by its code and message, falling back to
+// the status when the body couldn't be parsed.
+func (e s3Error) String() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// readS3Error parses the error body of a failed response.
+func readS3Error(resp *http.Response) s3Error {
+	var e s3Error
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err == nil {
+		_ = xml.Unmarshal(body, &e)
+	}
+	if e.Code == "" {
+		e.Code = resp.Status
+	}
+	return e
+}
+
+// fetch performs a GET request for the object, signed with the given
+// credentials if there are any, and returns it.
+func (r *Resolver) fetch(ctx context.Context, opts requestOptions, creds *aws.Credentials) (_ *ResolvedS3Resource, err error) {
+	ctx, span := framework.StartSpan(ctx, "s3.Get", trace.StringAttribute("object", opts.objectURI()))
+	defer func() { framework.EndSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	objectURL := opts.objectURL()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing request for %s: %w", opts.objectURI(), err)
+	}
+	framework.SetUserAgent(ctx, req)
+	if creds != nil {
+		req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+		signer := v4.NewSigner(func(o *v4.SignerOptions) {
+			// The key is already escaped the way S3 expects it.
+			o.DisableURIPathEscaping = true
+		})
+		if err := signer.SignHTTP(ctx, *creds, req, emptyPayloadHash, "s3", opts.region, time.Now()); err != nil {
+			return nil, fmt.Errorf("error signing request for %s: %w", opts.objectURI(), err)
+		}
+	}
+
+	httpClient := r.httpClient
+	if httpClient == nil {
+		httpClient = framework.HTTPClient(ctx)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, framework.TimeoutError(opts.objectURI(), opts.timeout)
+		}
+		return nil, fmt.Errorf("error requesting %s from %s: %w", opts.objectURI(), opts.endpoint.Host, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		s3Err := readS3Error(resp)
+		if s3Err.Code == "NoSuchBucket" {
+			return nil, &common.ResolutionNotFoundError{
+				Resource: "s3://" + opts.bucket,
+				Original: fmt.Errorf("bucket %s does not exist (%s)", opts.bucket, s3Err),
+			}
+		}
+		return nil, &common.ResolutionNotFoundError{
+			Resource: opts.objectURI(),
+			Original: fmt.Errorf("object %s does not exist (%s)", opts.objectURI(), s3Err),
+		}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		s3Err := readS3Error(resp)
+		if opts.secret == "" {
+			return nil, fmt.Errorf("access to %s was denied (%s), anonymous requests aren't allowed, set the %s param to a secret holding credentials", opts.objectURI(), s3Err, ParamSecret)
+		}
+		return nil, fmt.Errorf("access to %s was denied (%s), check the credentials in secret %s and the permissions granted to them", opts.objectURI(), s3Err, opts.secret)
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		return nil, fmt.Errorf("request for %s failed with status code %d (%s)", opts.objectURI(), resp.StatusCode, readS3Error(resp))
+	}
+
+	body, err := framework.ReadResponseBody(resp, opts.maxResponseSize)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, framework.TimeoutError(opts.objectURI(), opts.timeout)
+		}
+		return nil, fmt.Errorf("error reading %s: %w", opts.objectURI(), err)
+	}
+	return &ResolvedS3Resource{
+		Content:   body,
+		Bucket:    opts.bucket,
+		Key:       opts.key,
+		Region:    opts.region,
+		URL:       objectURL.String(),
+		VersionID: resp.Header.Get("X-Amz-Version-Id"),
+	}, nil
+}
+
+// ResolvedS3Resource wraps the data we want to return to Pipelines
+type ResolvedS3Resource struct {
+	Content []byte
+	Bucket  string
+	Key     string
+	Region  string
+	// URL is the url the object was fetched from.
+	URL string
+	// VersionID is the version of the object that was fetched, empty
+	// if versioning isn't enabled on the bucket.
+	VersionID string
+}
+
+var _ framework.ResolvedResource = &ResolvedS3Resource{}
+
+// Data returns the bytes of the fetched object.
+func (rr *ResolvedS3Resource) Data() []byte {
+	return rr.Content
+}
+
+// Annotations returns the url the object was fetched from along with
+// the digest of its content and its provenance.
+func (rr *ResolvedS3Resource) Annotations() map[string]string {
+	source := rr.Source()
+	coordinates := map[string]string{
+		"bucket": rr.Bucket,
+		"key":    rr.Key,
+		"region": rr.Region,
+	}
+	if rr.VersionID != "" {
+		coordinates["versionId"] = rr.VersionID
+	}
+	return map[string]string{
+		AnnotationKeyURL:    rr.URL,
+		AnnotationKeyDigest: "sha256:" + source.Digest["sha256"],
+		common.AnnotationKeyProvenance: common.Provenance{
+			ResolverType: LabelValueS3ResolverType,
+			URI:          source.URI,
+			Digest:       source.Digest,
+			Coordinates:  coordinates,
+		}.AnnotationValue(),
+	}
+}
+
+// Source is the source reference of the remote data that records the
+// s3:// uri of the object and the digest of its content.
+func (rr *ResolvedS3Resource) Source() *v1beta1.ConfigSource {
+	sum := sha256.Sum256(rr.Content)
+	return &v1beta1.ConfigSource{
+		URI: fmt.Sprintf("s3://%s/%s", rr.Bucket, rr.Key),
+		Digest: map[string]string{
+			"sha256": hex.EncodeToString(sum[:]),
+		},
+	}
+}
+
+func (r *Resolver) isDisabled(ctx context.Context) bool {
+	cfg := resolverconfig.FromContextOrDefaults(ctx)
+	if cfg.FeatureFlags.EnableS3Resolver {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,480 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	resolutioncommon "github.com/tektoncd/pipeline/pkg/resolution/common"
+	"github.com/tektoncd/pipeline/pkg/resolution/resolver/framework"
+	frtesting "github.com/tektoncd/pipeline/pkg/resolution/resolver/framework/testing"
+	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+)
+
+const testContent = "apiVersion: tekton.dev/v1beta1\nkind: Task\nmetadata:\n  name: foo\n"
+
+var credentialsSecret = &corev1.Secret{
+	ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "foo-ns"},
+	Data: map[string][]byte{
+		"aws_access_key_id":     []byte("AKIDEXAMPLE"),
+		"aws_secret_access_key": []byte("secret\n"),
+		"aws_session_token":     []byte("session"),
+	},
+}
+
+func TestGetSelector(t *testing.T) {
+	resolver := Resolver{}
+	sel := resolver.GetSelector(resolverContext())
+	if typ, has := sel[resolutioncommon.LabelKeyResolverType]; !has {
+		t.Fatalf("unexpected selector: %v", sel)
+	} else if typ != LabelValueS3ResolverType {
+		t.Fatalf("unexpected type: %q", typ)
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	incompleteSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "incomplete", Namespace: "foo-ns"},
+		Data:       map[string][]byte{"aws_access_key_id": []byte("AKIDEXAMPLE")},
+	}
+	validParams := map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1"}
+
+	testCases := []struct {
+		name         string
+		params       map[string]string
+		config       map[string]string
+		expectedErr  string
+		errOnResolve bool
+	}{
+		{
+			name:   "valid",
+			params: validParams,
+		},
+		{
+			name:   "region from config",
+			params: map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml"},
+			config: map[string]string{ConfigDefaultRegion: "eu-west-1"},
+		},
+		{
+			name:   "with endpoint and secret",
+			params: map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamEndpoint: "http://minio.example.com:9000", ParamSecret: "s3-credentials"},
+			config: map[string]string{ConfigAddressingStyle: AddressingStylePath},
+		},
+		{
+			name:        "missing bucket",
+			params:      map[string]string{ParamKey: "task.yaml", ParamRegion: "us-east-1"},
+			expectedErr: "missing required s3 resolver params: bucket",
+		},
+		{
+			name:        "missing everything",
+			params:      map[string]string{},
+			expectedErr: "missing required s3 resolver params: bucket, key, region",
+		},
+		{
+			name:        "invalid bucket",
+			params:      map[string]string{ParamBucket: "My_Bucket", ParamKey: "task.yaml", ParamRegion: "us-east-1"},
+			expectedErr: `invalid bucket param "My_Bucket": must be 3 to 63 lowercase letters, numbers, dots or hyphens, starting and ending with a letter or number`,
+		},
+		{
+			name:        "key with leading slash",
+			params:      map[string]string{ParamBucket: "tasks", ParamKey: "/task.yaml", ParamRegion: "us-east-1"},
+			expectedErr: `invalid key param "/task.yaml": must not start with a /`,
+		},
+		{
+			name:        "invalid region",
+			params:      map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "evil.com/"},
+			expectedErr: `invalid region param "evil.com/": must be lowercase letters, numbers or hyphens`,
+		},
+		{
+			name:        "unsupported endpoint scheme",
+			params:      map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamEndpoint: "ftp://minio.example.com"},
+			expectedErr: `invalid endpoint param "ftp://minio.example.com": scheme must be http or https`,
+		},
+		{
+			name:        "endpoint with path",
+			params:      map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamEndpoint: "https://minio.example.com/tasks"},
+			expectedErr: `invalid endpoint param "https://minio.example.com/tasks": must not include a path or query`,
+		},
+		{
+			name:        "invalid addressing style",
+			params:      validParams,
+			config:      map[string]string{ConfigAddressingStyle: "dns"},
+			expectedErr: `invalid addressing-style config "dns": must be virtual-hosted or path`,
+		},
+		{
+			name:        "invalid timeout config",
+			params:      validParams,
+			config:      map[string]string{ConfigFetchTimeout: "0s"},
+			expectedErr: "invalid fetch-timeout config: timeout must be greater than zero, got 0s",
+		},
+		{
+			name:         "missing secret",
+			params:       map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamSecret: "other"},
+			expectedErr:  "cannot get s3 credentials, secret other not found in namespace foo-ns",
+			errOnResolve: true,
+		},
+		{
+			name:         "incomplete secret",
+			params:       map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamSecret: "incomplete"},
+			expectedErr:  "cannot get s3 credentials, key aws_secret_access_key not found in secret incomplete in namespace foo-ns",
+			errOnResolve: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(credentialsSecret, incompleteSecret)}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+			ctx = framework.InjectResolverConfigToContext(ctx, tc.config)
+			err := resolver.ValidateParams(ctx, toParams(tc.params))
+			if tc.errOnResolve {
+				// The secret is only read when resolving.
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				_, err = resolver.Resolve(ctx, toParams(tc.params))
+			}
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestValidateParamsNoNetwork(t *testing.T) {
+	trap := frtesting.NewNetworkTrap(t)
+	resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(credentialsSecret)}
+	ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+	for _, config := range []map[string]string{
+		{ConfigDefaultEndpoint: trap.URL()},
+		{ConfigDefaultEndpoint: trap.URL(), ConfigAddressingStyle: AddressingStylePath},
+	} {
+		params := map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamSecret: "s3-credentials"}
+		if err := resolver.ValidateParams(framework.InjectResolverConfigToContext(ctx, config), toParams(params)); err != nil {
+			t.Fatalf("unexpected error validating params: %v", err)
+		}
+	}
+	params := map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamEndpoint: trap.URL()}
+	if err := resolver.ValidateParams(ctx, toParams(params)); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	trap.ExpectNoConnections(t)
+}
+
+func TestValidateParamsDisabled(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), toParams(map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1"}))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolveDisabled(t *testing.T) {
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), toParams(map[string]string{ParamBucket: "tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1"}))
+	if err == nil {
+		t.Fatalf("expected disabled err")
+	}
+	if d := cmp.Diff(disabledError, err.Error()); d != "" {
+		t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	testCases := []struct {
+		name            string
+		key             string
+		secret          string
+		addressingStyle string
+		versionID       string
+		expectedHost    string
+		expectedPath    string
+		expectedURL     string
+	}{
+		{
+			name:         "virtual-hosted style",
+			key:          "tasks/task.yaml",
+			expectedHost: "shared-tasks.s3.test",
+			expectedPath: "/tasks/task.yaml",
+			expectedURL:  "http://shared-tasks.s3.test/tasks/task.yaml",
+		},
+		{
+			name:            "path style",
+			key:             "tasks/task.yaml",
+			addressingStyle: AddressingStylePath,
+			expectedHost:    "s3.test",
+			expectedPath:    "/shared-tasks/tasks/task.yaml",
+			expectedURL:     "http://s3.test/shared-tasks/tasks/task.yaml",
+		},
+		{
+			name:            "signed with credentials",
+			key:             "tasks/task.yaml",
+			secret:          "s3-credentials",
+			addressingStyle: AddressingStylePath,
+			versionID:       "3HL4kqtJlcpXroDTDmJ",
+			expectedHost:    "s3.test",
+			expectedPath:    "/shared-tasks/tasks/task.yaml",
+			expectedURL:     "http://s3.test/shared-tasks/tasks/task.yaml",
+		},
+		{
+			name:         "escaped key",
+			key:          "tasks/my task+v1=2.yaml",
+			secret:       "s3-credentials",
+			expectedHost: "shared-tasks.s3.test",
+			expectedPath: "/tasks/my task+v1=2.yaml",
+			expectedURL:  "http://shared-tasks.s3.test/tasks/my%20task%2Bv1%3D2.yaml",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Host != tc.expectedHost || r.URL.Path != tc.expectedPath {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, "<Error><Code>UnexpectedRequest</Code><Message>%s %s</Message></Error>", r.Host, r.URL.Path)
+					return
+				}
+				if ua := r.Header.Get("User-Agent"); ua != framework.DefaultUserAgent {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, "<Error><Code>UnexpectedUserAgent</Code><Message>%s</Message></Error>", ua)
+					return
+				}
+				auth := r.Header.Get("Authorization")
+				if tc.secret == "" {
+					if auth != "" {
+						w.WriteHeader(http.StatusBadRequest)
+						fmt.Fprintf(w, "<Error><Code>UnexpectedAuthorization</Code><Message>%s</Message></Error>", auth)
+						return
+					}
+				} else if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+					!strings.Contains(auth, "/us-east-1/s3/aws4_request") ||
+					r.Header.Get("X-Amz-Security-Token") != "session" {
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+					return
+				}
+				if tc.versionID != "" {
+					w.Header().Set("X-Amz-Version-Id", tc.versionID)
+				}
+				fmt.Fprint(w, testContent)
+			}))
+			defer svr.Close()
+
+			resolver := Resolver{
+				kubeClientSet: fakek8s.NewSimpleClientset(credentialsSecret),
+				httpClient:    clientFor(svr),
+			}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+			ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{
+				ConfigDefaultEndpoint: "http://s3.test",
+				ConfigAddressingStyle: tc.addressingStyle,
+			})
+			params := map[string]string{ParamBucket: "shared-tasks", ParamKey: tc.key, ParamRegion: "us-east-1"}
+			if tc.secret != "" {
+				params[ParamSecret] = tc.secret
+			}
+			output, err := resolver.Resolve(ctx, toParams(params))
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if d := cmp.Diff(testContent, string(output.Data())); d != "" {
+				t.Errorf("unexpected resource: %s", diff.PrintWantGot(d))
+			}
+
+			annotations := output.Annotations()
+			if d := cmp.Diff(tc.expectedURL, annotations[AnnotationKeyURL]); d != "" {
+				t.Errorf("unexpected url annotation: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff("sha256:"+sha256Of(testContent), annotations[AnnotationKeyDigest]); d != "" {
+				t.Errorf("unexpected digest annotation: %s", diff.PrintWantGot(d))
+			}
+			provenance, err := resolutioncommon.ParseProvenance(annotations[resolutioncommon.AnnotationKeyProvenance])
+			if err != nil {
+				t.Fatalf("unexpected error parsing provenance: %v", err)
+			}
+			expectedCoordinates := map[string]string{"bucket": "shared-tasks", "key": tc.key, "region": "us-east-1"}
+			if tc.versionID != "" {
+				expectedCoordinates["versionId"] = tc.versionID
+			}
+			expectedProvenance := &resolutioncommon.Provenance{
+				ResolverType: LabelValueS3ResolverType,
+				URI:          "s3://shared-tasks/" + tc.key,
+				Digest:       map[string]string{"sha256": sha256Of(testContent)},
+				Coordinates:  expectedCoordinates,
+			}
+			if d := cmp.Diff(expectedProvenance, provenance); d != "" {
+				t.Errorf("unexpected provenance: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	testCases := []struct {
+		name             string
+		status           int
+		body             string
+		secret           string
+		expectedErr      string
+		expectedNotFound string
+	}{
+		{
+			name:             "missing object",
+			status:           http.StatusNotFound,
+			body:             "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>",
+			expectedErr:      "object s3://shared-tasks/task.yaml does not exist (NoSuchKey: The specified key does not exist.)",
+			expectedNotFound: "s3://shared-tasks/task.yaml",
+		},
+		{
+			name:             "missing bucket",
+			status:           http.StatusNotFound,
+			body:             "<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>",
+			expectedErr:      "bucket shared-tasks does not exist (NoSuchBucket: The specified bucket does not exist)",
+			expectedNotFound: "s3://shared-tasks",
+		},
+		{
+			name:        "access denied",
+			status:      http.StatusForbidden,
+			body:        "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>",
+			secret:      "s3-credentials",
+			expectedErr: "access to s3://shared-tasks/task.yaml was denied (AccessDenied: Access Denied), check the credentials in secret s3-credentials and the permissions granted to them",
+		},
+		{
+			name:        "anonymous access denied",
+			status:      http.StatusForbidden,
+			body:        "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>",
+			expectedErr: "access to s3://shared-tasks/task.yaml was denied (AccessDenied: Access Denied), anonymous requests aren't allowed, set the secret param to a secret holding credentials",
+		},
+		{
+			name:        "unparseable error",
+			status:      http.StatusInternalServerError,
+			body:        "oops",
+			expectedErr: "request for s3://shared-tasks/task.yaml failed with status code 500 (500 Internal Server Error)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer svr.Close()
+
+			resolver := Resolver{kubeClientSet: fakek8s.NewSimpleClientset(credentialsSecret)}
+			ctx := resolutioncommon.InjectRequestNamespace(resolverContext(), "foo-ns")
+			ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{ConfigAddressingStyle: AddressingStylePath})
+			params := map[string]string{ParamBucket: "shared-tasks", ParamKey: "task.yaml", ParamRegion: "us-east-1", ParamEndpoint: svr.URL}
+			if tc.secret != "" {
+				params[ParamSecret] = tc.secret
+			}
+			_, err := resolver.Resolve(ctx, toParams(params))
+			if err == nil {
+				t.Fatalf("expected err but didn't get one")
+			}
+			if d := cmp.Diff(tc.expectedErr, err.Error()); d != "" {
+				t.Errorf("unexpected error: %s", diff.PrintWantGot(d))
+			}
+			var notFoundErr *resolutioncommon.ResolutionNotFoundError
+			isNotFound := errors.As(err, &notFoundErr)
+			if tc.expectedNotFound == "" {
+				if isNotFound {
+					t.Errorf("expected an error other than ResolutionNotFoundError but got %v", err)
+				}
+				return
+			}
+			if !isNotFound || notFoundErr.Resource != tc.expectedNotFound {
+				t.Errorf("expected a ResolutionNotFoundError for %s but got %v", tc.expectedNotFound, err)
+			}
+		})
+	}
+}
+
+func TestEscapeKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		"tasks/task.yaml":       "tasks/task.yaml",
+		"a b/c+d=e.yaml":        "a%20b/c%2Bd%3De.yaml",
+		"unreserved-._~/x.yaml": "unreserved-._~/x.yaml",
+		"ünïcode.yaml":          "%C3%BCn%C3%AFcode.yaml",
+	} {
+		if d := cmp.Diff(expected, escapeKey(key)); d != "" {
+			t.Errorf("unexpected escaped key for %q: %s", key, diff.PrintWantGot(d))
+		}
+	}
+}
+
+// clientFor returns a client that sends every request to the given
+// server, whatever its host, so that virtual-hosted style requests can
+// be tested.
+func clientFor(svr *httptest.Server) *http.Client {
+	addr := svr.Listener.Addr().String()
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func resolverContext() context.Context {
+	return frtesting.ContextWithS3ResolverEnabled(context.Background())
+}
+
+func toParams(m map[string]string) []pipelinev1beta1.Param {
+	var params []pipelinev1beta1.Param
+
+	for k, v := range m {
+		params = append(params, pipelinev1beta1.Param{
+			Name:  k,
+			Value: *pipelinev1beta1.NewStructuredValues(v),
+		})
+	}
+
+	return params
+}
+
+func sha256Of(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}